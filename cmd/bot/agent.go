@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"tg-monitor-bot/internal/appmanager"
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/logging"
+	"tg-monitor-bot/internal/monitor"
+	"tg-monitor-bot/internal/storage"
+)
+
+// agentKeyEnv holds the agent's API key, so it stays out of the process list
+const agentKeyEnv = "AGENT_API_KEY"
+
+// probeAgent checks the sources assigned to its location and reports to the central API
+type probeAgent struct {
+	server   string
+	key      string
+	location string
+	client   *http.Client
+	monitor  *monitor.Monitor
+	logger   *logging.Logger
+	running  map[string]*agentCheck // sourceID -> check loop
+}
+
+// agentCheck is the check loop of one assigned source
+type agentCheck struct {
+	source appmanager.AgentSource
+	cancel context.CancelFunc
+}
+
+// runAgent runs a remote probe: it pulls the sources assigned to -location from the
+// central instance, checks them from here and reports the results, until SIGINT or SIGTERM
+func runAgent(args []string) int {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	server := fs.String("server", "", "URL of the central instance's API, e.g. https://monitor.example.com")
+	location := fs.String("location", "", "location name sources are assigned to, e.g. eu-west")
+	refresh := fs.Duration("refresh", time.Minute, "how often to pull the assigned sources")
+	timeout := fs.Duration("timeout", 10*time.Second, "HTTP request timeout")
+	pingTimeout := fs.Duration("ping-timeout", 5*time.Second, "ping timeout")
+	pingCount := fs.Int("count", 3, "ping packets to send")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s=<key> tg-monitor-bot agent -server <url> -location <name> [flags]\n", agentKeyEnv)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	key := os.Getenv(agentKeyEnv)
+	if *server == "" || *location == "" || key == "" {
+		fs.Usage()
+		return 2
+	}
+	locations, err := storage.NormalizeLocations([]string{*location})
+	if err != nil || len(locations) == 0 {
+		fmt.Fprintf(os.Stderr, "invalid location %q: %v\n", *location, err)
+		return 2
+	}
+
+	cfg, err := config.LoadFromMap(map[string]string{
+		"API_ENABLED":  "false",
+		"HTTP_TIMEOUT": timeout.String(),
+		"PING_TIMEOUT": pingTimeout.String(),
+		"PING_COUNT":   strconv.Itoa(*pingCount),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid check settings: %v\n", err)
+		return 2
+	}
+
+	agent := &probeAgent{
+		server:   strings.TrimSuffix(*server, "/") + "/api/v1",
+		key:      key,
+		location: locations[0],
+		client:   &http.Client{Timeout: 30 * time.Second},
+		monitor:  monitor.New(nil, cfg, nil),
+		logger:   logging.New("agent"),
+		running:  make(map[string]*agentCheck),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	agent.logger.Printf("🛰️  Probe agent for location %s reporting to %s", agent.location, *server)
+	ticker := time.NewTicker(*refresh)
+	defer ticker.Stop()
+	for {
+		agent.sync(ctx)
+		select {
+		case <-ctx.Done():
+			agent.logger.Println("🛑 Shutdown signal received")
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync pulls the assigned sources and starts, restarts or stops check loops to match.
+// When the central instance is unreachable the current loops keep running.
+func (a *probeAgent) sync(ctx context.Context) {
+	query := url.Values{"location": {a.location}, "version": {Version}}
+	var sources []appmanager.AgentSource
+	if err := a.call(ctx, http.MethodGet, "/agents/sources?"+query.Encode(), nil, &sources); err != nil {
+		a.logger.Warnf("Failed to pull assigned sources: %v", err)
+		return
+	}
+
+	assigned := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		assigned[source.ID] = true
+		if check, ok := a.running[source.ID]; ok {
+			if check.source == source {
+				continue
+			}
+			check.cancel()
+		}
+		interval, err := time.ParseDuration(source.CheckInterval)
+		if err != nil || interval <= 0 {
+			a.logger.Warnf("Skipping %s: invalid check interval %q", source.Name, source.CheckInterval)
+			delete(a.running, source.ID)
+			continue
+		}
+		checkCtx, cancel := context.WithCancel(ctx)
+		a.running[source.ID] = &agentCheck{source: source, cancel: cancel}
+		go a.checkLoop(checkCtx, source, interval)
+	}
+	for id, check := range a.running {
+		if !assigned[id] {
+			a.logger.Printf("No longer assigned: %s", check.source.Name)
+			check.cancel()
+			delete(a.running, id)
+		}
+	}
+}
+
+// checkLoop checks a source every interval and reports each result
+func (a *probeAgent) checkLoop(ctx context.Context, source appmanager.AgentSource, interval time.Duration) {
+	a.logger.Printf("Checking %s (%s %s) every %v", source.Name, source.Type, source.Target, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		status, reason := a.monitor.Probe(&storage.Source{ID: source.ID, Name: source.Name, Type: source.Type, Target: source.Target})
+		result := appmanager.AgentResult{
+			SourceID:  source.ID,
+			Status:    status,
+			LatencyMs: time.Since(start).Milliseconds(),
+			Error:     reason,
+			CheckedAt: start,
+		}
+		report := appmanager.AgentReport{Location: a.location, Version: Version, Results: []appmanager.AgentResult{result}}
+		if err := a.call(ctx, http.MethodPost, "/agents/results", report, nil); err != nil && ctx.Err() == nil {
+			a.logger.Warnf("Failed to report the check of %s: %v", source.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// call sends a JSON request to the central API and decodes the response into out
+func (a *probeAgent) call(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.server+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", a.key)
+	req.Header.Set("User-Agent", "outage-monitor-bot-agent/"+Version)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("server returned %d: %s", resp.StatusCode, bytes.TrimSpace(excerpt))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
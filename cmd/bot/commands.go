@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"tg-monitor-bot/internal/appmanager"
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/monitor"
+	"tg-monitor-bot/internal/storage"
+)
+
+// runCheck checks one target with the monitor's own ping/HTTP logic, without a database
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 10*time.Second, "HTTP request timeout")
+	pingTimeout := fs.Duration("ping-timeout", 5*time.Second, "ping timeout")
+	pingCount := fs.Int("count", 3, "ping packets to send")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: tg-monitor-bot check [flags] <ping|http> <target>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	sourceType, target := fs.Arg(0), fs.Arg(1)
+	if sourceType != "ping" && sourceType != "http" {
+		fmt.Fprintf(os.Stderr, "unsupported check type %q: use ping or http\n", sourceType)
+		return 2
+	}
+
+	cfg, err := config.LoadFromMap(map[string]string{
+		"API_ENABLED":  "false",
+		"HTTP_TIMEOUT": timeout.String(),
+		"PING_TIMEOUT": pingTimeout.String(),
+		"PING_COUNT":   strconv.Itoa(*pingCount),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid check settings: %v\n", err)
+		return 2
+	}
+
+	start := time.Now()
+	status, reason := monitor.New(nil, cfg, nil).Probe(&storage.Source{
+		Name:   target,
+		Type:   sourceType,
+		Target: target,
+	})
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if status != 1 {
+		fmt.Printf("❌ %s %s is offline (%s): %s\n", sourceType, target, elapsed, reason)
+		return 1
+	}
+	fmt.Printf("✅ %s %s is online (%s)\n", sourceType, target, elapsed)
+	return 0
+}
+
+// runExport writes the monitoring setup as JSON to stdout or the -o file
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "database file")
+	output := fs.String("o", "", "output file (default stdout)")
+	fs.Parse(args)
+
+	db, err := openExistingDB(*dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	export, err := db.Export()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Export failed: %v\n", err)
+		return 1
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Export failed: %v\n", err)
+		return 1
+	}
+	data = append(data, '\n')
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	// Webhook headers may carry credentials
+	if err := os.WriteFile(*output, data, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to write %s: %v\n", *output, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "✅ Exported %d sources, %d webhooks and %d chats to %s\n",
+		len(export.Sources), len(export.Webhooks), len(export.Chats), *output)
+	return 0
+}
+
+// runImport reads an export, or another tool's configuration with -format, from a file, or
+// stdin for "-", into the database
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "database file")
+	format := fs.String("format", "export", "export, uptime-kuma (backup JSON) or csv")
+	interval := fs.Duration("interval", 30*time.Second, "check interval of CSV rows without one")
+	dryRun := fs.Bool("dry-run", false, "with -format, only report what would be created")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: tg-monitor-bot import [flags] <file|->")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var data []byte
+	var err error
+	if path := fs.Arg(0); path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to read export: %v\n", err)
+		return 1
+	}
+
+	if *format != "export" {
+		return importExternal(*dbPath, *format, data, *interval, *dryRun)
+	}
+
+	var export storage.Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid export: %v\n", err)
+		return 1
+	}
+
+	db, err := openDB(*dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	result, err := db.Import(&export)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Import failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("✅ Imported %d sources, %d webhooks, %d chats, %d links and %d config keys\n",
+		result.Sources, result.Webhooks, result.Chats, result.Links, result.Config)
+	return 0
+}
+
+// importExternal creates sources, chats and webhooks from another tool's configuration
+func importExternal(dbPath, format string, data []byte, interval time.Duration, dryRun bool) int {
+	db, err := openDB(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	result, err := appmanager.ImportExternal(db, format, data, interval, dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Import failed: %v\n", err)
+		return 1
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "⚠️  %s\n", warning)
+	}
+	verb := "Imported"
+	if dryRun {
+		verb = "Would import"
+	}
+	fmt.Printf("✅ %s %d sources, %d webhooks, %d chats and %d links\n",
+		verb, result.Sources, result.Webhooks, result.Chats, result.Links)
+	return 0
+}
+
+// runBackup writes a copy of the database that can replace data/state.db as is
+func runBackup(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "database file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: tg-monitor-bot backup [flags] <path>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	db, err := openExistingDB(*dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if err := db.Backup(fs.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	fmt.Printf("✅ Database backed up to %s\n", fs.Arg(0))
+	return 0
+}
+
+// runGenKey prints a random API key and with -write stores it as API_KEY. Only the key goes
+// to stdout, so it can be captured with KEY=$(tg-monitor-bot genkey).
+func runGenKey(args []string) int {
+	return genKey(args, os.Stdout)
+}
+
+// genKey is runGenKey printing the key to out
+func genKey(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("genkey", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "database file")
+	write := fs.Bool("write", false, "store the key as API_KEY in the database (hashed)")
+	force := fs.Bool("force", false, "with -write, replace an API_KEY that is already set")
+	fs.Parse(args)
+
+	secret, err := storage.GenerateAPIKeySecret()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	if *write {
+		db, err := openDB(*dbPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		defer db.Close()
+
+		// Loading first seeds an empty database from the environment, as serve would
+		configManager := appmanager.NewConfigManager(db)
+		if err := configManager.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to load config: %v\n", err)
+			return 1
+		}
+		// Replacing the key locks out every client using it, so it takes -force
+		if configManager.Get("API_KEY") != "" && !*force {
+			fmt.Fprintln(os.Stderr, "❌ API_KEY is already set; use -force to replace it")
+			return 1
+		}
+		if err := configManager.SetMany(map[string]string{"API_KEY": secret}, "genkey"); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to store API_KEY: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(os.Stderr, "✅ Stored as API_KEY; it is only shown now, keep it somewhere safe")
+	}
+
+	fmt.Fprintln(out, secret)
+	return 0
+}
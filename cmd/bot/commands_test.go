@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tg-monitor-bot/internal/appmanager"
+	"tg-monitor-bot/internal/storage"
+)
+
+func TestGenKeyWrite(t *testing.T) {
+	t.Setenv("API_KEY", "")
+	path := filepath.Join(t.TempDir(), "state.db")
+
+	// storedAPIKey returns API_KEY as stored in the database
+	storedAPIKey := func() string {
+		t.Helper()
+		db, err := storage.NewBoltDB(path)
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+		configManager := appmanager.NewConfigManager(db)
+		if err := configManager.Load(); err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		return configManager.Get("API_KEY")
+	}
+
+	var out bytes.Buffer
+	if code := genKey([]string{"-db", path, "-write"}, &out); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	key := strings.TrimSpace(out.String())
+	if !strings.HasPrefix(key, "omk_") || len(key) != len("omk_")+48 {
+		t.Fatalf("Expected an omk_ key with 48 hex characters, got %q", key)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected the database to be written: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected the database holding the key to be 0600, got %o", perm)
+	}
+	stored := storedAPIKey()
+	if storage.ConfigAPIKeyHash(stored) != storage.HashAPIKeySecret(key) || strings.Contains(stored, key) {
+		t.Errorf("Expected the key stored hashed, got %q", stored)
+	}
+
+	// A second run keeps the existing key unless forced
+	out.Reset()
+	if code := genKey([]string{"-db", path, "-write"}, &out); code != 1 || out.Len() != 0 {
+		t.Errorf("Expected exit code 1 without a key printed, got %d and %q", code, out.String())
+	}
+	if storedAPIKey() != stored {
+		t.Error("Expected the existing API_KEY to be kept")
+	}
+	if code := genKey([]string{"-db", path, "-write", "-force"}, &out); code != 0 {
+		t.Fatalf("Expected exit code 0 with -force, got %d", code)
+	}
+	if replaced := strings.TrimSpace(out.String()); storage.ConfigAPIKeyHash(storedAPIKey()) != storage.HashAPIKeySecret(replaced) || replaced == key {
+		t.Errorf("Expected -force to store the new key %q", replaced)
+	}
+}
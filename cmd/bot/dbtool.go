@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+const dbtoolUsage = `Usage: tg-monitor-bot dbtool [-db path] <command> [flags] [args]
+
+Commands:
+  buckets              List buckets and their key counts
+  sources              List all sources, including those in trash
+  source <id>          Print a source with its sinks as JSON
+  changes [-n N] <id>  Print the latest status changes of a source
+  fix-orphans [-dry-run]
+                       Remove links and history pointing at missing sources or webhooks
+  dedupe [-dry-run]    Merge sources with the same type and target into the oldest one
+`
+
+// runDBTool inspects and repairs the database without starting the bot
+func runDBTool(args []string) int {
+	fs := flag.NewFlagSet("dbtool", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "database file")
+	fs.Usage = func() { fmt.Fprint(fs.Output(), dbtoolUsage) }
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	command, args := fs.Arg(0), fs.Args()[1:]
+
+	commands := map[string]func(*storage.BoltDB, []string) int{
+		"buckets":     dbtoolBuckets,
+		"sources":     dbtoolSources,
+		"source":      dbtoolSource,
+		"changes":     dbtoolChanges,
+		"fix-orphans": dbtoolFixOrphans,
+		"dedupe":      dbtoolDedupe,
+	}
+	run, ok := commands[command]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown dbtool command %q\n\n%s", command, dbtoolUsage)
+		return 2
+	}
+
+	db, err := openExistingDB(*dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	return run(db, args)
+}
+
+// dbtoolBuckets lists the buckets with their key counts
+func dbtoolBuckets(db *storage.BoltDB, args []string) int {
+	stats, err := db.BucketStats()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BUCKET\tKEYS")
+	for _, stat := range stats {
+		fmt.Fprintf(w, "%s\t%d\n", stat.Name, stat.Keys)
+	}
+	w.Flush()
+	return 0
+}
+
+// dbtoolSources lists all sources with their status and whether they are paused or in trash
+func dbtoolSources(db *storage.BoltDB, args []string) int {
+	active, err := db.GetAllSources()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	deleted, err := db.GetDeletedSources()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTYPE\tTARGET\tSTATUS\tSTATE")
+	for _, source := range append(active, deleted...) {
+		status := "offline"
+		if source.CurrentStatus == 1 {
+			status = "online"
+		}
+		state := "enabled"
+		switch {
+		case source.IsDeleted():
+			state = "trash"
+		case !source.Enabled:
+			state = "paused"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", source.ID, source.Name, source.Type, source.Target, status, state)
+	}
+	w.Flush()
+	return 0
+}
+
+// dbtoolSource prints one source with its sinks
+func dbtoolSource(db *storage.BoltDB, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: tg-monitor-bot dbtool source <id>")
+		return 2
+	}
+
+	source, err := db.GetSource(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	sinks, err := db.GetSourceSinks(source.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(map[string]interface{}{
+		"source": source,
+		"sinks":  sinks,
+	})
+	return 0
+}
+
+// dbtoolChanges prints the latest status changes of a source, newest first
+func dbtoolChanges(db *storage.BoltDB, args []string) int {
+	fs := flag.NewFlagSet("changes", flag.ExitOnError)
+	limit := fs.Int("n", 20, "number of changes to print")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: tg-monitor-bot dbtool changes [-n N] <id>")
+		return 2
+	}
+
+	changes, err := db.GetStatusChanges(fs.Arg(0), time.Time{}, time.Time{}, *limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tCHANGE\tPREVIOUS STATUS LASTED")
+	for _, change := range changes {
+		fmt.Fprintf(w, "%s\t%d → %d\t%s\n", change.Timestamp.Format(time.RFC3339), change.OldStatus, change.NewStatus,
+			(time.Duration(change.DurationMs) * time.Millisecond).Round(time.Second))
+	}
+	w.Flush()
+	return 0
+}
+
+// dbtoolFixOrphans removes, or with -dry-run counts, orphaned links and history
+func dbtoolFixOrphans(db *storage.BoltDB, args []string) int {
+	fs := flag.NewFlagSet("fix-orphans", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only report what would be removed")
+	fs.Parse(args)
+
+	report, err := db.FixOrphans(*dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	verb := "Removed"
+	if *dryRun {
+		verb = "Would remove"
+	}
+	fmt.Printf("%s %d orphaned records: %d sink links, %d sink index entries, %d status changes, %d rollups, %d incident notes, %d planned outages\n",
+		verb, report.Total(), report.SourceSinks, report.SinkSources, report.StatusChanges, report.Rollups, report.IncidentNotes, report.PlannedOutages)
+	return 0
+}
+
+// dbtoolDedupe merges, or with -dry-run lists, sources with the same type and target
+func dbtoolDedupe(db *storage.BoltDB, args []string) int {
+	fs := flag.NewFlagSet("dedupe", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only list the duplicates")
+	fs.Parse(args)
+
+	groups, err := db.FindDuplicateSources()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	merged := 0
+	for _, group := range groups {
+		fmt.Printf("%s %s: keeping %s (%s)\n", group.Keep.Type, group.Keep.Target, group.Keep.Name, group.Keep.ID)
+		for _, duplicate := range group.Duplicates {
+			fmt.Printf("  duplicate %s (%s)\n", duplicate.Name, duplicate.ID)
+		}
+		if *dryRun {
+			continue
+		}
+		if err := db.MergeDuplicateSources(group); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		merged += len(group.Duplicates)
+	}
+
+	switch {
+	case len(groups) == 0:
+		fmt.Println("No duplicate sources")
+	case *dryRun:
+		fmt.Println("Dry run, nothing changed")
+	default:
+		fmt.Printf("✅ Merged %d duplicates; they are in trash and can be restored\n", merged)
+	}
+	return 0
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"tg-monitor-bot/internal/logging"
+)
+
+// leaderLockEnv names the lock file that elects the active instance. Like CONFIG_FILE it is
+// read from the environment only, since it must be held before the database is opened.
+const leaderLockEnv = "LEADER_LOCK_FILE"
+
+// leaderLockRetry is how often a standby instance tries to take over the lock
+const leaderLockRetry = 5 * time.Second
+
+// leaderLock is a held LEADER_LOCK_FILE
+type leaderLock struct {
+	file *os.File
+}
+
+// acquireLeaderLock blocks until this process holds an exclusive lock on path, or returns
+// nil when stop is closed first. The kernel releases the lock when the holder exits or
+// crashes, so a standby takes over within leaderLockRetry.
+func acquireLeaderLock(path string, stop <-chan os.Signal, logger *logging.Logger) (*leaderLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", leaderLockEnv, err)
+	}
+
+	waitingSince := time.Now()
+	for attempt := 0; ; attempt++ {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if attempt == 0 {
+			holder, _ := os.ReadFile(path)
+			if len(holder) == 0 {
+				holder = []byte("another process")
+			}
+			logger.Printf("⏸️  Standby: %s is held by %s, waiting to take over", path, holder)
+		}
+
+		select {
+		case <-stop:
+			file.Close()
+			return nil, nil
+		case <-time.After(leaderLockRetry):
+		}
+	}
+
+	// Record who holds the lock, for the standby's log
+	hostname, _ := os.Hostname()
+	file.Truncate(0)
+	file.WriteAt([]byte(fmt.Sprintf("%s (pid %d)", hostname, os.Getpid())), 0)
+
+	if waited := time.Since(waitingSince); waited >= leaderLockRetry {
+		logger.Printf("👑 Acquired %s after %v on standby, taking over", path, waited.Round(time.Second))
+	} else {
+		logger.Printf("👑 Acquired %s, running as the active instance", path)
+	}
+	return &leaderLock{file: file}, nil
+}
+
+// release unlocks the file so a standby can take over
+func (l *leaderLock) release() {
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tg-monitor-bot/internal/logging"
+)
+
+func TestAcquireLeaderLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	logger := logging.New("leader")
+
+	first, err := acquireLeaderLock(path, nil, logger)
+	if err != nil || first == nil {
+		t.Fatalf("Expected the first acquire to hold the lock, got %v", err)
+	}
+	if holder, _ := os.ReadFile(path); !strings.Contains(string(holder), fmt.Sprintf("(pid %d)", os.Getpid())) {
+		t.Errorf("Expected the holder recorded in the lock file, got %q", holder)
+	}
+
+	// flock is per open file, so a second acquire in the same process waits like a standby
+	// would; a pending stop signal ends the wait without the lock
+	stop := make(chan os.Signal, 1)
+	stop <- os.Interrupt
+	second, err := acquireLeaderLock(path, stop, logger)
+	if err != nil || second != nil {
+		t.Fatalf("Expected no lock while the first is held, got %v, %v", second, err)
+	}
+
+	first.release()
+	second, err = acquireLeaderLock(path, stop, logger)
+	if err != nil || second == nil {
+		t.Fatalf("Expected the lock after release, got %v", err)
+	}
+	second.release()
+}
@@ -1,47 +1,153 @@
 package main
 
 import (
-	"log"
+	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
+	"strings"
 
 	"tg-monitor-bot/internal/appmanager"
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/logging"
 	"tg-monitor-bot/internal/storage"
 )
 
 // Version is injected at build time via -ldflags "-X main.Version=x.y.z"
 var Version = "dev"
 
+// defaultDBPath is where serve keeps its database unless -db says otherwise
+const defaultDBPath = "data/state.db"
+
+const usage = `Usage: tg-monitor-bot [command] [flags]
+
+Commands:
+  serve [-replica]       Run the bot, monitor and API (default); -replica serves a copy read-only
+  check <type> <target>  Check a ping or http target once and exit 1 if it is offline
+  once                   Check all sources once, record the results and exit 1 if any is down
+  agent                  Check the sources assigned to a location and report to a central instance
+  export [-o file]       Write sources, sinks and non-secret config as JSON
+  import <file>          Read an export into the database ("-" for stdin); -format
+                         uptime-kuma or csv imports another tool's monitors
+  backup <path>          Write a consistent copy of the database
+  selftest               Check the database, Telegram token, HTTP, ICMP and webhooks
+  setup                  Interactively configure Telegram, the API key and a first source
+  genkey [-write]        Print a random API key; -write stores it as API_KEY if none is set
+  dbtool <command>       Inspect and repair the database ("dbtool -h" lists commands)
+  version                Print the version
+
+Commands that open the database take -db (default data/state.db) and need the
+service stopped, since the database can only be opened by one process.
+Run "tg-monitor-bot <command> -h" for a command's flags.
+`
+
 func main() {
-	log.Println("🤖 Starting Outage Monitor Bot with AppManager...")
+	os.Exit(run(os.Args[1:]))
+}
 
-	// Initialize database
-	db, err := storage.NewBoltDB("data/state.db")
-	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+// run dispatches to a subcommand and returns the exit code. Without a command, or with
+// only flags (e.g. the old --selftest), the bot is served as before subcommands existed.
+func run(args []string) int {
+	if err := logging.Setup(os.Stderr, os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
 	}
-	defer db.Close()
 
-	// Create AppManager
-	manager := appmanager.New(db, Version)
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help" {
+		return runServe(args)
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "serve":
+		return runServe(args)
+	case "check":
+		return runCheck(args)
+	case "once":
+		return runOnce(args)
+	case "agent":
+		return runAgent(args)
+	case "export":
+		return runExport(args)
+	case "import":
+		return runImport(args)
+	case "backup":
+		return runBackup(args)
+	case "selftest":
+		return runSelfTestCommand(args)
+	case "setup":
+		return runSetup(args)
+	case "genkey":
+		return runGenKey(args)
+	case "dbtool":
+		return runDBTool(args)
+	case "version":
+		fmt.Println(Version)
+		return 0
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		return 2
+	}
+}
+
+// openDB opens the database and enables encryption at rest if a master key is configured
+func openDB(path string) (*storage.BoltDB, error) {
+	db, err := storage.NewBoltDB(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return enableEncryption(db)
+}
 
-	// Start AppManager (ConfigManager + Echo API + Bot)
-	if err := manager.Start(); err != nil {
-		log.Fatalf("Failed to start AppManager: %v", err)
+// openReplicaDB opens an existing copy of the database read-only, with encryption if a
+// master key is configured
+func openReplicaDB(path string) (*storage.BoltDB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("database not found: %w", err)
+	}
+	db, err := storage.OpenReadOnly(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replica database: %w", err)
 	}
+	return enableEncryption(db)
+}
 
-	log.Println("✅ Application started successfully")
-	log.Println("📡 Bot is running and monitoring sources")
-	log.Println("🌐 API server is available for config management")
-	log.Println("Press Ctrl+C to stop")
+// enableEncryption enables encryption at rest on db if a master key is configured, and
+// closes db when that fails
+func enableEncryption(db *storage.BoltDB) (*storage.BoltDB, error) {
+	encryptionKey, err := config.EncryptionKey()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load encryption key: %w", err)
+	}
+	if encryptionKey != nil {
+		if err := db.EnableEncryption(encryptionKey); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to enable encryption: %w", err)
+		}
+	}
+	return db, nil
+}
 
-	// Wait for interrupt signal for graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+// openExistingDB is openDB for commands that read the database, so a mistyped -db path
+// fails instead of creating an empty database
+func openExistingDB(path string) (*storage.BoltDB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("database not found: %w", err)
+	}
+	return openDB(path)
+}
 
-	log.Println("🛑 Shutdown signal received...")
-	manager.Shutdown()
-	log.Println("✅ Shutdown complete")
+// loadConfig loads the config the way serve does: from the database, env and *_FILE secrets
+func loadConfig(db *storage.BoltDB) (*config.Config, error) {
+	configManager := appmanager.NewConfigManager(db)
+	if err := configManager.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg, err := configManager.AsConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return cfg, nil
 }
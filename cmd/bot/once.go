@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"tg-monitor-bot/internal/bot"
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/logging"
+	"tg-monitor-bot/internal/monitor"
+	"tg-monitor-bot/internal/notifier"
+	"tg-monitor-bot/internal/storage"
+)
+
+// onceConcurrency bounds the checks running at the same time, since a ping takes
+// PING_COUNT seconds and checking sources one by one would be slow
+const onceConcurrency = 8
+
+// onceResult is one line of `once -json` output
+type onceResult struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Target    string `json:"target"`
+	Online    bool   `json:"online"`
+	Changed   bool   `json:"changed"` // Status differs from the previous run
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// runOnce checks every enabled ping and HTTP source once, records the results like the
+// monitor does and exits 1 if any is offline, for cron jobs instead of a long-running daemon
+func runOnce(args []string) int {
+	return checkAllOnce(args, os.Stdout)
+}
+
+// checkAllOnce is runOnce printing the results to out. It returns 0 when every source is
+// online, 1 when any is offline and 2 when the run itself fails.
+func checkAllOnce(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("once", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "database file")
+	notify := fs.Bool("notify", false, "send Telegram and webhook notifications for status changes")
+	jsonOutput := fs.Bool("json", false, "print results as JSON")
+	fs.Parse(args)
+
+	db, err := openExistingDB(*dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	defer db.Close()
+
+	cfg, err := loadConfig(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	// Results are written as they come in; there is no flush loop in a one-shot run
+	cfg.CheckFlushInterval = 0
+
+	sources, err := db.GetAllSources()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load sources: %v\n", err)
+		return 2
+	}
+	// Webhook sources are skipped: their heartbeats only arrive while serve is running
+	var checked []*storage.Source
+	for _, source := range sources {
+		if source.Enabled && source.Type != "webhook" {
+			checked = append(checked, source)
+		}
+	}
+
+	mon := monitor.New(db, cfg, nil)
+	outcomes := make([]*monitor.CheckOutcome, len(checked))
+	sem := make(chan struct{}, onceConcurrency)
+	var wg sync.WaitGroup
+	for i, source := range checked {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			outcomes[i] = mon.CheckOnce(source)
+		}()
+	}
+	wg.Wait()
+
+	if *notify {
+		notifyChanges(cfg, db, mon, outcomes)
+	}
+
+	offline := 0
+	results := make([]onceResult, len(outcomes))
+	for i, outcome := range outcomes {
+		if outcome.Status != 1 {
+			offline++
+		}
+		results[i] = onceResult{
+			ID:        outcome.Source.ID,
+			Name:      outcome.Source.Name,
+			Type:      outcome.Source.Type,
+			Target:    outcome.Source.Target,
+			Online:    outcome.Status == 1,
+			Changed:   outcome.Change != nil,
+			Error:     outcome.Error,
+			LatencyMs: outcome.Latency.Milliseconds(),
+		}
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to write results: %v\n", err)
+			return 2
+		}
+	} else {
+		for _, result := range results {
+			icon, changed := "✅", ""
+			if !result.Online {
+				icon = "❌"
+			}
+			if result.Changed {
+				changed = " (changed)"
+			}
+			fmt.Fprintf(out, "%s %s [%s %s] %dms%s %s\n", icon, result.Name, result.Type, result.Target, result.LatencyMs, changed, result.Error)
+		}
+		fmt.Fprintf(out, "%d of %d sources online\n", len(results)-offline, len(results))
+	}
+
+	if offline > 0 {
+		return 1
+	}
+	return 0
+}
+
+// notifyChanges sends notifications for status changes and waits until they are delivered,
+// since the process exits right after. Telegram is skipped in web-only mode.
+func notifyChanges(cfg *config.Config, db *storage.BoltDB, mon *monitor.Monitor, outcomes []*monitor.CheckOutcome) {
+	dispatcher := notifier.NewDispatcher(db)
+	dispatcher.SetSender(storage.SinkTypeWebhook, notifier.NewWebhookNotifier(db))
+	telegram := cfg.TelegramToken != "" && cfg.TelegramToken != "your_bot_token_here"
+	for _, outcome := range outcomes {
+		if outcome.Change == nil {
+			continue
+		}
+		if telegram {
+			telegram = false
+			if telegramBot, err := bot.New(cfg, db, mon); err != nil {
+				logging.New("once").Warnf("⚠️  Telegram notifications skipped: %v", err)
+			} else {
+				dispatcher.SetSender(storage.SinkTypeTelegram, telegramBot)
+			}
+		}
+		dispatcher.OnStatusChange(context.Background(), outcome.Source, outcome.Change)
+	}
+	dispatcher.Wait()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// failingWriter stands in for a closed stdout
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+// newOnceDB creates a database holding sources and returns its path
+func newOnceDB(t *testing.T, sources ...*storage.Source) string {
+	path := filepath.Join(t.TempDir(), "state.db")
+	db, err := storage.NewBoltDB(path)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+	for _, source := range sources {
+		if err := db.SaveSource(source); err != nil {
+			t.Fatalf("SaveSource failed: %v", err)
+		}
+	}
+	return path
+}
+
+func TestCheckAllOnce(t *testing.T) {
+	// The config is seeded from the environment; a web-only setup needs nothing else
+	t.Setenv("API_ENABLED", "false")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	online := func(name string) *storage.Source {
+		return &storage.Source{Name: name, Type: "http", Target: server.URL + "/up", CheckInterval: time.Minute, Enabled: true, CurrentStatus: 1}
+	}
+	offline := &storage.Source{Name: "Down", Type: "http", Target: server.URL + "/down", CheckInterval: time.Minute, Enabled: true, CurrentStatus: 1}
+	// Neither is checked: webhooks only get heartbeats while serving, disabled sources never
+	webhook := &storage.Source{Name: "Heartbeat", Type: "webhook", CheckInterval: time.Minute, Enabled: true, CurrentStatus: 0}
+	disabled := &storage.Source{Name: "Disabled", Type: "http", Target: server.URL + "/down", CheckInterval: time.Minute, CurrentStatus: 1}
+
+	t.Run("all online", func(t *testing.T) {
+		path := newOnceDB(t, online("API"), online("Web"), webhook, disabled)
+		var out bytes.Buffer
+		if code := checkAllOnce([]string{"-db", path}, &out); code != 0 {
+			t.Errorf("Expected exit code 0, got %d: %s", code, out.String())
+		}
+		if !strings.Contains(out.String(), "2 of 2 sources online") {
+			t.Errorf("Expected a summary of 2 sources, got %q", out.String())
+		}
+	})
+
+	t.Run("one offline", func(t *testing.T) {
+		path := newOnceDB(t, online("API"), offline, webhook, disabled)
+		var out bytes.Buffer
+		if code := checkAllOnce([]string{"-db", path, "-json"}, &out); code != 1 {
+			t.Errorf("Expected exit code 1, got %d: %s", code, out.String())
+		}
+
+		var results []onceResult
+		if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+			t.Fatalf("Failed to decode the JSON output %q: %v", out.String(), err)
+		}
+		byName := make(map[string]onceResult)
+		for _, result := range results {
+			byName[result.Name] = result
+		}
+		if len(results) != 2 || !byName["API"].Online || byName["API"].Changed {
+			t.Errorf("Expected API online and unchanged, and only the two http sources checked, got %+v", results)
+		}
+		if down := byName["Down"]; down.Online || !down.Changed || !strings.Contains(down.Error, "503") || down.Type != "http" {
+			t.Errorf("Expected Down offline with the status in the error, got %+v", down)
+		}
+
+		// The results are recorded like the monitor does
+		db, err := storage.NewBoltDB(path)
+		if err != nil {
+			t.Fatalf("Failed to reopen database: %v", err)
+		}
+		defer db.Close()
+		if stored, _ := db.GetSource(byName["Down"].ID); stored.CurrentStatus != 0 || stored.LastCheckTime.IsZero() {
+			t.Errorf("Expected Down stored offline with a check time, got %+v", stored)
+		}
+	})
+
+	t.Run("setup errors", func(t *testing.T) {
+		var out bytes.Buffer
+		if code := checkAllOnce([]string{"-db", filepath.Join(t.TempDir(), "missing.db")}, &out); code != 2 {
+			t.Errorf("Expected exit code 2 for a missing database, got %d", code)
+		}
+
+		path := newOnceDB(t, online("API"))
+		if code := checkAllOnce([]string{"-db", path, "-json"}, failingWriter{}); code != 2 {
+			t.Errorf("Expected exit code 2 when the output can't be written, got %d", code)
+		}
+	})
+}
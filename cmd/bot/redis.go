@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"tg-monitor-bot/internal/coord"
+	"tg-monitor-bot/internal/logging"
+)
+
+// Redis coordination is read from the environment only, since the lease must be held
+// before the database is opened
+const (
+	redisURLEnv    = "REDIS_URL"
+	redisPrefixEnv = "REDIS_PREFIX"
+)
+
+// Leader lease timing: a crashed leader is replaced within redisLeaseTTL plus one retry
+const (
+	redisLeaseName  = "leader"
+	redisLeaseTTL   = 15 * time.Second
+	redisLeaseRetry = 5 * time.Second
+)
+
+// openCoordinator connects to REDIS_URL, or returns nil when it is not set
+func openCoordinator() (*coord.Client, error) {
+	url := os.Getenv(redisURLEnv)
+	if url == "" {
+		return nil, nil
+	}
+	prefix := os.Getenv(redisPrefixEnv)
+	if prefix == "" {
+		prefix = coord.DefaultPrefix
+	}
+	client, err := coord.Open(url, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", redisURLEnv, err)
+	}
+	return client, nil
+}
+
+// acquireRedisLease blocks until this process holds the leader lease, or returns nil when
+// stop is closed first. A leader that stops renewing loses the lease after redisLeaseTTL.
+func acquireRedisLease(client *coord.Client, stop <-chan os.Signal, logger *logging.Logger) (*coord.Lease, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s (pid %d)", hostname, os.Getpid())
+	key := client.Key(redisLeaseName)
+	waitingSince := time.Now()
+	logged := false
+
+	lease, err := client.AcquireLease(ctx, redisLeaseName, owner, redisLeaseTTL, redisLeaseRetry, func(holder string) {
+		if !logged {
+			logger.Printf("⏸️  Standby: Redis lease %s is held by %s, waiting to take over", key, holder)
+			logged = true
+		}
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if waited := time.Since(waitingSince); waited >= redisLeaseRetry {
+		logger.Printf("👑 Acquired Redis lease %s after %v on standby, taking over", key, waited.Round(time.Second))
+	} else {
+		logger.Printf("👑 Acquired Redis lease %s, running as the active instance", key)
+	}
+	return lease, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"tg-monitor-bot/internal/appmanager"
+	"tg-monitor-bot/internal/logging"
+	"tg-monitor-bot/internal/storage"
+)
+
+// runSelfTestCommand runs the self-test against the database given by -db
+func runSelfTestCommand(args []string) int {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "database file")
+	fs.Parse(args)
+
+	return selfTestDB(*dbPath)
+}
+
+// selfTestDB opens the database and runs the self-test with its stored config
+func selfTestDB(path string) int {
+	db, err := openDB(path)
+	if err != nil {
+		logging.New("selftest").Errorf("❌ %v", err)
+		return 1
+	}
+	defer db.Close()
+
+	return runSelfTest(db)
+}
+
+// runSelfTest prints a self-test report for the stored config and returns the exit code
+func runSelfTest(db *storage.BoltDB) int {
+	cfg, err := loadConfig(db)
+	if err != nil {
+		logging.New("selftest").Errorf("❌ %v", err)
+		return 1
+	}
+
+	report := appmanager.RunSelfTest(context.Background(), db, cfg)
+
+	icons := map[string]string{
+		appmanager.SelfTestPass: "✅",
+		appmanager.SelfTestFail: "❌",
+		appmanager.SelfTestSkip: "➖",
+	}
+	for _, check := range report.Checks {
+		fmt.Printf("%s %-9s %s (%dms)\n", icons[check.Status], check.Name, check.Detail, check.DurationMs)
+	}
+
+	if !report.Passed {
+		fmt.Println("Self-test failed")
+		return 1
+	}
+	fmt.Println("Self-test passed")
+	return 0
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"tg-monitor-bot/internal/appmanager"
+	"tg-monitor-bot/internal/coord"
+	"tg-monitor-bot/internal/logging"
+)
+
+// runServe runs the bot, monitor and API until SIGINT or SIGTERM
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "database file")
+	selfTest := fs.Bool("selftest", false, "run the self-test and exit (same as the selftest command)")
+	replica := fs.Bool("replica", false, "serve the API and status page read-only from a copy of the database, without checks or the Telegram bot")
+	fs.Parse(args)
+
+	if *selfTest {
+		return selfTestDB(*dbPath)
+	}
+
+	logger := logging.New("main")
+	logger.Println("🤖 Starting Outage Monitor Bot with AppManager...")
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	coordinator, err := openCoordinator()
+	if err != nil {
+		logger.Errorf("❌ %v", err)
+		return 1
+	}
+	if coordinator != nil {
+		defer coordinator.Close()
+	}
+
+	if *replica {
+		return serveReplica(*dbPath, coordinator, quit, logger)
+	}
+
+	// Active/standby: only the holder of the lock opens the database and runs
+	if path := os.Getenv(leaderLockEnv); path != "" {
+		lock, err := acquireLeaderLock(path, quit, logger)
+		if err != nil {
+			logger.Errorf("❌ %v", err)
+			return 1
+		}
+		if lock == nil {
+			logger.Println("🛑 Shutdown signal received on standby")
+			return 0
+		}
+		defer lock.release()
+	}
+
+	// With Redis the lease elects the active instance instead, without a shared volume
+	var lost <-chan struct{}
+	if coordinator != nil && os.Getenv(leaderLockEnv) == "" {
+		lease, err := acquireRedisLease(coordinator, quit, logger)
+		if err != nil {
+			logger.Errorf("❌ %v", err)
+			return 1
+		}
+		if lease == nil {
+			logger.Println("🛑 Shutdown signal received on standby")
+			return 0
+		}
+		defer lease.Release()
+		lost = lease.Lost()
+	}
+
+	db, err := openDB(*dbPath)
+	if err != nil {
+		logger.Errorf("❌ %v", err)
+		return 1
+	}
+	defer db.Close()
+
+	// Create AppManager
+	manager := appmanager.New(db, Version)
+	if coordinator != nil {
+		manager.SetCoordinator(coordinator)
+	}
+
+	// Start AppManager (ConfigManager + Echo API + Bot)
+	if err := manager.Start(); err != nil {
+		logger.Errorf("❌ Failed to start AppManager: %v", err)
+		return 1
+	}
+
+	logger.Println("✅ Application started successfully")
+	logger.Println("📡 Bot is running and monitoring sources")
+	logger.Println("🌐 API server is available for config management")
+	logger.Println("Press Ctrl+C to stop")
+
+	// Wait for interrupt signal for graceful shutdown. Losing the lease means another
+	// instance may already be active, so this one stops and exits with an error for its
+	// supervisor to restart it as a standby.
+	select {
+	case <-quit:
+		logger.Println("🛑 Shutdown signal received...")
+	case <-lost:
+		logger.Errorf("❌ Lost the Redis leader lease, stopping")
+		manager.Shutdown()
+		return 1
+	}
+	manager.Shutdown()
+	logger.Println("✅ Shutdown complete")
+	return 0
+}
+
+// serveReplica serves a read-only copy of the database until quit. The copy is reopened
+// when a newer one replaces the file, e.g. from a periodic backup. With a coordinator, live
+// status changes come from the leader through Redis.
+func serveReplica(dbPath string, coordinator *coord.Client, quit <-chan os.Signal, logger *logging.Logger) int {
+	db, err := openReplicaDB(dbPath)
+	if err != nil {
+		logger.Errorf("❌ %v", err)
+		return 1
+	}
+	defer db.Close()
+
+	manager := appmanager.New(db, Version)
+	if coordinator != nil {
+		manager.SetCoordinator(coordinator)
+	}
+	if err := manager.Start(); err != nil {
+		logger.Errorf("❌ Failed to start AppManager: %v", err)
+		return 1
+	}
+
+	logger.Println("✅ Read-only replica started")
+	logger.Println("🌐 API server and status page serve the copy at " + dbPath)
+	logger.Println("Press Ctrl+C to stop")
+
+	<-quit
+
+	logger.Println("🛑 Shutdown signal received...")
+	manager.Shutdown()
+	logger.Println("✅ Shutdown complete")
+	return 0
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"tg-monitor-bot/internal/appmanager"
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/logging"
+	"tg-monitor-bot/internal/monitor"
+	"tg-monitor-bot/internal/storage"
+)
+
+// minAPIKeyLength rejects short hand-made keys in setup; generated keys are much longer
+const minAPIKeyLength = 16
+
+// errInputClosed ends the wizard when stdin runs out, e.g. Ctrl+D
+var errInputClosed = errors.New("input closed")
+
+// wizard reads answers line by line, so setup also works with piped input
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask prints prompt and returns the trimmed answer, or def when the answer is empty
+func (w *wizard) ask(prompt, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", prompt, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", prompt)
+	}
+	line, err := w.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Fprintln(w.out)
+		return "", errInputClosed
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// confirm asks a yes/no question
+func (w *wizard) confirm(prompt string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	answer, err := w.ask(prompt+" ("+hint+")", "")
+	if err != nil {
+		return false, err
+	}
+	if answer == "" {
+		return def, nil
+	}
+	return strings.HasPrefix(strings.ToLower(answer), "y"), nil
+}
+
+// runSetup interactively writes the Telegram token, allowed users, API key and a first source
+// into the database, validating each, so a first run needs no hand-written .env
+func runSetup(args []string) int {
+	return setup(args, os.Stdin, os.Stdout)
+}
+
+// setup is runSetup reading the answers from in and writing the prompts to out
+func setup(args []string, in io.Reader, out io.Writer) int {
+	fs := flag.NewFlagSet("setup", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "database file")
+	fs.Parse(args)
+
+	// Component logs would interleave with the prompts; failures are reported as errors
+	logging.Setup(io.Discard, "", "")
+
+	if err := os.MkdirAll(filepath.Dir(*dbPath), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	db, err := openDB(*dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	configManager := appmanager.NewConfigManager(db)
+	if err := configManager.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load config: %v\n", err)
+		return 1
+	}
+
+	w := &wizard{in: bufio.NewReader(in), out: out}
+	fmt.Fprintf(w.out, "Outage Monitor setup, writing to %s. Press Enter to keep the value in brackets.\n\n", *dbPath)
+
+	if err := w.run(db, configManager); err != nil {
+		if errors.Is(err, errInputClosed) {
+			fmt.Fprintln(os.Stderr, "Setup cancelled, nothing was saved")
+		} else {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// run asks all questions, then saves everything at the end, so an aborted run changes nothing
+func (w *wizard) run(db *storage.BoltDB, configManager *appmanager.ConfigManager) error {
+	values := make(map[string]string)
+
+	token, err := w.askTelegramToken(configManager.Get("TELEGRAM_TOKEN"))
+	if err != nil {
+		return err
+	}
+	values["TELEGRAM_TOKEN"] = token
+
+	var allowedUsers []int64
+	if token != "" {
+		if allowedUsers, err = w.askAllowedUsers(configManager.Get("ALLOWED_USERS")); err != nil {
+			return err
+		}
+		values["ALLOWED_USERS"] = config.JoinIDs(allowedUsers)
+	}
+
+	apiKey, err := w.askAPIKey(configManager.Get("API_KEY") != "")
+	if err != nil {
+		return err
+	}
+	if apiKey != "" {
+		values["API_KEY"] = apiKey
+	}
+
+	source, notify, err := w.askSource(allowedUsers)
+	if err != nil {
+		return err
+	}
+
+	// Keys given as *_FILE secrets can't be written; keep their file values
+	for key := range values {
+		if configManager.FilePath(key) != "" {
+			fmt.Fprintf(w.out, "⚠️  %s is read from %s and was not changed\n", key, configManager.FilePath(key))
+			delete(values, key)
+		}
+	}
+	if err := configManager.SetMany(values, "setup"); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if _, err := configManager.AsConfig(); err != nil {
+		return fmt.Errorf("saved config is invalid: %w", err)
+	}
+
+	if source != nil {
+		if err := db.SaveSource(source); err != nil {
+			return fmt.Errorf("failed to save source: %w", err)
+		}
+		if notify != 0 {
+			if err := db.AddSourceChat(source.ID, notify); err != nil {
+				return fmt.Errorf("failed to link source to chat: %w", err)
+			}
+		}
+	}
+
+	fmt.Fprintln(w.out, "\n✅ Setup saved.")
+	if apiKey != "" {
+		fmt.Fprintf(w.out, "   API key: %s\n   It is stored hashed and only shown now, keep it somewhere safe.\n", apiKey)
+	}
+	fmt.Fprintln(w.out, "   Start the monitor with: tg-monitor-bot serve")
+	return nil
+}
+
+// askTelegramToken asks for a bot token until getMe accepts it; empty means web-only mode
+func (w *wizard) askTelegramToken(current string) (string, error) {
+	def := ""
+	if current != "" && current != "your_bot_token_here" {
+		def = "keep current"
+	}
+	for {
+		answer, err := w.ask("Telegram bot token from @BotFather (Enter for web-only mode)", def)
+		if err != nil {
+			return "", err
+		}
+		switch answer {
+		case "":
+			return "", nil
+		case "keep current":
+			return current, nil
+		}
+
+		username, err := appmanager.CheckTelegramToken(context.Background(), answer, 10*time.Second)
+		if err != nil {
+			fmt.Fprintf(w.out, "❌ Token rejected: %v\n", err)
+			continue
+		}
+		fmt.Fprintf(w.out, "✅ Connected to bot @%s\n", username)
+		return answer, nil
+	}
+}
+
+// askAllowedUsers asks for the Telegram user IDs allowed to use the bot
+func (w *wizard) askAllowedUsers(current string) ([]int64, error) {
+	fmt.Fprintln(w.out, "Only these users can use the bot; leave empty to allow anyone (message @userinfobot for your ID).")
+	for {
+		answer, err := w.ask("Allowed Telegram user IDs, comma separated", current)
+		if err != nil {
+			return nil, err
+		}
+		ids, err := parseIDs(answer)
+		if err != nil {
+			fmt.Fprintf(w.out, "❌ %v\n", err)
+			continue
+		}
+		return ids, nil
+	}
+}
+
+// askAPIKey returns a typed or generated key, or "" to keep the stored one
+func (w *wizard) askAPIKey(hasKey bool) (string, error) {
+	prompt, def := "API key for the dashboard and API (Enter to generate one)", "generate"
+	if hasKey {
+		prompt, def = "API key for the dashboard and API (\"generate\" for a new one)", "keep current"
+	}
+	for {
+		answer, err := w.ask(prompt, def)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case answer == "keep current":
+			return "", nil
+		case answer == "generate":
+			return storage.GenerateAPIKeySecret()
+		case len(answer) < minAPIKeyLength:
+			fmt.Fprintf(w.out, "❌ Use at least %d characters, or \"generate\"\n", minAPIKeyLength)
+		default:
+			return answer, nil
+		}
+	}
+}
+
+// askSource asks for an optional first source and test-checks it. It returns the source and
+// the chat to notify (0 for none).
+func (w *wizard) askSource(allowedUsers []int64) (*storage.Source, int64, error) {
+	add, err := w.confirm("\nAdd a first source to monitor?", true)
+	if err != nil || !add {
+		return nil, 0, err
+	}
+
+	// Unknown status until the monitor's first check, as for sources created via the API
+	source := &storage.Source{Enabled: true, CheckInterval: 30 * time.Second, CurrentStatus: -1}
+	for source.Type == "" {
+		answer, err := w.ask("Type, ping or http", "http")
+		if err != nil {
+			return nil, 0, err
+		}
+		if answer != "ping" && answer != "http" {
+			fmt.Fprintln(w.out, "❌ Type must be ping or http")
+			continue
+		}
+		source.Type = answer
+	}
+
+	example := "https://example.com"
+	if source.Type == "ping" {
+		example = "192.168.1.1"
+	}
+	for {
+		if source.Target, err = w.ask("Target, e.g. "+example, ""); err != nil {
+			return nil, 0, err
+		}
+		if source.Target == "" {
+			continue
+		}
+
+		cfg, _ := config.LoadFromMap(map[string]string{"API_ENABLED": "false"})
+		fmt.Fprintln(w.out, "Checking...")
+		status, reason := monitor.New(nil, cfg, nil).Probe(source)
+		if status == 1 {
+			fmt.Fprintln(w.out, "✅ Online")
+			break
+		}
+		fmt.Fprintf(w.out, "❌ Offline: %s\n", reason)
+		keep, err := w.confirm("Save it anyway?", false)
+		if err != nil {
+			return nil, 0, err
+		}
+		if keep {
+			break
+		}
+	}
+
+	if source.Name, err = w.ask("Name", source.Target); err != nil {
+		return nil, 0, err
+	}
+	for {
+		answer, err := w.ask("Check interval", source.CheckInterval.String())
+		if err != nil {
+			return nil, 0, err
+		}
+		interval, err := time.ParseDuration(answer)
+		if err != nil || interval < time.Second {
+			fmt.Fprintln(w.out, "❌ Use a duration of at least 1s, e.g. 30s or 5m")
+			continue
+		}
+		source.CheckInterval = interval
+		break
+	}
+
+	// A private chat has the same ID as the user
+	if len(allowedUsers) == 0 {
+		return source, 0, nil
+	}
+	notify, err := w.confirm(fmt.Sprintf("Send its notifications to user %d on Telegram?", allowedUsers[0]), true)
+	if err != nil || !notify {
+		return source, 0, err
+	}
+	return source, allowedUsers[0], nil
+}
+
+// parseIDs parses a comma-separated list of Telegram IDs
+func parseIDs(list string) ([]int64, error) {
+	var ids []int64
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a numeric user ID", field)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tg-monitor-bot/internal/appmanager"
+	"tg-monitor-bot/internal/storage"
+)
+
+func TestSetupWizard(t *testing.T) {
+	t.Setenv("TELEGRAM_TOKEN", "")
+	t.Setenv("API_KEY", "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "data", "state.db")
+
+	// Web-only, a typed key after one that is too short, then an http source after a bad type
+	// and interval
+	answers := strings.Join([]string{
+		"",                          // Telegram token: web-only mode
+		"short",                     // Rejected API key
+		"a-long-enough-api-key-123", // API key
+		"y",                         // Add a source
+		"smtp",                      // Rejected type
+		"http",                      // Type
+		server.URL,                  // Target, checked online
+		"",                          // Name: the target
+		"1ms",                       // Rejected interval
+		"2m",                        // Interval
+	}, "\n") + "\n"
+	var out bytes.Buffer
+	if code := setup([]string{"-db", path}, strings.NewReader(answers), &out); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, out.String())
+	}
+	for _, want := range []string{"❌ Use at least 16 characters", "❌ Type must be ping or http", "✅ Online",
+		"❌ Use a duration of at least 1s", "✅ Setup saved.", "API key: a-long-enough-api-key-123"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the output to contain %q, got %s", want, out.String())
+		}
+	}
+
+	db, err := storage.NewBoltDB(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	configManager := appmanager.NewConfigManager(db)
+	if err := configManager.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if got := configManager.Get("API_KEY"); storage.ConfigAPIKeyHash(got) != storage.HashAPIKeySecret("a-long-enough-api-key-123") {
+		t.Errorf("Expected the typed API key stored hashed, got %q", got)
+	}
+	if got := configManager.Get("TELEGRAM_TOKEN"); got != "" {
+		t.Errorf("Expected no Telegram token in web-only mode, got %q", got)
+	}
+	sources, _ := db.GetAllSources()
+	if len(sources) != 1 || sources[0].Name != server.URL || sources[0].Type != "http" || sources[0].CheckInterval != 2*time.Minute {
+		t.Errorf("Expected the http source named after its target with a 2m interval, got %+v", sources)
+	}
+	db.Close()
+
+	// Input ending early, like Ctrl+D, cancels without saving anything
+	out.Reset()
+	if code := setup([]string{"-db", path}, strings.NewReader("\ngenerate\n"), &out); code != 1 {
+		t.Fatalf("Expected exit code 1 for input closed early, got %d", code)
+	}
+	if db, err = storage.NewBoltDB(path); err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	configManager = appmanager.NewConfigManager(db)
+	configManager.Load()
+	if got := configManager.Get("API_KEY"); storage.ConfigAPIKeyHash(got) != storage.HashAPIKeySecret("a-long-enough-api-key-123") {
+		t.Errorf("Expected a cancelled run to keep the API key, got %q", got)
+	}
+	if sources, _ := db.GetAllSources(); len(sources) != 1 {
+		t.Errorf("Expected a cancelled run to add no source, got %d", len(sources))
+	}
+}
@@ -11,6 +11,7 @@ import { SourcesPanel } from './components/dashboard/SourcesPanel'
 import { TabNavigation, type TabId } from './components/dashboard/TabNavigation'
 import { SinksPanel } from './components/dashboard/SinksPanel'
 import { EventsPanel } from './components/dashboard/EventsPanel'
+import { UptimeOverview } from './components/dashboard/UptimeOverview'
 import { ToastContainer, type ToastMessage } from './components/dashboard/Toast'
 import type {
   HealthResponse,
@@ -94,6 +95,12 @@ function App() {
   }
 
   const handleConfigUpdate = async (key: string, value: string) => {
+    // Validate first: applying a config change reloads the bot
+    const validation = await api.validateConfig({ [key]: value })
+    if (!validation.valid) {
+      const errors = validation.problems.filter((p) => p.severity === 'error')
+      throw new Error(errors.map((p) => (p.key ? `${p.key}: ${p.message}` : p.message)).join('; '))
+    }
     await api.updateConfig(key, value)
     // Reload data after a short delay to show new config
     setTimeout(loadData, 1000)
@@ -155,6 +162,13 @@ function App() {
     setSources(sourcesData)
   }
 
+  const handleRotateWebhookToken = async (id: string) => {
+    await api.rotateWebhookToken(id)
+    // Reload sources immediately
+    const sourcesData = await api.getSources()
+    setSources(sourcesData)
+  }
+
   const formatUptime = (seconds: number): string => {
     const days = Math.floor(seconds / 86400)
     const hours = Math.floor((seconds % 86400) / 3600)
@@ -290,6 +304,9 @@ function App() {
               />
             </div>
 
+            {/* Uptime of every source */}
+            <UptimeOverview />
+
             {/* Two Column Layout */}
             <div className="grid grid-cols-1 lg:grid-cols-3 gap-6">
               {/* Configuration Panel (2 columns) */}
@@ -372,6 +389,7 @@ function App() {
             onDeleteSource={handleDeleteSource}
             onPauseSource={handlePauseSource}
             onResumeSource={handleResumeSource}
+            onRotateWebhookToken={handleRotateWebhookToken}
             isLoading={loading}
           />
         )}
@@ -11,27 +11,32 @@ export function ConfigPanel({ config, onUpdate, isLoading }: ConfigPanelProps) {
   const [editingKey, setEditingKey] = useState<string | null>(null)
   const [editValue, setEditValue] = useState('')
   const [submitting, setSubmitting] = useState(false)
+  const [saveError, setSaveError] = useState<string | null>(null)
 
   const handleEdit = (key: string, currentValue: string) => {
     setEditingKey(key)
     setEditValue(currentValue)
+    setSaveError(null)
   }
 
   const handleCancel = () => {
     setEditingKey(null)
     setEditValue('')
+    setSaveError(null)
   }
 
   const handleSave = async () => {
     if (!editingKey) return
 
     setSubmitting(true)
+    setSaveError(null)
     try {
       await onUpdate(editingKey, editValue)
       setEditingKey(null)
       setEditValue('')
     } catch (error) {
       console.error('Failed to update config:', error)
+      setSaveError(error instanceof Error ? error.message : 'Failed to update config')
     } finally {
       setSubmitting(false)
     }
@@ -77,6 +82,9 @@ export function ConfigPanel({ config, onUpdate, isLoading }: ConfigPanelProps) {
               ) : (
                 <p className="mt-1 text-sm text-gray-500 dark:text-gray-400 truncate">{value}</p>
               )}
+              {editingKey === key && saveError && (
+                <p className="mt-1 text-xs text-error-600 dark:text-error-400">{saveError}</p>
+              )}
             </div>
             <div className="flex items-center gap-2">
               {editingKey === key ? (
@@ -133,11 +133,11 @@ export function SinksPanel({ onToast }: SinksPanelProps) {
   const handleTestWebhook = async (webhookId: string, url: string) => {
     try {
       setError(null)
-      await api.testWebhook(webhookId)
+      const result = await api.testWebhook(webhookId)
       onToast({
         type: 'success',
         title: 'Test webhook sent!',
-        message: `Test payload successfully sent to ${url}`,
+        message: `${url} responded with HTTP ${result.status_code} in ${result.latency_ms} ms`,
       })
     } catch (err) {
       const errorMessage = err instanceof Error ? err.message : 'Failed to send test notification'
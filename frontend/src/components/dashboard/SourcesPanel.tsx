@@ -13,6 +13,7 @@ interface SourcesPanelProps {
   onDeleteSource: (id: string) => Promise<void>
   onPauseSource: (id: string) => Promise<void>
   onResumeSource: (id: string) => Promise<void>
+  onRotateWebhookToken: (id: string) => Promise<void>
   isLoading?: boolean
 }
 
@@ -35,6 +36,7 @@ export function SourcesPanel({
   onDeleteSource,
   onPauseSource,
   onResumeSource,
+  onRotateWebhookToken,
   isLoading,
 }: SourcesPanelProps) {
   const [showCreateForm, setShowCreateForm] = useState(false)
@@ -174,6 +176,19 @@ export function SourcesPanel({
     }
   }
 
+  const handleRotateWebhookToken = async (id: string, name: string) => {
+    if (!confirm(`Generate a new heartbeat URL for "${name}"? The current URL will stop working.`)) return
+
+    setSubmitting(true)
+    try {
+      await onRotateWebhookToken(id)
+    } catch (error) {
+      console.error('Failed to rotate webhook token:', error)
+    } finally {
+      setSubmitting(false)
+    }
+  }
+
   const formatDuration = (ns: number): string => {
     const seconds = ns / 1_000_000_000
     if (seconds < 60) return `${seconds}s`
@@ -441,6 +456,16 @@ export function SourcesPanel({
                 >
                   {source.enabled ? 'Pause' : 'Resume'}
                 </button>
+                {source.type === 'webhook' && (
+                  <button
+                    onClick={() => handleRotateWebhookToken(source.id, source.name)}
+                    disabled={submitting}
+                    className="px-3 py-2 text-xs font-medium text-gray-700 dark:text-gray-300 hover:bg-gray-50 dark:hover:bg-gray-700 rounded-md"
+                    title="Generate a new heartbeat URL and invalidate the current one"
+                  >
+                    Rotate URL
+                  </button>
+                )}
                 <button
                   onClick={() => handleEdit(source)}
                   disabled={submitting}
@@ -0,0 +1,101 @@
+import { useState, useEffect, useCallback } from 'react'
+import { api } from '../../lib/api'
+import type { UptimeSummary } from '../../types'
+
+const PERIODS = ['24h', '7d', '30d', '90d']
+
+export function UptimeOverview() {
+  const [period, setPeriod] = useState('7d')
+  const [summary, setSummary] = useState<UptimeSummary | null>(null)
+  const [error, setError] = useState<string | null>(null)
+
+  const loadUptime = useCallback(async () => {
+    try {
+      setError(null)
+      setSummary(await api.getUptime(period))
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to load uptime')
+    }
+  }, [period])
+
+  useEffect(() => {
+    if (api.getApiKey()) {
+      loadUptime()
+      // Refresh every minute; uptime moves slowly
+      const interval = setInterval(loadUptime, 60000)
+      return () => clearInterval(interval)
+    }
+  }, [loadUptime])
+
+  const formatPercent = (uptime: number | null): string =>
+    uptime === null ? '—' : `${uptime.toFixed(2)}%`
+
+  const formatDowntime = (ms: number): string => {
+    const minutes = Math.floor(ms / 60000)
+    const hours = Math.floor(minutes / 60)
+    if (hours >= 24) return `${Math.floor(hours / 24)}d ${hours % 24}h`
+    if (hours > 0) return `${hours}h ${minutes % 60}m`
+    return `${minutes}m`
+  }
+
+  return (
+    <div className="bg-white dark:bg-gray-800 rounded-lg border border-gray-200 dark:border-gray-700 p-6 shadow-sm">
+      <div className="flex items-center justify-between mb-4">
+        <h3 className="text-lg font-semibold text-gray-900 dark:text-gray-100 flex items-center gap-2">
+          <span className="text-xl">📈</span>
+          Uptime
+          {summary && (
+            <span className="text-sm font-normal text-gray-500 dark:text-gray-400">
+              {formatPercent(summary.uptime_percent)} overall, {summary.outage_count} outage(s)
+            </span>
+          )}
+        </h3>
+        <select
+          value={period}
+          onChange={(e) => setPeriod(e.target.value)}
+          className="text-sm rounded-md border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100 px-2 py-1"
+        >
+          {PERIODS.map((p) => (
+            <option key={p} value={p}>
+              Last {p}
+            </option>
+          ))}
+        </select>
+      </div>
+
+      {error && <p className="text-sm text-error-600">{error}</p>}
+
+      {summary && summary.sources.length === 0 && (
+        <p className="text-sm text-gray-500 dark:text-gray-400">No sources to monitor</p>
+      )}
+
+      {summary && summary.sources.length > 0 && (
+        <table className="w-full text-sm">
+          <thead>
+            <tr className="text-left text-gray-500 dark:text-gray-400 border-b border-gray-200 dark:border-gray-700">
+              <th className="py-2">Source</th>
+              <th className="py-2 text-right">Uptime</th>
+              <th className="py-2 text-right">Outages</th>
+              <th className="py-2 text-right">Downtime</th>
+            </tr>
+          </thead>
+          <tbody>
+            {summary.sources.map((row) => (
+              <tr key={row.source_id} className="border-b border-gray-100 dark:border-gray-700 text-gray-900 dark:text-gray-100">
+                <td className="py-2">
+                  {!row.enabled ? '⏸️' : row.current_status === 1 ? '🟢' : '🔴'} {row.source_name}
+                </td>
+                <td className="py-2 text-right font-mono">{formatPercent(row.uptime_percent)}</td>
+                <td className="py-2 text-right">{row.outage_count}</td>
+                <td className="py-2 text-right">
+                  {formatDowntime(row.downtime_ms)}
+                  {row.ongoing && <span className="ml-1 text-error-600">(ongoing)</span>}
+                </td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
+    </div>
+  )
+}
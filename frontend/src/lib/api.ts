@@ -5,6 +5,7 @@ import type {
   ConfigEntry,
   UpdateConfigRequest,
   UpdateConfigResponse,
+  ConfigValidationResponse,
   ReloadResponse,
   Source,
   CreateSourceRequest,
@@ -14,9 +15,13 @@ import type {
   UpdateWebhookRequest,
   TelegramChat,
   StatusChangeEvent,
+  UptimeSummary,
+  WebhookTestResult,
 } from '../types'
 
-const API_BASE = '/api'
+// Versioned JSON API; public endpoints such as /health are not versioned
+const API_BASE = '/api/v1'
+const PUBLIC_BASE = '/api'
 
 class ApiClient {
   private apiKey: string = ''
@@ -44,7 +49,8 @@ class ApiClient {
 
   private async request<T>(
     endpoint: string,
-    options: RequestInit = {}
+    options: RequestInit = {},
+    base: string = API_BASE
   ): Promise<T> {
     const headers: Record<string, string> = {
       'Content-Type': 'application/json',
@@ -56,16 +62,18 @@ class ApiClient {
       headers['X-API-Key'] = this.getApiKey()
     }
 
-    const response = await fetch(`${API_BASE}${endpoint}`, {
+    const response = await fetch(`${base}${endpoint}`, {
       ...options,
       headers,
     })
 
     if (!response.ok) {
-      const error = await response.json().catch(() => ({
+      const body = await response.json().catch(() => ({
         error: response.statusText,
       }))
-      throw new Error(error.error || `HTTP ${response.status}`)
+      // Errors use {"error": {"code", "message"}}; webhook test failures report a plain string
+      const message = typeof body.error === 'string' ? body.error : body.error?.message
+      throw new Error(message || `HTTP ${response.status}`)
     }
 
     return response.json()
@@ -73,7 +81,7 @@ class ApiClient {
 
   // Health endpoint (no auth required)
   async getHealth(): Promise<HealthResponse> {
-    return this.request<HealthResponse>('/health')
+    return this.request<HealthResponse>('/health', {}, PUBLIC_BASE)
   }
 
   // Status endpoint (requires auth)
@@ -100,6 +108,15 @@ class ApiClient {
     })
   }
 
+  async validateConfig(
+    values: Record<string, string>
+  ): Promise<ConfigValidationResponse> {
+    return this.request<ConfigValidationResponse>('/config/validate', {
+      method: 'POST',
+      body: JSON.stringify(values),
+    })
+  }
+
   async reloadBot(): Promise<ReloadResponse> {
     return this.request<ReloadResponse>('/config/reload', {
       method: 'POST',
@@ -145,6 +162,12 @@ class ApiClient {
     )
   }
 
+  async rotateWebhookToken(id: string): Promise<Source> {
+    return this.request<Source>(`/sources/${id}/webhook-token/rotate`, {
+      method: 'POST',
+    })
+  }
+
   // Webhook endpoints (require auth)
   async getWebhooks(): Promise<Webhook[]> {
     return this.request<Webhook[]>('/webhooks')
@@ -204,6 +227,11 @@ class ApiClient {
     return this.request<StatusChangeEvent[]>(endpoint)
   }
 
+  // Uptime of all sources over a period such as 7d or 24h (requires auth)
+  async getUptime(period = '7d'): Promise<UptimeSummary> {
+    return this.request<UptimeSummary>(`/uptime?period=${encodeURIComponent(period)}`)
+  }
+
   // Source-Webhook associations (require auth)
   async getSourceWebhooks(sourceId: string): Promise<Webhook[]> {
     return this.request<Webhook[]>(`/sources/${sourceId}/webhooks`)
@@ -264,10 +292,8 @@ class ApiClient {
     )
   }
 
-  async testWebhook(
-    webhookId: string
-  ): Promise<{ message: string; webhook_id: string; url: string; sent_at: string }> {
-    return this.request<{ message: string; webhook_id: string; url: string; sent_at: string }>(
+  async testWebhook(webhookId: string): Promise<WebhookTestResult> {
+    return this.request<WebhookTestResult>(
       `/test/webhook/${webhookId}`,
       { method: 'POST' }
     )
@@ -82,6 +82,18 @@ export interface UpdateConfigResponse {
   restarting: string
 }
 
+export interface ConfigProblem {
+  key?: string
+  severity: 'error' | 'warning'
+  message: string
+}
+
+export interface ConfigValidationResponse {
+  valid: boolean
+  problems: ConfigProblem[]
+  reload?: string
+}
+
 export interface ReloadResponse {
   message: string
 }
@@ -101,6 +113,7 @@ export interface Source {
   grace_period_multiplier?: number
   expected_headers?: string
   expected_content?: string
+  managed_by?: 'file' // Declared in CONFIG_FILE; edits are overwritten on the next file change
 }
 
 export interface CreateSourceRequest {
@@ -168,8 +181,40 @@ export interface StatusChangeEvent {
   timestamp: string // ISO datetime
 }
 
+export interface SourceUptime {
+  source_id: string
+  source_name: string
+  current_status: number
+  enabled: boolean
+  uptime_percent: number | null // null when the status was never known in the period
+  outage_count: number
+  downtime_ms: number
+  ongoing: boolean
+}
+
+export interface UptimeSummary {
+  from: string // ISO datetime
+  to: string
+  uptime_percent: number | null // Weighted by each source's monitored time
+  outage_count: number
+  downtime_ms: number
+  sources: SourceUptime[] // By name
+}
+
 export interface SinkAssociation {
   source_id: string
   sink_id: string
   sink_type: 'telegram' | 'webhook'
 }
+
+export interface WebhookTestResult {
+  message?: string
+  webhook_id: string
+  url: string
+  success: boolean
+  status_code?: number
+  latency_ms: number
+  response_body?: string
+  error?: string
+  sent_at: string
+}
@@ -12,6 +12,11 @@ export default defineConfig({
   },
   server: {
     proxy: {
+      // Versioned API paths are served as-is; other /api paths map to the backend root
+      '/api/v1': {
+        target: 'http://localhost:8080',
+        changeOrigin: true,
+      },
       '/api': {
         target: 'http://localhost:8080',
         changeOrigin: true,
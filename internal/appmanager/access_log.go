@@ -0,0 +1,79 @@
+package appmanager
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/logging"
+)
+
+// requestLoggerContextKey caches the request-scoped logger
+const requestLoggerContextKey = "request_logger"
+
+// quietPaths are probes polled by orchestrators; they are only logged when they fail
+var quietPaths = map[string]bool{
+	"/health": true,
+	"/livez":  true,
+	"/readyz": true,
+}
+
+// accessLogMiddleware logs one "request" record per request with its status and latency as
+// fields (request_id, method, path, route, status, latency_ms, bytes, remote_ip, user_agent,
+// key). Errors are rendered here so the logged status is the one the client received.
+func (am *AppManager) accessLogMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		if err := next(c); err != nil {
+			c.Error(err)
+		}
+
+		req, res := c.Request(), c.Response()
+		if quietPaths[req.URL.Path] && res.Status < http.StatusInternalServerError {
+			return nil
+		}
+
+		attrs := []slog.Attr{
+			slog.String("request_id", res.Header().Get(echo.HeaderXRequestID)),
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.String("route", c.Path()), // Matched route, e.g. /sources/:id
+			slog.Int("status", res.Status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int64("bytes", res.Size),
+			slog.String("remote_ip", c.RealIP()),
+		}
+		if ua := req.UserAgent(); ua != "" {
+			attrs = append(attrs, slog.String("user_agent", ua))
+		}
+		// Name of the credential that authenticated the request
+		if name, ok := c.Get(authKeyContextKey).(string); ok {
+			attrs = append(attrs, slog.String("key", name))
+		}
+
+		level := slog.LevelInfo
+		if res.Status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		am.logger.LogAttrs(req.Context(), level, "request", attrs...)
+		return nil
+	}
+}
+
+// log returns a logger whose records carry the request ID, for correlating handler logs
+// with the access log and error responses
+func (am *AppManager) log(c echo.Context) *logging.Logger {
+	if logger, ok := c.Get(requestLoggerContextKey).(*logging.Logger); ok {
+		return logger
+	}
+
+	id := c.Response().Header().Get(echo.HeaderXRequestID)
+	if id == "" {
+		return am.logger
+	}
+	logger := am.logger.With("request_id", id)
+	c.Set(requestLoggerContextKey, logger)
+	return logger
+}
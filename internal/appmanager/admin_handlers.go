@@ -0,0 +1,26 @@
+package appmanager
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// handleCompact compacts the database file. Storage access (and therefore
+// monitor writes) is paused for the duration of the swap.
+func (am *AppManager) handleCompact(c echo.Context) error {
+	am.log(c).Println("Database compaction requested via API")
+
+	result, err := am.storage.Compact()
+	if err != nil {
+		am.log(c).Errorf("Database compaction failed: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":     "Database compacted",
+		"size_before": result.SizeBefore,
+		"size_after":  result.SizeAfter,
+		"duration_ms": result.DurationMs,
+	})
+}
@@ -0,0 +1,317 @@
+package appmanager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+const (
+	// agentStaleIntervals is after how many missed check intervals a location's result no
+	// longer counts, e.g. because its agent stopped
+	agentStaleIntervals = 3
+	// maxAgentResults bounds the results in one report
+	maxAgentResults = 1000
+)
+
+// AgentSource is a source as handed to a probe agent: only what it needs to check it
+type AgentSource struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Type          string `json:"type"` // "ping" or "http"
+	Target        string `json:"target"`
+	CheckInterval string `json:"check_interval"`
+}
+
+// AgentResult is one check run by a probe agent
+type AgentResult struct {
+	SourceID  string    `json:"source_id"`
+	Status    int       `json:"status"` // 1 online, 0 offline
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// AgentReport is the body of POST /agents/results
+type AgentReport struct {
+	Location string        `json:"location"`
+	Version  string        `json:"version,omitempty"`
+	Results  []AgentResult `json:"results"`
+}
+
+// AgentReportResponse counts the results of a report that were recorded
+type AgentReportResponse struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"` // Unknown sources or sources not assigned to the location
+}
+
+// AgentInfo describes a probe agent that has pulled sources or reported results
+type AgentInfo struct {
+	Location   string    `json:"location"`
+	Version    string    `json:"version,omitempty"`
+	RemoteIP   string    `json:"remote_ip"`
+	LastSeen   time.Time `json:"last_seen"`
+	LastReport time.Time `json:"last_report,omitempty"`
+	Sources    int       `json:"sources"` // Assigned at the last pull
+}
+
+// LocationStatus is the latest result of a source from one location
+type LocationStatus struct {
+	Location  string    `json:"location"` // "local" for the central instance's own checks
+	Status    int       `json:"status"`   // 1 online, 0 offline, -1 unknown
+	LatencyMs int64     `json:"latency_ms,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at,omitempty"`
+	Stale     bool      `json:"stale"` // No result for 3 check intervals; ignored in the summary
+}
+
+// SourceLocationsResponse is the per-location status of a source
+type SourceLocationsResponse struct {
+	SourceID  string           `json:"source_id"`
+	Locations []LocationStatus `json:"locations"`
+	Summary   string           `json:"summary"` // e.g. "down from eu; up from local, us"
+}
+
+// agentState holds what probe agents reported. It is kept in memory: agents report again
+// within a check interval after a restart.
+type agentState struct {
+	mu      sync.Mutex
+	agents  map[string]*AgentInfo                 // location -> agent
+	results map[string]map[string]*LocationStatus // sourceID -> location -> latest result
+}
+
+// seen records a request from the agent at location
+func (s *agentState) seen(location, version, remoteIP string, now time.Time) *AgentInfo {
+	if s.agents == nil {
+		s.agents = make(map[string]*AgentInfo)
+	}
+	agent, ok := s.agents[location]
+	if !ok {
+		agent = &AgentInfo{Location: location}
+		s.agents[location] = agent
+	}
+	agent.RemoteIP = remoteIP
+	agent.LastSeen = now
+	if version != "" {
+		agent.Version = version
+	}
+	return agent
+}
+
+// locationStale reports whether a result checked at checkedAt is too old for interval
+func locationStale(checkedAt time.Time, interval time.Duration, now time.Time) bool {
+	return now.Sub(checkedAt) > agentStaleIntervals*interval
+}
+
+// agentLocation reads and normalizes the location of an agent request
+func agentLocation(raw string) (string, error) {
+	locations, err := storage.NormalizeLocations([]string{raw})
+	if err != nil {
+		return "", err
+	}
+	if len(locations) == 0 {
+		return "", fmt.Errorf("location is required")
+	}
+	return locations[0], nil
+}
+
+// handleGetAgentSources returns the enabled ping and http sources assigned to ?location=
+func (am *AppManager) handleGetAgentSources(c echo.Context) error {
+	location, err := agentLocation(c.QueryParam("location"))
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		am.log(c).Errorf("Failed to get sources: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get sources")
+	}
+
+	assigned := []AgentSource{}
+	for _, source := range sources {
+		if !source.Enabled || source.Type == "webhook" || !source.HasLocation(location) {
+			continue
+		}
+		assigned = append(assigned, AgentSource{
+			ID:            source.ID,
+			Name:          source.Name,
+			Type:          source.Type,
+			Target:        source.Target,
+			CheckInterval: source.CheckInterval.String(),
+		})
+	}
+
+	am.agents.mu.Lock()
+	agent := am.agents.seen(location, c.QueryParam("version"), c.RealIP(), time.Now())
+	agent.Sources = len(assigned)
+	am.agents.mu.Unlock()
+
+	return c.JSON(http.StatusOK, assigned)
+}
+
+// handleAgentReport records check results from a probe agent and alerts a source's chats
+// when its status from that location changes
+func (am *AppManager) handleAgentReport(c echo.Context) error {
+	var report AgentReport
+	if err := c.Bind(&report); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+	location, err := agentLocation(report.Location)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	if len(report.Results) > maxAgentResults {
+		return errorJSON(c, http.StatusBadRequest, fmt.Sprintf("At most %d results per report", maxAgentResults))
+	}
+
+	// Resolve sources before taking the lock
+	var resp AgentReportResponse
+	sources := make([]*storage.Source, len(report.Results))
+	for i, result := range report.Results {
+		source, err := am.storage.GetSource(result.SourceID)
+		if err != nil || source.IsDeleted() || !source.HasLocation(location) || (result.Status != 0 && result.Status != 1) {
+			resp.Rejected++
+			continue
+		}
+		sources[i] = source
+	}
+
+	type change struct {
+		source *storage.Source
+		status int
+	}
+	var changes []change
+	now := time.Now()
+
+	am.agents.mu.Lock()
+	agent := am.agents.seen(location, report.Version, c.RealIP(), now)
+	agent.LastReport = now
+	if am.agents.results == nil {
+		am.agents.results = make(map[string]map[string]*LocationStatus)
+	}
+	for i, result := range report.Results {
+		source := sources[i]
+		if source == nil {
+			continue
+		}
+		if result.CheckedAt.IsZero() || result.CheckedAt.After(now) {
+			result.CheckedAt = now // Missing or from an agent with a skewed clock
+		}
+
+		byLocation := am.agents.results[source.ID]
+		if byLocation == nil {
+			byLocation = make(map[string]*LocationStatus)
+			am.agents.results[source.ID] = byLocation
+		}
+		previous := byLocation[location]
+		if previous != nil && result.CheckedAt.Before(previous.CheckedAt) {
+			resp.Accepted++ // Out of order: keep the newer result
+			continue
+		}
+		byLocation[location] = &LocationStatus{
+			Location:  location,
+			Status:    result.Status,
+			LatencyMs: result.LatencyMs,
+			Error:     result.Error,
+			CheckedAt: result.CheckedAt,
+		}
+		resp.Accepted++
+
+		if previous != nil && previous.Status != result.Status && source.Enabled &&
+			!locationStale(previous.CheckedAt, source.CheckInterval, now) {
+			changes = append(changes, change{source: source, status: result.Status})
+		}
+	}
+	am.agents.mu.Unlock()
+
+	for _, ch := range changes {
+		locations := am.sourceLocations(ch.source, now)
+		direction := "🔴 %s is down from %s"
+		if ch.status == 1 {
+			direction = "🟢 %s is up again from %s"
+		}
+		text := fmt.Sprintf(direction, ch.source.Name, location) + "\n" + locations.Summary
+		am.log(c).Printf("%s (%s)", fmt.Sprintf(direction, ch.source.Name, location), locations.Summary)
+		go am.alertSourceChats(context.Background(), ch.source.ID, text)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// handleGetAgents lists the probe agents seen since startup, by location
+func (am *AppManager) handleGetAgents(c echo.Context) error {
+	am.agents.mu.Lock()
+	agents := make([]AgentInfo, 0, len(am.agents.agents))
+	for _, agent := range am.agents.agents {
+		agents = append(agents, *agent)
+	}
+	am.agents.mu.Unlock()
+
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Location < agents[j].Location })
+	return c.JSON(http.StatusOK, agents)
+}
+
+// handleGetSourceLocations returns a source's status from the central instance and from
+// each of its probe locations
+func (am *AppManager) handleGetSourceLocations(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	return c.JSON(http.StatusOK, am.sourceLocations(source, time.Now()))
+}
+
+// sourceLocations combines the central instance's status of a source with the latest
+// result from each assigned location
+func (am *AppManager) sourceLocations(source *storage.Source, now time.Time) *SourceLocationsResponse {
+	resp := &SourceLocationsResponse{SourceID: source.ID}
+	local := LocationStatus{Location: storage.LocalLocation, Status: source.CurrentStatus, Error: source.LastError}
+	if !source.LastCheckTime.IsZero() {
+		local.CheckedAt = source.LastCheckTime
+	}
+	resp.Locations = append(resp.Locations, local)
+
+	am.agents.mu.Lock()
+	for _, location := range source.Locations {
+		status := LocationStatus{Location: location, Status: -1}
+		if result := am.agents.results[source.ID][location]; result != nil {
+			status = *result
+			status.Stale = locationStale(result.CheckedAt, source.CheckInterval, now)
+		}
+		resp.Locations = append(resp.Locations, status)
+	}
+	am.agents.mu.Unlock()
+
+	var down, up []string
+	for _, status := range resp.Locations {
+		switch {
+		case status.Stale:
+		case status.Status == 0:
+			down = append(down, status.Location)
+		case status.Status == 1:
+			up = append(up, status.Location)
+		}
+	}
+	var parts []string
+	if len(down) > 0 {
+		parts = append(parts, "down from "+strings.Join(down, ", "))
+	}
+	if len(up) > 0 {
+		parts = append(parts, "up from "+strings.Join(up, ", "))
+	}
+	resp.Summary = strings.Join(parts, "; ")
+	if resp.Summary == "" {
+		resp.Summary = "no results yet"
+	}
+	return resp
+}
@@ -1,11 +1,16 @@
 package appmanager
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/storage"
 )
 
 // setupRoutes configures all API routes
@@ -17,57 +22,192 @@ func (am *AppManager) setupRoutes() {
 	// Middleware
 	am.echoServer.Use(am.apiKeyMiddleware)
 
+	// API documentation (no API key)
+	am.echoServer.GET("/openapi.json", am.handleOpenAPISpec)
+	am.echoServer.GET("/docs", am.handleSwaggerUI)
+
+	// Embedded admin dashboard (static files; API calls from it carry the API key)
+	am.echoServer.GET("/ui", am.handleUIRedirect)
+	am.echoServer.GET("/ui/*", uiHandler())
+
+	// Status badges for READMEs (no API key)
+	am.echoServer.GET("/badge/:source_id", am.handleBadge)
+
+	// Public status page (no API key; 404 unless STATUS_PAGE_ENABLED)
+	am.echoServer.GET("/statuspage", am.handleStatusPage, etagMiddleware)
+	am.echoServer.GET("/statuspage.json", am.handleStatusPageJSON, etagMiddleware)
+
+	// Probes (no API key)
+	am.echoServer.GET("/health", am.handleHealth)
+	am.echoServer.GET("/livez", am.handleLivez)
+	am.echoServer.GET("/readyz", am.handleReadyz)
+
+	// JSON API under /api/v1, with deprecated aliases at the old unversioned paths
+	api := apiRoutes{am.echoServer}
+
 	// Config endpoints
-	am.echoServer.GET("/config", am.handleGetAllConfig)
-	am.echoServer.GET("/config/:key", am.handleGetConfig)
-	am.echoServer.PUT("/config/:key", am.handleUpdateConfig)
-	am.echoServer.POST("/config/reload", am.handleReloadConfig)
+	api.GET("/config", am.handleGetAllConfig)
+	api.GET("/config/:key", am.handleGetConfig)
+	api.PUT("/config/:key", am.handleUpdateConfig)
+	api.POST("/config/validate", am.handleValidateConfig)
+	api.POST("/config/reload", am.handleReloadConfig)
+	api.GET("/features", am.handleGetFeatureFlags)
+	api.PUT("/features/:name", am.handleUpdateFeatureFlag)
+
+	// Admin endpoints
+	api.POST("/admin/compact", am.handleCompact)
+	api.POST("/admin/selftest", am.handleSelfTest)
+
+	// Debug endpoints (admin scope; pprof 404 unless PPROF_ENABLED)
+	api.GET("/debug/runtime", am.handleDebugRuntime)
+	api.GET("/debug/pprof/*", am.handlePprof)
+	api.POST("/debug/pprof/*", am.handlePprof)
 
 	// Status endpoints
-	am.echoServer.GET("/health", am.handleHealth)
-	am.echoServer.GET("/status", am.handleStatus)
+	api.GET("/status", am.handleStatus)
+	api.GET("/system/events", am.handleGetSystemEvents)
+
+	// Remote probe agents (write scope to report results)
+	api.GET("/agents", am.handleGetAgents)
+	api.GET("/agents/sources", am.handleGetAgentSources)
+	api.POST("/agents/results", am.handleAgentReport)
 
 	// Source endpoints - collection routes
-	am.echoServer.GET("/sources", am.handleGetSources)
-	am.echoServer.POST("/sources", am.handleCreateSource)
+	api.GET("/sources", am.handleGetSources, etagMiddleware)
+	api.POST("/sources", am.handleCreateSource)
+	api.POST("/sources/bulk", am.handleBulkSources)
+	api.GET("/templates", am.handleGetSourceTemplates)
+	api.POST("/templates", am.handleCreateSourceTemplate)
+	api.GET("/templates/:id", am.handleGetSourceTemplate)
+	api.PUT("/templates/:id", am.handleUpdateSourceTemplate)
+	api.DELETE("/templates/:id", am.handleDeleteSourceTemplate)
+	api.GET("/discovery", am.handleGetDiscovery)
+	api.POST("/discovery/scan", am.handleStartDiscovery)
+	api.POST("/discovery/promote", am.handlePromoteHosts)
+	api.DELETE("/discovery/hosts/:ip", am.handleDeleteDiscoveredHost)
+	api.POST("/import", am.handleImportExternal)
+	api.GET("/tags", am.handleGetTags)
+	api.POST("/tags/:tag/pause", am.handlePauseTag)
+	api.POST("/tags/:tag/resume", am.handleResumeTag)
+	api.GET("/sources/deleted", am.handleGetDeletedSources)
 	// Source-specific sub-resource routes (must come BEFORE generic :id routes)
 	// These use :source_id or :id as parameter names matching their handlers
-	am.echoServer.POST("/sources/:id/pause", am.handlePauseSource)
-	am.echoServer.POST("/sources/:id/resume", am.handleResumeSource)
-	am.echoServer.GET("/sources/:source_id/webhooks", am.handleGetSourceWebhooks)
-	am.echoServer.POST("/sources/:source_id/webhooks/:webhook_id", am.handleAddSourceWebhook)
-	am.echoServer.DELETE("/sources/:source_id/webhooks/:webhook_id", am.handleRemoveSourceWebhook)
-	am.echoServer.GET("/sources/:source_id/telegram-chats", am.handleGetSourceTelegramChats)
-	am.echoServer.POST("/sources/:source_id/telegram-chats/:chat_id", am.handleAddSourceTelegramChat)
-	am.echoServer.DELETE("/sources/:source_id/telegram-chats/:chat_id", am.handleRemoveSourceTelegramChat)
+	api.POST("/sources/:id/check", am.handleCheckSource)
+	api.GET("/sources/:id/durations", am.handleGetSourceDurations)
+	api.GET("/sources/:id/locations", am.handleGetSourceLocations)
+	api.POST("/sources/:id/pause", am.handlePauseSource)
+	api.POST("/sources/:id/resume", am.handleResumeSource)
+	api.POST("/sources/:id/restore", am.handleRestoreSource)
+	api.POST("/sources/:id/clone", am.handleCloneSource)
+	api.POST("/sources/:id/webhook-token/rotate", am.handleRotateWebhookToken)
+	api.GET("/sources/:id/webhook-requests", am.handleGetWebhookRequests)
+	api.GET("/sources/:id/rollups", am.handleGetSourceRollups)
+	api.GET("/sources/:id/heatmap", am.handleGetSourceHeatmap)
+	api.GET("/sources/:id/uptime", am.handleGetSourceUptime)
+	api.GET("/uptime", am.handleGetUptime)
+	api.GET("/sources/:id/health-score", am.handleGetSourceHealthScore)
+	api.GET("/health-scores", am.handleGetHealthScores)
+	api.GET("/reliability", am.handleGetReliability)
+	api.GET("/sources/:id/slo", am.handleGetSourceSLO)
+	api.GET("/slo", am.handleGetSLOs)
+	api.GET("/sources/:id/history", am.handleGetSourceHistory)
+	api.GET("/sources/:id/timeline", am.handleGetSourceTimeline)
+	api.GET("/sources/:id/history.csv", am.handleGetSourceHistoryCSV)
+	api.GET("/sources/:id/notes", am.handleGetIncidentNotes)
+	api.POST("/sources/:id/notes", am.handleAddIncidentNote)
+	api.DELETE("/sources/:id/notes/:note_id", am.handleDeleteIncidentNote)
+	api.GET("/sources/:id/planned-outages", am.handleGetPlannedOutages)
+	api.POST("/sources/:id/planned-outages", am.handleMarkOutagePlanned)
+	api.DELETE("/sources/:id/planned-outages/:change_id", am.handleUnmarkOutagePlanned)
+	api.GET("/sources/:id/postmortem", am.handleGetPostmortem)
+	api.GET("/sources/:id/metrics.csv", am.handleGetSourceMetricsCSV)
+	api.GET("/sources/:source_id/webhooks", am.handleGetSourceWebhooks)
+	api.POST("/sources/:source_id/webhooks/:webhook_id", am.handleAddSourceWebhook)
+	api.DELETE("/sources/:source_id/webhooks/:webhook_id", am.handleRemoveSourceWebhook)
+	api.GET("/sources/:source_id/telegram-chats", am.handleGetSourceTelegramChats)
+	api.POST("/sources/:source_id/telegram-chats/:chat_id", am.handleAddSourceTelegramChat)
+	api.DELETE("/sources/:source_id/telegram-chats/:chat_id", am.handleRemoveSourceTelegramChat)
+	api.GET("/sources/:source_id/sinks", am.handleGetSourceSinks)
+	api.POST("/sources/:source_id/sinks/:sink_id", am.handleAddSourceSink)
+	api.DELETE("/sources/:source_id/sinks/:sink_id", am.handleRemoveSourceSink)
 	// Generic source routes (must come AFTER specific sub-resource routes)
-	am.echoServer.PUT("/sources/:id", am.handleUpdateSource)
-	am.echoServer.DELETE("/sources/:id", am.handleDeleteSource)
+	api.GET("/sources/:id", am.handleGetSource)
+	api.PUT("/sources/:id", am.handleUpdateSource)
+	api.DELETE("/sources/:id", am.handleDeleteSource)
 
 	// Webhook endpoints
-	am.echoServer.GET("/webhooks", am.handleGetWebhooks)
-	am.echoServer.POST("/webhooks", am.handleCreateWebhook)
-	am.echoServer.PUT("/webhooks/:id", am.handleUpdateWebhook)
-	am.echoServer.DELETE("/webhooks/:id", am.handleDeleteWebhook)
+	api.GET("/webhooks", am.handleGetWebhooks)
+	api.POST("/webhooks", am.handleCreateWebhook)
+	api.PUT("/webhooks/:id", am.handleUpdateWebhook)
+	api.DELETE("/webhooks/:id", am.handleDeleteWebhook)
+
+	// Sink endpoints: Telegram chats and webhooks alike, by sink ID ("<type>:<key>")
+	api.GET("/sinks", am.handleGetSinks)
+	api.POST("/sinks", am.handleCreateSink)
+	api.GET("/sinks/:sink_id", am.handleGetSink)
+	api.PUT("/sinks/:sink_id", am.handleUpdateSink)
+	api.DELETE("/sinks/:sink_id", am.handleDeleteSink)
+
+	// GraphQL (read-only queries; 404 unless GRAPHQL_ENABLED)
+	api.GET("/graphql", am.handleGraphQL)
+	api.POST("/graphql", am.handleGraphQL)
 
 	// Events endpoints
-	am.echoServer.GET("/events", am.handleGetEvents)
+	api.GET("/events", am.handleGetEvents, etagMiddleware)
+	api.GET("/events/stream", am.handleEventStream, am.requireFeature("event_stream"))
+	api.GET("/deliveries", am.handleGetDeliveries)
+
+	// Reports over the last completed week or month
+	api.GET("/reports/:period", am.handleGetReport)
+
+	// Maintenance windows (suppress notifications of the sources they cover)
+	api.GET("/maintenance", am.handleGetMaintenanceWindows)
+	api.POST("/maintenance", am.handleCreateMaintenanceWindow)
+	api.GET("/maintenance/:id", am.handleGetMaintenanceWindow)
+	api.PUT("/maintenance/:id", am.handleUpdateMaintenanceWindow)
+	api.DELETE("/maintenance/:id", am.handleDeleteMaintenanceWindow)
+	api.POST("/maintenance/:id/close", am.handleCloseMaintenanceWindow)
 
 	// Telegram chat endpoints
-	am.echoServer.GET("/telegram-chats", am.handleGetTelegramChats)
-	am.echoServer.POST("/telegram-chats", am.handleAddTelegramChat)
-	am.echoServer.DELETE("/telegram-chats/:chat_id", am.handleRemoveTelegramChat)
+	api.GET("/telegram-chats", am.handleGetTelegramChats)
+	api.POST("/telegram-chats", am.handleAddTelegramChat)
+	api.DELETE("/telegram-chats/:chat_id", am.handleRemoveTelegramChat)
+
+	// Namespace management (admin scope)
+	api.GET("/namespaces", am.handleGetNamespaces)
+	api.POST("/namespaces", am.handleCreateNamespace)
+	api.PUT("/namespaces/:name", am.handleUpdateNamespace)
+	api.DELETE("/namespaces/:name", am.handleDeleteNamespace)
+
+	// API key management (admin scope)
+	api.GET("/keys", am.handleGetAPIKeys)
+	api.POST("/keys", am.handleCreateAPIKey)
+	api.POST("/keys/:id/revoke", am.handleRevokeAPIKey)
+	api.DELETE("/keys/:id", am.handleDeleteAPIKey)
+
+	// Telegram users allowed to use the bot (admin scope)
+	api.GET("/users", am.handleGetUsers)
+	api.GET("/users/seen", am.handleGetSeenUsers)
+	api.POST("/users", am.handleAddUser)
+	api.DELETE("/users/:user", am.handleRemoveUser)
 
 	// Test notification endpoints
-	am.echoServer.POST("/test/telegram/:chat_id", am.handleTestTelegramChat)
-	am.echoServer.POST("/test/webhook/:webhook_id", am.handleTestWebhook)
+	api.POST("/test/telegram/:chat_id", am.handleTestTelegramChat)
+	api.POST("/test/webhook/:webhook_id", am.handleTestWebhook)
+	api.POST("/test/all", am.handleTestAllSinks)
 }
 
-// apiKeyMiddleware validates X-API-Key header
+// apiKeyMiddleware authenticates the X-API-Key header (API_KEY or a named key) or, with OIDC
+// enabled, a bearer JWT, and enforces the credential's scope and namespace
 func (am *AppManager) apiKeyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		// Skip auth for health endpoint
-		if c.Path() == "/health" {
+		// Skip auth for health and probe endpoints
+		if c.Path() == "/health" || c.Path() == "/livez" || c.Path() == "/readyz" {
+			return next(c)
+		}
+		// Skip auth for API documentation and the public status page
+		switch c.Path() {
+		case "/openapi.json", "/docs", "/statuspage", "/statuspage.json", "/ui", "/ui/*", "/badge/:source_id":
 			return next(c)
 		}
 		// Skip auth for incoming webhook heartbeat (public URL for monitored services)
@@ -76,21 +216,63 @@ func (am *AppManager) apiKeyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 		}
 
 		apiKey := c.Request().Header.Get("X-API-Key")
-		if apiKey == "" {
-			am.logger.Printf("Missing API key from %s on %s %s", c.RealIP(), c.Request().Method, c.Path())
-			return c.JSON(http.StatusUnauthorized, map[string]string{
-				"error": "Missing X-API-Key header",
-			})
+		// Browsers' EventSource cannot set headers, so the stream also accepts ?api_key=
+		if apiKey == "" && apiRoute(c) == "/events/stream" {
+			apiKey = c.QueryParam("api_key")
+		}
+		// With OIDC enabled, a bearer JWT may be sent instead of an API key
+		bearer := ""
+		if am.oidc != nil {
+			bearer, _ = strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+		}
+		if apiKey == "" && bearer == "" {
+			am.log(c).Warnf("Missing API key from %s on %s %s", c.RealIP(), c.Request().Method, c.Path())
+			message := "Missing X-API-Key header"
+			if am.oidc != nil {
+				message = "Missing X-API-Key or Authorization: Bearer header"
+			}
+			return errorJSON(c, http.StatusUnauthorized, message)
+		}
+
+		var name, scope, namespace string
+		var err error
+		if apiKey != "" {
+			name, scope, namespace, err = am.authenticateAPIKey(apiKey)
+			if err != nil {
+				// Never log the presented key: a typo'd real key would end up in the logs
+				am.log(c).Warnf("Invalid API key attempt from %s on %s %s: %v",
+					c.RealIP(), c.Request().Method, c.Path(), err)
+				// The reason names the key, so it stays in the log
+				return errorJSON(c, http.StatusUnauthorized, "Invalid API key")
+			}
+		} else {
+			name, scope, err = am.oidc.verify(bearer)
+			if err != nil {
+				am.log(c).Warnf("Invalid bearer token from %s on %s %s: %v", c.RealIP(), c.Request().Method, c.Path(), err)
+			}
+		}
+		if err != nil {
+			return errorJSON(c, http.StatusUnauthorized, err.Error())
+		}
+
+		if required := requiredScope(c); !storage.ScopeAllows(scope, required) {
+			am.log(c).Warnf("API key %s (%s) denied on %s %s", name, scope, c.Request().Method, c.Path())
+			return errorJSON(c, http.StatusForbidden, fmt.Sprintf("API key scope %s does not allow this request (requires %s)", scope, required))
 		}
 
-		if apiKey != am.apiKey {
-			am.logger.Printf("Invalid API key attempt from %s on %s %s - provided: %q (expected: %q)",
-				c.RealIP(), c.Request().Method, c.Path(), apiKey, am.apiKey)
-			return c.JSON(http.StatusUnauthorized, map[string]string{
-				"error": "Invalid API key",
-			})
+		if namespace != "" {
+			if !namespacedRoute(apiRoute(c)) {
+				am.log(c).Warnf("API key %s (namespace %s) denied on %s %s", name, namespace, c.Request().Method, c.Path())
+				return errorJSON(c, http.StatusForbidden, fmt.Sprintf("API key is limited to namespace %s and can't use this endpoint", namespace))
+			}
+			if message := am.namespaceRouteError(c, namespace); message != "" {
+				return errorJSON(c, http.StatusNotFound, message)
+			}
 		}
 
+		c.Set(authKeyContextKey, name)
+		c.Set(authScopeContextKey, scope)
+		c.Set(authNamespaceContextKey, namespace)
 		return next(c)
 	}
 }
@@ -102,12 +284,8 @@ func (am *AppManager) handleGetAllConfig(c echo.Context) error {
 	// Mask sensitive values
 	masked := make(map[string]string)
 	for key, value := range configs {
-		if key == "TELEGRAM_TOKEN" || key == "API_KEY" {
-			if len(value) > 8 {
-				masked[key] = value[:4] + "..." + value[len(value)-4:]
-			} else {
-				masked[key] = "***"
-			}
+		if storage.IsSensitiveConfigKey(key) {
+			masked[key] = maskString(value)
 		} else {
 			masked[key] = value
 		}
@@ -122,8 +300,18 @@ func (am *AppManager) handleGetConfig(c echo.Context) error {
 
 	value := am.configManager.Get(key)
 	if value == "" {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Config key not found",
+		return errorJSON(c, http.StatusNotFound, "Config key not found")
+	}
+
+	if storage.IsSensitiveConfigKey(key) {
+		value = maskString(value)
+	}
+
+	if path := am.configManager.FilePath(key); path != "" {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"key":        key,
+			"value":      value,
+			"updated_by": "file:" + path,
 		})
 	}
 
@@ -140,7 +328,7 @@ func (am *AppManager) handleGetConfig(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"key":        entry.Key,
-		"value":      entry.Value,
+		"value":      value,
 		"updated_at": entry.UpdatedAt,
 		"updated_by": entry.UpdatedBy,
 	})
@@ -157,41 +345,96 @@ func (am *AppManager) handleUpdateConfig(c echo.Context) error {
 
 	var req UpdateConfigRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
 	}
 
 	if req.Value == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Value cannot be empty",
-		})
+		return errorJSON(c, http.StatusBadRequest, "Value cannot be empty")
 	}
 
 	// Update config
 	if err := am.configManager.Set(key, req.Value); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+		if errors.Is(err, ErrFileBackedConfig) {
+			return errorJSON(c, http.StatusConflict, err.Error())
+		}
+		return errorJSON(c, http.StatusInternalServerError, err.Error())
 	}
 
-	am.logger.Printf("Config updated via API: %s", key)
+	am.log(c).Printf("Config updated via API: %s", key)
+
+	// Only the key is recorded: values may be secrets
+	am.recordSystemEvent(storage.SystemEventConfigChange, "Config updated: "+key, map[string]string{
+		"key": key,
+		"by":  authKeyName(c),
+	})
 
 	// Note: The onChange callback will trigger bot restart automatically
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message":   "Config updated successfully",
 		"key":       key,
-		"restarting": "Bot will restart with new config",
+		"restarting": "Bot will reload the affected components with new config",
 	})
 }
 
+// ConfigValidationResponse reports the problems found in a proposed config change
+type ConfigValidationResponse struct {
+	Valid    bool             `json:"valid"` // No errors; warnings don't make a config invalid
+	Problems []config.Problem `json:"problems"`
+	Reload   string           `json:"reload,omitempty"` // What applying it would reload: none, full, or monitor, telegram and access joined by +
+}
+
+// handleValidateConfig checks a full or partial config map, merged over the current config,
+// without applying it
+func (am *AppManager) handleValidateConfig(c echo.Context) error {
+	var values map[string]string
+	if err := c.Bind(&values); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body: expected a map of config keys to string values")
+	}
+	if len(values) == 0 {
+		return errorJSON(c, http.StatusBadRequest, "No config values to validate")
+	}
+
+	merged := am.configManager.GetAll()
+	for key, value := range values {
+		if key == "API_KEY" {
+			value = storage.HashConfigAPIKey(value)
+		}
+		merged[key] = value
+	}
+
+	problems := config.Validate(merged)
+	resp := ConfigValidationResponse{Problems: problems}
+	if cfg, err := config.LoadFromMap(merged); err == nil {
+		if _, err := newTLSSettings(cfg); err != nil {
+			resp.Problems = append(resp.Problems, config.Problem{Severity: config.SeverityError, Message: err.Error()})
+		}
+		if _, err := newOIDCVerifier(cfg); err != nil {
+			resp.Problems = append(resp.Problems, config.Problem{Severity: config.SeverityError, Message: err.Error()})
+		}
+		if _, err := newAPIAddress(cfg); err != nil {
+			resp.Problems = append(resp.Problems, config.Problem{Key: "API_BIND", Severity: config.SeverityError, Message: err.Error()})
+		}
+		if am.botProcess != nil {
+			if current := am.botProcess.GetConfig(); current != nil {
+				resp.Reload = classifyConfigChange(current, cfg).String()
+			}
+		}
+	}
+
+	resp.Valid = !config.HasErrors(resp.Problems)
+	if resp.Problems == nil {
+		resp.Problems = []config.Problem{}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
 // handleReloadConfig forces a bot restart with current config
 func (am *AppManager) handleReloadConfig(c echo.Context) error {
-	am.logger.Println("Manual reload requested via API")
+	am.log(c).Println("Manual reload requested via API")
 
 	// Trigger restart
-	go am.RestartBot()
+	go am.RestartBot("manual reload")
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "Bot restart initiated",
@@ -221,7 +464,15 @@ func (am *AppManager) handleHealth(c echo.Context) error {
 	overallStatus := "healthy"
 	httpStatus := http.StatusOK
 
-	if !botRunning {
+	// A replica runs no bot; it is healthy while it can read its copy
+	replica := am.isReplica()
+	if replica {
+		if err := am.storage.Ping(); err != nil {
+			overallStatus = "unhealthy"
+			httpStatus = http.StatusServiceUnavailable
+			lastError = err
+		}
+	} else if !botRunning {
 		overallStatus = "degraded"
 		httpStatus = http.StatusServiceUnavailable
 	} else if !botHealthy {
@@ -229,13 +480,23 @@ func (am *AppManager) handleHealth(c echo.Context) error {
 		httpStatus = http.StatusServiceUnavailable
 	}
 
+	// The watchdog catches a monitor that runs but no longer completes checks
+	checksStalled, lastCheck := am.watchdog.get()
+	if checksStalled && botRunning {
+		overallStatus = "unhealthy"
+		httpStatus = http.StatusServiceUnavailable
+	}
+
+	apiRunning, apiError := am.apiServer.get()
+
 	response := map[string]interface{}{
 		"status":             overallStatus,
 		"bot_running":        botRunning,
 		"bot_healthy":        botHealthy,
 		"monitor_running":    monitorRunning,
 		"telegram_connected": telegramConnected,
-		"api_running":        true,
+		"api_running":        apiRunning,
+		"checks_stalled":     checksStalled,
 		"uptime":             uptime.String(),
 		"uptime_seconds":     int(uptime.Seconds()),
 		"version":            am.version,
@@ -244,45 +505,77 @@ func (am *AppManager) handleHealth(c echo.Context) error {
 	if lastError != nil {
 		response["last_error"] = lastError.Error()
 	}
+	if apiError != "" {
+		response["api_error"] = apiError
+	}
+	if !lastCheck.IsZero() {
+		response["last_check"] = lastCheck
+	}
+	if replica {
+		response["read_only"] = true
+		response["replica"] = am.replicaStatus()
+	}
 
 	return c.JSON(httpStatus, response)
 }
 
-// handleStatus returns detailed status
+// handleStatus returns detailed status; the config is only included for admin credentials
 func (am *AppManager) handleStatus(c echo.Context) error {
 	botStatus := am.botProcess.GetStatus()
 	uptime := time.Since(am.startTime)
+	admin := storage.ScopeAllows(authScope(c), storage.ScopeAdmin)
 
-	// Get current config from ConfigManager
-	allConfig := am.configManager.GetAll()
+	schemaVersion, _ := am.storage.SchemaVersion()
 
-	// Mask sensitive values
-	maskedConfig := make(map[string]string)
-	for key, value := range allConfig {
-		if key == "TELEGRAM_TOKEN" || key == "API_KEY" {
-			if len(value) > 8 {
-				maskedConfig[key] = value[:4] + "..." + value[len(value)-4:]
-			} else {
-				maskedConfig[key] = "***"
-			}
-		} else {
-			maskedConfig[key] = value
-		}
+	apiRunning, apiError := am.apiServer.get()
+	apiStatus := map[string]interface{}{
+		"enabled": am.apiEnabled,
+		"running": apiRunning,
+		"port":    am.apiPort,
+		"address": am.apiAddr.String(),
+		"uptime":  uptime.String(),
+	}
+	if apiError != "" {
+		apiStatus["last_error"] = apiError
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
+	status := map[string]interface{}{
 		"timestamp": time.Now(),
 		"bot":       botStatus,
-		"api": map[string]interface{}{
-			"enabled": am.apiEnabled,
-			"port":    am.apiPort,
-			"uptime":  uptime.String(),
-		},
-		"config": maskedConfig,
+		"api":       apiStatus,
 		"system": map[string]interface{}{
 			"uptime":        uptime.String(),
 			"uptime_seconds": int(uptime.Seconds()),
 			"started_at":    am.startTime,
+			"schema_version": schemaVersion,
 		},
-	})
+	}
+	if admin {
+		// Mask sensitive values
+		maskedConfig := make(map[string]string)
+		for key, value := range am.configManager.GetAll() {
+			if storage.IsSensitiveConfigKey(key) {
+				maskedConfig[key] = maskString(value)
+			} else {
+				maskedConfig[key] = value
+			}
+		}
+		status["config"] = maskedConfig
+	} else {
+		// The bot's config holds the masked Telegram token and allowed users
+		delete(botStatus, "config")
+	}
+	if fileStatus := am.configFile.status(); fileStatus != nil {
+		status["config_file"] = fileStatus
+	}
+	if am.isReplica() {
+		status["replica"] = am.replicaStatus()
+	}
+	if cfg, err := am.configManager.AsConfig(); err == nil {
+		if heartbeat := am.deadMansSwitch.status(cfg); heartbeat != nil {
+			status["dead_mans_switch"] = heartbeat
+		}
+	}
+
+	return c.JSON(http.StatusOK, status)
 }
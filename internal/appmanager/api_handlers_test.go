@@ -1,18 +1,39 @@
 package appmanager
 
 import (
+	"bufio"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
 	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/logging"
+	"tg-monitor-bot/internal/monitor"
+	"tg-monitor-bot/internal/notifier"
 	"tg-monitor-bot/internal/storage"
 )
 
@@ -36,11 +57,12 @@ func setupTestAppManager(t *testing.T) (*AppManager, *storage.BoltDB, func()) {
 
 	am := &AppManager{
 		storage:    db,
+		events:     monitor.NewEventBus(),
 		apiKey:     cfg.APIKey,
 		apiEnabled: cfg.APIEnabled,
 		apiPort:    cfg.APIPort,
 		echoServer: echo.New(),
-		logger:     log.New(os.Stdout, "[TEST] ", log.LstdFlags),
+		logger:     logging.New("test"),
 	}
 
 	// Initialize config manager
@@ -51,7 +73,8 @@ func setupTestAppManager(t *testing.T) (*AppManager, *storage.BoltDB, func()) {
 	botProcess := NewBotProcess(db)
 	am.botProcess = botProcess
 
-	// Setup routes
+	// Setup middleware and routes
+	am.setupBaseMiddleware()
 	am.setupRoutes()
 
 	cleanup := func() {
@@ -309,6 +332,30 @@ func TestSourcesEndpoints(t *testing.T) {
 			if source.Name != "Test Server 2" {
 				t.Errorf("Expected name 'Test Server 2', got %s", source.Name)
 			}
+
+			db.AddSourceChat(sourceID, 12345)
+			db.SaveStatusChange(&storage.StatusChange{SourceID: sourceID, OldStatus: -1, NewStatus: 1, Timestamp: time.Now()})
+
+			rec := makeRequest(t, am, http.MethodGet, "/sources/"+sourceID+"?include=history", "", "test-api-key")
+			if rec.Code != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", rec.Code)
+			}
+			var detail SourceDetailResponse
+			json.Unmarshal(rec.Body.Bytes(), &detail)
+			if detail.Source == nil || detail.Name != "Test Server 2" {
+				t.Errorf("Expected source fields at top level, got %s", rec.Body.String())
+			}
+			if len(detail.TelegramChats) != 1 || detail.TelegramChats[0].ChatID != 12345 {
+				t.Errorf("Expected attached chat, got %+v", detail.TelegramChats)
+			}
+			if detail.Webhooks == nil || len(detail.History) != 1 {
+				t.Errorf("Expected empty webhooks and one history entry, got %s", rec.Body.String())
+			}
+
+			rec = makeRequest(t, am, http.MethodGet, "/sources/nonexistent", "", "test-api-key")
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("Expected status 404 for unknown source, got %d", rec.Code)
+			}
 		})
 
 		// Test PUT /sources/:id (update)
@@ -335,10 +382,51 @@ func TestSourcesEndpoints(t *testing.T) {
 				t.Errorf("Expected status 200, got %d", rec.Code)
 			}
 
-			// Verify deletion
-			_, err := db.GetSource(sourceID)
-			if err == nil {
-				t.Error("Source should be deleted but still exists")
+			// Verify soft deletion
+			source, err := db.GetSource(sourceID)
+			if err != nil {
+				t.Fatalf("Soft-deleted source should still exist: %v", err)
+			}
+			if !source.IsDeleted() {
+				t.Error("Source should be marked as deleted")
+			}
+
+			// A deleted source is gone from the API until restored
+			for _, path := range []string{"", "/uptime", "/history", "/timeline", "/history.csv", "/slo", "/heatmap", "/health-score", "/notes", "/planned-outages", "/postmortem", "/rollups"} {
+				rec := makeRequest(t, am, http.MethodGet, "/sources/"+sourceID+path, "", "test-api-key")
+				if rec.Code != http.StatusNotFound {
+					t.Errorf("Expected status 404 for GET /sources/:id%s of a deleted source, got %d", path, rec.Code)
+				}
+			}
+			if err := monitor.New(db, &config.Config{}, nil).PauseSource(sourceID); !errors.Is(err, monitor.ErrSourceNotFound) {
+				t.Errorf("Expected ErrSourceNotFound pausing a deleted source, got %v", err)
+			}
+		})
+
+		// Test POST /sources/:id/restore
+		t.Run("restore source", func(t *testing.T) {
+			rec := makeRequest(t, am, http.MethodPost, "/sources/"+sourceID+"/restore", "", "test-api-key")
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("Expected status 200, got %d. Body: %s", rec.Code, rec.Body.String())
+			}
+
+			source, err := db.GetSource(sourceID)
+			if err != nil || source.IsDeleted() {
+				t.Error("Source should be restored")
+			}
+		})
+
+		// Test DELETE /sources/:id?purge=true
+		t.Run("purge source", func(t *testing.T) {
+			rec := makeRequest(t, am, http.MethodDelete, "/sources/"+sourceID+"?purge=true", "", "test-api-key")
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", rec.Code)
+			}
+
+			if _, err := db.GetSource(sourceID); err == nil {
+				t.Error("Source should be purged but still exists")
 			}
 		})
 	})
@@ -364,3 +452,4543 @@ func TestReloadEndpoint(t *testing.T) {
 		t.Error("Response should contain a message")
 	}
 }
+
+// TestCompactEndpoint tests the POST /admin/compact endpoint
+func TestCompactEndpoint(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	if err := db.SaveSource(&storage.Source{Name: "Before Compact", Type: "ping", Target: "8.8.8.8"}); err != nil {
+		t.Fatalf("Failed to save source: %v", err)
+	}
+
+	rec := makeRequest(t, am, http.MethodPost, "/admin/compact", "", "test-api-key")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", rec.Code, rec.Body.String())
+	}
+
+	// Database must remain usable after the file swap
+	source, err := db.GetSourceByName("Before Compact")
+	if err != nil {
+		t.Fatalf("Source lost after compaction: %v", err)
+	}
+	if source.Target != "8.8.8.8" {
+		t.Errorf("Expected target 8.8.8.8, got %s", source.Target)
+	}
+}
+
+// TestOpenAPICoversAllRoutes ensures every registered route is documented in /openapi.json
+func TestOpenAPICoversAllRoutes(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	// Served without API key
+	rec := makeRequest(t, am, http.MethodGet, "/openapi.json", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var spec struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Failed to parse spec: %v", err)
+	}
+
+	routes := map[string]bool{}
+	for _, route := range am.echoServer.Routes() {
+		routes[route.Method+" "+route.Path] = true
+	}
+	for _, route := range am.echoServer.Routes() {
+		// Deprecated unversioned aliases are not documented, but must have an /api/v1 route
+		if route.Name == legacyRouteName {
+			if !routes[route.Method+" "+apiV1Prefix+route.Path] {
+				t.Errorf("Alias %s %s has no %s route", route.Method, route.Path, apiV1Prefix)
+			}
+			continue
+		}
+		path := openAPIPath(route.Path)
+		if _, ok := spec.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("Route %s %s missing from OpenAPI spec", route.Method, route.Path)
+		}
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/docs", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "openapi.json") {
+		t.Errorf("Expected Swagger UI page, got %d", rec.Code)
+	}
+}
+
+// TestEventStream tests that published status changes reach GET /events/stream subscribers
+func TestEventStream(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	server := httptest.NewServer(am.echoServer)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events/stream?api_key=test-api-key")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %s", ct)
+	}
+
+	// Wait for the handler to subscribe before publishing
+	for i := 0; i < 100 && am.events.SubscriberCount() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	am.events.Publish(monitor.Event{
+		SourceID:   "src-1",
+		SourceName: "Router",
+		Change:     &storage.StatusChange{ID: "change-1", SourceID: "src-1", OldStatus: 1, NewStatus: 0, Timestamp: time.Now()},
+	})
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var event StatusChangeEventResponse
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			t.Fatalf("Failed to parse event: %v", err)
+		}
+		if event.ID != "change-1" || event.SourceName != "Router" || event.NewStatus != 0 {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+		return
+	}
+	t.Fatal("Stream ended without an event")
+}
+
+// TestStatusPage tests the public status page endpoints
+func TestStatusPage(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	am.configManager.Set("API_KEY", "test-api-key")
+
+	// Disabled by default
+	rec := makeRequest(t, am, http.MethodGet, "/statuspage.json", "", "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404 while disabled, got %d", rec.Code)
+	}
+
+	am.configManager.Set("STATUS_PAGE_ENABLED", "true")
+	db.SaveSource(&storage.Source{Name: "Website", Type: "http", Target: "https://internal.example", CurrentStatus: 0, Enabled: true, Public: true})
+	db.SaveSource(&storage.Source{Name: "Internal DB", Type: "ping", Target: "10.0.0.5", CurrentStatus: 1, Enabled: true})
+
+	rec = makeRequest(t, am, http.MethodGet, "/statuspage.json", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "internal.example") {
+		t.Error("Status page must not expose source targets")
+	}
+
+	var page StatusPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(page.Sources) != 1 || page.Sources[0].Name != "Website" {
+		t.Fatalf("Expected only the public source, got %+v", page.Sources)
+	}
+	if page.Status != "major_outage" {
+		t.Errorf("Expected major_outage, got %s", page.Status)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/statuspage", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Website") {
+		t.Errorf("Expected HTML page listing Website, got %d", rec.Code)
+	}
+}
+
+// TestDashboard tests that the embedded dashboard is served without an API key
+func TestDashboard(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	rec := makeRequest(t, am, http.MethodGet, "/ui", "", "")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "ui/" {
+		t.Errorf("Expected redirect to ui/, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	for _, path := range []string{"/ui/", "/ui/app.js", "/ui/style.css"} {
+		rec = makeRequest(t, am, http.MethodGet, path, "", "")
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status 200 for %s, got %d", path, rec.Code)
+		}
+	}
+}
+
+// TestBadge tests the public SVG status badge
+func TestBadge(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	source := &storage.Source{Name: "Website", Type: "http", Target: "https://example.com", CurrentStatus: 1, Enabled: true}
+	db.SaveSource(source)
+
+	rec := makeRequest(t, am, http.MethodGet, "/badge/"+source.ID+"?label=<api>", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "image/svg+xml") {
+		t.Errorf("Expected SVG content type, got %s", ct)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "online") || !strings.Contains(body, "&lt;api&gt;") {
+		t.Errorf("Expected escaped label and online status, got %s", body)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/badge/nonexistent", "", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+}
+
+// TestBulkSources tests atomic and per-item bulk source operations
+func TestBulkSources(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	existing := &storage.Source{Name: "Old", Type: "ping", Target: "8.8.8.8", CheckInterval: 30 * time.Second, Enabled: true}
+	doomed := &storage.Source{Name: "Doomed", Type: "ping", Target: "1.1.1.1", CheckInterval: 30 * time.Second, Enabled: true}
+	db.SaveSource(existing)
+	db.SaveSource(doomed)
+
+	// Atomic: one invalid operation rejects the whole batch
+	body := `{"atomic":true,"operations":[
+		{"op":"create","source":{"name":"New","type":"http","target":"https://example.com","check_interval":"1m"}},
+		{"op":"delete","id":"nonexistent"}]}`
+	rec := makeRequest(t, am, http.MethodPost, "/sources/bulk", body, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp BulkSourceResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Applied != 0 || resp.Results[0].Status != "skipped" || resp.Results[1].Status != "failed" {
+		t.Errorf("Unexpected atomic response: %+v", resp)
+	}
+	if all, _ := db.GetAllSources(); len(all) != 2 {
+		t.Errorf("Expected atomic failure to write nothing, got %d sources", len(all))
+	}
+
+	// Non-atomic: valid operations are applied, failures reported per item
+	body = `{"operations":[
+		{"op":"create","source":{"name":"New","type":"http","target":"https://example.com","check_interval":"1m"}},
+		{"op":"update","id":"` + existing.ID + `","source":{"name":"Renamed","type":"ping","target":"8.8.4.4","check_interval":"1m","enabled":true}},
+		{"op":"delete","id":"` + doomed.ID + `"},
+		{"op":"create","source":{"name":"","type":"ping"}}]}`
+	rec = makeRequest(t, am, http.MethodPost, "/sources/bulk", body, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	resp = BulkSourceResponse{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Applied != 3 || resp.Failed != 1 {
+		t.Errorf("Expected 3 applied and 1 failed, got %+v", resp)
+	}
+
+	if updated, _ := db.GetSource(existing.ID); updated.Name != "Renamed" || updated.Target != "8.8.4.4" {
+		t.Errorf("Expected source to be updated, got %+v", updated)
+	}
+	if deleted, _ := db.GetSource(doomed.ID); !deleted.IsDeleted() {
+		t.Error("Expected source to be moved to trash")
+	}
+	if _, err := db.GetSource(resp.Results[0].ID); err != nil {
+		t.Errorf("Expected created source to exist: %v", err)
+	}
+}
+
+// TestSourceTags tests tag normalization, listing and event filtering
+func TestSourceTags(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	body := `{"name":"DB","type":"ping","target":"10.0.0.1","check_interval":"30s","tags":[" Prod ","db","prod"]}`
+	rec := makeRequest(t, am, http.MethodPost, "/sources", body, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var tagged storage.Source
+	json.Unmarshal(rec.Body.Bytes(), &tagged)
+	if strings.Join(tagged.Tags, ",") != "prod,db" {
+		t.Errorf("Expected normalized tags [prod db], got %v", tagged.Tags)
+	}
+
+	body = `{"name":"Bad","type":"ping","target":"10.0.0.2","check_interval":"30s","tags":["no spaces"]}`
+	rec = makeRequest(t, am, http.MethodPost, "/sources", body, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid tag, got %d", rec.Code)
+	}
+
+	other := &storage.Source{Name: "Web", Type: "http", Target: "https://example.com", Tags: []string{"prod"}}
+	db.SaveSource(other)
+
+	rec = makeRequest(t, am, http.MethodGet, "/tags", "", "test-api-key")
+	var tags []TagSummary
+	json.Unmarshal(rec.Body.Bytes(), &tags)
+	if len(tags) != 2 || tags[0] != (TagSummary{Tag: "db", Sources: 1}) || tags[1] != (TagSummary{Tag: "prod", Sources: 2}) {
+		t.Errorf("Unexpected tag summary: %+v", tags)
+	}
+
+	now := time.Now()
+	db.SaveStatusChange(&storage.StatusChange{SourceID: tagged.ID, OldStatus: 1, NewStatus: 0, Timestamp: now.Add(-2 * time.Minute)})
+	db.SaveStatusChange(&storage.StatusChange{SourceID: other.ID, OldStatus: 1, NewStatus: 0, Timestamp: now.Add(-time.Minute)})
+
+	rec = makeRequest(t, am, http.MethodGet, "/events?tag=db", "", "test-api-key")
+	var events []StatusChangeEventResponse
+	json.Unmarshal(rec.Body.Bytes(), &events)
+	if len(events) != 1 || events[0].SourceID != tagged.ID {
+		t.Errorf("Expected only the db-tagged source's event, got %+v", events)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/events?tag=PROD", "", "test-api-key")
+	events = nil
+	json.Unmarshal(rec.Body.Bytes(), &events)
+	if len(events) != 2 || events[0].SourceID != other.ID {
+		t.Errorf("Expected both prod events newest first, got %+v", events)
+	}
+}
+
+// TestSourceQuery tests filtering, sorting and pagination of GET /sources
+func TestSourceQuery(t *testing.T) {
+	now := time.Now()
+	sources := []*storage.Source{
+		{ID: "a", Name: "alpha", Type: "ping", CurrentStatus: 1, Enabled: true, LastChangeTime: now.Add(-3 * time.Hour)},
+		{ID: "b", Name: "Bravo", Type: "http", CurrentStatus: 0, Enabled: true, LastChangeTime: now.Add(-1 * time.Hour)},
+		{ID: "c", Name: "charlie", Type: "http", CurrentStatus: 1, Enabled: false, LastChangeTime: now.Add(-2 * time.Hour)},
+		{ID: "d", Name: "delta", Type: "webhook", CurrentStatus: -1, Enabled: true, LastChangeTime: now},
+	}
+
+	tests := []struct {
+		query string
+		want  string
+		total int
+	}{
+		{"", "a,b,c,d", 4},
+		{"type=http", "b,c", 2},
+		{"enabled=false", "c", 1},
+		{"status=online", "a,c", 2},
+		{"sort=last_change&order=desc", "d,b,c,a", 4},
+		{"sort=status", "d,b,a,c", 4},
+		{"limit=2&offset=1", "b,c", 4},
+		{"offset=10", "", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/sources?"+tt.query, nil), httptest.NewRecorder())
+			q, err := parseSourceQuery(c)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			page, total := q.apply(sources)
+			ids := make([]string, len(page))
+			for i, s := range page {
+				ids[i] = s.ID
+			}
+			if got := strings.Join(ids, ","); got != tt.want || total != tt.total {
+				t.Errorf("Expected %s (total %d), got %s (total %d)", tt.want, tt.total, got, total)
+			}
+		})
+	}
+
+	for _, bad := range []string{"status=up", "sort=target", "order=sideways", "limit=0", "offset=-1", "enabled=maybe"} {
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/sources?"+bad, nil), httptest.NewRecorder())
+		if _, err := parseSourceQuery(c); err == nil {
+			t.Errorf("Expected error for %s", bad)
+		}
+	}
+}
+
+// TestCheckSource tests the synchronous manual check endpoint
+func TestCheckSource(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+
+	source := &storage.Source{Name: "Web", Type: "http", Target: target.URL, CheckInterval: time.Minute, CurrentStatus: -1, Enabled: true}
+	db.SaveSource(source)
+
+	rec := makeRequest(t, am, http.MethodPost, "/sources/"+source.ID+"/check", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp CheckSourceResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Status != 1 || resp.PreviousStatus != -1 || !resp.Changed || resp.Event == nil {
+		t.Errorf("Expected recorded change to online, got %+v", resp)
+	}
+
+	stored, _ := db.GetSource(source.ID)
+	if stored.CurrentStatus != 1 || stored.LastCheckTime.IsZero() {
+		t.Errorf("Expected check to be persisted, got %+v", stored)
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/sources/nonexistent/check", "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+}
+
+// TestTLSSettings tests validation of the API TLS config
+func TestTLSSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		scheme  string
+		wantErr bool
+	}{
+		{"plain http", config.Config{}, "http", false},
+		{"static certificate", config.Config{TLSCertFile: "tls.crt", TLSKeyFile: "tls.key"}, "https", false},
+		{"autocert", config.Config{TLSAutocertDomain: "monitor.example.com", DBPath: "data/state.db"}, "https", false},
+		{"cert without key", config.Config{TLSCertFile: "tls.crt"}, "", true},
+		{"cert and autocert", config.Config{TLSCertFile: "tls.crt", TLSKeyFile: "tls.key", TLSAutocertDomain: "monitor.example.com"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := newTLSSettings(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && settings.scheme() != tt.scheme {
+				t.Errorf("Expected scheme %s, got %s", tt.scheme, settings.scheme())
+			}
+		})
+	}
+
+	settings, _ := newTLSSettings(&config.Config{TLSAutocertDomain: "monitor.example.com", DBPath: "data/state.db"})
+	if settings.autocertCache != filepath.Join("data", "autocert") {
+		t.Errorf("Expected autocert cache next to the DB, got %s", settings.autocertCache)
+	}
+}
+
+// TestCORS tests that configured origins get CORS headers, including on keyless preflight requests
+func TestCORS(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	am.corsOrigins = []string{"https://dash.example.com"}
+	am.corsHeaders = []string{"Content-Type", "X-API-Key"}
+	am.echoServer = echo.New()
+	am.setupCORS()
+	am.setupRoutes()
+
+	req := httptest.NewRequest(http.MethodOptions, "/sources", nil)
+	req.Header.Set("Origin", "https://dash.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	rec := httptest.NewRecorder()
+	am.echoServer.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected preflight status 204, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://dash.example.com" {
+		t.Errorf("Expected allowed origin header, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/sources", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("X-API-Key", "test-api-key")
+	rec = httptest.NewRecorder()
+	am.echoServer.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no CORS header for unknown origin, got %q", got)
+	}
+}
+
+// TestAPIKeyScopes tests named API keys: creation, scope enforcement, revocation and expiry
+func TestAPIKeyScopes(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	rec := makeRequest(t, am, http.MethodPost, "/keys", `{"name":"dashboard","scope":"read"}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created CreateAPIKeyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if created.Secret == "" || created.Scope != storage.ScopeRead {
+		t.Fatalf("Expected a read key with a secret, got %+v", created)
+	}
+	if strings.Contains(rec.Body.String(), storage.HashAPIKeySecret(created.Secret)) {
+		t.Error("Expected the key hash not to be returned")
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/tags", "", created.Secret)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected read key to list tags, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/sources", `{"name":"x","type":"ping","target":"1.1.1.1","check_interval":"30s"}`, created.Secret)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected read key to be denied writes with 403, got %d", rec.Code)
+	}
+
+	_, writeSecret, err := db.CreateAPIKey("ci", storage.ScopeWrite, "", nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/keys", "", writeSecret)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected write key to be denied key management with 403, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPut, "/config/LOG_LEVEL", `{"value":"debug"}`, writeSecret)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected write key to be denied config changes with 403, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/keys", `{"name":"bad","scope":"root"}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown scope, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/keys/"+created.ID+"/revoke", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 revoking key, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/sources", "", created.Secret)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), `"Invalid API key"`) {
+		t.Errorf("Expected revoked key to get a generic 401, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	expired := time.Now().Add(-time.Hour)
+	_, expiredSecret, err := db.CreateAPIKey("old", storage.ScopeAdmin, "", &expired)
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/sources", "", expiredSecret)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), `"Invalid API key"`) {
+		t.Errorf("Expected expired key to get a generic 401, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = makeRequest(t, am, http.MethodDelete, "/keys/"+created.ID, "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 deleting key, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodDelete, "/keys/"+created.ID, "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 deleting missing key, got %d", rec.Code)
+	}
+}
+
+func TestAPIKeyRoles(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	source := &storage.Source{Name: "nas", Type: "ping", Target: "192.168.1.5", CheckInterval: time.Minute}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+	_, readSecret, err := db.CreateAPIKey("dashboard", storage.ScopeRead, "", nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	_, operatorSecret, err := db.CreateAPIKey("on-call", storage.ScopeOperator, "", nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+
+	rec := makeRequest(t, am, http.MethodPost, "/maintenance", `{"name":"upgrade"}`, operatorSecret)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("Expected operator key to open a maintenance window, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/maintenance", `{"name":"upgrade"}`, readSecret)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected read key to be denied maintenance windows with 403, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodDelete, "/sources/"+source.ID, "", operatorSecret)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected operator key to be denied deleting sources with 403, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/webhooks", `{"name":"x","url":"https://example.com"}`, operatorSecret)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected operator key to be denied creating webhooks with 403, got %d", rec.Code)
+	}
+
+	// Config reads are admin-only, as masked secrets still show their ends
+	rec = makeRequest(t, am, http.MethodGet, "/config", "", readSecret)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected read key to be denied config with 403, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/status", "", readSecret)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var status map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if _, ok := status["config"]; ok {
+		t.Error("Expected /status without config for a read key")
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/status", "", "test-api-key")
+	if !strings.Contains(rec.Body.String(), `"config"`) {
+		t.Error("Expected /status with config for the admin key")
+	}
+}
+
+func TestAllowedUsers(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	if err := db.RecordTelegramUser(&storage.TelegramUser{ID: 111, Username: "alice"}); err != nil {
+		t.Fatalf("RecordTelegramUser failed: %v", err)
+	}
+
+	rec := makeRequest(t, am, http.MethodPost, "/users", `{"user":"@Alice"}`, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/users", `{"user":"222"}`, "test-api-key")
+	var users []AllowedUserResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &users); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(users) != 2 || users[0].Username != "alice" || users[1].ID != 222 || users[1].LastSeenAt != nil {
+		t.Errorf("Expected alice and 222, got %+v", users)
+	}
+	if got := am.configManager.Get("ALLOWED_USERS"); got != "111,222" {
+		t.Errorf("Expected ALLOWED_USERS 111,222, got %q", got)
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/users", `{"user":"@bob"}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a user who never messaged the bot, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/users/seen", "", "test-api-key")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"allowed":true`) {
+		t.Errorf("Expected alice to be listed as allowed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = makeRequest(t, am, http.MethodDelete, "/users/@alice", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 removing alice, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodDelete, "/users/111", "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 removing a user who isn't allowed, got %d", rec.Code)
+	}
+	// An empty list would let everyone in
+	rec = makeRequest(t, am, http.MethodDelete, "/users/222", "", "test-api-key")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 removing the last user, got %d", rec.Code)
+	}
+
+	_, writeSecret, err := db.CreateAPIKey("ci", storage.ScopeWrite, "", nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/users", "", writeSecret)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected write key to be denied user management with 403, got %d", rec.Code)
+	}
+}
+
+// signTestJWT builds an RS256 token signed with key
+func signTestJWT(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// TestOIDCBearerAuth tests JWT authentication against a stub OIDC provider
+func TestOIDCBearerAuth(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	var issuer string
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/jwks"})
+		case "/jwks":
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+				"kid": "k1",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer provider.Close()
+	issuer = provider.URL
+
+	if _, err := newOIDCVerifier(&config.Config{OIDCIssuerURL: issuer, OIDCAudience: "outage-monitor", OIDCRoleMapping: "ops=superuser"}); err == nil {
+		t.Error("Expected error for a mapping to an unknown scope")
+	}
+	if _, err := newOIDCVerifier(&config.Config{OIDCIssuerURL: issuer, OIDCRoleMapping: "ops=read"}); err == nil {
+		t.Error("Expected error when OIDC_AUDIENCE is unset")
+	}
+	am.oidc, err = newOIDCVerifier(&config.Config{
+		OIDCIssuerURL:   issuer,
+		OIDCAudience:    "outage-monitor",
+		OIDCRolesClaim:  "realm_access.roles",
+		OIDCRoleMapping: "monitor-viewer=read,monitor-admin=admin",
+	})
+	if err != nil {
+		t.Fatalf("newOIDCVerifier failed: %v", err)
+	}
+
+	claims := func(roles []string, aud string, exp time.Time) map[string]interface{} {
+		return map[string]interface{}{
+			"iss":                issuer,
+			"sub":                "user-1",
+			"aud":                []string{aud},
+			"exp":                exp.Unix(),
+			"preferred_username": "alice",
+			"realm_access":       map[string]interface{}{"roles": roles},
+		}
+	}
+	bearer := func(path, method, token string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		am.echoServer.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	hour := time.Now().Add(time.Hour)
+	viewer := signTestJWT(t, key, "k1", claims([]string{"monitor-viewer", "other"}, "outage-monitor", hour))
+	if code := bearer("/tags", http.MethodGet, viewer); code != http.StatusOK {
+		t.Errorf("Expected viewer token to read, got %d", code)
+	}
+	if code := bearer("/tags/prod/pause", http.MethodPost, viewer); code != http.StatusForbidden {
+		t.Errorf("Expected viewer token to be denied writes with 403, got %d", code)
+	}
+
+	admin := signTestJWT(t, key, "k1", claims([]string{"monitor-viewer", "monitor-admin"}, "outage-monitor", hour))
+	if code := bearer("/keys", http.MethodGet, admin); code != http.StatusOK {
+		t.Errorf("Expected admin token to manage keys, got %d", code)
+	}
+
+	unmapped := signTestJWT(t, key, "k1", claims([]string{"other"}, "outage-monitor", hour))
+	if code := bearer("/tags", http.MethodGet, unmapped); code != http.StatusForbidden {
+		t.Errorf("Expected token without mapped roles to get 403, got %d", code)
+	}
+
+	tests := map[string]string{
+		"expired":        signTestJWT(t, key, "k1", claims([]string{"monitor-admin"}, "outage-monitor", time.Now().Add(-time.Hour))),
+		"wrong audience": signTestJWT(t, key, "k1", claims([]string{"monitor-admin"}, "someone-else", hour)),
+		"unknown kid":    signTestJWT(t, key, "k2", claims([]string{"monitor-admin"}, "outage-monitor", hour)),
+		"tampered":       admin[:len(admin)-4] + "AAAA",
+		"malformed":      "not-a-jwt",
+	}
+	for name, token := range tests {
+		if code := bearer("/tags", http.MethodGet, token); code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", name, code)
+		}
+	}
+
+	// API keys keep working alongside OIDC
+	rec := makeRequest(t, am, http.MethodGet, "/tags", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected API key to still work, got %d", rec.Code)
+	}
+}
+
+// TestOIDCKeyRefreshBackoff tests that a failing JWKS endpoint is not fetched on every request
+func TestOIDCKeyRefreshBackoff(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	var issuer string
+	var jwksFetches atomic.Int32
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/jwks"})
+		case "/jwks":
+			jwksFetches.Add(1)
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer provider.Close()
+	issuer = provider.URL
+
+	am.oidc, err = newOIDCVerifier(&config.Config{
+		OIDCIssuerURL:   issuer,
+		OIDCAudience:    "outage-monitor",
+		OIDCRoleMapping: "monitor-admin=admin",
+	})
+	if err != nil {
+		t.Fatalf("newOIDCVerifier failed: %v", err)
+	}
+
+	token := signTestJWT(t, key, "k1", map[string]interface{}{
+		"iss":   issuer,
+		"sub":   "user-1",
+		"aud":   "outage-monitor",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"monitor-admin"},
+	})
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/tags", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		am.echoServer.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Request %d: expected 401 while the JWKS is unavailable, got %d", i, rec.Code)
+		}
+	}
+	if got := jwksFetches.Load(); got != 1 {
+		t.Errorf("Expected one JWKS fetch within the refresh interval, got %d", got)
+	}
+
+	// Once the interval has passed the next request tries again
+	am.oidc.mu.Lock()
+	am.oidc.attemptedAt = time.Now().Add(-jwksMinRefresh)
+	am.oidc.mu.Unlock()
+	if _, _, err := am.oidc.verify(token); err == nil {
+		t.Error("Expected verification to fail while the JWKS is unavailable")
+	}
+	if got := jwksFetches.Load(); got != 2 {
+		t.Errorf("Expected a retry after the refresh interval, got %d fetches", got)
+	}
+}
+
+// TestAPIKeyHashedAtRest tests that API_KEY is stored hashed, still authenticates and is never echoed back
+func TestAPIKeyHashedAtRest(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	am.apiKey = storage.HashConfigAPIKey("test-api-key")
+	rec := makeRequest(t, am, http.MethodGet, "/tags", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected hashed API_KEY to authenticate the plain key, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/tags", "", am.apiKey)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the stored hash itself to be rejected, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodPut, "/config/API_KEY", `{"value":"rotated-secret-value"}`, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	entry, err := db.GetConfig("API_KEY")
+	if err != nil {
+		t.Fatalf("GetConfig failed: %v", err)
+	}
+	if entry.Value != storage.HashConfigAPIKey("rotated-secret-value") {
+		t.Errorf("Expected API_KEY to be stored hashed, got %q", entry.Value)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/config/API_KEY", "", "test-api-key")
+	if strings.Contains(rec.Body.String(), entry.Value) {
+		t.Errorf("Expected GET /config/API_KEY to mask the value, got %s", rec.Body.String())
+	}
+}
+
+// TestProbes tests the liveness and readiness endpoints
+func TestProbes(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	rec := makeRequest(t, am, http.MethodGet, "/livez", "", "")
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected /livez 200 without API key, got %d", rec.Code)
+	}
+
+	// The bot process has not been started, so the monitor is not ready
+	rec = makeRequest(t, am, http.MethodGet, "/readyz", "", "")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected /readyz 503 before start, got %d", rec.Code)
+	}
+	var probe ProbeResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &probe); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if probe.Status != "not_ready" || !probe.Checks["storage"].OK || probe.Checks["monitor"].OK {
+		t.Errorf("Expected storage ok and monitor not ready, got %+v", probe)
+	}
+}
+
+// TestWebhookTestDelivery tests that webhook tests report the target's response
+func TestWebhookTestDelivery(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(strings.Repeat("x", 4096)))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	tests := []struct {
+		path       string
+		wantCode   int
+		wantStatus int
+		wantBody   string
+	}{
+		{"/ok", http.StatusOK, http.StatusOK, "ok"},
+		{"/broken", http.StatusBadGateway, http.StatusInternalServerError, strings.Repeat("x", 1024)},
+	}
+	for _, tt := range tests {
+		webhook := &storage.Webhook{Name: tt.path, URL: target.URL + tt.path, Method: http.MethodPost, Enabled: true}
+		if err := db.SaveWebhook(webhook); err != nil {
+			t.Fatalf("Failed to save webhook: %v", err)
+		}
+
+		rec := makeRequest(t, am, http.MethodPost, "/test/webhook/"+webhook.ID, "", "test-api-key")
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: expected %d, got %d: %s", tt.path, tt.wantCode, rec.Code, rec.Body.String())
+		}
+		var result WebhookTestResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if result.StatusCode != tt.wantStatus || result.ResponseBody != tt.wantBody || result.Success != (tt.wantCode == http.StatusOK) {
+			t.Errorf("%s: unexpected result %+v", tt.path, result.DeliveryResult)
+		}
+	}
+}
+
+// TestRotateWebhookToken tests that rotating a token invalidates the old heartbeat URL
+func TestRotateWebhookToken(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	source := &storage.Source{Name: "cron", Type: "webhook", WebhookToken: "oldtoken", CheckInterval: time.Minute, Enabled: true}
+	pingSource := &storage.Source{Name: "ping", Type: "ping", Target: "8.8.8.8", CheckInterval: time.Minute, Enabled: true}
+	for _, s := range []*storage.Source{source, pingSource} {
+		if err := db.SaveSource(s); err != nil {
+			t.Fatalf("Failed to save source: %v", err)
+		}
+	}
+
+	rec := makeRequest(t, am, http.MethodPost, "/sources/"+source.ID+"/webhook-token/rotate", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var rotated storage.Source
+	if err := json.Unmarshal(rec.Body.Bytes(), &rotated); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if rotated.WebhookToken == "" || rotated.WebhookToken == "oldtoken" {
+		t.Fatalf("Expected a new token, got %q", rotated.WebhookToken)
+	}
+
+	if rec := makeRequest(t, am, http.MethodPost, "/webhooks/incoming/oldtoken", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected old token to be rejected with 404, got %d", rec.Code)
+	}
+	if rec := makeRequest(t, am, http.MethodPost, "/webhooks/incoming/"+rotated.WebhookToken, "", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected new token to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := makeRequest(t, am, http.MethodPost, "/sources/"+pingSource.ID+"/webhook-token/rotate", "", "test-api-key"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a ping source, got %d", rec.Code)
+	}
+	if rec := makeRequest(t, am, http.MethodPost, "/sources/nonexistent/webhook-token/rotate", "", "test-api-key"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing source, got %d", rec.Code)
+	}
+}
+
+// TestWebhookRequestInspector tests that incoming requests are recorded with their validation outcome
+func TestWebhookRequestInspector(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	source := &storage.Source{Name: "cron", Type: "webhook", WebhookToken: "tok123", ExpectedContent: "ok", CheckInterval: time.Minute, Enabled: true}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("Failed to save source: %v", err)
+	}
+
+	if rec := makeRequest(t, am, http.MethodPost, "/webhooks/incoming/tok123", "nope", "client-secret-key"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected content mismatch to be rejected, got %d", rec.Code)
+	}
+	if rec := makeRequest(t, am, http.MethodPost, "/webhooks/incoming/tok123", "status ok", ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected heartbeat to be accepted, got %d", rec.Code)
+	}
+
+	rec := makeRequest(t, am, http.MethodGet, "/sources/"+source.ID+"/webhook-requests", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var requests []IncomingWebhookRequest
+	if err := json.Unmarshal(rec.Body.Bytes(), &requests); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(requests) != 2 || requests[0].Outcome != "accepted" || requests[1].Outcome != "content_mismatch" {
+		t.Fatalf("Expected accepted then content_mismatch, got %+v", requests)
+	}
+	if requests[1].BodySnippet != "nope" || requests[1].StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected rejected body and status to be recorded, got %+v", requests[1])
+	}
+	if key := requests[1].Headers["X-Api-Key"]; key == "" || strings.Contains(key, "secret") {
+		t.Errorf("Expected credential header to be masked, got %q", key)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/sources/"+source.ID+"/webhook-requests?limit=1", "", "test-api-key")
+	if err := json.Unmarshal(rec.Body.Bytes(), &requests); err != nil || len(requests) != 1 {
+		t.Errorf("Expected 1 request with limit=1, got %d (%v)", len(requests), err)
+	}
+	if rec := makeRequest(t, am, http.MethodGet, "/sources/"+source.ID+"/webhook-requests?limit=0", "", "test-api-key"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for limit=0, got %d", rec.Code)
+	}
+}
+
+// TestErrorEnvelope tests that unknown routes, method mismatches, handler errors and
+// panics all return the same JSON error envelope with a request ID
+func TestErrorEnvelope(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	am.echoServer.GET("/panic", func(c echo.Context) error { panic("boom") })
+
+	tests := []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{http.MethodGet, "/no-such-route", http.StatusNotFound, "not_found"},
+		{http.MethodDelete, "/health", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.MethodGet, "/sources/nonexistent/webhook-requests", http.StatusNotFound, "not_found"},
+		{http.MethodGet, "/panic", http.StatusInternalServerError, "internal_server_error"},
+		{http.MethodGet, "/config", http.StatusUnauthorized, "unauthorized"},
+	}
+	for _, tt := range tests {
+		apiKey := "test-api-key"
+		if tt.code == "unauthorized" {
+			apiKey = ""
+		}
+		rec := makeRequest(t, am, tt.method, tt.path, "", apiKey)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.status, rec.Code)
+			continue
+		}
+
+		var body ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s %s: expected JSON error envelope, got %q", tt.method, tt.path, rec.Body.String())
+			continue
+		}
+		requestID := rec.Header().Get(echo.HeaderXRequestID)
+		if body.Error.Code != tt.code || body.Error.Message == "" || requestID == "" || body.Error.RequestID != requestID {
+			t.Errorf("%s %s: unexpected error %+v (X-Request-ID %q)", tt.method, tt.path, body.Error, requestID)
+		}
+	}
+}
+
+// TestAccessLog tests that requests are logged as records with fields and handler logs carry the request ID
+func TestAccessLog(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	var buf strings.Builder
+	am.logger = logging.NewWithHandler(slog.NewJSONHandler(&buf, nil), "appmanager")
+
+	rec := makeRequest(t, am, http.MethodDelete, "/sources/nonexistent", "", "wrong-key")
+	requestID := rec.Header().Get(echo.HeaderXRequestID)
+	if rec.Code != http.StatusUnauthorized || requestID == "" {
+		t.Fatalf("Expected 401 with a request ID, got %d (%q)", rec.Code, requestID)
+	}
+	makeRequest(t, am, http.MethodGet, "/livez", "", "")
+
+	type record struct {
+		Msg       string `json:"msg"`
+		Component string `json:"component"`
+		RequestID string `json:"request_id"`
+		Method    string `json:"method"`
+		Route     string `json:"route"`
+		Status    int    `json:"status"`
+	}
+	var entries []record
+	var handlerRecord *record
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Expected JSON log records, got %q", line)
+		}
+		if r.Msg == "request" {
+			entries = append(entries, r)
+		} else if strings.Contains(r.Msg, "Invalid API key attempt") {
+			handlerRecord = &r
+		}
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected one access log entry (probes are quiet), got %d:\n%s", len(entries), buf.String())
+	}
+	entry := entries[0]
+	if entry.RequestID != requestID || entry.Method != http.MethodDelete || entry.Route != "/sources/:id" || entry.Status != http.StatusUnauthorized || entry.Component != "appmanager" {
+		t.Errorf("Unexpected access log entry %+v", entry)
+	}
+	if handlerRecord == nil || handlerRecord.RequestID != requestID {
+		t.Errorf("Expected handler log record to carry the request ID, got %+v", handlerRecord)
+	}
+}
+
+// TestCompressionAndETags tests gzip responses and If-None-Match revalidation
+func TestCompressionAndETags(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	am.echoServer.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("Expected gzip-encoded /openapi.json, got headers %v", rec.Header())
+	}
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set("X-API-Key", "test-api-key")
+		req.Header.Set("Accept-Encoding", "gzip")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		am.echoServer.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("Expected 200 with a weak ETag, got %d (%q)", first.Code, etag)
+	}
+	if rec := get(etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("Expected empty 304 for a matching ETag, got %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+
+	source := &storage.Source{Name: "api", Type: "http", Target: "https://example.com", CheckInterval: time.Minute, Enabled: true}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("Failed to save source: %v", err)
+	}
+	if err := db.SaveStatusChange(&storage.StatusChange{SourceID: source.ID, OldStatus: 1, NewStatus: 0, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Failed to save status change: %v", err)
+	}
+	rec = get(etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("Expected 200 with a new ETag after a change, got %d (%q)", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+// TestAPIVersioning tests that the API is served under /api/v1 and the old paths still work as deprecated aliases
+func TestAPIVersioning(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/tags", "", "test-api-key")
+	if rec.Code != http.StatusOK || rec.Header().Get("Deprecation") != "" {
+		t.Errorf("Expected /api/v1/tags 200 without deprecation, got %d (%v)", rec.Code, rec.Header())
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/tags", "", "test-api-key")
+	if rec.Code != http.StatusOK || rec.Header().Get("Deprecation") != "true" || !strings.Contains(rec.Header().Get("Link"), "</api/v1/tags>") {
+		t.Errorf("Expected deprecated alias pointing to /api/v1/tags, got %d (%v)", rec.Code, rec.Header())
+	}
+
+	// Scopes apply to versioned paths like their aliases
+	if rec := makeRequest(t, am, http.MethodGet, "/api/v1/keys", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected /api/v1/keys to require a key, got %d", rec.Code)
+	}
+	// Public URLs are not versioned
+	if rec := makeRequest(t, am, http.MethodGet, "/api/v1/livez", "", "test-api-key"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected probes to stay at the root, got %d for /api/v1/livez", rec.Code)
+	}
+}
+
+func TestSystemEvents(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+	am.configManager.Set("API_KEY", "test-api-key")
+
+	// A startup not followed by a shutdown means the previous run crashed
+	am.recordStartup()
+	am.recordStartup()
+
+	if rec := makeRequest(t, am, http.MethodPut, "/api/v1/config/TELEGRAM_TOKEN", `{"value":"secret-token"}`, "test-api-key"); rec.Code != http.StatusOK {
+		t.Fatalf("Expected config update to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	am.echoServer.GET("/api/v1/test-panic", func(c echo.Context) error { panic("boom") })
+	if rec := makeRequest(t, am, http.MethodGet, "/api/v1/test-panic", "", "test-api-key"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected recovered panic to return 500, got %d", rec.Code)
+	}
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/system/events", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "secret-token") {
+		t.Error("Config values must not be recorded in system events")
+	}
+	var events []storage.SystemEvent
+	if err := json.Unmarshal(rec.Body.Bytes(), &events); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	types := make([]string, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	if strings.Join(types, ",") != "panic,config_change,startup,startup" {
+		t.Fatalf("Expected panic, config change and two startups, got %v", types)
+	}
+	if events[1].Details["key"] != "TELEGRAM_TOKEN" {
+		t.Errorf("Expected changed config key to be recorded, got %v", events[1].Details)
+	}
+	if events[2].Details["unclean_shutdown"] != "true" || events[3].Details["unclean_shutdown"] != "" {
+		t.Errorf("Expected only the second startup to be flagged unclean, got %v and %v", events[2].Details, events[3].Details)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/system/events?type=startup&limit=1", "", "test-api-key")
+	events = nil
+	json.Unmarshal(rec.Body.Bytes(), &events)
+	if len(events) != 1 || events[0].Type != storage.SystemEventStartup {
+		t.Errorf("Expected one startup with type filter and limit, got %+v", events)
+	}
+
+	if rec := makeRequest(t, am, http.MethodGet, "/api/v1/system/events?from=yesterday", "", "test-api-key"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid from, got %d", rec.Code)
+	}
+}
+
+func TestDeliveries(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, delivery := range []*storage.Delivery{
+		{SinkType: storage.SinkTypeTelegram, SourceID: "api", StatusChangeID: "c1", Success: true},
+		{SinkType: storage.SinkTypeWebhook, SourceID: "api", StatusChangeID: "c1", Success: false, StatusCode: 500, Error: "unexpected status 500"},
+		{SinkType: storage.SinkTypeWebhook, SourceID: "web", StatusChangeID: "c2", Success: true, StatusCode: 200},
+		{SinkType: storage.SinkTypeTelegram, SourceID: "web", StatusChangeID: "c2", Success: true},
+	} {
+		delivery.Timestamp = start.Add(time.Duration(i) * time.Minute)
+		if err := db.SaveDelivery(delivery); err != nil {
+			t.Fatalf("SaveDelivery failed: %v", err)
+		}
+	}
+
+	get := func(query string) []storage.Delivery {
+		t.Helper()
+		rec := makeRequest(t, am, http.MethodGet, "/api/v1/deliveries"+query, "", "test-api-key")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var deliveries []storage.Delivery
+		if err := json.Unmarshal(rec.Body.Bytes(), &deliveries); err != nil {
+			t.Fatalf("%s: failed to parse response: %v", query, err)
+		}
+		return deliveries
+	}
+
+	if all := get(""); len(all) != 4 || all[0].SourceID != "web" || all[0].SinkType != storage.SinkTypeTelegram {
+		t.Errorf("Expected all 4 deliveries newest first, got %+v", all)
+	}
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{
+		{"?source_id=api", 2},
+		{"?sink_type=webhook", 2},
+		{"?status_change_id=c2", 2},
+		{"?success=false", 1},
+		{"?source_id=api&sink_type=webhook&success=true", 0},
+		{"?source_id=missing", 0},
+		{"?limit=3", 3},
+		{"?limit=0", 4},    // Out of range: the default
+		{"?limit=5000", 4}, // Over the maximum: the default
+	} {
+		if got := get(tt.query); len(got) != tt.want {
+			t.Errorf("%s: expected %d deliveries, got %d", tt.query, tt.want, len(got))
+		}
+	}
+
+	failed := get("?success=false&source_id=api")
+	if len(failed) != 1 || failed[0].StatusCode != 500 || failed[0].Error != "unexpected status 500" {
+		t.Errorf("Expected the failed webhook delivery with its status and error, got %+v", failed)
+	}
+	if limited := get("?limit=1&source_id=api"); len(limited) != 1 || limited[0].SinkType != storage.SinkTypeWebhook {
+		t.Errorf("Expected the newest delivery of api, got %+v", limited)
+	}
+
+	// An empty result is an empty array, not null
+	if rec := makeRequest(t, am, http.MethodGet, "/api/v1/deliveries?source_id=missing", "", "test-api-key"); strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("Expected [], got %s", rec.Body.String())
+	}
+	if rec := makeRequest(t, am, http.MethodGet, "/api/v1/deliveries?success=maybe", "", "test-api-key"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid success filter, got %d", rec.Code)
+	}
+}
+
+func TestClassifyConfigChange(t *testing.T) {
+	base := config.Config{TelegramToken: "t1", AllowedUsers: []int64{1}, PingTimeout: 5 * time.Second, CheckFlushInterval: 30 * time.Second}
+
+	tests := []struct {
+		name   string
+		change func(*config.Config)
+		want   configReload
+	}{
+		{"unrelated setting", func(c *config.Config) { c.MetricsRetention = time.Hour }, 0},
+		{"ping timeout", func(c *config.Config) { c.PingTimeout = time.Second }, reloadMonitor},
+		{"check interval", func(c *config.Config) { c.DefaultCheckInterval = time.Minute }, reloadMonitor},
+		{"token", func(c *config.Config) { c.TelegramToken = "t2" }, reloadTelegram},
+		{"allowed users", func(c *config.Config) { c.AllowedUsers = []int64{1, 2} }, reloadAccess},
+		{"token and timeout", func(c *config.Config) { c.TelegramToken = ""; c.HTTPTimeout = time.Second }, reloadMonitor | reloadTelegram},
+		{"flush interval", func(c *config.Config) { c.CheckFlushInterval = 0 }, reloadFull},
+	}
+	for _, tt := range tests {
+		next := base
+		tt.change(&next)
+		if got := classifyConfigChange(&base, &next); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestBotProcessReloadKeepsMonitor(t *testing.T) {
+	_, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	cfg := &config.Config{PingCount: 3, PingTimeout: 5 * time.Second, HTTPTimeout: 10 * time.Second}
+	bp := NewBotProcess(db)
+	if err := bp.Start(cfg); err != nil {
+		t.Fatalf("Failed to start bot process: %v", err)
+	}
+	defer bp.Stop()
+	mon := bp.GetMonitor()
+
+	next := *cfg
+	next.PingTimeout = time.Second
+	if err := bp.Reload(&next, classifyConfigChange(cfg, &next)); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if bp.GetMonitor() != mon {
+		t.Error("Expected monitor-only change to keep the running monitor")
+	}
+	if bp.GetConfig().PingTimeout != time.Second {
+		t.Errorf("Expected new config to be applied, got %v", bp.GetConfig().PingTimeout)
+	}
+}
+
+// TestBotProcessTelegramFailureKeepsMonitor tests that the monitor runs while the Telegram bot can't start
+func TestBotProcessTelegramFailureKeepsMonitor(t *testing.T) {
+	_, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	cfg := &config.Config{TelegramToken: "invalid-token", PingCount: 3, PingTimeout: 5 * time.Second, HTTPTimeout: 10 * time.Second}
+	bp := NewBotProcess(db)
+	if err := bp.Start(cfg); err != nil {
+		t.Fatalf("Failed to start bot process: %v", err)
+	}
+	defer bp.Stop()
+
+	mon := bp.GetMonitor()
+	if mon == nil {
+		t.Fatal("Expected monitor to run without a working Telegram bot")
+	}
+	if bp.IsHealthy() || bp.GetBot() != nil {
+		t.Error("Expected process to report the Telegram failure")
+	}
+	if status := bp.GetStatus(); status["monitor_running"] != true || status["telegram_connected"] != false {
+		t.Errorf("Expected monitor running without Telegram, got %v", status)
+	}
+
+	// Fixing the token (here: web-only mode) recovers without touching the monitor
+	next := *cfg
+	next.TelegramToken = ""
+	if err := bp.Reload(&next, classifyConfigChange(cfg, &next)); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if !bp.IsHealthy() || bp.GetLastError() != nil {
+		t.Errorf("Expected healthy process after reload, last error: %v", bp.GetLastError())
+	}
+	if bp.GetMonitor() != mon {
+		t.Error("Expected Telegram reload to keep the running monitor")
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+	am.configManager.Set("API_KEY", "test-api-key")
+
+	validate := func(body string) ConfigValidationResponse {
+		t.Helper()
+		rec := makeRequest(t, am, http.MethodPost, "/api/v1/config/validate", body, "test-api-key")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp ConfigValidationResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return resp
+	}
+
+	if resp := validate(`{"PING_TIMEOUT":"2s","API_PORT":"9090"}`); !resp.Valid || len(resp.Problems) != 0 {
+		t.Errorf("Expected valid partial config, got %+v", resp)
+	}
+
+	resp := validate(`{"API_PORT":"70000","PING_TIMEOUT":"soon","TELEGRAM_TOKEN":"abc","TLS_CERT_FILE":"cert.pem","PING_CONT":"3"}`)
+	if resp.Valid {
+		t.Fatal("Expected invalid config")
+	}
+	found := make(map[string]string)
+	for _, p := range resp.Problems {
+		found[p.Key] = p.Severity
+	}
+	for key, severity := range map[string]string{"API_PORT": "error", "PING_TIMEOUT": "error", "TELEGRAM_TOKEN": "error", "PING_CONT": "warning", "": "error"} {
+		if found[key] != severity {
+			t.Errorf("Expected %s problem for %q, got %+v", severity, key, resp.Problems)
+		}
+	}
+	if am.configManager.Get("API_PORT") == "70000" {
+		t.Error("Validation must not apply the config")
+	}
+
+	if rec := makeRequest(t, am, http.MethodPost, "/api/v1/config/validate", `{}`, "test-api-key"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty body, got %d", rec.Code)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+	am.configManager.Set("API_KEY", "test-api-key")
+
+	// An API-created source is adopted by the declaration with its name
+	legacy := &storage.Source{Name: "api", Type: "http", Target: "http://old.example", CheckInterval: time.Minute, Enabled: true}
+	if err := db.SaveSource(legacy); err != nil {
+		t.Fatalf("Failed to save source: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+	}
+	write(`config:
+  PING_TIMEOUT: 2s
+  PING_COUNT: 5
+sources:
+  - name: api
+    type: http
+    target: http://api.example
+    check_interval: 30s
+    tags: [prod]
+  - name: backup
+    type: webhook
+    check_interval: 1h
+`)
+	t.Setenv(configFileEnv, path)
+
+	file := am.loadConfigFile()
+	if file == nil {
+		t.Fatalf("Expected config file to load, got %v", am.configFile.status())
+	}
+	if err := am.applyConfigFile(file); err != nil {
+		t.Fatalf("Failed to apply config file: %v", err)
+	}
+	if am.configManager.Get("PING_TIMEOUT") != "2s" || am.configManager.Get("PING_COUNT") != "5" {
+		t.Errorf("Expected config from file, got %v", am.configManager.GetAll())
+	}
+
+	sources, _ := db.GetAllSources()
+	byName := make(map[string]*storage.Source)
+	for _, source := range sources {
+		byName[source.Name] = source
+	}
+	if len(sources) != 2 || byName["api"].ID != legacy.ID || byName["api"].Target != "http://api.example" {
+		t.Fatalf("Expected legacy source to be adopted and updated, got %+v", sources)
+	}
+	if byName["backup"].ManagedBy != managedByFile || byName["backup"].WebhookToken == "" {
+		t.Errorf("Expected file-managed webhook source with token, got %+v", byName["backup"])
+	}
+
+	// Invalid files are rejected as a whole
+	write(`config:
+  PING_TIMEOUT: 1s
+sources:
+  - name: api
+    type: carrier-pigeon
+`)
+	am.reloadConfigFile()
+	if am.configManager.Get("PING_TIMEOUT") != "2s" {
+		t.Error("Expected config of a rejected file not to be applied")
+	}
+	if status := am.configFile.status(); status["last_error"] == nil {
+		t.Errorf("Expected rejected file to be reported, got %v", status)
+	}
+
+	// Removing a declared source moves it to trash; the watcher picks up the change
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := am.watchConfigFile(ctx); err != nil {
+		t.Fatalf("Failed to watch config file: %v", err)
+	}
+	write(`sources:
+  - name: api
+    type: http
+    target: http://api.example
+    check_interval: 30s
+    tags: [prod]
+`)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if deleted, _ := db.GetDeletedSources(); len(deleted) == 1 && deleted[0].Name == "backup" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected removed source to be moved to trash, status %v", am.configFile.status())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// TestConfigFileSecrets tests that <KEY>_FILE secrets are loaded, never persisted and read-only
+func TestConfigFileSecrets(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	tokenPath := filepath.Join(dir, "telegram_token")
+	keyPath := filepath.Join(dir, "api_key")
+	if err := os.WriteFile(tokenPath, []byte("123456:secret-from-file\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	if err := os.WriteFile(keyPath, []byte("file-api-key\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	t.Setenv("TELEGRAM_TOKEN", "123456:from-env")
+	t.Setenv("TELEGRAM_TOKEN_FILE", tokenPath)
+	t.Setenv("API_KEY_FILE", keyPath)
+
+	if err := am.configManager.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if got := am.configManager.Get("TELEGRAM_TOKEN"); got != "123456:secret-from-file" {
+		t.Errorf("Expected token from file, got %q", got)
+	}
+	if _, err := db.GetConfig("TELEGRAM_TOKEN"); err == nil {
+		t.Error("Secret from file should not be saved to the database")
+	}
+
+	if got := am.configManager.Get("API_KEY"); got != storage.HashConfigAPIKey("file-api-key") {
+		t.Errorf("Expected hashed API key from file, got %q", got)
+	}
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/config/TELEGRAM_TOKEN", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var entry map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &entry)
+	if entry["updated_by"] != "file:"+tokenPath {
+		t.Errorf("Expected updated_by file:%s, got %v", tokenPath, entry["updated_by"])
+	}
+	if strings.Contains(rec.Body.String(), "secret-from-file") {
+		t.Error("Token should be masked")
+	}
+
+	rec = makeRequest(t, am, http.MethodPut, "/api/v1/config/TELEGRAM_TOKEN", `{"value":"123456:other"}`, "test-api-key")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 when changing a file-backed key, got %d", rec.Code)
+	}
+	if err := am.configManager.SetMany(map[string]string{"API_KEY": "x"}, "file"); !errors.Is(err, ErrFileBackedConfig) {
+		t.Errorf("Expected ErrFileBackedConfig from SetMany, got %v", err)
+	}
+
+	// An unreadable secret fails loading
+	t.Setenv("API_KEY_FILE", filepath.Join(dir, "missing"))
+	if err := NewConfigManager(db).Load(); err == nil {
+		t.Error("Expected Load to fail for a missing secret file")
+	}
+}
+
+// TestFeatureFlags tests listing and toggling feature flags and gating routes on them
+func TestFeatureFlags(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+	am.configManager.Set("API_KEY", "test-api-key")
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/features", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var flags []FeatureFlagResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &flags); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(flags) != len(config.FeatureFlags) {
+		t.Fatalf("Expected %d flags, got %d", len(config.FeatureFlags), len(flags))
+	}
+	for _, flag := range flags {
+		if flag.Enabled != flag.Default {
+			t.Errorf("Expected %s to start at its default %t", flag.Name, flag.Default)
+		}
+	}
+
+	rec = makeRequest(t, am, http.MethodPut, "/api/v1/features/event_stream", `{"enabled":false}`, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if am.configManager.FeatureEnabled("event_stream") {
+		t.Error("Expected event_stream to be disabled")
+	}
+	if entry, err := db.GetConfig("FEATURE_EVENT_STREAM"); err != nil || entry.Value != "false" {
+		t.Errorf("Expected flag stored as FEATURE_EVENT_STREAM=false, got %+v (%v)", entry, err)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/events/stream", "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 from a disabled feature, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodPut, "/api/v1/features/teleport", `{"enabled":true}`, "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown flag, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPut, "/api/v1/features/event_stream", `{}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without enabled, got %d", rec.Code)
+	}
+
+	problems := config.Validate(map[string]string{"FEATURE_EVENT_STREAM": "maybe", "FEATURE_TELEPORT": "true"})
+	found := make(map[string]string)
+	for _, p := range problems {
+		found[p.Key] = p.Severity
+	}
+	if found["FEATURE_EVENT_STREAM"] != config.SeverityError || found["FEATURE_TELEPORT"] != config.SeverityWarning {
+		t.Errorf("Expected invalid and unknown flag problems, got %+v", problems)
+	}
+}
+
+// TestAPIAddress tests API_BIND parsing and serving the API on a Unix socket
+func TestAPIAddress(t *testing.T) {
+	cases := []struct {
+		bind    string
+		network string
+		address string
+		wantErr bool
+	}{
+		{"", "tcp", ":8080", false},
+		{"127.0.0.1:9090", "tcp", "127.0.0.1:9090", false},
+		{"[::1]:9090", "tcp", "[::1]:9090", false},
+		{"unix:///run/bot.sock", "unix", "/run/bot.sock", false},
+		{"127.0.0.1", "", "", true},
+		{"unix://", "", "", true},
+	}
+	for _, tc := range cases {
+		addr, err := newAPIAddress(&config.Config{APIPort: 8080, APIBind: tc.bind})
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: unexpected error %v", tc.bind, err)
+			continue
+		}
+		if addr.network != tc.network || addr.address != tc.address {
+			t.Errorf("%q: got %s %s, want %s %s", tc.bind, addr.network, addr.address, tc.network, tc.address)
+		}
+	}
+	if _, err := newAPIAddress(&config.Config{APIBind: "unix:///run/bot.sock", TLSCertFile: "cert.pem", TLSKeyFile: "key.pem"}); err == nil {
+		t.Error("Expected TLS on a Unix socket to be rejected")
+	}
+
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	// Socket paths are limited to ~100 bytes, t.TempDir can be longer
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	addr := apiAddress{network: "unix", address: filepath.Join(dir, "api.sock")}
+
+	// A stale socket from a previous run is replaced
+	stale, err := net.Listen("unix", addr.address)
+	if err != nil {
+		t.Fatalf("Failed to create stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := addr.listenUnix()
+	if err != nil {
+		t.Fatalf("listenUnix failed: %v", err)
+	}
+	go http.Serve(listener, am.echoServer)
+	defer listener.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", addr.address)
+		},
+	}}
+	resp, err := client.Get("http://unix/openapi.json")
+	if err != nil {
+		t.Fatalf("Request over Unix socket failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 over the Unix socket, got %d", resp.StatusCode)
+	}
+
+	regular := filepath.Join(dir, "regular")
+	os.WriteFile(regular, nil, 0600)
+	if _, err := (apiAddress{network: "unix", address: regular}).listenUnix(); err == nil {
+		t.Error("Expected listenUnix to refuse replacing a regular file")
+	}
+}
+
+// TestServeAPIBindFailure tests that a port in use fails startup after retries and is reported
+func TestServeAPIBindFailure(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	defer func(delays []time.Duration) { apiBindRetryDelays = delays }(apiBindRetryDelays)
+	apiBindRetryDelays = []time.Duration{10 * time.Millisecond}
+
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to occupy a port: %v", err)
+	}
+	defer taken.Close()
+
+	am.apiAddr = apiAddress{network: "tcp", address: taken.Addr().String()}
+	if err := am.serveAPI(); err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Fatalf("Expected address in use error, got %v", err)
+	}
+	if running, apiError := am.apiServer.get(); running || apiError == "" {
+		t.Errorf("Expected API reported as failed, got running=%t error=%q", running, apiError)
+	}
+
+	am.echoServer = echo.New()
+	am.apiAddr = apiAddress{network: "tcp", address: "127.0.0.1:0"}
+	if err := am.serveAPI(); err != nil {
+		t.Fatalf("serveAPI failed: %v", err)
+	}
+	if running, _ := am.apiServer.get(); !running {
+		t.Error("Expected API reported as running")
+	}
+
+	am.echoServer.Shutdown(context.Background())
+	for i := 0; i < 100; i++ {
+		if running, _ := am.apiServer.get(); !running {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if running, apiError := am.apiServer.get(); running || apiError != "" {
+		t.Errorf("Expected clean stop after shutdown, got running=%t error=%q", running, apiError)
+	}
+}
+
+// TestSelfTest tests POST /admin/selftest with a local HTTP target and an unreachable webhook
+func TestSelfTest(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+	am.configManager.Set("API_KEY", "test-api-key")
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+	defer func(u string) { selfTestHTTPURL = u }(selfTestHTTPURL)
+	selfTestHTTPURL = target.URL
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve a port: %v", err)
+	}
+	closed.Close()
+	db.SaveWebhook(&storage.Webhook{ID: "wh1", Name: "Down sink", URL: "http://" + closed.Addr().String() + "/hook", Enabled: true})
+
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/admin/selftest", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report SelfTestReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	statuses := make(map[string]string)
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	// icmp depends on the host's capabilities, so it isn't asserted
+	for name, want := range map[string]string{"storage": SelfTestPass, "telegram": SelfTestSkip, "http": SelfTestPass, "webhooks": SelfTestFail} {
+		if statuses[name] != want {
+			t.Errorf("Expected %s check to %s, got %q", name, want, statuses[name])
+		}
+	}
+	if report.Passed {
+		t.Error("Expected report to fail with an unreachable webhook")
+	}
+}
+
+// TestDebugEndpoints tests /debug/runtime and the PPROF_ENABLED gate on /debug/pprof/
+func TestDebugEndpoints(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+	am.configManager.Set("API_KEY", "test-api-key")
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/debug/runtime", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report RuntimeDebug
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if report.Goroutines == 0 || report.Heap.Alloc == 0 || report.Bolt == nil || report.Bolt.FileSize == 0 {
+		t.Errorf("Expected goroutine, heap and bolt stats, got %+v", report)
+	}
+
+	// Debug endpoints need admin scope
+	_, readKey, _ := db.CreateAPIKey("viewer", storage.ScopeRead, "", nil)
+	if rec := makeRequest(t, am, http.MethodGet, "/api/v1/debug/runtime", "", readKey); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a read key, got %d", rec.Code)
+	}
+
+	if rec := makeRequest(t, am, http.MethodGet, "/api/v1/debug/pprof/", "", "test-api-key"); rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 while PPROF_ENABLED is off, got %d", rec.Code)
+	}
+	am.configManager.Set("PPROF_ENABLED", "true")
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/debug/pprof/", "", "test-api-key")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Errorf("Expected pprof index, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/debug/pprof/goroutine?debug=1", "", "test-api-key")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine profile") {
+		t.Errorf("Expected goroutine profile, got %d", rec.Code)
+	}
+}
+
+// TestStatusPerformance tests the check loop metrics in GET /status
+func TestStatusPerformance(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+	am.botProcess.running = true
+
+	source := &storage.Source{Name: "Web", Type: "http", Target: target.URL, CheckInterval: time.Minute, CurrentStatus: -1, Enabled: true}
+	db.SaveSource(source)
+	for i := 0; i < 2; i++ {
+		if rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources/"+source.ID+"/check", "", "test-api-key"); rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/status", "", "test-api-key")
+	var resp struct {
+		Bot struct {
+			Performance struct {
+				ChecksPerMinute        int                `json:"checks_per_minute"`
+				AvgCheckDurationMs     map[string]float64 `json:"avg_check_duration_ms"`
+				ActiveMonitors         int                `json:"active_monitors"`
+				NotificationQueueDepth int64              `json:"notification_queue_depth"`
+				LastStorageWrite       *struct {
+					Operation string `json:"operation"`
+				} `json:"last_storage_write"`
+			} `json:"performance"`
+		} `json:"bot"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	perf := resp.Bot.Performance
+	if perf.ChecksPerMinute != 2 {
+		t.Errorf("Expected 2 checks in the last minute, got %d", perf.ChecksPerMinute)
+	}
+	if _, ok := perf.AvgCheckDurationMs["http"]; !ok || len(perf.AvgCheckDurationMs) != 1 {
+		t.Errorf("Expected an average duration for http only, got %v", perf.AvgCheckDurationMs)
+	}
+	// The second check found no change; with no flush interval its check time is written directly
+	if perf.LastStorageWrite == nil || perf.LastStorageWrite.Operation != "update_source_status" {
+		t.Errorf("Expected last storage write to be update_source_status, got %+v", perf.LastStorageWrite)
+	}
+	if perf.ActiveMonitors != 0 || perf.NotificationQueueDepth != 0 {
+		t.Errorf("Expected no monitor goroutines or queued notifications, got %+v", perf)
+	}
+}
+
+// TestDeadMansSwitch tests that heartbeats are only sent while the monitor runs
+func TestDeadMansSwitch(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer receiver.Close()
+
+	am.configManager.Set("API_KEY", "test-api-key")
+	am.configManager.Set("HEARTBEAT_URL", receiver.URL)
+	cfg, err := am.configManager.AsConfig()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+
+	am.sendDeadMansHeartbeat(context.Background(), cfg)
+	if hits.Load() != 0 {
+		t.Errorf("Expected no heartbeat while the monitor is stopped, got %d", hits.Load())
+	}
+
+	am.botProcess.running = true
+	am.sendDeadMansHeartbeat(context.Background(), cfg)
+	if hits.Load() != 1 {
+		t.Errorf("Expected 1 heartbeat while the monitor runs, got %d", hits.Load())
+	}
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/status", "", "test-api-key")
+	var resp struct {
+		DeadMansSwitch map[string]interface{} `json:"dead_mans_switch"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if resp.DeadMansSwitch["last_sent"] == nil || resp.DeadMansSwitch["last_error"] != nil {
+		t.Errorf("Expected a successful heartbeat in /status, got %v", resp.DeadMansSwitch)
+	}
+}
+
+// TestWatchdog tests that /health reports stalled checks and recovers once they resume
+func TestWatchdog(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	am.configManager.Set("API_KEY", "test-api-key")
+	cfg, err := am.configManager.AsConfig()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+	am.botProcess.running = true
+	am.botProcess.healthy = true
+	source := &storage.Source{Name: "Cron", Type: "webhook", CheckInterval: time.Hour, CurrentStatus: -1, Enabled: true}
+	db.SaveSource(source)
+	am.botProcess.monitor.AddSource(ctx, source)
+
+	health := func() (int, bool) {
+		rec := makeRequest(t, am, http.MethodGet, "/health", "", "")
+		var resp struct {
+			ChecksStalled bool `json:"checks_stalled"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return rec.Code, resp.ChecksStalled
+	}
+
+	am.checkWatchdog(ctx, cfg, time.Now())
+	if code, stalled := health(); code != http.StatusOK || stalled {
+		t.Fatalf("Expected healthy checks, got status %d, stalled %v", code, stalled)
+	}
+
+	// Three hourly intervals exceed the 5m default timeout
+	am.checkWatchdog(ctx, cfg, time.Now().Add(2*time.Hour))
+	if code, stalled := health(); code != http.StatusOK || stalled {
+		t.Fatalf("Expected no stall within 3 check intervals, got status %d, stalled %v", code, stalled)
+	}
+	am.checkWatchdog(ctx, cfg, time.Now().Add(4*time.Hour))
+	if code, stalled := health(); code != http.StatusServiceUnavailable || !stalled {
+		t.Fatalf("Expected stalled checks, got status %d, stalled %v", code, stalled)
+	}
+
+	am.checkWatchdog(ctx, cfg, time.Now())
+	if code, stalled := health(); code != http.StatusOK || stalled {
+		t.Fatalf("Expected recovery, got status %d, stalled %v", code, stalled)
+	}
+
+	events, err := db.GetSystemEvents(storage.SystemEventFilter{Type: storage.SystemEventWatchdog})
+	if err != nil {
+		t.Fatalf("Failed to get system events: %v", err)
+	}
+	if len(events) != 2 {
+		t.Errorf("Expected stall and recovery events, got %d", len(events))
+	}
+}
+
+// TestSourceSLO tests declaring an SLO on a source and reading its error budget
+func TestSourceSLO(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources",
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","slo":{"target":100}}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for a 100%% target, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/sources",
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","slo":{"target":99.9}}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created storage.Source
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.SLO == nil || created.SLO.Target != 99.9 || created.SLO.WindowDays != storage.DefaultSLOWindowDays {
+		t.Fatalf("Expected SLO 99.9%% over %d days, got %+v", storage.DefaultSLOWindowDays, created.SLO)
+	}
+
+	// Online for the last day except a 30 minute outage an hour ago
+	now := time.Now()
+	created.CreatedAt = now.Add(-24 * time.Hour)
+	db.SaveSource(&created)
+	db.SaveStatusChange(&storage.StatusChange{SourceID: created.ID, OldStatus: -1, NewStatus: 1, Timestamp: created.CreatedAt})
+	db.SaveStatusChange(&storage.StatusChange{SourceID: created.ID, OldStatus: 1, NewStatus: 0, Timestamp: now.Add(-90 * time.Minute)})
+	db.SaveStatusChange(&storage.StatusChange{SourceID: created.ID, OldStatus: 0, NewStatus: 1, Timestamp: now.Add(-60 * time.Minute)})
+
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/sources/"+created.ID+"/slo", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var budget storage.ErrorBudget
+	if err := json.Unmarshal(rec.Body.Bytes(), &budget); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if budget.DowntimeMs != (30*time.Minute).Milliseconds() || budget.BurnRate6h <= 0 {
+		t.Errorf("Expected 30m downtime and a 6h burn rate, got %+v", budget)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/slo", "", "test-api-key")
+	var budgets []storage.ErrorBudget
+	json.Unmarshal(rec.Body.Bytes(), &budgets)
+	if len(budgets) != 1 || budgets[0].SourceID != created.ID {
+		t.Errorf("Expected the one source with an SLO, got %+v", budgets)
+	}
+
+	// Removing the SLO
+	rec = makeRequest(t, am, http.MethodPut, "/api/v1/sources/"+created.ID,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","enabled":true,"slo":{"target":0}}`, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/sources/"+created.ID+"/slo", "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 without an SLO, got %d", rec.Code)
+	}
+}
+
+// TestSourceDurations tests the check duration percentiles of a source
+func TestSourceDurations(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer target.Close()
+
+	rec := makeRequest(t, am, http.MethodGet, "/sources/nonexistent/durations", "", "test-api-key")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503 without a monitor, got %d", rec.Code)
+	}
+
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+
+	// The target responds slower than 80% of the interval, so every check counts as slow
+	source := &storage.Source{Name: "Slow", Type: "http", Target: target.URL, CheckInterval: 20 * time.Millisecond, CurrentStatus: -1, Enabled: true}
+	db.SaveSource(source)
+	for i := 0; i < 3; i++ {
+		if rec := makeRequest(t, am, http.MethodPost, "/sources/"+source.ID+"/check", "", "test-api-key"); rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/sources/"+source.ID+"/durations", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp monitor.CheckDurations
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Samples != 3 || resp.SlowChecks != 3 || resp.IntervalMs != 20 {
+		t.Errorf("Expected 3 slow samples on a 20ms interval, got %+v", resp)
+	}
+	if resp.P50Ms < 20 || resp.P95Ms < resp.P50Ms || resp.MaxMs < resp.P95Ms {
+		t.Errorf("Expected ordered percentiles of at least 20ms, got %+v", resp)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/sources/nonexistent/durations", "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+}
+
+// TestResponseLimits tests that slow or wrongly sized responses fail http checks despite a 200
+func TestResponseLimits(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+		case "/empty":
+		default:
+			w.Write([]byte(strings.Repeat("x", 2048)))
+		}
+	}))
+	defer target.Close()
+
+	invalid := []string{
+		`{"name":"Ping","type":"ping","target":"8.8.8.8","check_interval":"1m","response_limits":{"max_response_ms":500}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","response_limits":{"min_body_bytes":100,"max_body_bytes":10}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","response_limits":{"max_response_ms":-1}}`,
+	}
+	for _, body := range invalid {
+		if rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	tests := []struct {
+		path      string
+		limits    string
+		wantError string // Prefix; "" = online
+	}{
+		{"/", `{"max_response_ms":1000,"min_body_bytes":1,"max_body_bytes":4096}`, ""},
+		{"/slow", `{"max_response_ms":10}`, "HTTP 200 but slow response"},
+		{"/empty", `{"min_body_bytes":1}`, "HTTP 200 but body too small: 0 bytes"},
+		{"/", `{"max_body_bytes":1024}`, "HTTP 200 but body too large: more than 1024 bytes"},
+	}
+	ids := make([]string, len(tests))
+	for i, tt := range tests {
+		body := fmt.Sprintf(`{"name":"Limits %d","type":"http","target":"%s%s","check_interval":"1m","response_limits":%s}`, i, target.URL, tt.path, tt.limits)
+		rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key")
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var created storage.Source
+		json.Unmarshal(rec.Body.Bytes(), &created)
+		if created.ResponseLimits.IsZero() {
+			t.Fatalf("Expected response limits %s, got none", tt.limits)
+		}
+		ids[i] = created.ID
+	}
+
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+	for i, tt := range tests {
+		rec := makeRequest(t, am, http.MethodPost, "/sources/"+ids[i]+"/check", "", "test-api-key")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var result CheckSourceResponse
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		if tt.wantError == "" {
+			if result.Status != 1 || result.Error != "" {
+				t.Errorf("%s with %s: expected online, got %+v", tt.path, tt.limits, result)
+			}
+		} else if result.Status != 0 || !strings.HasPrefix(result.Error, tt.wantError) {
+			t.Errorf("%s with %s: expected offline with %q, got %+v", tt.path, tt.limits, tt.wantError, result)
+		}
+	}
+	am.botProcess.monitor = nil
+
+	// An empty object removes the limits; omitting them keeps them
+	source := &storage.Source{Name: "Kept", Type: "http", Target: target.URL, CheckInterval: time.Minute, Enabled: true,
+		ResponseLimits: &storage.ResponseLimits{MaxResponseMs: 1000}}
+	db.SaveSource(source)
+	update := fmt.Sprintf(`{"name":"Kept","type":"http","target":"%s","check_interval":"1m","enabled":true}`, target.URL)
+	rec := makeRequest(t, am, http.MethodPut, "/api/v1/sources/"+source.ID, update, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if stored, _ := db.GetSource(source.ID); stored.ResponseLimits == nil || stored.ResponseLimits.MaxResponseMs != 1000 {
+		t.Errorf("Expected the limits to be kept, got %+v", stored.ResponseLimits)
+	}
+	update = fmt.Sprintf(`{"name":"Kept","type":"http","target":"%s","check_interval":"1m","enabled":true,"response_limits":{}}`, target.URL)
+	rec = makeRequest(t, am, http.MethodPut, "/api/v1/sources/"+source.ID, update, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if stored, _ := db.GetSource(source.ID); stored.ResponseLimits != nil {
+		t.Errorf("Expected the limits to be removed, got %+v", stored.ResponseLimits)
+	}
+}
+
+// TestResponseSchema tests that http responses violating a source's JSON Schema fail checks
+func TestResponseSchema(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/degraded":
+			w.Write([]byte(`{"status":"degraded","checks":[{"name":"db","ok":false}]}`))
+		case "/html":
+			w.Write([]byte(`<html>Maintenance</html>`))
+		case "/missing":
+			w.Write([]byte(`{"checks":[]}`))
+		default:
+			w.Write([]byte(`{"status":"ok","checks":[{"name":"db","ok":true}]}`))
+		}
+	}))
+	defer target.Close()
+
+	schema := `{"type":"object","required":["status"],"properties":{"status":{"enum":["ok"]},` +
+		`"checks":{"type":"array","items":{"type":"object","properties":{"ok":{"const":true}}}}}}`
+
+	invalid := []string{
+		`{"name":"Ping","type":"ping","target":"8.8.8.8","check_interval":"1m","response_schema":` + schema + `}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","response_schema":{"type":"float"}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","response_schema":{"$ref":"#/defs/a"}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","response_schema":"ok"}`,
+	}
+	for _, body := range invalid {
+		if rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	tests := []struct {
+		path      string
+		wantError string // "" = online
+	}{
+		{"/", ""},
+		{"/degraded", `HTTP 200 but response violates the schema: /checks/0/ok: expected true, got false`},
+		{"/html", "HTTP 200 but response violates the schema: response is not valid JSON"},
+		{"/missing", `HTTP 200 but response violates the schema: missing required property "status"`},
+	}
+	ids := make([]string, len(tests))
+	for i, tt := range tests {
+		body := fmt.Sprintf(`{"name":"Schema %d","type":"http","target":"%s%s","check_interval":"1m","response_schema":%s}`, i, target.URL, tt.path, schema)
+		rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key")
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var created storage.Source
+		json.Unmarshal(rec.Body.Bytes(), &created)
+		if string(created.ResponseSchema) != schema {
+			t.Fatalf("Expected the schema to be stored, got %s", created.ResponseSchema)
+		}
+		ids[i] = created.ID
+	}
+
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+	for i, tt := range tests {
+		rec := makeRequest(t, am, http.MethodPost, "/sources/"+ids[i]+"/check", "", "test-api-key")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var result CheckSourceResponse
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		if tt.wantError == "" {
+			if result.Status != 1 || result.Error != "" {
+				t.Errorf("%s: expected online, got %+v", tt.path, result)
+			}
+			continue
+		}
+		if result.Status != 0 || !strings.HasPrefix(result.Error, tt.wantError) {
+			t.Errorf("%s: expected offline with %q, got %+v", tt.path, tt.wantError, result)
+		}
+		// The violation is kept as the source's error
+		if stored, _ := db.GetSource(ids[i]); stored.LastError != result.Error {
+			t.Errorf("%s: expected last_error %q, got %q", tt.path, result.Error, stored.LastError)
+		}
+	}
+	am.botProcess.monitor = nil
+
+	// null removes the schema
+	update := fmt.Sprintf(`{"name":"Schema 1","type":"http","target":"%s/degraded","check_interval":"1m","enabled":true,"response_schema":null}`, target.URL)
+	rec := makeRequest(t, am, http.MethodPut, "/api/v1/sources/"+ids[1], update, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if stored, _ := db.GetSource(ids[1]); stored.ResponseSchema != nil {
+		t.Errorf("Expected the schema to be removed, got %s", stored.ResponseSchema)
+	}
+}
+
+// TestRedirectPolicy tests following, refusing and requiring redirects in http checks
+func TestRedirectPolicy(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/parked":
+			http.Redirect(w, r, "/parking", http.StatusFound)
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusFound)
+		case "/c":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer target.Close()
+
+	invalid := []string{
+		`{"name":"Ping","type":"ping","target":"8.8.8.8","check_interval":"1m","redirect_policy":{"mode":"none"}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","redirect_policy":{"mode":"sometimes"}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","redirect_policy":{"mode":"none","max_redirects":3}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","redirect_policy":{"mode":"require"}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","redirect_policy":{"mode":"follow","target":"https://example.com/login"}}`,
+	}
+	for _, body := range invalid {
+		if rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	tests := []struct {
+		path      string
+		policy    string
+		wantError string // Prefix; "" = online
+	}{
+		{"/parked", `null`, ""},
+		{"/parked", `{"mode":"none"}`, "HTTP 302 redirect to /parking, but redirects are not followed"},
+		{"/ok", `{"mode":"none"}`, ""},
+		{"/a", `{"mode":"follow","max_redirects":2}`, "request failed:"},
+		{"/a", `{"mode":"follow","max_redirects":3}`, ""},
+		{"/a", `{"mode":"require","target":"` + target.URL + `/login"}`, ""},
+		{"/parked", `{"mode":"require","target":"` + target.URL + `/login"}`, "HTTP 200 from " + target.URL + "/parking, expected a redirect to " + target.URL + "/login"},
+	}
+	ids := make([]string, len(tests))
+	for i, tt := range tests {
+		body := fmt.Sprintf(`{"name":"Redirect %d","type":"http","target":"%s%s","check_interval":"1m","redirect_policy":%s}`, i, target.URL, tt.path, tt.policy)
+		rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key")
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var created storage.Source
+		json.Unmarshal(rec.Body.Bytes(), &created)
+		ids[i] = created.ID
+	}
+
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+	for i, tt := range tests {
+		rec := makeRequest(t, am, http.MethodPost, "/sources/"+ids[i]+"/check", "", "test-api-key")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var result CheckSourceResponse
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		if tt.wantError == "" {
+			if result.Status != 1 || result.Error != "" {
+				t.Errorf("%s with %s: expected online, got %+v", tt.path, tt.policy, result)
+			}
+		} else if result.Status != 0 || !strings.HasPrefix(result.Error, tt.wantError) {
+			t.Errorf("%s with %s: expected offline with %q, got %+v", tt.path, tt.policy, tt.wantError, result)
+		}
+	}
+	am.botProcess.monitor = nil
+
+	// An empty policy restores the default
+	update := fmt.Sprintf(`{"name":"Redirect 1","type":"http","target":"%s/parked","check_interval":"1m","enabled":true,"redirect_policy":{}}`, target.URL)
+	rec := makeRequest(t, am, http.MethodPut, "/api/v1/sources/"+ids[1], update, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if stored, _ := db.GetSource(ids[1]); stored.RedirectPolicy != nil {
+		t.Errorf("Expected the policy to be removed, got %+v", stored.RedirectPolicy)
+	}
+}
+
+// TestTLSPolicy tests validating TLS policies and that checks with one still verify certificates
+func TestTLSPolicy(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+	untrusted := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer untrusted.Close()
+
+	invalid := []string{
+		`{"name":"Ping","type":"ping","target":"8.8.8.8","check_interval":"1m","tls_policy":{"min_version":"1.2"}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","tls_policy":{"min_version":"2"}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","tls_policy":{"blocked_ciphers":["TLS_NOPE"]}}`,
+	}
+	for _, body := range invalid {
+		if rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources",
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","tls_policy":{"min_version":"1.2","blocked_ciphers":["insecure","tls_rsa_with_aes_128_cbc_sha"]}}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created storage.Source
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.TLSPolicy == nil || created.TLSPolicy.MinVersion != "1.2" ||
+		!slices.Equal(created.TLSPolicy.BlockedCiphers, []string{"insecure", "TLS_RSA_WITH_AES_128_CBC_SHA"}) {
+		t.Fatalf("Expected the normalized policy, got %+v", created.TLSPolicy)
+	}
+
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+	defer func() { am.botProcess.monitor = nil }()
+	for _, tt := range []struct {
+		url       string
+		wantError string
+	}{
+		{plain.URL, "not served over TLS"},
+		{untrusted.URL, "request failed:"}, // The audit transport accepts weak TLS but not unknown certificates
+	} {
+		source := &storage.Source{Name: tt.url, Type: "http", Target: tt.url, CheckInterval: time.Minute, CurrentStatus: -1, Enabled: true,
+			TLSPolicy: &storage.TLSPolicy{MinVersion: "1.2"}}
+		db.SaveSource(source)
+		rec := makeRequest(t, am, http.MethodPost, "/sources/"+source.ID+"/check", "", "test-api-key")
+		var result CheckSourceResponse
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		if result.Status != 0 || !strings.HasPrefix(result.Error, tt.wantError) {
+			t.Errorf("%s: expected offline with %q, got %+v", tt.url, tt.wantError, result)
+		}
+	}
+}
+
+// TestSourceResolver tests per-source DNS resolvers
+func TestSourceResolver(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	invalid := []string{
+		`{"name":"Hook","type":"webhook","check_interval":"1m","resolver":"1.1.1.1"}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","resolver":"dns.example.com"}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","resolver":"1.1.1.1:0"}`,
+	}
+	for _, body := range invalid {
+		if rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources",
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","resolver":"SYSTEM"}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created storage.Source
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.Resolver != "system" {
+		t.Fatalf("Expected resolver system, got %q", created.Resolver)
+	}
+	rec = makeRequest(t, am, http.MethodPut, "/api/v1/sources/"+created.ID,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","enabled":true,"resolver":""}`, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if source, _ := db.GetSource(created.ID); source.Resolver != "" {
+		t.Errorf("Expected the resolver removed, got %q", source.Resolver)
+	}
+
+	// A DNS server that resolves every A query to 127.0.0.1
+	dns, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer dns.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := dns.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			end := 12
+			for end < n && buf[end] != 0 {
+				end += int(buf[end]) + 1
+			}
+			end += 5 // Root label, type and class
+			if end > n {
+				continue
+			}
+			answer := append([]byte(nil), buf[:end]...)
+			answer[2], answer[3] = 0x81, 0x80                         // Response, recursion available
+			answer[6], answer[7], answer[10], answer[11] = 0, 0, 0, 0 // No answers or additional records yet
+			if buf[end-4] == 0 && buf[end-3] == 1 {                   // Type A
+				answer[7] = 1
+				answer = append(answer, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 127, 0, 0, 1)
+			}
+			dns.WriteTo(answer, addr)
+		}
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	source := &storage.Source{Name: "Internal", Type: "http", Target: "http://status.internal.test:" + port, CheckInterval: time.Minute,
+		CurrentStatus: -1, Enabled: true, Resolver: dns.LocalAddr().String()}
+	db.SaveSource(source)
+
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+	defer func() { am.botProcess.monitor = nil }()
+	rec = makeRequest(t, am, http.MethodPost, "/sources/"+source.ID+"/check", "", "test-api-key")
+	var result CheckSourceResponse
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if result.Status != 1 {
+		t.Errorf("Expected the host resolved by the source's resolver, got %+v", result)
+	}
+}
+
+// TestAddressFamily tests restricting checks to an address family
+func TestAddressFamily(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	invalid := []string{
+		`{"name":"Hook","type":"webhook","check_interval":"1m","address_family":"ipv4"}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","address_family":"ipv5"}`,
+	}
+	for _, body := range invalid {
+		if rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources",
+		`{"name":"Router","type":"ping","target":"router.example.com","check_interval":"1m","address_family":"IPv4"}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created storage.Source
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.AddressFamily != storage.AddressFamilyIPv4 {
+		t.Fatalf("Expected address family ipv4, got %q", created.AddressFamily)
+	}
+	rec = makeRequest(t, am, http.MethodPut, "/api/v1/sources/"+created.ID,
+		`{"name":"Router","type":"webhook","check_interval":"1m","enabled":true}`, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if source, _ := db.GetSource(created.ID); source.AddressFamily != "" {
+		t.Errorf("Expected the address family cleared for a webhook source, got %q", source.AddressFamily)
+	}
+
+	// Listens on IPv4 only, so localhost is reachable over IPv4 alone
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	target := "http://localhost:" + port
+
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+	defer func() { am.botProcess.monitor = nil }()
+	for _, tt := range []struct {
+		family string
+		want   int
+	}{
+		{storage.AddressFamilyIPv4, 1},
+		{storage.AddressFamilyIPv6, 0},
+		{storage.AddressFamilyPreferIPv6, 1}, // Falls back to IPv4
+	} {
+		source := &storage.Source{Name: tt.family, Type: "http", Target: target, CheckInterval: time.Minute, CurrentStatus: -1, Enabled: true,
+			AddressFamily: tt.family}
+		db.SaveSource(source)
+		rec := makeRequest(t, am, http.MethodPost, "/sources/"+source.ID+"/check", "", "test-api-key")
+		var result CheckSourceResponse
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		if result.Status != tt.want {
+			t.Errorf("%s: expected status %d, got %+v", tt.family, tt.want, result)
+		}
+	}
+}
+
+// TestSourceProxy tests checking http sources through a SOCKS5 proxy
+func TestSourceProxy(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	invalid := []string{
+		`{"name":"Router","type":"ping","target":"10.0.0.1","check_interval":"1m","proxy":"socks5://127.0.0.1:9050"}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","proxy":"http://127.0.0.1:8080"}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","proxy":"socks5://127.0.0.1"}`,
+	}
+	for _, body := range invalid {
+		if rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// A SOCKS5 proxy without authentication that connects every request to server,
+	// recording the requested host
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer proxy.Close()
+	requested := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := proxy.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 262)
+				if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+					return
+				}
+				io.ReadFull(conn, buf[:buf[1]]) // Methods
+				conn.Write([]byte{5, 0})
+				if _, err := io.ReadFull(conn, buf[:5]); err != nil || buf[3] != 3 { // Domain name
+					return
+				}
+				host := make([]byte, buf[4]+2)
+				io.ReadFull(conn, host)
+				requested <- string(host[:buf[4]])
+				upstream, err := net.Dial("tcp", server.Listener.Addr().String())
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources",
+		`{"name":"Hidden","type":"http","target":"http://exampleonionservice.onion","check_interval":"1m","proxy":"socks5h://`+proxy.Addr().String()+`"}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created storage.Source
+	json.Unmarshal(rec.Body.Bytes(), &created)
+
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+	rec = makeRequest(t, am, http.MethodPost, "/sources/"+created.ID+"/check", "", "test-api-key")
+	var result CheckSourceResponse
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if result.Status != 1 {
+		t.Errorf("Expected the onion service reached through the proxy, got %+v", result)
+	}
+	if host := <-requested; host != "exampleonionservice.onion" {
+		t.Errorf("Expected the proxy to resolve exampleonionservice.onion, got %q", host)
+	}
+	am.botProcess.monitor = nil
+
+	rec = makeRequest(t, am, http.MethodPut, "/api/v1/sources/"+created.ID,
+		`{"name":"Hidden","type":"http","target":"http://exampleonionservice.onion","check_interval":"1m","enabled":true,"proxy":""}`, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if source, _ := db.GetSource(created.ID); source.Proxy != "" {
+		t.Errorf("Expected the proxy removed, got %q", source.Proxy)
+	}
+}
+
+// TestSourceHeaders tests the User-Agent and request headers of http checks
+func TestSourceHeaders(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	invalid := []string{
+		`{"name":"Router","type":"ping","target":"10.0.0.1","check_interval":"1m","headers":{"User-Agent":"x"}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","headers":{"Bad Name":"x"}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","headers":{"X-Note":"a\r\nX-Injected: b"}}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","headers":{"x-note":"a","X-Note":"b"}}`,
+	}
+	for _, body := range invalid {
+		if rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	// Like a WAF, rejects Go's default User-Agent
+	received := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		if strings.HasPrefix(r.UserAgent(), "Go-http-client") {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources",
+		`{"name":"API","type":"http","target":"`+server.URL+`","check_interval":"1m","headers":{"x-trace":"1","X-Team":""}}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created storage.Source
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if !maps.Equal(created.Headers, map[string]string{"X-Trace": "1", "X-Team": ""}) {
+		t.Fatalf("Expected canonical header names, got %v", created.Headers)
+	}
+
+	check := func() CheckSourceResponse {
+		rec := makeRequest(t, am, http.MethodPost, "/sources/"+created.ID+"/check", "", "test-api-key")
+		var result CheckSourceResponse
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		return result
+	}
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+	if result := check(); result.Status != 0 || !strings.Contains(result.Error, "403") {
+		t.Errorf("Expected Go's User-Agent to be rejected, got %+v", result)
+	}
+	<-received
+
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second, HTTPUserAgent: "OutageMonitor/1.0",
+		HTTPHeaders: "X-Env=prod,X-Team=ops"}, nil)
+	if result := check(); result.Status != 1 {
+		t.Errorf("Expected online with HTTP_USER_AGENT, got %+v", result)
+	}
+	headers := <-received
+	if headers.Get("User-Agent") != "OutageMonitor/1.0" || headers.Get("X-Env") != "prod" || headers.Get("X-Trace") != "1" ||
+		headers.Values("X-Team") != nil {
+		t.Errorf("Expected HTTP_HEADERS, HTTP_USER_AGENT and the source's headers without X-Team, got %v", headers)
+	}
+	am.botProcess.monitor = nil
+
+	rec = makeRequest(t, am, http.MethodPut, "/api/v1/sources/"+created.ID,
+		`{"name":"API","type":"http","target":"`+server.URL+`","check_interval":"1m","enabled":true,"headers":{}}`, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if source, _ := db.GetSource(created.ID); source.Headers != nil {
+		t.Errorf("Expected the headers removed, got %v", source.Headers)
+	}
+}
+
+// TestHTTPVersion tests requiring HTTP/2 or an HTTP/3 advertisement
+func TestHTTPVersion(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	for _, body := range []string{
+		`{"name":"Router","type":"ping","target":"10.0.0.1","check_interval":"1m","http_version":"2"}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","http_version":"1.0"}`,
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","http_version":"3"}`,
+	} {
+		if rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	http1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", `h2=":443"; ma=86400`)
+	}))
+	defer http1.Close()
+	h2c := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", `h3=":443"; ma=86400, h3-29=":443"`)
+	}))
+	h2c.Config.Protocols = new(http.Protocols)
+	h2c.Config.Protocols.SetHTTP1(true)
+	h2c.Config.Protocols.SetUnencryptedHTTP2(true)
+	h2c.Start()
+	defer h2c.Close()
+
+	am.botProcess.monitor = monitor.New(db, &config.Config{HTTPTimeout: 5 * time.Second}, nil)
+	defer func() { am.botProcess.monitor = nil }()
+	for _, tt := range []struct {
+		url       string
+		version   string
+		wantError string // Prefix; empty = online
+	}{
+		{http1.URL, storage.HTTPVersion2, "request failed:"}, // Prior knowledge fails against HTTP/1.1
+		{h2c.URL, storage.HTTPVersion2, ""},
+		{http1.URL, storage.HTTPVersion3Advertised, `HTTP/3 not advertised: Alt-Svc is "h2=\":443\"; ma=86400"`},
+		{h2c.URL, storage.HTTPVersion3Advertised, ""},
+	} {
+		source := &storage.Source{Name: tt.url + tt.version, Type: "http", Target: tt.url, CheckInterval: time.Minute, CurrentStatus: -1,
+			Enabled: true, HTTPVersion: tt.version}
+		db.SaveSource(source)
+		rec := makeRequest(t, am, http.MethodPost, "/sources/"+source.ID+"/check", "", "test-api-key")
+		var result CheckSourceResponse
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		if online := tt.wantError == ""; online != (result.Status == 1) || !strings.HasPrefix(result.Error, tt.wantError) {
+			t.Errorf("%s requiring %s: expected error %q, got %+v", tt.url, tt.version, tt.wantError, result)
+		}
+	}
+}
+
+// TestHTTPTransportSettings tests the HTTP_* transport settings of checks
+func TestHTTPTransportSettings(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+	am.configManager.Set("API_KEY", "test-api-key")
+
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/config/validate",
+		`{"HTTP_MAX_IDLE_CONNS":"-1","HTTP_MAX_CONNS_PER_HOST":"many","HTTP_DISABLE_KEEP_ALIVES":"maybe","HTTP_TLS_HANDSHAKE_TIMEOUT":"-5s"}`, "test-api-key")
+	var validation ConfigValidationResponse
+	json.Unmarshal(rec.Body.Bytes(), &validation)
+	found := make(map[string]bool)
+	for _, p := range validation.Problems {
+		found[p.Key] = p.Severity == "error"
+	}
+	for _, key := range []string{"HTTP_MAX_IDLE_CONNS", "HTTP_MAX_CONNS_PER_HOST", "HTTP_DISABLE_KEEP_ALIVES", "HTTP_TLS_HANDSHAKE_TIMEOUT"} {
+		if !found[key] {
+			t.Errorf("Expected an error for %s, got %+v", key, validation.Problems)
+		}
+	}
+
+	var connections atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	source := &storage.Source{Name: "API", Type: "http", Target: server.URL, CheckInterval: time.Minute, CurrentStatus: -1, Enabled: true}
+	db.SaveSource(source)
+
+	cfg := &config.Config{HTTPTimeout: 5 * time.Second, HTTPMaxIdleConns: 100, HTTPTLSHandshakeTimeout: 10 * time.Second}
+	mon := monitor.New(db, cfg, nil)
+	am.botProcess.monitor = mon
+	defer func() { am.botProcess.monitor = nil }()
+	checkTwice := func() int32 {
+		connections.Store(0)
+		for i := 0; i < 2; i++ {
+			rec := makeRequest(t, am, http.MethodPost, "/sources/"+source.ID+"/check", "", "test-api-key")
+			var result CheckSourceResponse
+			json.Unmarshal(rec.Body.Bytes(), &result)
+			if result.Status != 1 {
+				t.Fatalf("Expected online, got %+v", result)
+			}
+		}
+		return connections.Load()
+	}
+	if n := checkTwice(); n != 1 {
+		t.Errorf("Expected checks to reuse the connection, got %d connections", n)
+	}
+
+	updated := *cfg
+	updated.HTTPDisableKeepAlives = true
+	mon.UpdateConfig(&updated)
+	if n := checkTwice(); n != 2 {
+		t.Errorf("Expected a connection per check with HTTP_DISABLE_KEEP_ALIVES, got %d connections", n)
+	}
+}
+
+// TestProbeAgents tests assigning sources to probe locations and aggregating their results
+func TestProbeAgents(t *testing.T) {
+	am, _, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources",
+		`{"name":"Hook","type":"webhook","check_interval":"1m","locations":["eu"]}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for locations on a webhook source, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/sources",
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","locations":["local"]}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for the reserved location, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/sources",
+		`{"name":"API","type":"http","target":"https://example.com","check_interval":"1m","locations":["EU","us"]}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var source storage.Source
+	json.Unmarshal(rec.Body.Bytes(), &source)
+	if len(source.Locations) != 2 || source.Locations[0] != "eu" {
+		t.Fatalf("Expected normalized locations [eu us], got %v", source.Locations)
+	}
+
+	pull := func(location string) []AgentSource {
+		rec := makeRequest(t, am, http.MethodGet, "/api/v1/agents/sources?location="+location, "", "test-api-key")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var sources []AgentSource
+		json.Unmarshal(rec.Body.Bytes(), &sources)
+		return sources
+	}
+	if sources := pull("eu"); len(sources) != 1 || sources[0].ID != source.ID || sources[0].CheckInterval != "1m0s" {
+		t.Errorf("Expected the source assigned to eu, got %+v", sources)
+	}
+	if sources := pull("asia"); len(sources) != 0 {
+		t.Errorf("Expected no sources for asia, got %+v", sources)
+	}
+	if rec := makeRequest(t, am, http.MethodGet, "/api/v1/agents/sources", "", "test-api-key"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a location, got %d", rec.Code)
+	}
+
+	report := func(location string, status int) AgentReportResponse {
+		body := fmt.Sprintf(`{"location":%q,"results":[{"source_id":%q,"status":%d,"latency_ms":42},{"source_id":"nonexistent","status":1}]}`,
+			location, source.ID, status)
+		rec := makeRequest(t, am, http.MethodPost, "/api/v1/agents/results", body, "test-api-key")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp AgentReportResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+	if resp := report("eu", 0); resp.Accepted != 1 || resp.Rejected != 1 {
+		t.Errorf("Expected 1 accepted and 1 rejected result, got %+v", resp)
+	}
+	report("us", 1)
+	if resp := report("asia", 1); resp.Accepted != 0 {
+		t.Errorf("Expected results from an unassigned location to be rejected, got %+v", resp)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/sources/"+source.ID+"/locations", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var locations SourceLocationsResponse
+	json.Unmarshal(rec.Body.Bytes(), &locations)
+	if len(locations.Locations) != 3 || locations.Locations[0].Location != storage.LocalLocation ||
+		locations.Locations[1].Status != 0 || locations.Locations[2].LatencyMs != 42 {
+		t.Errorf("Expected local, eu (down) and us (up), got %+v", locations.Locations)
+	}
+	if locations.Summary != "down from eu; up from us" {
+		t.Errorf("Expected summary %q, got %q", "down from eu; up from us", locations.Summary)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/agents", "", "test-api-key")
+	var agents []AgentInfo
+	json.Unmarshal(rec.Body.Bytes(), &agents)
+	if len(agents) != 3 || agents[0].Location != "asia" || agents[1].Sources != 1 || agents[1].LastReport.IsZero() {
+		t.Errorf("Expected agents asia, eu and us, got %+v", agents)
+	}
+}
+
+// TestReadOnlyReplica tests serving a copy of the database without changing it
+func TestReadOnlyReplica(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "replica.db")
+	primary, err := storage.NewBoltDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	primary.SaveSource(&storage.Source{Name: "API", Type: "http", Target: "https://example.com", CheckInterval: time.Minute, CurrentStatus: 1, Enabled: true})
+	primary.SaveConfig("API_KEY", storage.HashConfigAPIKey("primary-key"), "test")
+	primary.Close()
+
+	db, err := storage.OpenReadOnly(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open replica: %v", err)
+	}
+	defer db.Close()
+
+	t.Setenv("API_KEY", "replica-key")
+	am := &AppManager{storage: db, events: monitor.NewEventBus(), echoServer: echo.New(), logger: logging.New("test")}
+	am.configManager = NewConfigManager(db)
+	if err := am.configManager.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg, err := am.configManager.AsConfig()
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	am.apiKey = cfg.APIKey
+	am.botProcess = NewBotProcess(db)
+	am.startReplica(cfg)
+	defer am.maintenanceCancel()
+	am.setupBaseMiddleware()
+	am.echoServer.Use(am.readOnlyMiddleware)
+	am.setupRoutes()
+
+	if rec := makeRequest(t, am, http.MethodGet, "/api/v1/sources", "", "primary-key"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the replica's own API_KEY to replace the primary's, got status %d", rec.Code)
+	}
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/sources", "", "replica-key")
+	var sources []*storage.Source
+	json.Unmarshal(rec.Body.Bytes(), &sources)
+	if rec.Code != http.StatusOK || len(sources) != 1 || sources[0].Name != "API" {
+		t.Fatalf("Expected the copied source, got status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/sources",
+		`{"name":"New","type":"http","target":"https://example.org","check_interval":"1m"}`, "replica-key")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for a change, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/health", "", "")
+	var health struct {
+		Status   string `json:"status"`
+		ReadOnly bool   `json:"read_only"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &health)
+	if rec.Code != http.StatusOK || health.Status != "healthy" || !health.ReadOnly {
+		t.Errorf("Expected a healthy read-only replica, got status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// A newer copy replacing the file is picked up on reload
+	writer, err := storage.NewBoltDB(dbPath + ".new")
+	if err != nil {
+		t.Fatalf("Failed to create copy: %v", err)
+	}
+	writer.Close()
+	if err := os.Rename(dbPath+".new", dbPath); err != nil {
+		t.Fatalf("Failed to replace copy: %v", err)
+	}
+	am.replica.size = -1 // The rename may keep the modification time at this resolution
+	am.reloadReplica()
+	if status := am.replicaStatus(); status.LastError != "" {
+		t.Fatalf("Expected the copy to reopen, got %q", status.LastError)
+	}
+	if sources, _ := db.GetAllSources(); len(sources) != 0 {
+		t.Errorf("Expected the empty copy after reload, got %d sources", len(sources))
+	}
+}
+
+// TestReports tests weekly reports in each format and their scheduled delivery
+func TestReports(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	now := time.Now()
+	from, to := reportBounds("week", now, 0)
+	if to.Sub(from) != 7*24*time.Hour || from.Weekday() != time.Monday || to.After(now) {
+		t.Fatalf("Expected the last completed Monday-to-Monday week, got %v to %v", from, to)
+	}
+
+	// API was down for 2h on the Tuesday of the last week; Site was up throughout
+	created := from.AddDate(0, 0, -30)
+	for _, source := range []*storage.Source{
+		{ID: "api", Name: "API", Type: "http", Target: "https://api.example.com", Tags: []string{"prod"}, Enabled: true, CreatedAt: created},
+		{ID: "site", Name: "Site", Type: "http", Target: "https://example.com", Enabled: true, CreatedAt: created},
+	} {
+		db.SaveSource(source)
+		db.SaveStatusChange(&storage.StatusChange{SourceID: source.ID, OldStatus: -1, NewStatus: 1, Timestamp: created})
+	}
+	db.SaveStatusChange(&storage.StatusChange{SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(30 * time.Hour)})
+	db.SaveStatusChange(&storage.StatusChange{SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(32 * time.Hour)})
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/reports/week", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if !report.From.Equal(from) || report.Summary.Sources != 2 || report.Summary.OutageCount != 1 {
+		t.Fatalf("Expected 1 outage across 2 sources from %v, got %+v", from, report)
+	}
+	if report.Summary.MTTRMs == nil || *report.Summary.MTTRMs != (2*time.Hour).Milliseconds() {
+		t.Errorf("Expected a 2h MTTR, got %v", report.Summary.MTTRMs)
+	}
+	if len(report.Sources) != 2 || report.Sources[0].Name != "API" {
+		t.Errorf("Expected API listed first with the lowest uptime, got %+v", report.Sources)
+	}
+	if report.Summary.MTBFMs == nil || len(report.Tags) != 1 || report.Tags[0].Tag != "prod" ||
+		report.Tags[0].MTTRMs == nil || *report.Tags[0].MTTRMs != (2*time.Hour).Milliseconds() {
+		t.Errorf("Expected an MTBF and a prod tag row with a 2h MTTR, got %+v and %+v", report.Summary, report.Tags)
+	}
+	if len(report.TopOutages) != 1 || report.TopOutages[0].SourceName != "API" {
+		t.Errorf("Expected the API outage as the longest, got %+v", report.TopOutages)
+	}
+	if len(report.MTTRTrend) != reportTrendPeriods || !report.MTTRTrend[reportTrendPeriods-1].From.Equal(from) {
+		t.Errorf("Expected a %d week trend ending with the report's week, got %+v", reportTrendPeriods, report.MTTRTrend)
+	}
+
+	// Tag filter
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/reports/week?tag=prod", "", "test-api-key")
+	json.Unmarshal(rec.Body.Bytes(), &report)
+	if report.Tag != "prod" || len(report.Sources) != 1 {
+		t.Errorf("Expected only the prod source, got %+v", report.Sources)
+	}
+
+	// Other formats
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/reports/week?format=markdown", "", "test-api-key")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "| API |") ||
+		!strings.Contains(rec.Header().Get("Content-Disposition"), "report-week-"+from.Format("2006-01-02")+".md") {
+		t.Errorf("Expected a Markdown attachment with a row for API, got %d %q: %s", rec.Code, rec.Header().Get("Content-Disposition"), rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/reports/week?format=html", "", "test-api-key")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<td>API</td>") {
+		t.Errorf("Expected an HTML report, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/reports/week?format=text", "", "test-api-key")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Weekly report") {
+		t.Errorf("Expected a text report, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for _, path := range []string{"/api/v1/reports/day", "/api/v1/reports/week?offset=-1", "/api/v1/reports/month?format=pdf"} {
+		if rec := makeRequest(t, am, http.MethodGet, path, "", "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", path, rec.Code)
+		}
+	}
+
+	// Scheduled delivery is recorded once per period
+	am.configManager.Set("API_KEY", "test-api-key")
+	am.configManager.Set("REPORT_PERIODS", "week")
+	cfg, err := am.configManager.AsConfig()
+	if err != nil {
+		t.Fatalf("AsConfig failed: %v", err)
+	}
+	am.sendScheduledReports(context.Background(), cfg, now)
+	if sent, _ := db.GetReportSent("week"); !sent.Equal(from) {
+		t.Errorf("Expected the week of %v recorded as sent, got %v", from, sent)
+	}
+	if sent, _ := db.GetReportSent("month"); !sent.IsZero() {
+		t.Errorf("Expected no monthly report without it in REPORT_PERIODS, got %v", sent)
+	}
+}
+
+// TestSourceCSVExport tests the history and daily metrics CSV exports
+func TestSourceCSVExport(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -3)
+	db.SaveSource(&storage.Source{ID: "api", Name: "API, public", Type: "http", Target: "https://example.com", Enabled: true, CreatedAt: day.AddDate(0, 0, -1)})
+	db.SaveStatusChange(&storage.StatusChange{SourceID: "api", OldStatus: -1, NewStatus: 1, Timestamp: day.AddDate(0, 0, -1)})
+	db.SaveStatusChange(&storage.StatusChange{SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: day.Add(6 * time.Hour), DurationMs: (30 * time.Hour).Milliseconds()})
+	db.SaveStatusChange(&storage.StatusChange{SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: day.Add(12 * time.Hour), DurationMs: (6 * time.Hour).Milliseconds()})
+
+	from := day.Format("2006-01-02")
+	to := day.AddDate(0, 0, 2).Format("2006-01-02")
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/sources/api/history.csv?from="+from+"&to="+to, "", "test-api-key")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("Expected a CSV response, got %d %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); !strings.Contains(disposition, "api-history-"+from+"-"+to+".csv") {
+		t.Errorf("Expected a dated attachment name, got %q", disposition)
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(rows) != 3 || rows[1][2] != "API, public" || rows[1][4] != "offline" || rows[2][5] != "21600000" {
+		t.Errorf("Expected a header and the two changes in range, got %v", rows)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/sources/api/metrics.csv?from="+from+"&to="+to, "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rows, err = csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	// Neither day is rolled up, so both are replayed
+	if len(rows) != 3 || rows[1][0] != from || rows[1][3] != "75.0000" || rows[1][5] != "21600000" || rows[1][6] != "1" || rows[2][3] != "100.0000" {
+		t.Errorf("Expected 75%% uptime with one 6h outage, then a clean day, got %v", rows)
+	}
+
+	if rec := makeRequest(t, am, http.MethodGet, "/api/v1/sources/api/metrics.csv?from="+to+"&to="+from, "", "test-api-key"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an inverted range, got %d", rec.Code)
+	}
+	if rec := makeRequest(t, am, http.MethodGet, "/api/v1/sources/missing/history.csv", "", "test-api-key"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown source, got %d", rec.Code)
+	}
+}
+
+// TestUptimeSummary tests the all-sources uptime table on /uptime
+func TestUptimeSummary(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	// API went down 2h ago for an hour; Site has been up throughout
+	now := time.Now()
+	created := now.AddDate(0, 0, -30)
+	for _, source := range []*storage.Source{
+		{ID: "api", Name: "API", Type: "http", Target: "https://api.example.com", Tags: []string{"prod"}, CurrentStatus: 1, Enabled: true, CreatedAt: created},
+		{ID: "site", Name: "Site", Type: "http", Target: "https://example.com", CurrentStatus: 1, Enabled: true, CreatedAt: created},
+	} {
+		db.SaveSource(source)
+		db.SaveStatusChange(&storage.StatusChange{SourceID: source.ID, OldStatus: -1, NewStatus: 1, Timestamp: created})
+	}
+	db.SaveStatusChange(&storage.StatusChange{SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: now.Add(-2 * time.Hour)})
+	db.SaveStatusChange(&storage.StatusChange{SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: now.Add(-time.Hour)})
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/uptime?period=7d", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var summary storage.UptimeSummary
+	if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(summary.Sources) != 2 || summary.Sources[0].SourceName != "API" || summary.OutageCount != 1 {
+		t.Fatalf("Expected 1 outage across API and Site, got %+v", summary)
+	}
+	if hours := time.Duration(summary.Sources[0].DowntimeMs) * time.Millisecond; hours < 59*time.Minute || hours > 61*time.Minute {
+		t.Errorf("Expected about 1h of API downtime, got %v", hours)
+	}
+	if summary.Sources[1].UptimePercent == nil || *summary.Sources[1].UptimePercent != 100 {
+		t.Errorf("Expected 100%% uptime for Site, got %v", summary.Sources[1].UptimePercent)
+	}
+	if summary.To.Sub(summary.From) != 7*24*time.Hour {
+		t.Errorf("Expected a 7 day period, got %v to %v", summary.From, summary.To)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/uptime?tag=prod", "", "test-api-key")
+	json.Unmarshal(rec.Body.Bytes(), &summary)
+	if len(summary.Sources) != 1 || summary.Sources[0].SourceID != "api" {
+		t.Errorf("Expected only the prod source, got %+v", summary.Sources)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/uptime?period=400d", "", "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a period over 366d, got %d", rec.Code)
+	}
+}
+
+// TestReliability tests MTTR and MTBF per source and tag on /reliability
+func TestReliability(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	// API had outages of 1h and 3h in the last day; Web had none
+	now := time.Now()
+	created := now.AddDate(0, 0, -30)
+	for _, source := range []*storage.Source{
+		{ID: "api", Name: "API", Type: "http", Target: "https://api.example.com", Tags: []string{"prod"}, CurrentStatus: 1, Enabled: true, CreatedAt: created},
+		{ID: "web", Name: "Web", Type: "http", Target: "https://example.com", Tags: []string{"prod"}, CurrentStatus: 1, Enabled: true, CreatedAt: created},
+	} {
+		db.SaveSource(source)
+		db.SaveStatusChange(&storage.StatusChange{SourceID: source.ID, OldStatus: -1, NewStatus: 1, Timestamp: created})
+	}
+	for _, change := range []*storage.StatusChange{
+		{SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: now.Add(-20 * time.Hour)},
+		{SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: now.Add(-19 * time.Hour)},
+		{SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: now.Add(-10 * time.Hour)},
+		{SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: now.Add(-7 * time.Hour)},
+	} {
+		db.SaveStatusChange(change)
+	}
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/reliability?windows=1d,7d", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report ReliabilityReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(report.Windows) != 2 || report.Windows[0].Window != "1d" || report.Windows[1].Window != "7d" {
+		t.Fatalf("Expected 1d and 7d windows, got %+v", report.Windows)
+	}
+	day := report.Windows[0]
+	if len(day.Sources) != 2 || day.Sources[0].Name != "API" {
+		t.Fatalf("Expected API and Web, got %+v", day.Sources)
+	}
+	api := day.Sources[0].Stats
+	if api.OutageCount != 2 || api.MTTRMs == nil || *api.MTTRMs != (2*time.Hour).Milliseconds() {
+		t.Errorf("Expected 2 outages with a 2h MTTR for API, got %+v", api)
+	}
+	if api.MTBFMs == nil || *api.MTBFMs != (10*time.Hour).Milliseconds() {
+		t.Errorf("Expected a 10h MTBF for API, got %v", api.MTBFMs)
+	}
+	if day.Sources[1].Stats.MTTRMs != nil || day.Sources[1].Stats.MTBFMs != nil {
+		t.Errorf("Expected no MTTR or MTBF without outages, got %+v", day.Sources[1].Stats)
+	}
+	if len(day.Tags) != 1 || day.Tags[0].Tag != "prod" || day.Tags[0].Stats.Sources != 2 ||
+		day.Tags[0].Stats.MTBFMs == nil || *day.Tags[0].Stats.MTBFMs != (22*time.Hour).Milliseconds() {
+		t.Errorf("Expected a prod tag over 2 sources with a 22h MTBF, got %+v", day.Tags)
+	}
+	if day.Overall.OutageCount != 2 || day.Overall.RecoveredCount != 2 {
+		t.Errorf("Expected 2 recovered outages overall, got %+v", day.Overall)
+	}
+
+	for _, query := range []string{"windows=1d,x", "windows=400d", "windows=1d,2d,3d,4d,5d,6d,7d"} {
+		rec = makeRequest(t, am, http.MethodGet, "/api/v1/reliability?"+query, "", "test-api-key")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, rec.Code)
+		}
+	}
+}
+
+// TestSourceTimeline tests the status bar segments on /sources/:id/timeline
+func TestSourceTimeline(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	// Up for days, down from 3h ago to 1h ago, up since
+	now := time.Now()
+	source := &storage.Source{ID: "api", Name: "API", Type: "http", Target: "https://api.example.com", CurrentStatus: 1, Enabled: true, CreatedAt: now.AddDate(0, 0, -7)}
+	db.SaveSource(source)
+	db.SaveStatusChange(&storage.StatusChange{SourceID: "api", OldStatus: -1, NewStatus: 1, Timestamp: source.CreatedAt})
+	db.SaveStatusChange(&storage.StatusChange{SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: now.Add(-3 * time.Hour)})
+	db.SaveStatusChange(&storage.StatusChange{SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: now.Add(-time.Hour)})
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/sources/api/timeline", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var timeline SourceTimelineResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &timeline); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if timeline.To.Sub(timeline.From) != 24*time.Hour {
+		t.Errorf("Expected the last 24 hours by default, got %v to %v", timeline.From, timeline.To)
+	}
+	states := make([]string, len(timeline.Segments))
+	for i, segment := range timeline.Segments {
+		states[i] = segment.State
+	}
+	if strings.Join(states, ",") != "up,down,up" {
+		t.Fatalf("Expected up, down, up segments, got %v", states)
+	}
+	if !timeline.Segments[0].Start.Equal(timeline.From) || !timeline.Segments[2].End.Equal(timeline.To) {
+		t.Errorf("Expected segments to cover the whole range, got %+v", timeline.Segments)
+	}
+	if timeline.Segments[1].DurationMs != (2 * time.Hour).Milliseconds() {
+		t.Errorf("Expected a 2h down segment, got %dms", timeline.Segments[1].DurationMs)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/sources/api/timeline?from="+now.Add(time.Hour).Format(time.RFC3339)+"&to="+now.Add(2*time.Hour).Format(time.RFC3339), "", "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a range in the future, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/sources/missing/timeline", "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown source, got %d", rec.Code)
+	}
+}
+
+// TestSourceHeatmap tests the calendar heatmap built from daily rollups
+func TestSourceHeatmap(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	source := &storage.Source{ID: "api", Name: "API", Type: "http", Target: "https://api.example.com", CurrentStatus: 1, Enabled: true, CreatedAt: today.AddDate(0, 0, -30)}
+	db.SaveSource(source)
+	db.SaveStatusChange(&storage.StatusChange{SourceID: "api", OldStatus: -1, NewStatus: 1, Timestamp: source.CreatedAt})
+
+	// Two rolled-up days: a perfect one and one at 98%; the days between have no rollup
+	day := 24 * time.Hour
+	db.SaveDailyRollup(&storage.DailyRollup{SourceID: "api", Date: today.AddDate(0, 0, -3).Format("2006-01-02"), UptimePercent: 100, MonitoredMs: day.Milliseconds()})
+	db.SaveDailyRollup(&storage.DailyRollup{SourceID: "api", Date: today.AddDate(0, 0, -2).Format("2006-01-02"), UptimePercent: 98, OutageCount: 1,
+		DowntimeMs: (day / 50).Milliseconds(), MonitoredMs: day.Milliseconds()})
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/sources/api/heatmap?days=7", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var heatmap SourceHeatmapResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &heatmap); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(heatmap.Days) != 7 || heatmap.To != today.Format("2006-01-02") || heatmap.Days[6].Date != heatmap.To {
+		t.Fatalf("Expected 7 days ending today, got %+v", heatmap.Days)
+	}
+	levels := make([]string, len(heatmap.Days))
+	for i, cell := range heatmap.Days {
+		levels[i] = cell.Level
+	}
+	// Today is replayed: online since creation
+	if strings.Join(levels, ",") != "no_data,no_data,no_data,perfect,poor,no_data,perfect" {
+		t.Errorf("Unexpected levels %v", levels)
+	}
+	if heatmap.Days[4].OutageCount != 1 || heatmap.Days[4].Weekday != int(today.AddDate(0, 0, -2).Weekday()) {
+		t.Errorf("Expected the 98%% day with 1 outage, got %+v", heatmap.Days[4])
+	}
+	if heatmap.UptimePercent == nil || *heatmap.UptimePercent >= 99.9 || *heatmap.UptimePercent <= 98 {
+		t.Errorf("Expected overall uptime between the two days, got %v", heatmap.UptimePercent)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/sources/api/heatmap?days=400", "", "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for more than 366 days, got %d", rec.Code)
+	}
+}
+
+// TestMaintenanceWindows tests CRUD, scoping and closing on /maintenance
+func TestMaintenanceWindows(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	db.SaveSource(&storage.Source{ID: "api", Name: "API", Type: "http", Target: "https://api.example.com", Tags: []string{"prod"}, Enabled: true, CreatedAt: time.Now()})
+	db.SaveSource(&storage.Source{ID: "web", Name: "Web", Type: "http", Target: "https://example.com", Enabled: true, CreatedAt: time.Now()})
+
+	// A deploy pipeline opens a window for prod sources before deploying
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/maintenance", `{"name": "Deploy", "tags": ["Prod"]}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var deploy MaintenanceWindowResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &deploy); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if !deploy.Active || deploy.End != nil || len(deploy.Tags) != 1 || deploy.Tags[0] != "prod" {
+		t.Fatalf("Expected an active open-ended window for the prod tag, got %+v", deploy)
+	}
+
+	// A weekly window for Web that starts tomorrow
+	start := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	body := fmt.Sprintf(`{"name": "Patching", "source_ids": ["web"], "start": %q, "end": %q, "recurrence": "weekly"}`,
+		start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339))
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/maintenance", body, "test-api-key")
+	var patching MaintenanceWindowResponse
+	json.Unmarshal(rec.Body.Bytes(), &patching)
+	if rec.Code != http.StatusCreated || patching.Active || patching.NextStart == nil || !patching.NextStart.Equal(start) {
+		t.Fatalf("Expected an inactive weekly window starting tomorrow, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var windows []MaintenanceWindowResponse
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/maintenance?active=true", "", "test-api-key")
+	json.Unmarshal(rec.Body.Bytes(), &windows)
+	if len(windows) != 1 || windows[0].ID != deploy.ID {
+		t.Errorf("Expected only the deploy window to be active, got %+v", windows)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/maintenance?source_id=web", "", "test-api-key")
+	json.Unmarshal(rec.Body.Bytes(), &windows)
+	if len(windows) != 1 || windows[0].ID != patching.ID {
+		t.Errorf("Expected only the patching window to cover Web, got %+v", windows)
+	}
+
+	// The pipeline closes the window after deploying
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/maintenance/"+deploy.ID+"/close", "", "test-api-key")
+	json.Unmarshal(rec.Body.Bytes(), &deploy)
+	if rec.Code != http.StatusOK || deploy.Active || deploy.End == nil {
+		t.Errorf("Expected the closed window to have ended, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/maintenance/"+deploy.ID+"/close", "", "test-api-key")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 closing an ended window, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/maintenance/"+patching.ID+"/close", "", "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 closing a recurring window, got %d", rec.Code)
+	}
+
+	for _, invalid := range []string{
+		`{"source_ids": ["missing"]}`,
+		`{"recurrence": "daily"}`,
+		`{"start": "2026-03-01T10:00:00Z", "end": "2026-03-01T09:00:00Z"}`,
+	} {
+		rec = makeRequest(t, am, http.MethodPost, "/api/v1/maintenance", invalid, "test-api-key")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", invalid, rec.Code)
+		}
+	}
+
+	rec = makeRequest(t, am, http.MethodDelete, "/api/v1/maintenance/"+patching.ID, "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/maintenance/"+patching.ID, "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", rec.Code)
+	}
+}
+
+// TestCloneSource tests copying a source's configuration and sinks with POST /sources/:id/clone
+func TestSourceNotificationMessages(t *testing.T) {
+	source, err := sourceFromCreateRequest(CreateSourceRequest{
+		Name: "Internet", Type: "ping", Target: "1.1.1.1", CheckInterval: "30s",
+		OutageMessage: "  Call the ISP at 0800 123 456 ", RecoveryMessage: "Close the ticket",
+	})
+	if err != nil {
+		t.Fatalf("sourceFromCreateRequest failed: %v", err)
+	}
+	if source.NotificationMessage(0) != "Call the ISP at 0800 123 456" || source.NotificationMessage(1) != "Close the ticket" {
+		t.Errorf("Expected trimmed outage and recovery messages, got %q and %q", source.OutageMessage, source.RecoveryMessage)
+	}
+
+	_, err = sourceFromCreateRequest(CreateSourceRequest{
+		Name: "Internet", Type: "ping", Target: "1.1.1.1", CheckInterval: "30s",
+		OutageMessage: strings.Repeat("x", storage.MaxNotificationMessageLength+1),
+	})
+	if err == nil {
+		t.Error("Expected error for a message over the limit")
+	}
+
+	// Omitted messages are kept, empty ones removed
+	empty := ""
+	req := UpdateSourceRequest{Name: "Internet", Type: "ping", Target: "1.1.1.1", CheckInterval: "30s", Enabled: true, RecoveryMessage: &empty}
+	if err := applyUpdateRequest(source, req); err != nil {
+		t.Fatalf("applyUpdateRequest failed: %v", err)
+	}
+	if source.OutageMessage == "" || source.RecoveryMessage != "" {
+		t.Errorf("Expected the outage message kept and the recovery message removed, got %q and %q", source.OutageMessage, source.RecoveryMessage)
+	}
+}
+
+func TestSourceDependencies(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	router := &storage.Source{Name: "Core Router", Type: "ping", Target: "10.0.0.1", CheckInterval: time.Minute, Enabled: true}
+	db.SaveSource(router)
+
+	body := `{"name":"App","type":"http","target":"https://app.example.com","check_interval":"1m","depends_on":["` + router.ID + `"," ` + router.ID + `"]}`
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var app storage.Source
+	json.Unmarshal(rec.Body.Bytes(), &app)
+	if len(app.DependsOn) != 1 || app.DependsOn[0] != router.ID {
+		t.Errorf("Expected the router as the only dependency, got %v", app.DependsOn)
+	}
+
+	// Unknown sources and cycles are rejected
+	body = `{"name":"Web","type":"http","target":"https://example.com","check_interval":"1m","depends_on":["missing"]}`
+	if rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", body, "test-api-key"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown dependency, got %d", rec.Code)
+	}
+	body = `{"name":"Core Router","type":"ping","target":"10.0.0.1","check_interval":"1m","enabled":true,"depends_on":["` + app.ID + `"]}`
+	rec = makeRequest(t, am, http.MethodPut, "/api/v1/sources/"+router.ID, body, "test-api-key")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "cycle") {
+		t.Errorf("Expected status 400 for a cycle, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Omitted keeps them, [] clears them
+	body = `{"name":"App","type":"http","target":"https://app.example.com","check_interval":"1m","enabled":true}`
+	makeRequest(t, am, http.MethodPut, "/api/v1/sources/"+app.ID, body, "test-api-key")
+	if stored, _ := db.GetSource(app.ID); len(stored.DependsOn) != 1 {
+		t.Errorf("Expected the dependency kept, got %v", stored.DependsOn)
+	}
+	body = `{"name":"App","type":"http","target":"https://app.example.com","check_interval":"1m","enabled":true,"depends_on":[]}`
+	makeRequest(t, am, http.MethodPut, "/api/v1/sources/"+app.ID, body, "test-api-key")
+	if stored, _ := db.GetSource(app.ID); len(stored.DependsOn) != 0 {
+		t.Errorf("Expected the dependencies cleared, got %v", stored.DependsOn)
+	}
+}
+
+func TestSourceLastError(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	checkTime := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	source := &storage.Source{Name: "API", Type: "http", Target: "https://example.com", CheckInterval: time.Minute, Enabled: true, CurrentStatus: 1}
+	db.SaveSource(source)
+	db.UpdateSourceStatus(source.ID, 0, checkTime, "HTTP 503 Service Unavailable")
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/sources/"+source.ID, "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"last_error":"HTTP 503 Service Unavailable"`) ||
+		!strings.Contains(rec.Body.String(), `"last_error_time":"2026-03-01T12:00:00Z"`) {
+		t.Errorf("Expected the last error and its time, got %s", rec.Body.String())
+	}
+
+	// Cleared by a successful check, so the field is left out
+	db.UpdateSourceStatus(source.ID, 1, checkTime.Add(time.Minute), "")
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/sources/"+source.ID, "", "test-api-key")
+	if strings.Contains(rec.Body.String(), `"last_error":`) {
+		t.Errorf("Expected no last_error after recovery, got %s", rec.Body.String())
+	}
+}
+
+func TestCloneSource(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	original := &storage.Source{ID: "host-1", Name: "Host 1", Type: "ping", Target: "10.0.0.1", CheckInterval: time.Minute, CurrentStatus: 0, Enabled: false, Public: true, Tags: []string{"lan"}, SLO: &storage.SLO{Target: 99.9}, CreatedAt: time.Now()}
+	db.SaveSource(original)
+	db.SaveChat(&storage.Chat{ChatID: -100123, Name: "Ops"})
+	db.AddSourceChat(original.ID, -100123)
+	db.SaveWebhook(&storage.Webhook{ID: "wh1", Name: "Sink", URL: "https://example.com/hook", Enabled: true})
+	db.AddSourceWebhook(original.ID, "wh1")
+
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources/host-1/clone", `{"name": "Host 2", "target": "10.0.0.2"}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var clone SourceDetailResponse
+	json.Unmarshal(rec.Body.Bytes(), &clone)
+	if clone.ID == "" || clone.ID == original.ID || clone.Name != "Host 2" || clone.Target != "10.0.0.2" {
+		t.Fatalf("Expected a new source named Host 2 for 10.0.0.2, got %+v", clone.Source)
+	}
+	if clone.Type != "ping" || clone.CheckInterval != time.Minute || !clone.Public || !clone.HasTag("lan") || clone.SLO == nil || clone.SLO.Target != 99.9 {
+		t.Errorf("Expected the configuration to be copied, got %+v", clone.Source)
+	}
+	if !clone.Enabled || clone.CurrentStatus != -1 {
+		t.Errorf("Expected the clone to start enabled with an unknown status, got enabled=%v status=%d", clone.Enabled, clone.CurrentStatus)
+	}
+	if len(clone.TelegramChats) != 1 || clone.TelegramChats[0].ChatID != -100123 || len(clone.Webhooks) != 1 || clone.Webhooks[0].ID != "wh1" {
+		t.Errorf("Expected the chat and webhook to be copied, got %+v and %+v", clone.TelegramChats, clone.Webhooks)
+	}
+	if chats, _ := db.GetSourceChats(original.ID); len(chats) != 1 {
+		t.Errorf("Expected the original to keep its chat, got %v", chats)
+	}
+
+	// The target defaults to the original's
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/sources/host-1/clone", `{"name": "Host 1 (slow)"}`, "test-api-key")
+	json.Unmarshal(rec.Body.Bytes(), &clone)
+	if rec.Code != http.StatusCreated || clone.Target != "10.0.0.1" {
+		t.Errorf("Expected the original target, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Webhook clones get their own token
+	db.SaveSource(&storage.Source{ID: "cron", Name: "Cron", Type: "webhook", CheckInterval: time.Hour, WebhookToken: "secret", GracePeriodMultiplier: 3, CreatedAt: time.Now()})
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/sources/cron/clone", `{"name": "Cron 2"}`, "test-api-key")
+	json.Unmarshal(rec.Body.Bytes(), &clone)
+	if rec.Code != http.StatusCreated || clone.WebhookToken == "" || clone.WebhookToken == "secret" || clone.GracePeriodMultiplier != 3 {
+		t.Errorf("Expected a webhook clone with a new token, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for _, tc := range []struct {
+		path, body string
+		status     int
+	}{
+		{"/api/v1/sources/host-1/clone", `{"target": "10.0.0.3"}`, http.StatusBadRequest},
+		{"/api/v1/sources/cron/clone", `{"name": "Cron 3", "target": "10.0.0.3"}`, http.StatusBadRequest},
+		{"/api/v1/sources/missing/clone", `{"name": "Missing"}`, http.StatusNotFound},
+	} {
+		if rec := makeRequest(t, am, http.MethodPost, tc.path, tc.body, "test-api-key"); rec.Code != tc.status {
+			t.Errorf("POST %s %s: expected %d, got %d", tc.path, tc.body, tc.status, rec.Code)
+		}
+	}
+}
+
+// TestSourceTemplates tests /templates and creating sources from a template
+func TestSourceTemplates(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	db.SaveChat(&storage.Chat{ChatID: -100123, Name: "Ops"})
+
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/templates", `{"name": "lan-host", "type": "ping", "check_interval": "30s", "tags": ["LAN"], "public": true, "telegram_chat_ids": [-100123]}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var template storage.SourceTemplate
+	json.Unmarshal(rec.Body.Bytes(), &template)
+	if template.ID == "" || template.CheckInterval != 30*time.Second || len(template.Tags) != 1 || template.Tags[0] != "lan" {
+		t.Fatalf("Expected a normalized template, got %+v", template)
+	}
+
+	if rec := makeRequest(t, am, http.MethodPost, "/api/v1/templates", `{"name": "LAN-HOST", "type": "ping", "check_interval": "30s"}`, "test-api-key"); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a duplicate name, got %d", rec.Code)
+	}
+	for _, body := range []string{
+		`{"type": "ping", "check_interval": "30s"}`,
+		`{"name": "x", "type": "dns", "check_interval": "30s"}`,
+		`{"name": "x", "type": "ping", "check_interval": "soon"}`,
+		`{"name": "x", "type": "ping", "check_interval": "30s", "telegram_chat_ids": [7]}`,
+		`{"name": "x", "type": "ping", "check_interval": "30s", "webhook_ids": ["missing"]}`,
+	} {
+		if rec := makeRequest(t, am, http.MethodPost, "/api/v1/templates", body, "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	// Only name and target come from the request
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/sources", `{"template": "lan-host", "name": "Host 21", "target": "192.168.1.21", "type": "http", "check_interval": "5m"}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var source storage.Source
+	json.Unmarshal(rec.Body.Bytes(), &source)
+	if source.Type != "ping" || source.Target != "192.168.1.21" || source.CheckInterval != 30*time.Second || !source.Public || !source.HasTag("lan") {
+		t.Errorf("Expected the template's settings, got %+v", source)
+	}
+	if chats, _ := db.GetSourceChats(source.ID); len(chats) != 1 || chats[0] != -100123 {
+		t.Errorf("Expected the template's chat to be attached, got %v", chats)
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/sources/bulk", `{"operations": [{"op": "create", "source": {"template": "`+template.ID+`", "name": "Host 22", "target": "192.168.1.22"}}, {"op": "create", "source": {"template": "lan-host", "name": "Host 23"}}]}`, "test-api-key")
+	var bulk BulkSourceResponse
+	json.Unmarshal(rec.Body.Bytes(), &bulk)
+	if bulk.Applied != 1 || bulk.Failed != 1 || bulk.Results[0].Source == nil {
+		t.Fatalf("Expected one bulk create from the template and one missing target, got %s", rec.Body.String())
+	}
+	if chats, _ := db.GetSourceChats(bulk.Results[0].Source.ID); len(chats) != 1 {
+		t.Errorf("Expected the template's chat on the bulk-created source, got %v", chats)
+	}
+
+	if rec := makeRequest(t, am, http.MethodPost, "/api/v1/sources", `{"template": "missing", "name": "Host", "target": "10.0.0.1"}`, "test-api-key"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown template, got %d", rec.Code)
+	}
+
+	// Editing the template doesn't change existing sources
+	rec = makeRequest(t, am, http.MethodPut, "/api/v1/templates/lan-host", `{"name": "lan-host", "type": "ping", "check_interval": "1m"}`, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if existing, _ := db.GetSource(source.ID); existing.CheckInterval != 30*time.Second {
+		t.Errorf("Expected the existing source to keep its interval, got %v", existing.CheckInterval)
+	}
+
+	if rec := makeRequest(t, am, http.MethodDelete, "/api/v1/templates/"+template.ID, "", "test-api-key"); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/api/v1/templates", "", "test-api-key")
+	if strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("Expected no templates after delete, got %s", rec.Body.String())
+	}
+}
+
+func TestNetworkDiscovery(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	now := time.Now()
+	db.SaveSource(&storage.Source{Name: "NAS", Type: "ping", Target: "10.0.0.5", CheckInterval: time.Minute, Enabled: true})
+	db.SaveSourceTemplate(&storage.SourceTemplate{Name: "web", Type: "http", CheckInterval: 2 * time.Minute, Tags: []string{"lan"}})
+	db.SaveDiscoveredHosts([]*storage.DiscoveredHost{
+		{IP: "10.0.0.5", Methods: []string{storage.DiscoveredByPing}, LastSeen: now},
+		{IP: "10.0.0.7", Hostname: "printer.lan", Methods: []string{storage.DiscoveredByARP}, LastSeen: now},
+		{IP: "10.0.0.9", Methods: []string{storage.DiscoveredByTCP}, OpenPorts: []int{443}, LastSeen: now},
+	})
+
+	rec := makeRequest(t, am, http.MethodGet, "/api/v1/discovery?new=true", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var discovery DiscoveryResponse
+	json.Unmarshal(rec.Body.Bytes(), &discovery)
+	if len(discovery.Hosts) != 2 || discovery.Hosts[0].IP != "10.0.0.7" || discovery.Scan != nil {
+		t.Fatalf("Expected the 2 unmonitored hosts and no scan, got %+v", discovery)
+	}
+
+	// Monitor not available in test mode
+	if rec := makeRequest(t, am, http.MethodPost, "/api/v1/discovery/scan", `{"ranges": ["10.0.0.0/24"]}`, "test-api-key"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a monitor, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/discovery/promote", `{"hosts": ["10.0.0.7", "10.0.0.5", "10.0.0.99"], "check_interval": "1m", "tags": ["found"]}`, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var promoted BulkSourceResponse
+	json.Unmarshal(rec.Body.Bytes(), &promoted)
+	if promoted.Applied != 1 || promoted.Failed != 2 {
+		t.Fatalf("Expected 1 applied and 2 failed (monitored, unknown), got %+v", promoted)
+	}
+	source := promoted.Results[0].Source
+	if source == nil || source.Name != "printer" || source.Type != "ping" || source.Target != "10.0.0.7" || !source.HasTag("found") {
+		t.Errorf("Expected a ping source named after the hostname, got %+v", source)
+	}
+	if host, _ := db.GetDiscoveredHost("10.0.0.7"); host.SourceID != promoted.Results[0].ID {
+		t.Errorf("Expected the host to be linked to its source, got %q", host.SourceID)
+	}
+
+	// From a template: https, since only 443 is open
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/discovery/promote", `{"hosts": ["10.0.0.9"], "template": "web"}`, "test-api-key")
+	json.Unmarshal(rec.Body.Bytes(), &promoted)
+	if promoted.Applied != 1 || promoted.Results[0].Source.Target != "https://10.0.0.9" || promoted.Results[0].Source.CheckInterval != 2*time.Minute {
+		t.Errorf("Expected an http source from the template, got %s", rec.Body.String())
+	}
+
+	for _, body := range []string{`{"hosts": []}`, `{"hosts": ["10.0.0.9"], "type": "webhook", "check_interval": "1m"}`} {
+		if rec := makeRequest(t, am, http.MethodPost, "/api/v1/discovery/promote", body, "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	if rec := makeRequest(t, am, http.MethodDelete, "/api/v1/discovery/hosts/10.0.0.9", "", "test-api-key"); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	if rec := makeRequest(t, am, http.MethodDelete, "/api/v1/discovery/hosts/10.0.0.9", "", "test-api-key"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted host, got %d", rec.Code)
+	}
+}
+
+func TestImportExternal(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+	am.configManager.Set("TELEGRAM_TOKEN", "test-token")
+	am.configManager.Set("API_KEY", "test-api-key")
+
+	db.SaveChat(&storage.Chat{ChatID: -100123, Name: "Ops"})
+	db.SaveSource(&storage.Source{Name: "Existing", Type: "ping", Target: "10.0.0.1", CheckInterval: time.Minute, Enabled: true})
+
+	backup := `{
+		"version": "1.23.0",
+		"notificationList": [
+			{"id": 1, "name": "Ops Telegram", "active": 1, "config": "{\"type\":\"telegram\",\"telegramChatID\":\"-100123\"}"},
+			{"id": 2, "name": "Hook", "active": true, "config": "{\"type\":\"webhook\",\"webhookURL\":\"https://hooks.example.com/kuma\"}"},
+			{"id": 3, "name": "Mail", "active": 1, "config": "{\"type\":\"smtp\"}"}
+		],
+		"monitorList": [
+			{"id": 1, "name": "Website", "type": "http", "url": "https://example.com", "interval": 60, "active": 1,
+			 "notificationIDList": {"1": true, "2": true}, "tags": [{"name": "Prod Web", "value": ""}, {"name": "team", "value": "web"}]},
+			{"id": 2, "name": "Router", "type": "ping", "hostname": "192.168.1.1", "interval": 30, "active": 0, "notificationIDList": {"1": true}},
+			{"id": 3, "name": "Backup job", "type": "push", "interval": 3600, "active": true, "notificationIDList": {}},
+			{"id": 4, "name": "Search", "type": "keyword", "url": "https://example.com/search", "keyword": "ok"},
+			{"id": 5, "name": "DB", "type": "port", "hostname": "db", "port": 5432},
+			{"id": 6, "name": "existing", "type": "ping", "hostname": "10.0.0.1"}
+		]
+	}`
+
+	rec := makeRequest(t, am, http.MethodPost, "/api/v1/import?format=uptime-kuma&dry_run=true", backup, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result ExternalImportResult
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if !result.DryRun || result.Sources != 4 || result.Webhooks != 1 || result.Chats != 0 || len(result.Warnings) != 4 {
+		t.Fatalf("Expected 4 sources and 1 webhook with 4 warnings (smtp, keyword, port, existing), got %+v", result)
+	}
+	if sources, _ := db.GetAllSources(); len(sources) != 1 {
+		t.Fatalf("Expected a dry run to write nothing, got %d sources", len(sources))
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/import?format=uptime-kuma", backup, "test-api-key")
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if rec.Code != http.StatusOK || result.Sources != 4 || result.Links != 3 {
+		t.Fatalf("Expected 4 sources and 3 links, got %d: %s", rec.Code, rec.Body.String())
+	}
+	byName := make(map[string]*storage.Source)
+	for _, source := range result.Created {
+		byName[source.Name] = source
+	}
+	website := byName["Website"]
+	if website == nil || website.Type != "http" || website.CheckInterval != time.Minute || !website.HasTag("prod-web") || !website.HasTag("team:web") {
+		t.Errorf("Expected an http source with converted tags, got %+v", website)
+	}
+	if chats, _ := db.GetSourceChats(website.ID); len(chats) != 1 || chats[0] != -100123 {
+		t.Errorf("Expected the Telegram notification as chat, got %v", chats)
+	}
+	if webhooks, _ := db.GetSourceWebhooks(website.ID); len(webhooks) != 1 || webhooks[0].URL != "https://hooks.example.com/kuma" {
+		t.Errorf("Expected the webhook notification as webhook, got %v", webhooks)
+	}
+	if router := byName["Router"]; router == nil || router.Enabled || router.Target != "192.168.1.1" {
+		t.Errorf("Expected a paused ping source, got %+v", router)
+	}
+	if push := byName["Backup job"]; push == nil || push.Type != "webhook" || push.WebhookToken == "" || push.CheckInterval != time.Hour {
+		t.Errorf("Expected a webhook source with a token, got %+v", push)
+	}
+	if chat, _ := db.GetChat(-100123); chat == nil || chat.Name != "Ops" {
+		t.Errorf("Expected the known chat to keep its name, got %+v", chat)
+	}
+
+	// Importing again creates nothing
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/import?format=uptime-kuma", backup, "test-api-key")
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if result.Sources != 0 || result.Webhooks != 0 {
+		t.Errorf("Expected a repeated import to create nothing, got %+v", result)
+	}
+
+	csvBody := "name,type,target,tags,telegram_chat_ids,webhook_urls\n" +
+		"API,http,https://api.example.com,prod;api,42,https://hooks.example.com/kuma\n" +
+		"Broken,dns,example.com,,,\n"
+	rec = makeRequest(t, am, http.MethodPost, "/api/v1/import?format=csv", csvBody, "test-api-key")
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if rec.Code != http.StatusOK || result.Sources != 1 || result.Chats != 1 || result.Webhooks != 0 || len(result.Warnings) != 1 {
+		t.Fatalf("Expected 1 source and chat 42, reusing the webhook, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if api := result.Created[0]; api.CheckInterval != 30*time.Second || !api.HasTag("api") {
+		t.Errorf("Expected the default interval and tags, got %+v", api)
+	}
+
+	for _, path := range []string{"/api/v1/import?format=nagios", "/api/v1/import?format=csv"} {
+		if rec := makeRequest(t, am, http.MethodPost, path, "target\n1.2.3.4\n", "test-api-key"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", path, rec.Code)
+		}
+	}
+}
+
+// TestNamespaces tests that namespaced keys only see and change their namespace's resources
+func TestNamespaces(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	rec := makeRequest(t, am, http.MethodPost, "/namespaces", `{"name":"Alice","description":"Alice's homelab"}`, "test-api-key")
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"name":"alice"`) {
+		t.Fatalf("Expected status 201 with a normalized name, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/namespaces", `{"name":"alice"}`, "test-api-key")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a duplicate namespace, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/keys", `{"name":"alice","scope":"admin","namespace":"alice"}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a namespaced admin key, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/keys", `{"name":"alice","scope":"write","namespace":"bob"}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown namespace, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/keys", `{"name":"alice","scope":"write","namespace":"alice"}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating a namespaced key, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var key CreateAPIKeyResponse
+	json.Unmarshal(rec.Body.Bytes(), &key)
+
+	global := &storage.Source{Name: "admin-router", Type: "ping", Target: "10.0.0.1", CheckInterval: time.Minute, Enabled: true}
+	if err := db.SaveSource(global); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+	db.SaveChat(&storage.Chat{ChatID: 100, Name: "admin"})
+
+	rec = makeRequest(t, am, http.MethodPost, "/sources", `{"name":"nas","type":"ping","target":"192.168.1.5","check_interval":"30s","namespace":"other"}`, key.Secret)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 creating in another namespace, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/sources", `{"name":"nas","type":"ping","target":"192.168.1.5","check_interval":"30s"}`, key.Secret)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var nas storage.Source
+	json.Unmarshal(rec.Body.Bytes(), &nas)
+	if nas.Namespace != "alice" {
+		t.Errorf("Expected the source in the key's namespace, got %q", nas.Namespace)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/sources/"+global.ID, "", key.Secret)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another namespace's source, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodDelete, "/sources/"+global.ID, "", key.Secret)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 deleting another namespace's source, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/sources/bulk", `{"operations":[{"op":"delete","id":"`+global.ID+`"}]}`, key.Secret)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"failed":1`) {
+		t.Errorf("Expected bulk delete of another namespace's source to fail, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/tags", "", key.Secret)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 on an endpoint spanning namespaces, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/telegram-chats", `{"chat_id":100,"name":"mine"}`, key.Secret)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 taking over a registered chat, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/telegram-chats", `{"chat_id":200,"name":"alice"}`, key.Secret)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 registering a chat, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if chat, _ := db.GetChat(200); chat == nil || chat.Namespace != "alice" {
+		t.Errorf("Expected the chat in the key's namespace, got %+v", chat)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/telegram-chats", "", key.Secret)
+	if strings.Contains(rec.Body.String(), `"chat_id":100`) || !strings.Contains(rec.Body.String(), `"chat_id":200`) {
+		t.Errorf("Expected only the namespace's chats, got %s", rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/telegram-chats?namespace=alice", "", "test-api-key")
+	if strings.Contains(rec.Body.String(), `"chat_id":100`) || !strings.Contains(rec.Body.String(), `"chat_id":200`) {
+		t.Errorf("Expected ?namespace= to filter for unscoped keys, got %s", rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/telegram-chats", "", "test-api-key")
+	if !strings.Contains(rec.Body.String(), `"chat_id":100`) || !strings.Contains(rec.Body.String(), `"chat_id":200`) {
+		t.Errorf("Expected unscoped keys to see every chat, got %s", rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/sources/"+nas.ID+"/telegram-chats/100", "", key.Secret)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 attaching another namespace's chat, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/sources/"+nas.ID+"/telegram-chats/200", "", key.Secret)
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Errorf("Expected the namespace's chat to be attached, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/webhooks", `{"name":"hook","url":"https://example.com","enabled":true}`, key.Secret)
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"namespace":"alice"`) {
+		t.Errorf("Expected the webhook in the key's namespace, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = makeRequest(t, am, http.MethodPut, "/sources/"+global.ID, `{"name":"admin-router","type":"ping","target":"10.0.0.1","check_interval":"1m","enabled":true,"namespace":"alice"}`, "test-api-key")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"namespace":"alice"`) {
+		t.Errorf("Expected an unscoped key to move a source, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = makeRequest(t, am, http.MethodDelete, "/namespaces/alice", "", "test-api-key")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 deleting a namespace in use, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/namespaces", "", "test-api-key")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"sources":2`) {
+		t.Errorf("Expected namespace usage counts, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/namespaces", "", key.Secret)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected namespace management to need the admin scope, got %d", rec.Code)
+	}
+}
+
+// TestGraphQL tests nested read-only queries on /graphql
+func TestGraphQL(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	query := func(body string) (int, map[string]interface{}) {
+		rec := makeRequest(t, am, http.MethodPost, "/graphql", body, "test-api-key")
+		var resp map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	am.configManager.Set("API_KEY", "test-api-key")
+
+	if code, _ := query(`{"query":"{ sources { id } }"}`); code != http.StatusNotFound {
+		t.Fatalf("Expected 404 while GRAPHQL_ENABLED is off, got %d", code)
+	}
+	if err := am.configManager.Set("GRAPHQL_ENABLED", "true"); err != nil {
+		t.Fatalf("Failed to enable GraphQL: %v", err)
+	}
+
+	api := &storage.Source{Name: "API", Type: "http", Target: "https://api.example.com", CurrentStatus: 1, Enabled: true, Tags: []string{"prod"}, CreatedAt: time.Now().Add(-24 * time.Hour)}
+	db.SaveSource(api)
+	db.SaveSource(&storage.Source{Name: "Staging", Type: "ping", Target: "10.0.0.9", CurrentStatus: 0, Enabled: true})
+	db.AddSourceChat(api.ID, -100123)
+	for i, status := range []int{0, 1} {
+		db.SaveStatusChange(&storage.StatusChange{SourceID: api.ID, OldStatus: 1 - status, NewStatus: status, Timestamp: time.Now().Add(time.Duration(i-2) * time.Hour)})
+	}
+
+	code, resp := query(`{"query":"query Dash($tag: String, $n: Int = 1) { prod: sources(tag: $tag) { __typename name status tags telegramChats { chatId } changes(limit: $n) { newStatus source { name } } uptime(period: \"7d\") { outageCount } } }","variables":{"tag":"prod"}}`)
+	if code != http.StatusOK || resp["errors"] != nil {
+		t.Fatalf("Expected 200 without errors, got %d: %v", code, resp)
+	}
+	sources := resp["data"].(map[string]interface{})["prod"].([]interface{})
+	if len(sources) != 1 {
+		t.Fatalf("Expected 1 prod source, got %d", len(sources))
+	}
+	src := sources[0].(map[string]interface{})
+	if src["__typename"] != "Source" || src["name"] != "API" || src["status"] != "online" {
+		t.Errorf("Unexpected source fields: %v", src)
+	}
+	if chats := src["telegramChats"].([]interface{}); len(chats) != 1 || chats[0].(map[string]interface{})["chatId"] != "-100123" {
+		t.Errorf("Expected chat -100123, got %v", chats)
+	}
+	changes := src["changes"].([]interface{})
+	if len(changes) != 1 || changes[0].(map[string]interface{})["newStatus"] != float64(1) {
+		t.Errorf("Expected the newest change only, got %v", changes)
+	}
+	if src["uptime"].(map[string]interface{})["outageCount"] != float64(1) {
+		t.Errorf("Expected 1 outage, got %v", src["uptime"])
+	}
+
+	// Fields come back in selection order
+	rec := makeRequest(t, am, http.MethodGet, "/graphql?query="+url.QueryEscape(`{ source(id: "`+api.ID+`") { target name } }`), "", "test-api-key")
+	if !strings.Contains(rec.Body.String(), `{"target":"https://api.example.com","name":"API"}`) {
+		t.Errorf("Expected ordered fields via GET, got %s", rec.Body.String())
+	}
+
+	for name, body := range map[string]string{
+		"mutation":      `{"query":"mutation { deleteSource(id: \"x\") { id } }"}`,
+		"unknown field": `{"query":"{ sources { password } }"}`,
+		"missing arg":   `{"query":"{ source { id } }"}`,
+		"no selection":  `{"query":"{ sources }"}`,
+		"syntax":        `{"query":"{ sources { id }"}`,
+	} {
+		if code, resp := query(body); code != http.StatusBadRequest || resp["errors"] == nil {
+			t.Errorf("%s: expected 400 with errors, got %d: %v", name, code, resp)
+		}
+	}
+
+	// Field errors null the field and are reported with their path
+	code, resp = query(`{"query":"{ sources { name uptime(period: \"999d\") { outageCount } } }"}`)
+	if code != http.StatusOK || resp["errors"] == nil {
+		t.Errorf("Expected 200 with field errors, got %d: %v", code, resp)
+	}
+}
+
+func TestSinks(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	source := &storage.Source{Name: "api", Type: "http", Target: "https://example.com", CheckInterval: time.Minute, Enabled: true}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+
+	rec := makeRequest(t, am, http.MethodPost, "/sinks", `{"type":"telegram","chat_id":-100123,"name":"Ops"}`, "test-api-key")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/sinks", `{"type":"webhook","name":"Hook","url":"https://hooks.example.com","enabled":true}`, "test-api-key")
+	var webhook storage.Sink
+	if err := json.Unmarshal(rec.Body.Bytes(), &webhook); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("Expected a webhook sink, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if webhook.ID != "webhook:"+webhook.Key || webhook.Target != "https://hooks.example.com" {
+		t.Errorf("Unexpected webhook sink: %+v", webhook)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/sinks", `{"type":"pager"}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown type, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/sinks", `{"type":"telegram","chat_id":1,"url":"https://x"}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for webhook fields on a telegram sink, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/sinks?type=telegram", "", "test-api-key")
+	var sinks []storage.Sink
+	if err := json.Unmarshal(rec.Body.Bytes(), &sinks); err != nil || len(sinks) != 1 || sinks[0].ID != "telegram:-100123" {
+		t.Errorf("Expected only the telegram sink, got %s", rec.Body.String())
+	}
+
+	for _, sinkID := range []string{"telegram:-100123", webhook.ID} {
+		rec = makeRequest(t, am, http.MethodPost, "/sources/"+source.ID+"/sinks/"+sinkID, "", "test-api-key")
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201 linking %s, got %d: %s", sinkID, rec.Code, rec.Body.String())
+		}
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/sources/"+source.ID+"/sinks/webhook:missing", "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 linking a missing sink, got %d", rec.Code)
+	}
+	// Links made through the typed endpoints and through sinks are the same
+	if chats, _ := db.GetSourceChats(source.ID); len(chats) != 1 || chats[0] != -100123 {
+		t.Errorf("Expected the chat link, got %v", chats)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/sources/"+source.ID+"/sinks", "", "test-api-key")
+	if err := json.Unmarshal(rec.Body.Bytes(), &sinks); err != nil || len(sinks) != 2 {
+		t.Errorf("Expected two sinks on the source, got %s", rec.Body.String())
+	}
+
+	rec = makeRequest(t, am, http.MethodPut, "/sinks/"+webhook.ID, `{"enabled":false}`, "test-api-key")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"enabled":false`) {
+		t.Errorf("Expected the webhook to be disabled, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodPut, "/sinks/telegram:-100123", `{"enabled":false}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 disabling a telegram sink, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodDelete, "/sinks/"+webhook.ID, "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/sinks/telegram:-100123", "", "test-api-key")
+	var detail SinkResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil || len(detail.SourceIDs) != 1 || detail.SourceIDs[0] != source.ID {
+		t.Errorf("Expected the chat to notify the source, got %s", rec.Body.String())
+	}
+	if ids, _ := db.GetSourceSinkIDs(source.ID); len(ids) != 1 {
+		t.Errorf("Expected the webhook link to go with the webhook, got %v", ids)
+	}
+}
+
+func TestTestAllSinks(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	var payload notifier.WebhookPayload
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer target.Close()
+
+	source := &storage.Source{Name: "api", Type: "http", Target: "https://example.com", CheckInterval: time.Minute, Enabled: true}
+	db.SaveSource(source)
+	webhook := &storage.Webhook{Name: "Hook", URL: target.URL, Method: http.MethodPost, Enabled: true}
+	db.SaveWebhook(webhook)
+	db.AddSourceWebhook(source.ID, webhook.ID)
+	db.AddSourceChat(source.ID, -100123)
+
+	rec := makeRequest(t, am, http.MethodPost, "/test/all", `{"source_id":"`+source.ID+`"}`, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp TestAllSinksResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Sent != 1 || resp.Skipped != 1 || len(resp.Results) != 2 {
+		t.Fatalf("Expected the webhook sent and the chat skipped without a bot, got %+v", resp)
+	}
+	if !payload.Test || payload.Source == nil || payload.Source.ID != source.ID {
+		t.Errorf("Expected a test payload for the source, got %+v", payload)
+	}
+	if deliveries, _ := db.GetDeliveries(storage.DeliveryFilter{}); len(deliveries) != 0 {
+		t.Errorf("Expected test deliveries to stay out of the delivery log, got %d", len(deliveries))
+	}
+
+	webhook.URL = "http://127.0.0.1:1"
+	db.SaveWebhook(webhook)
+	rec = makeRequest(t, am, http.MethodPost, "/test/all", `{"source_id":"`+source.ID+`"}`, "test-api-key")
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("Expected status 502 when a delivery fails, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodPost, "/test/all", `{"source_id":"missing"}`, "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown source, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/test/all", `{}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without source_id, got %d", rec.Code)
+	}
+}
+
+func TestIncidentNotes(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	from, _ := reportBounds("week", time.Now(), 0)
+	source := &storage.Source{ID: "api", Name: "API", Type: "http", Target: "https://api.example.com", Enabled: true, CreatedAt: from.AddDate(0, 0, -30)}
+	db.SaveSource(source)
+
+	rec := makeRequest(t, am, http.MethodPost, "/sources/api/notes", `{"text":"too early"}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without an outage, got %d", rec.Code)
+	}
+
+	db.SaveStatusChange(&storage.StatusChange{ID: "up", SourceID: "api", OldStatus: -1, NewStatus: 1, Timestamp: source.CreatedAt})
+	db.SaveStatusChange(&storage.StatusChange{ID: "down", SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(30 * time.Hour)})
+	db.SaveStatusChange(&storage.StatusChange{ID: "back", SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(32 * time.Hour)})
+
+	// Written after the outage ended: attached to it as the latest one
+	rec = makeRequest(t, am, http.MethodPost, "/sources/api/notes", `{"text":"ISP confirmed fiber cut, ETA 2h"}`, "test-api-key")
+	var note storage.IncidentNote
+	if err := json.Unmarshal(rec.Body.Bytes(), &note); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if note.ChangeID != "down" || note.Author == "" {
+		t.Errorf("Expected the note on the outage with an author, got %+v", note)
+	}
+	at := from.Add(-time.Hour).Format(time.RFC3339)
+	rec = makeRequest(t, am, http.MethodPost, "/sources/api/notes", `{"text":"before any outage","at":"`+at+`"}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a time before the first outage, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/sources/api/notes", `{"text":""}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an empty note, got %d", rec.Code)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/sources/api/notes", "", "test-api-key")
+	var notes []storage.IncidentNote
+	json.Unmarshal(rec.Body.Bytes(), &notes)
+	if rec.Code != http.StatusOK || len(notes) != 1 || notes[0].ID != note.ID {
+		t.Fatalf("Expected the note listed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/reports/week", "", "test-api-key")
+	var report Report
+	json.Unmarshal(rec.Body.Bytes(), &report)
+	if len(report.TopOutages) != 1 || len(report.TopOutages[0].Notes) != 1 || report.TopOutages[0].Notes[0].ID != note.ID {
+		t.Errorf("Expected the note on the report's outage, got %+v", report.TopOutages)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/reports/week?format=text", "", "test-api-key")
+	if !strings.Contains(rec.Body.String(), "ISP confirmed fiber cut") {
+		t.Errorf("Expected the note in the text report, got %s", rec.Body.String())
+	}
+
+	rec = makeRequest(t, am, http.MethodDelete, "/sources/api/notes/"+note.ID, "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodDelete, "/sources/api/notes/"+note.ID, "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a deleted note, got %d", rec.Code)
+	}
+}
+
+func TestPostmortem(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	base := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	db.SaveSource(&storage.Source{ID: "api", Name: "API", Type: "http", Target: "https://api.example.com", Enabled: true, CreatedAt: base})
+	db.SaveSource(&storage.Source{ID: "db", Name: "Database", Type: "tcp", Target: "db:5432", Enabled: true, CreatedAt: base})
+	db.SaveSource(&storage.Source{ID: "cdn", Name: "CDN", Type: "http", Target: "https://cdn.example.com", Enabled: true, CreatedAt: base})
+
+	rec := makeRequest(t, am, http.MethodGet, "/sources/api/postmortem", "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 without an outage, got %d", rec.Code)
+	}
+
+	for _, change := range []*storage.StatusChange{
+		{ID: "api-up", SourceID: "api", OldStatus: -1, NewStatus: 1, Timestamp: base},
+		{ID: "db-up", SourceID: "db", OldStatus: -1, NewStatus: 1, Timestamp: base},
+		{ID: "cdn-up", SourceID: "cdn", OldStatus: -1, NewStatus: 1, Timestamp: base},
+		{ID: "db-down", SourceID: "db", OldStatus: 1, NewStatus: 0, Timestamp: base.Add(9 * time.Hour)},
+		{ID: "api-down", SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: base.Add(10 * time.Hour)},
+		{ID: "db-back", SourceID: "db", OldStatus: 0, NewStatus: 1, Timestamp: base.Add(11 * time.Hour)},
+		{ID: "api-back", SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: base.Add(12 * time.Hour)},
+		{ID: "cdn-down", SourceID: "cdn", OldStatus: 1, NewStatus: 0, Timestamp: base.Add(20 * time.Hour)},
+	} {
+		db.SaveStatusChange(change)
+	}
+	outage, _ := db.FindOutage("api", base.Add(10*time.Hour))
+	db.AddIncidentNote(outage, &storage.IncidentNote{Text: "DB failover | replica lag", Author: "ops"})
+	db.SaveDelivery(&storage.Delivery{Timestamp: base.Add(10*time.Hour + time.Second), SinkType: "webhook", SinkID: "webhook:1", SinkName: "pager", SourceID: "api", StatusChangeID: "api-down", OldStatus: 1, NewStatus: 0, Success: true, LatencyMs: 40})
+	db.SaveDelivery(&storage.Delivery{Timestamp: base.Add(time.Second), SinkType: "webhook", SinkID: "webhook:1", SinkName: "pager", SourceID: "api", StatusChangeID: "api-up", Success: true})
+
+	at := base.Add(15 * time.Hour).Format(time.RFC3339)
+	rec = makeRequest(t, am, http.MethodGet, "/sources/api/postmortem?format=json&at="+at, "", "test-api-key")
+	var pm Postmortem
+	if err := json.Unmarshal(rec.Body.Bytes(), &pm); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if pm.ChangeID != "api-down" || pm.Ongoing || pm.End == nil || pm.DurationMs != (2*time.Hour).Milliseconds() {
+		t.Errorf("Expected the two-hour outage, got %+v", pm)
+	}
+	if len(pm.Affected) != 1 || pm.Affected[0].SourceID != "db" || !pm.Affected[0].Start.Equal(base.Add(10*time.Hour)) {
+		t.Errorf("Expected only the database, clipped to the outage, got %+v", pm.Affected)
+	}
+	if len(pm.Notes) != 1 || len(pm.Deliveries) != 1 || pm.Deliveries[0].StatusChangeID != "api-down" {
+		t.Errorf("Expected the note and the outage's delivery, got %+v %+v", pm.Notes, pm.Deliveries)
+	}
+	// api down, delivery, db back, api back, then the note written afterwards
+	if len(pm.Timeline) != 5 || pm.Timeline[2].SourceName != "Database" || pm.Timeline[4].Kind != postmortemNote {
+		t.Errorf("Expected a merged timeline, got %+v", pm.Timeline)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/sources/api/postmortem?at="+at, "", "test-api-key")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/markdown") {
+		t.Fatalf("Expected Markdown, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{"# Postmortem: API outage", "| Duration | 2h |", "Database", `DB failover \| replica lag`, "## Root cause"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in the postmortem, got %s", want, body)
+		}
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "postmortem-api-") {
+		t.Errorf("Expected an attachment filename, got %q", rec.Header().Get("Content-Disposition"))
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/sources/api/postmortem?format=pdf", "", "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown format, got %d", rec.Code)
+	}
+}
+
+func TestPlannedOutages(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	base := time.Now().Add(-10 * time.Hour).Truncate(time.Second)
+	db.SaveSource(&storage.Source{ID: "api", Name: "API", Type: "http", Target: "https://api.example.com", Enabled: true, CreatedAt: base})
+
+	rec := makeRequest(t, am, http.MethodPost, "/sources/api/planned-outages", `{"reason":"deploy"}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without an outage, got %d", rec.Code)
+	}
+
+	db.SaveStatusChange(&storage.StatusChange{ID: "up", SourceID: "api", OldStatus: -1, NewStatus: 1, Timestamp: base})
+	db.SaveStatusChange(&storage.StatusChange{ID: "down", SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: base.Add(2 * time.Hour)})
+	db.SaveStatusChange(&storage.StatusChange{ID: "back", SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: base.Add(3 * time.Hour)})
+
+	rec = makeRequest(t, am, http.MethodPost, "/sources/api/planned-outages", `{"reason":"deploy"}`, "test-api-key")
+	var mark storage.PlannedOutage
+	if err := json.Unmarshal(rec.Body.Bytes(), &mark); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mark.ChangeID != "down" || mark.MarkedBy == "" {
+		t.Errorf("Expected the latest outage marked with its author, got %+v", mark)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/sources/api/uptime?period=24h", "", "test-api-key")
+	var stats storage.UptimeStats
+	json.Unmarshal(rec.Body.Bytes(), &stats)
+	if stats.PlannedDowntimeMs != time.Hour.Milliseconds() || stats.SLAUptimePercent == nil || *stats.SLAUptimePercent != 100 {
+		t.Errorf("Expected the hour planned and 100%% SLA uptime, got %+v", stats)
+	}
+	if stats.UptimePercent == nil || *stats.UptimePercent >= 100 {
+		t.Errorf("Expected raw uptime to still count the outage, got %v", stats.UptimePercent)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/sources/api/planned-outages", "", "test-api-key")
+	var marks []storage.PlannedOutage
+	json.Unmarshal(rec.Body.Bytes(), &marks)
+	if rec.Code != http.StatusOK || len(marks) != 1 {
+		t.Fatalf("Expected the mark listed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = makeRequest(t, am, http.MethodDelete, "/sources/api/planned-outages/down", "", "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodDelete, "/sources/api/planned-outages/down", "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unmarked outage, got %d", rec.Code)
+	}
+}
+
+func TestHealthScores(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	base := time.Now().Add(-20 * time.Hour).Truncate(time.Second)
+	db.SaveSource(&storage.Source{ID: "api", Name: "API", Type: "http", Target: "https://api.example.com", Enabled: true, CreatedAt: base})
+	db.SaveSource(&storage.Source{ID: "web", Name: "Web", Type: "http", Target: "https://example.com", Enabled: true, CreatedAt: base})
+	db.SaveSource(&storage.Source{ID: "new", Name: "New", Type: "http", Target: "https://new.example.com", Enabled: true, CurrentStatus: -1, CreatedAt: base})
+	db.SaveStatusChange(&storage.StatusChange{ID: "api-up", SourceID: "api", OldStatus: -1, NewStatus: 1, Timestamp: base})
+	db.SaveStatusChange(&storage.StatusChange{ID: "web-up", SourceID: "web", OldStatus: -1, NewStatus: 1, Timestamp: base})
+	// One 1h outage in 20h monitored: 95% uptime scores 50 and one flap 90, blended 60
+	db.SaveStatusChange(&storage.StatusChange{ID: "down", SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: base.Add(2 * time.Hour)})
+	db.SaveStatusChange(&storage.StatusChange{ID: "back", SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: base.Add(3 * time.Hour)})
+
+	rec := makeRequest(t, am, http.MethodGet, "/sources/api/health-score", "", "test-api-key")
+	var health monitor.HealthScore
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if health.Score == nil || *health.Score != 60 || health.Status != monitor.HealthDegraded {
+		t.Errorf("Expected a degraded score of 60, got %+v", health)
+	}
+	if health.Flaps != 1 || health.FlapScore != 90 || health.Latency != nil || health.LatencyScore != nil {
+		t.Errorf("Expected one flap and no latency component without a monitor, got %+v", health)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/health-scores", "", "test-api-key")
+	var scores []monitor.HealthScore
+	json.Unmarshal(rec.Body.Bytes(), &scores)
+	if rec.Code != http.StatusOK || len(scores) != 3 {
+		t.Fatalf("Expected three scores, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if scores[0].SourceID != "api" || scores[1].SourceID != "web" || scores[2].SourceID != "new" || scores[2].Status != monitor.HealthUnknown {
+		t.Errorf("Expected lowest score first and unknown last, got %+v", scores)
+	}
+
+	rec = makeRequest(t, am, http.MethodGet, "/health-scores?window=forever", "", "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid window, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodGet, "/sources/missing/health-score", "", "test-api-key")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing source, got %d", rec.Code)
+	}
+}
+
+func TestAlertmanagerWebhookFormat(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	var payload notifier.AlertmanagerPayload
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer target.Close()
+
+	rec := makeRequest(t, am, http.MethodPost, "/webhooks", `{"url":"`+target.URL+`","format":"pagerduty"}`, "test-api-key")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown format, got %d", rec.Code)
+	}
+	rec = makeRequest(t, am, http.MethodPost, "/webhooks", `{"name":"am","url":"`+target.URL+`","format":"alertmanager","enabled":true}`, "test-api-key")
+	var webhook storage.Webhook
+	if err := json.Unmarshal(rec.Body.Bytes(), &webhook); err != nil || rec.Code != http.StatusCreated || webhook.Format != storage.WebhookFormatAlertmanager {
+		t.Fatalf("Expected an alertmanager webhook, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	source := &storage.Source{Name: "api", Type: "http", Target: "https://example.com", CheckInterval: time.Minute, Enabled: true, Tags: []string{"prod"}}
+	db.SaveSource(source)
+	db.AddSourceWebhook(source.ID, webhook.ID)
+
+	rec = makeRequest(t, am, http.MethodPost, "/test/all", `{"source_id":"`+source.ID+`"}`, "test-api-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if payload.Version != "4" || payload.Status != "firing" || payload.Receiver != "am" || len(payload.Alerts) != 1 {
+		t.Fatalf("Expected a firing Alertmanager group, got %+v", payload)
+	}
+	alert := payload.Alerts[0]
+	if alert.Labels["alertname"] != "SourceDown" || alert.Labels["source_id"] != source.ID || alert.Labels["tags"] != "prod" || alert.Labels["test"] != "true" {
+		t.Errorf("Expected the source's labels, got %v", alert.Labels)
+	}
+	if alert.Fingerprint == "" || alert.StartsAt.IsZero() || !alert.EndsAt.IsZero() {
+		t.Errorf("Expected a fingerprint, a start and no end, got %+v", alert)
+	}
+
+	rec = makeRequest(t, am, http.MethodPut, "/webhooks/"+webhook.ID, `{"format":""}`, "test-api-key")
+	var updated storage.Webhook
+	json.Unmarshal(rec.Body.Bytes(), &updated)
+	if rec.Code != http.StatusOK || updated.ID != webhook.ID || updated.Format != "" {
+		t.Errorf("Expected the native format restored, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAlertGrouping(t *testing.T) {
+	am, db, cleanup := setupTestAppManager(t)
+	defer cleanup()
+
+	bodies := make(chan []byte, 10)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies <- body
+	}))
+	defer target.Close()
+
+	webhook := &storage.Webhook{Name: "ops", URL: target.URL, Method: http.MethodPost, Enabled: true}
+	db.SaveWebhook(webhook)
+	dispatcher := notifier.NewDispatcher(db)
+	dispatcher.SetSender(storage.SinkTypeWebhook, notifier.NewWebhookNotifier(db))
+	grouper := notifier.NewGrouper(dispatcher, db)
+	grouper.Configure(50*time.Millisecond, 3)
+
+	notify := func(names ...string) {
+		for _, name := range names {
+			source := &storage.Source{Name: name, Type: "http", Target: "https://" + name + ".example.com", Enabled: true}
+			db.SaveSource(source)
+			db.AddSourceWebhook(source.ID, webhook.ID)
+			change := &storage.StatusChange{SourceID: source.ID, OldStatus: 1, NewStatus: 0}
+			db.SaveStatusChange(change)
+			grouper.OnStatusChange(context.Background(), source, change)
+		}
+	}
+
+	// Three sources down within the window make one notification
+	notify("db", "api", "web")
+	var payload notifier.WebhookGroupPayload
+	select {
+	case body := <-bodies:
+		json.Unmarshal(body, &payload)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the group notification")
+	}
+	dispatcher.Wait()
+	if payload.AlertGroup == nil || payload.AlertGroup.Count != 3 || len(payload.Changes) != 3 {
+		t.Fatalf("Expected a group of 3 changes, got %+v", payload)
+	}
+	if !strings.HasPrefix(payload.AlertGroup.Summary, "3 sources went OFFLINE") || len(bodies) != 0 {
+		t.Errorf("Expected a single notification with a summary, got %q and %d more", payload.AlertGroup.Summary, len(bodies))
+	}
+
+	rec := makeRequest(t, am, http.MethodGet, "/events?group_id="+payload.AlertGroup.ID, "", "test-api-key")
+	var events []StatusChangeEventResponse
+	json.Unmarshal(rec.Body.Bytes(), &events)
+	if rec.Code != http.StatusOK || len(events) != 3 || events[0].GroupID != payload.AlertGroup.ID {
+		t.Errorf("Expected the group's 3 events, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := makeRequest(t, am, http.MethodGet, "/events?group_id=missing", "", "test-api-key"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown group, got %d", rec.Code)
+	}
+
+	// Fewer than the minimum are notified one by one
+	notify("cache", "queue")
+	for i := 0; i < 2; i++ {
+		select {
+		case body := <-bodies:
+			var single notifier.WebhookPayload
+			if json.Unmarshal(body, &single); single.Source == nil {
+				t.Errorf("Expected a per-source payload, got %s", body)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected a notification per source")
+		}
+	}
+	dispatcher.Wait()
+}
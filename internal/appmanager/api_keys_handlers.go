@@ -0,0 +1,100 @@
+package appmanager
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// CreateAPIKeyRequest is the body for POST /keys
+type CreateAPIKeyRequest struct {
+	Name      string `json:"name"`
+	Scope     string `json:"scope"`                // read, operator, write or admin
+	ExpiresIn string `json:"expires_in,omitempty"` // e.g. "720h" or "90d"; empty = never expires
+	Namespace string `json:"namespace,omitempty"`  // Limits the key to one namespace (any scope but admin)
+}
+
+// CreateAPIKeyResponse returns the new key; Secret is shown only once
+type CreateAPIKeyResponse struct {
+	*storage.APIKey
+	Secret string `json:"secret"`
+}
+
+// handleGetAPIKeys lists named API keys (secrets are never returned)
+func (am *AppManager) handleGetAPIKeys(c echo.Context) error {
+	keys, err := am.storage.ListAPIKeys()
+	if err != nil {
+		return errorJSON(c, http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, keys)
+}
+
+// handleCreateAPIKey creates a named API key and returns its secret
+func (am *AppManager) handleCreateAPIKey(c echo.Context) error {
+	var req CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errorJSON(c, http.StatusBadRequest, "name is required")
+	}
+	if !storage.ValidScope(req.Scope) {
+		return errorJSON(c, http.StatusBadRequest, "scope must be 'read', 'operator', 'write' or 'admin'")
+	}
+
+	namespace, err := am.resolveNamespace(c, req.Namespace)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	if namespace != "" && req.Scope == storage.ScopeAdmin {
+		return errorJSON(c, http.StatusBadRequest, "Namespaced keys can't have the admin scope")
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresIn != "" {
+		ttl, err := parsePeriod(req.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			return errorJSON(c, http.StatusBadRequest, "Invalid expires_in (use '720h', '90d', etc.)")
+		}
+		t := time.Now().Add(ttl)
+		expiresAt = &t
+	}
+
+	key, secret, err := am.storage.CreateAPIKey(req.Name, req.Scope, namespace, expiresAt)
+	if err != nil {
+		return errorJSON(c, http.StatusInternalServerError, err.Error())
+	}
+
+	am.log(c).Printf("API key %s (%s) created by %s", key.Name, key.Scope, authKeyName(c))
+	return c.JSON(http.StatusCreated, CreateAPIKeyResponse{APIKey: key, Secret: secret})
+}
+
+// handleRevokeAPIKey revokes a key; it stays listed until deleted
+func (am *AppManager) handleRevokeAPIKey(c echo.Context) error {
+	key, err := am.storage.RevokeAPIKey(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "API key not found")
+	}
+
+	am.log(c).Printf("API key %s revoked by %s", key.Name, authKeyName(c))
+	return c.JSON(http.StatusOK, key)
+}
+
+// handleDeleteAPIKey removes a key permanently
+func (am *AppManager) handleDeleteAPIKey(c echo.Context) error {
+	if err := am.storage.DeleteAPIKey(c.Param("id")); err != nil {
+		return errorJSON(c, http.StatusNotFound, "API key not found")
+	}
+
+	am.log(c).Printf("API key %s deleted by %s", c.Param("id"), authKeyName(c))
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "API key deleted",
+	})
+}
@@ -0,0 +1,126 @@
+package appmanager
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// authKeyContextKey holds the name of the credential that authenticated the request
+const authKeyContextKey = "auth_key_name"
+
+// authScopeContextKey holds the scope of the credential that authenticated the request
+const authScopeContextKey = "auth_scope"
+
+// authNamespaceContextKey holds the namespace the authenticating key is limited to, if any
+const authNamespaceContextKey = "auth_namespace"
+
+// apiKeyTouchInterval limits how often LastUsedAt is written for a busy key
+const apiKeyTouchInterval = time.Minute
+
+// authenticateAPIKey resolves a presented key to a credential name, scope and namespace.
+// The API_KEY from config is the bootstrap credential and always has admin scope.
+func (am *AppManager) authenticateAPIKey(presented string) (string, string, string, error) {
+	// Compare hashes in constant time; API_KEY may be configured in plain or hashed form
+	presentedHash := []byte(storage.HashAPIKeySecret(presented))
+	if am.apiKey != "" && subtle.ConstantTimeCompare(presentedHash, []byte(storage.ConfigAPIKeyHash(am.apiKey))) == 1 {
+		return "API_KEY", storage.ScopeAdmin, "", nil
+	}
+
+	key, err := am.storage.FindAPIKey(presented)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to look up API key: %w", err)
+	}
+	if key == nil {
+		return "", "", "", fmt.Errorf("Invalid API key")
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return "", "", "", fmt.Errorf("API key %s has been revoked", key.Name)
+	}
+	if !key.Active(now) {
+		return "", "", "", fmt.Errorf("API key %s has expired", key.Name)
+	}
+
+	if !am.isReplica() && (key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval) {
+		if err := am.storage.TouchAPIKey(key.ID, now); err != nil {
+			am.logger.Errorf("Failed to record use of API key %s: %v", key.Name, err)
+		}
+	}
+	return key.Name, key.Scope, key.Namespace, nil
+}
+
+// routeGroup sets the scopes needed to read (GET/HEAD) and to change the routes starting
+// with one of its prefixes
+type routeGroup struct {
+	name     string
+	prefixes []string
+	read     string
+	change   string
+}
+
+// routeGroups are checked in order; routes in no group need read scope to read and write
+// scope to change
+var routeGroups = []routeGroup{
+	// Config (secrets are masked but still partly shown), keys, namespaces, bot users and internals
+	{name: "admin", prefixes: []string{"/config", "/features", "/keys", "/namespaces", "/users", "/admin/", "/debug/"},
+		read: storage.ScopeAdmin, change: storage.ScopeAdmin},
+	// Day-to-day operation that doesn't create, change or delete sources and sinks
+	{name: "operations", prefixes: []string{
+		"/sources/:id/check", "/sources/:id/pause", "/sources/:id/resume", "/sources/:id/notes",
+		"/sources/:id/planned-outages",
+		"/tags/:tag/pause", "/tags/:tag/resume",
+		"/maintenance", "/test/", "/discovery/scan",
+	}, read: storage.ScopeRead, change: storage.ScopeOperator},
+	// Read-only queries, also when sent as POST
+	{name: "graphql", prefixes: []string{"/graphql"}, read: storage.ScopeRead, change: storage.ScopeRead},
+}
+
+// requiredScope returns the scope a request needs according to its route group
+func requiredScope(c echo.Context) string {
+	method := c.Request().Method
+	reading := method == http.MethodGet || method == http.MethodHead
+	path := apiRoute(c)
+
+	for _, group := range routeGroups {
+		for _, prefix := range group.prefixes {
+			if strings.HasPrefix(path, prefix) {
+				if reading {
+					return group.read
+				}
+				return group.change
+			}
+		}
+	}
+	if reading {
+		return storage.ScopeRead
+	}
+	return storage.ScopeWrite
+}
+
+// authKeyName returns the name of the credential that authenticated the request
+func authKeyName(c echo.Context) string {
+	if name, ok := c.Get(authKeyContextKey).(string); ok {
+		return name
+	}
+	return "unknown"
+}
+
+// authNamespace returns the namespace the request's key is limited to; empty for unscoped keys
+func authNamespace(c echo.Context) string {
+	namespace, _ := c.Get(authNamespaceContextKey).(string)
+	return namespace
+}
+
+// authScope returns the scope of the credential that authenticated the request
+func authScope(c echo.Context) string {
+	scope, _ := c.Get(authScopeContextKey).(string)
+	return scope
+}
@@ -0,0 +1,105 @@
+package appmanager
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Badge colors (shields.io palette)
+const (
+	badgeColorOnline  = "#4c1"
+	badgeColorOffline = "#e05d44"
+	badgeColorUnknown = "#9f9f9f"
+	badgeColorLabel   = "#555"
+)
+
+// handleBadge returns a shields.io-style SVG status badge for a source.
+// No API key is required so badges can be embedded in READMEs and wikis; the badge only reveals
+// status and uptime. Optional query params: label (default "status"), period (default 30d).
+func (am *AppManager) handleBadge(c echo.Context) error {
+	source, err := am.storage.GetSource(c.Param("source_id"))
+	if err != nil || source.IsDeleted() {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	label := c.QueryParam("label")
+	if label == "" {
+		label = "status"
+	}
+
+	period := 30 * 24 * time.Hour
+	if periodStr := c.QueryParam("period"); periodStr != "" {
+		period, err = parsePeriod(periodStr)
+		if err != nil || period <= 0 || period > maxUptimePeriod {
+			return errorJSON(c, http.StatusBadRequest, "Invalid period (use e.g. 30d, 7d or 12h; max 366d)")
+		}
+	}
+
+	message, color := publicStatus(source), badgeColorUnknown
+	switch message {
+	case "online":
+		color = badgeColorOnline
+	case "offline":
+		color = badgeColorOffline
+	}
+
+	to := time.Now()
+	if stats, err := am.storage.ComputeUptimeStats(source, to.Add(-period), to); err != nil {
+		am.log(c).Errorf("Badge: failed to compute uptime for %s: %v", source.ID, err)
+	} else if stats.UptimePercent != nil {
+		message = fmt.Sprintf("%s %s", message, formatBadgePercent(*stats.UptimePercent))
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "image/svg+xml;charset=utf-8")
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache, max-age=0")
+	return c.String(http.StatusOK, renderBadge(label, message, color))
+}
+
+// formatBadgePercent trims trailing precision: 100%, 99.95%, 87.5%
+func formatBadgePercent(uptime float64) string {
+	if uptime >= 100 {
+		return "100%"
+	}
+	s := fmt.Sprintf("%.2f", uptime)
+	for s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+	if s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+	return s + "%"
+}
+
+// badgeTextWidth approximates the rendered width of text in 11px Verdana
+func badgeTextWidth(text string) int {
+	return len([]rune(text))*7 + 10
+}
+
+// renderBadge draws a flat two-part badge
+func renderBadge(label, message, color string) string {
+	lw, mw := badgeTextWidth(label), badgeTextWidth(message)
+	total := lw + mw
+	label, message = html.EscapeString(label), html.EscapeString(message)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)">
+<rect width="%[2]d" height="20" fill="%[7]s"/>
+<rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/>
+<rect width="%[1]d" height="20" fill="url(#s)"/>
+</g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text>
+<text x="%[8]d" y="14">%[4]s</text>
+<text x="%[9]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text>
+<text x="%[9]d" y="14">%[5]s</text>
+</g>
+</svg>
+`, total, lw, mw, label, message, color, badgeColorLabel, lw/2, lw+mw/2)
+}
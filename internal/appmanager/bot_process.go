@@ -3,13 +3,14 @@ package appmanager
 import (
 	"context"
 	"fmt"
-	"log"
 	"math"
 	"sync"
 	"time"
 
 	"tg-monitor-bot/internal/bot"
 	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/errreport"
+	"tg-monitor-bot/internal/logging"
 	"tg-monitor-bot/internal/monitor"
 	"tg-monitor-bot/internal/notifier"
 	"tg-monitor-bot/internal/storage"
@@ -25,27 +26,39 @@ type BotProcess struct {
 	bot             *bot.Bot
 	monitor         *monitor.Monitor
 	webhookNotifier *notifier.WebhookNotifier
+	dispatcher      *notifier.Dispatcher // Notifies the sinks of changed sources
+	grouper         *notifier.Grouper    // Combines correlated changes in front of dispatcher
+	events          *monitor.EventBus
 	ctx             context.Context
 	cancel          context.CancelFunc
+	botCancel       context.CancelFunc // Stops only the Telegram bot, see startTelegram
 	running         bool
 	healthy         bool
 	lastError       error
 	startTime       time.Time
 	restartFunc     RestartFunc
+	usersFunc       bot.AllowedUsersFunc // Passed to every Telegram bot for /add_user and /remove_user
 	restartAttempts int
 	restartTimer    *time.Timer
 	mu              sync.Mutex
-	logger          *log.Logger
+	logger          *logging.Logger
 }
 
 // NewBotProcess creates a new BotProcess
 func NewBotProcess(db *storage.BoltDB) *BotProcess {
 	return &BotProcess{
 		storage: db,
-		logger:  log.New(log.Writer(), "[BOT-PROCESS] ", log.LstdFlags),
+		logger:  logging.New("bot_process"),
 	}
 }
 
+// SetEventBus sets the bus that monitors created by this process publish status changes to
+func (bp *BotProcess) SetEventBus(bus *monitor.EventBus) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	bp.events = bus
+}
+
 // SetRestartFunc sets the callback for auto-restart
 func (bp *BotProcess) SetRestartFunc(fn RestartFunc) {
 	bp.mu.Lock()
@@ -53,7 +66,16 @@ func (bp *BotProcess) SetRestartFunc(fn RestartFunc) {
 	bp.restartFunc = fn
 }
 
-// Start initializes and starts bot + monitor
+// SetAllowedUsersFunc sets the callback Telegram bots change ALLOWED_USERS with
+func (bp *BotProcess) SetAllowedUsersFunc(fn bot.AllowedUsersFunc) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	bp.usersFunc = fn
+}
+
+// Start initializes and starts the monitor, then the Telegram bot. The monitor doesn't
+// depend on Telegram: when the bot can't start, checks and webhook notifications keep
+// running and only the Telegram bot is retried.
 func (bp *BotProcess) Start(cfg *config.Config) error {
 	bp.mu.Lock()
 	defer bp.mu.Unlock()
@@ -71,126 +93,119 @@ func (bp *BotProcess) Start(cfg *config.Config) error {
 	// Create context for bot
 	bp.ctx, bp.cancel = context.WithCancel(context.Background())
 
-	// Check if Telegram token is provided (treat placeholder as empty)
-	if cfg.TelegramToken == "" || cfg.TelegramToken == "your_bot_token_here" {
-		bp.logger.Println("⚠️  TELEGRAM_TOKEN not set - running in web-only mode")
-		bp.logger.Println("   Monitor will check sources but won't send Telegram notifications")
-		bp.logger.Println("   API endpoints are fully functional for source management")
-
-		// Create webhook notifier (still sends webhooks even without Telegram)
-		webhookNotifier := notifier.NewWebhookNotifier(bp.storage)
-		bp.webhookNotifier = webhookNotifier
-
-		// Initialize Monitor with webhook callback only (no Telegram bot)
-		mon := monitor.New(bp.storage, cfg, webhookNotifier.OnStatusChange)
-		bp.monitor = mon
-
-		// Start monitor (loads sources and starts goroutines)
-		if err := mon.Start(bp.ctx); err != nil {
-			bp.lastError = fmt.Errorf("failed to start monitor: %w", err)
-			bp.logger.Printf("❌ Monitor start failed: %v", err)
-			bp.running = true     // Mark as running but unhealthy
-			bp.restartAttempts++
-			bp.scheduleAutoRestart() // Schedule auto-restart
-			return nil               // Don't kill the app
-		}
-
-		bp.running = true
-		bp.healthy = true
-		bp.restartAttempts = 0
-		bp.logger.Println("✅ Bot process started in web-only mode (monitor active, no Telegram bot)")
-		return nil
-	}
-
-	// Initialize Bot first (with nil monitor)
-	telegramBot, err := bot.New(cfg, bp.storage, nil)
-	if err != nil {
-		bp.lastError = fmt.Errorf("failed to initialize bot: %w", err)
-		bp.logger.Printf("❌ Bot initialization failed: %v", bp.formatBotError(err))
-		bp.running = true     // Mark as running but unhealthy
-		bp.restartAttempts++
-		bp.scheduleAutoRestart() // Schedule auto-restart
-		return nil               // Don't kill the app
-	}
-	bp.bot = telegramBot
-
-	// Create webhook notifier
-	webhookNotifier := notifier.NewWebhookNotifier(bp.storage)
-	bp.webhookNotifier = webhookNotifier
+	// Webhooks are sent with or without Telegram; startTelegram registers the bot as the
+	// sender for Telegram sinks
+	bp.webhookNotifier = notifier.NewWebhookNotifier(bp.storage)
+	bp.dispatcher = notifier.NewDispatcher(bp.storage)
+	bp.dispatcher.SetSender(storage.SinkTypeWebhook, bp.webhookNotifier)
 
-	// Create composite callback that calls both bot and webhook notifier
-	compositeCallback := func(source *storage.Source, change *storage.StatusChange) {
-		// Call bot callback (Telegram notifications)
-		go telegramBot.OnStatusChange(source, change)
-		// Call webhook notifier callback
-		go webhookNotifier.OnStatusChange(source, change)
-	}
-
-	// Initialize Monitor with composite callback
-	mon := monitor.New(bp.storage, cfg, compositeCallback)
-	bp.monitor = mon
+	bp.grouper = notifier.NewGrouper(bp.dispatcher, bp.storage)
+	bp.grouper.Configure(cfg.AlertGroupWindow, cfg.AlertGroupMinSources)
 
-	// Wire monitor to bot
-	telegramBot.SetMonitor(mon)
+	mon := monitor.New(bp.storage, cfg, bp.statusChangeCallback(bp.grouper))
+	mon.SetEventBus(bp.events)
 
 	// Start monitor (loads sources and starts goroutines)
 	if err := mon.Start(bp.ctx); err != nil {
 		bp.lastError = fmt.Errorf("failed to start monitor: %w", err)
-		bp.logger.Printf("❌ Monitor start failed: %v", err)
-		bp.running = true     // Mark as running but unhealthy
+		bp.logger.Errorf("❌ Monitor start failed: %v", err)
+		bp.running = true // Mark as running but unhealthy
 		bp.restartAttempts++
-		bp.scheduleAutoRestart() // Schedule auto-restart
-		return nil               // Don't kill the app
+		bp.scheduleAutoRestartLocked(false) // Schedule full auto-restart
+		return nil                          // Don't kill the app
 	}
+	bp.monitor = mon
+	bp.running = true
 
-	// Start bot in goroutine with error recovery
-	go bp.runBotWithRecovery(telegramBot)
+	if err := bp.startTelegram(cfg); err != nil {
+		bp.restartAttempts++
+		bp.scheduleAutoRestartLocked(true) // Retry the Telegram bot only
+		return nil                         // Don't kill the app
+	}
 
-	bp.running = true
-	bp.healthy = true
-	bp.restartAttempts = 0 // Reset on successful start
 	bp.logger.Println("✅ Bot process started successfully")
-
 	return nil
 }
 
-// runBotWithRecovery runs the bot with panic recovery
-func (bp *BotProcess) runBotWithRecovery(telegramBot *bot.Bot) {
+// statusChangeCallback notifies the sinks of the source through grouper. Changes during a
+// maintenance window covering the source are recorded but not notified.
+func (bp *BotProcess) statusChangeCallback(grouper *notifier.Grouper) monitor.StatusChangeCallback {
+	return func(ctx context.Context, source *storage.Source, change *storage.StatusChange) {
+		window, err := bp.storage.InMaintenance(source, change.Timestamp)
+		if err != nil {
+			bp.logger.Errorf("Failed to check maintenance windows: %v", err)
+		} else if window != nil {
+			bp.logger.Printf("Notification for %s suppressed by maintenance window %q", source.Name, window.Name)
+			return
+		}
+
+		go grouper.OnStatusChange(ctx, source, change)
+	}
+}
+
+// runBotWithRecovery runs the bot with panic recovery until ctx is cancelled. A crash or
+// unexpected stop schedules a restart of the Telegram bot only; the monitor keeps running.
+func (bp *BotProcess) runBotWithRecovery(ctx context.Context, telegramBot *bot.Bot) {
 	defer func() {
 		if r := recover(); r != nil {
 			bp.mu.Lock()
-			bp.healthy = false
-			bp.lastError = fmt.Errorf("bot panic: %v", r)
-			bp.restartAttempts++
+			current := bp.bot == telegramBot
+			if current {
+				bp.healthy = false
+				bp.lastError = fmt.Errorf("bot panic: %v", r)
+				bp.restartAttempts++
+			}
 			bp.mu.Unlock()
-			bp.logger.Printf("❌ Bot panicked: %v", r)
-
-			// Schedule auto-restart
-			bp.scheduleAutoRestart()
+			bp.logger.Errorf("❌ Bot panicked: %v", r)
+			errreport.CapturePanic(ctx, r, errreport.Tags{"component": "bot"})
+			recordSystemEvent(bp.storage, bp.logger, storage.SystemEventPanic, "Bot panicked", map[string]string{
+				"component": "bot",
+				"error":     fmt.Sprint(r),
+			})
+
+			// Schedule auto-restart, unless the bot was already replaced
+			if current {
+				bp.scheduleAutoRestart(true)
+			}
 		}
 	}()
 
 	// Start bot - this blocks until context is cancelled
-	telegramBot.Start(bp.ctx)
+	telegramBot.Start(ctx)
 
 	// If we get here, bot stopped normally
 	bp.mu.Lock()
-	wasUnexpected := bp.ctx.Err() == nil
+	wasUnexpected := ctx.Err() == nil && bp.bot == telegramBot
 	if wasUnexpected {
 		// Bot stopped unexpectedly (not due to cancellation)
 		bp.healthy = false
 		bp.lastError = fmt.Errorf("bot stopped unexpectedly")
 		bp.restartAttempts++
-		bp.logger.Println("⚠️  Bot stopped unexpectedly")
+		bp.logger.Warnf("⚠️  Bot stopped unexpectedly")
 	}
 	bp.mu.Unlock()
 
-	// Schedule auto-restart if it was unexpected
 	if wasUnexpected {
-		bp.scheduleAutoRestart()
+		recordSystemEvent(bp.storage, bp.logger, storage.SystemEventBotStop, "Bot stopped unexpectedly", map[string]string{
+			"component": "telegram",
+		})
+		bp.scheduleAutoRestart(true)
 	}
 }
 
+// StartReplica prepares the process for a read-only replica: the monitor is created so
+// handlers can read sources through it, but it never checks anything, and there is no
+// Telegram bot or webhook notifier
+func (bp *BotProcess) StartReplica(cfg *config.Config) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	bp.config = cfg
+	bp.startTime = time.Now()
+	bp.monitor = monitor.New(bp.storage, cfg, nil)
+	bp.logger.Println("Read-only replica: checks and the Telegram bot are not started")
+}
+
 // Stop gracefully stops bot and monitor
 func (bp *BotProcess) Stop() error {
 	bp.mu.Lock()
@@ -209,6 +224,11 @@ func (bp *BotProcess) Stop() error {
 		bp.logger.Println("Cancelled pending auto-restart")
 	}
 
+	// Changes held back for grouping are notified before the senders go away
+	if bp.grouper != nil {
+		bp.grouper.Flush()
+	}
+
 	// Cancel context to stop all goroutines
 	if bp.cancel != nil {
 		bp.cancel()
@@ -219,14 +239,126 @@ func (bp *BotProcess) Stop() error {
 
 	bp.running = false
 	bp.bot = nil
+	bp.botCancel = nil
 	bp.monitor = nil
 	bp.webhookNotifier = nil
+	bp.dispatcher = nil
+	bp.grouper = nil
 
 	bp.logger.Println("Bot process stopped")
 
 	return nil
 }
 
+// Reload applies a config change without tearing down the monitor: check settings are
+// updated in place and the Telegram bot is recreated only when reloadTelegram is set.
+// Returns an error when the monitor isn't running; callers fall back to Restart. A Telegram
+// bot that fails to start with the new config is retried on its own.
+func (bp *BotProcess) Reload(cfg *config.Config, reload configReload) error {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	if !bp.running || bp.monitor == nil {
+		return fmt.Errorf("bot process is not running")
+	}
+
+	bp.config = cfg // Auto-restart settings are read from here on use
+	if reload&reloadMonitor != 0 {
+		bp.monitor.UpdateConfig(cfg)
+		if bp.grouper != nil {
+			bp.grouper.Configure(cfg.AlertGroupWindow, cfg.AlertGroupMinSources)
+		}
+		bp.logger.Println("Monitor settings updated in place")
+	}
+	if reload&reloadTelegram != 0 {
+		if err := bp.startTelegram(cfg); err != nil {
+			bp.restartAttempts++
+			bp.scheduleAutoRestartLocked(true)
+		}
+	} else if reload&reloadAccess != 0 && bp.bot != nil {
+		bp.bot.SetAllowedUsers(cfg.AllowedUsers)
+		bp.logger.Println("Allowed users updated in place")
+	}
+	return nil
+}
+
+// RestartTelegram recreates the Telegram bot with the current config, leaving the monitor
+// running. Used by auto-restart after Telegram failures.
+func (bp *BotProcess) RestartTelegram() error {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	if !bp.running || bp.monitor == nil {
+		return fmt.Errorf("bot process is not running")
+	}
+
+	if err := bp.startTelegram(bp.config); err != nil {
+		bp.restartAttempts++
+		bp.scheduleAutoRestartLocked(true)
+		return err
+	}
+
+	recordSystemEvent(bp.storage, bp.logger, storage.SystemEventBotRestart, "Telegram bot restarted (auto-restart)", map[string]string{
+		"reason":    "auto-restart",
+		"component": "telegram",
+	})
+	return nil
+}
+
+// startTelegram replaces the Telegram bot, leaving the monitor and its goroutines running.
+// On success the process is healthy again. Must be called with bp.mu held.
+func (bp *BotProcess) startTelegram(cfg *config.Config) error {
+	if bp.botCancel != nil {
+		bp.botCancel()
+		bp.botCancel = nil
+		// Give the old bot time to stop polling, Telegram rejects concurrent getUpdates
+		time.Sleep(500 * time.Millisecond)
+	}
+	bp.bot = nil
+	bp.dispatcher.SetSender(storage.SinkTypeTelegram, nil)
+
+	if cfg.TelegramToken == "" || cfg.TelegramToken == "your_bot_token_here" {
+		bp.logger.Println("⚠️  TELEGRAM_TOKEN not set - running in web-only mode")
+		bp.logger.Println("   Monitor will check sources but won't send Telegram notifications")
+		bp.logger.Println("   API endpoints are fully functional for source management")
+		bp.telegramStarted()
+		return nil
+	}
+
+	telegramBot, err := bot.New(cfg, bp.storage, nil)
+	if err != nil {
+		bp.healthy = false
+		bp.lastError = fmt.Errorf("failed to initialize bot: %w", err)
+		bp.logger.Errorf("❌ Bot initialization failed: %v (monitor keeps running)", bp.formatBotError(err))
+		return bp.lastError
+	}
+	telegramBot.SetMonitor(bp.monitor)
+	telegramBot.SetAllowedUsersFunc(bp.usersFunc)
+	telegramBot.SetDispatcher(bp.dispatcher)
+	bp.bot = telegramBot
+	bp.dispatcher.SetSender(storage.SinkTypeTelegram, telegramBot)
+
+	botCtx, botCancel := context.WithCancel(bp.ctx)
+	bp.botCancel = botCancel
+	go bp.runBotWithRecovery(botCtx, telegramBot)
+
+	bp.logger.Println("✅ Telegram bot started")
+	bp.telegramStarted()
+	return nil
+}
+
+// telegramStarted marks the process healthy and drops a pending Telegram retry.
+// Must be called with bp.mu held.
+func (bp *BotProcess) telegramStarted() {
+	bp.healthy = true
+	bp.lastError = nil
+	bp.restartAttempts = 0
+	if bp.restartTimer != nil {
+		bp.restartTimer.Stop()
+		bp.restartTimer = nil
+	}
+}
+
 // Restart stops and starts with new config
 func (bp *BotProcess) Restart(cfg *config.Config) error {
 	bp.logger.Println("Restarting bot process with new config...")
@@ -320,13 +452,38 @@ func (bp *BotProcess) GetStatus() map[string]interface{} {
 			sources, err := bp.monitor.GetAllSources()
 			if err == nil {
 				enabled := 0
+				failing := []map[string]interface{}{}
 				for _, src := range sources {
 					if src.Enabled {
 						enabled++
 					}
+					if src.Enabled && src.LastError != "" {
+						failing = append(failing, map[string]interface{}{
+							"id":              src.ID,
+							"name":            src.Name,
+							"last_error":      src.LastError,
+							"last_error_time": src.LastErrorTime,
+						})
+					}
 				}
 				status["total_sources"] = len(sources)
 				status["active_sources"] = enabled
+				status["failing_sources"] = failing
+			}
+
+			// Check loop health; notifications in flight are deliveries started for status
+			// changes but not yet finished
+			var queued int64
+			if bp.dispatcher != nil {
+				queued = bp.dispatcher.InFlight()
+			}
+			perf := bp.monitor.Stats()
+			status["performance"] = map[string]interface{}{
+				"checks_per_minute":        perf.ChecksPerMinute,
+				"avg_check_duration_ms":    perf.AvgCheckDurationMs,
+				"active_monitors":          perf.ActiveMonitors,
+				"last_storage_write":       perf.LastStorageWrite,
+				"notification_queue_depth": queued,
 			}
 		}
 	}
@@ -342,11 +499,16 @@ func maskString(s string) string {
 	return s[:4] + "..." + s[len(s)-4:]
 }
 
-// scheduleAutoRestart schedules an automatic restart after backoff delay
-func (bp *BotProcess) scheduleAutoRestart() {
+// scheduleAutoRestart schedules an automatic restart after backoff delay: of the Telegram
+// bot alone when telegramOnly is set, of the whole process otherwise
+func (bp *BotProcess) scheduleAutoRestart(telegramOnly bool) {
 	bp.mu.Lock()
 	defer bp.mu.Unlock()
+	bp.scheduleAutoRestartLocked(telegramOnly)
+}
 
+// scheduleAutoRestartLocked is scheduleAutoRestart for callers holding bp.mu
+func (bp *BotProcess) scheduleAutoRestartLocked(telegramOnly bool) {
 	// Check if auto-restart is enabled
 	if bp.config == nil || !bp.config.AutoRestartEnabled {
 		bp.logger.Println("Auto-restart disabled, not scheduling restart")
@@ -355,14 +517,18 @@ func (bp *BotProcess) scheduleAutoRestart() {
 
 	// Check max attempts
 	if bp.config.AutoRestartMaxAttempts > 0 && bp.restartAttempts >= bp.config.AutoRestartMaxAttempts {
-		bp.logger.Printf("⚠️  Max restart attempts (%d) reached, not scheduling restart", bp.config.AutoRestartMaxAttempts)
+		bp.logger.Warnf("⚠️  Max restart attempts (%d) reached, not scheduling restart", bp.config.AutoRestartMaxAttempts)
 		return
 	}
 
 	// Calculate backoff delay
 	delay := bp.calculateBackoffDelay()
 
-	bp.logger.Printf("🔄 Scheduling auto-restart in %s (attempt %d)", delay, bp.restartAttempts+1)
+	component := "bot process"
+	if telegramOnly {
+		component = "Telegram bot"
+	}
+	bp.logger.Printf("🔄 Scheduling %s auto-restart in %s (attempt %d)", component, delay, bp.restartAttempts+1)
 
 	// Cancel existing timer if any
 	if bp.restartTimer != nil {
@@ -372,12 +538,18 @@ func (bp *BotProcess) scheduleAutoRestart() {
 	// Schedule restart
 	bp.restartTimer = time.AfterFunc(delay, func() {
 		bp.logger.Println("⏰ Auto-restart timer triggered")
+		if telegramOnly {
+			if err := bp.RestartTelegram(); err != nil {
+				bp.logger.Errorf("❌ Telegram bot auto-restart failed: %v", err)
+			}
+			return
+		}
 		if bp.restartFunc != nil {
 			if err := bp.restartFunc(); err != nil {
-				bp.logger.Printf("❌ Auto-restart failed: %v", err)
+				bp.logger.Errorf("❌ Auto-restart failed: %v", err)
 			}
 		} else {
-			bp.logger.Println("⚠️  No restart function set, cannot auto-restart")
+			bp.logger.Warnf("⚠️  No restart function set, cannot auto-restart")
 		}
 	})
 }
@@ -415,6 +587,13 @@ func (bp *BotProcess) IncrementRestartAttempts() {
 	bp.restartAttempts++
 }
 
+// GetConfig returns the config the process is running with, nil before the first start
+func (bp *BotProcess) GetConfig() *config.Config {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	return bp.config
+}
+
 // GetMonitor returns the monitor instance
 func (bp *BotProcess) GetMonitor() *monitor.Monitor {
 	bp.mu.Lock()
@@ -443,6 +622,13 @@ func (bp *BotProcess) GetWebhookNotifier() *notifier.WebhookNotifier {
 	return bp.webhookNotifier
 }
 
+// GetDispatcher returns the notification dispatcher, or nil while the bot process is stopped
+func (bp *BotProcess) GetDispatcher() *notifier.Dispatcher {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	return bp.dispatcher
+}
+
 // formatBotError converts cryptic Telegram API errors into user-friendly messages
 func (bp *BotProcess) formatBotError(err error) string {
 	errStr := err.Error()
@@ -0,0 +1,104 @@
+package appmanager
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// Conditional request headers (not defined by Echo)
+const (
+	headerETag        = "ETag"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// gzipMinLength skips compressing small responses, where gzip overhead outweighs savings
+const gzipMinLength = 1024
+
+// compressionMiddleware gzips responses for clients that accept it. The SSE stream is
+// skipped: compressed events would sit in the gzip buffer instead of being flushed. So are
+// pprof profiles, which are gzipped already.
+func compressionMiddleware() echo.MiddlewareFunc {
+	return middleware.GzipWithConfig(middleware.GzipConfig{
+		Skipper: func(c echo.Context) bool {
+			route := apiRoute(c)
+			return route == "/events/stream" || route == "/debug/pprof/*"
+		},
+		MinLength: gzipMinLength,
+	})
+}
+
+// etagWriter holds back a response so its ETag can be computed before sending it
+type etagWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *etagWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// etagMiddleware adds a weak ETag to successful GET responses and answers 304 Not Modified
+// when If-None-Match matches, so polling clients skip unchanged bodies. Responses are
+// still rendered in full; the saving is bandwidth, not server work.
+func etagMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if c.Request().Method != http.MethodGet {
+			return next(c)
+		}
+
+		res := c.Response()
+		original := res.Writer
+		buffered := &etagWriter{ResponseWriter: original, status: http.StatusOK}
+		res.Writer = buffered
+		err := next(c)
+		res.Writer = original
+		if err != nil {
+			return err
+		}
+
+		// Send the held-back response through Echo again so status and size are accurate
+		res.Committed = false
+		res.Size = 0
+
+		header := res.Header()
+		if buffered.status == http.StatusOK {
+			sum := sha256.Sum256(buffered.body.Bytes())
+			etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+			header.Set(headerETag, etag)
+			if header.Get(echo.HeaderCacheControl) == "" {
+				header.Set(echo.HeaderCacheControl, "no-cache") // Cache, but revalidate every time
+			}
+			if etagMatches(c.Request().Header.Get(headerIfNoneMatch), etag) {
+				header.Del(echo.HeaderContentType)
+				res.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+		}
+
+		res.WriteHeader(buffered.status)
+		_, err = res.Write(buffered.body.Bytes())
+		return err
+	}
+}
+
+// etagMatches reports whether an If-None-Match header matches etag (weak comparison)
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,445 @@
+package appmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/storage"
+)
+
+// configFileEnv names the YAML file with declarative config and sources. Like DB_PATH it
+// is read from the environment only, since it decides where the rest of the config comes from.
+const configFileEnv = "CONFIG_FILE"
+
+// managedByFile marks sources declared in the config file
+const managedByFile = "file"
+
+// configFileDebounce coalesces the burst of events editors and ConfigMap updates produce
+const configFileDebounce = 500 * time.Millisecond
+
+// configFile is the layout of CONFIG_FILE
+type configFile struct {
+	Config  map[string]string `yaml:"config"`  // Same keys as the environment variables
+	Sources []fileSource      `yaml:"sources"` // Declared sources, matched to stored ones by name
+}
+
+// fileSource declares a source in the config file
+type fileSource struct {
+	Name                  string                  `yaml:"name"`
+	Type                  string                  `yaml:"type"`
+	Target                string                  `yaml:"target"`
+	CheckInterval         string                  `yaml:"check_interval"`
+	Enabled               *bool                   `yaml:"enabled"` // Default true
+	Public                bool                    `yaml:"public"`
+	Tags                  []string                `yaml:"tags"`
+	GracePeriodMultiplier *float64                `yaml:"grace_period_multiplier"`
+	ExpectedHeaders       string                  `yaml:"expected_headers"`
+	ExpectedContent       string                  `yaml:"expected_content"`
+	SLO                   *storage.SLO            `yaml:"slo"`
+	ResponseLimits        *storage.ResponseLimits `yaml:"response_limits"`
+	ResponseSchema        interface{}             `yaml:"response_schema"` // JSON Schema written in YAML
+	RedirectPolicy        *storage.RedirectPolicy `yaml:"redirect_policy"`
+	TLSPolicy             *storage.TLSPolicy      `yaml:"tls_policy"`
+	Locations             []string                `yaml:"locations"`
+	Resolver              string                  `yaml:"resolver"`
+	AddressFamily         string                  `yaml:"address_family"`
+	Proxy                 string                  `yaml:"proxy"`
+	Headers               map[string]string       `yaml:"headers"`
+	HTTPVersion           string                  `yaml:"http_version"`
+	OutageMessage         string                  `yaml:"outage_message"`
+	RecoveryMessage       string                  `yaml:"recovery_message"`
+}
+
+// configFileState tracks the config file and the result of applying it, for /status
+type configFileState struct {
+	mu          sync.Mutex
+	path        string
+	hash        [sha256.Size]byte // Of the last applied content, to skip no-op events
+	lastApplied time.Time
+	lastError   string
+}
+
+// status returns the file's state for /status, nil when no file is configured
+func (s *configFileState) status() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" {
+		return nil
+	}
+	status := map[string]interface{}{"path": s.path}
+	if !s.lastApplied.IsZero() {
+		status["last_applied"] = s.lastApplied
+	}
+	if s.lastError != "" {
+		status["last_error"] = s.lastError
+	}
+	return status
+}
+
+// readConfigFile parses the config file. Content is returned so callers can skip unchanged files.
+func readConfigFile(path string) (*configFile, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var file configFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true) // Typos in field names are errors, not silently ignored settings
+
+	// io.EOF means the file is empty
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	return &file, data, nil
+}
+
+// loadConfigFile applies the config section of CONFIG_FILE during startup, before the
+// config is read, so API and bot settings from the file take effect on this start
+func (am *AppManager) loadConfigFile() *configFile {
+	path := os.Getenv(configFileEnv)
+	if path == "" {
+		return nil
+	}
+	if am.isReplica() {
+		am.logger.Warnf("Ignoring %s: a read-only replica serves the primary's config and sources", configFileEnv)
+		return nil
+	}
+	am.configFile.path = path
+
+	file, data, err := readConfigFile(path)
+	if err != nil {
+		am.configFileFailed(fmt.Errorf("failed to read %s: %w", path, err))
+		return nil
+	}
+	if err := am.applyFileConfig(file.Config); err != nil {
+		am.configFileFailed(err)
+		return nil
+	}
+	am.configFile.hash = sha256.Sum256(data)
+	return file
+}
+
+// reloadConfigFile re-reads CONFIG_FILE and applies config and sources when the content changed
+func (am *AppManager) reloadConfigFile() {
+	file, data, err := readConfigFile(am.configFile.path)
+	if err != nil {
+		am.configFileFailed(fmt.Errorf("failed to read %s: %w", am.configFile.path, err))
+		return
+	}
+
+	hash := sha256.Sum256(data)
+	am.configFile.mu.Lock()
+	unchanged := hash == am.configFile.hash
+	am.configFile.mu.Unlock()
+	if unchanged {
+		return
+	}
+
+	am.logger.Printf("Config file %s changed, applying", am.configFile.path)
+	if err := am.applyConfigFile(file); err != nil {
+		am.configFileFailed(err)
+		return
+	}
+	am.configFile.mu.Lock()
+	am.configFile.hash = hash
+	am.configFile.mu.Unlock()
+}
+
+// applyConfigFile validates the whole file first and applies nothing if any part is invalid
+func (am *AppManager) applyConfigFile(file *configFile) error {
+	changes, err := am.planFileSources(file.Sources)
+	if err != nil {
+		return err
+	}
+	if err := am.applyFileConfig(file.Config); err != nil {
+		return err
+	}
+
+	if len(changes) > 0 {
+		sources := make([]*storage.Source, len(changes))
+		for i, change := range changes {
+			sources[i] = change.source
+		}
+		if err := am.storage.SaveSources(sources); err != nil {
+			return fmt.Errorf("failed to save sources from config file: %w", err)
+		}
+		if am.botProcess != nil {
+			am.reconcileBulkChanges(changes)
+		}
+		am.logger.Printf("Applied %d source change(s) from config file", len(changes))
+	}
+
+	am.configFileApplied()
+	return nil
+}
+
+// applyFileConfig validates the config section merged over the current config and saves the
+// values that differ, triggering one reload
+func (am *AppManager) applyFileConfig(values map[string]string) error {
+	current := am.configManager.GetAll()
+	merged := am.configManager.GetAll()
+	for key, value := range values {
+		merged[key] = value
+	}
+
+	// Only problems with values from the file block it; the rest are already in effect
+	var messages []string
+	for _, p := range config.Validate(merged) {
+		if _, fromFile := values[p.Key]; p.Severity == config.SeverityError && (fromFile || p.Key == "") {
+			messages = append(messages, strings.TrimPrefix(p.Key+": "+p.Message, ": "))
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(messages, "; "))
+	}
+
+	changed := make(map[string]string)
+	for key, value := range values {
+		stored := value
+		if key == "API_KEY" {
+			stored = storage.HashConfigAPIKey(value)
+		}
+		if current[key] != stored {
+			changed[key] = value
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if err := am.configManager.SetMany(changed, managedByFile); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(changed))
+	for key := range changed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		am.recordSystemEvent(storage.SystemEventConfigChange, "Config updated: "+key, map[string]string{
+			"key": key,
+			"by":  "config file",
+		})
+	}
+	return nil
+}
+
+// planFileSources matches declared sources to stored ones by name and returns the creates,
+// updates and deletes (of file-managed sources no longer declared) needed to converge.
+// Stored sources created through the API are adopted when a declared source has their name.
+func (am *AppManager) planFileSources(declared []fileSource) ([]*bulkChange, error) {
+	stored, err := am.storage.GetAllSources()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sources: %w", err)
+	}
+	byName := make(map[string]*storage.Source)
+	for _, source := range stored {
+		if existing, ok := byName[source.Name]; !ok || (existing.ManagedBy != managedByFile && source.ManagedBy == managedByFile) {
+			byName[source.Name] = source
+		}
+	}
+
+	var changes []*bulkChange
+	var problems []string
+	declaredNames := make(map[string]bool)
+	for i, decl := range declared {
+		if declaredNames[decl.Name] {
+			problems = append(problems, fmt.Sprintf("sources[%d]: duplicate name %q", i, decl.Name))
+			continue
+		}
+		declaredNames[decl.Name] = true
+
+		enabled := decl.Enabled == nil || *decl.Enabled
+		tags := decl.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		locations := decl.Locations
+		if locations == nil {
+			locations = []string{}
+		}
+		slo := decl.SLO
+		if slo == nil {
+			slo = &storage.SLO{} // Not declared: remove
+		}
+		responseLimits := decl.ResponseLimits
+		if responseLimits == nil {
+			responseLimits = &storage.ResponseLimits{} // Not declared: remove
+		}
+		redirectPolicy := decl.RedirectPolicy
+		if redirectPolicy == nil {
+			redirectPolicy = &storage.RedirectPolicy{} // Not declared: default
+		}
+		headers := decl.Headers
+		if headers == nil {
+			headers = map[string]string{} // Not declared: remove
+		}
+		tlsPolicy := decl.TLSPolicy
+		if tlsPolicy == nil {
+			tlsPolicy = &storage.TLSPolicy{} // Not declared: remove
+		}
+		responseSchema, err := json.Marshal(decl.ResponseSchema) // Not declared: null, which removes it
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("sources[%d] (%s): response_schema: %v", i, decl.Name, err))
+			continue
+		}
+
+		existing, ok := byName[decl.Name]
+		if !ok {
+			source, err := sourceFromCreateRequest(CreateSourceRequest{
+				Name: decl.Name, Type: decl.Type, Target: decl.Target, CheckInterval: decl.CheckInterval,
+				GracePeriodMultiplier: decl.GracePeriodMultiplier, ExpectedHeaders: decl.ExpectedHeaders,
+				ExpectedContent: decl.ExpectedContent, Public: decl.Public, Tags: tags, SLO: decl.SLO,
+				Locations: locations, OutageMessage: decl.OutageMessage, RecoveryMessage: decl.RecoveryMessage,
+				ResponseLimits: decl.ResponseLimits, ResponseSchema: responseSchema,
+				RedirectPolicy: decl.RedirectPolicy, TLSPolicy: decl.TLSPolicy, Resolver: decl.Resolver,
+				AddressFamily: decl.AddressFamily, Proxy: decl.Proxy, Headers: decl.Headers,
+				HTTPVersion: decl.HTTPVersion,
+			})
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("sources[%d] (%s): %v", i, decl.Name, err))
+				continue
+			}
+			if source.Type == "webhook" {
+				if source.WebhookToken, err = am.generateWebhookToken(); err != nil {
+					return nil, fmt.Errorf("failed to generate webhook token: %w", err)
+				}
+			}
+			source.Enabled = enabled
+			source.ManagedBy = managedByFile
+			changes = append(changes, &bulkChange{op: "create", source: source})
+			continue
+		}
+
+		updated := *existing
+		if err := applyUpdateRequest(&updated, UpdateSourceRequest{
+			Name: decl.Name, Type: decl.Type, Target: decl.Target, CheckInterval: decl.CheckInterval,
+			Enabled: enabled, GracePeriodMultiplier: decl.GracePeriodMultiplier,
+			ExpectedHeaders: decl.ExpectedHeaders, ExpectedContent: decl.ExpectedContent,
+			Public: &decl.Public, Tags: tags, SLO: slo, Locations: locations,
+			ResponseLimits: responseLimits, ResponseSchema: responseSchema,
+			RedirectPolicy: redirectPolicy, TLSPolicy: tlsPolicy, Resolver: &decl.Resolver,
+			AddressFamily: &decl.AddressFamily, Proxy: &decl.Proxy, Headers: headers, HTTPVersion: &decl.HTTPVersion,
+			OutageMessage: &decl.OutageMessage, RecoveryMessage: &decl.RecoveryMessage,
+		}); err != nil {
+			problems = append(problems, fmt.Sprintf("sources[%d] (%s): %v", i, decl.Name, err))
+			continue
+		}
+		if updated.Type == "webhook" && updated.WebhookToken == "" {
+			if updated.WebhookToken, err = am.generateWebhookToken(); err != nil {
+				return nil, fmt.Errorf("failed to generate webhook token: %w", err)
+			}
+		}
+		updated.ManagedBy = managedByFile
+		if !sameDefinition(&updated, existing) {
+			changes = append(changes, &bulkChange{op: "update", source: &updated})
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid sources: %s", strings.Join(problems, "; "))
+	}
+
+	// Sources removed from the file go to trash, so a bad edit can be undone
+	now := time.Now()
+	for _, source := range stored {
+		if source.ManagedBy == managedByFile && !declaredNames[source.Name] {
+			deleted := *source
+			deleted.DeletedAt = &now
+			changes = append(changes, &bulkChange{op: "delete", source: &deleted})
+		}
+	}
+
+	return changes, nil
+}
+
+// sameDefinition reports whether two sources agree on everything the config file declares
+func sameDefinition(a, b *storage.Source) bool {
+	return a.Name == b.Name && a.Type == b.Type && a.Target == b.Target &&
+		a.CheckInterval == b.CheckInterval && a.Enabled == b.Enabled && a.Public == b.Public &&
+		slices.Equal(a.Tags, b.Tags) && slices.Equal(a.Locations, b.Locations) && a.GracePeriodMultiplier == b.GracePeriodMultiplier &&
+		a.ExpectedHeaders == b.ExpectedHeaders && a.ExpectedContent == b.ExpectedContent &&
+		a.OutageMessage == b.OutageMessage && a.RecoveryMessage == b.RecoveryMessage &&
+		a.WebhookToken == b.WebhookToken && a.ManagedBy == b.ManagedBy &&
+		(a.SLO == nil) == (b.SLO == nil) && (a.SLO == nil || *a.SLO == *b.SLO) &&
+		(a.ResponseLimits == nil) == (b.ResponseLimits == nil) && (a.ResponseLimits == nil || *a.ResponseLimits == *b.ResponseLimits) &&
+		bytes.Equal(a.ResponseSchema, b.ResponseSchema) &&
+		(a.RedirectPolicy == nil) == (b.RedirectPolicy == nil) && (a.RedirectPolicy == nil || *a.RedirectPolicy == *b.RedirectPolicy) &&
+		a.TLSPolicy.Equal(b.TLSPolicy) && a.Resolver == b.Resolver && a.AddressFamily == b.AddressFamily &&
+		a.Proxy == b.Proxy && maps.Equal(a.Headers, b.Headers) && a.HTTPVersion == b.HTTPVersion
+}
+
+// watchConfigFile re-applies CONFIG_FILE whenever it changes, until ctx is cancelled. The
+// directory is watched rather than the file, so editors that replace the file and
+// Kubernetes ConfigMaps (which swap a symlink) are picked up too.
+func (am *AppManager) watchConfigFile(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(am.configFile.path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				debounce = time.After(configFileDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				am.logger.Errorf("Config file watcher error: %v", err)
+			case <-debounce:
+				debounce = nil
+				am.reloadConfigFile()
+			}
+		}
+	}()
+
+	am.logger.Printf("Watching config file %s", am.configFile.path)
+	return nil
+}
+
+// configFileApplied records a successful apply
+func (am *AppManager) configFileApplied() {
+	am.configFile.mu.Lock()
+	defer am.configFile.mu.Unlock()
+	am.configFile.lastApplied = time.Now()
+	am.configFile.lastError = ""
+}
+
+// configFileFailed records and logs a rejected file; the current config stays in effect
+func (am *AppManager) configFileFailed(err error) {
+	am.configFile.mu.Lock()
+	defer am.configFile.mu.Unlock()
+	am.configFile.lastError = err.Error()
+	am.logger.Errorf("❌ Config file not applied: %v", err)
+}
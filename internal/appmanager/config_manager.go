@@ -1,30 +1,103 @@
 package appmanager
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"sync"
 
 	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/logging"
 	"tg-monitor-bot/internal/storage"
 )
 
+// ErrFileBackedConfig is returned when changing a config value that is read from a *_FILE secret
+var ErrFileBackedConfig = errors.New("value is read from a *_FILE secret and can't be changed at runtime")
+
+// envKeys are the config keys read from the environment (and from <KEY>_FILE secrets)
+var envKeys = []string{
+	"TELEGRAM_TOKEN",
+	"ALLOWED_USERS",
+	"ADMIN_CHAT_IDS",
+	"DB_PATH",
+	"PING_COUNT",
+	"PING_TIMEOUT",
+	"HTTP_TIMEOUT",
+	"DEFAULT_CHECK_INTERVAL",
+	"METRICS_RETENTION",
+	"CHECK_FLUSH_INTERVAL",
+	"DELETED_SOURCE_RETENTION",
+	"COMPACTION_INTERVAL",
+	"LOG_FILE",
+	"LOG_FILE_MAX_SIZE",
+	"LOG_FILE_MAX_AGE",
+	"LOG_FILE_MAX_BACKUPS",
+	"OTLP_ENDPOINT",
+	"OTLP_HEADERS",
+	"TRACING_SAMPLE_RATIO",
+	"HEARTBEAT_URL",
+	"HEARTBEAT_INTERVAL",
+	"WATCHDOG_TIMEOUT",
+	"ALERT_GROUP_WINDOW",
+	"ALERT_GROUP_MIN_SOURCES",
+	"SENTRY_DSN",
+	"SENTRY_ENVIRONMENT",
+	"SLO_FAST_BURN_RATE",
+	"SLO_SLOW_BURN_RATE",
+	"REPORT_PERIODS",
+	"REPORT_EMAIL_TO",
+	"SMTP_HOST",
+	"SMTP_PORT",
+	"SMTP_USERNAME",
+	"SMTP_PASSWORD",
+	"SMTP_FROM",
+	"DISCOVERY_RANGES",
+	"DISCOVERY_PORTS",
+	"DISCOVERY_INTERVAL",
+	"DNS_RESOLVER",
+	"HTTP_USER_AGENT",
+	"HTTP_HEADERS",
+	"HTTP_MAX_IDLE_CONNS",
+	"HTTP_MAX_CONNS_PER_HOST",
+	"HTTP_DISABLE_KEEP_ALIVES",
+	"HTTP_TLS_HANDSHAKE_TIMEOUT",
+	"API_ENABLED",
+	"API_PORT",
+	"API_BIND",
+	"API_KEY",
+	"TLS_CERT_FILE",
+	"TLS_KEY_FILE",
+	"TLS_AUTOCERT_DOMAIN",
+	"TLS_AUTOCERT_CACHE_DIR",
+	"CORS_ALLOWED_ORIGINS",
+	"CORS_ALLOWED_HEADERS",
+	"OIDC_ISSUER_URL",
+	"OIDC_AUDIENCE",
+	"OIDC_ROLES_CLAIM",
+	"OIDC_ROLE_MAPPING",
+	"GRAPHQL_ENABLED",
+	"PPROF_ENABLED",
+	"STATUS_PAGE_ENABLED",
+	"STATUS_PAGE_TITLE",
+}
+
 // ConfigManager manages configuration with DB persistence
 type ConfigManager struct {
 	storage  *storage.BoltDB
 	cache    map[string]string
+	fileKeys map[string]string // Keys read from <KEY>_FILE secrets, to the file path; never persisted
 	mu       sync.RWMutex
 	onChange func() // Callback when config changes
-	logger   *log.Logger
+	logger   *logging.Logger
 }
 
 // NewConfigManager creates a new ConfigManager
 func NewConfigManager(db *storage.BoltDB) *ConfigManager {
 	return &ConfigManager{
-		storage: db,
-		cache:   make(map[string]string),
-		logger:  log.New(log.Writer(), "[CONFIG] ", log.LstdFlags),
+		storage:  db,
+		cache:    make(map[string]string),
+		fileKeys: make(map[string]string),
+		logger:   logging.New("config"),
 	}
 }
 
@@ -33,7 +106,8 @@ func (cm *ConfigManager) SetOnChange(callback func()) {
 	cm.onChange = callback
 }
 
-// Load reads config from DB, falls back to environment variables
+// Load reads config from DB, falls back to environment variables. Values from <KEY>_FILE
+// secrets override both and are kept in memory only.
 func (cm *ConfigManager) Load() error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
@@ -44,43 +118,55 @@ func (cm *ConfigManager) Load() error {
 		return fmt.Errorf("failed to get config from DB: %w", err)
 	}
 
+	if cm.storage.ReadOnly() {
+		return cm.loadReadOnly(dbConfigs)
+	}
+
 	if len(dbConfigs) > 0 {
 		// Load from database
 		cm.logger.Printf("Loading configuration from database (%d entries)", len(dbConfigs))
 		for key, entry := range dbConfigs {
 			cm.cache[key] = entry.Value
 		}
-		return nil
+
+		// Databases written before API_KEY was hashed at rest still hold it in plain text
+		if entry, ok := dbConfigs["API_KEY"]; ok {
+			if hashed := storage.HashConfigAPIKey(entry.Value); hashed != entry.Value {
+				cm.cache["API_KEY"] = hashed
+				if err := cm.storage.SaveConfig("API_KEY", hashed, entry.UpdatedBy); err != nil {
+					cm.logger.Warnf("Failed to hash stored API_KEY: %v", err)
+				} else {
+					cm.logger.Println("Stored API_KEY replaced with its SHA-256 hash")
+				}
+			}
+		}
+		return cm.loadFileValues()
 	}
 
 	// Database is empty, load from environment and save to DB
 	cm.logger.Println("Database empty, loading from environment variables")
 
-	envKeys := []string{
-		"TELEGRAM_TOKEN",
-		"ALLOWED_USERS",
-		"DB_PATH",
-		"PING_COUNT",
-		"PING_TIMEOUT",
-		"HTTP_TIMEOUT",
-		"DEFAULT_CHECK_INTERVAL",
-		"METRICS_RETENTION",
-		"API_ENABLED",
-		"API_PORT",
-		"API_KEY",
-	}
-
 	for _, key := range envKeys {
+		if os.Getenv(key+"_FILE") != "" {
+			continue // Secrets from files are never written to the database
+		}
 		value := os.Getenv(key)
+		if key == "API_KEY" {
+			value = storage.HashConfigAPIKey(value)
+		}
 		if value != "" {
 			cm.cache[key] = value
 			// Save to database for future runs
 			if err := cm.storage.SaveConfig(key, value, "env"); err != nil {
-				cm.logger.Printf("Warning: Failed to save %s to DB: %v", key, err)
+				cm.logger.Warnf("Failed to save %s to DB: %v", key, err)
 			}
 		}
 	}
 
+	if err := cm.loadFileValues(); err != nil {
+		return err
+	}
+
 	// Set defaults for missing values
 	cm.setDefaults()
 
@@ -88,17 +174,77 @@ func (cm *ConfigManager) Load() error {
 	return nil
 }
 
+// loadReadOnly loads the config of a read-only replica: the copied config with the
+// replica's own environment on top (e.g. a different API_KEY or API_PORT), without
+// writing anything back
+func (cm *ConfigManager) loadReadOnly(dbConfigs map[string]*storage.ConfigEntry) error {
+	for key, entry := range dbConfigs {
+		cm.cache[key] = entry.Value
+	}
+	overridden := 0
+	for _, key := range envKeys {
+		if value := os.Getenv(key); value != "" {
+			cm.cache[key] = value
+			overridden++
+		}
+	}
+	if value, ok := cm.cache["API_KEY"]; ok {
+		cm.cache["API_KEY"] = storage.HashConfigAPIKey(value)
+	}
+
+	if err := cm.loadFileValues(); err != nil {
+		return err
+	}
+	cm.setDefaults()
+
+	cm.logger.Printf("Loaded %d config entries from the read-only database, %d overridden by environment", len(dbConfigs), overridden)
+	return nil
+}
+
+// loadFileValues reads <KEY>_FILE secrets (Docker/Kubernetes style) into the cache.
+// A secret that can't be read fails startup rather than silently running without it.
+func (cm *ConfigManager) loadFileValues() error {
+	for _, key := range envKeys {
+		value, ok, err := config.FileValue(key)
+		if !ok {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if key == "API_KEY" {
+			value = storage.HashConfigAPIKey(value)
+		}
+		cm.cache[key] = value
+		cm.fileKeys[key] = os.Getenv(key + "_FILE")
+		cm.logger.Printf("Loaded %s from %s_FILE", key, key)
+	}
+	return nil
+}
+
+// FilePath returns the file a config value is read from, or "" when it isn't file-backed
+func (cm *ConfigManager) FilePath(key string) string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.fileKeys[key]
+}
+
 // setDefaults sets default values for missing config
 func (cm *ConfigManager) setDefaults() {
 	defaults := map[string]string{
-		"DB_PATH":                "data/state.db",
-		"PING_COUNT":             "3",
-		"PING_TIMEOUT":           "5s",
-		"HTTP_TIMEOUT":           "10s",
-		"DEFAULT_CHECK_INTERVAL": "30s",
-		"METRICS_RETENTION":      "720h",
-		"API_ENABLED":            "true",
-		"API_PORT":               "8080",
+		"DB_PATH":                  "data/state.db",
+		"PING_COUNT":               "3",
+		"PING_TIMEOUT":             "5s",
+		"HTTP_TIMEOUT":             "10s",
+		"DEFAULT_CHECK_INTERVAL":   "30s",
+		"METRICS_RETENTION":        "720h",
+		"CHECK_FLUSH_INTERVAL":     "30s",
+		"DELETED_SOURCE_RETENTION": "720h",
+		"API_ENABLED":              "true",
+		"API_PORT":                 "8080",
+		"GRAPHQL_ENABLED":          "false",
+		"STATUS_PAGE_ENABLED":      "false",
+		"STATUS_PAGE_TITLE":        "Service Status",
 	}
 
 	for key, defaultValue := range defaults {
@@ -120,6 +266,14 @@ func (cm *ConfigManager) Set(key, value string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
+	if _, ok := cm.fileKeys[key]; ok {
+		return fmt.Errorf("%s: %w", key, ErrFileBackedConfig)
+	}
+
+	if key == "API_KEY" {
+		value = storage.HashConfigAPIKey(value)
+	}
+
 	// Update cache
 	cm.cache[key] = value
 
@@ -138,6 +292,43 @@ func (cm *ConfigManager) Set(key, value string) error {
 	return nil
 }
 
+// SetMany updates several config values at once, recording updatedBy as their origin,
+// and triggers a single onChange callback
+func (cm *ConfigManager) SetMany(values map[string]string, updatedBy string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	for key := range values {
+		if _, ok := cm.fileKeys[key]; ok {
+			return fmt.Errorf("%s: %w", key, ErrFileBackedConfig)
+		}
+	}
+
+	for key, value := range values {
+		if key == "API_KEY" {
+			value = storage.HashConfigAPIKey(value)
+		}
+		if err := cm.storage.SaveConfig(key, value, updatedBy); err != nil {
+			return fmt.Errorf("failed to save config %s to DB: %w", key, err)
+		}
+		cm.cache[key] = value
+		cm.logger.Printf("Config updated from %s: %s", updatedBy, key)
+	}
+
+	if cm.onChange != nil && len(values) > 0 {
+		go cm.onChange()
+	}
+
+	return nil
+}
+
+// FeatureEnabled reports whether a feature flag is on, falling back to its default
+func (cm *ConfigManager) FeatureEnabled(name string) bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return config.FeatureEnabled(cm.cache, name)
+}
+
 // GetAll returns all config as map
 func (cm *ConfigManager) GetAll() map[string]string {
 	cm.mu.RLock()
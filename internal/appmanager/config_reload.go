@@ -0,0 +1,110 @@
+package appmanager
+
+import (
+	"slices"
+	"strings"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/storage"
+)
+
+// configReload is a set of bot process components a config change has to reload
+type configReload int
+
+const (
+	reloadMonitor  configReload = 1 << iota // Check settings, applied to the running monitor in place
+	reloadTelegram                          // Token, recreates the Telegram bot only
+	reloadAccess                            // Allowed users, applied to the running Telegram bot in place
+	reloadFull                              // Everything else the bot process reads once at start
+)
+
+// String lists the reloaded components, e.g. "monitor+telegram"
+func (r configReload) String() string {
+	if r&reloadFull != 0 {
+		return "full"
+	}
+	var parts []string
+	if r&reloadMonitor != 0 {
+		parts = append(parts, "monitor")
+	}
+	if r&reloadTelegram != 0 {
+		parts = append(parts, "telegram")
+	}
+	if r&reloadAccess != 0 {
+		parts = append(parts, "access")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, "+")
+}
+
+// classifyConfigChange decides what a change from prev to next config has to reload. Settings
+// the bot process doesn't use (API, maintenance, status page) reload nothing: they are read
+// from the ConfigManager on use or need an application restart anyway.
+func classifyConfigChange(prev, next *config.Config) configReload {
+	var reload configReload
+
+	// The flush loop is started once per monitor
+	if prev.CheckFlushInterval != next.CheckFlushInterval {
+		reload |= reloadFull
+	}
+
+	if prev.TelegramToken != next.TelegramToken {
+		reload |= reloadTelegram
+	}
+	if !slices.Equal(prev.AllowedUsers, next.AllowedUsers) {
+		reload |= reloadAccess
+	}
+
+	if prev.PingCount != next.PingCount || prev.PingTimeout != next.PingTimeout ||
+		prev.HTTPTimeout != next.HTTPTimeout || prev.DefaultCheckInterval != next.DefaultCheckInterval ||
+		prev.DNSResolver != next.DNSResolver || prev.HTTPUserAgent != next.HTTPUserAgent || prev.HTTPHeaders != next.HTTPHeaders {
+		reload |= reloadMonitor
+	}
+	// New transport settings replace the monitor's transports, dropping idle connections
+	if prev.HTTPMaxIdleConns != next.HTTPMaxIdleConns || prev.HTTPMaxConnsPerHost != next.HTTPMaxConnsPerHost ||
+		prev.HTTPDisableKeepAlives != next.HTTPDisableKeepAlives || prev.HTTPTLSHandshakeTimeout != next.HTTPTLSHandshakeTimeout {
+		reload |= reloadMonitor
+	}
+	// Notification grouping is reconfigured along with the monitor
+	if prev.AlertGroupWindow != next.AlertGroupWindow || prev.AlertGroupMinSources != next.AlertGroupMinSources {
+		reload |= reloadMonitor
+	}
+
+	return reload
+}
+
+// applyConfigChange reloads only the parts of the bot process affected by a config change,
+// falling back to a full restart when that isn't possible
+func (am *AppManager) applyConfigChange() error {
+	cfg, err := am.configManager.AsConfig()
+	if err != nil {
+		am.logger.Errorf("❌ Failed to get config for reload: %v", err)
+		return err
+	}
+
+	current := am.botProcess.GetConfig()
+	if current == nil {
+		return am.RestartBot("config change")
+	}
+
+	reload := classifyConfigChange(current, cfg)
+	if reload&reloadFull != 0 || !am.configManager.FeatureEnabled("selective_reload") {
+		return am.RestartBot("config change")
+	}
+
+	if err := am.botProcess.Reload(cfg, reload); err != nil {
+		am.logger.Warnf("Selective reload failed (%v), restarting bot", err)
+		return am.RestartBot("config change")
+	}
+
+	am.logger.Printf("✅ Config applied without full restart (reloaded: %s)", reload)
+	if reload&reloadTelegram != 0 {
+		am.recordSystemEvent(storage.SystemEventBotRestart, "Telegram bot restarted (config change)", map[string]string{
+			"reason":    "config change",
+			"component": "telegram",
+		})
+	}
+	return nil
+}
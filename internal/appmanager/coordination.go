@@ -0,0 +1,70 @@
+package appmanager
+
+import (
+	"context"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"tg-monitor-bot/internal/coord"
+	"tg-monitor-bot/internal/monitor"
+)
+
+// eventsChannel carries status changes from the leader to replicas
+const eventsChannel = "events"
+
+// SetCoordinator shares status changes through Redis: the leader publishes them and
+// read-only replicas republish them to their own event stream. Call before Start.
+func (am *AppManager) SetCoordinator(client *coord.Client) {
+	am.coord = client
+}
+
+// startCoordination starts the event fan-out for this instance's role, until Shutdown
+func (am *AppManager) startCoordination() {
+	if am.coord == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	am.coordCancel = cancel
+
+	if am.isReplica() {
+		am.logger.Printf("Receiving status changes from Redis at %s", am.coord.Addr())
+		go am.coord.Subscribe(ctx, eventsChannel, am.receiveEvent, func(err error) {
+			am.logger.Warnf("Redis subscription failed, reconnecting: %v", err)
+		})
+		return
+	}
+	am.logger.Printf("Publishing status changes to Redis at %s", am.coord.Addr())
+	go am.publishEvents(ctx)
+}
+
+// publishEvents forwards the monitor's status changes to Redis until ctx is cancelled
+func (am *AppManager) publishEvents(ctx context.Context) {
+	events, unsubscribe := am.events.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			payload, err := msgpack.Marshal(event)
+			if err != nil {
+				am.logger.Warnf("Failed to encode status change: %v", err)
+				continue
+			}
+			if err := am.coord.Publish(ctx, eventsChannel, payload); err != nil && ctx.Err() == nil {
+				am.logger.Warnf("Failed to publish status change of %s to Redis: %v", event.SourceName, err)
+			}
+		}
+	}
+}
+
+// receiveEvent republishes a status change from the leader to local subscribers
+func (am *AppManager) receiveEvent(payload []byte) {
+	var event monitor.Event
+	if err := msgpack.Unmarshal(payload, &event); err != nil || event.Change == nil {
+		am.logger.Warnf("Ignoring malformed status change from Redis: %v", err)
+		return
+	}
+	am.events.Publish(event)
+}
@@ -0,0 +1,127 @@
+package appmanager
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// mimeTextCSV is the content type of the CSV exports
+const mimeTextCSV = "text/csv; charset=utf-8"
+
+// statusName names a status value in exports
+func statusName(status int) string {
+	switch status {
+	case 1:
+		return "online"
+	case 0:
+		return "offline"
+	}
+	return "unknown"
+}
+
+// handleGetSourceHistoryCSV exports a source's status changes over ?from= (default 30 days
+// ago) to ?to= (default now) as CSV, one row per change, oldest first
+func (am *AppManager) handleGetSourceHistoryCSV(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+	from, to, err := parseHistoryRange(c, 30*24*time.Hour)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	changes, err := am.storage.GetStatusChangesInRange(source.ID, from, to)
+	if err != nil {
+		am.log(c).Errorf("Failed to get status changes: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get events")
+	}
+
+	w := startCSV(c, source, "history", from, to)
+	w.Write([]string{"timestamp", "source_id", "source_name", "old_status", "new_status", "previous_status_duration_ms"})
+	for _, change := range changes {
+		w.Write([]string{
+			change.Timestamp.UTC().Format(time.RFC3339),
+			source.ID,
+			source.Name,
+			statusName(change.OldStatus),
+			statusName(change.NewStatus),
+			strconv.FormatInt(change.DurationMs, 10),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// handleGetSourceMetricsCSV exports a source's daily uptime metrics over the UTC days
+// touched by ?from= (default 30 days ago) to ?to= (default now) as CSV. Completed days come
+// from the daily rollups; days not rolled up yet, such as today, are replayed.
+func (am *AppManager) handleGetSourceMetricsCSV(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+	from, to, err := parseHistoryRange(c, 30*24*time.Hour)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	firstDay := from.UTC().Truncate(24 * time.Hour)
+
+	rollups, err := am.storage.GetDailyRollups(source.ID, firstDay, to)
+	if err != nil {
+		am.log(c).Errorf("Failed to get rollups: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get rollups")
+	}
+	byDate := make(map[string]*storage.DailyRollup, len(rollups))
+	for _, rollup := range rollups {
+		byDate[rollup.Date] = rollup
+	}
+
+	var days []*storage.DailyRollup
+	for day := firstDay; day.Before(to) && !day.After(time.Now()); day = day.AddDate(0, 0, 1) {
+		rollup, ok := byDate[day.Format("2006-01-02")]
+		if !ok {
+			if rollup, err = am.storage.ComputeDailyRollup(source, day); err != nil {
+				am.log(c).Errorf("Failed to compute rollup: %v", err)
+				return errorJSON(c, http.StatusInternalServerError, "Failed to compute metrics")
+			}
+		}
+		days = append(days, rollup)
+	}
+
+	w := startCSV(c, source, "metrics", from, to)
+	w.Write([]string{"date", "source_id", "source_name", "uptime_percent", "monitored_ms", "downtime_ms", "outage_count"})
+	for _, rollup := range days {
+		uptime := "" // Nothing was monitored that day
+		if rollup.MonitoredMs > 0 {
+			uptime = strconv.FormatFloat(rollup.UptimePercent, 'f', 4, 64)
+		}
+		w.Write([]string{
+			rollup.Date,
+			source.ID,
+			source.Name,
+			uptime,
+			strconv.FormatInt(rollup.MonitoredMs, 10),
+			strconv.FormatInt(rollup.DowntimeMs, 10),
+			strconv.Itoa(rollup.OutageCount),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// startCSV writes the headers of a CSV download named like
+// <source>-history-2026-03-01-2026-03-31.csv and returns a writer for the body
+func startCSV(c echo.Context, source *storage.Source, kind string, from, to time.Time) *csv.Writer {
+	name := source.ID + "-" + kind + "-" + from.UTC().Format("2006-01-02") + "-" + to.UTC().Format("2006-01-02") + ".csv"
+	c.Response().Header().Set(echo.HeaderContentType, mimeTextCSV)
+	c.Response().Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Response().WriteHeader(http.StatusOK)
+	return csv.NewWriter(c.Response())
+}
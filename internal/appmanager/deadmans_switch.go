@@ -0,0 +1,121 @@
+package appmanager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"tg-monitor-bot/internal/config"
+)
+
+// deadMansSwitchTick is how often the heartbeat loop checks whether a ping is due, so
+// HEARTBEAT_URL and HEARTBEAT_INTERVAL changes apply without a restart
+const deadMansSwitchTick = 10 * time.Second
+
+// deadMansSwitchTimeout bounds a single heartbeat request
+const deadMansSwitchTimeout = 10 * time.Second
+
+// deadMansSwitch tracks the outgoing heartbeats to HEARTBEAT_URL. An external service such
+// as healthchecks.io alerts when they stop, which covers the monitor host itself going down.
+type deadMansSwitch struct {
+	mu          sync.Mutex
+	client      *http.Client
+	lastAttempt time.Time
+	lastSent    time.Time
+	lastError   string
+}
+
+// status returns the heartbeat state for /status, nil when HEARTBEAT_URL is unset
+func (s *deadMansSwitch) status(cfg *config.Config) map[string]interface{} {
+	if cfg == nil || cfg.HeartbeatURL == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := map[string]interface{}{"interval": cfg.HeartbeatInterval.String()}
+	if !s.lastSent.IsZero() {
+		status["last_sent"] = s.lastSent
+	}
+	if s.lastError != "" {
+		status["last_error"] = s.lastError
+	}
+	return status
+}
+
+// runDeadMansSwitch sends heartbeats until ctx is cancelled
+func (am *AppManager) runDeadMansSwitch(ctx context.Context) {
+	ticker := time.NewTicker(deadMansSwitchTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cfg, err := am.configManager.AsConfig()
+			if err != nil || cfg.HeartbeatURL == "" {
+				continue
+			}
+			am.deadMansSwitch.mu.Lock()
+			due := time.Since(am.deadMansSwitch.lastAttempt) >= cfg.HeartbeatInterval
+			am.deadMansSwitch.mu.Unlock()
+			if due {
+				am.sendDeadMansHeartbeat(ctx, cfg)
+			}
+		}
+	}
+}
+
+// sendDeadMansHeartbeat pings HEARTBEAT_URL, but only while the monitor is running and its
+// checks complete: a stopped or stalled monitor must look dead to the external service.
+func (am *AppManager) sendDeadMansHeartbeat(ctx context.Context, cfg *config.Config) {
+	s := &am.deadMansSwitch
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastAttempt = time.Now()
+
+	if am.botProcess == nil || !am.botProcess.IsRunning() {
+		s.lastError = "skipped: monitor is not running"
+		am.logger.Warnf("Dead man's switch heartbeat skipped: monitor is not running")
+		return
+	}
+	if stalled, _ := am.watchdog.get(); stalled {
+		s.lastError = "skipped: checks stalled"
+		am.logger.Warnf("Dead man's switch heartbeat skipped: checks stalled")
+		return
+	}
+
+	if err := s.ping(ctx, cfg.HeartbeatURL); err != nil {
+		s.lastError = err.Error()
+		am.logger.Warnf("Dead man's switch heartbeat failed: %v", err)
+		return
+	}
+	s.lastSent = s.lastAttempt
+	s.lastError = ""
+}
+
+// ping requests url and expects a 2xx response
+func (s *deadMansSwitch) ping(ctx context.Context, url string) error {
+	if s.client == nil {
+		s.client = &http.Client{Timeout: deadMansSwitchTimeout}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid HEARTBEAT_URL: %w", err)
+	}
+	req.Header.Set("User-Agent", "outage-monitor-bot")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
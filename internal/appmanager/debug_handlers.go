@@ -0,0 +1,116 @@
+package appmanager
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// RuntimeDebug is the response of GET /debug/runtime
+type RuntimeDebug struct {
+	GoVersion      string           `json:"go_version"`
+	NumCPU         int              `json:"num_cpu"`
+	GOMAXPROCS     int              `json:"gomaxprocs"`
+	Goroutines     int              `json:"goroutines"`
+	MonitorWorkers int              `json:"monitor_workers"` // Per-source check goroutines
+	Heap           RuntimeHeapStats `json:"heap"`
+	GC             RuntimeGCStats   `json:"gc"`
+	Bolt           *storage.DBStats `json:"bolt,omitempty"`
+}
+
+// RuntimeHeapStats are the heap figures from runtime.MemStats, in bytes
+type RuntimeHeapStats struct {
+	Alloc      uint64 `json:"alloc"`
+	Sys        uint64 `json:"sys"`
+	Inuse      uint64 `json:"inuse"`
+	Idle       uint64 `json:"idle"`
+	Released   uint64 `json:"released"`
+	Objects    uint64 `json:"objects"`
+	TotalAlloc uint64 `json:"total_alloc"`
+}
+
+// RuntimeGCStats summarize garbage collection since start
+type RuntimeGCStats struct {
+	NumGC        uint32     `json:"num_gc"`
+	PauseTotalMs float64    `json:"pause_total_ms"`
+	LastGC       *time.Time `json:"last_gc,omitempty"`
+	NextGC       uint64     `json:"next_gc"` // Heap size that triggers the next GC
+}
+
+// handleDebugRuntime reports goroutine, heap, GC and bbolt statistics
+func (am *AppManager) handleDebugRuntime(c echo.Context) error {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	report := RuntimeDebug{
+		GoVersion:  runtime.Version(),
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: runtime.NumGoroutine(),
+		Heap: RuntimeHeapStats{
+			Alloc:      mem.HeapAlloc,
+			Sys:        mem.HeapSys,
+			Inuse:      mem.HeapInuse,
+			Idle:       mem.HeapIdle,
+			Released:   mem.HeapReleased,
+			Objects:    mem.HeapObjects,
+			TotalAlloc: mem.TotalAlloc,
+		},
+		GC: RuntimeGCStats{
+			NumGC:        mem.NumGC,
+			PauseTotalMs: float64(mem.PauseTotalNs) / float64(time.Millisecond),
+			NextGC:       mem.NextGC,
+		},
+	}
+	if mem.LastGC > 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC))
+		report.GC.LastGC = &lastGC
+	}
+	if am.botProcess != nil {
+		if m := am.botProcess.GetMonitor(); m != nil {
+			report.MonitorWorkers = m.ActiveMonitors()
+		}
+	}
+
+	stats, err := am.storage.Stats()
+	if err != nil {
+		am.log(c).Errorf("Failed to read database stats: %v", err)
+	} else {
+		report.Bolt = stats
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// handlePprof serves net/http/pprof under /debug/pprof/ (404 unless PPROF_ENABLED).
+// The profile name is taken from the route, since pprof's own index only dispatches
+// below the unprefixed /debug/pprof/ path.
+func (am *AppManager) handlePprof(c echo.Context) error {
+	cfg, err := am.configManager.AsConfig()
+	if err != nil || !cfg.PprofEnabled {
+		return errorJSON(c, http.StatusNotFound, "pprof is disabled")
+	}
+
+	var handler http.Handler
+	switch name := c.Param("*"); name {
+	case "":
+		handler = http.HandlerFunc(pprof.Index)
+	case "cmdline":
+		handler = http.HandlerFunc(pprof.Cmdline)
+	case "profile":
+		handler = http.HandlerFunc(pprof.Profile)
+	case "symbol":
+		handler = http.HandlerFunc(pprof.Symbol)
+	case "trace":
+		handler = http.HandlerFunc(pprof.Trace)
+	default:
+		handler = pprof.Handler(name)
+	}
+	handler.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
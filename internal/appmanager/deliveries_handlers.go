@@ -0,0 +1,47 @@
+package appmanager
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// handleGetDeliveries returns notification delivery attempts, newest first.
+// Optional query params: source_id, sink_type, status_change_id, success (true/false), limit.
+func (am *AppManager) handleGetDeliveries(c echo.Context) error {
+	filter := storage.DeliveryFilter{
+		SourceID:       c.QueryParam("source_id"),
+		SinkType:       c.QueryParam("sink_type"),
+		StatusChangeID: c.QueryParam("status_change_id"),
+		Limit:          100,
+	}
+
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 1000 {
+			filter.Limit = l
+		}
+	}
+
+	if successStr := c.QueryParam("success"); successStr != "" {
+		success, err := strconv.ParseBool(successStr)
+		if err != nil {
+			return errorJSON(c, http.StatusBadRequest, "success must be true or false")
+		}
+		filter.Success = &success
+	}
+
+	deliveries, err := am.storage.GetDeliveries(filter)
+	if err != nil {
+		am.log(c).Errorf("Failed to get deliveries: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get deliveries")
+	}
+
+	if deliveries == nil {
+		deliveries = []*storage.Delivery{}
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
@@ -0,0 +1,284 @@
+package appmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/monitor"
+	"tg-monitor-bot/internal/storage"
+)
+
+// discoveryTick is how often the scheduler looks whether a discovery scan is due
+const discoveryTick = time.Minute
+
+// DiscoveryResponse is the response body for GET /discovery
+type DiscoveryResponse struct {
+	Scan  *monitor.DiscoveryScan    `json:"scan"` // Last or running scan since startup; null if none
+	Hosts []*storage.DiscoveredHost `json:"hosts"`
+}
+
+// DiscoveryScanRequest is the request body for POST /discovery/scan; empty fields use
+// DISCOVERY_RANGES and DISCOVERY_PORTS
+type DiscoveryScanRequest struct {
+	Ranges []string `json:"ranges,omitempty"` // CIDR ranges or single IPv4 addresses
+	Ports  []int    `json:"ports,omitempty"`  // TCP ports probed on every address
+}
+
+// PromoteHostsRequest is the request body for POST /discovery/promote
+type PromoteHostsRequest struct {
+	Hosts         []string `json:"hosts"`                    // Discovered host IPs
+	Type          string   `json:"type,omitempty"`           // "ping" (default) or "http"
+	CheckInterval string   `json:"check_interval,omitempty"` // Default: DEFAULT_CHECK_INTERVAL
+	Tags          []string `json:"tags,omitempty"`
+	Template      string   `json:"template,omitempty"` // Template ID or name; type, interval and tags are then ignored
+}
+
+// handleGetDiscovery lists discovered hosts with the last scan; ?new=true keeps only hosts
+// no source monitors yet
+func (am *AppManager) handleGetDiscovery(c echo.Context) error {
+	hosts, err := am.storage.GetDiscoveredHosts()
+	if err != nil {
+		am.log(c).Errorf("Failed to list discovered hosts: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to list discovered hosts")
+	}
+
+	onlyNew := c.QueryParam("new") == "true"
+	resp := DiscoveryResponse{Hosts: []*storage.DiscoveredHost{}}
+	for _, host := range hosts {
+		if !onlyNew || host.SourceID == "" {
+			resp.Hosts = append(resp.Hosts, host)
+		}
+	}
+	if mon := am.botProcess.GetMonitor(); mon != nil {
+		resp.Scan = mon.LastDiscovery()
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// handleStartDiscovery starts a discovery scan in the background; poll GET /discovery for
+// the result
+func (am *AppManager) handleStartDiscovery(c echo.Context) error {
+	var req DiscoveryScanRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+	mon := am.botProcess.GetMonitor()
+	if mon == nil {
+		return errorJSON(c, http.StatusServiceUnavailable, "Monitor not available")
+	}
+
+	cfg, err := am.configManager.AsConfig()
+	if err != nil {
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get config")
+	}
+	if len(req.Ranges) == 0 {
+		req.Ranges = cfg.DiscoveryRanges
+	}
+	if len(req.Ports) == 0 {
+		req.Ports = cfg.DiscoveryPorts
+	}
+	for _, port := range req.Ports {
+		if port < 1 || port > 65535 {
+			return errorJSON(c, http.StatusBadRequest, fmt.Sprintf("Invalid port: %d", port))
+		}
+	}
+
+	scan, err := mon.StartDiscovery(req.Ranges, req.Ports, nil)
+	if errors.Is(err, monitor.ErrDiscoveryRunning) {
+		return errorJSON(c, http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	am.log(c).Printf("Discovery scan of %v started via API", scan.Ranges)
+	return c.JSON(http.StatusAccepted, scan)
+}
+
+// handlePromoteHosts creates a source for each discovered host, like a bulk create
+func (am *AppManager) handlePromoteHosts(c echo.Context) error {
+	var req PromoteHostsRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+	if len(req.Hosts) == 0 {
+		return errorJSON(c, http.StatusBadRequest, "hosts must not be empty")
+	}
+	if len(req.Hosts) > maxBulkOperations {
+		return errorJSON(c, http.StatusBadRequest, fmt.Sprintf("At most %d hosts per request", maxBulkOperations))
+	}
+	if req.Type == "" {
+		req.Type = "ping"
+	}
+	if req.Template == "" && req.Type != "ping" && req.Type != "http" {
+		return errorJSON(c, http.StatusBadRequest, "type must be 'ping' or 'http'")
+	}
+	if req.CheckInterval == "" && req.Template == "" {
+		cfg, err := am.configManager.AsConfig()
+		if err != nil {
+			return errorJSON(c, http.StatusInternalServerError, "Failed to get config")
+		}
+		req.CheckInterval = cfg.DefaultCheckInterval.String()
+	}
+
+	results := make([]BulkSourceResult, len(req.Hosts))
+	changes := make([]*bulkChange, len(req.Hosts))
+	seen := make(map[string]bool)
+	failed := 0
+
+	for i, ip := range req.Hosts {
+		results[i] = BulkSourceResult{Index: i, Op: "create"}
+		if seen[ip] {
+			results[i].Status, results[i].Error = "failed", "Host appears more than once in this request"
+			failed++
+			continue
+		}
+		seen[ip] = true
+
+		change, err := am.preparePromotion(ip, req)
+		if err != nil {
+			results[i].Status, results[i].Error = "failed", fmt.Sprintf("%s: %v", ip, err)
+			failed++
+			continue
+		}
+		changes[i] = change
+		results[i].ID = change.source.ID
+	}
+
+	var toSave []*storage.Source
+	for _, change := range changes {
+		if change != nil {
+			toSave = append(toSave, change.source)
+		}
+	}
+	if len(toSave) > 0 {
+		if err := am.storage.SaveSources(toSave); err != nil {
+			return errorJSON(c, http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	applied := 0
+	for i, change := range changes {
+		if change == nil {
+			continue
+		}
+		if change.template != nil {
+			if err := am.storage.AttachTemplateSinks(change.template, change.source.ID); err != nil {
+				am.log(c).Errorf("Failed to attach template sinks to %s: %v", change.source.ID, err)
+			}
+		}
+		if err := am.storage.SetDiscoveredHostSource(req.Hosts[i], change.source.ID); err != nil {
+			am.log(c).Warnf("Failed to link discovered host %s to %s: %v", req.Hosts[i], change.source.ID, err)
+		}
+		results[i].Status = "applied"
+		results[i].Source = change.source
+		applied++
+	}
+
+	am.reconcileBulkChanges(changes)
+
+	am.log(c).Printf("Promoted discovered hosts via API: %d applied, %d failed", applied, failed)
+
+	return c.JSON(http.StatusOK, BulkSourceResponse{Applied: applied, Failed: failed, Results: results})
+}
+
+// preparePromotion validates a discovered host and builds the source monitoring it
+func (am *AppManager) preparePromotion(ip string, req PromoteHostsRequest) (*bulkChange, error) {
+	host, err := am.storage.GetDiscoveredHost(ip)
+	if err != nil {
+		return nil, errors.New("Discovered host not found")
+	}
+	if host.SourceID != "" {
+		if source, err := am.getLiveSource(host.SourceID); err == nil {
+			return nil, fmt.Errorf("Already monitored by %s", source.Name)
+		}
+	}
+
+	create := CreateSourceRequest{
+		Name:          host.SourceName(),
+		Type:          req.Type,
+		CheckInterval: req.CheckInterval,
+		Tags:          req.Tags,
+		Template:      req.Template,
+	}
+	if req.Template == "" {
+		create.Target = host.SourceTarget(req.Type)
+		source, err := sourceFromCreateRequest(create)
+		if err != nil {
+			return nil, err
+		}
+		return &bulkChange{op: "create", source: source}, nil
+	}
+
+	template, err := am.storage.FindSourceTemplate(req.Template)
+	if err != nil {
+		return nil, fmt.Errorf("Unknown template: %s", req.Template)
+	}
+	if template.Type == "webhook" {
+		return nil, errors.New("Webhook templates can't monitor a host")
+	}
+	create.Target = host.SourceTarget(template.Type)
+	source, template, err := am.sourceFromTemplate(create)
+	if err != nil {
+		return nil, err
+	}
+	return &bulkChange{op: "create", source: source, template: template}, nil
+}
+
+// handleDeleteDiscoveredHost forgets a discovered host until a scan finds it again
+func (am *AppManager) handleDeleteDiscoveredHost(c echo.Context) error {
+	ip := c.Param("ip")
+	if err := am.storage.DeleteDiscoveredHost(ip); err != nil {
+		return errorJSON(c, http.StatusNotFound, "Discovered host not found")
+	}
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Discovered host deleted successfully",
+		"ip":      ip,
+	})
+}
+
+// runDiscovery scans DISCOVERY_RANGES every DISCOVERY_INTERVAL and alerts admin chats when
+// hosts no source monitors show up. The first scan runs a tick after startup.
+func (am *AppManager) runDiscovery(ctx context.Context) {
+	ticker := time.NewTicker(discoveryTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if cfg, err := am.configManager.AsConfig(); err == nil {
+				am.scanIfDue(ctx, cfg, time.Now())
+			}
+		}
+	}
+}
+
+// scanIfDue starts a scheduled discovery scan when the last one started an interval ago
+func (am *AppManager) scanIfDue(ctx context.Context, cfg *config.Config, now time.Time) {
+	mon := am.botProcess.GetMonitor()
+	if cfg.DiscoveryInterval <= 0 || len(cfg.DiscoveryRanges) == 0 || mon == nil {
+		return
+	}
+	if last := mon.LastDiscovery(); last != nil && (last.Running || now.Sub(last.StartedAt) < cfg.DiscoveryInterval) {
+		return
+	}
+
+	_, err := mon.StartDiscovery(cfg.DiscoveryRanges, cfg.DiscoveryPorts, func(scan *monitor.DiscoveryScan) {
+		if scan.Error != "" || scan.New == 0 {
+			return
+		}
+		am.alertAdmins(ctx, cfg, fmt.Sprintf("🔎 Discovery found %d new host(s) in %v. Use /discovered to list them and /promote to monitor them.",
+			scan.New, scan.Ranges))
+	})
+	if err != nil && !errors.Is(err, monitor.ErrDiscoveryRunning) {
+		am.logger.Warnf("Scheduled discovery scan failed to start: %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+package appmanager
+
+import (
+	"os"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/errreport"
+)
+
+// applyErrorReporting starts, reconfigures or stops error reporting to SENTRY_DSN. Like
+// tracing it runs at start and on every config change; unchanged settings keep the client.
+func (am *AppManager) applyErrorReporting(cfg *config.Config) {
+	hostname, _ := os.Hostname()
+	if err := errreport.Setup(errreport.Options{
+		DSN:         cfg.SentryDSN,
+		Environment: cfg.SentryEnvironment,
+		Release:     am.version,
+		ServerName:  hostname,
+	}); err != nil {
+		am.logger.Errorf("❌ Error reporting disabled: SENTRY_DSN: %v", err)
+	}
+}
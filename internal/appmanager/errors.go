@@ -0,0 +1,112 @@
+package appmanager
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"tg-monitor-bot/internal/errreport"
+	"tg-monitor-bot/internal/storage"
+)
+
+// ErrorResponse is the body of every API error response
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes an API error
+type ErrorDetail struct {
+	Code      string `json:"code"` // Machine-readable status, e.g. not_found or method_not_allowed
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"` // Same as the X-Request-ID response header
+}
+
+// setupBaseMiddleware installs request IDs, access logging, panic recovery, compression
+// and the JSON error handler. Must run before any other middleware so that every response carries a
+// request ID and every request is logged.
+func (am *AppManager) setupBaseMiddleware() {
+	am.echoServer.HTTPErrorHandler = am.httpErrorHandler
+	am.echoServer.Use(middleware.RequestID())
+	am.echoServer.Use(am.accessLogMiddleware)
+	// Inside the access log so panics are logged as 500s
+	am.echoServer.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
+		LogErrorFunc: am.recordHandlerPanic,
+	}))
+	am.echoServer.Use(compressionMiddleware())
+}
+
+// recordHandlerPanic logs a recovered handler panic with its stack, records it in the
+// system event log and sends it to error reporting
+func (am *AppManager) recordHandlerPanic(c echo.Context, err error, stack []byte) error {
+	am.log(c).Errorf("Panic handling %s %s: %v\n%s", c.Request().Method, c.Request().URL.Path, err, stack)
+	errreport.CapturePanic(c.Request().Context(), err, errreport.Tags{
+		"component":  "api",
+		"route":      c.Request().Method + " " + c.Path(),
+		"request_id": c.Response().Header().Get(echo.HeaderXRequestID),
+	})
+	am.recordSystemEvent(storage.SystemEventPanic, "HTTP handler panicked", map[string]string{
+		"component":  "api",
+		"route":      c.Request().Method + " " + c.Path(),
+		"request_id": c.Response().Header().Get(echo.HeaderXRequestID),
+		"error":      err.Error(),
+	})
+	return err
+}
+
+// errorCode derives the error code from an HTTP status: 404 → not_found
+func errorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(strings.ReplaceAll(text, "-", " ")), " ", "_")
+}
+
+// newErrorResponse builds the error envelope for a request
+func newErrorResponse(c echo.Context, status int, message string) ErrorResponse {
+	return ErrorResponse{Error: ErrorDetail{
+		Code:      errorCode(status),
+		Message:   message,
+		RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+	}}
+}
+
+// errorJSON writes an error response in the standard envelope
+func errorJSON(c echo.Context, status int, message string) error {
+	return c.JSON(status, newErrorResponse(c, status, message))
+}
+
+// httpErrorHandler renders errors returned by handlers and middleware (unknown routes,
+// method mismatches, recovered panics) in the standard envelope
+func (am *AppManager) httpErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	message := http.StatusText(status)
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		status = httpErr.Code
+		if text, ok := httpErr.Message.(string); ok {
+			message = text
+		} else {
+			message = http.StatusText(status)
+		}
+	}
+	if status >= http.StatusInternalServerError {
+		am.log(c).Errorf("Error handling %s %s: %v", c.Request().Method, c.Request().URL.Path, err)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = errorJSON(c, status, message)
+	}
+	if err != nil {
+		am.log(c).Errorf("Failed to send error response: %v", err)
+	}
+}
@@ -1,8 +1,14 @@
 package appmanager
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
@@ -18,6 +24,21 @@ type StatusChangeEventResponse struct {
 	NewStatus   int    `json:"new_status"`
 	DurationMs  int64  `json:"duration_ms"`
 	Timestamp   string `json:"timestamp"`
+	GroupID     string `json:"group_id,omitempty"` // Alert group the change was notified in
+}
+
+// newStatusChangeEventResponse converts a stored status change for the API
+func newStatusChangeEventResponse(change *storage.StatusChange, sourceName string) StatusChangeEventResponse {
+	return StatusChangeEventResponse{
+		ID:         change.ID,
+		SourceID:   change.SourceID,
+		SourceName: sourceName,
+		OldStatus:  change.OldStatus,
+		NewStatus:  change.NewStatus,
+		DurationMs: change.DurationMs,
+		Timestamp:  change.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+		GroupID:    change.GroupID,
+	}
 }
 
 // handleGetEvents returns status change events
@@ -33,23 +54,43 @@ func (am *AppManager) handleGetEvents(c echo.Context) error {
 		}
 	}
 
+	// Optional time range: from <= timestamp < to
+	from, err := parseTimeParam(c.QueryParam("from"))
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid from: "+err.Error())
+	}
+	to, err := parseTimeParam(c.QueryParam("to"))
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid to: "+err.Error())
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return errorJSON(c, http.StatusBadRequest, "from must be before to")
+	}
+
 	// Get status changes from storage
 	var statusChanges []*storage.StatusChange
-	var err error
 
-	if sourceID != "" {
+	if groupID := c.QueryParam("group_id"); groupID != "" {
+		// Every change notified in an alert group; other filters don't apply
+		group, groupErr := am.storage.GetAlertGroup(groupID)
+		if groupErr != nil {
+			return errorJSON(c, http.StatusNotFound, "Alert group not found")
+		}
+		statusChanges, err = am.storage.GetAlertGroupChanges(group)
+	} else if tags := c.QueryParams()["tag"]; len(tags) > 0 {
+		// Get changes for sources carrying every tag
+		statusChanges, err = am.getTaggedStatusChanges(sourceID, tags, from, to, limit)
+	} else if sourceID != "" {
 		// Get changes for specific source
-		statusChanges, err = am.storage.GetStatusChanges(sourceID, limit)
+		statusChanges, err = am.storage.GetStatusChanges(sourceID, from, to, limit)
 	} else {
 		// Get recent changes across all sources
-		statusChanges, err = am.storage.GetRecentChanges(limit)
+		statusChanges, err = am.storage.GetRecentChanges(from, to, limit)
 	}
 
 	if err != nil {
-		am.logger.Printf("Failed to get status changes: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get events",
-		})
+		am.log(c).Errorf("Failed to get status changes: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get events")
 	}
 
 	// Convert to response format with source information
@@ -57,21 +98,338 @@ func (am *AppManager) handleGetEvents(c echo.Context) error {
 	for _, change := range statusChanges {
 		source, err := am.storage.GetSource(change.SourceID)
 		if err != nil {
-			am.logger.Printf("Failed to get source %s: %v", change.SourceID, err)
+			am.log(c).Errorf("Failed to get source %s: %v", change.SourceID, err)
 			continue
 		}
 
-		event := StatusChangeEventResponse{
-			ID:         change.ID,
-			SourceID:   change.SourceID,
-			SourceName: source.Name,
-			OldStatus:  change.OldStatus,
-			NewStatus:  change.NewStatus,
-			DurationMs: change.DurationMs,
-			Timestamp:  change.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
-		}
-		events = append(events, event)
+		events = append(events, newStatusChangeEventResponse(change, source.Name))
 	}
 
 	return c.JSON(http.StatusOK, events)
 }
+
+// getTaggedStatusChanges returns the newest changes across the sources carrying every tag,
+// optionally narrowed to one source
+func (am *AppManager) getTaggedStatusChanges(sourceID string, tags []string, from, to time.Time, limit int) ([]*storage.StatusChange, error) {
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*storage.StatusChange
+	for _, source := range filterSourcesByTags(sources, tags) {
+		if sourceID != "" && source.ID != sourceID {
+			continue
+		}
+		sourceChanges, err := am.storage.GetStatusChanges(source.ID, from, to, limit)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, sourceChanges...)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Timestamp.After(changes[j].Timestamp)
+	})
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+	return changes, nil
+}
+
+// sseKeepAliveInterval is how often a comment is sent to keep idle SSE connections open through proxies
+const sseKeepAliveInterval = 30 * time.Second
+
+// handleEventStream streams status changes to the client as Server-Sent Events.
+// Optional query param source_id limits the stream to one source.
+func (am *AppManager) handleEventStream(c echo.Context) error {
+	if am.events == nil {
+		return errorJSON(c, http.StatusServiceUnavailable, "Event stream not available")
+	}
+
+	sourceID := c.QueryParam("source_id")
+
+	events, unsubscribe := am.events.Subscribe()
+	defer unsubscribe()
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx response buffering
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, ": connected\n\n")
+	w.Flush()
+
+	am.log(c).Printf("SSE client connected from %s (%d subscribers)", c.RealIP(), am.events.SubscriberCount())
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			am.log(c).Printf("SSE client disconnected from %s", c.RealIP())
+			return nil
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			w.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if sourceID != "" && event.SourceID != sourceID {
+				continue
+			}
+
+			data, err := json.Marshal(newStatusChangeEventResponse(event.Change, event.SourceName))
+			if err != nil {
+				am.log(c).Errorf("Failed to marshal SSE event: %v", err)
+				continue
+			}
+
+			fmt.Fprintf(w, "id: %s\nevent: status_change\ndata: %s\n\n", event.Change.ID, data)
+			w.Flush()
+		}
+	}
+}
+
+// maxUptimePeriod caps the period accepted by GET /sources/:id/uptime
+const maxUptimePeriod = 366 * 24 * time.Hour
+
+// handleGetSourceUptime returns SLA statistics (uptime %, outages, MTTR, MTBF, longest outage)
+// for a source over ?period= (e.g. 30d, 7d, 12h; default 30d)
+func (am *AppManager) handleGetSourceUptime(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	period := 30 * 24 * time.Hour
+	if periodStr := c.QueryParam("period"); periodStr != "" {
+		period, err = parsePeriod(periodStr)
+		if err != nil || period <= 0 || period > maxUptimePeriod {
+			return errorJSON(c, http.StatusBadRequest, "Invalid period (use e.g. 30d, 7d or 12h; max 366d)")
+		}
+	}
+
+	to := time.Now()
+	stats, err := am.storage.ComputeUptimeStats(source, to.Add(-period), to)
+	if err != nil {
+		am.log(c).Errorf("Failed to compute uptime: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to compute uptime")
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+// handleGetUptime returns the uptime, outage count and downtime of every source (or those with
+// ?tag=) over ?period= (default 7d), for the dashboard overview
+func (am *AppManager) handleGetUptime(c echo.Context) error {
+	period := 7 * 24 * time.Hour
+	if periodStr := c.QueryParam("period"); periodStr != "" {
+		var err error
+		period, err = parsePeriod(periodStr)
+		if err != nil || period <= 0 || period > maxUptimePeriod {
+			return errorJSON(c, http.StatusBadRequest, "Invalid period (use e.g. 30d, 7d or 12h; max 366d)")
+		}
+	}
+
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		am.log(c).Errorf("Failed to get sources: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get sources")
+	}
+	if tag := strings.TrimSpace(c.QueryParam("tag")); tag != "" {
+		sources = slices.DeleteFunc(sources, func(s *storage.Source) bool { return !s.HasTag(tag) })
+	}
+
+	to := time.Now()
+	summary, err := am.storage.ComputeUptimeSummary(sources, to.Add(-period), to)
+	if err != nil {
+		am.log(c).Errorf("Failed to compute uptime: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to compute uptime")
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
+
+// SourceHistoryResponse is a source's status timeline over a range, for drawing without client-side replay
+type SourceHistoryResponse struct {
+	SourceID   string                      `json:"source_id"`
+	SourceName string                      `json:"source_name"`
+	From       time.Time                   `json:"from"`
+	To         time.Time                   `json:"to"`
+	Changes    []StatusChangeEventResponse `json:"changes"` // Oldest first
+	Outages    []storage.OutageWindow      `json:"outages"` // Oldest first, clipped to the range
+	Totals     *storage.UptimeStats        `json:"totals"`
+}
+
+// handleGetSourceHistory returns status changes, outage windows and totals for a source over
+// ?from= (default 7 days ago) to ?to= (default now)
+func (am *AppManager) handleGetSourceHistory(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	from, to, err := parseHistoryRange(c, 7*24*time.Hour)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	changes, err := am.storage.GetStatusChangesInRange(source.ID, from, to)
+	if err != nil {
+		am.log(c).Errorf("Failed to get status changes: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get events")
+	}
+	outages, err := am.storage.GetOutageWindows(source, from, to)
+	if err != nil {
+		am.log(c).Errorf("Failed to compute outages: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to compute outages")
+	}
+	totals, err := am.storage.ComputeUptimeStats(source, from, to)
+	if err != nil {
+		am.log(c).Errorf("Failed to compute uptime: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to compute uptime")
+	}
+
+	history := SourceHistoryResponse{
+		SourceID:   source.ID,
+		SourceName: source.Name,
+		From:       from,
+		To:         to,
+		Changes:    make([]StatusChangeEventResponse, 0, len(changes)),
+		Outages:    outages,
+		Totals:     totals,
+	}
+	for _, change := range changes {
+		history.Changes = append(history.Changes, newStatusChangeEventResponse(change, source.Name))
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
+
+// SourceTimelineResponse is a source's state over a range as contiguous segments, for
+// rendering a status bar
+type SourceTimelineResponse struct {
+	SourceID   string                    `json:"source_id"`
+	SourceName string                    `json:"source_name"`
+	From       time.Time                 `json:"from"`
+	To         time.Time                 `json:"to"`
+	Segments   []storage.TimelineSegment `json:"segments"` // Oldest first, covering the range
+}
+
+// handleGetSourceTimeline returns a source's state segments over ?from= (default 24 hours
+// ago) to ?to= (default now; later times are clipped to now)
+func (am *AppManager) handleGetSourceTimeline(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	from, to, err := parseHistoryRange(c, 24*time.Hour)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+	if !from.Before(to) {
+		return errorJSON(c, http.StatusBadRequest, "from must be in the past")
+	}
+
+	segments, err := am.storage.GetTimeline(source, from, to)
+	if err != nil {
+		am.log(c).Errorf("Failed to compute timeline: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to compute timeline")
+	}
+
+	return c.JSON(http.StatusOK, SourceTimelineResponse{
+		SourceID:   source.ID,
+		SourceName: source.Name,
+		From:       from,
+		To:         to,
+		Segments:   segments,
+	})
+}
+
+// parseHistoryRange parses ?from= and ?to= for history endpoints: to defaults to now and
+// from to span before it, and the range may cover at most maxUptimePeriod
+func parseHistoryRange(c echo.Context, span time.Duration) (from, to time.Time, err error) {
+	if from, err = parseTimeParam(c.QueryParam("from")); err != nil {
+		return from, to, fmt.Errorf("Invalid from: %v", err)
+	}
+	if to, err = parseTimeParam(c.QueryParam("to")); err != nil {
+		return from, to, fmt.Errorf("Invalid to: %v", err)
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-span)
+	}
+	if !from.Before(to) || to.Sub(from) > maxUptimePeriod {
+		return from, to, fmt.Errorf("from must be before to, at most 366 days apart")
+	}
+	return from, to, nil
+}
+
+// parsePeriod parses a duration that may also be given in days, e.g. "30d"
+func parsePeriod(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// parseTimeParam parses an optional RFC3339 timestamp or YYYY-MM-DD date (UTC midnight)
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 timestamp or YYYY-MM-DD date")
+	}
+	return t, nil
+}
+
+// handleGetSourceRollups returns pre-computed daily uptime aggregates for a source
+func (am *AppManager) handleGetSourceRollups(c echo.Context) error {
+	sourceID := c.Param("id")
+
+	if _, err := am.getActiveSource(sourceID); err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	days := 90
+	if daysStr := c.QueryParam("days"); daysStr != "" {
+		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 && d <= 366 {
+			days = d
+		}
+	}
+
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -days)
+
+	rollups, err := am.storage.GetDailyRollups(sourceID, from, to)
+	if err != nil {
+		am.log(c).Errorf("Failed to get rollups: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get rollups")
+	}
+
+	if rollups == nil {
+		rollups = []*storage.DailyRollup{}
+	}
+
+	return c.JSON(http.StatusOK, rollups)
+}
@@ -0,0 +1,75 @@
+package appmanager
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/storage"
+)
+
+// FeatureFlagResponse is a feature flag with its current state
+type FeatureFlagResponse struct {
+	config.FeatureFlag
+	Enabled bool `json:"enabled"`
+}
+
+// UpdateFeatureFlagRequest is the body for PUT /features/:name
+type UpdateFeatureFlagRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// requireFeature answers 404 for routes of a feature that is switched off
+func (am *AppManager) requireFeature(name string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !am.configManager.FeatureEnabled(name) {
+				return errorJSON(c, http.StatusNotFound, "Feature "+name+" is disabled")
+			}
+			return next(c)
+		}
+	}
+}
+
+// handleGetFeatureFlags lists all feature flags with their current state
+func (am *AppManager) handleGetFeatureFlags(c echo.Context) error {
+	flags := make([]FeatureFlagResponse, 0, len(config.FeatureFlags))
+	for _, flag := range config.FeatureFlags {
+		flags = append(flags, FeatureFlagResponse{FeatureFlag: flag, Enabled: am.configManager.FeatureEnabled(flag.Name)})
+	}
+	return c.JSON(http.StatusOK, flags)
+}
+
+// handleUpdateFeatureFlag switches a feature flag on or off. It is stored as a FEATURE_<NAME>
+// config key, so it persists, shows up in /config and can be declared in CONFIG_FILE.
+func (am *AppManager) handleUpdateFeatureFlag(c echo.Context) error {
+	name := c.Param("name")
+	flag, ok := config.LookupFeatureFlag(name)
+	if !ok {
+		return errorJSON(c, http.StatusNotFound, "Unknown feature flag")
+	}
+
+	var req UpdateFeatureFlagRequest
+	if err := c.Bind(&req); err != nil || req.Enabled == nil {
+		return errorJSON(c, http.StatusBadRequest, "enabled (true or false) is required")
+	}
+
+	key := config.FeatureKey(name)
+	if err := am.configManager.Set(key, strconv.FormatBool(*req.Enabled)); err != nil {
+		if errors.Is(err, ErrFileBackedConfig) {
+			return errorJSON(c, http.StatusConflict, err.Error())
+		}
+		return errorJSON(c, http.StatusInternalServerError, err.Error())
+	}
+
+	am.log(c).Printf("Feature %s set to %t via API", name, *req.Enabled)
+	am.recordSystemEvent(storage.SystemEventConfigChange, "Config updated: "+key, map[string]string{
+		"key": key,
+		"by":  authKeyName(c),
+	})
+
+	return c.JSON(http.StatusOK, FeatureFlagResponse{FeatureFlag: flag, Enabled: *req.Enabled})
+}
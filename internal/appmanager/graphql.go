@@ -0,0 +1,562 @@
+package appmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A small GraphQL executor for read-only queries. It supports what the dashboard needs:
+// nested selections, aliases, arguments, variables and __typename. Mutations,
+// subscriptions, fragments, directives and introspection are rejected.
+
+// gqlSelection is one field in a selection set
+type gqlSelection struct {
+	alias      string
+	name       string
+	args       map[string]interface{} // Literal values; gqlVariable for $refs
+	selections []*gqlSelection
+}
+
+// gqlVariable is a $name reference inside an argument value
+type gqlVariable string
+
+// gqlDocument is a parsed query operation
+type gqlDocument struct {
+	defaults   map[string]interface{} // Variable defaults from the operation definition
+	selections []*gqlSelection
+}
+
+// gqlField describes one field of a schema type
+type gqlField struct {
+	Type    string            // Object type of the result; "" for scalars and scalar lists
+	Args    map[string]string // Argument name → GraphQL type, e.g. "Int" or "ID!"
+	Resolve func(parent interface{}, args map[string]interface{}) (interface{}, error)
+}
+
+// gqlSchema maps type names to their fields; "Query" is the root
+type gqlSchema map[string]map[string]gqlField
+
+// gqlError is a GraphQL error entry
+type gqlError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// gqlObject keeps result fields in selection order, as the spec requires
+type gqlObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// MarshalJSON writes the fields in selection order
+func (o *gqlObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// --- Lexer ---
+
+type gqlToken struct {
+	kind  byte // 'n' name, 'i' int, 'f' float, 's' string, 'p' punctuator, 0 EOF
+	value string
+}
+
+// gqlLex splits a query into tokens, dropping whitespace, commas and comments
+func gqlLex(src string) ([]gqlToken, error) {
+	var tokens []gqlToken
+	for i := 0; i < len(src); {
+		ch := src[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',':
+			i++
+		case ch == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, gqlToken{kind: 'p', value: "..."})
+			i += 3
+		case strings.ContainsRune("{}()[]:!$=@|&", rune(ch)):
+			tokens = append(tokens, gqlToken{kind: 'p', value: string(ch)})
+			i++
+		case ch == '"':
+			if strings.HasPrefix(src[i:], `"""`) {
+				return nil, fmt.Errorf("block strings are not supported")
+			}
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				if j < len(src) && src[j] == '\n' {
+					return nil, fmt.Errorf("unterminated string")
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			value, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", src[i:j+1])
+			}
+			tokens = append(tokens, gqlToken{kind: 's', value: value})
+			i = j + 1
+		case ch == '-' || (ch >= '0' && ch <= '9'):
+			j := i + 1
+			kind := byte('i')
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || strings.ContainsRune(".eE+-", rune(src[j]))) {
+				if !(src[j] >= '0' && src[j] <= '9') {
+					kind = 'f'
+				}
+				j++
+			}
+			tokens = append(tokens, gqlToken{kind: kind, value: src[i:j]})
+			i = j
+		case gqlNameChar(ch) && !(ch >= '0' && ch <= '9'):
+			j := i + 1
+			for j < len(src) && gqlNameChar(src[j]) {
+				j++
+			}
+			tokens = append(tokens, gqlToken{kind: 'n', value: src[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", ch)
+		}
+	}
+	return tokens, nil
+}
+
+// gqlNameChar reports whether ch may appear in a GraphQL name
+func gqlNameChar(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
+
+// --- Parser ---
+
+type gqlParser struct {
+	tokens []gqlToken
+	pos    int
+}
+
+func (p *gqlParser) peek() gqlToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return gqlToken{}
+}
+
+func (p *gqlParser) next() gqlToken {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *gqlParser) isPunct(value string) bool {
+	t := p.peek()
+	return t.kind == 'p' && t.value == value
+}
+
+func (p *gqlParser) expectPunct(value string) error {
+	if t := p.next(); t.kind != 'p' || t.value != value {
+		return fmt.Errorf("expected %q, got %q", value, t.value)
+	}
+	return nil
+}
+
+func (p *gqlParser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != 'n' {
+		return "", fmt.Errorf("expected a name, got %q", t.value)
+	}
+	return t.value, nil
+}
+
+// parseGraphQL parses a single query operation
+func parseGraphQL(query string) (*gqlDocument, error) {
+	tokens, err := gqlLex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &gqlParser{tokens: tokens}
+	doc := &gqlDocument{defaults: map[string]interface{}{}}
+
+	if t := p.peek(); t.kind == 'n' {
+		switch t.value {
+		case "query":
+			p.next()
+		case "mutation", "subscription":
+			return nil, fmt.Errorf("only queries are supported")
+		case "fragment":
+			return nil, fmt.Errorf("fragments are not supported")
+		default:
+			return nil, fmt.Errorf("unexpected %q", t.value)
+		}
+		if p.peek().kind == 'n' {
+			p.next() // Operation name
+		}
+		if p.isPunct("(") {
+			if err := p.parseVariableDefinitions(doc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if doc.selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != 0 {
+		return nil, fmt.Errorf("only one operation per request is supported")
+	}
+	return doc, nil
+}
+
+// parseVariableDefinitions reads ($name: Type = default, ...); types are not enforced
+func (p *gqlParser) parseVariableDefinitions(doc *gqlDocument) error {
+	p.next()
+	for !p.isPunct(")") {
+		if err := p.expectPunct("$"); err != nil {
+			return err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if p.isPunct("=") {
+			p.next()
+			value, err := p.parseValue()
+			if err != nil {
+				return err
+			}
+			doc.defaults[name] = value
+		}
+	}
+	p.next()
+	return nil
+}
+
+// skipType consumes a type reference such as [ID!]!
+func (p *gqlParser) skipType() error {
+	if p.isPunct("[") {
+		p.next()
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return err
+	}
+	if p.isPunct("!") {
+		p.next()
+	}
+	return nil
+}
+
+func (p *gqlParser) parseSelectionSet() ([]*gqlSelection, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var selections []*gqlSelection
+	for !p.isPunct("}") {
+		if p.peek().kind == 0 {
+			return nil, fmt.Errorf("unterminated selection set")
+		}
+		if p.isPunct("...") {
+			return nil, fmt.Errorf("fragments are not supported")
+		}
+		sel, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	p.next()
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("empty selection set")
+	}
+	return selections, nil
+}
+
+func (p *gqlParser) parseSelection() (*gqlSelection, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	sel := &gqlSelection{alias: name, name: name, args: map[string]interface{}{}}
+	if p.isPunct(":") {
+		p.next()
+		if sel.name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isPunct("(") {
+		p.next()
+		for !p.isPunct(")") {
+			arg, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return nil, err
+			}
+			if sel.args[arg], err = p.parseValue(); err != nil {
+				return nil, err
+			}
+		}
+		p.next()
+	}
+
+	if p.isPunct("@") {
+		return nil, fmt.Errorf("directives are not supported")
+	}
+	if p.isPunct("{") {
+		if sel.selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return sel, nil
+}
+
+func (p *gqlParser) parseValue() (interface{}, error) {
+	t := p.next()
+	switch t.kind {
+	case 's':
+		return t.value, nil
+	case 'i':
+		n, err := strconv.ParseInt(t.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %s", t.value)
+		}
+		return float64(n), nil // Same representation as JSON variables
+	case 'f':
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", t.value)
+		}
+		return f, nil
+	case 'n':
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return t.value, nil // Enum values are passed as strings
+	case 'p':
+		switch t.value {
+		case "$":
+			name, err := p.expectName()
+			return gqlVariable(name), err
+		case "[":
+			list := []interface{}{}
+			for !p.isPunct("]") {
+				if p.peek().kind == 0 {
+					return nil, fmt.Errorf("unterminated list")
+				}
+				v, err := p.parseValue()
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			p.next()
+			return list, nil
+		case "{":
+			return nil, fmt.Errorf("input objects are not supported")
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q in value", t.value)
+}
+
+// --- Validation and execution ---
+
+// validate checks fields, arguments and selection sets against the schema before anything runs
+func (s gqlSchema) validate(selections []*gqlSelection, typeName string) error {
+	for _, sel := range selections {
+		if sel.name == "__typename" {
+			if sel.selections != nil {
+				return fmt.Errorf("field __typename cannot have a selection set")
+			}
+			continue
+		}
+		field, ok := s[typeName][sel.name]
+		if !ok {
+			return fmt.Errorf("cannot query field %q on type %s", sel.name, typeName)
+		}
+		for arg := range sel.args {
+			if _, ok := field.Args[arg]; !ok {
+				return fmt.Errorf("unknown argument %q on field %s.%s", arg, typeName, sel.name)
+			}
+		}
+		for arg, argType := range field.Args {
+			if _, ok := sel.args[arg]; !ok && strings.HasSuffix(argType, "!") {
+				return fmt.Errorf("argument %q of type %s is required on field %s.%s", arg, argType, typeName, sel.name)
+			}
+		}
+		switch {
+		case field.Type == "" && sel.selections != nil:
+			return fmt.Errorf("field %s.%s is a scalar and cannot have a selection set", typeName, sel.name)
+		case field.Type != "" && sel.selections == nil:
+			return fmt.Errorf("field %s.%s of type %s must have a selection set", typeName, sel.name, field.Type)
+		case field.Type != "":
+			if err := s.validate(sel.selections, field.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// gqlExecution carries variables and collected errors through one query
+type gqlExecution struct {
+	schema    gqlSchema
+	variables map[string]interface{}
+	errors    []gqlError
+}
+
+// execute runs a validated query and returns the data object and any field errors
+func (s gqlSchema) execute(doc *gqlDocument, variables map[string]interface{}) (*gqlObject, []gqlError) {
+	vars := make(map[string]interface{}, len(doc.defaults)+len(variables))
+	for k, v := range doc.defaults {
+		vars[k] = v
+	}
+	for k, v := range variables {
+		vars[k] = v
+	}
+
+	ex := &gqlExecution{schema: s, variables: vars}
+	data := ex.resolveObject(doc.selections, "Query", nil, nil)
+	return data, ex.errors
+}
+
+func (ex *gqlExecution) resolveObject(selections []*gqlSelection, typeName string, parent interface{}, path []interface{}) *gqlObject {
+	obj := &gqlObject{values: map[string]interface{}{}}
+	for _, sel := range selections {
+		if _, seen := obj.values[sel.alias]; !seen {
+			obj.keys = append(obj.keys, sel.alias)
+		}
+		fieldPath := append(append([]interface{}{}, path...), sel.alias)
+
+		if sel.name == "__typename" {
+			obj.values[sel.alias] = typeName
+			continue
+		}
+
+		field := ex.schema[typeName][sel.name]
+		args := make(map[string]interface{}, len(sel.args))
+		for name, value := range sel.args {
+			args[name] = ex.substitute(value)
+		}
+
+		value, err := field.Resolve(parent, args)
+		if err != nil {
+			obj.values[sel.alias] = nil
+			ex.errors = append(ex.errors, gqlError{Message: err.Error(), Path: fieldPath})
+			continue
+		}
+		obj.values[sel.alias] = ex.complete(sel, field, value, fieldPath)
+	}
+	return obj
+}
+
+// complete resolves nested selections on an object or list result
+func (ex *gqlExecution) complete(sel *gqlSelection, field gqlField, value interface{}, path []interface{}) interface{} {
+	if field.Type == "" || value == nil {
+		return value
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	if rv.Kind() == reflect.Slice {
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = ex.resolveObject(sel.selections, field.Type, rv.Index(i).Interface(), append(append([]interface{}{}, path...), i))
+		}
+		return list
+	}
+	return ex.resolveObject(sel.selections, field.Type, value, path)
+}
+
+// substitute replaces variable references inside an argument value
+func (ex *gqlExecution) substitute(value interface{}) interface{} {
+	switch v := value.(type) {
+	case gqlVariable:
+		return ex.variables[string(v)]
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = ex.substitute(item)
+		}
+		return out
+	}
+	return value
+}
+
+// gqlString reads an optional string argument
+func gqlString(args map[string]interface{}, name, fallback string) (string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return fallback, nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("argument %q must be a string", name)
+}
+
+// gqlInt reads an optional integer argument
+func gqlInt(args map[string]interface{}, name string, fallback int) (int, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return fallback, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q must be an integer", name)
+}
+
+// gqlBool reads an optional boolean argument; nil means not given
+func gqlBool(args map[string]interface{}, name string) (*bool, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return &v, nil
+	}
+	return nil, fmt.Errorf("argument %q must be a boolean", name)
+}
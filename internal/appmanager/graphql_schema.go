@@ -0,0 +1,291 @@
+package appmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// maxGraphQLListLimit caps limit arguments on list fields
+const maxGraphQLListLimit = 1000
+
+// GraphQLRequest is the body for POST /graphql
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"` // Accepted and ignored: one operation per document
+}
+
+// GraphQLResponse is the standard GraphQL result envelope
+type GraphQLResponse struct {
+	Data   *gqlObject `json:"data,omitempty"`
+	Errors []gqlError `json:"errors,omitempty"`
+}
+
+// gqlProp exposes a scalar property of T
+func gqlProp[T any](get func(T) interface{}) gqlField {
+	return gqlField{Resolve: func(parent interface{}, _ map[string]interface{}) (interface{}, error) {
+		return get(parent.(T)), nil
+	}}
+}
+
+// graphQLSchema builds the query schema over storage
+func (am *AppManager) graphQLSchema() gqlSchema {
+	return gqlSchema{
+		"Query": {
+			"sources": {
+				Type: "Source",
+				Args: map[string]string{"tag": "String", "type": "String", "enabled": "Boolean", "status": "String"},
+				Resolve: func(_ interface{}, args map[string]interface{}) (interface{}, error) {
+					return am.gqlSources(args)
+				},
+			},
+			"source": {
+				Type: "Source",
+				Args: map[string]string{"id": "ID!"},
+				Resolve: func(_ interface{}, args map[string]interface{}) (interface{}, error) {
+					id, err := gqlString(args, "id", "")
+					if err != nil {
+						return nil, err
+					}
+					source, err := am.storage.GetSource(id)
+					if err != nil || source.IsDeleted() {
+						return nil, nil
+					}
+					return source, nil
+				},
+			},
+			"events": {
+				Type: "StatusChange",
+				Args: map[string]string{"limit": "Int"},
+				Resolve: func(_ interface{}, args map[string]interface{}) (interface{}, error) {
+					limit, err := gqlLimit(args, 50)
+					if err != nil {
+						return nil, err
+					}
+					return am.storage.GetRecentChanges(time.Time{}, time.Time{}, limit)
+				},
+			},
+			"webhooks": {
+				Type: "Webhook",
+				Resolve: func(interface{}, map[string]interface{}) (interface{}, error) {
+					return am.storage.ListWebhooks()
+				},
+			},
+			"telegramChats": {
+				Type: "TelegramChat",
+				Resolve: func(interface{}, map[string]interface{}) (interface{}, error) {
+					return am.storage.ListChats()
+				},
+			},
+		},
+
+		"Source": {
+			"id":             gqlProp(func(s *storage.Source) interface{} { return s.ID }),
+			"name":           gqlProp(func(s *storage.Source) interface{} { return s.Name }),
+			"type":           gqlProp(func(s *storage.Source) interface{} { return s.Type }),
+			"target":         gqlProp(func(s *storage.Source) interface{} { return s.Target }),
+			"enabled":        gqlProp(func(s *storage.Source) interface{} { return s.Enabled }),
+			"public":         gqlProp(func(s *storage.Source) interface{} { return s.Public }),
+			"tags":           gqlProp(func(s *storage.Source) interface{} { return nonNilTags(s.Tags) }),
+			"checkInterval":  gqlProp(func(s *storage.Source) interface{} { return s.CheckInterval.String() }),
+			"currentStatus":  gqlProp(func(s *storage.Source) interface{} { return s.CurrentStatus }),
+			"status":         gqlProp(func(s *storage.Source) interface{} { return publicStatus(s) }),
+			"lastCheckTime":  gqlProp(func(s *storage.Source) interface{} { return s.LastCheckTime }),
+			"lastChangeTime": gqlProp(func(s *storage.Source) interface{} { return s.LastChangeTime }),
+			"lastError":      gqlProp(func(s *storage.Source) interface{} { return s.LastError }),
+			"createdAt":      gqlProp(func(s *storage.Source) interface{} { return s.CreatedAt }),
+			"telegramChats": {
+				Type: "TelegramChat",
+				Resolve: func(parent interface{}, _ map[string]interface{}) (interface{}, error) {
+					return am.getSourceTelegramChats(parent.(*storage.Source).ID)
+				},
+			},
+			"webhooks": {
+				Type: "Webhook",
+				Resolve: func(parent interface{}, _ map[string]interface{}) (interface{}, error) {
+					return am.storage.GetSourceWebhooks(parent.(*storage.Source).ID)
+				},
+			},
+			"changes": {
+				Type: "StatusChange",
+				Args: map[string]string{"limit": "Int"},
+				Resolve: func(parent interface{}, args map[string]interface{}) (interface{}, error) {
+					limit, err := gqlLimit(args, 10)
+					if err != nil {
+						return nil, err
+					}
+					return am.storage.GetStatusChanges(parent.(*storage.Source).ID, time.Time{}, time.Time{}, limit)
+				},
+			},
+			"uptime": {
+				Type: "UptimeStats",
+				Args: map[string]string{"period": "String"},
+				Resolve: func(parent interface{}, args map[string]interface{}) (interface{}, error) {
+					value, err := gqlString(args, "period", "30d")
+					if err != nil {
+						return nil, err
+					}
+					period, err := parsePeriod(value)
+					if err != nil || period <= 0 || period > maxUptimePeriod {
+						return nil, fmt.Errorf("invalid period (use e.g. 30d, 7d or 12h; max 366d)")
+					}
+					to := time.Now()
+					return am.storage.ComputeUptimeStats(parent.(*storage.Source), to.Add(-period), to)
+				},
+			},
+		},
+
+		"StatusChange": {
+			"id":         gqlProp(func(sc *storage.StatusChange) interface{} { return sc.ID }),
+			"sourceId":   gqlProp(func(sc *storage.StatusChange) interface{} { return sc.SourceID }),
+			"oldStatus":  gqlProp(func(sc *storage.StatusChange) interface{} { return sc.OldStatus }),
+			"newStatus":  gqlProp(func(sc *storage.StatusChange) interface{} { return sc.NewStatus }),
+			"timestamp":  gqlProp(func(sc *storage.StatusChange) interface{} { return sc.Timestamp }),
+			"durationMs": gqlProp(func(sc *storage.StatusChange) interface{} { return sc.DurationMs }),
+			"source": {
+				Type: "Source",
+				Resolve: func(parent interface{}, _ map[string]interface{}) (interface{}, error) {
+					source, err := am.storage.GetSource(parent.(*storage.StatusChange).SourceID)
+					if err != nil {
+						return nil, nil // Purged sources leave their history behind
+					}
+					return source, nil
+				},
+			},
+		},
+
+		"UptimeStats": {
+			"from":              gqlProp(func(u *storage.UptimeStats) interface{} { return u.From }),
+			"to":                gqlProp(func(u *storage.UptimeStats) interface{} { return u.To }),
+			"uptimePercent":     gqlProp(func(u *storage.UptimeStats) interface{} { return u.UptimePercent }),
+			"monitoredMs":       gqlProp(func(u *storage.UptimeStats) interface{} { return u.MonitoredMs }),
+			"downtimeMs":        gqlProp(func(u *storage.UptimeStats) interface{} { return u.DowntimeMs }),
+			"outageCount":       gqlProp(func(u *storage.UptimeStats) interface{} { return u.OutageCount }),
+			"mttrMs":            gqlProp(func(u *storage.UptimeStats) interface{} { return u.MTTRMs }),
+			"mtbfMs":            gqlProp(func(u *storage.UptimeStats) interface{} { return u.MTBFMs }),
+			"longestOutageMs":   gqlProp(func(u *storage.UptimeStats) interface{} { return u.LongestOutageMs }),
+			"ongoing":           gqlProp(func(u *storage.UptimeStats) interface{} { return u.Ongoing }),
+			"plannedDowntimeMs": gqlProp(func(u *storage.UptimeStats) interface{} { return u.PlannedDowntimeMs }),
+			"slaUptimePercent":  gqlProp(func(u *storage.UptimeStats) interface{} { return u.SLAUptimePercent }),
+		},
+
+		// Webhook headers are omitted: they often carry credentials
+		"Webhook": {
+			"id":            gqlProp(func(w *storage.Webhook) interface{} { return w.ID }),
+			"name":          gqlProp(func(w *storage.Webhook) interface{} { return w.Name }),
+			"url":           gqlProp(func(w *storage.Webhook) interface{} { return w.URL }),
+			"method":        gqlProp(func(w *storage.Webhook) interface{} { return w.Method }),
+			"enabled":       gqlProp(func(w *storage.Webhook) interface{} { return w.Enabled }),
+			"createdAt":     gqlProp(func(w *storage.Webhook) interface{} { return w.CreatedAt }),
+			"lastTriggered": gqlProp(func(w *storage.Webhook) interface{} { return w.LastTriggered }),
+		},
+
+		// Chat IDs are strings: they exceed GraphQL's 32-bit Int
+		"TelegramChat": {
+			"chatId":    gqlProp(func(ch *storage.Chat) interface{} { return strconv.FormatInt(ch.ChatID, 10) }),
+			"name":      gqlProp(func(ch *storage.Chat) interface{} { return ch.Name }),
+			"createdAt": gqlProp(func(ch *storage.Chat) interface{} { return ch.CreatedAt }),
+		},
+	}
+}
+
+// gqlSources applies the same filters as GET /sources
+func (am *AppManager) gqlSources(args map[string]interface{}) (interface{}, error) {
+	query := &sourceQuery{sortBy: "name"}
+
+	tag, err := gqlString(args, "tag", "")
+	if err != nil {
+		return nil, err
+	}
+	if tag != "" {
+		query.tags = []string{tag}
+	}
+	if query.typ, err = gqlString(args, "type", ""); err != nil {
+		return nil, err
+	}
+	if query.enabled, err = gqlBool(args, "enabled"); err != nil {
+		return nil, err
+	}
+	status, err := gqlString(args, "status", "")
+	if err != nil {
+		return nil, err
+	}
+	if status != "" {
+		value, err := parseStatusFilter(status)
+		if err != nil {
+			return nil, err
+		}
+		query.status = &value
+	}
+
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		return nil, err
+	}
+	sources, _ = query.apply(sources)
+	return sources, nil
+}
+
+// gqlLimit reads a limit argument between 1 and maxGraphQLListLimit
+func gqlLimit(args map[string]interface{}, fallback int) (int, error) {
+	limit, err := gqlInt(args, "limit", fallback)
+	if err != nil {
+		return 0, err
+	}
+	if limit < 1 || limit > maxGraphQLListLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxGraphQLListLimit)
+	}
+	return limit, nil
+}
+
+// nonNilTags returns an empty list instead of null for untagged sources
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// handleGraphQL executes a read-only GraphQL query (POST body or GET ?query=&variables=)
+func (am *AppManager) handleGraphQL(c echo.Context) error {
+	cfg, err := am.configManager.AsConfig()
+	if err != nil || !cfg.GraphQLEnabled {
+		return errorJSON(c, http.StatusNotFound, "GraphQL is disabled")
+	}
+
+	var req GraphQLRequest
+	if c.Request().Method == http.MethodGet {
+		req.Query = c.QueryParam("query")
+		if vars := c.QueryParam("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				return c.JSON(http.StatusBadRequest, GraphQLResponse{Errors: []gqlError{{Message: "variables must be a JSON object"}}})
+			}
+		}
+	} else if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, GraphQLResponse{Errors: []gqlError{{Message: "Invalid request body"}}})
+	}
+
+	if req.Query == "" {
+		return c.JSON(http.StatusBadRequest, GraphQLResponse{Errors: []gqlError{{Message: "query is required"}}})
+	}
+
+	doc, err := parseGraphQL(req.Query)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, GraphQLResponse{Errors: []gqlError{{Message: "Syntax error: " + err.Error()}}})
+	}
+
+	schema := am.graphQLSchema()
+	if err := schema.validate(doc.selections, "Query"); err != nil {
+		return c.JSON(http.StatusBadRequest, GraphQLResponse{Errors: []gqlError{{Message: err.Error()}}})
+	}
+
+	data, errors := schema.execute(doc, req.Variables)
+	return c.JSON(http.StatusOK, GraphQLResponse{Data: data, Errors: errors})
+}
@@ -0,0 +1,86 @@
+package appmanager
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/monitor"
+	"tg-monitor-bot/internal/storage"
+)
+
+// healthWindow parses ?window= (e.g. 24h, 7d; default 24h, max 366d)
+func healthWindow(c echo.Context) (time.Duration, bool) {
+	value := c.QueryParam("window")
+	if value == "" {
+		return monitor.DefaultHealthWindow, true
+	}
+	window, err := parsePeriod(value)
+	return window, err == nil && window > 0 && window <= maxUptimePeriod
+}
+
+// handleGetSourceHealthScore returns the 0-100 health score of a source over ?window=
+func (am *AppManager) handleGetSourceHealthScore(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+	window, ok := healthWindow(c)
+	if !ok {
+		return errorJSON(c, http.StatusBadRequest, "Invalid window (use e.g. 24h or 7d; max 366d)")
+	}
+
+	health, err := monitor.ComputeHealthScore(am.storage, am.botProcess.GetMonitor(), source, window, time.Now())
+	if err != nil {
+		am.log(c).Errorf("Failed to compute health score of %s: %v", source.ID, err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to compute health score")
+	}
+	return c.JSON(http.StatusOK, health)
+}
+
+// handleGetHealthScores returns the health scores of every source the key may see (or those
+// with ?tag=) over ?window=, lowest first and unknown last
+func (am *AppManager) handleGetHealthScores(c echo.Context) error {
+	window, ok := healthWindow(c)
+	if !ok {
+		return errorJSON(c, http.StatusBadRequest, "Invalid window (use e.g. 24h or 7d; max 366d)")
+	}
+
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		am.log(c).Errorf("Failed to get sources: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get sources")
+	}
+	namespace, filtered := namespaceFilter(c)
+	tag := strings.TrimSpace(c.QueryParam("tag"))
+	sources = slices.DeleteFunc(sources, func(s *storage.Source) bool {
+		return (filtered && s.Namespace != namespace) || (tag != "" && !s.HasTag(tag))
+	})
+
+	now := time.Now()
+	mon := am.botProcess.GetMonitor()
+	scores := make([]*monitor.HealthScore, 0, len(sources))
+	for _, source := range sources {
+		health, err := monitor.ComputeHealthScore(am.storage, mon, source, window, now)
+		if err != nil {
+			am.log(c).Errorf("Failed to compute health score of %s: %v", source.ID, err)
+			return errorJSON(c, http.StatusInternalServerError, "Failed to compute health scores")
+		}
+		scores = append(scores, health)
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		a, b := scores[i].Score, scores[j].Score
+		switch {
+		case a == nil || b == nil:
+			return a != nil && b == nil
+		case *a != *b:
+			return *a < *b
+		}
+		return scores[i].SourceName < scores[j].SourceName
+	})
+	return c.JSON(http.StatusOK, scores)
+}
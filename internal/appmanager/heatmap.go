@@ -0,0 +1,120 @@
+package appmanager
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// Heatmap levels, from best to worst, so clients only have to pick a color per level
+const (
+	heatmapNoData   = "no_data"  // Nothing monitored, or no rollup for the day
+	heatmapPerfect  = "perfect"  // 100%
+	heatmapGood     = "good"     // At least 99.9%
+	heatmapDegraded = "degraded" // At least 99%
+	heatmapPoor     = "poor"     // At least 95%
+	heatmapBad      = "bad"      // Below 95%
+)
+
+// SourceHeatmapResponse is a source's daily availability for a calendar heatmap
+type SourceHeatmapResponse struct {
+	SourceID      string       `json:"source_id"`
+	SourceName    string       `json:"source_name"`
+	From          string       `json:"from"`           // First day, YYYY-MM-DD (UTC)
+	To            string       `json:"to"`             // Last day (today), YYYY-MM-DD (UTC)
+	UptimePercent *float64     `json:"uptime_percent"` // Over all days, weighted by monitored time
+	Days          []HeatmapDay `json:"days"`           // One per day, oldest first
+}
+
+// HeatmapDay is one cell of a calendar heatmap
+type HeatmapDay struct {
+	Date          string   `json:"date"` // YYYY-MM-DD (UTC)
+	Weekday       int      `json:"weekday"`
+	UptimePercent *float64 `json:"uptime_percent"` // nil without data
+	OutageCount   int      `json:"outage_count"`
+	DowntimeMs    int64    `json:"downtime_ms"`
+	Level         string   `json:"level"`
+}
+
+// heatmapLevel buckets a day's uptime
+func heatmapLevel(uptime *float64) string {
+	switch {
+	case uptime == nil:
+		return heatmapNoData
+	case *uptime >= 100:
+		return heatmapPerfect
+	case *uptime >= 99.9:
+		return heatmapGood
+	case *uptime >= 99:
+		return heatmapDegraded
+	case *uptime >= 95:
+		return heatmapPoor
+	}
+	return heatmapBad
+}
+
+// handleGetSourceHeatmap returns a source's uptime per UTC day over the last ?days= days
+// (default 90, max 366), including today. Completed days come from the daily rollups only, so
+// long ranges stay cheap; today is replayed.
+func (am *AppManager) handleGetSourceHeatmap(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	days := 90
+	if value := c.QueryParam("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil || days <= 0 || days > 366 {
+			return errorJSON(c, http.StatusBadRequest, "Invalid days (1 to 366)")
+		}
+	}
+
+	now := time.Now()
+	today := now.UTC().Truncate(24 * time.Hour)
+	first := today.AddDate(0, 0, -(days - 1))
+	rollups, err := am.storage.GetDailyRollups(source.ID, first, today.AddDate(0, 0, -1))
+	if err != nil {
+		am.log(c).Errorf("Failed to get rollups: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get rollups")
+	}
+	current, err := am.storage.ComputeDailyRollup(source, today)
+	if err != nil {
+		am.log(c).Errorf("Failed to compute rollup: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to compute today's uptime")
+	}
+	rollups = append(rollups, current)
+
+	byDate := make(map[string]*storage.DailyRollup, len(rollups))
+	for _, rollup := range rollups {
+		byDate[rollup.Date] = rollup
+	}
+
+	heatmap := SourceHeatmapResponse{
+		SourceID:   source.ID,
+		SourceName: source.Name,
+		From:       first.Format("2006-01-02"),
+		To:         today.Format("2006-01-02"),
+		Days:       make([]HeatmapDay, 0, days),
+	}
+	if uptime, ok := storage.UptimeFromRollups(rollups); ok {
+		heatmap.UptimePercent = &uptime
+	}
+	for day := first; !day.After(today); day = day.AddDate(0, 0, 1) {
+		cell := HeatmapDay{Date: day.Format("2006-01-02"), Weekday: int(day.Weekday())}
+		if rollup, ok := byDate[cell.Date]; ok && rollup.MonitoredMs > 0 {
+			uptime := rollup.UptimePercent
+			cell.UptimePercent = &uptime
+			cell.OutageCount = rollup.OutageCount
+			cell.DowntimeMs = rollup.DowntimeMs
+		}
+		cell.Level = heatmapLevel(cell.UptimePercent)
+		heatmap.Days = append(heatmap.Days, cell)
+	}
+
+	return c.JSON(http.StatusOK, heatmap)
+}
@@ -0,0 +1,506 @@
+package appmanager
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// Formats accepted by ImportExternal
+const (
+	ImportFormatUptimeKuma = "uptime-kuma" // Uptime Kuma backup JSON (Settings > Backup > Export)
+	ImportFormatCSV        = "csv"         // One source per row, see parseSourcesCSV
+)
+
+// ExternalImportResult reports what ImportExternal created, or would create on a dry run
+type ExternalImportResult struct {
+	storage.ImportResult
+	DryRun   bool              `json:"dry_run"`
+	Created  []*storage.Source `json:"created"`
+	Warnings []string          `json:"warnings"` // What was skipped or changed, and why
+}
+
+// externalImport is another tool's setup converted to this bot's records, before writing
+type externalImport struct {
+	sources  []*storage.Source
+	chats    map[int64]string            // Chat ID to name
+	webhooks map[string]*storage.Webhook // By URL
+	// Notification targets of each source, by index in sources
+	sourceChats    map[int][]int64
+	sourceWebhooks map[int][]string // Webhook URLs
+	warnings       []string
+}
+
+func newExternalImport() *externalImport {
+	return &externalImport{
+		chats:          make(map[int64]string),
+		webhooks:       make(map[string]*storage.Webhook),
+		sourceChats:    make(map[int][]int64),
+		sourceWebhooks: make(map[int][]string),
+	}
+}
+
+func (imp *externalImport) warnf(format string, args ...interface{}) {
+	imp.warnings = append(imp.warnings, fmt.Sprintf(format, args...))
+}
+
+// ImportExternal creates the sources, Telegram chats and webhooks described by another
+// monitoring tool's configuration. Sources named like an existing source, and chats and
+// webhook URLs already registered, are not created again, so importing is safe to repeat.
+// Ping/http sources without an interval get defaultInterval. With dryRun nothing is written.
+func ImportExternal(db *storage.BoltDB, format string, data []byte, defaultInterval time.Duration, dryRun bool) (*ExternalImportResult, error) {
+	var imp *externalImport
+	var err error
+	switch format {
+	case ImportFormatUptimeKuma:
+		imp, err = parseUptimeKuma(data)
+	case ImportFormatCSV:
+		imp, err = parseSourcesCSV(bytes.NewReader(data), defaultInterval)
+	default:
+		return nil, fmt.Errorf("format must be '%s' or '%s'", ImportFormatUptimeKuma, ImportFormatCSV)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := db.GetAllSources()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sources: %w", err)
+	}
+	names := make(map[string]bool, len(existing))
+	for _, source := range existing {
+		names[strings.ToLower(source.Name)] = true
+	}
+	webhooks, err := db.ListWebhooks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhooks: %w", err)
+	}
+	webhookIDs := make(map[string]string, len(webhooks))
+	for _, webhook := range webhooks {
+		webhookIDs[webhook.URL] = webhook.ID
+	}
+
+	export := &storage.Export{FormatVersion: storage.ExportFormatVersion}
+	result := &ExternalImportResult{DryRun: dryRun, Created: []*storage.Source{}, Warnings: imp.warnings}
+	usedChats := make(map[int64]bool)
+	for i, source := range imp.sources {
+		if names[strings.ToLower(source.Name)] {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: a source with this name already exists", source.Name))
+			continue
+		}
+		names[strings.ToLower(source.Name)] = true
+
+		if source.Type == "webhook" {
+			if source.WebhookToken, err = newWebhookToken(db); err != nil {
+				return nil, fmt.Errorf("failed to generate webhook token: %w", err)
+			}
+		}
+		export.Sources = append(export.Sources, source)
+		result.Created = append(result.Created, source)
+
+		for _, chatID := range imp.sourceChats[i] {
+			usedChats[chatID] = true
+			export.SourceSinks = append(export.SourceSinks, storage.SourceSink{SourceID: source.ID, SinkID: storage.TelegramSinkID(chatID)})
+		}
+		for _, webhookURL := range imp.sourceWebhooks[i] {
+			id, ok := webhookIDs[webhookURL]
+			if !ok {
+				webhook := imp.webhooks[webhookURL]
+				export.Webhooks = append(export.Webhooks, webhook)
+				id = webhook.ID
+				webhookIDs[webhookURL] = id
+			}
+			export.SourceSinks = append(export.SourceSinks, storage.SourceSink{SourceID: source.ID, SinkID: storage.WebhookSinkID(id)})
+		}
+	}
+
+	// Register chats the imported sources notify, keeping the names of known chats
+	chatIDs := make([]int64, 0, len(usedChats))
+	for chatID := range usedChats {
+		chatIDs = append(chatIDs, chatID)
+	}
+	sort.Slice(chatIDs, func(i, j int) bool { return chatIDs[i] < chatIDs[j] })
+	for _, chatID := range chatIDs {
+		if _, err := db.GetChat(chatID); err == nil {
+			continue
+		}
+		export.Chats = append(export.Chats, &storage.Chat{ChatID: chatID, Name: imp.chats[chatID]})
+	}
+
+	if dryRun {
+		result.ImportResult = storage.ImportResult{
+			Sources:  len(export.Sources),
+			Webhooks: len(export.Webhooks),
+			Chats:    len(export.Chats),
+			Links:    len(export.SourceSinks),
+		}
+		return result, nil
+	}
+
+	written, err := db.Import(export)
+	if err != nil {
+		return nil, err
+	}
+	result.ImportResult = *written
+	return result, nil
+}
+
+// kumaDefaultInterval is Uptime Kuma's check interval in seconds, for monitors without one
+const kumaDefaultInterval = 60
+
+// kumaBackup is the part of an Uptime Kuma backup file that is imported
+type kumaBackup struct {
+	NotificationList []kumaNotification `json:"notificationList"`
+	MonitorList      []kumaMonitor      `json:"monitorList"`
+}
+
+// kumaNotification is a notification provider; config holds its settings as a JSON string
+type kumaNotification struct {
+	ID     int       `json:"id"`
+	Name   string    `json:"name"`
+	Config string    `json:"config"`
+	Active *kumaBool `json:"active"` // Missing means active
+}
+
+// kumaNotificationConfig holds the settings of the providers that are imported
+type kumaNotificationConfig struct {
+	Type                     string `json:"type"`
+	TelegramChatID           string `json:"telegramChatID"`
+	WebhookURL               string `json:"webhookURL"`
+	WebhookAdditionalHeaders string `json:"webhookAdditionalHeaders"` // JSON object
+}
+
+// kumaMonitor is a monitor; which address field is set depends on its type
+type kumaMonitor struct {
+	ID                 int             `json:"id"`
+	Name               string          `json:"name"`
+	Type               string          `json:"type"`
+	URL                string          `json:"url"`
+	Hostname           string          `json:"hostname"`
+	Interval           int             `json:"interval"` // Seconds
+	Active             *kumaBool       `json:"active"`   // Missing means active
+	NotificationIDList map[string]bool `json:"notificationIDList"`
+	Tags               []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"tags"`
+}
+
+// kumaBool accepts the booleans Uptime Kuma writes as true/false or 1/0
+type kumaBool bool
+
+func (b *kumaBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", "1":
+		*b = true
+	case "false", "0", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+// isSet reports whether b is true or missing
+func (b *kumaBool) isSet() bool {
+	return b == nil || bool(*b)
+}
+
+// parseUptimeKuma converts the monitors of an Uptime Kuma backup to sources: http and
+// keyword to http, ping to ping and push to webhook. Telegram and webhook notifications
+// become chats and webhooks; other monitor and notification types are reported.
+func parseUptimeKuma(data []byte) (*externalImport, error) {
+	var backup kumaBackup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("invalid Uptime Kuma backup: %v", err)
+	}
+	if backup.MonitorList == nil {
+		return nil, errors.New("invalid Uptime Kuma backup: no monitorList")
+	}
+
+	imp := newExternalImport()
+	chats := make(map[int]int64)     // Notification ID to chat ID
+	webhooks := make(map[int]string) // Notification ID to webhook URL
+	for _, notification := range backup.NotificationList {
+		var cfg kumaNotificationConfig
+		if err := json.Unmarshal([]byte(notification.Config), &cfg); err != nil {
+			imp.warnf("Notification %s: invalid config", notification.Name)
+			continue
+		}
+		switch cfg.Type {
+		case "telegram":
+			chatID, err := strconv.ParseInt(strings.TrimSpace(cfg.TelegramChatID), 10, 64)
+			if err != nil {
+				imp.warnf("Notification %s: invalid Telegram chat ID %q", notification.Name, cfg.TelegramChatID)
+				continue
+			}
+			chats[notification.ID] = chatID
+			imp.chats[chatID] = notification.Name
+		case "webhook":
+			if _, err := url.ParseRequestURI(cfg.WebhookURL); err != nil {
+				imp.warnf("Notification %s: invalid webhook URL", notification.Name)
+				continue
+			}
+			webhook := &storage.Webhook{Name: notification.Name, URL: cfg.WebhookURL, Method: "POST", Enabled: notification.Active.isSet()}
+			if cfg.WebhookAdditionalHeaders != "" {
+				if err := json.Unmarshal([]byte(cfg.WebhookAdditionalHeaders), &webhook.Headers); err != nil {
+					imp.warnf("Notification %s: additional headers are not a JSON object of strings, skipped them", notification.Name)
+				}
+			}
+			imp.addWebhook(webhook)
+			webhooks[notification.ID] = cfg.WebhookURL
+		default:
+			imp.warnf("Notification %s: %s notifications are not supported", notification.Name, cfg.Type)
+		}
+	}
+
+	for _, monitor := range backup.MonitorList {
+		interval := monitor.Interval
+		if interval <= 0 {
+			interval = kumaDefaultInterval
+		}
+		req := CreateSourceRequest{Name: strings.TrimSpace(monitor.Name), CheckInterval: fmt.Sprintf("%ds", interval)}
+		switch monitor.Type {
+		case "http", "keyword", "json-query":
+			req.Type, req.Target = "http", monitor.URL
+			if monitor.Type != "http" {
+				imp.warnf("%s: imported as a plain http check, the %s check is not supported", req.Name, monitor.Type)
+			}
+		case "ping":
+			req.Type, req.Target = "ping", monitor.Hostname
+		case "push":
+			req.Type = "webhook"
+		case "group":
+			continue // Groups only organize monitors in Uptime Kuma's UI
+		default:
+			imp.warnf("%s: %s monitors are not supported", req.Name, monitor.Type)
+			continue
+		}
+		for _, tag := range monitor.Tags {
+			name := tag.Name
+			if tag.Value != "" {
+				name += ":" + tag.Value
+			}
+			req.Tags = append(req.Tags, tagFromName(name))
+		}
+
+		source, err := sourceFromCreateRequest(req)
+		if err != nil {
+			imp.warnf("%s: %v", req.Name, err)
+			continue
+		}
+		source.Enabled = monitor.Active.isSet()
+
+		i := len(imp.sources)
+		imp.sources = append(imp.sources, source)
+		ids := make([]string, 0, len(monitor.NotificationIDList))
+		for id, enabled := range monitor.NotificationIDList {
+			if enabled {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			notificationID, _ := strconv.Atoi(id)
+			if chatID, ok := chats[notificationID]; ok {
+				imp.sourceChats[i] = append(imp.sourceChats[i], chatID)
+			}
+			if webhookURL, ok := webhooks[notificationID]; ok {
+				imp.sourceWebhooks[i] = append(imp.sourceWebhooks[i], webhookURL)
+			}
+		}
+	}
+	return imp, nil
+}
+
+// tagFromName turns a tag name from another tool into a valid tag: lowercase, with runs of
+// characters tags can't contain replaced by "-"
+func tagFromName(name string) string {
+	var tag strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.:", r) {
+			tag.WriteRune(r)
+			dash = false
+		} else if !dash {
+			tag.WriteRune('-')
+			dash = true
+		}
+	}
+	return tag.String()
+}
+
+// addWebhook registers a webhook to create, once per URL
+func (imp *externalImport) addWebhook(webhook *storage.Webhook) {
+	if _, ok := imp.webhooks[webhook.URL]; !ok {
+		webhook.ID = uuid.New().String()
+		imp.webhooks[webhook.URL] = webhook
+	}
+}
+
+// parseSourcesCSV reads one source per row. The header names the columns, in any order:
+// name and type are required; target, check_interval, tags, telegram_chat_ids,
+// webhook_urls and enabled are optional. List columns are separated by ";".
+func parseSourcesCSV(r io.Reader, defaultInterval time.Duration) (*externalImport, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "type"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("invalid CSV: missing %s column", required)
+		}
+	}
+
+	imp := newExternalImport()
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		req := CreateSourceRequest{
+			Name:          field("name"),
+			Type:          strings.ToLower(field("type")),
+			Target:        field("target"),
+			CheckInterval: field("check_interval"),
+			Tags:          splitList(field("tags")),
+		}
+		if req.CheckInterval == "" {
+			req.CheckInterval = defaultInterval.String()
+		}
+		source, err := sourceFromCreateRequest(req)
+		if err != nil {
+			imp.warnf("Line %d: %v", line, err)
+			continue
+		}
+		if enabled := field("enabled"); enabled != "" {
+			if source.Enabled, err = strconv.ParseBool(enabled); err != nil {
+				imp.warnf("Line %d: invalid enabled %q", line, enabled)
+				continue
+			}
+		}
+
+		var chatIDs []int64
+		for _, value := range splitList(field("telegram_chat_ids")) {
+			chatID, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				err = fmt.Errorf("invalid Telegram chat ID %q", value)
+				break
+			}
+			chatIDs = append(chatIDs, chatID)
+		}
+		if err != nil {
+			imp.warnf("Line %d: %v", line, err)
+			continue
+		}
+		webhookURLs := splitList(field("webhook_urls"))
+		for _, webhookURL := range webhookURLs {
+			parsed, perr := url.ParseRequestURI(webhookURL)
+			if perr != nil || parsed.Host == "" {
+				err = fmt.Errorf("invalid webhook URL %q", webhookURL)
+				break
+			}
+		}
+		if err != nil {
+			imp.warnf("Line %d: %v", line, err)
+			continue
+		}
+
+		i := len(imp.sources)
+		imp.sources = append(imp.sources, source)
+		for _, chatID := range chatIDs {
+			if _, ok := imp.chats[chatID]; !ok {
+				imp.chats[chatID] = strconv.FormatInt(chatID, 10)
+			}
+			imp.sourceChats[i] = append(imp.sourceChats[i], chatID)
+		}
+		for _, webhookURL := range webhookURLs {
+			parsed, _ := url.Parse(webhookURL)
+			imp.addWebhook(&storage.Webhook{Name: parsed.Host, URL: webhookURL, Method: "POST", Enabled: true})
+			imp.sourceWebhooks[i] = append(imp.sourceWebhooks[i], webhookURL)
+		}
+	}
+	return imp, nil
+}
+
+// splitList splits a ";"-separated CSV cell, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// maxImportSize caps the body of POST /import
+const maxImportSize = 10 << 20
+
+// handleImportExternal creates sources, chats and webhooks from another monitoring tool's
+// configuration in the request body, as ?format= says, and starts monitoring them
+func (am *AppManager) handleImportExternal(c echo.Context) error {
+	data, err := io.ReadAll(io.LimitReader(c.Request().Body, maxImportSize+1))
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Failed to read request body")
+	}
+	if len(data) > maxImportSize {
+		return errorJSON(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Import is larger than %d MB", maxImportSize>>20))
+	}
+
+	cfg, err := am.configManager.AsConfig()
+	if err != nil {
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get config")
+	}
+	dryRun := c.QueryParam("dry_run") == "true"
+	result, err := ImportExternal(am.storage, c.QueryParam("format"), data, cfg.DefaultCheckInterval, dryRun)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	if dryRun {
+		return c.JSON(http.StatusOK, result)
+	}
+
+	changes := make([]*bulkChange, len(result.Created))
+	for i, source := range result.Created {
+		changes[i] = &bulkChange{op: "create", source: source}
+	}
+	am.reconcileBulkChanges(changes)
+
+	am.log(c).Printf("Imported %d source(s), %d webhook(s) and %d chat(s) from %s via API (%d warning(s))",
+		result.Sources, result.Webhooks, result.Chats, c.QueryParam("format"), len(result.Warnings))
+	return c.JSON(http.StatusOK, result)
+}
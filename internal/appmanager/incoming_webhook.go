@@ -10,13 +10,26 @@ import (
 	"time"
 
 	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
 )
 
 const webhookTokenChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 const webhookTokenLength = 8
 
+// maxWebhookBodySize caps how much of an incoming heartbeat body is read
+const maxWebhookBodySize = 1 << 20
+
+// heartbeatSnippetLength is how many body bytes are kept for debugging
+const heartbeatSnippetLength = 512
+
 // generateWebhookToken returns a short random token, checking DB for uniqueness
 func (am *AppManager) generateWebhookToken() (string, error) {
+	return newWebhookToken(am.storage)
+}
+
+// newWebhookToken returns a short random token no source in db uses
+func newWebhookToken(db *storage.BoltDB) (string, error) {
 	for i := 0; i < 10; i++ {
 		b := make([]byte, webhookTokenLength)
 		if _, err := rand.Read(b); err != nil {
@@ -26,7 +39,7 @@ func (am *AppManager) generateWebhookToken() (string, error) {
 			b[j] = webhookTokenChars[int(b[j])%len(webhookTokenChars)]
 		}
 		token := string(b)
-		_, err := am.storage.GetSourceByWebhookToken(token)
+		_, err := db.GetSourceByWebhookToken(token)
 		if err != nil {
 			return token, nil
 		}
@@ -34,26 +47,84 @@ func (am *AppManager) generateWebhookToken() (string, error) {
 	return "", fmt.Errorf("could not generate unique webhook token")
 }
 
+// handleRotateWebhookToken replaces the incoming webhook token of a webhook source.
+// The old heartbeat URL stops working immediately; history is kept.
+func (am *AppManager) handleRotateWebhookToken(c echo.Context) error {
+	source, err := am.storage.GetSource(c.Param("id"))
+	if err != nil || source.IsDeleted() {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+	if source.Type != "webhook" {
+		return errorJSON(c, http.StatusBadRequest, "Only webhook sources have an incoming webhook token")
+	}
+
+	token, err := am.generateWebhookToken()
+	if err != nil {
+		return errorJSON(c, http.StatusInternalServerError, "Failed to generate webhook token: "+err.Error())
+	}
+	source.WebhookToken = token
+
+	if err := am.storage.SaveSource(source); err != nil {
+		return errorJSON(c, http.StatusInternalServerError, err.Error())
+	}
+
+	if mon := am.botProcess.GetMonitor(); mon != nil {
+		mon.UpdateWebhookToken(source.ID, token)
+	}
+
+	am.log(c).Printf("Rotated incoming webhook token for source %s (%s)", source.Name, source.ID)
+
+	return c.JSON(http.StatusOK, source)
+}
+
 // handleIncomingWebhook processes GET or POST requests to /webhooks/incoming/:token.
 // No API key required. Validates optional headers/body and records heartbeat.
 func (am *AppManager) handleIncomingWebhook(c echo.Context) error {
 	token := c.Param("token")
 	if token == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Missing webhook token",
-		})
+		return errorJSON(c, http.StatusBadRequest, "Missing webhook token")
 	}
 
 	source, err := am.storage.GetSourceByWebhookToken(token)
 	if err != nil {
-		am.logger.Printf("Incoming webhook: token not found: %s", token)
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Webhook not found",
-		})
+		am.log(c).Warnf("Incoming webhook: token not found: %s", token)
+		return errorJSON(c, http.StatusNotFound, "Webhook not found")
+	}
+
+	var body []byte
+	if c.Request().Body != nil {
+		body, err = io.ReadAll(io.LimitReader(c.Request().Body, maxWebhookBodySize))
+		if err != nil {
+			return errorJSON(c, http.StatusBadRequest, "Failed to read body")
+		}
+	}
+
+	snippet := body
+	if len(snippet) > heartbeatSnippetLength {
+		snippet = snippet[:heartbeatSnippetLength]
+	}
+
+	// Record every request with its validation outcome for GET /sources/:id/webhook-requests
+	record := &IncomingWebhookRequest{
+		ReceivedAt:  time.Now(),
+		RemoteIP:    c.RealIP(),
+		Method:      c.Request().Method,
+		Headers:     recordedHeaders(c.Request().Header),
+		BodySnippet: strings.ToValidUTF8(string(snippet), ""),
+	}
+	respond := func(status int, outcome, detail string, response interface{}) error {
+		record.StatusCode = status
+		record.Outcome = outcome
+		record.Detail = detail
+		am.webhookRequests.add(source.ID, record)
+		return c.JSON(status, response)
+	}
+	reject := func(status int, outcome, detail, message string) error {
+		return respond(status, outcome, detail, newErrorResponse(c, status, message))
 	}
 
 	if !source.Enabled {
-		return c.JSON(http.StatusOK, map[string]string{
+		return respond(http.StatusOK, webhookOutcomePaused, "", map[string]string{
 			"status": "ok",
 			"note":   "Source is paused",
 		})
@@ -63,62 +134,55 @@ func (am *AppManager) handleIncomingWebhook(c echo.Context) error {
 	if source.ExpectedHeaders != "" {
 		var expected map[string]string
 		if err := json.Unmarshal([]byte(source.ExpectedHeaders), &expected); err != nil {
-			am.logger.Printf("Incoming webhook: invalid expected_headers for source %s: %v", source.ID, err)
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "Invalid source configuration",
-			})
+			am.log(c).Warnf("Incoming webhook: invalid expected_headers for source %s: %v", source.ID, err)
+			return reject(http.StatusInternalServerError, webhookOutcomeError, "invalid expected_headers: "+err.Error(), "Invalid source configuration")
 		}
 		for k, v := range expected {
 			got := c.Request().Header.Get(k)
 			if got != v {
-				am.logger.Printf("Incoming webhook: header %q mismatch for source %s", k, source.Name)
-				return c.JSON(http.StatusUnauthorized, map[string]string{
-					"error": "Header validation failed",
-				})
+				am.log(c).Warnf("Incoming webhook: header %q mismatch for source %s", k, source.Name)
+				detail := fmt.Sprintf("header %q does not match", k)
+				if got == "" {
+					detail = fmt.Sprintf("header %q is missing", k)
+				}
+				return reject(http.StatusUnauthorized, webhookOutcomeHeaderMismatch, detail, "Header validation failed")
 			}
 		}
 	}
 
 	// Validate expected content (substring in body) for POST/PUT/PATCH
 	if source.ExpectedContent != "" {
-		if c.Request().Body != nil {
-			body, err := io.ReadAll(c.Request().Body)
-			if err != nil {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "Failed to read body",
-				})
-			}
-			if !strings.Contains(string(body), source.ExpectedContent) {
-				am.logger.Printf("Incoming webhook: body content mismatch for source %s", source.Name)
-				return c.JSON(http.StatusUnauthorized, map[string]string{
-					"error": "Content validation failed",
-				})
-			}
-		} else {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Expected content in body",
-			})
+		if len(body) == 0 {
+			return reject(http.StatusBadRequest, webhookOutcomeMissingContent, "request body is empty", "Expected content in body")
+		}
+		if !strings.Contains(string(body), source.ExpectedContent) {
+			am.log(c).Warnf("Incoming webhook: body content mismatch for source %s", source.Name)
+			return reject(http.StatusUnauthorized, webhookOutcomeContentMismatch, "body does not contain the expected content", "Content validation failed")
 		}
 	}
 
-	now := time.Now()
+	heartbeat := &storage.Heartbeat{
+		ReceivedAt:     record.ReceivedAt,
+		RemoteIP:       record.RemoteIP,
+		UserAgent:      c.Request().UserAgent(),
+		Method:         record.Method,
+		PayloadSnippet: record.BodySnippet,
+	}
 
 	// Persist heartbeat
-	if err := am.storage.UpdateSourceStatus(source.ID, 1, now); err != nil {
-		am.logger.Printf("Incoming webhook: failed to update source status: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to record heartbeat",
-		})
+	if err := am.storage.RecordHeartbeat(source.ID, heartbeat); err != nil {
+		am.log(c).Errorf("Incoming webhook: failed to update source status: %v", err)
+		return reject(http.StatusInternalServerError, webhookOutcomeError, "failed to record heartbeat", "Failed to record heartbeat")
 	}
 
 	// Update monitor cache so next tick sees the new last-check time
 	if mon := am.botProcess.GetMonitor(); mon != nil {
-		mon.RecordWebhookReceived(source.ID, now)
+		mon.RecordWebhookReceived(source.ID, heartbeat)
 	}
 
-	am.logger.Printf("Incoming webhook: heartbeat recorded for %s (token %s)", source.Name, token)
+	am.log(c).Debugf("Incoming webhook: heartbeat recorded for %s (token %s, from %s)", source.Name, token, heartbeat.RemoteIP)
 
-	return c.JSON(http.StatusOK, map[string]string{
+	return respond(http.StatusOK, webhookOutcomeAccepted, "", map[string]string{
 		"status": "ok",
 	})
 }
@@ -0,0 +1,175 @@
+package appmanager
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"tg-monitor-bot/internal/config"
+)
+
+// unixSocketPrefix marks an API_BIND value as a Unix socket path, e.g. unix:///run/bot.sock
+const unixSocketPrefix = "unix://"
+
+// unixSocketMode lets a reverse proxy in the same group connect to the socket
+const unixSocketMode = 0660
+
+// apiStartTimeout bounds the wait for the API server to start listening
+const apiStartTimeout = 5 * time.Second
+
+// apiBindRetryDelays is the backoff while the address is in use, e.g. still held by the
+// previous process during a rolling restart
+var apiBindRetryDelays = []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+
+// apiServerState tracks whether the API server is listening, for /health and /status
+type apiServerState struct {
+	mu        sync.Mutex
+	running   bool
+	lastError string
+}
+
+// set records whether the server is listening and, when it failed, why
+func (s *apiServerState) set(running bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = running
+	s.lastError = ""
+	if err != nil {
+		s.lastError = err.Error()
+	}
+}
+
+// get returns whether the server is listening and why it stopped, if it failed
+func (s *apiServerState) get() (bool, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running, s.lastError
+}
+
+// apiAddress is where the API server listens: a TCP host:port or a Unix socket path
+type apiAddress struct {
+	network string // "tcp" or "unix"
+	address string
+}
+
+// newAPIAddress parses API_BIND, defaulting to all interfaces at API_PORT
+func newAPIAddress(cfg *config.Config) (apiAddress, error) {
+	bind := strings.TrimSpace(cfg.APIBind)
+	if bind == "" {
+		return apiAddress{network: "tcp", address: fmt.Sprintf(":%d", cfg.APIPort)}, nil
+	}
+
+	if path, ok := strings.CutPrefix(bind, unixSocketPrefix); ok {
+		if path == "" {
+			return apiAddress{}, fmt.Errorf("API_BIND %q has no socket path", bind)
+		}
+		// The proxy in front of the socket terminates TLS
+		if cfg.TLSCertFile != "" || cfg.TLSAutocertDomain != "" {
+			return apiAddress{}, fmt.Errorf("TLS is not supported on a Unix socket API_BIND")
+		}
+		return apiAddress{network: "unix", address: path}, nil
+	}
+
+	if _, _, err := net.SplitHostPort(bind); err != nil {
+		return apiAddress{}, fmt.Errorf("API_BIND must be host:port or %s/path/to.sock: %w", unixSocketPrefix, err)
+	}
+	return apiAddress{network: "tcp", address: bind}, nil
+}
+
+// String returns the address as configured, e.g. 127.0.0.1:8080 or unix:///run/bot.sock
+func (a apiAddress) String() string {
+	if a.network == "unix" {
+		return unixSocketPrefix + a.address
+	}
+	return a.address
+}
+
+// listenUnix creates the Unix socket, replacing a stale one left behind by a crash.
+// The socket file is removed again when the listener is closed.
+func (a apiAddress) listenUnix() (net.Listener, error) {
+	if info, err := os.Lstat(a.address); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", a.address)
+		}
+		if err := os.Remove(a.address); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", a.address)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(a.address, unixSocketMode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// serveAPI starts the Echo server in the background and waits until it listens, so bind
+// failures (port in use, permission denied, bad certificate) fail startup instead of leaving
+// the app running without an API. Binding is retried with backoff while the address is in use.
+func (am *AppManager) serveAPI() error {
+	for attempt := 0; ; attempt++ {
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- am.startServer(am.apiAddr.address)
+		}()
+
+		err := am.waitForAPIServer(errCh)
+		if err == nil {
+			am.apiServer.set(true, nil)
+			go am.watchAPIServer(errCh)
+			return nil
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) || attempt >= len(apiBindRetryDelays) {
+			am.apiServer.set(false, err)
+			return err
+		}
+
+		delay := apiBindRetryDelays[attempt]
+		am.logger.Warnf("⚠️  %s is in use, retrying in %s", am.apiAddr, delay)
+		time.Sleep(delay)
+	}
+}
+
+// waitForAPIServer returns once the server listens, or with the error it stopped with
+func (am *AppManager) waitForAPIServer(errCh <-chan error) error {
+	deadline := time.After(apiStartTimeout)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-errCh:
+			if err == nil {
+				err = errors.New("API server stopped during startup")
+			}
+			return err
+		case <-deadline:
+			return fmt.Errorf("API server did not start listening within %s", apiStartTimeout)
+		case <-ticker.C:
+			if am.echoServer.ListenerAddr() != nil || am.echoServer.TLSListenerAddr() != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// watchAPIServer records the API server stopping; anything but a shutdown is a failure
+func (am *AppManager) watchAPIServer(errCh <-chan error) {
+	err := <-errCh
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	} else {
+		am.logger.Errorf("❌ Echo server stopped: %v", err)
+	}
+	am.apiServer.set(false, err)
+}
@@ -0,0 +1,20 @@
+package appmanager
+
+import (
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/logging"
+)
+
+// applyLogFile copies log output to LOG_FILE with the configured rotation, or stops when it
+// is empty. It runs at start and on every config change, since rotation settings are cheap
+// to apply in place.
+func (am *AppManager) applyLogFile(cfg *config.Config) {
+	err := logging.SetFile(cfg.LogFile, logging.RotateOptions{
+		MaxSize:    int64(cfg.LogFileMaxSize) << 20,
+		MaxAge:     cfg.LogFileMaxAge,
+		MaxBackups: cfg.LogFileMaxBackups,
+	})
+	if err != nil {
+		am.logger.Errorf("❌ Failed to open log file %s: %v", cfg.LogFile, err)
+	}
+}
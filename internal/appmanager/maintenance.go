@@ -0,0 +1,143 @@
+package appmanager
+
+import (
+	"context"
+	"time"
+
+	"tg-monitor-bot/internal/config"
+)
+
+// maintenanceInterval is how often background maintenance jobs run
+const maintenanceInterval = 1 * time.Hour
+
+// rollupBackfillDays limits how far back daily rollups are computed for sources without any
+const rollupBackfillDays = 90
+
+// startMaintenance launches the periodic background maintenance loop
+func (am *AppManager) startMaintenance() {
+	ctx, cancel := context.WithCancel(context.Background())
+	am.maintenanceCancel = cancel
+	am.lastCompaction = time.Now() // don't compact right after every restart
+
+	go am.runDeadMansSwitch(ctx)
+	go am.runWatchdog(ctx)
+	go am.runSLOAlerts(ctx)
+	go am.runDiscovery(ctx)
+
+	go func() {
+		ticker := time.NewTicker(maintenanceInterval)
+		defer ticker.Stop()
+
+		am.runMaintenance()
+
+		for {
+			select {
+			case <-ctx.Done():
+				am.logger.Println("Maintenance loop stopped")
+				return
+			case <-ticker.C:
+				am.runMaintenance()
+			}
+		}
+	}()
+}
+
+// runMaintenance runs all maintenance jobs once with the current config
+func (am *AppManager) runMaintenance() {
+	cfg, err := am.configManager.AsConfig()
+	if err != nil {
+		am.logger.Warnf("Maintenance skipped, failed to get config: %v", err)
+		return
+	}
+
+	am.purgeDeletedSources(cfg)
+	am.pruneDeliveries(cfg)
+	am.pruneSystemEvents(cfg)
+	am.rollupDailyUptime()
+	am.compactIfDue(cfg)
+	am.sendScheduledReports(context.Background(), cfg, time.Now())
+}
+
+// purgeDeletedSources permanently removes sources that stayed in trash past the retention period
+func (am *AppManager) purgeDeletedSources(cfg *config.Config) {
+	purged, err := am.storage.PurgeDeletedSources(cfg.DeletedSourceRetention)
+	if err != nil {
+		am.logger.Errorf("Failed to purge deleted sources: %v", err)
+		return
+	}
+	if purged > 0 {
+		am.logger.Printf("Purged %d source(s) deleted more than %v ago", purged, cfg.DeletedSourceRetention)
+	}
+}
+
+// pruneDeliveries drops delivery log entries older than the metrics retention period
+func (am *AppManager) pruneDeliveries(cfg *config.Config) {
+	if _, err := am.storage.DeleteOldDeliveries(cfg.MetricsRetention); err != nil {
+		am.logger.Errorf("Failed to prune delivery log: %v", err)
+	}
+}
+
+// pruneSystemEvents drops system events older than the metrics retention period
+func (am *AppManager) pruneSystemEvents(cfg *config.Config) {
+	if _, err := am.storage.DeleteOldSystemEvents(cfg.MetricsRetention); err != nil {
+		am.logger.Errorf("Failed to prune system events: %v", err)
+	}
+}
+
+// rollupDailyUptime computes daily uptime aggregates for every completed UTC day not yet rolled up
+func (am *AppManager) rollupDailyUptime() {
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		am.logger.Errorf("Failed to load sources for rollup: %v", err)
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	earliest := today.AddDate(0, 0, -rollupBackfillDays)
+	computed := 0
+
+	for _, source := range sources {
+		latest, err := am.storage.GetLatestRollupDate(source.ID)
+		if err != nil {
+			am.logger.Errorf("Failed to get latest rollup for %s: %v", source.Name, err)
+			continue
+		}
+
+		day := latest.AddDate(0, 0, 1)
+		if latest.IsZero() {
+			day = source.CreatedAt.UTC().Truncate(24 * time.Hour)
+		}
+		if day.Before(earliest) {
+			day = earliest
+		}
+
+		for ; day.Before(today); day = day.AddDate(0, 0, 1) {
+			rollup, err := am.storage.ComputeDailyRollup(source, day)
+			if err != nil {
+				am.logger.Errorf("Failed to compute rollup for %s on %s: %v", source.Name, day.Format("2006-01-02"), err)
+				break
+			}
+			if err := am.storage.SaveDailyRollup(rollup); err != nil {
+				am.logger.Errorf("Failed to save rollup for %s: %v", source.Name, err)
+				break
+			}
+			computed++
+		}
+	}
+
+	if computed > 0 {
+		am.logger.Printf("Computed %d daily uptime rollup(s)", computed)
+	}
+}
+
+// compactIfDue compacts the database when the scheduled compaction interval has elapsed
+func (am *AppManager) compactIfDue(cfg *config.Config) {
+	if cfg.CompactionInterval <= 0 || time.Since(am.lastCompaction) < cfg.CompactionInterval {
+		return
+	}
+
+	if _, err := am.storage.Compact(); err != nil {
+		am.logger.Errorf("Scheduled compaction failed: %v", err)
+	}
+	am.lastCompaction = time.Now()
+}
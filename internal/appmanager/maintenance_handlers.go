@@ -0,0 +1,191 @@
+package appmanager
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// MaintenanceWindowRequest is the request body for creating or replacing a maintenance window
+type MaintenanceWindowRequest struct {
+	Name       string     `json:"name"`
+	SourceIDs  []string   `json:"source_ids,omitempty"` // With tags, empty covers every source
+	Tags       []string   `json:"tags,omitempty"`
+	Start      *time.Time `json:"start"` // Default now
+	End        *time.Time `json:"end"`   // Omit to keep a one-off window open until closed
+	Recurrence string     `json:"recurrence,omitempty"`
+	RecurUntil *time.Time `json:"recur_until,omitempty"`
+}
+
+// MaintenanceWindowResponse is a maintenance window with its state at the time of the request
+type MaintenanceWindowResponse struct {
+	*storage.MaintenanceWindow
+	Active    bool       `json:"active"`
+	NextStart *time.Time `json:"next_start,omitempty"`
+}
+
+// newMaintenanceWindowResponse adds a window's state at now
+func newMaintenanceWindowResponse(window *storage.MaintenanceWindow, now time.Time) MaintenanceWindowResponse {
+	return MaintenanceWindowResponse{
+		MaintenanceWindow: window,
+		Active:            window.ActiveAt(now),
+		NextStart:         window.NextStart(now),
+	}
+}
+
+// handleGetMaintenanceWindows lists maintenance windows by start. ?active=true returns only
+// those active now, and ?source_id= only those covering a source.
+func (am *AppManager) handleGetMaintenanceWindows(c echo.Context) error {
+	windows, err := am.storage.GetMaintenanceWindows()
+	if err != nil {
+		am.log(c).Errorf("Failed to list maintenance windows: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to list maintenance windows")
+	}
+
+	activeOnly := false
+	if value := c.QueryParam("active"); value != "" {
+		if activeOnly, err = strconv.ParseBool(value); err != nil {
+			return errorJSON(c, http.StatusBadRequest, "Invalid active (use true or false)")
+		}
+	}
+	var source *storage.Source
+	if sourceID := c.QueryParam("source_id"); sourceID != "" {
+		if source, err = am.storage.GetSource(sourceID); err != nil {
+			return errorJSON(c, http.StatusNotFound, "Source not found")
+		}
+	}
+
+	now := time.Now()
+	result := make([]MaintenanceWindowResponse, 0, len(windows))
+	for _, window := range windows {
+		if source != nil && !window.Covers(source) {
+			continue
+		}
+		response := newMaintenanceWindowResponse(window, now)
+		if activeOnly && !response.Active {
+			continue
+		}
+		result = append(result, response)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// handleGetMaintenanceWindow returns a maintenance window
+func (am *AppManager) handleGetMaintenanceWindow(c echo.Context) error {
+	window, err := am.storage.GetMaintenanceWindow(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Maintenance window not found")
+	}
+	return c.JSON(http.StatusOK, newMaintenanceWindowResponse(window, time.Now()))
+}
+
+// handleCreateMaintenanceWindow creates a maintenance window, starting now unless start is given
+func (am *AppManager) handleCreateMaintenanceWindow(c echo.Context) error {
+	window := &storage.MaintenanceWindow{}
+	if err := am.bindMaintenanceWindow(c, window); err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := am.storage.SaveMaintenanceWindow(window); err != nil {
+		am.log(c).Errorf("Failed to create maintenance window: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to create maintenance window")
+	}
+
+	return c.JSON(http.StatusCreated, newMaintenanceWindowResponse(window, time.Now()))
+}
+
+// handleUpdateMaintenanceWindow replaces a maintenance window's name, scope and schedule
+func (am *AppManager) handleUpdateMaintenanceWindow(c echo.Context) error {
+	window, err := am.storage.GetMaintenanceWindow(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Maintenance window not found")
+	}
+	if err := am.bindMaintenanceWindow(c, window); err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := am.storage.SaveMaintenanceWindow(window); err != nil {
+		am.log(c).Errorf("Failed to update maintenance window: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to update maintenance window")
+	}
+
+	return c.JSON(http.StatusOK, newMaintenanceWindowResponse(window, time.Now()))
+}
+
+// handleCloseMaintenanceWindow ends a one-off maintenance window now, e.g. after a deploy
+func (am *AppManager) handleCloseMaintenanceWindow(c echo.Context) error {
+	window, err := am.storage.GetMaintenanceWindow(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Maintenance window not found")
+	}
+	if window.Recurrence != storage.RecurNone {
+		return errorJSON(c, http.StatusBadRequest, "Recurring windows can't be closed; set recur_until or delete them")
+	}
+
+	now := time.Now()
+	if !window.ActiveAt(now) {
+		return errorJSON(c, http.StatusConflict, "Maintenance window is not active")
+	}
+	window.End = &now
+	if err := am.storage.SaveMaintenanceWindow(window); err != nil {
+		am.log(c).Errorf("Failed to close maintenance window: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to close maintenance window")
+	}
+
+	return c.JSON(http.StatusOK, newMaintenanceWindowResponse(window, now))
+}
+
+// handleDeleteMaintenanceWindow deletes a maintenance window
+func (am *AppManager) handleDeleteMaintenanceWindow(c echo.Context) error {
+	id := c.Param("id")
+	if _, err := am.storage.GetMaintenanceWindow(id); err != nil {
+		return errorJSON(c, http.StatusNotFound, "Maintenance window not found")
+	}
+
+	if err := am.storage.DeleteMaintenanceWindow(id); err != nil {
+		am.log(c).Errorf("Failed to delete maintenance window: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to delete maintenance window")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Maintenance window deleted successfully",
+		"id":      id,
+	})
+}
+
+// bindMaintenanceWindow reads a MaintenanceWindowRequest into window and validates it
+func (am *AppManager) bindMaintenanceWindow(c echo.Context, window *storage.MaintenanceWindow) error {
+	var req MaintenanceWindowRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("Invalid request body")
+	}
+
+	tags, err := storage.NormalizeTags(req.Tags)
+	if err != nil {
+		return err
+	}
+	for _, sourceID := range req.SourceIDs {
+		if _, err := am.storage.GetSource(sourceID); err != nil {
+			return fmt.Errorf("Unknown source: %s", sourceID)
+		}
+	}
+
+	window.Name = strings.TrimSpace(req.Name)
+	window.SourceIDs = req.SourceIDs
+	window.Tags = tags
+	window.Start = time.Now()
+	if req.Start != nil {
+		window.Start = *req.Start
+	}
+	window.End = req.End
+	window.Recurrence = req.Recurrence
+	window.RecurUntil = req.RecurUntil
+	return window.Validate()
+}
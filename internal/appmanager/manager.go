@@ -3,15 +3,21 @@ package appmanager
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 
+	"tg-monitor-bot/internal/coord"
+	"tg-monitor-bot/internal/errreport"
+	"tg-monitor-bot/internal/logging"
+	"tg-monitor-bot/internal/monitor"
 	"tg-monitor-bot/internal/storage"
+	"tg-monitor-bot/internal/tracing"
 )
 
 // AppManager orchestrates the entire application
@@ -19,21 +25,43 @@ type AppManager struct {
 	storage       *storage.BoltDB
 	configManager *ConfigManager
 	botProcess    *BotProcess
+	events        *monitor.EventBus
 	echoServer    *echo.Echo
 	apiKey        string
 	apiPort       int
 	apiEnabled    bool
+	tls           tlsSettings
+	apiAddr       apiAddress
+	apiServer     apiServerState
+	corsOrigins   []string
+	corsHeaders   []string
+	oidc          *oidcVerifier // nil unless OIDC_ISSUER_URL is set
 	startTime     time.Time
-	logger        *log.Logger
+	logger        *logging.Logger
 	version       string
+
+	maintenanceCancel context.CancelFunc
+	lastCompaction    time.Time
+	webhookRequests   webhookRequestLog // Recent incoming webhook requests per source
+	configFile        configFileState   // CONFIG_FILE path and last apply result
+	configFileCancel  context.CancelFunc
+	deadMansSwitch    deadMansSwitch // Outgoing heartbeats to HEARTBEAT_URL
+	watchdog          watchdogState  // Whether checks have stalled
+	sloAlerts         sloAlertState  // Burn rate alert level per source
+	agents            agentState     // Remote probe agents and their latest results
+	replica           replicaState   // Copy served when the database is read-only
+	coord             *coord.Client  // Redis coordination, nil unless REDIS_URL is set
+	coordCancel       context.CancelFunc
+	usersMu           sync.Mutex // Serializes ALLOWED_USERS changes, see setUserAllowed
 }
 
 // New creates a new AppManager
 func New(db *storage.BoltDB, version string) *AppManager {
 	return &AppManager{
 		storage:    db,
+		events:     monitor.NewEventBus(),
 		startTime:  time.Now(),
-		logger:     log.New(log.Writer(), "[APPMANAGER] ", log.LstdFlags),
+		logger:     logging.New("appmanager"),
 		version:    version,
 	}
 }
@@ -45,29 +73,54 @@ func (am *AppManager) Start() error {
 	// Create ConfigManager
 	am.configManager = NewConfigManager(am.storage)
 
-	// Set onChange callback to restart bot
-	am.configManager.SetOnChange(func() {
-		am.logger.Println("Config changed, triggering bot restart...")
-		if err := am.RestartBot(); err != nil {
-			am.logger.Printf("Failed to restart bot: %v", err)
-		}
-	})
-
 	// Load config from DB or env
 	if err := am.configManager.Load(); err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	// Values from CONFIG_FILE override DB and env; applied before the callback is set,
+	// since there is no bot to reload yet
+	file := am.loadConfigFile()
+
+	// Set onChange callback to reload the affected parts of the bot
+	am.configManager.SetOnChange(func() {
+		am.logger.Println("Config changed, reloading bot...")
+		if cfg, err := am.configManager.AsConfig(); err == nil {
+			am.applyLogFile(cfg)
+			am.applyTracing(cfg)
+			am.applyErrorReporting(cfg)
+		}
+		if err := am.applyConfigChange(); err != nil {
+			am.logger.Errorf("Failed to reload bot: %v", err)
+		}
+	})
+
 	// Get config for initialization
 	cfg, err := am.configManager.AsConfig()
 	if err != nil {
 		return fmt.Errorf("failed to parse config: %w", err)
 	}
+	am.applyLogFile(cfg)
+	am.applyTracing(cfg)
+	am.applyErrorReporting(cfg)
+
+	am.recordStartup()
 
 	// Store API settings
 	am.apiEnabled = cfg.APIEnabled
 	am.apiPort = cfg.APIPort
 	am.apiKey = cfg.APIKey
+	am.corsOrigins = cfg.CORSAllowedOrigins
+	am.corsHeaders = cfg.CORSAllowedHeaders
+	if am.oidc, err = newOIDCVerifier(cfg); err != nil {
+		return fmt.Errorf("invalid OIDC config: %w", err)
+	}
+	if am.tls, err = newTLSSettings(cfg); err != nil {
+		return fmt.Errorf("invalid TLS config: %w", err)
+	}
+	if am.apiAddr, err = newAPIAddress(cfg); err != nil {
+		return fmt.Errorf("invalid API bind address: %w", err)
+	}
 
 	// Start Echo server if API is enabled
 	if am.apiEnabled {
@@ -80,22 +133,68 @@ func (am *AppManager) Start() error {
 
 	// Create and start bot process
 	am.botProcess = NewBotProcess(am.storage)
+	am.botProcess.SetEventBus(am.events)
+
+	// A replica only serves the copied data: no checks, bot, config file or maintenance
+	if am.isReplica() {
+		am.startReplica(cfg)
+		am.startCoordination()
+		am.logger.Println("✅ AppManager started as a read-only replica")
+		return nil
+	}
 
 	// Set auto-restart callback
 	am.botProcess.SetRestartFunc(func() error {
 		am.logger.Println("Auto-restart callback triggered")
-		return am.RestartBot()
+		return am.RestartBot("auto-restart")
 	})
+	am.botProcess.SetAllowedUsersFunc(am.setUserAllowed)
 
 	if err := am.botProcess.Start(cfg); err != nil {
 		// Log the error but don't fail - bot process tracks its own health
-		am.logger.Printf("⚠️  Bot process started with errors: %v", err)
+		am.logger.Warnf("⚠️  Bot process started with errors: %v", err)
+		am.recordSystemEvent(storage.SystemEventBotStart, "Bot started with errors", map[string]string{"error": err.Error()})
+	} else {
+		am.recordSystemEvent(storage.SystemEventBotStart, "Bot started", nil)
 	}
 
+	// Declared sources are reconciled once the monitor runs, then on every file change
+	if file != nil {
+		if err := am.applyConfigFile(file); err != nil {
+			am.configFileFailed(err)
+		}
+	}
+	if am.configFile.path != "" {
+		ctx, cancel := context.WithCancel(context.Background())
+		am.configFileCancel = cancel
+		if err := am.watchConfigFile(ctx); err != nil {
+			am.logger.Warnf("⚠️  Failed to watch config file, changes need a restart: %v", err)
+		}
+	}
+
+	// Start background maintenance jobs
+	am.startMaintenance()
+	am.startCoordination()
+
 	am.logger.Println("✅ AppManager started successfully")
 	return nil
 }
 
+// setupCORS registers the CORS middleware when allowed origins are configured
+func (am *AppManager) setupCORS() {
+	if len(am.corsOrigins) == 0 {
+		return
+	}
+	am.echoServer.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:  am.corsOrigins,
+		AllowHeaders:  am.corsHeaders,
+		AllowMethods:  []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
+		ExposeHeaders: []string{"X-Total-Count", echo.HeaderXRequestID, headerETag},
+		MaxAge:        3600,
+	}))
+	am.logger.Printf("CORS enabled for origins: %s", strings.Join(am.corsOrigins, ", "))
+}
+
 // startEchoServer initializes and starts the Echo HTTP server
 func (am *AppManager) startEchoServer() error {
 	am.echoServer = echo.New()
@@ -104,8 +203,16 @@ func (am *AppManager) startEchoServer() error {
 	am.echoServer.HideBanner = true
 	am.echoServer.HidePort = true
 
-	// Add middleware
-	am.echoServer.Use(middleware.Recover())
+	// Request IDs, access log, panic recovery and JSON errors
+	am.setupBaseMiddleware()
+
+	// CORS must run before API key auth so preflight requests (which carry no key) succeed
+	am.setupCORS()
+
+	// A replica rejects changes before they reach auth or handlers
+	if am.isReplica() {
+		am.echoServer.Use(am.readOnlyMiddleware)
+	}
 
 	// Setup routes
 	am.setupRoutes()
@@ -113,47 +220,52 @@ func (am *AppManager) startEchoServer() error {
 	// Log API key in development mode
 	if am.isDevMode() {
 		if am.apiKey == "" {
-			am.logger.Println("⚠️  DEV MODE: No API key configured. API will require X-API-Key header.")
+			am.logger.Warnf("⚠️  DEV MODE: No API key configured. API will require X-API-Key header.")
 		} else {
-			am.logger.Printf("🔑 DEV MODE: API Key = %s", am.apiKey)
+			am.logger.Println("🔑 DEV MODE: API key configured (stored hashed; use the value from your environment)")
 		}
 	}
 
-	// Start server in goroutine
-	go func() {
-		addr := fmt.Sprintf(":%d", am.apiPort)
-		am.logger.Printf("Starting Echo server on %s", addr)
-
-		if err := am.echoServer.Start(addr); err != nil {
-			am.logger.Printf("Echo server stopped: %v", err)
+	// Echo only creates TCP listeners, so a Unix socket is opened here
+	if am.apiAddr.network == "unix" {
+		listener, err := am.apiAddr.listenUnix()
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", am.apiAddr, err)
 		}
-	}()
+		am.echoServer.Listener = listener
+	}
 
-	// Give server time to start
-	time.Sleep(100 * time.Millisecond)
+	am.logger.Printf("Starting Echo server on %s (%s)", am.apiAddr, am.tls.scheme())
+	if err := am.serveAPI(); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", am.apiAddr, err)
+	}
 
-	am.logger.Printf("✅ Echo API server started on port %d", am.apiPort)
+	am.logger.Printf("✅ Echo API server started on %s", am.apiAddr)
 	return nil
 }
 
-// RestartBot stops and starts the bot with fresh config
-func (am *AppManager) RestartBot() error {
-	am.logger.Println("Restarting bot...")
+// RestartBot stops and starts the bot with fresh config. The reason is recorded in the
+// system event log.
+func (am *AppManager) RestartBot(reason string) error {
+	am.logger.Printf("Restarting bot (%s)...", reason)
 
 	// Get fresh config
 	cfg, err := am.configManager.AsConfig()
 	if err != nil {
-		am.logger.Printf("❌ Failed to get config for restart: %v", err)
+		am.logger.Errorf("❌ Failed to get config for restart: %v", err)
 		return fmt.Errorf("failed to get config: %w", err)
 	}
 
 	// Restart bot process - don't fail if bot has errors, it tracks its own health
+	details := map[string]string{"reason": reason}
 	if err := am.botProcess.Restart(cfg); err != nil {
-		am.logger.Printf("⚠️  Bot restarted with errors: %v", err)
+		am.logger.Warnf("⚠️  Bot restarted with errors: %v", err)
 		// Don't return error - bot is running but may be unhealthy
+		details["error"] = err.Error()
 	} else {
 		am.logger.Println("✅ Bot restarted successfully")
 	}
+	am.recordSystemEvent(storage.SystemEventBotRestart, "Bot restarted ("+reason+")", details)
 
 	return nil
 }
@@ -162,10 +274,25 @@ func (am *AppManager) RestartBot() error {
 func (am *AppManager) Shutdown() error {
 	am.logger.Println("Shutting down AppManager...")
 
+	// Stop maintenance jobs
+	if am.maintenanceCancel != nil {
+		am.maintenanceCancel()
+	}
+
+	// Stop sharing status changes
+	if am.coordCancel != nil {
+		am.coordCancel()
+	}
+
+	// Stop watching the config file
+	if am.configFileCancel != nil {
+		am.configFileCancel()
+	}
+
 	// Stop bot process
 	if am.botProcess != nil {
 		if err := am.botProcess.Stop(); err != nil {
-			am.logger.Printf("Error stopping bot: %v", err)
+			am.logger.Errorf("Error stopping bot: %v", err)
 		}
 	}
 
@@ -175,11 +302,20 @@ func (am *AppManager) Shutdown() error {
 		defer cancel()
 
 		if err := am.echoServer.Shutdown(ctx); err != nil {
-			am.logger.Printf("Error shutting down Echo: %v", err)
+			am.logger.Errorf("Error shutting down Echo: %v", err)
 		}
 	}
 
+	am.recordSystemEvent(storage.SystemEventShutdown, "Application stopped", map[string]string{
+		"uptime": time.Since(am.startTime).Round(time.Second).String(),
+	})
+
+	// Export the spans of the last checks and notifications, and pending error reports
+	tracing.Shutdown()
+	errreport.Shutdown()
+
 	am.logger.Println("✅ AppManager shutdown complete")
+	logging.SetFile("", logging.RotateOptions{})
 	return nil
 }
 
@@ -0,0 +1,192 @@
+package appmanager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// namespacedRoutes are the route prefixes keys limited to a namespace may use. Everything
+// else (config, templates, discovery, tags, aggregates, events) spans namespaces.
+var namespacedRoutes = []string{"/sources", "/sinks", "/webhooks", "/telegram-chats", "/test/"}
+
+// NamespaceRequest is the request body for creating or updating a namespace
+type NamespaceRequest struct {
+	Name        string `json:"name"` // Create only; lowercase letters, digits and "-_.:"
+	Description string `json:"description,omitempty"`
+}
+
+// NamespaceResponse is a namespace with the resources that belong to it
+type NamespaceResponse struct {
+	*storage.Namespace
+	Usage storage.NamespaceUsage `json:"usage"`
+}
+
+// namespacedRoute reports whether a route is open to keys limited to a namespace
+func namespacedRoute(route string) bool {
+	for _, prefix := range namespacedRoutes {
+		if strings.HasPrefix(route, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceRouteError checks that the sources, sinks, webhooks and chats named by the route
+// parameters belong to namespace, and returns the not-found message for the first that doesn't
+func (am *AppManager) namespaceRouteError(c echo.Context, namespace string) string {
+	route := apiRoute(c)
+	for _, name := range c.ParamNames() {
+		value := c.Param(name)
+		switch {
+		case name == "source_id", name == "id" && strings.HasPrefix(route, "/sources/"):
+			if source, err := am.storage.GetSource(value); err != nil || source.Namespace != namespace {
+				return "Source not found"
+			}
+		case name == "webhook_id", name == "id" && strings.HasPrefix(route, "/webhooks/"):
+			if webhook, err := am.storage.GetWebhook(value); err != nil || webhook.Namespace != namespace {
+				return "Webhook not found"
+			}
+		case name == "sink_id":
+			if sink, err := am.storage.GetSink(value); err != nil || sink.Namespace != namespace {
+				return "Sink not found"
+			}
+		case name == "chat_id":
+			chatID, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return "Telegram chat not found"
+			}
+			if chat, err := am.storage.GetChat(chatID); err != nil || chat.Namespace != namespace {
+				return "Telegram chat not found"
+			}
+		}
+	}
+	return ""
+}
+
+// namespaceAllows reports whether the request's key may see a resource in namespace
+func namespaceAllows(c echo.Context, namespace string) bool {
+	own := authNamespace(c)
+	return own == "" || own == namespace
+}
+
+// namespaceFilter returns the namespace list endpoints are limited to: the key's own, or
+// ?namespace= for unscoped keys. ok is false when every namespace is listed.
+func namespaceFilter(c echo.Context) (namespace string, ok bool) {
+	if own := authNamespace(c); own != "" {
+		return own, true
+	}
+	if requested := c.QueryParam("namespace"); requested != "" {
+		return strings.ToLower(strings.TrimSpace(requested)), true
+	}
+	return "", false
+}
+
+// resolveNamespace returns the namespace a created or moved resource belongs to: always the
+// key's own for namespaced keys, otherwise the requested one, which must exist ("" = global)
+func (am *AppManager) resolveNamespace(c echo.Context, requested string) (string, error) {
+	requested = strings.ToLower(strings.TrimSpace(requested))
+	if own := authNamespace(c); own != "" {
+		if requested != "" && requested != own {
+			return "", fmt.Errorf("API key is limited to namespace %s", own)
+		}
+		return own, nil
+	}
+	if requested == "" {
+		return "", nil
+	}
+	if _, err := am.storage.GetNamespace(requested); err != nil {
+		return "", fmt.Errorf("Unknown namespace: %s", requested)
+	}
+	return requested, nil
+}
+
+// handleGetNamespaces lists namespaces with the number of resources in each
+func (am *AppManager) handleGetNamespaces(c echo.Context) error {
+	namespaces, err := am.storage.ListNamespaces()
+	if err != nil {
+		am.log(c).Errorf("Failed to list namespaces: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to list namespaces")
+	}
+
+	resp := make([]NamespaceResponse, 0, len(namespaces))
+	for _, ns := range namespaces {
+		usage, err := am.storage.GetNamespaceUsage(ns.Name)
+		if err != nil {
+			am.log(c).Errorf("Failed to count resources of namespace %s: %v", ns.Name, err)
+			return errorJSON(c, http.StatusInternalServerError, "Failed to list namespaces")
+		}
+		resp = append(resp, NamespaceResponse{Namespace: ns, Usage: usage})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// handleCreateNamespace creates a namespace
+func (am *AppManager) handleCreateNamespace(c echo.Context) error {
+	var req NamespaceRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+	name, err := storage.NormalizeNamespace(req.Name)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	if _, err := am.storage.GetNamespace(name); err == nil {
+		return errorJSON(c, http.StatusConflict, "A namespace with this name already exists")
+	}
+
+	ns := &storage.Namespace{Name: name, Description: strings.TrimSpace(req.Description)}
+	if err := am.storage.SaveNamespace(ns); err != nil {
+		am.log(c).Errorf("Failed to create namespace: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to create namespace")
+	}
+
+	am.log(c).Printf("Namespace %s created by %s", ns.Name, authKeyName(c))
+	return c.JSON(http.StatusCreated, ns)
+}
+
+// handleUpdateNamespace changes a namespace's description; namespaces can't be renamed
+func (am *AppManager) handleUpdateNamespace(c echo.Context) error {
+	ns, err := am.storage.GetNamespace(c.Param("name"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Namespace not found")
+	}
+	var req NamespaceRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+	if req.Name != "" && !strings.EqualFold(req.Name, ns.Name) {
+		return errorJSON(c, http.StatusBadRequest, "Namespaces can't be renamed")
+	}
+
+	ns.Description = strings.TrimSpace(req.Description)
+	if err := am.storage.SaveNamespace(ns); err != nil {
+		am.log(c).Errorf("Failed to update namespace: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to update namespace")
+	}
+	return c.JSON(http.StatusOK, ns)
+}
+
+// handleDeleteNamespace deletes a namespace that no longer owns anything
+func (am *AppManager) handleDeleteNamespace(c echo.Context) error {
+	name := c.Param("name")
+	err := am.storage.DeleteNamespace(name)
+	if errors.Is(err, storage.ErrNamespaceInUse) {
+		return errorJSON(c, http.StatusConflict, "Namespace still has sources, webhooks, chats or API keys; move or delete them first")
+	}
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Namespace not found")
+	}
+
+	am.log(c).Printf("Namespace %s deleted by %s", name, authKeyName(c))
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Namespace deleted",
+		"name":    name,
+	})
+}
@@ -0,0 +1,97 @@
+package appmanager
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// AddIncidentNoteRequest is the request body for adding a note to an outage
+type AddIncidentNoteRequest struct {
+	Text string     `json:"text"`
+	At   *time.Time `json:"at,omitempty"` // A time during or after the outage; default now, i.e. the latest outage
+}
+
+// handleGetIncidentNotes lists the notes on a source's outages that started between ?from=
+// and ?to= (default all), oldest outage first
+func (am *AppManager) handleGetIncidentNotes(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	var from, to time.Time
+	if value := c.QueryParam("from"); value != "" {
+		if from, err = parseTimeParam(value); err != nil {
+			return errorJSON(c, http.StatusBadRequest, "Invalid from: "+err.Error())
+		}
+	}
+	if value := c.QueryParam("to"); value != "" {
+		if to, err = parseTimeParam(value); err != nil {
+			return errorJSON(c, http.StatusBadRequest, "Invalid to: "+err.Error())
+		}
+	}
+
+	notes, err := am.storage.GetIncidentNotes(source.ID, from, to)
+	if err != nil {
+		am.log(c).Errorf("Failed to get incident notes of %s: %v", source.ID, err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get incident notes")
+	}
+	return c.JSON(http.StatusOK, notes)
+}
+
+// handleAddIncidentNote attaches a note to the outage of a source in progress at the given
+// time, or the last one before it
+func (am *AppManager) handleAddIncidentNote(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	var req AddIncidentNoteRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+	at := time.Now()
+	if req.At != nil {
+		at = *req.At
+	}
+
+	outage, err := am.storage.FindOutage(source.ID, at)
+	if errors.Is(err, storage.ErrNoOutage) {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		am.log(c).Errorf("Failed to find outage of %s: %v", source.ID, err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to add incident note")
+	}
+
+	note := &storage.IncidentNote{Text: req.Text, Author: authKeyName(c)}
+	if err := am.storage.AddIncidentNote(outage, note); err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	am.log(c).Printf("Incident note added to %s (outage of %s) by %s", source.Name, outage.Timestamp.Format(time.RFC3339), note.Author)
+	return c.JSON(http.StatusCreated, note)
+}
+
+// handleDeleteIncidentNote removes a note from a source's outage
+func (am *AppManager) handleDeleteIncidentNote(c echo.Context) error {
+	sourceID := c.Param("id")
+	noteID := c.Param("note_id")
+	if _, err := am.getActiveSource(sourceID); err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+	if err := am.storage.DeleteIncidentNote(sourceID, noteID); err != nil {
+		return errorJSON(c, http.StatusNotFound, "Incident note not found")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Incident note deleted",
+		"id":      noteID,
+	})
+}
@@ -0,0 +1,372 @@
+package appmanager
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/storage"
+)
+
+const (
+	// jwksMaxAge forces a periodic JWKS refresh so rotated keys are picked up
+	jwksMaxAge = time.Hour
+	// jwksMinRefresh rate-limits refreshes triggered by tokens with an unknown key ID
+	jwksMinRefresh = time.Minute
+	// jwtClockSkew is the leeway allowed on exp and nbf
+	jwtClockSkew = time.Minute
+)
+
+// oidcVerifier validates bearer JWTs issued by an OIDC provider and maps their roles to API scopes
+type oidcVerifier struct {
+	issuer     string
+	audience   string
+	rolesClaim []string          // Dot path split into segments
+	roleScopes map[string]string // Role claim value → API key scope
+	client     *http.Client
+
+	mu          sync.Mutex
+	jwksURI     string
+	keys        map[string]crypto.PublicKey // By key ID
+	fetchedAt   time.Time                   // Last successful refresh
+	attemptedAt time.Time                   // Last refresh attempt, successful or not
+}
+
+// jwtClaims are the registered claims checked on every token; the rest are kept raw for role lookup
+type jwtClaims struct {
+	Issuer            string          `json:"iss"`
+	Subject           string          `json:"sub"`
+	Audience          json.RawMessage `json:"aud"`
+	ExpiresAt         int64           `json:"exp"`
+	NotBefore         int64           `json:"nbf"`
+	PreferredUsername string          `json:"preferred_username"`
+	Email             string          `json:"email"`
+}
+
+// newOIDCVerifier returns nil when OIDC_ISSUER_URL is unset. The provider is not contacted
+// until the first token arrives, so an unreachable IdP does not block startup.
+func newOIDCVerifier(cfg *config.Config) (*oidcVerifier, error) {
+	if cfg.OIDCIssuerURL == "" {
+		return nil, nil
+	}
+
+	roleScopes := make(map[string]string)
+	for _, pair := range strings.Split(cfg.OIDCRoleMapping, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		role, scope, ok := strings.Cut(pair, "=")
+		role, scope = strings.TrimSpace(role), strings.TrimSpace(scope)
+		if !ok || role == "" || !storage.ValidScope(scope) {
+			return nil, fmt.Errorf("invalid OIDC_ROLE_MAPPING entry %q (use role=read|operator|write|admin)", pair)
+		}
+		roleScopes[role] = scope
+	}
+	if len(roleScopes) == 0 {
+		return nil, fmt.Errorf("OIDC_ROLE_MAPPING is required when OIDC_ISSUER_URL is set")
+	}
+	// Without an audience check, any token the issuer minted for another client would be accepted
+	if cfg.OIDCAudience == "" {
+		return nil, fmt.Errorf("OIDC_AUDIENCE is required when OIDC_ISSUER_URL is set")
+	}
+
+	rolesClaim := cfg.OIDCRolesClaim
+	if rolesClaim == "" {
+		rolesClaim = config.DefaultOIDCRolesClaim
+	}
+
+	return &oidcVerifier{
+		issuer:     strings.TrimSuffix(cfg.OIDCIssuerURL, "/"),
+		audience:   cfg.OIDCAudience,
+		rolesClaim: strings.Split(rolesClaim, "."),
+		roleScopes: roleScopes,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// verify checks the token's signature and claims and returns the caller's name and the
+// highest scope granted by its roles ("" when no role is mapped)
+func (v *oidcVerifier) verify(token string) (string, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return "", "", fmt.Errorf("malformed token header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", "", fmt.Errorf("malformed token signature")
+	}
+
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return "", "", err
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return "", "", err
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return "", "", fmt.Errorf("malformed token claims")
+	}
+	var raw map[string]interface{}
+	if err := decodeJWTSegment(parts[1], &raw); err != nil {
+		return "", "", fmt.Errorf("malformed token claims")
+	}
+
+	now := time.Now()
+	if strings.TrimSuffix(claims.Issuer, "/") != v.issuer {
+		return "", "", fmt.Errorf("token issuer mismatch")
+	}
+	if !audienceContains(claims.Audience, v.audience) {
+		return "", "", fmt.Errorf("token audience mismatch")
+	}
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(jwtClockSkew)) {
+		return "", "", fmt.Errorf("token has expired")
+	}
+	if claims.NotBefore != 0 && now.Add(jwtClockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return "", "", fmt.Errorf("token is not valid yet")
+	}
+
+	name := claims.PreferredUsername
+	if name == "" {
+		name = claims.Email
+	}
+	if name == "" {
+		name = claims.Subject
+	}
+
+	scope := ""
+	for _, role := range v.roles(raw) {
+		if granted, ok := v.roleScopes[role]; ok && !storage.ScopeAllows(scope, granted) {
+			scope = granted
+		}
+	}
+	return "jwt:" + name, scope, nil
+}
+
+// roles reads the configured roles claim; both arrays and space-separated strings are accepted
+func (v *oidcVerifier) roles(claims map[string]interface{}) []string {
+	var value interface{} = claims
+	for _, segment := range v.rolesClaim {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = obj[segment]
+	}
+
+	switch roles := value.(type) {
+	case string:
+		return strings.Fields(roles)
+	case []interface{}:
+		var out []string
+		for _, role := range roles {
+			if s, ok := role.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// key returns the signing key for kid, refreshing the JWKS when it is stale or the kid is unknown
+func (v *oidcVerifier) key(kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	key, ok := v.keys[kid]
+	if ok && now.Sub(v.fetchedAt) <= jwksMaxAge {
+		return key, nil
+	}
+	// Failed refreshes count too, so an unreachable provider is not hit on every request
+	if now.Sub(v.attemptedAt) < jwksMinRefresh {
+		if ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown token signing key")
+	}
+
+	v.attemptedAt = now
+	if err := v.refreshKeys(); err != nil {
+		if ok {
+			return key, nil // Keep using the cached key while the provider is unreachable
+		}
+		return nil, fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+	v.fetchedAt = now
+
+	if key, ok = v.keys[kid]; !ok {
+		return nil, fmt.Errorf("unknown token signing key")
+	}
+	return key, nil
+}
+
+// refreshKeys fetches the discovery document (once) and the JWKS it points to
+func (v *oidcVerifier) refreshKeys() error {
+	if v.jwksURI == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(v.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+			return err
+		}
+		if discovery.JWKSURI == "" {
+			return fmt.Errorf("discovery document has no jwks_uri")
+		}
+		v.jwksURI = discovery.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(v.jwksURI, &jwks); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch jwk.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	v.keys = keys
+	return nil
+}
+
+// getJSON fetches url and decodes the JSON response into out
+func (v *oidcVerifier) getJSON(url string, out interface{}) error {
+	resp, err := v.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// verifyJWTSignature checks a JWS signature; only asymmetric algorithms are accepted
+func verifyJWTSignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	var h hash.Hash
+	var hashID crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		h, hashID = sha256.New(), crypto.SHA256
+	case "RS384", "ES384":
+		h, hashID = sha512.New384(), crypto.SHA384
+	case "RS512":
+		h, hashID = sha512.New(), crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") || rsa.VerifyPKCS1v15(k, hashID, digest, signature) != nil {
+			return fmt.Errorf("invalid token signature")
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+			return fmt.Errorf("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return fmt.Errorf("invalid token signature")
+		}
+	default:
+		return fmt.Errorf("invalid token signature")
+	}
+	return nil
+}
+
+// decodeJWTSegment base64url-decodes a token segment and unmarshals its JSON
+func decodeJWTSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// audienceContains reports whether aud (a string or an array of strings) includes audience
+func audienceContains(aud json.RawMessage, audience string) bool {
+	var single string
+	if json.Unmarshal(aud, &single) == nil {
+		return single == audience
+	}
+	var many []string
+	if json.Unmarshal(aud, &many) == nil {
+		for _, a := range many {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,575 @@
+package appmanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/monitor"
+	"tg-monitor-bot/internal/storage"
+)
+
+// apiParam describes a query parameter of an API operation
+type apiParam struct {
+	Name        string
+	Type        string // OpenAPI primitive type: string, integer, boolean
+	Description string
+}
+
+// apiOperation documents one route for the OpenAPI spec.
+// Body and Response hold a zero value of the Go type that is bound or returned;
+// nil Response means a generic JSON object ({"message": ...} style).
+type apiOperation struct {
+	Method      string
+	Path        string // Echo path, e.g. /sources/:id; non-public routes are served under /api/v1
+	Tag         string
+	Summary     string
+	Query       []apiParam
+	Body        interface{}
+	Response    interface{}
+	Status      int    // Success status code (default 200)
+	Public      bool   // No X-API-Key required
+	ContentType string // Response content type when not JSON (e.g. text/html)
+}
+
+// apiOperations lists every AppManager route. TestOpenAPICoversAllRoutes keeps it in sync with setupRoutes.
+var apiOperations = []apiOperation{
+	// Docs
+	{Method: http.MethodGet, Path: "/openapi.json", Tag: "docs", Summary: "OpenAPI 3 document for this API", Public: true},
+	{Method: http.MethodGet, Path: "/docs", Tag: "docs", Summary: "Swagger UI", Public: true, ContentType: "text/html"},
+
+	// Embedded dashboard
+	{Method: http.MethodGet, Path: "/ui", Tag: "docs", Summary: "Redirect to the embedded admin dashboard", Public: true, Status: http.StatusMovedPermanently, ContentType: "text/html"},
+	{Method: http.MethodGet, Path: "/ui/*", Tag: "docs", Summary: "Embedded admin dashboard (static files)", Public: true, ContentType: "text/html"},
+
+	// Badges
+	{Method: http.MethodGet, Path: "/badge/:source_id", Tag: "statuspage", Summary: "SVG status badge with uptime", Public: true, ContentType: "image/svg+xml", Query: []apiParam{
+		{Name: "label", Type: "string", Description: "Left-hand text (default \"status\")"},
+		{Name: "period", Type: "string", Description: "Uptime period, e.g. 30d (default 30d)"},
+	}},
+
+	// Public status page
+	{Method: http.MethodGet, Path: "/statuspage", Tag: "statuspage", Summary: "Public status page of sources marked public (404 unless STATUS_PAGE_ENABLED)", Public: true, ContentType: "text/html"},
+	{Method: http.MethodGet, Path: "/statuspage.json", Tag: "statuspage", Summary: "Public status page as JSON", Public: true, Response: StatusPage{}},
+
+	// Incoming webhook heartbeats
+	{Method: http.MethodGet, Path: "/webhooks/incoming/:token", Tag: "heartbeats", Summary: "Record a heartbeat for a webhook source", Public: true},
+	{Method: http.MethodPost, Path: "/webhooks/incoming/:token", Tag: "heartbeats", Summary: "Record a heartbeat for a webhook source (body checked against expected_content)", Public: true},
+
+	// Config
+	{Method: http.MethodGet, Path: "/config", Tag: "config", Summary: "List all config values (secrets masked)", Response: map[string]string{}},
+	{Method: http.MethodGet, Path: "/config/:key", Tag: "config", Summary: "Get a config entry"},
+	{Method: http.MethodPut, Path: "/config/:key", Tag: "config", Summary: "Update a config entry and reload the affected parts of the bot", Body: UpdateConfigRequest{}},
+	{Method: http.MethodPost, Path: "/config/validate", Tag: "config", Summary: "Check a full or partial config map (merged over the current config) without applying it", Body: map[string]string{}, Response: ConfigValidationResponse{}},
+	{Method: http.MethodPost, Path: "/config/reload", Tag: "config", Summary: "Restart the bot with the current config"},
+	{Method: http.MethodGet, Path: "/features", Tag: "config", Summary: "List feature flags of experimental subsystems with their current state", Response: []FeatureFlagResponse{}},
+	{Method: http.MethodPut, Path: "/features/:name", Tag: "config", Summary: "Switch a feature flag on or off (stored as config key FEATURE_<NAME>)", Body: UpdateFeatureFlagRequest{}, Response: FeatureFlagResponse{}},
+
+	// Admin
+	{Method: http.MethodPost, Path: "/admin/compact", Tag: "admin", Summary: "Compact the database file", Response: storage.CompactResult{}},
+	{Method: http.MethodPost, Path: "/admin/selftest", Tag: "admin", Summary: "Check database writability, Telegram token, outbound HTTP, ICMP capability and webhook reachability", Response: SelfTestReport{}},
+
+	// Debug
+	{Method: http.MethodGet, Path: "/debug/runtime", Tag: "admin", Summary: "Goroutine count, heap, GC and bbolt statistics", Response: RuntimeDebug{}},
+	{Method: http.MethodGet, Path: "/debug/pprof/*", Tag: "admin", Summary: "net/http/pprof profiles, e.g. heap, goroutine?debug=1 or profile?seconds=30 (404 unless PPROF_ENABLED)", ContentType: "application/octet-stream"},
+	{Method: http.MethodPost, Path: "/debug/pprof/*", Tag: "admin", Summary: "pprof symbol lookup (404 unless PPROF_ENABLED)", ContentType: "text/plain"},
+
+	// Status
+	{Method: http.MethodGet, Path: "/health", Tag: "status", Summary: "Health check", Public: true},
+	{Method: http.MethodGet, Path: "/livez", Tag: "status", Summary: "Liveness probe: the process is up", Public: true, Response: ProbeResponse{}},
+	{Method: http.MethodGet, Path: "/readyz", Tag: "status", Summary: "Readiness probe: storage, monitor and Telegram (503 when not ready)", Public: true, Response: ProbeResponse{}},
+	{Method: http.MethodGet, Path: "/status", Tag: "status", Summary: "Detailed bot, API and system status"},
+	{Method: http.MethodGet, Path: "/system/events", Tag: "status", Summary: "Application history (startups, shutdowns, bot restarts, config changes, panics), newest first", Response: []*storage.SystemEvent{}, Query: []apiParam{
+		{Name: "type", Type: "string", Description: "startup, shutdown, bot_start, bot_stop, bot_restart, config_change or panic"},
+		{Name: "from", Type: "string", Description: "Only events at or after this time (RFC3339 or YYYY-MM-DD)"},
+		{Name: "to", Type: "string", Description: "Only events before this time (RFC3339 or YYYY-MM-DD)"},
+		{Name: "limit", Type: "integer", Description: "Maximum results (default 100, max 1000)"},
+	}},
+
+	// Remote probe agents
+	{Method: http.MethodGet, Path: "/agents", Tag: "agents", Summary: "Probe agents seen since startup, by location", Response: []AgentInfo{}},
+	{Method: http.MethodGet, Path: "/agents/sources", Tag: "agents", Summary: "Enabled ping and http sources assigned to a location, for its agent to check", Response: []AgentSource{}, Query: []apiParam{
+		{Name: "location", Type: "string", Description: "Location of the agent, e.g. eu-west (required)"},
+		{Name: "version", Type: "string", Description: "Agent version, shown in GET /agents"},
+	}},
+	{Method: http.MethodPost, Path: "/agents/results", Tag: "agents", Summary: "Report check results from a location; status changes are sent to the source's chats", Body: AgentReport{}, Response: AgentReportResponse{}},
+
+	// Sources
+	{Method: http.MethodGet, Path: "/sources", Tag: "sources", Summary: "List monitored sources (total matches in X-Total-Count)", Response: []*storage.Source{}, Query: []apiParam{
+		{Name: "namespace", Type: "string", Description: "Unscoped keys: only this namespace (namespaced keys always see their own)"},
+		{Name: "tag", Type: "string", Description: "Only sources with this tag (repeat to require several)"},
+		{Name: "type", Type: "string", Description: "ping, http or webhook"},
+		{Name: "enabled", Type: "boolean", Description: "false lists paused sources"},
+		{Name: "status", Type: "string", Description: "online, offline or unknown"},
+		{Name: "sort", Type: "string", Description: "name (default), status, last_change, last_check or created"},
+		{Name: "order", Type: "string", Description: "asc (default) or desc"},
+		{Name: "limit", Type: "integer", Description: "Page size, 1-1000 (default: all)"},
+		{Name: "offset", Type: "integer", Description: "Matches to skip"},
+	}},
+	{Method: http.MethodPost, Path: "/sources", Tag: "sources", Summary: "Create a source", Body: CreateSourceRequest{}, Response: storage.Source{}, Status: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/sources/bulk", Tag: "sources", Summary: "Create, update and delete many sources in one transaction", Body: BulkSourceRequest{}, Response: BulkSourceResponse{}},
+	{Method: http.MethodGet, Path: "/templates", Tag: "templates", Summary: "List source templates by name", Response: []storage.SourceTemplate{}},
+	{Method: http.MethodPost, Path: "/templates", Tag: "templates", Summary: "Create a source template; POST /sources with \"template\" then only needs a name and target", Body: SourceTemplateRequest{}, Response: storage.SourceTemplate{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/templates/:id", Tag: "templates", Summary: "Get a source template by ID or name", Response: storage.SourceTemplate{}},
+	{Method: http.MethodPut, Path: "/templates/:id", Tag: "templates", Summary: "Replace a source template; sources created from it are unchanged", Body: SourceTemplateRequest{}, Response: storage.SourceTemplate{}},
+	{Method: http.MethodDelete, Path: "/templates/:id", Tag: "templates", Summary: "Delete a source template; sources created from it are kept"},
+	{Method: http.MethodGet, Path: "/discovery", Tag: "discovery", Summary: "List hosts found by network discovery with the last scan", Response: DiscoveryResponse{}, Query: []apiParam{
+		{Name: "new", Type: "boolean", Description: "Only hosts no source monitors yet"},
+	}},
+	{Method: http.MethodPost, Path: "/discovery/scan", Tag: "discovery", Summary: "Start a discovery scan of CIDR ranges (ping, ARP and TCP ports) in the background", Body: DiscoveryScanRequest{}, Response: monitor.DiscoveryScan{}, Status: http.StatusAccepted},
+	{Method: http.MethodPost, Path: "/discovery/promote", Tag: "discovery", Summary: "Create a source for each discovered host", Body: PromoteHostsRequest{}, Response: BulkSourceResponse{}},
+	{Method: http.MethodDelete, Path: "/discovery/hosts/:ip", Tag: "discovery", Summary: "Forget a discovered host until a scan finds it again"},
+	{Method: http.MethodPost, Path: "/import", Tag: "sources", Summary: "Create sources, chats and webhooks from an Uptime Kuma backup or a CSV file in the body", Response: ExternalImportResult{}, Query: []apiParam{
+		{Name: "format", Type: "string", Description: "uptime-kuma (backup JSON) or csv (columns name, type, target, check_interval, tags, telegram_chat_ids, webhook_urls, enabled)"},
+		{Name: "dry_run", Type: "boolean", Description: "Report what would be created without writing"},
+	}},
+	{Method: http.MethodGet, Path: "/tags", Tag: "sources", Summary: "List tags in use with source counts", Response: []TagSummary{}},
+	{Method: http.MethodPost, Path: "/tags/:tag/pause", Tag: "sources", Summary: "Pause every source with the tag", Response: TagActionResponse{}},
+	{Method: http.MethodPost, Path: "/tags/:tag/resume", Tag: "sources", Summary: "Resume every source with the tag", Response: TagActionResponse{}},
+	{Method: http.MethodGet, Path: "/sources/deleted", Tag: "sources", Summary: "List sources in trash", Response: []*storage.Source{}, Query: []apiParam{{Name: "namespace", Type: "string", Description: "Unscoped keys: only this namespace"}}},
+	{Method: http.MethodGet, Path: "/sources/:id", Tag: "sources", Summary: "Get a source with its chats and webhooks", Response: SourceDetailResponse{}, Query: []apiParam{
+		{Name: "include", Type: "string", Description: "history: add the 50 most recent status changes"},
+	}},
+	{Method: http.MethodPut, Path: "/sources/:id", Tag: "sources", Summary: "Update a source", Body: UpdateSourceRequest{}, Response: storage.Source{}},
+	{Method: http.MethodDelete, Path: "/sources/:id", Tag: "sources", Summary: "Move a source to trash, or delete it permanently", Query: []apiParam{
+		{Name: "purge", Type: "boolean", Description: "Permanently delete the source with its history and associations"},
+	}},
+	{Method: http.MethodPost, Path: "/sources/:id/check", Tag: "sources", Summary: "Run an immediate check and return status, latency and error", Response: CheckSourceResponse{}},
+	{Method: http.MethodGet, Path: "/sources/:id/durations", Tag: "sources", Summary: "p50/p95 of the last 100 check durations and how many came close to the interval", Response: monitor.CheckDurations{}},
+	{Method: http.MethodGet, Path: "/sources/:id/locations", Tag: "sources", Summary: "Status from the central instance and each probe location, with a summary like \"down from eu; up from local, us\"", Response: SourceLocationsResponse{}},
+	{Method: http.MethodPost, Path: "/sources/:id/pause", Tag: "sources", Summary: "Pause monitoring"},
+	{Method: http.MethodPost, Path: "/sources/:id/resume", Tag: "sources", Summary: "Resume monitoring"},
+	{Method: http.MethodPost, Path: "/sources/:id/restore", Tag: "sources", Summary: "Restore a source from trash", Response: storage.Source{}},
+	{Method: http.MethodPost, Path: "/sources/:id/clone", Tag: "sources", Summary: "Create a source with the configuration, chats and webhooks of this one under a new name and target", Body: CloneSourceRequest{}, Response: SourceDetailResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/sources/:id/webhook-token/rotate", Tag: "sources", Summary: "Replace the incoming webhook token of a webhook source; the old URL stops working", Response: storage.Source{}},
+	{Method: http.MethodGet, Path: "/sources/:id/webhook-requests", Tag: "sources", Summary: "Recent incoming requests to a webhook source with their validation outcome, newest first", Response: []*IncomingWebhookRequest{}, Query: []apiParam{
+		{Name: "limit", Type: "integer", Description: "Maximum number of requests (1-50, default 20)"},
+	}},
+	{Method: http.MethodGet, Path: "/sources/:id/rollups", Tag: "sources", Summary: "Daily uptime aggregates, oldest first", Response: []*storage.DailyRollup{}, Query: []apiParam{
+		{Name: "days", Type: "integer", Description: "Number of days to return (default 90, max 366)"},
+	}},
+	{Method: http.MethodGet, Path: "/sources/:id/heatmap", Tag: "sources", Summary: "Uptime and level per UTC day for a calendar heatmap, oldest first", Response: SourceHeatmapResponse{}, Query: []apiParam{
+		{Name: "days", Type: "integer", Description: "Days including today (default 90, max 366)"},
+	}},
+	{Method: http.MethodGet, Path: "/sources/:id/history", Tag: "sources", Summary: "Status changes, outage windows and totals over a range", Response: SourceHistoryResponse{}, Query: []apiParam{
+		{Name: "from", Type: "string", Description: "Inclusive start (RFC3339 or YYYY-MM-DD, default 7 days before to)"},
+		{Name: "to", Type: "string", Description: "Exclusive end (RFC3339 or YYYY-MM-DD, default now)"},
+	}},
+	{Method: http.MethodGet, Path: "/sources/:id/timeline", Tag: "sources", Summary: "Contiguous up/down/unknown/maintenance segments over a range, for a status bar", Response: SourceTimelineResponse{}, Query: []apiParam{
+		{Name: "from", Type: "string", Description: "Inclusive start (RFC3339 or YYYY-MM-DD, default 24 hours before to)"},
+		{Name: "to", Type: "string", Description: "Exclusive end (RFC3339 or YYYY-MM-DD, default now; clipped to now)"},
+	}},
+	{Method: http.MethodGet, Path: "/sources/:id/notes", Tag: "sources", Summary: "Notes on the source's outages, oldest outage first", Response: []storage.IncidentNote{}, Query: []apiParam{
+		{Name: "from", Type: "string", Description: "Only outages that started at or after (RFC3339 or YYYY-MM-DD)"},
+		{Name: "to", Type: "string", Description: "Only outages that started before (RFC3339 or YYYY-MM-DD)"},
+	}},
+	{Method: http.MethodPost, Path: "/sources/:id/notes", Tag: "sources", Summary: "Add a note to the outage in progress at `at` (default now) or the last one before it", Body: AddIncidentNoteRequest{}, Response: storage.IncidentNote{}, Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/sources/:id/notes/:note_id", Tag: "sources", Summary: "Delete an incident note"},
+	{Method: http.MethodGet, Path: "/sources/:id/planned-outages", Tag: "sources", Summary: "Outages of the source marked as planned, oldest first", Response: []storage.PlannedOutage{}, Query: []apiParam{
+		{Name: "from", Type: "string", Description: "Only outages that started at or after (RFC3339 or YYYY-MM-DD)"},
+		{Name: "to", Type: "string", Description: "Only outages that started before (RFC3339 or YYYY-MM-DD)"},
+	}},
+	{Method: http.MethodPost, Path: "/sources/:id/planned-outages", Tag: "sources", Summary: "Mark the outage in progress at `at` (default now) or the last one before it as planned, leaving it out of SLA-adjusted uptime", Body: MarkOutagePlannedRequest{}, Response: storage.PlannedOutage{}, Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/sources/:id/planned-outages/:change_id", Tag: "sources", Summary: "Count an outage as unplanned again"},
+	{Method: http.MethodGet, Path: "/sources/:id/postmortem", Tag: "sources", Summary: "Markdown postmortem skeleton for an outage: timeline, duration, other affected sources, notes and notifications", ContentType: "text/markdown", Query: []apiParam{
+		{Name: "at", Type: "string", Description: "A time during or after the outage (RFC3339 or YYYY-MM-DD, default now, i.e. the latest outage)"},
+		{Name: "format", Type: "string", Description: "markdown (default) or json for the Postmortem object"},
+	}},
+	{Method: http.MethodGet, Path: "/sources/:id/history.csv", Tag: "sources", Summary: "Status changes over a range as CSV, oldest first", ContentType: "text/csv", Query: []apiParam{
+		{Name: "from", Type: "string", Description: "Inclusive start (RFC3339 or YYYY-MM-DD, default 30 days before to)"},
+		{Name: "to", Type: "string", Description: "Exclusive end (RFC3339 or YYYY-MM-DD, default now)"},
+	}},
+	{Method: http.MethodGet, Path: "/sources/:id/metrics.csv", Tag: "sources", Summary: "Daily uptime, downtime and outage counts over a range as CSV", ContentType: "text/csv", Query: []apiParam{
+		{Name: "from", Type: "string", Description: "Start; the whole UTC day is included (RFC3339 or YYYY-MM-DD, default 30 days before to)"},
+		{Name: "to", Type: "string", Description: "Exclusive end (RFC3339 or YYYY-MM-DD, default now)"},
+	}},
+	{Method: http.MethodGet, Path: "/sources/:id/uptime", Tag: "sources", Summary: "SLA statistics: raw and SLA-adjusted uptime %, planned downtime, outages, MTTR, MTBF, longest outage", Response: storage.UptimeStats{}, Query: []apiParam{
+		{Name: "period", Type: "string", Description: "Look-back period, e.g. 30d, 7d or 12h (default 30d, max 366d)"},
+	}},
+	{Method: http.MethodGet, Path: "/sources/:id/health-score", Tag: "sources", Summary: "0-100 health score blending uptime, latency trend and flap count", Response: monitor.HealthScore{}, Query: []apiParam{
+		{Name: "window", Type: "string", Description: "Look-back window, e.g. 24h or 7d (default 24h, max 366d)"},
+	}},
+	{Method: http.MethodGet, Path: "/health-scores", Tag: "sources", Summary: "Health scores of every source, lowest first", Response: []monitor.HealthScore{}, Query: []apiParam{
+		{Name: "window", Type: "string", Description: "Look-back window, e.g. 24h or 7d (default 24h, max 366d)"},
+		{Name: "tag", Type: "string", Description: "Only sources with this tag"},
+		{Name: "namespace", Type: "string", Description: "Unscoped keys: only this namespace"},
+	}},
+	{Method: http.MethodGet, Path: "/uptime", Tag: "sources", Summary: "Uptime %, outage count and downtime of every source over a period, by name", Response: storage.UptimeSummary{}, Query: []apiParam{
+		{Name: "period", Type: "string", Description: "Look-back period, e.g. 7d, 30d or 12h (default 7d, max 366d)"},
+		{Name: "tag", Type: "string", Description: "Only sources with this tag"},
+	}},
+	{Method: http.MethodGet, Path: "/reliability", Tag: "sources", Summary: "MTTR and MTBF per source, per tag and overall over look-back windows", Response: ReliabilityReport{}, Query: []apiParam{
+		{Name: "windows", Type: "string", Description: "Comma-separated periods, e.g. 7d,30d,90d (the default; up to 6, each max 366d)"},
+		{Name: "tag", Type: "string", Description: "Only sources with this tag"},
+	}},
+	{Method: http.MethodGet, Path: "/sources/:id/slo", Tag: "sources", Summary: "Error budget and burn rates of the source's SLO (404 without one)", Response: storage.ErrorBudget{}},
+	{Method: http.MethodGet, Path: "/slo", Tag: "sources", Summary: "Error budgets of all sources with an SLO, least remaining first", Response: []*storage.ErrorBudget{}},
+	{Method: http.MethodGet, Path: "/sources/:source_id/webhooks", Tag: "sources", Summary: "List webhooks attached to a source", Response: []*storage.Webhook{}},
+	{Method: http.MethodPost, Path: "/sources/:source_id/webhooks/:webhook_id", Tag: "sources", Summary: "Attach a webhook to a source", Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/sources/:source_id/webhooks/:webhook_id", Tag: "sources", Summary: "Detach a webhook from a source"},
+	{Method: http.MethodGet, Path: "/sources/:source_id/telegram-chats", Tag: "sources", Summary: "List Telegram chats attached to a source", Response: []*storage.Chat{}},
+	{Method: http.MethodPost, Path: "/sources/:source_id/telegram-chats/:chat_id", Tag: "sources", Summary: "Attach a Telegram chat to a source", Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/sources/:source_id/telegram-chats/:chat_id", Tag: "sources", Summary: "Detach a Telegram chat from a source"},
+	{Method: http.MethodGet, Path: "/sources/:source_id/sinks", Tag: "sources", Summary: "List sinks of every type attached to a source", Response: []*storage.Sink{}},
+	{Method: http.MethodPost, Path: "/sources/:source_id/sinks/:sink_id", Tag: "sources", Summary: "Attach a sink (e.g. telegram:-100123 or webhook:<id>) to a source", Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/sources/:source_id/sinks/:sink_id", Tag: "sources", Summary: "Detach a sink from a source"},
+
+	// Webhooks
+	{Method: http.MethodGet, Path: "/webhooks", Tag: "webhooks", Summary: "List outgoing webhooks", Response: []*storage.Webhook{}, Query: []apiParam{{Name: "namespace", Type: "string", Description: "Unscoped keys: only this namespace"}}},
+	{Method: http.MethodPost, Path: "/webhooks", Tag: "webhooks", Summary: "Create an outgoing webhook", Body: CreateWebhookRequest{}, Response: storage.Webhook{}, Status: http.StatusCreated},
+	{Method: http.MethodPut, Path: "/webhooks/:id", Tag: "webhooks", Summary: "Update an outgoing webhook", Body: UpdateWebhookRequest{}, Response: storage.Webhook{}},
+	{Method: http.MethodDelete, Path: "/webhooks/:id", Tag: "webhooks", Summary: "Delete an outgoing webhook"},
+
+	{Method: http.MethodGet, Path: "/sinks", Tag: "sinks", Summary: "List notification sinks of every type", Response: []*storage.Sink{}, Query: []apiParam{
+		{Name: "type", Type: "string", Description: "Only sinks of this type: telegram or webhook"},
+		{Name: "namespace", Type: "string", Description: "Unscoped keys: only this namespace"},
+	}},
+	{Method: http.MethodPost, Path: "/sinks", Tag: "sinks", Summary: "Create a sink; the fields besides type are those of the type's own endpoint", Body: CreateSinkRequest{}, Response: storage.Sink{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/sinks/:sink_id", Tag: "sinks", Summary: "Get a sink with the sources it notifies", Response: SinkResponse{}},
+	{Method: http.MethodPut, Path: "/sinks/:sink_id", Tag: "sinks", Summary: "Update a sink", Body: UpdateSinkRequest{}, Response: storage.Sink{}},
+	{Method: http.MethodDelete, Path: "/sinks/:sink_id", Tag: "sinks", Summary: "Delete a sink and its source associations"},
+
+	// Events
+	{Method: http.MethodGet, Path: "/events", Tag: "events", Summary: "Status changes, newest first", Response: []StatusChangeEventResponse{}, Query: []apiParam{
+		{Name: "source_id", Type: "string", Description: "Only changes of this source"},
+		{Name: "tag", Type: "string", Description: "Only changes of sources with this tag (repeat to require several)"},
+		{Name: "from", Type: "string", Description: "Inclusive lower bound (RFC3339 or YYYY-MM-DD)"},
+		{Name: "to", Type: "string", Description: "Exclusive upper bound (RFC3339 or YYYY-MM-DD)"},
+		{Name: "limit", Type: "integer", Description: "Maximum results (default 100, max 1000)"},
+		{Name: "group_id", Type: "string", Description: "Every change of this alert group, ignoring the other filters (404 when unknown)"},
+	}},
+	{Method: http.MethodGet, Path: "/events/stream", Tag: "events", Summary: "Live status changes as Server-Sent Events (event: status_change, data: StatusChangeEventResponse; 404 when feature event_stream is off)", ContentType: "text/event-stream", Query: []apiParam{
+		{Name: "source_id", Type: "string", Description: "Only changes of this source"},
+		{Name: "api_key", Type: "string", Description: "API key, for clients such as EventSource that cannot set X-API-Key"},
+	}},
+	{Method: http.MethodGet, Path: "/deliveries", Tag: "events", Summary: "Notification delivery attempts, newest first", Response: []*storage.Delivery{}, Query: []apiParam{
+		{Name: "source_id", Type: "string", Description: "Only deliveries for this source"},
+		{Name: "sink_type", Type: "string", Description: "telegram or webhook"},
+		{Name: "status_change_id", Type: "string", Description: "Only deliveries for this status change"},
+		{Name: "success", Type: "boolean", Description: "Filter by delivery result"},
+		{Name: "limit", Type: "integer", Description: "Maximum results (default 100, max 1000)"},
+	}},
+
+	// Reports
+	{Method: http.MethodGet, Path: "/reports/:period", Tag: "reports", Summary: "Uptime, longest outages and MTTR trend over the last completed week or month (period: week or month)", Response: Report{}, Query: []apiParam{
+		{Name: "tag", Type: "string", Description: "Only sources with this tag"},
+		{Name: "offset", Type: "integer", Description: "Periods further back, 0-52 (default 0: the last completed one)"},
+		{Name: "format", Type: "string", Description: "json (default), markdown, html or text; markdown and text are sent as attachments"},
+	}},
+
+	// Maintenance windows
+	{Method: http.MethodGet, Path: "/maintenance", Tag: "maintenance", Summary: "List maintenance windows by start", Response: []MaintenanceWindowResponse{}, Query: []apiParam{
+		{Name: "active", Type: "boolean", Description: "Only windows active now"},
+		{Name: "source_id", Type: "string", Description: "Only windows covering this source"},
+	}},
+	{Method: http.MethodPost, Path: "/maintenance", Tag: "maintenance", Summary: "Create a maintenance window (starts now unless start is given)", Body: MaintenanceWindowRequest{}, Response: MaintenanceWindowResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/maintenance/:id", Tag: "maintenance", Summary: "Get a maintenance window", Response: MaintenanceWindowResponse{}},
+	{Method: http.MethodPut, Path: "/maintenance/:id", Tag: "maintenance", Summary: "Replace a maintenance window's name, scope and schedule", Body: MaintenanceWindowRequest{}, Response: MaintenanceWindowResponse{}},
+	{Method: http.MethodDelete, Path: "/maintenance/:id", Tag: "maintenance", Summary: "Delete a maintenance window"},
+	{Method: http.MethodPost, Path: "/maintenance/:id/close", Tag: "maintenance", Summary: "End an active one-off maintenance window now", Response: MaintenanceWindowResponse{}},
+
+	// Telegram chats
+	{Method: http.MethodGet, Path: "/telegram-chats", Tag: "telegram", Summary: "List registered Telegram chats", Response: []*storage.Chat{}, Query: []apiParam{{Name: "namespace", Type: "string", Description: "Unscoped keys: only this namespace"}}},
+	{Method: http.MethodPost, Path: "/telegram-chats", Tag: "telegram", Summary: "Register a Telegram chat", Body: AddTelegramChatRequest{}, Response: storage.Chat{}, Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/telegram-chats/:chat_id", Tag: "telegram", Summary: "Remove a Telegram chat and its source associations"},
+
+	// GraphQL
+	{Method: http.MethodGet, Path: "/graphql", Tag: "graphql", Summary: "Run a read-only GraphQL query from ?query= and ?variables= (404 unless GRAPHQL_ENABLED)", Response: GraphQLResponse{}, Query: []apiParam{
+		{Name: "query", Type: "string", Description: "GraphQL query document"},
+		{Name: "variables", Type: "string", Description: "JSON object of variables"},
+	}},
+	{Method: http.MethodPost, Path: "/graphql", Tag: "graphql", Summary: "Run a read-only GraphQL query (404 unless GRAPHQL_ENABLED)", Body: GraphQLRequest{}, Response: GraphQLResponse{}},
+
+	// Namespaces
+	{Method: http.MethodGet, Path: "/namespaces", Tag: "namespaces", Summary: "List namespaces with their resource counts (admin scope)", Response: []NamespaceResponse{}},
+	{Method: http.MethodPost, Path: "/namespaces", Tag: "namespaces", Summary: "Create a namespace", Body: NamespaceRequest{}, Response: storage.Namespace{}, Status: http.StatusCreated},
+	{Method: http.MethodPut, Path: "/namespaces/:name", Tag: "namespaces", Summary: "Update a namespace's description", Body: NamespaceRequest{}, Response: storage.Namespace{}},
+	{Method: http.MethodDelete, Path: "/namespaces/:name", Tag: "namespaces", Summary: "Delete a namespace that owns nothing (409 otherwise)"},
+
+	// API keys
+	{Method: http.MethodGet, Path: "/keys", Tag: "keys", Summary: "List named API keys (admin scope; secrets are never returned)", Response: []storage.APIKey{}},
+	{Method: http.MethodPost, Path: "/keys", Tag: "keys", Summary: "Create a named API key; the secret is only returned here", Body: CreateAPIKeyRequest{}, Response: CreateAPIKeyResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/keys/:id/revoke", Tag: "keys", Summary: "Revoke an API key", Response: storage.APIKey{}},
+	{Method: http.MethodDelete, Path: "/keys/:id", Tag: "keys", Summary: "Delete an API key"},
+
+	// Telegram users
+	{Method: http.MethodGet, Path: "/users", Tag: "users", Summary: "List the Telegram users in ALLOWED_USERS (admin scope; empty = everyone may use the bot)", Response: []AllowedUserResponse{}},
+	{Method: http.MethodGet, Path: "/users/seen", Tag: "users", Summary: "List the Telegram users who have messaged the bot, most recent first", Response: []SeenUserResponse{}},
+	{Method: http.MethodPost, Path: "/users", Tag: "users", Summary: "Allow a Telegram user by ID or @username; applied without restarting the bot", Body: AllowedUserRequest{}, Response: []AllowedUserResponse{}},
+	{Method: http.MethodDelete, Path: "/users/:user", Tag: "users", Summary: "Remove a Telegram user, by ID or @username, from ALLOWED_USERS", Response: []AllowedUserResponse{}},
+
+	// Test notifications
+	{Method: http.MethodPost, Path: "/test/telegram/:chat_id", Tag: "test", Summary: "Send a test notification to a Telegram chat"},
+	{Method: http.MethodPost, Path: "/test/webhook/:webhook_id", Tag: "test", Summary: "Send a test notification to a webhook and report the delivery result (502 on failure)", Response: WebhookTestResponse{}},
+	{Method: http.MethodPost, Path: "/test/all", Tag: "test", Summary: "Send a test notification through every sink of a source and report the result per sink (502 if any failed)", Body: TestAllSinksRequest{}, Response: TestAllSinksResponse{}},
+}
+
+// echoPathParam matches Echo path parameters such as :id
+var echoPathParam = regexp.MustCompile(`:([a-zA-Z_]+)`)
+
+// openAPIPath converts an Echo route path to OpenAPI form: /sources/:id → /sources/{id}, /ui/* → /ui/{path}
+func openAPIPath(path string) string {
+	path = echoPathParam.ReplaceAllString(path, "{$1}")
+	return strings.Replace(path, "*", "{path}", 1)
+}
+
+// handleOpenAPISpec serves the OpenAPI 3 document
+func (am *AppManager) handleOpenAPISpec(c echo.Context) error {
+	return c.JSON(http.StatusOK, buildOpenAPISpec(am.version))
+}
+
+// handleSwaggerUI serves a Swagger UI page for /openapi.json
+func (am *AppManager) handleSwaggerUI(c echo.Context) error {
+	return c.HTML(http.StatusOK, swaggerUIPage)
+}
+
+// buildOpenAPISpec generates the OpenAPI document from apiOperations.
+// Schemas are derived from the Go types via their json tags.
+func buildOpenAPISpec(version string) map[string]interface{} {
+	schemas := newSchemaRegistry()
+	errorSchema := schemas.schemaFor(reflect.TypeOf(ErrorResponse{}))
+	paths := map[string]map[string]interface{}{}
+
+	for _, op := range apiOperations {
+		path := openAPIPath(op.Path)
+		if !op.Public {
+			path = openAPIPath(apiV1Prefix + op.Path)
+		}
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+
+		var params []map[string]interface{}
+		for _, match := range echoPathParam.FindAllStringSubmatch(op.Path, -1) {
+			params = append(params, map[string]interface{}{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		if strings.HasSuffix(op.Path, "*") {
+			params = append(params, map[string]interface{}{
+				"name":     "path",
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, q := range op.Query {
+			params = append(params, map[string]interface{}{
+				"name":        q.Name,
+				"in":          "query",
+				"description": q.Description,
+				"schema":      map[string]interface{}{"type": q.Type},
+			})
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		var content map[string]interface{}
+		if op.ContentType != "" {
+			content = map[string]interface{}{
+				op.ContentType: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+			}
+		} else {
+			responseSchema := map[string]interface{}{"type": "object", "additionalProperties": true}
+			if op.Response != nil {
+				responseSchema = schemas.schemaFor(reflect.TypeOf(op.Response))
+			}
+			content = map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": responseSchema},
+			}
+		}
+
+		responses := map[string]interface{}{
+			strconv.Itoa(status): map[string]interface{}{
+				"description": http.StatusText(status),
+				"content":     content,
+			},
+			"default": map[string]interface{}{
+				"description": "Error",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": errorSchema},
+				},
+			},
+		}
+
+		operation := map[string]interface{}{
+			"tags":        []string{op.Tag},
+			"summary":     op.Summary,
+			"operationId": operationID(op),
+			"responses":   responses,
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if op.Body != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemas.schemaFor(reflect.TypeOf(op.Body))},
+				},
+			}
+		}
+		if op.Public {
+			operation["security"] = []interface{}{}
+		}
+
+		paths[path][strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Outage Monitor Bot API",
+			"description": "REST API of the outage monitor: sources, notification sinks, history and configuration.",
+			"version":     version,
+		},
+		// Relative to the document, so "Try it out" works behind the /api/ reverse proxy too
+		"servers": []interface{}{map[string]interface{}{"url": "."}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				"apiKey":     map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT", "description": "OIDC access token (only when OIDC_ISSUER_URL is set)"},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"apiKey": []string{}},
+			map[string]interface{}{"bearerAuth": []string{}},
+		},
+	}
+}
+
+// operationID derives a stable operation ID such as get_sources_id
+func operationID(op apiOperation) string {
+	id := strings.ToLower(op.Method) + strings.NewReplacer("/", "_", ":", "", "-", "_", ".", "_").Replace(op.Path)
+	return strings.TrimSuffix(id, "_")
+}
+
+// schemaRegistry converts Go types to OpenAPI schemas, collecting named structs as components
+type schemaRegistry struct {
+	components map[string]interface{}
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{components: map[string]interface{}{}}
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	rawJSONType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaFor returns the schema for t, registering named structs under components/schemas
+func (r *schemaRegistry) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == durationType:
+		return map[string]interface{}{"type": "integer", "format": "int64", "description": "Duration in nanoseconds"}
+	case t == rawJSONType:
+		return map[string]interface{}{"type": "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": r.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": r.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		if _, ok := r.components[t.Name()]; !ok {
+			r.components[t.Name()] = map[string]interface{}{} // Placeholder guards against recursive types
+			r.components[t.Name()] = r.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema builds an object schema from the exported, JSON-visible fields of t
+func (r *schemaRegistry) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		// Embedded structs without a json name are flattened, as encoding/json does
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for name, schema := range r.structSchema(embedded)["properties"].(map[string]interface{}) {
+					properties[name] = schema
+				}
+				continue
+			}
+		}
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if tagName := strings.Split(tag, ",")[0]; tagName != "" {
+				name = tagName
+			}
+		}
+		properties[name] = r.schemaFor(field.Type)
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// swaggerUIPage loads Swagger UI from a CDN and points it at /openapi.json
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Outage Monitor Bot API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({
+      url: "openapi.json",
+      dom_id: "#swagger-ui",
+      persistAuthorization: true
+    });
+  </script>
+</body>
+</html>
+`
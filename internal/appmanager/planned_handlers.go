@@ -0,0 +1,97 @@
+package appmanager
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// MarkOutagePlannedRequest is the request body for marking an outage as planned
+type MarkOutagePlannedRequest struct {
+	Reason string     `json:"reason,omitempty"`
+	At     *time.Time `json:"at,omitempty"` // A time during or after the outage; default now, i.e. the latest outage
+}
+
+// handleGetPlannedOutages lists a source's outages marked as planned that started between
+// ?from= and ?to= (default all), oldest first
+func (am *AppManager) handleGetPlannedOutages(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	var from, to time.Time
+	if value := c.QueryParam("from"); value != "" {
+		if from, err = parseTimeParam(value); err != nil {
+			return errorJSON(c, http.StatusBadRequest, "Invalid from: "+err.Error())
+		}
+	}
+	if value := c.QueryParam("to"); value != "" {
+		if to, err = parseTimeParam(value); err != nil {
+			return errorJSON(c, http.StatusBadRequest, "Invalid to: "+err.Error())
+		}
+	}
+
+	marks, err := am.storage.GetPlannedOutages(source.ID, from, to)
+	if err != nil {
+		am.log(c).Errorf("Failed to get planned outages of %s: %v", source.ID, err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get planned outages")
+	}
+	return c.JSON(http.StatusOK, marks)
+}
+
+// handleMarkOutagePlanned marks the outage of a source in progress at the given time, or
+// the last one before it, as planned
+func (am *AppManager) handleMarkOutagePlanned(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	var req MarkOutagePlannedRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+	at := time.Now()
+	if req.At != nil {
+		at = *req.At
+	}
+
+	outage, err := am.storage.FindOutage(source.ID, at)
+	if errors.Is(err, storage.ErrNoOutage) {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		am.log(c).Errorf("Failed to find outage of %s: %v", source.ID, err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to mark outage as planned")
+	}
+
+	mark := &storage.PlannedOutage{Reason: req.Reason, MarkedBy: authKeyName(c)}
+	if err := am.storage.MarkOutagePlanned(outage, mark); err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	am.log(c).Printf("Outage of %s at %s marked as planned by %s", source.Name, outage.Timestamp.Format(time.RFC3339), mark.MarkedBy)
+	return c.JSON(http.StatusCreated, mark)
+}
+
+// handleUnmarkOutagePlanned counts a source's outage as unplanned again
+func (am *AppManager) handleUnmarkOutagePlanned(c echo.Context) error {
+	sourceID := c.Param("id")
+	changeID := c.Param("change_id")
+	if _, err := am.getActiveSource(sourceID); err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+	if err := am.storage.UnmarkOutagePlanned(sourceID, changeID); err != nil {
+		return errorJSON(c, http.StatusNotFound, "Planned outage not found")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message":   "Outage is no longer planned",
+		"change_id": changeID,
+	})
+}
@@ -0,0 +1,315 @@
+package appmanager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// Postmortem event kinds
+const (
+	postmortemStatusChange = "status_change"
+	postmortemNote         = "note"
+	postmortemDelivery     = "delivery"
+)
+
+// Postmortem collects what is known about one outage of a source, as the starting point of
+// a postmortem document
+type Postmortem struct {
+	SourceID    string                  `json:"source_id"`
+	SourceName  string                  `json:"source_name"`
+	SourceType  string                  `json:"source_type"`
+	Target      string                  `json:"target,omitempty"`
+	ChangeID    string                  `json:"change_id"` // Status change that started the outage
+	Start       time.Time               `json:"start"`
+	End         *time.Time              `json:"end"`         // nil while the outage is ongoing
+	DurationMs  int64                   `json:"duration_ms"` // Until now while ongoing
+	Ongoing     bool                    `json:"ongoing"`
+	LastError   string                  `json:"last_error,omitempty"` // The source's current error, while ongoing
+	Timeline    []PostmortemEvent       `json:"timeline"`             // Oldest first
+	Affected    []ReportOutage          `json:"affected_sources"`     // Other sources down during the outage, clipped to it
+	Notes       []*storage.IncidentNote `json:"notes"`
+	Deliveries  []*storage.Delivery     `json:"deliveries"` // Notifications about the outage, oldest first
+	GeneratedAt time.Time               `json:"generated_at"`
+}
+
+// PostmortemEvent is one line of a postmortem timeline
+type PostmortemEvent struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"` // status_change, note or delivery
+	SourceName string    `json:"source_name"`
+	Text       string    `json:"text"`
+}
+
+// buildPostmortem gathers the outage of source that started with the status change outage.
+// Other sources are only considered when visible reports whether the request may see them.
+func (am *AppManager) buildPostmortem(source *storage.Source, outage *storage.StatusChange, visible func(*storage.Source) bool, now time.Time) (*Postmortem, error) {
+	pm := &Postmortem{
+		SourceID:    source.ID,
+		SourceName:  source.Name,
+		SourceType:  source.Type,
+		Target:      source.Target,
+		ChangeID:    outage.ID,
+		Start:       outage.Timestamp,
+		Timeline:    []PostmortemEvent{},
+		Affected:    []ReportOutage{},
+		Deliveries:  []*storage.Delivery{},
+		GeneratedAt: now,
+	}
+
+	// The outage lasts until the next change to online
+	changes, err := am.storage.GetStatusChangesInRange(source.ID, outage.Timestamp, now.Add(time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status changes: %w", err)
+	}
+	end := now
+	changeIDs := make(map[string]bool)
+	for _, change := range changes {
+		changeIDs[change.ID] = true
+		pm.Timeline = append(pm.Timeline, PostmortemEvent{
+			Time:       change.Timestamp,
+			Kind:       postmortemStatusChange,
+			SourceName: source.Name,
+			Text:       fmt.Sprintf("%s went %s", source.Name, statusLabel(change.NewStatus)),
+		})
+		if change.NewStatus == 1 {
+			end = change.Timestamp
+			pm.End = &end
+			break
+		}
+	}
+	pm.Ongoing = pm.End == nil
+	pm.DurationMs = end.Sub(pm.Start).Milliseconds()
+	if pm.Ongoing {
+		pm.LastError = source.LastError
+	}
+
+	notes, err := am.storage.GetIncidentNotes(source.ID, outage.Timestamp, outage.Timestamp.Add(time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident notes: %w", err)
+	}
+	pm.Notes = notes
+	for _, note := range notes {
+		pm.Timeline = append(pm.Timeline, PostmortemEvent{
+			Time:       note.CreatedAt,
+			Kind:       postmortemNote,
+			SourceName: source.Name,
+			Text:       fmt.Sprintf("Note by %s: %s", note.Author, note.Text),
+		})
+	}
+
+	deliveries, err := am.storage.GetDeliveries(storage.DeliveryFilter{SourceID: source.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		delivery := deliveries[i]
+		if !changeIDs[delivery.StatusChangeID] {
+			continue
+		}
+		pm.Deliveries = append(pm.Deliveries, delivery)
+		pm.Timeline = append(pm.Timeline, PostmortemEvent{
+			Time:       delivery.Timestamp,
+			Kind:       postmortemDelivery,
+			SourceName: source.Name,
+			Text:       fmt.Sprintf("%s %s: %s", delivery.SinkType, deliverySinkName(delivery), deliveryResult(delivery)),
+		})
+	}
+
+	// Other sources that were down at some point during the outage
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sources: %w", err)
+	}
+	windowEnd := end.Add(time.Nanosecond)
+	for _, other := range sources {
+		if other.ID == source.ID || !visible(other) {
+			continue
+		}
+		windows, err := am.storage.GetOutageWindows(other, pm.Start, windowEnd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute outages of %s: %w", other.Name, err)
+		}
+		for _, window := range windows {
+			pm.Affected = append(pm.Affected, ReportOutage{
+				SourceID:   other.ID,
+				SourceName: other.Name,
+				Start:      window.Start,
+				End:        window.End,
+				DurationMs: window.DurationMs,
+				Ongoing:    window.Ongoing && pm.Ongoing,
+			})
+			if !window.StartedBefore {
+				pm.Timeline = append(pm.Timeline, PostmortemEvent{
+					Time:       window.Start,
+					Kind:       postmortemStatusChange,
+					SourceName: other.Name,
+					Text:       fmt.Sprintf("%s went OFFLINE", other.Name),
+				})
+			}
+			if !window.Ongoing {
+				pm.Timeline = append(pm.Timeline, PostmortemEvent{
+					Time:       window.End,
+					Kind:       postmortemStatusChange,
+					SourceName: other.Name,
+					Text:       fmt.Sprintf("%s went ONLINE", other.Name),
+				})
+			}
+		}
+	}
+	sort.Slice(pm.Affected, func(i, j int) bool { return pm.Affected[i].Start.Before(pm.Affected[j].Start) })
+	sort.SliceStable(pm.Timeline, func(i, j int) bool { return pm.Timeline[i].Time.Before(pm.Timeline[j].Time) })
+
+	return pm, nil
+}
+
+// statusLabel names a source status in postmortems
+func statusLabel(status int) string {
+	switch status {
+	case 1:
+		return "ONLINE"
+	case 0:
+		return "OFFLINE"
+	}
+	return "UNKNOWN"
+}
+
+// deliverySinkName names the sink of a delivery, falling back to its ID
+func deliverySinkName(delivery *storage.Delivery) string {
+	if delivery.SinkName != "" {
+		return delivery.SinkName
+	}
+	return delivery.SinkID
+}
+
+// deliveryResult describes the outcome of a delivery
+func deliveryResult(delivery *storage.Delivery) string {
+	if delivery.Success {
+		return fmt.Sprintf("delivered in %dms", delivery.LatencyMs)
+	}
+	return "failed: " + delivery.Error
+}
+
+// renderPostmortemMarkdown renders a postmortem skeleton with the facts filled in and the
+// analysis left to write
+func renderPostmortemMarkdown(pm *Postmortem) string {
+	const timeFormat = "2006-01-02 15:04:05"
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Postmortem: %s outage on %s UTC\n\n", pm.SourceName, pm.Start.UTC().Format("2006-01-02 15:04"))
+
+	end := "ongoing"
+	if pm.End != nil {
+		end = pm.End.UTC().Format(timeFormat) + " UTC"
+	}
+	target := pm.SourceType
+	if pm.Target != "" {
+		target += " " + pm.Target
+	}
+	b.WriteString("| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Source | %s (%s) |\n", markdownCell(pm.SourceName), markdownCell(target))
+	fmt.Fprintf(&b, "| Start | %s UTC |\n", pm.Start.UTC().Format(timeFormat))
+	fmt.Fprintf(&b, "| End | %s |\n", end)
+	fmt.Fprintf(&b, "| Duration | %s |\n", reportDuration(pm.DurationMs))
+	fmt.Fprintf(&b, "| Other sources affected | %d |\n", len(pm.Affected))
+	if pm.LastError != "" {
+		fmt.Fprintf(&b, "| Last error | %s |\n", markdownCell(pm.LastError))
+	}
+
+	b.WriteString("\n## Summary\n\n_What happened and who was affected, in two or three sentences._\n")
+
+	b.WriteString("\n## Impact\n\n")
+	if len(pm.Affected) == 0 {
+		b.WriteString("No other monitored source was down during the outage.\n")
+	} else {
+		b.WriteString("Other sources down during the outage:\n\n| Source | Start | End | Duration |\n|---|---|---|---:|\n")
+		for _, outage := range pm.Affected {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", markdownCell(outage.SourceName),
+				outage.Start.UTC().Format(timeFormat), reportOutageEnd(outage), reportDuration(outage.DurationMs))
+		}
+	}
+
+	b.WriteString("\n## Timeline (UTC)\n\n| Time | Event |\n|---|---|\n")
+	for _, event := range pm.Timeline {
+		fmt.Fprintf(&b, "| %s | %s |\n", event.Time.UTC().Format(timeFormat), markdownCell(event.Text))
+	}
+
+	b.WriteString("\n## Notifications\n\n")
+	if len(pm.Deliveries) == 0 {
+		b.WriteString("No notifications were recorded in the delivery log.\n")
+	} else {
+		b.WriteString("| Time | Sink | Change | Result |\n|---|---|---|---|\n")
+		for _, delivery := range pm.Deliveries {
+			fmt.Fprintf(&b, "| %s | %s %s | %s → %s | %s |\n", delivery.Timestamp.UTC().Format(timeFormat),
+				delivery.SinkType, markdownCell(deliverySinkName(delivery)),
+				statusLabel(delivery.OldStatus), statusLabel(delivery.NewStatus), markdownCell(deliveryResult(delivery)))
+		}
+	}
+
+	b.WriteString("\n## Notes\n\n")
+	if len(pm.Notes) == 0 {
+		b.WriteString("_No incident notes were added._\n")
+	}
+	for _, note := range pm.Notes {
+		fmt.Fprintf(&b, "- %s\n", reportNote(note))
+	}
+
+	b.WriteString("\n## Root cause\n\n_Why did it happen?_\n")
+	b.WriteString("\n## Resolution\n\n_How was service restored?_\n")
+	b.WriteString("\n## Action items\n\n- [ ] \n")
+	return b.String()
+}
+
+// handleGetPostmortem renders a postmortem skeleton for the outage of a source in progress at
+// ?at= (default now), or the last one before it, as Markdown or with ?format=json as JSON
+func (am *AppManager) handleGetPostmortem(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "markdown"
+	}
+	if format != "markdown" && format != "json" {
+		return errorJSON(c, http.StatusBadRequest, "Invalid format (use markdown or json)")
+	}
+
+	now := time.Now()
+	at := now
+	if value := c.QueryParam("at"); value != "" {
+		if at, err = parseTimeParam(value); err != nil {
+			return errorJSON(c, http.StatusBadRequest, "Invalid at: "+err.Error())
+		}
+	}
+
+	outage, err := am.storage.FindOutage(source.ID, at)
+	if errors.Is(err, storage.ErrNoOutage) {
+		return errorJSON(c, http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		am.log(c).Errorf("Failed to find outage of %s: %v", source.ID, err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to build postmortem")
+	}
+
+	visible := func(other *storage.Source) bool { return namespaceAllows(c, other.Namespace) }
+	pm, err := am.buildPostmortem(source, outage, visible, now)
+	if err != nil {
+		am.log(c).Errorf("Failed to build postmortem for %s: %v", source.ID, err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to build postmortem")
+	}
+
+	if format == "json" {
+		return c.JSON(http.StatusOK, pm)
+	}
+	name := "postmortem-" + source.ID + "-" + pm.Start.UTC().Format("2006-01-02") + ".md"
+	c.Response().Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	return c.Blob(http.StatusOK, "text/markdown; charset=utf-8", []byte(renderPostmortemMarkdown(pm)))
+}
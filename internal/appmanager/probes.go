@@ -0,0 +1,90 @@
+package appmanager
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ProbeCheck is the state of one component checked by /readyz
+type ProbeCheck struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ProbeResponse is returned by /livez and /readyz
+type ProbeResponse struct {
+	Status string                `json:"status"` // "ok" or "not_ready"
+	Checks map[string]ProbeCheck `json:"checks,omitempty"`
+}
+
+// handleLivez reports that the process is up and serving HTTP. It deliberately checks
+// nothing else, so a liveness probe never restarts the pod over a flapping dependency.
+func (am *AppManager) handleLivez(c echo.Context) error {
+	return c.JSON(http.StatusOK, ProbeResponse{Status: "ok"})
+}
+
+// handleReadyz reports whether the app can do its job: storage open, monitor started,
+// and Telegram connected unless running in web-only mode
+func (am *AppManager) handleReadyz(c echo.Context) error {
+	checks := make(map[string]ProbeCheck)
+
+	if err := am.storage.Ping(); err != nil {
+		checks["storage"] = ProbeCheck{OK: false, Detail: err.Error()}
+	} else {
+		checks["storage"] = ProbeCheck{OK: true}
+	}
+
+	// A replica only needs its copy of the database
+	if am.isReplica() {
+		return am.readyzResponse(c, checks)
+	}
+
+	status := am.botProcess.GetStatus()
+	monitorRunning, _ := status["monitor_running"].(bool)
+	telegramConnected, _ := status["telegram_connected"].(bool)
+	webOnly, _ := status["web_only_mode"].(bool)
+	lastError, _ := status["last_error"].(string)
+
+	if monitorRunning {
+		checks["monitor"] = ProbeCheck{OK: true}
+	} else {
+		checks["monitor"] = ProbeCheck{OK: false, Detail: firstNonEmpty(lastError, "monitor not started")}
+	}
+
+	switch {
+	case webOnly:
+		checks["telegram"] = ProbeCheck{OK: true, Detail: "web-only mode"}
+	case telegramConnected:
+		checks["telegram"] = ProbeCheck{OK: true}
+	default:
+		checks["telegram"] = ProbeCheck{OK: false, Detail: firstNonEmpty(lastError, "not connected")}
+	}
+
+	return am.readyzResponse(c, checks)
+}
+
+// readyzResponse reports ready when every check passed
+func (am *AppManager) readyzResponse(c echo.Context, checks map[string]ProbeCheck) error {
+	response := ProbeResponse{Status: "ok", Checks: checks}
+	httpStatus := http.StatusOK
+	for _, check := range checks {
+		if !check.OK {
+			response.Status = "not_ready"
+			httpStatus = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	return c.JSON(httpStatus, response)
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
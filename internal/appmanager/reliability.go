@@ -0,0 +1,135 @@
+package appmanager
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+const (
+	defaultReliabilityWindows = "7d,30d,90d"
+	maxReliabilityWindows     = 6
+)
+
+// ReliabilityReport is the MTTR and MTBF of sources over one or more look-back windows
+type ReliabilityReport struct {
+	Tag         string              `json:"tag,omitempty"`
+	GeneratedAt time.Time           `json:"generated_at"`
+	Windows     []ReliabilityWindow `json:"windows"` // In the order requested
+}
+
+// ReliabilityWindow is the reliability of sources over the window ending now
+type ReliabilityWindow struct {
+	Window  string              `json:"window"` // As requested, e.g. "30d"
+	From    time.Time           `json:"from"`
+	To      time.Time           `json:"to"`
+	Overall storage.Reliability `json:"overall"`
+	Sources []ReliabilitySource `json:"sources"` // By name
+	Tags    []ReliabilityTag    `json:"tags"`    // By tag
+}
+
+// ReliabilitySource is one source's reliability over a window
+type ReliabilitySource struct {
+	SourceID string              `json:"source_id"`
+	Name     string              `json:"name"`
+	Tags     []string            `json:"tags,omitempty"`
+	Stats    storage.Reliability `json:"stats"`
+}
+
+// ReliabilityTag is the combined reliability of the sources with a tag over a window
+type ReliabilityTag struct {
+	Tag   string              `json:"tag"`
+	Stats storage.Reliability `json:"stats"`
+}
+
+// handleGetReliability returns MTTR and MTBF per source, per tag and overall for each of
+// ?windows= (comma-separated periods, default 7d,30d,90d), optionally only for ?tag=
+func (am *AppManager) handleGetReliability(c echo.Context) error {
+	value := c.QueryParam("windows")
+	if value == "" {
+		value = defaultReliabilityWindows
+	}
+	windows := strings.Split(value, ",")
+	periods := make([]time.Duration, len(windows))
+	for i, window := range windows {
+		windows[i] = strings.TrimSpace(window)
+		period, err := parsePeriod(windows[i])
+		if err != nil || period <= 0 || period > maxUptimePeriod {
+			return errorJSON(c, http.StatusBadRequest, fmt.Sprintf("Invalid window %q (use e.g. 30d, 7d or 12h; max 366d)", windows[i]))
+		}
+		periods[i] = period
+	}
+	if len(windows) > maxReliabilityWindows {
+		return errorJSON(c, http.StatusBadRequest, fmt.Sprintf("At most %d windows", maxReliabilityWindows))
+	}
+
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		am.log(c).Errorf("Failed to get sources: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get sources")
+	}
+	tag := strings.TrimSpace(c.QueryParam("tag"))
+	if tag != "" {
+		sources = slices.DeleteFunc(sources, func(s *storage.Source) bool { return !s.HasTag(tag) })
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
+
+	now := time.Now()
+	report := ReliabilityReport{Tag: tag, GeneratedAt: now, Windows: make([]ReliabilityWindow, 0, len(windows))}
+	for i, window := range windows {
+		result, err := am.computeReliabilityWindow(sources, now.Add(-periods[i]), now)
+		if err != nil {
+			am.log(c).Errorf("Failed to compute reliability: %v", err)
+			return errorJSON(c, http.StatusInternalServerError, "Failed to compute reliability")
+		}
+		result.Window = window
+		report.Windows = append(report.Windows, *result)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// computeReliabilityWindow computes the reliability of sources over [from, to), per source,
+// per tag and overall
+func (am *AppManager) computeReliabilityWindow(sources []*storage.Source, from, to time.Time) (*ReliabilityWindow, error) {
+	result := &ReliabilityWindow{
+		From:    from,
+		To:      to,
+		Sources: make([]ReliabilitySource, 0, len(sources)),
+		Tags:    []ReliabilityTag{},
+	}
+	byTag := make(map[string]*storage.Reliability)
+
+	for _, source := range sources {
+		stats, err := am.storage.ComputeReliability(source, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute reliability of %s: %w", source.Name, err)
+		}
+		result.Sources = append(result.Sources, ReliabilitySource{
+			SourceID: source.ID,
+			Name:     source.Name,
+			Tags:     source.Tags,
+			Stats:    *stats,
+		})
+		result.Overall.Add(stats)
+		for _, tag := range source.Tags {
+			if byTag[tag] == nil {
+				byTag[tag] = &storage.Reliability{}
+			}
+			byTag[tag].Add(stats)
+		}
+	}
+
+	for tag, stats := range byTag {
+		result.Tags = append(result.Tags, ReliabilityTag{Tag: tag, Stats: *stats})
+	}
+	sort.Slice(result.Tags, func(i, j int) bool { return result.Tags[i].Tag < result.Tags[j].Tag })
+	return result, nil
+}
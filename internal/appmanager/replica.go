@@ -0,0 +1,127 @@
+package appmanager
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/config"
+)
+
+// replicaReloadInterval is how often a replica looks for a newer copy of the database
+const replicaReloadInterval = 30 * time.Second
+
+// replicaState tracks the copy of the database a read-only replica serves
+type replicaState struct {
+	mu        sync.Mutex
+	modTime   time.Time // Of the file when it was last opened
+	size      int64
+	loadedAt  time.Time
+	lastError string
+}
+
+// ReplicaStatus describes the copy a read-only replica serves, for /status and /health
+type ReplicaStatus struct {
+	Path      string    `json:"path"`
+	Modified  time.Time `json:"modified"`  // Modification time of the copy being served
+	LoadedAt  time.Time `json:"loaded_at"` // When it was last (re)opened
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// isReplica reports whether this instance serves a read-only copy of the database
+func (am *AppManager) isReplica() bool {
+	return am.storage.ReadOnly()
+}
+
+// startReplica serves the API from a read-only copy: nothing is checked or notified, and
+// the file is reopened when a newer copy replaces it
+func (am *AppManager) startReplica(cfg *config.Config) {
+	am.botProcess.StartReplica(cfg)
+
+	am.replica.mu.Lock()
+	if info, err := os.Stat(am.storage.Path()); err == nil {
+		am.replica.modTime = info.ModTime()
+		am.replica.size = info.Size()
+	}
+	am.replica.loadedAt = time.Now()
+	am.replica.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	am.maintenanceCancel = cancel
+	go am.runReplicaReload(ctx)
+}
+
+// runReplicaReload reopens the database whenever its file changes, until ctx is cancelled
+func (am *AppManager) runReplicaReload(ctx context.Context) {
+	ticker := time.NewTicker(replicaReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			am.reloadReplica()
+		}
+	}
+}
+
+// reloadReplica reopens the database if a newer copy replaced the file. Copies should be
+// written next to it and renamed into place, so a half-written file is never opened.
+func (am *AppManager) reloadReplica() {
+	info, err := os.Stat(am.storage.Path())
+
+	am.replica.mu.Lock()
+	defer am.replica.mu.Unlock()
+	if err != nil {
+		am.replica.lastError = err.Error()
+		am.logger.Warnf("Replica database unavailable, serving the open copy: %v", err)
+		return
+	}
+	if info.ModTime().Equal(am.replica.modTime) && info.Size() == am.replica.size {
+		return
+	}
+
+	if err := am.storage.Reopen(); err != nil {
+		am.replica.lastError = err.Error()
+		am.logger.Warnf("Failed to reopen replica database, serving the previous copy: %v", err)
+		return
+	}
+	am.replica.modTime = info.ModTime()
+	am.replica.size = info.Size()
+	am.replica.loadedAt = time.Now()
+	am.replica.lastError = ""
+	am.logger.Printf("Reopened replica database (modified %s)", info.ModTime().Format(time.RFC3339))
+}
+
+// replicaStatus returns the state of the served copy
+func (am *AppManager) replicaStatus() ReplicaStatus {
+	am.replica.mu.Lock()
+	defer am.replica.mu.Unlock()
+	return ReplicaStatus{
+		Path:      am.storage.Path(),
+		Modified:  am.replica.modTime,
+		LoadedAt:  am.replica.loadedAt,
+		LastError: am.replica.lastError,
+	}
+}
+
+// readOnlyMiddleware rejects requests that would change data on a replica. GraphQL
+// queries are read-only and allowed over POST too.
+func (am *AppManager) readOnlyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		switch c.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return next(c)
+		}
+		if strings.HasSuffix(c.Request().URL.Path, "/graphql") {
+			return next(c)
+		}
+		return errorJSON(c, http.StatusMethodNotAllowed, "Read-only replica: changes must be made on the primary instance")
+	}
+}
@@ -0,0 +1,440 @@
+package appmanager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/notifier"
+	"tg-monitor-bot/internal/storage"
+)
+
+const (
+	reportTopOutages   = 10 // Longest outages listed in a report
+	reportTrendPeriods = 6  // Periods in the MTTR trend, including the reported one
+	maxReportOffset    = 52 // How many periods back a report may be requested
+)
+
+// Report summarizes availability over a completed week or month, globally or for a tag
+type Report struct {
+	Period      string             `json:"period"` // "week" or "month"
+	Tag         string             `json:"tag,omitempty"`
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Summary     ReportSummary      `json:"summary"`
+	Sources     []ReportSource     `json:"sources"`     // Lowest uptime first
+	Tags        []ReportTag        `json:"tags"`        // By tag
+	TopOutages  []ReportOutage     `json:"top_outages"` // Longest first
+	MTTRTrend   []ReportTrendPoint `json:"mttr_trend"`  // Oldest first, ending with this period
+}
+
+// ReportSummary totals a period over all sources in the report
+type ReportSummary struct {
+	Sources           int      `json:"sources"`
+	UptimePercent     *float64 `json:"uptime_percent"` // Weighted by monitored time; nil when nothing was monitored
+	DowntimeMs        int64    `json:"downtime_ms"`
+	OutageCount       int      `json:"outage_count"`        // Outages that started in the period
+	MTTRMs            *int64   `json:"mttr_ms"`             // Over outages that started and ended in the period
+	MTBFMs            *int64   `json:"mtbf_ms"`             // Total uptime / outage count
+	PlannedDowntimeMs int64    `json:"planned_downtime_ms"` // Part of DowntimeMs in planned outages or maintenance windows
+	SLAUptimePercent  *float64 `json:"sla_uptime_percent"`  // With planned downtime left out
+}
+
+// ReportSource is one source's row in a report
+type ReportSource struct {
+	SourceID          string   `json:"source_id"`
+	Name              string   `json:"name"`
+	Type              string   `json:"type"`
+	Tags              []string `json:"tags,omitempty"`
+	UptimePercent     *float64 `json:"uptime_percent"`
+	DowntimeMs        int64    `json:"downtime_ms"`
+	OutageCount       int      `json:"outage_count"`
+	MTTRMs            *int64   `json:"mttr_ms"`
+	MTBFMs            *int64   `json:"mtbf_ms"`
+	LongestOutageMs   int64    `json:"longest_outage_ms"`
+	PlannedDowntimeMs int64    `json:"planned_downtime_ms"`
+	SLAUptimePercent  *float64 `json:"sla_uptime_percent"`
+}
+
+// ReportTag is the combined reliability of the sources with a tag in a report
+type ReportTag struct {
+	Tag           string   `json:"tag"`
+	Sources       int      `json:"sources"`
+	UptimePercent *float64 `json:"uptime_percent"`
+	OutageCount   int      `json:"outage_count"`
+	MTTRMs        *int64   `json:"mttr_ms"`
+	MTBFMs        *int64   `json:"mtbf_ms"`
+}
+
+// ReportOutage is an outage listed in a report, clipped to the period
+type ReportOutage struct {
+	SourceID   string                  `json:"source_id"`
+	SourceName string                  `json:"source_name"`
+	Start      time.Time               `json:"start"`
+	End        time.Time               `json:"end"`
+	DurationMs int64                   `json:"duration_ms"`
+	Ongoing    bool                    `json:"ongoing"`           // Not over by the end of the period
+	Planned    bool                    `json:"planned,omitempty"` // Marked as planned
+	Notes      []*storage.IncidentNote `json:"notes,omitempty"`
+}
+
+// ReportTrendPoint is the summary of one period in a report's trend
+type ReportTrendPoint struct {
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	UptimePercent *float64  `json:"uptime_percent"`
+	OutageCount   int       `json:"outage_count"`
+	MTTRMs        *int64    `json:"mttr_ms"`
+	MTBFMs        *int64    `json:"mtbf_ms"`
+}
+
+// reportBounds returns the UTC period that ended offset periods before the current one:
+// weeks run Monday to Monday, months from the first of the month
+func reportBounds(period string, now time.Time, offset int) (from, to time.Time) {
+	today := now.UTC().Truncate(24 * time.Hour)
+	if period == "month" {
+		current := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+		from = current.AddDate(0, -(offset + 1), 0)
+		return from, from.AddDate(0, 1, 0)
+	}
+	current := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	from = current.AddDate(0, 0, -7*(offset+1))
+	return from, from.AddDate(0, 0, 7)
+}
+
+// buildReport computes the report for a completed period, offset periods back, of the
+// sources with tag (all sources when empty)
+func (am *AppManager) buildReport(period, tag string, offset int, now time.Time) (*Report, error) {
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		return nil, err
+	}
+	if tag != "" {
+		sources = slices.DeleteFunc(sources, func(s *storage.Source) bool { return !s.HasTag(tag) })
+	}
+
+	from, to := reportBounds(period, now, offset)
+	report := &Report{
+		Period:      period,
+		Tag:         tag,
+		From:        from,
+		To:          to,
+		GeneratedAt: now,
+		Sources:     []ReportSource{},
+		TopOutages:  []ReportOutage{},
+	}
+
+	var outages []ReportOutage
+	report.Sources, report.Tags, outages, report.Summary, err = am.reportPeriod(sources, from, to)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(outages, func(i, j int) bool { return outages[i].DurationMs > outages[j].DurationMs })
+	if len(outages) > reportTopOutages {
+		outages = outages[:reportTopOutages]
+	}
+	report.TopOutages = outages
+
+	for i := reportTrendPeriods - 1; i >= 0; i-- {
+		trendFrom, trendTo := reportBounds(period, now, offset+i)
+		summary := report.Summary
+		if i > 0 {
+			if _, _, _, summary, err = am.reportPeriod(sources, trendFrom, trendTo); err != nil {
+				return nil, err
+			}
+		}
+		report.MTTRTrend = append(report.MTTRTrend, ReportTrendPoint{
+			From:          trendFrom,
+			To:            trendTo,
+			UptimePercent: summary.UptimePercent,
+			OutageCount:   summary.OutageCount,
+			MTTRMs:        summary.MTTRMs,
+			MTBFMs:        summary.MTBFMs,
+		})
+	}
+
+	return report, nil
+}
+
+// reportPeriod computes the rows, per-tag rows, outages and totals of sources over [from, to)
+func (am *AppManager) reportPeriod(sources []*storage.Source, from, to time.Time) ([]ReportSource, []ReportTag, []ReportOutage, ReportSummary, error) {
+	rows := make([]ReportSource, 0, len(sources))
+	var outages []ReportOutage
+	summary := ReportSummary{Sources: len(sources)}
+	var total storage.Reliability
+	byTag := make(map[string]*storage.Reliability)
+
+	for _, source := range sources {
+		if source.CreatedAt.After(to) {
+			continue
+		}
+		stats, err := am.storage.ComputeUptimeStats(source, from, to)
+		if err != nil {
+			return nil, nil, nil, summary, fmt.Errorf("failed to compute uptime of %s: %w", source.Name, err)
+		}
+		windows, err := am.storage.GetOutageWindows(source, from, to)
+		if err != nil {
+			return nil, nil, nil, summary, fmt.Errorf("failed to compute outages of %s: %w", source.Name, err)
+		}
+		notes, err := am.storage.GetIncidentNotes(source.ID, time.Time{}, to)
+		if err != nil {
+			return nil, nil, nil, summary, fmt.Errorf("failed to get incident notes of %s: %w", source.Name, err)
+		}
+
+		rows = append(rows, ReportSource{
+			SourceID:          source.ID,
+			Name:              source.Name,
+			Type:              source.Type,
+			Tags:              source.Tags,
+			UptimePercent:     stats.UptimePercent,
+			DowntimeMs:        stats.DowntimeMs,
+			OutageCount:       stats.OutageCount,
+			MTTRMs:            stats.MTTRMs,
+			MTBFMs:            stats.MTBFMs,
+			LongestOutageMs:   stats.LongestOutageMs,
+			PlannedDowntimeMs: stats.PlannedDowntimeMs,
+			SLAUptimePercent:  stats.SLAUptimePercent,
+		})
+
+		reliability := storage.Reliability{
+			Sources:           1,
+			MonitoredMs:       stats.MonitoredMs,
+			DowntimeMs:        stats.DowntimeMs,
+			OutageCount:       stats.OutageCount,
+			PlannedDowntimeMs: stats.PlannedDowntimeMs,
+		}
+		for _, window := range windows {
+			if !window.StartedBefore && !window.Ongoing {
+				reliability.RecoveredCount++
+				reliability.RecoveryMs += window.DurationMs
+			}
+			outages = append(outages, ReportOutage{
+				SourceID:   source.ID,
+				SourceName: source.Name,
+				Start:      window.Start,
+				End:        window.End,
+				DurationMs: window.DurationMs,
+				Ongoing:    window.Ongoing,
+				Planned:    window.Planned,
+				Notes:      am.outageNotes(source, window, notes),
+			})
+		}
+		total.Add(&reliability)
+		for _, tag := range source.Tags {
+			if byTag[tag] == nil {
+				byTag[tag] = &storage.Reliability{}
+			}
+			byTag[tag].Add(&reliability)
+		}
+	}
+
+	summary.UptimePercent = total.UptimePercent
+	summary.DowntimeMs = total.DowntimeMs
+	summary.OutageCount = total.OutageCount
+	summary.MTTRMs = total.MTTRMs
+	summary.MTBFMs = total.MTBFMs
+	summary.PlannedDowntimeMs = total.PlannedDowntimeMs
+	summary.SLAUptimePercent = total.SLAUptimePercent
+
+	tags := make([]ReportTag, 0, len(byTag))
+	for tag, reliability := range byTag {
+		tags = append(tags, ReportTag{
+			Tag:           tag,
+			Sources:       reliability.Sources,
+			UptimePercent: reliability.UptimePercent,
+			OutageCount:   reliability.OutageCount,
+			MTTRMs:        reliability.MTTRMs,
+			MTBFMs:        reliability.MTBFMs,
+		})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+
+	// Lowest uptime first; sources never monitored in the period last
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i].UptimePercent, rows[j].UptimePercent
+		switch {
+		case a == nil || b == nil:
+			return a != nil && b == nil
+		case *a != *b:
+			return *a < *b
+		}
+		return rows[i].Name < rows[j].Name
+	})
+
+	return rows, tags, outages, summary, nil
+}
+
+// outageNotes picks the notes on an outage window from a source's notes. A window clipped to
+// the start of the range is matched by the change that actually started the outage.
+func (am *AppManager) outageNotes(source *storage.Source, window storage.OutageWindow, notes []*storage.IncidentNote) []*storage.IncidentNote {
+	if len(notes) == 0 {
+		return nil
+	}
+	start := window.Start
+	if window.StartedBefore {
+		outage, err := am.storage.FindOutage(source.ID, window.Start)
+		if err != nil {
+			return nil
+		}
+		start = outage.Timestamp
+	}
+
+	var matched []*storage.IncidentNote
+	for _, note := range notes {
+		if note.OutageStart.Equal(start) {
+			matched = append(matched, note)
+		}
+	}
+	return matched
+}
+
+// handleGetReport returns the report for the last completed week or month. Query
+// parameters: tag (only sources with the tag), offset (periods further back, default 0)
+// and format (json, markdown, html or text).
+func (am *AppManager) handleGetReport(c echo.Context) error {
+	period := c.Param("period")
+	if !slices.Contains(config.ReportPeriods, period) {
+		return errorJSON(c, http.StatusBadRequest, "Invalid period (use week or month)")
+	}
+
+	offset := 0
+	if value := c.QueryParam("offset"); value != "" {
+		var err error
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 || offset > maxReportOffset {
+			return errorJSON(c, http.StatusBadRequest, fmt.Sprintf("Invalid offset (0 to %d periods back)", maxReportOffset))
+		}
+	}
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "json"
+	}
+	if !slices.Contains([]string{"json", "markdown", "html", "text"}, format) {
+		return errorJSON(c, http.StatusBadRequest, "Invalid format (use json, markdown, html or text)")
+	}
+
+	report, err := am.buildReport(period, strings.TrimSpace(c.QueryParam("tag")), offset, time.Now())
+	if err != nil {
+		am.log(c).Errorf("Failed to build report: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to build report")
+	}
+
+	switch format {
+	case "markdown":
+		setReportFilename(c, report, "md")
+		return c.Blob(http.StatusOK, "text/markdown; charset=utf-8", []byte(renderReportMarkdown(report)))
+	case "text":
+		setReportFilename(c, report, "txt")
+		return c.Blob(http.StatusOK, echo.MIMETextPlainCharsetUTF8, []byte(renderReportText(report)))
+	case "html":
+		html, err := renderReportHTML(report)
+		if err != nil {
+			am.log(c).Errorf("Failed to render report: %v", err)
+			return errorJSON(c, http.StatusInternalServerError, "Failed to render report")
+		}
+		return c.HTML(http.StatusOK, html)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// setReportFilename offers a downloaded report under a name like report-week-2026-03-02.md
+func setReportFilename(c echo.Context, report *Report, extension string) {
+	name := "report-" + report.Period
+	if report.Tag != "" {
+		name += "-" + report.Tag
+	}
+	name += "-" + report.From.Format("2006-01-02") + "." + extension
+	c.Response().Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+}
+
+// sendScheduledReports sends each configured report once its period has ended: as text to
+// the admin chats and as HTML email to REPORT_EMAIL_TO. The last period sent is stored, so
+// restarts don't repeat a report and a missed one is sent late rather than not at all.
+func (am *AppManager) sendScheduledReports(ctx context.Context, cfg *config.Config, now time.Time) {
+	for _, period := range cfg.ReportPeriods {
+		if !slices.Contains(config.ReportPeriods, period) {
+			continue
+		}
+		from, _ := reportBounds(period, now, 0)
+		sent, err := am.storage.GetReportSent(period)
+		if err != nil {
+			am.logger.Errorf("Failed to read when the %s report was sent: %v", period, err)
+			continue
+		}
+		if !sent.Before(from) {
+			continue
+		}
+
+		report, err := am.buildReport(period, "", 0, now)
+		if err != nil {
+			am.logger.Errorf("Failed to build the %s report: %v", period, err)
+			continue
+		}
+		if !am.deliverReport(ctx, cfg, report) {
+			continue // Retried on the next maintenance run
+		}
+		if err := am.storage.SetReportSent(period, from); err != nil {
+			am.logger.Errorf("Failed to record the %s report as sent: %v", period, err)
+		}
+		am.logger.Printf("Sent the %s report for %s", period, report.From.Format("2006-01-02"))
+	}
+}
+
+// deliverReport sends a report to the admin chats and email recipients. It reports false
+// when email failed, so the report is retried; Telegram failures are only logged, as a
+// retry would repeat the report in the chats that got it.
+func (am *AppManager) deliverReport(ctx context.Context, cfg *config.Config, report *Report) bool {
+	am.alertAdmins(ctx, cfg, truncateTelegram(renderReportText(report)))
+
+	if len(cfg.ReportEmailTo) == 0 {
+		return true
+	}
+	html, err := renderReportHTML(report)
+	if err != nil {
+		am.logger.Errorf("Failed to render the %s report: %v", report.Period, err)
+		return false
+	}
+	err = notifier.SendEmail(notifier.SMTPSettings{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	}, notifier.Email{
+		To:      cfg.ReportEmailTo,
+		Subject: reportTitle(report),
+		Text:    renderReportText(report),
+		HTML:    html,
+	})
+	if err != nil {
+		am.logger.Errorf("Failed to email the %s report: %v", report.Period, err)
+		return false
+	}
+	return true
+}
+
+// telegramMessageLimit is the longest text message Telegram accepts
+const telegramMessageLimit = 4096
+
+// truncateTelegram cuts text to fit a Telegram message at a line boundary
+func truncateTelegram(text string) string {
+	if len(text) <= telegramMessageLimit {
+		return text
+	}
+	const more = "\n… (truncated, see GET /api/v1/reports)"
+	cut := text[:telegramMessageLimit-len(more)]
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + more
+}
@@ -0,0 +1,257 @@
+package appmanager
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// reportTitle names a report, e.g. "Weekly report (api), 2026-03-02 to 2026-03-08"
+func reportTitle(report *Report) string {
+	kind := "Weekly"
+	if report.Period == "month" {
+		kind = "Monthly"
+	}
+	title := kind + " report"
+	if report.Tag != "" {
+		title += " (" + report.Tag + ")"
+	}
+	return title + ", " + report.From.Format("2006-01-02") + " to " + report.To.AddDate(0, 0, -1).Format("2006-01-02")
+}
+
+// reportPercent formats an uptime, or a dash when nothing was monitored
+func reportPercent(uptime *float64) string {
+	if uptime == nil {
+		return "—"
+	}
+	return fmt.Sprintf("%.2f%%", *uptime)
+}
+
+// reportDuration formats milliseconds compactly, e.g. "1d 4h", "3h 12m", "45s"
+func reportDuration(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	switch {
+	case d <= 0:
+		return "0s"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return reportUnits(int(d.Minutes()), "m", int(d.Seconds())%60, "s")
+	case d < 24*time.Hour:
+		return reportUnits(int(d.Hours()), "h", int(d.Minutes())%60, "m")
+	}
+	return reportUnits(int(d.Hours())/24, "d", int(d.Hours())%24, "h")
+}
+
+// reportUnits formats two units, leaving out the smaller one when it is zero
+func reportUnits(major int, majorUnit string, minor int, minorUnit string) string {
+	if minor == 0 {
+		return fmt.Sprintf("%d%s", major, majorUnit)
+	}
+	return fmt.Sprintf("%d%s %d%s", major, majorUnit, minor, minorUnit)
+}
+
+// reportMTTR formats a mean time to recovery or between failures, or a dash when there is none
+func reportMTTR(ms *int64) string {
+	if ms == nil {
+		return "—"
+	}
+	return reportDuration(*ms)
+}
+
+// reportOutageEnd formats when an outage ended
+func reportOutageEnd(outage ReportOutage) string {
+	if outage.Ongoing {
+		return "ongoing"
+	}
+	return outage.End.Format("2006-01-02 15:04")
+}
+
+// reportPlanned describes the SLA-adjusted uptime of a summary, or nothing when there was
+// no planned downtime
+func reportPlanned(summary ReportSummary) string {
+	if summary.PlannedDowntimeMs == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (SLA-adjusted %s, %s planned)", reportPercent(summary.SLAUptimePercent), reportDuration(summary.PlannedDowntimeMs))
+}
+
+// reportOutageDuration formats how long an outage lasted, flagging planned ones
+func reportOutageDuration(outage ReportOutage) string {
+	if outage.Planned {
+		return reportDuration(outage.DurationMs) + " (planned)"
+	}
+	return reportDuration(outage.DurationMs)
+}
+
+// reportNote formats an incident note with its author
+func reportNote(note *storage.IncidentNote) string {
+	return fmt.Sprintf("%s (%s, %s)", note.Text, note.Author, note.CreatedAt.UTC().Format("2006-01-02 15:04"))
+}
+
+// renderReportText renders a report as fixed-width plain text, for Telegram, email and print
+func renderReportText(report *Report) string {
+	var b strings.Builder
+	title := reportTitle(report)
+	fmt.Fprintf(&b, "%s\n%s\n\n", title, strings.Repeat("=", len([]rune(title))))
+	fmt.Fprintf(&b, "Uptime %s%s across %d source(s), %d outage(s), %s downtime, MTTR %s, MTBF %s\n",
+		reportPercent(report.Summary.UptimePercent), reportPlanned(report.Summary), report.Summary.Sources, report.Summary.OutageCount,
+		reportDuration(report.Summary.DowntimeMs), reportMTTR(report.Summary.MTTRMs), reportMTTR(report.Summary.MTBFMs))
+
+	if len(report.Sources) > 0 {
+		b.WriteString("\nSources\n")
+		for _, row := range report.Sources {
+			fmt.Fprintf(&b, "  %-24s %9s  %3d outage(s)  %10s down  MTTR %-8s MTBF %s\n",
+				row.Name, reportPercent(row.UptimePercent), row.OutageCount, reportDuration(row.DowntimeMs), reportMTTR(row.MTTRMs), reportMTTR(row.MTBFMs))
+		}
+	}
+
+	if len(report.Tags) > 0 {
+		b.WriteString("\nTags\n")
+		for _, row := range report.Tags {
+			fmt.Fprintf(&b, "  %-24s %9s  %3d source(s)  %3d outage(s)  MTTR %-8s MTBF %s\n",
+				row.Tag, reportPercent(row.UptimePercent), row.Sources, row.OutageCount, reportMTTR(row.MTTRMs), reportMTTR(row.MTBFMs))
+		}
+	}
+
+	if len(report.TopOutages) > 0 {
+		b.WriteString("\nLongest outages\n")
+		for _, outage := range report.TopOutages {
+			fmt.Fprintf(&b, "  %-24s %s to %-16s %s\n",
+				outage.SourceName, outage.Start.Format("2006-01-02 15:04"), reportOutageEnd(outage), reportOutageDuration(outage))
+			for _, note := range outage.Notes {
+				fmt.Fprintf(&b, "      - %s\n", reportNote(note))
+			}
+		}
+	}
+
+	b.WriteString("\nMTTR trend\n")
+	for _, point := range report.MTTRTrend {
+		fmt.Fprintf(&b, "  %s  %9s  %3d outage(s)  MTTR %s\n",
+			point.From.Format("2006-01-02"), reportPercent(point.UptimePercent), point.OutageCount, reportMTTR(point.MTTRMs))
+	}
+	return b.String()
+}
+
+// renderReportMarkdown renders a report as Markdown tables
+func renderReportMarkdown(report *Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", reportTitle(report))
+	fmt.Fprintf(&b, "**Uptime %s**%s across %d source(s): %d outage(s), %s downtime, MTTR %s, MTBF %s.\n",
+		reportPercent(report.Summary.UptimePercent), reportPlanned(report.Summary), report.Summary.Sources, report.Summary.OutageCount,
+		reportDuration(report.Summary.DowntimeMs), reportMTTR(report.Summary.MTTRMs), reportMTTR(report.Summary.MTBFMs))
+
+	b.WriteString("\n## Sources\n\n| Source | Uptime | SLA uptime | Outages | Downtime | MTTR | MTBF | Longest outage |\n|---|---:|---:|---:|---:|---:|---:|---:|\n")
+	for _, row := range report.Sources {
+		fmt.Fprintf(&b, "| %s | %s | %s | %d | %s | %s | %s | %s |\n", markdownCell(row.Name), reportPercent(row.UptimePercent),
+			reportPercent(row.SLAUptimePercent), row.OutageCount, reportDuration(row.DowntimeMs), reportMTTR(row.MTTRMs), reportMTTR(row.MTBFMs), reportDuration(row.LongestOutageMs))
+	}
+
+	if len(report.Tags) > 0 {
+		b.WriteString("\n## Tags\n\n| Tag | Sources | Uptime | Outages | MTTR | MTBF |\n|---|---:|---:|---:|---:|---:|\n")
+		for _, row := range report.Tags {
+			fmt.Fprintf(&b, "| %s | %d | %s | %d | %s | %s |\n", markdownCell(row.Tag), row.Sources,
+				reportPercent(row.UptimePercent), row.OutageCount, reportMTTR(row.MTTRMs), reportMTTR(row.MTBFMs))
+		}
+	}
+
+	if len(report.TopOutages) > 0 {
+		b.WriteString("\n## Longest outages\n\n| Source | Start | End | Duration | Notes |\n|---|---|---|---:|---|\n")
+		for _, outage := range report.TopOutages {
+			notes := make([]string, len(outage.Notes))
+			for i, note := range outage.Notes {
+				notes[i] = markdownCell(reportNote(note))
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", markdownCell(outage.SourceName),
+				outage.Start.Format("2006-01-02 15:04"), reportOutageEnd(outage), reportOutageDuration(outage), strings.Join(notes, "<br>"))
+		}
+	}
+
+	b.WriteString("\n## MTTR trend\n\n| Period | Uptime | Outages | MTTR |\n|---|---:|---:|---:|\n")
+	for _, point := range report.MTTRTrend {
+		fmt.Fprintf(&b, "| %s | %s | %d | %s |\n", point.From.Format("2006-01-02"),
+			reportPercent(point.UptimePercent), point.OutageCount, reportMTTR(point.MTTRMs))
+	}
+	return b.String()
+}
+
+// markdownCell escapes the characters that would break a table cell
+func markdownCell(value string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(value)
+}
+
+// renderReportHTML renders a report as a standalone HTML page, with inline styles so it
+// survives email clients
+func renderReportHTML(report *Report) (string, error) {
+	var buf bytes.Buffer
+	err := reportTemplate.Execute(&buf, struct {
+		Title string
+		*Report
+	}{reportTitle(report), report})
+	return buf.String(), err
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"pct":      reportPercent,
+	"duration": reportDuration,
+	"mttr":     reportMTTR,
+	"ended":    reportOutageEnd,
+	"note":     reportNote,
+	"planned":  reportPlanned,
+	"lasted":   reportOutageDuration,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+</head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', sans-serif; max-width: 760px; margin: 24px auto; padding: 0 16px; color: #1f2328;">
+  <h1 style="font-size: 22px;">{{.Title}}</h1>
+  <p><strong>Uptime {{pct .Summary.UptimePercent}}</strong>{{planned .Summary}} across {{.Summary.Sources}} source(s): {{.Summary.OutageCount}} outage(s), {{duration .Summary.DowntimeMs}} downtime, MTTR {{mttr .Summary.MTTRMs}}, MTBF {{mttr .Summary.MTBFMs}}.</p>
+
+  <h2 style="font-size: 18px;">Sources</h2>
+  <table style="border-collapse: collapse; width: 100%;">
+    <tr style="text-align: left; border-bottom: 1px solid #d0d7de;"><th>Source</th><th>Uptime</th><th>SLA uptime</th><th>Outages</th><th>Downtime</th><th>MTTR</th><th>MTBF</th><th>Longest</th></tr>
+    {{range .Sources}}
+    <tr style="border-bottom: 1px solid #eaeef2;"><td>{{.Name}}</td><td>{{pct .UptimePercent}}</td><td>{{pct .SLAUptimePercent}}</td><td>{{.OutageCount}}</td><td>{{duration .DowntimeMs}}</td><td>{{mttr .MTTRMs}}</td><td>{{mttr .MTBFMs}}</td><td>{{duration .LongestOutageMs}}</td></tr>
+    {{else}}
+    <tr><td colspan="8">No sources.</td></tr>
+    {{end}}
+  </table>
+
+  {{if .Tags}}
+  <h2 style="font-size: 18px;">Tags</h2>
+  <table style="border-collapse: collapse; width: 100%;">
+    <tr style="text-align: left; border-bottom: 1px solid #d0d7de;"><th>Tag</th><th>Sources</th><th>Uptime</th><th>Outages</th><th>MTTR</th><th>MTBF</th></tr>
+    {{range .Tags}}
+    <tr style="border-bottom: 1px solid #eaeef2;"><td>{{.Tag}}</td><td>{{.Sources}}</td><td>{{pct .UptimePercent}}</td><td>{{.OutageCount}}</td><td>{{mttr .MTTRMs}}</td><td>{{mttr .MTBFMs}}</td></tr>
+    {{end}}
+  </table>
+  {{end}}
+
+  {{if .TopOutages}}
+  <h2 style="font-size: 18px;">Longest outages</h2>
+  <table style="border-collapse: collapse; width: 100%;">
+    <tr style="text-align: left; border-bottom: 1px solid #d0d7de;"><th>Source</th><th>Start</th><th>End</th><th>Duration</th><th>Notes</th></tr>
+    {{range .TopOutages}}
+    <tr style="border-bottom: 1px solid #eaeef2;"><td>{{.SourceName}}</td><td>{{.Start.Format "2006-01-02 15:04"}}</td><td>{{ended .}}</td><td>{{lasted .}}</td><td>{{range $i, $note := .Notes}}{{if $i}}<br>{{end}}{{note $note}}{{end}}</td></tr>
+    {{end}}
+  </table>
+  {{end}}
+
+  <h2 style="font-size: 18px;">MTTR trend</h2>
+  <table style="border-collapse: collapse; width: 100%;">
+    <tr style="text-align: left; border-bottom: 1px solid #d0d7de;"><th>Period</th><th>Uptime</th><th>Outages</th><th>MTTR</th></tr>
+    {{range .MTTRTrend}}
+    <tr style="border-bottom: 1px solid #eaeef2;"><td>{{.From.Format "2006-01-02"}}</td><td>{{pct .UptimePercent}}</td><td>{{.OutageCount}}</td><td>{{mttr .MTTRMs}}</td></tr>
+    {{end}}
+  </table>
+
+  <p style="color: #656d76; font-size: 12px;">Generated {{.GeneratedAt.UTC.Format "2006-01-02 15:04 MST"}}. Times are UTC.</p>
+</body>
+</html>
+`))
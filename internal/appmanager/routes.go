@@ -0,0 +1,60 @@
+package appmanager
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// apiV1Prefix is the prefix of the versioned JSON API. Public URLs that are handed out or
+// embedded elsewhere (probes, docs, dashboard, status page, badges, heartbeat URLs) are
+// not versioned and stay at the root.
+const apiV1Prefix = "/api/v1"
+
+// legacyRouteName marks the unversioned aliases of /api/v1 routes
+const legacyRouteName = "legacy-alias"
+
+// apiRoutes registers each route under /api/v1 and, until clients have migrated, as a
+// deprecated alias at its old unversioned path
+type apiRoutes struct {
+	echo *echo.Echo
+}
+
+func (r apiRoutes) add(method, path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	r.echo.Add(method, apiV1Prefix+path, h, m...)
+	alias := r.echo.Add(method, path, h, append([]echo.MiddlewareFunc{deprecatedAliasMiddleware}, m...)...)
+	alias.Name = legacyRouteName
+}
+
+func (r apiRoutes) GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	r.add(http.MethodGet, path, h, m...)
+}
+
+func (r apiRoutes) POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	r.add(http.MethodPost, path, h, m...)
+}
+
+func (r apiRoutes) PUT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	r.add(http.MethodPut, path, h, m...)
+}
+
+func (r apiRoutes) DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	r.add(http.MethodDelete, path, h, m...)
+}
+
+// deprecatedAliasMiddleware points clients of an unversioned alias to its /api/v1 path
+func deprecatedAliasMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Response().Header()
+		header.Set("Deprecation", "true")
+		header.Set("Link", "<"+apiV1Prefix+c.Request().URL.Path+`>; rel="successor-version"`)
+		return next(c)
+	}
+}
+
+// apiRoute returns the matched route without the version prefix, so route-based rules
+// (auth, scopes, compression) apply to /api/v1 paths and their aliases alike
+func apiRoute(c echo.Context) string {
+	return strings.TrimPrefix(c.Path(), apiV1Prefix)
+}
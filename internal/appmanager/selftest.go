@@ -0,0 +1,205 @@
+package appmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram/bot"
+	"github.com/labstack/echo/v4"
+	probing "github.com/prometheus-community/pro-bing"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/storage"
+)
+
+// Self-test check statuses
+const (
+	SelfTestPass = "pass"
+	SelfTestFail = "fail"
+	SelfTestSkip = "skip" // Not applicable to this deployment, e.g. Telegram in web-only mode
+)
+
+// selfTestHTTPURL is requested to verify outbound HTTPS; any response passes
+var selfTestHTTPURL = "https://api.telegram.org"
+
+// selfTestPingTarget is pinged to verify ICMP sockets can be opened
+const selfTestPingTarget = "127.0.0.1"
+
+// SelfTestCheck is the result of one self-test check
+type SelfTestCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"` // pass, fail or skip
+	Detail     string `json:"detail,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// SelfTestReport summarizes a self-test run
+type SelfTestReport struct {
+	Passed bool            `json:"passed"` // No check failed
+	Checks []SelfTestCheck `json:"checks"`
+}
+
+// RunSelfTest verifies what the monitor depends on: a writable database, a valid Telegram
+// token, outbound HTTP, ICMP sockets for ping checks and reachable webhook sinks
+func RunSelfTest(ctx context.Context, db *storage.BoltDB, cfg *config.Config) *SelfTestReport {
+	report := &SelfTestReport{Passed: true}
+	run := func(name string, check func() (string, string)) {
+		start := time.Now()
+		status, detail := check()
+		report.Checks = append(report.Checks, SelfTestCheck{
+			Name:       name,
+			Status:     status,
+			Detail:     detail,
+			DurationMs: time.Since(start).Milliseconds(),
+		})
+		if status == SelfTestFail {
+			report.Passed = false
+		}
+	}
+
+	run("storage", func() (string, string) {
+		if err := db.PingWrite(); err != nil {
+			return SelfTestFail, err.Error()
+		}
+		return SelfTestPass, "database is writable"
+	})
+	run("telegram", func() (string, string) { return selfTestTelegram(ctx, cfg) })
+	run("http", func() (string, string) { return selfTestHTTP(ctx, cfg) })
+	run("icmp", func() (string, string) { return selfTestICMP(cfg) })
+	run("webhooks", func() (string, string) { return selfTestWebhooks(ctx, db, cfg) })
+
+	return report
+}
+
+// selfTestTelegram validates the token with getMe
+func selfTestTelegram(ctx context.Context, cfg *config.Config) (string, string) {
+	if cfg.TelegramToken == "" || cfg.TelegramToken == "your_bot_token_here" {
+		return SelfTestSkip, "TELEGRAM_TOKEN not set (web-only mode)"
+	}
+
+	username, err := CheckTelegramToken(ctx, cfg.TelegramToken, cfg.HTTPTimeout)
+	if err != nil {
+		return SelfTestFail, err.Error()
+	}
+	return SelfTestPass, "bot @" + username
+}
+
+// CheckTelegramToken calls getMe with token and returns the bot's username
+func CheckTelegramToken(ctx context.Context, token string, timeout time.Duration) (string, error) {
+	b, err := tgbot.New(token, tgbot.WithSkipGetMe())
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	me, err := b.GetMe(ctx)
+	if err != nil {
+		return "", fmt.Errorf("getMe failed: %w", err)
+	}
+	return me.Username, nil
+}
+
+// selfTestHTTP makes an outbound HTTPS request, as HTTP checks and webhooks do
+func selfTestHTTP(ctx context.Context, cfg *config.Config) (string, string) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.HTTPTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, selfTestHTTPURL, nil)
+	if err != nil {
+		return SelfTestFail, err.Error()
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return SelfTestFail, err.Error()
+	}
+	resp.Body.Close()
+	return SelfTestPass, fmt.Sprintf("%s answered %d", selfTestHTTPURL, resp.StatusCode)
+}
+
+// selfTestICMP pings localhost the way ping checks do, which on Linux needs CAP_NET_RAW
+func selfTestICMP(cfg *config.Config) (string, string) {
+	pinger, err := probing.NewPinger(selfTestPingTarget)
+	if err != nil {
+		return SelfTestFail, err.Error()
+	}
+	pinger.Count = 1
+	pinger.Timeout = cfg.PingTimeout
+	pinger.SetPrivileged(runtime.GOOS != "darwin")
+
+	if err := pinger.Run(); err != nil {
+		if errors.Is(err, os.ErrPermission) && runtime.GOOS == "linux" {
+			binary, _ := os.Executable()
+			return SelfTestFail, fmt.Sprintf("%v: ping checks need CAP_NET_RAW, run `sudo setcap cap_net_raw+ep %s` (Docker: --cap-add=NET_RAW)", err, binary)
+		}
+		return SelfTestFail, err.Error()
+	}
+	if pinger.Statistics().PacketsRecv == 0 {
+		return SelfTestFail, "no reply from " + selfTestPingTarget
+	}
+	return SelfTestPass, "ICMP sockets available"
+}
+
+// selfTestWebhooks connects to each enabled webhook's host without sending a notification
+func selfTestWebhooks(ctx context.Context, db *storage.BoltDB, cfg *config.Config) (string, string) {
+	webhooks, err := db.ListWebhooks()
+	if err != nil {
+		return SelfTestFail, err.Error()
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.HTTPTimeout}
+	checked := 0
+	var failures []string
+	for _, webhook := range webhooks {
+		if !webhook.Enabled {
+			continue
+		}
+		checked++
+		u, err := url.Parse(webhook.URL)
+		if err != nil || u.Hostname() == "" {
+			failures = append(failures, fmt.Sprintf("%s: invalid URL", webhook.Name))
+			continue
+		}
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", webhook.Name, err))
+			continue
+		}
+		conn.Close()
+	}
+
+	switch {
+	case checked == 0:
+		return SelfTestSkip, "no enabled webhooks"
+	case len(failures) > 0:
+		return SelfTestFail, fmt.Sprintf("%d of %d unreachable: %s", len(failures), checked, strings.Join(failures, "; "))
+	default:
+		return SelfTestPass, fmt.Sprintf("%d reachable", checked)
+	}
+}
+
+// handleSelfTest runs the self-test against the current config
+func (am *AppManager) handleSelfTest(c echo.Context) error {
+	cfg, err := am.configManager.AsConfig()
+	if err != nil {
+		return errorJSON(c, http.StatusInternalServerError, err.Error())
+	}
+
+	report := RunSelfTest(c.Request().Context(), am.storage, cfg)
+	am.log(c).Printf("Self-test requested via API: passed=%t", report.Passed)
+	return c.JSON(http.StatusOK, report)
+}
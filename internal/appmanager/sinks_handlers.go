@@ -0,0 +1,323 @@
+package appmanager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/notifier"
+	"tg-monitor-bot/internal/storage"
+)
+
+// CreateSinkRequest is the request body for creating a sink of any type. Name and namespace
+// apply to every type; chat_id only to telegram sinks, the other webhook fields only to
+// webhook sinks.
+type CreateSinkRequest struct {
+	Type   string `json:"type"`              // telegram or webhook
+	ChatID int64  `json:"chat_id,omitempty"` // telegram
+	CreateWebhookRequest
+}
+
+// UpdateSinkRequest is the request body for updating a sink; omitted fields are left unchanged.
+// Telegram sinks only have a name and a namespace.
+type UpdateSinkRequest struct {
+	UpdateWebhookRequest
+}
+
+// SinkResponse is a sink with the sources linked to it
+type SinkResponse struct {
+	*storage.Sink
+	SourceIDs []string `json:"source_ids"`
+}
+
+// handleGetSinks lists the sinks of every type, or of ?type=, limited to the key's namespace
+// or ?namespace= if given
+func (am *AppManager) handleGetSinks(c echo.Context) error {
+	all, err := am.storage.ListSinks(c.QueryParam("type"))
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	namespace, filtered := namespaceFilter(c)
+	sinks := []*storage.Sink{}
+	for _, sink := range all {
+		if !filtered || sink.Namespace == namespace {
+			sinks = append(sinks, sink)
+		}
+	}
+	return c.JSON(http.StatusOK, sinks)
+}
+
+// handleGetSink returns a sink with the IDs of the sources it notifies
+func (am *AppManager) handleGetSink(c echo.Context) error {
+	sink, err := am.storage.GetSink(c.Param("sink_id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Sink not found")
+	}
+
+	sourceIDs, err := am.storage.GetSinkSources(sink.ID)
+	if err != nil {
+		am.log(c).Errorf("Failed to get sources of sink %s: %v", sink.ID, err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get sink")
+	}
+	if sourceIDs == nil {
+		sourceIDs = []string{}
+	}
+	return c.JSON(http.StatusOK, SinkResponse{Sink: sink, SourceIDs: sourceIDs})
+}
+
+// handleCreateSink creates a sink in the registry of its type
+func (am *AppManager) handleCreateSink(c echo.Context) error {
+	var req CreateSinkRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	var sink *storage.Sink
+	switch req.Type {
+	case storage.SinkTypeTelegram:
+		if req.URL != "" || req.Method != "" || len(req.Headers) > 0 || req.Format != "" {
+			return errorJSON(c, http.StatusBadRequest, "url, method, headers and format only apply to webhook sinks")
+		}
+		chat, err := am.chatFromRequest(c, AddTelegramChatRequest{ChatID: req.ChatID, Name: req.Name, Namespace: req.Namespace})
+		if errors.Is(err, errChatRegistered) {
+			return errorJSON(c, http.StatusConflict, err.Error())
+		}
+		if err != nil {
+			return errorJSON(c, http.StatusBadRequest, err.Error())
+		}
+		if err := am.storage.SaveChat(chat); err != nil {
+			am.log(c).Errorf("Failed to save chat: %v", err)
+			return errorJSON(c, http.StatusInternalServerError, "Failed to create sink")
+		}
+		sink = chat.Sink()
+
+	case storage.SinkTypeWebhook:
+		if req.ChatID != 0 {
+			return errorJSON(c, http.StatusBadRequest, "chat_id only applies to telegram sinks")
+		}
+		webhook, err := am.webhookFromCreateRequest(c, req.CreateWebhookRequest)
+		if err != nil {
+			return errorJSON(c, http.StatusBadRequest, err.Error())
+		}
+		if err := am.storage.SaveWebhook(webhook); err != nil {
+			am.log(c).Errorf("Failed to create webhook: %v", err)
+			return errorJSON(c, http.StatusInternalServerError, "Failed to create sink")
+		}
+		sink = webhook.Sink()
+
+	default:
+		return errorJSON(c, http.StatusBadRequest, fmt.Sprintf("type must be one of %s", strings.Join(storage.SinkTypes(), ", ")))
+	}
+
+	am.log(c).Printf("Sink %s created by %s", sink.ID, authKeyName(c))
+	return c.JSON(http.StatusCreated, sink)
+}
+
+// handleUpdateSink updates a sink in the registry of its type
+func (am *AppManager) handleUpdateSink(c echo.Context) error {
+	sink, err := am.storage.GetSink(c.Param("sink_id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Sink not found")
+	}
+
+	var req UpdateSinkRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	switch sink.Type {
+	case storage.SinkTypeTelegram:
+		if req.URL != nil || req.Method != nil || len(req.Headers) > 0 || req.Enabled != nil || req.Format != nil {
+			return errorJSON(c, http.StatusBadRequest, "url, method, headers, format and enabled only apply to webhook sinks")
+		}
+		chatID, _ := strconv.ParseInt(sink.Key, 10, 64)
+		chat, err := am.storage.GetChat(chatID)
+		if err != nil {
+			return errorJSON(c, http.StatusNotFound, "Sink not found")
+		}
+		if req.Namespace != nil {
+			if chat.Namespace, err = am.resolveNamespace(c, *req.Namespace); err != nil {
+				return errorJSON(c, http.StatusBadRequest, err.Error())
+			}
+		}
+		if req.Name != nil {
+			chat.Name = *req.Name
+		}
+		if err := am.storage.SaveChat(chat); err != nil {
+			am.log(c).Errorf("Failed to update chat: %v", err)
+			return errorJSON(c, http.StatusInternalServerError, "Failed to update sink")
+		}
+		sink = chat.Sink()
+
+	case storage.SinkTypeWebhook:
+		webhook, err := am.storage.GetWebhook(sink.Key)
+		if err != nil {
+			return errorJSON(c, http.StatusNotFound, "Sink not found")
+		}
+		if err := am.applyWebhookUpdate(c, webhook, req.UpdateWebhookRequest); err != nil {
+			return errorJSON(c, http.StatusBadRequest, err.Error())
+		}
+		if err := am.storage.SaveWebhook(webhook); err != nil {
+			am.log(c).Errorf("Failed to update webhook: %v", err)
+			return errorJSON(c, http.StatusInternalServerError, "Failed to update sink")
+		}
+		sink = webhook.Sink()
+	}
+
+	return c.JSON(http.StatusOK, sink)
+}
+
+// handleDeleteSink deletes a sink together with its links to sources
+func (am *AppManager) handleDeleteSink(c echo.Context) error {
+	sink, err := am.storage.GetSink(c.Param("sink_id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Sink not found")
+	}
+
+	if err := am.storage.DeleteSink(sink.ID); err != nil {
+		am.log(c).Errorf("Failed to delete sink %s: %v", sink.ID, err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to delete sink")
+	}
+
+	am.log(c).Printf("Sink %s deleted by %s", sink.ID, authKeyName(c))
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Sink deleted",
+		"id":      sink.ID,
+	})
+}
+
+// handleGetSourceSinks returns the sinks of every type linked to a source
+func (am *AppManager) handleGetSourceSinks(c echo.Context) error {
+	sourceID := c.Param("source_id")
+	if _, err := am.storage.GetSource(sourceID); err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	sinks, err := am.storage.GetSourceSinks(sourceID)
+	if err != nil {
+		am.log(c).Errorf("Failed to get source sinks: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get source sinks")
+	}
+	return c.JSON(http.StatusOK, sinks)
+}
+
+// handleAddSourceSink links a sink to a source
+func (am *AppManager) handleAddSourceSink(c echo.Context) error {
+	sourceID := c.Param("source_id")
+	if _, err := am.storage.GetSource(sourceID); err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+	sink, err := am.storage.GetSink(c.Param("sink_id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Sink not found")
+	}
+
+	if err := am.storage.AddSourceSink(sourceID, sink.ID); err != nil {
+		am.log(c).Errorf("Failed to add source sink: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to add sink to source")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]string{
+		"message":   "Sink added to source",
+		"source_id": sourceID,
+		"sink_id":   sink.ID,
+	})
+}
+
+// handleRemoveSourceSink unlinks a sink from a source
+func (am *AppManager) handleRemoveSourceSink(c echo.Context) error {
+	sourceID := c.Param("source_id")
+	sinkID := c.Param("sink_id")
+	if _, _, err := storage.ParseSinkID(sinkID); err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := am.storage.RemoveSourceSink(sourceID, sinkID); err != nil {
+		am.log(c).Errorf("Failed to remove source sink: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to remove sink from source")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message":   "Sink removed from source",
+		"source_id": sourceID,
+		"sink_id":   sinkID,
+	})
+}
+
+// TestAllSinksRequest is the request body for POST /test/all
+type TestAllSinksRequest struct {
+	SourceID string `json:"source_id"`
+}
+
+// TestAllSinksResponse reports a test notification per sink of a source; when any delivery
+// failed it is returned with status 502
+type TestAllSinksResponse struct {
+	SourceID   string                `json:"source_id"`
+	SourceName string                `json:"source_name"`
+	Sent       int                   `json:"sent"`
+	Failed     int                   `json:"failed"`
+	Skipped    int                   `json:"skipped"`
+	Results    []notifier.TestResult `json:"results"`
+	SentAt     time.Time             `json:"sent_at"`
+}
+
+// handleTestAllSinks sends a test outage notification through every sink of a source, so
+// routing can be checked before a real outage. Telegram sinks are skipped while no bot runs.
+func (am *AppManager) handleTestAllSinks(c echo.Context) error {
+	var req TestAllSinksRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+	if req.SourceID == "" {
+		return errorJSON(c, http.StatusBadRequest, "source_id is required")
+	}
+
+	// The source is in the body, so namespaceRouteError doesn't cover it
+	source, err := am.storage.GetSource(req.SourceID)
+	if err != nil || !namespaceAllows(c, source.Namespace) {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+
+	// Fall back to webhooks only when the bot isn't running
+	dispatcher := am.botProcess.GetDispatcher()
+	if dispatcher == nil {
+		dispatcher = notifier.NewDispatcher(am.storage)
+		dispatcher.SetSender(storage.SinkTypeWebhook, notifier.NewWebhookNotifier(am.storage))
+	}
+
+	results, err := dispatcher.Test(c.Request().Context(), source)
+	if err != nil {
+		am.log(c).Errorf("Failed to test sinks of source %s: %v", source.ID, err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to test sinks")
+	}
+
+	response := TestAllSinksResponse{
+		SourceID:   source.ID,
+		SourceName: source.Name,
+		Results:    results,
+		SentAt:     time.Now(),
+	}
+	for _, result := range results {
+		switch {
+		case result.Skipped != "":
+			response.Skipped++
+		case result.Success:
+			response.Sent++
+		default:
+			response.Failed++
+		}
+	}
+
+	am.log(c).Printf("Sent test notifications for %s: %d sent, %d failed, %d skipped",
+		source.Name, response.Sent, response.Failed, response.Skipped)
+	if response.Failed > 0 {
+		return c.JSON(http.StatusBadGateway, response)
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,129 @@
+package appmanager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/storage"
+)
+
+// sloAlertInterval is how often burn rates are evaluated
+const sloAlertInterval = 5 * time.Minute
+
+// Burn rate alert levels, from least to most urgent
+const (
+	burnNone = iota
+	burnSlow // SLO_SLOW_BURN_RATE exceeded over 6h
+	burnFast // SLO_FAST_BURN_RATE exceeded over 1h
+)
+
+// sloAlertState remembers each source's burn rate alert level, so alerts are sent when it
+// rises and once when it clears, rather than on every evaluation
+type sloAlertState struct {
+	mu     sync.Mutex
+	levels map[string]int // sourceID -> burn level
+}
+
+// runSLOAlerts evaluates burn rates until ctx is cancelled
+func (am *AppManager) runSLOAlerts(ctx context.Context) {
+	ticker := time.NewTicker(sloAlertInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if cfg, err := am.configManager.AsConfig(); err == nil {
+				am.checkSLOBurnRates(ctx, cfg, time.Now())
+			}
+		}
+	}
+}
+
+// burnLevel classifies a budget's burn rates against the configured thresholds
+func burnLevel(cfg *config.Config, budget *storage.ErrorBudget) int {
+	switch {
+	case cfg.SLOFastBurnRate > 0 && budget.BurnRate1h >= cfg.SLOFastBurnRate:
+		return burnFast
+	case cfg.SLOSlowBurnRate > 0 && budget.BurnRate6h >= cfg.SLOSlowBurnRate:
+		return burnSlow
+	}
+	return burnNone
+}
+
+// checkSLOBurnRates alerts a source's chats when its error budget starts burning faster
+// than allowed, and once more when the burn rate is back below the thresholds
+func (am *AppManager) checkSLOBurnRates(ctx context.Context, cfg *config.Config, now time.Time) {
+	budgets, err := am.errorBudgets(now)
+	if err != nil {
+		am.logger.Errorf("Failed to compute error budgets: %v", err)
+		return
+	}
+
+	am.sloAlerts.mu.Lock()
+	previous := am.sloAlerts.levels
+	am.sloAlerts.levels = make(map[string]int, len(budgets))
+	var alerts []*storage.ErrorBudget
+	var cleared []*storage.ErrorBudget
+	for _, budget := range budgets {
+		level := burnLevel(cfg, budget)
+		if level != burnNone {
+			am.sloAlerts.levels[budget.SourceID] = level
+		}
+		switch {
+		case level > previous[budget.SourceID]:
+			alerts = append(alerts, budget)
+		case level == burnNone && previous[budget.SourceID] != burnNone:
+			cleared = append(cleared, budget)
+		}
+	}
+	am.sloAlerts.mu.Unlock()
+
+	for _, budget := range alerts {
+		window, rate, threshold := "hour", budget.BurnRate1h, cfg.SLOFastBurnRate
+		if burnLevel(cfg, budget) == burnSlow {
+			window, rate, threshold = "6 hours", budget.BurnRate6h, cfg.SLOSlowBurnRate
+		}
+		am.logger.Warnf("SLO burn rate of %s is %.1fx over the last %s (threshold %gx)", budget.SourceName, rate, window, threshold)
+		am.alertSourceChats(ctx, budget.SourceID, fmt.Sprintf(
+			"🔥 %s is burning its error budget %.1fx too fast (last %s).\nSLO %g%% over %d days, %s of the budget left.",
+			budget.SourceName, rate, window, budget.Target, budget.WindowDays, formatBudgetRemaining(budget)))
+	}
+	for _, budget := range cleared {
+		am.logger.Printf("SLO burn rate of %s is back to normal", budget.SourceName)
+		am.alertSourceChats(ctx, budget.SourceID, fmt.Sprintf(
+			"✅ %s error budget burn rate is back to normal, %s of the budget left.",
+			budget.SourceName, formatBudgetRemaining(budget)))
+	}
+}
+
+// formatBudgetRemaining renders the remaining share of an error budget
+func formatBudgetRemaining(budget *storage.ErrorBudget) string {
+	if budget.RemainingPercent == nil {
+		return "all"
+	}
+	if *budget.RemainingPercent <= 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%.1f%%", *budget.RemainingPercent)
+}
+
+// alertSourceChats sends text to the Telegram chats that get a source's notifications
+func (am *AppManager) alertSourceChats(ctx context.Context, sourceID, text string) {
+	telegramBot := am.botProcess.GetBot()
+	if telegramBot == nil {
+		return
+	}
+	chatIDs, err := am.storage.GetSourceChats(sourceID)
+	if err != nil {
+		am.logger.Errorf("Failed to get chats for source %s: %v", sourceID, err)
+		return
+	}
+	for _, chatID := range chatIDs {
+		telegramBot.SendAlert(ctx, chatID, text)
+	}
+}
@@ -0,0 +1,66 @@
+package appmanager
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// handleGetSourceSLO returns the error budget of a source's SLO
+func (am *AppManager) handleGetSourceSLO(c echo.Context) error {
+	source, err := am.getActiveSource(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+	if source.SLO == nil {
+		return errorJSON(c, http.StatusNotFound, "Source has no SLO")
+	}
+
+	budget, err := am.storage.ComputeErrorBudget(source, time.Now())
+	if err != nil {
+		am.log(c).Errorf("Failed to compute error budget: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to compute error budget")
+	}
+
+	return c.JSON(http.StatusOK, budget)
+}
+
+// handleGetSLOs returns the error budgets of all sources with an SLO, least budget first
+func (am *AppManager) handleGetSLOs(c echo.Context) error {
+	budgets, err := am.errorBudgets(time.Now())
+	if err != nil {
+		am.log(c).Errorf("Failed to compute error budgets: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to compute error budgets")
+	}
+
+	return c.JSON(http.StatusOK, budgets)
+}
+
+// errorBudgets computes the error budget of every source with an SLO, least budget first
+func (am *AppManager) errorBudgets(now time.Time) ([]*storage.ErrorBudget, error) {
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		return nil, err
+	}
+
+	budgets := []*storage.ErrorBudget{}
+	for _, source := range sources {
+		if source.SLO == nil {
+			continue
+		}
+		budget, err := am.storage.ComputeErrorBudget(source, now)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+	sort.SliceStable(budgets, func(i, j int) bool {
+		a, b := budgets[i].RemainingPercent, budgets[j].RemainingPercent
+		return a != nil && (b == nil || *a < *b)
+	})
+	return budgets, nil
+}
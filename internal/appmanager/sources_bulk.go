@@ -0,0 +1,253 @@
+package appmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// maxBulkOperations caps the size of a single POST /sources/bulk request
+const maxBulkOperations = 500
+
+// BulkSourceRequest is the request body for POST /sources/bulk
+type BulkSourceRequest struct {
+	// Atomic applies all operations or none; otherwise valid operations are applied and failures reported per item
+	Atomic     bool                  `json:"atomic"`
+	Operations []BulkSourceOperation `json:"operations"`
+}
+
+// BulkSourceOperation is one create, update or delete in a bulk request
+type BulkSourceOperation struct {
+	Op     string          `json:"op"`               // "create", "update" or "delete"
+	ID     string          `json:"id,omitempty"`     // update and delete
+	Source json.RawMessage `json:"source,omitempty"` // create: CreateSourceRequest, update: UpdateSourceRequest
+}
+
+// BulkSourceResult reports the outcome of one operation, in request order
+type BulkSourceResult struct {
+	Index  int             `json:"index"`
+	Op     string          `json:"op"`
+	ID     string          `json:"id,omitempty"`
+	Status string          `json:"status"` // "applied", "failed" or "skipped" (atomic batch rejected)
+	Error  string          `json:"error,omitempty"`
+	Source *storage.Source `json:"source,omitempty"`
+}
+
+// BulkSourceResponse is the response body for POST /sources/bulk
+type BulkSourceResponse struct {
+	Applied int                `json:"applied"`
+	Failed  int                `json:"failed"`
+	Results []BulkSourceResult `json:"results"`
+}
+
+// bulkChange is a validated operation waiting to be written
+type bulkChange struct {
+	op       string
+	source   *storage.Source
+	template *storage.SourceTemplate // create from a template: sinks to attach once saved
+}
+
+// handleBulkSources applies many source creates/updates/deletes in one database transaction
+// and reconciles the monitor once afterwards, instead of one API call and restart per source
+func (am *AppManager) handleBulkSources(c echo.Context) error {
+	var req BulkSourceRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+	if len(req.Operations) == 0 {
+		return errorJSON(c, http.StatusBadRequest, "operations must not be empty")
+	}
+	if len(req.Operations) > maxBulkOperations {
+		return errorJSON(c, http.StatusBadRequest, fmt.Sprintf("At most %d operations per request", maxBulkOperations))
+	}
+
+	results := make([]BulkSourceResult, len(req.Operations))
+	changes := make([]*bulkChange, len(req.Operations))
+	seen := make(map[string]bool)
+	failed := 0
+
+	for i, op := range req.Operations {
+		results[i] = BulkSourceResult{Index: i, Op: op.Op, ID: op.ID}
+		if op.ID != "" && seen[op.ID] {
+			results[i].Status, results[i].Error = "failed", "Source appears more than once in this request"
+			failed++
+			continue
+		}
+		seen[op.ID] = true
+
+		change, err := am.prepareBulkChange(c, op)
+		if err != nil {
+			results[i].Status, results[i].Error = "failed", err.Error()
+			failed++
+			continue
+		}
+		changes[i] = change
+		results[i].ID = change.source.ID
+	}
+
+	if req.Atomic && failed > 0 {
+		for i := range results {
+			if results[i].Status == "" {
+				results[i].Status = "skipped"
+			}
+		}
+		return c.JSON(http.StatusBadRequest, BulkSourceResponse{Failed: failed, Results: results})
+	}
+
+	var toSave []*storage.Source
+	for _, change := range changes {
+		if change != nil {
+			toSave = append(toSave, change.source)
+		}
+	}
+	if len(toSave) > 0 {
+		if err := am.storage.SaveSources(toSave); err != nil {
+			return errorJSON(c, http.StatusInternalServerError, err.Error())
+		}
+	}
+	for _, change := range changes {
+		if change == nil || change.template == nil {
+			continue
+		}
+		if err := am.storage.AttachTemplateSinks(change.template, change.source.ID); err != nil {
+			am.log(c).Errorf("Failed to attach template sinks to %s: %v", change.source.ID, err)
+		}
+	}
+
+	am.reconcileBulkChanges(changes)
+
+	applied := 0
+	for i, change := range changes {
+		if change == nil {
+			continue
+		}
+		results[i].Status = "applied"
+		if change.op != "delete" {
+			results[i].Source = change.source
+		}
+		applied++
+	}
+
+	am.log(c).Printf("Bulk source operation via API: %d applied, %d failed", applied, failed)
+
+	return c.JSON(http.StatusOK, BulkSourceResponse{Applied: applied, Failed: failed, Results: results})
+}
+
+// prepareBulkChange validates one operation and builds the source as it should be stored
+func (am *AppManager) prepareBulkChange(c echo.Context, op BulkSourceOperation) (*bulkChange, error) {
+	switch op.Op {
+	case "create":
+		var req CreateSourceRequest
+		if err := json.Unmarshal(op.Source, &req); err != nil {
+			return nil, fmt.Errorf("Invalid source: %v", err)
+		}
+		var source *storage.Source
+		var template *storage.SourceTemplate
+		var err error
+		if req.Template != "" {
+			source, template, err = am.sourceFromTemplate(req)
+		} else {
+			source, err = sourceFromCreateRequest(req)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if source.Namespace, err = am.resolveNamespace(c, req.Namespace); err != nil {
+			return nil, err
+		}
+		if err := am.storage.ValidateDependencies(source); err != nil {
+			return nil, err
+		}
+		if source.Type == "webhook" {
+			token, err := am.generateWebhookToken()
+			if err != nil {
+				return nil, fmt.Errorf("Failed to generate webhook token: %v", err)
+			}
+			source.WebhookToken = token
+		}
+		return &bulkChange{op: op.Op, source: source, template: template}, nil
+
+	case "update":
+		source, err := am.getLiveSource(op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !namespaceAllows(c, source.Namespace) {
+			return nil, fmt.Errorf("Source not found")
+		}
+		var req UpdateSourceRequest
+		if err := json.Unmarshal(op.Source, &req); err != nil {
+			return nil, fmt.Errorf("Invalid source: %v", err)
+		}
+		namespace := source.Namespace
+		if req.Namespace != nil {
+			if namespace, err = am.resolveNamespace(c, *req.Namespace); err != nil {
+				return nil, err
+			}
+		}
+		if err := applyUpdateRequest(source, req); err != nil {
+			return nil, err
+		}
+		source.Namespace = namespace
+		if err := am.storage.ValidateDependencies(source); err != nil {
+			return nil, err
+		}
+		return &bulkChange{op: op.Op, source: source}, nil
+
+	case "delete":
+		source, err := am.getLiveSource(op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !namespaceAllows(c, source.Namespace) {
+			return nil, fmt.Errorf("Source not found")
+		}
+		now := time.Now()
+		source.DeletedAt = &now
+		return &bulkChange{op: op.Op, source: source}, nil
+
+	default:
+		return nil, fmt.Errorf("op must be 'create', 'update' or 'delete'")
+	}
+}
+
+// getLiveSource loads a source that is not in trash
+func (am *AppManager) getLiveSource(id string) (*storage.Source, error) {
+	if id == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	source, err := am.storage.GetSource(id)
+	if err != nil || source.IsDeleted() {
+		return nil, fmt.Errorf("Source not found")
+	}
+	return source, nil
+}
+
+// reconcileBulkChanges starts and stops monitor goroutines for the written sources
+func (am *AppManager) reconcileBulkChanges(changes []*bulkChange) {
+	monitor := am.botProcess.GetMonitor()
+	if monitor == nil {
+		return
+	}
+	ctx := am.botProcess.GetContext()
+
+	for _, change := range changes {
+		if change == nil {
+			continue
+		}
+		// Same as single update/delete: stop the old goroutine (paused sources keep one too)
+		if change.op != "create" {
+			monitor.RemoveSource(change.source.ID)
+		}
+		if change.op != "delete" && change.source.Enabled {
+			if err := monitor.AddSource(ctx, change.source); err != nil {
+				am.logger.Warnf("Failed to add source %s to monitor: %v", change.source.ID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package appmanager
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// maxSourcesLimit caps ?limit= on GET /sources
+const maxSourcesLimit = 1000
+
+// sourceQuery holds the filters, ordering and page requested on GET /sources
+type sourceQuery struct {
+	namespace *string // the key's namespace or ?namespace=; nil = all
+	tags      []string
+	typ       string
+	enabled   *bool
+	status    *int // 1 online, 0 offline, -1 unknown
+	sortBy    string
+	desc      bool
+	limit     int // 0 = no limit
+	offset    int
+}
+
+// sourceSorters compare two sources for each supported ?sort= value
+var sourceSorters = map[string]func(a, b *storage.Source) bool{
+	"name":        func(a, b *storage.Source) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) },
+	"status":      func(a, b *storage.Source) bool { return a.CurrentStatus < b.CurrentStatus },
+	"last_change": func(a, b *storage.Source) bool { return a.LastChangeTime.Before(b.LastChangeTime) },
+	"last_check":  func(a, b *storage.Source) bool { return a.LastCheckTime.Before(b.LastCheckTime) },
+	"created":     func(a, b *storage.Source) bool { return a.CreatedAt.Before(b.CreatedAt) },
+}
+
+// parseSourceQuery reads namespace, tag, type, enabled, status, sort, order, limit and offset
+func parseSourceQuery(c echo.Context) (*sourceQuery, error) {
+	q := &sourceQuery{
+		tags:   c.QueryParams()["tag"],
+		typ:    c.QueryParam("type"),
+		sortBy: c.QueryParam("sort"),
+	}
+	if namespace, ok := namespaceFilter(c); ok {
+		q.namespace = &namespace
+	}
+
+	if v := c.QueryParam("enabled"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("enabled must be true or false")
+		}
+		q.enabled = &enabled
+	}
+
+	if v := c.QueryParam("status"); v != "" {
+		status, err := parseStatusFilter(v)
+		if err != nil {
+			return nil, err
+		}
+		q.status = &status
+	}
+
+	if q.sortBy == "" {
+		q.sortBy = "name"
+	}
+	if _, ok := sourceSorters[q.sortBy]; !ok {
+		return nil, fmt.Errorf("sort must be one of name, status, last_change, last_check, created")
+	}
+
+	switch c.QueryParam("order") {
+	case "", "asc":
+	case "desc":
+		q.desc = true
+	default:
+		return nil, fmt.Errorf("order must be 'asc' or 'desc'")
+	}
+
+	if v := c.QueryParam("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxSourcesLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxSourcesLimit)
+		}
+		q.limit = limit
+	}
+	if v := c.QueryParam("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("offset must be a non-negative integer")
+		}
+		q.offset = offset
+	}
+
+	return q, nil
+}
+
+// parseStatusFilter maps online/offline/unknown to CurrentStatus values
+func parseStatusFilter(value string) (int, error) {
+	switch value {
+	case "online":
+		return 1, nil
+	case "offline":
+		return 0, nil
+	case "unknown":
+		return -1, nil
+	}
+	return 0, fmt.Errorf("status must be 'online', 'offline' or 'unknown'")
+}
+
+// apply filters and sorts sources, returning the requested page and the number of matches
+func (q *sourceQuery) apply(sources []*storage.Source) ([]*storage.Source, int) {
+	if len(q.tags) > 0 {
+		sources = filterSourcesByTags(sources, q.tags)
+	}
+
+	filtered := make([]*storage.Source, 0, len(sources))
+	for _, source := range sources {
+		if q.namespace != nil && source.Namespace != *q.namespace {
+			continue
+		}
+		if q.typ != "" && source.Type != q.typ {
+			continue
+		}
+		if q.enabled != nil && source.Enabled != *q.enabled {
+			continue
+		}
+		if q.status != nil && source.CurrentStatus != *q.status {
+			continue
+		}
+		filtered = append(filtered, source)
+	}
+
+	less := sourceSorters[q.sortBy]
+	byName := sourceSorters["name"]
+	sort.SliceStable(filtered, func(i, j int) bool {
+		a, b := filtered[i], filtered[j]
+		if q.desc {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return byName(filtered[i], filtered[j]) // Ties keep a stable, readable order
+	})
+
+	total := len(filtered)
+	if q.offset >= total {
+		return []*storage.Source{}, total
+	}
+	filtered = filtered[q.offset:]
+	if q.limit > 0 && len(filtered) > q.limit {
+		filtered = filtered[:q.limit]
+	}
+	return filtered, total
+}
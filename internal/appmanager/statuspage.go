@@ -0,0 +1,217 @@
+package appmanager
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// statusPageDays is how many completed days of uptime the status page shows
+const statusPageDays = 30
+
+// StatusPage is the public, read-only view of sources marked public
+type StatusPage struct {
+	Title     string             `json:"title"`
+	Status    string             `json:"status"` // "operational", "partial_outage" or "major_outage"
+	UpdatedAt time.Time          `json:"updated_at"`
+	Sources   []StatusPageSource `json:"sources"`
+}
+
+// StatusPageSource is a source as shown on the status page; it deliberately omits targets and IDs
+type StatusPageSource struct {
+	Name   string          `json:"name"`
+	Status string          `json:"status"` // "online", "offline", "paused" or "unknown"
+	Since  time.Time       `json:"since"`
+	Uptime *float64        `json:"uptime_percent,omitempty"` // Over the last statusPageDays completed days
+	Days   []StatusPageDay `json:"days"`
+}
+
+// StatusPageDay is the uptime of one completed UTC day
+type StatusPageDay struct {
+	Date          string  `json:"date"`
+	UptimePercent float64 `json:"uptime_percent"`
+}
+
+// handleStatusPage renders the public status page as HTML
+func (am *AppManager) handleStatusPage(c echo.Context) error {
+	page, err := am.buildStatusPage()
+	if err != nil {
+		return am.statusPageError(c, err)
+	}
+	if page == nil {
+		return c.String(http.StatusNotFound, "Not found")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	c.Response().WriteHeader(http.StatusOK)
+	return statusPageTemplate.Execute(c.Response(), page)
+}
+
+// handleStatusPageJSON returns the public status page as JSON
+func (am *AppManager) handleStatusPageJSON(c echo.Context) error {
+	page, err := am.buildStatusPage()
+	if err != nil {
+		return am.statusPageError(c, err)
+	}
+	if page == nil {
+		return errorJSON(c, http.StatusNotFound, "Status page is disabled")
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// statusPageError logs the cause but returns a generic error to unauthenticated visitors
+func (am *AppManager) statusPageError(c echo.Context, err error) error {
+	am.log(c).Errorf("Failed to build status page: %v", err)
+	return errorJSON(c, http.StatusInternalServerError, "Status page unavailable")
+}
+
+// buildStatusPage collects public sources; it returns nil when the status page is disabled
+func (am *AppManager) buildStatusPage() (*StatusPage, error) {
+	cfg, err := am.configManager.AsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.StatusPageEnabled {
+		return nil, nil
+	}
+
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		return nil, err
+	}
+
+	page := &StatusPage{
+		Title:     cfg.StatusPageTitle,
+		UpdatedAt: time.Now(),
+		Sources:   []StatusPageSource{},
+	}
+
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -statusPageDays)
+	offline, known := 0, 0
+
+	for _, source := range sources {
+		if !source.Public {
+			continue
+		}
+
+		entry := StatusPageSource{
+			Name:   source.Name,
+			Status: publicStatus(source),
+			Since:  source.LastChangeTime,
+			Days:   []StatusPageDay{},
+		}
+
+		rollups, err := am.storage.GetDailyRollups(source.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, rollup := range rollups {
+			entry.Days = append(entry.Days, StatusPageDay{Date: rollup.Date, UptimePercent: rollup.UptimePercent})
+		}
+		if uptime, ok := storage.UptimeFromRollups(rollups); ok {
+			entry.Uptime = &uptime
+		}
+
+		switch entry.Status {
+		case "online":
+			known++
+		case "offline":
+			known++
+			offline++
+		}
+
+		page.Sources = append(page.Sources, entry)
+	}
+
+	switch {
+	case offline == 0:
+		page.Status = "operational"
+	case offline < known:
+		page.Status = "partial_outage"
+	default:
+		page.Status = "major_outage"
+	}
+
+	return page, nil
+}
+
+// publicStatus maps a source's state to the status page vocabulary
+func publicStatus(source *storage.Source) string {
+	switch {
+	case !source.Enabled:
+		return "paused"
+	case source.CurrentStatus == 1:
+		return "online"
+	case source.CurrentStatus == 0:
+		return "offline"
+	default:
+		return "unknown"
+	}
+}
+
+var statusPageTemplate = template.Must(template.New("statuspage").Funcs(template.FuncMap{
+	"pct": func(v *float64) string {
+		if v == nil {
+			return "—"
+		}
+		return fmt.Sprintf("%.2f%%", *v)
+	},
+	"dayColor": func(uptime float64) string {
+		switch {
+		case uptime >= 99.9:
+			return "#2da44e"
+		case uptime >= 95:
+			return "#d4a72c"
+		default:
+			return "#cf222e"
+		}
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <meta http-equiv="refresh" content="60">
+  <title>{{.Title}}</title>
+  <style>
+    body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 760px; margin: 40px auto; padding: 0 16px; color: #1f2328; }
+    .banner { padding: 16px; border-radius: 6px; color: #fff; font-weight: 600; margin-bottom: 24px; }
+    .operational { background: #2da44e; } .partial_outage { background: #d4a72c; } .major_outage { background: #cf222e; }
+    .source { border: 1px solid #d0d7de; border-radius: 6px; padding: 12px 16px; margin-bottom: 12px; }
+    .row { display: flex; justify-content: space-between; }
+    .online { color: #2da44e; } .offline { color: #cf222e; } .paused, .unknown { color: #656d76; }
+    .days { display: flex; gap: 2px; margin-top: 8px; }
+    .day { flex: 1; height: 24px; border-radius: 2px; }
+    footer { color: #656d76; font-size: 12px; margin-top: 24px; }
+  </style>
+</head>
+<body>
+  <h1>{{.Title}}</h1>
+  <div class="banner {{.Status}}">
+    {{if eq .Status "operational"}}All systems operational{{else if eq .Status "partial_outage"}}Partial outage{{else}}Major outage{{end}}
+  </div>
+  {{range .Sources}}
+  <div class="source">
+    <div class="row">
+      <strong>{{.Name}}</strong>
+      <span class="{{.Status}}">{{.Status}}</span>
+    </div>
+    <div class="row"><small>{{pct .Uptime}} uptime (last 30 days)</small></div>
+    <div class="days">
+      {{range .Days}}<div class="day" style="background: {{dayColor .UptimePercent}}" title="{{.Date}}: {{printf "%.2f" .UptimePercent}}%"></div>{{end}}
+    </div>
+  </div>
+  {{else}}
+  <p>No services are listed.</p>
+  {{end}}
+  <footer>Updated {{.UpdatedAt.UTC.Format "2006-01-02 15:04 MST"}}</footer>
+</body>
+</html>
+`))
@@ -0,0 +1,90 @@
+package appmanager
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/logging"
+	"tg-monitor-bot/internal/storage"
+)
+
+// recordSystemEvent appends an application lifecycle event to the system event log.
+// Failures are logged and otherwise ignored: the history is diagnostic, never critical.
+func recordSystemEvent(db *storage.BoltDB, logger *logging.Logger, eventType, message string, details map[string]string) {
+	event := &storage.SystemEvent{Type: eventType, Message: message, Details: details}
+	if err := db.RecordSystemEvent(event); err != nil {
+		logger.Errorf("Failed to record %s system event: %v", eventType, err)
+	}
+}
+
+// recordSystemEvent records a lifecycle event of the application
+func (am *AppManager) recordSystemEvent(eventType, message string, details map[string]string) {
+	if am.isReplica() {
+		return // The copy is read-only; the primary records its own history
+	}
+	recordSystemEvent(am.storage, am.logger, eventType, message, details)
+}
+
+// recordStartup records a startup, noting when the previous run ended without a clean
+// shutdown (crash, OOM kill, power loss) so the gap in monitoring is explained
+func (am *AppManager) recordStartup() {
+	details := map[string]string{
+		"version": am.version,
+		"pid":     strconv.Itoa(os.Getpid()),
+	}
+	message := "Application started"
+
+	last, err := am.storage.GetLastSystemEvent()
+	if err != nil {
+		am.logger.Errorf("Failed to read last system event: %v", err)
+	} else if last != nil && last.Type != storage.SystemEventShutdown {
+		details["unclean_shutdown"] = "true"
+		details["last_event_at"] = last.Timestamp.UTC().Format(time.RFC3339)
+		message = "Application started after an unclean shutdown"
+	}
+
+	am.recordSystemEvent(storage.SystemEventStartup, message, details)
+}
+
+// handleGetSystemEvents returns the application's own history (startups, shutdowns, bot
+// restarts, config changes, panics), newest first.
+// Optional query params: type, from, to (RFC3339 or YYYY-MM-DD), limit.
+func (am *AppManager) handleGetSystemEvents(c echo.Context) error {
+	filter := storage.SystemEventFilter{
+		Type:  c.QueryParam("type"),
+		Limit: 100,
+	}
+
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 1000 {
+			filter.Limit = l
+		}
+	}
+
+	var err error
+	if filter.From, err = parseTimeParam(c.QueryParam("from")); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid from: "+err.Error())
+	}
+	if filter.To, err = parseTimeParam(c.QueryParam("to")); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid to: "+err.Error())
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return errorJSON(c, http.StatusBadRequest, "from must be before to")
+	}
+
+	events, err := am.storage.GetSystemEvents(filter)
+	if err != nil {
+		am.log(c).Errorf("Failed to get system events: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get system events")
+	}
+
+	if events == nil {
+		events = []*storage.SystemEvent{}
+	}
+
+	return c.JSON(http.StatusOK, events)
+}
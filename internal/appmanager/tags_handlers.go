@@ -0,0 +1,128 @@
+package appmanager
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// TagSummary describes one tag in use and how many sources carry it
+type TagSummary struct {
+	Tag     string `json:"tag"`
+	Sources int    `json:"sources"`
+}
+
+// TagActionResponse is returned by tag-scoped pause/resume
+type TagActionResponse struct {
+	Message string   `json:"message"`
+	Tag     string   `json:"tag"`
+	IDs     []string `json:"ids"` // Sources whose state changed
+}
+
+// normalizeTagParam lowercases and trims a tag taken from a URL or query string
+func normalizeTagParam(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// filterSourcesByTags keeps sources that carry every one of tags
+func filterSourcesByTags(sources []*storage.Source, tags []string) []*storage.Source {
+	var filtered []*storage.Source
+	for _, source := range sources {
+		matches := true
+		for _, tag := range tags {
+			if !source.HasTag(normalizeTagParam(tag)) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, source)
+		}
+	}
+	return filtered
+}
+
+// handleGetTags lists the tags in use, alphabetically, with their source counts
+func (am *AppManager) handleGetTags(c echo.Context) error {
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		return errorJSON(c, http.StatusInternalServerError, err.Error())
+	}
+
+	counts := make(map[string]int)
+	for _, source := range sources {
+		for _, tag := range source.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]TagSummary, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagSummary{Tag: tag, Sources: count})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+
+	return c.JSON(http.StatusOK, tags)
+}
+
+// handlePauseTag pauses every source carrying the tag
+func (am *AppManager) handlePauseTag(c echo.Context) error {
+	return am.setTagEnabled(c, false)
+}
+
+// handleResumeTag resumes every source carrying the tag
+func (am *AppManager) handleResumeTag(c echo.Context) error {
+	return am.setTagEnabled(c, true)
+}
+
+// setTagEnabled pauses or resumes the tagged sources that are not already in the requested state
+func (am *AppManager) setTagEnabled(c echo.Context, enabled bool) error {
+	tag := normalizeTagParam(c.Param("tag"))
+
+	monitor := am.botProcess.GetMonitor()
+	if monitor == nil {
+		return errorJSON(c, http.StatusServiceUnavailable, "Monitor not available")
+	}
+
+	sources, err := am.storage.GetAllSources()
+	if err != nil {
+		return errorJSON(c, http.StatusInternalServerError, err.Error())
+	}
+
+	tagged := filterSourcesByTags(sources, []string{tag})
+	if len(tagged) == 0 {
+		return errorJSON(c, http.StatusNotFound, "No sources with tag "+tag)
+	}
+
+	changed := []string{}
+	for _, source := range tagged {
+		if source.Enabled == enabled {
+			continue
+		}
+		if enabled {
+			err = monitor.ResumeSource(source.ID)
+		} else {
+			err = monitor.PauseSource(source.ID)
+		}
+		if err != nil {
+			return errorJSON(c, http.StatusInternalServerError, err.Error())
+		}
+		changed = append(changed, source.ID)
+	}
+
+	action := "paused"
+	if enabled {
+		action = "resumed"
+	}
+	am.log(c).Printf("Tag %s %s via API: %d sources", tag, action, len(changed))
+
+	return c.JSON(http.StatusOK, TagActionResponse{
+		Message: "Sources " + action,
+		Tag:     tag,
+		IDs:     changed,
+	})
+}
@@ -2,6 +2,7 @@ package appmanager
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -9,70 +10,94 @@ import (
 
 	"github.com/labstack/echo/v4"
 
+	"tg-monitor-bot/internal/notifier"
 	"tg-monitor-bot/internal/storage"
 )
 
-// handleGetTelegramChats returns all configured telegram chats from the registry
+// errChatRegistered is returned when a namespaced key adds a chat registered in another namespace
+var errChatRegistered = errors.New("Telegram chat is already registered")
+
+// AddTelegramChatRequest is the request body for adding a chat to the registry
+type AddTelegramChatRequest struct {
+	ChatID    int64  `json:"chat_id"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"` // Scopes the bot in this chat to the namespace; unscoped keys only
+}
+
+// handleGetTelegramChats returns the telegram chats in the registry, of the key's namespace
+// or ?namespace= if given
 func (am *AppManager) handleGetTelegramChats(c echo.Context) error {
-	chats, err := am.storage.ListChats()
+	all, err := am.storage.ListChats()
 	if err != nil {
-		am.logger.Printf("Failed to list chats: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to list telegram chats",
-		})
+		am.log(c).Errorf("Failed to list chats: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to list telegram chats")
 	}
-	if chats == nil {
-		chats = []*storage.Chat{}
+	namespace, filtered := namespaceFilter(c)
+	chats := []*storage.Chat{}
+	for _, chat := range all {
+		if !filtered || chat.Namespace == namespace {
+			chats = append(chats, chat)
+		}
 	}
 	return c.JSON(http.StatusOK, chats)
 }
 
 // handleAddTelegramChat adds a named telegram chat to the registry
 func (am *AppManager) handleAddTelegramChat(c echo.Context) error {
-	var req struct {
-		ChatID int64  `json:"chat_id"`
-		Name   string `json:"name"`
-	}
+	var req AddTelegramChatRequest
 
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
 	}
 
-	if req.ChatID == 0 {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Chat ID is required",
-		})
+	chat, err := am.chatFromRequest(c, req)
+	if errors.Is(err, errChatRegistered) {
+		return errorJSON(c, http.StatusConflict, err.Error())
 	}
-
-	chat := &storage.Chat{
-		ChatID: req.ChatID,
-		Name:   req.Name,
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
 	}
+
 	if err := am.storage.SaveChat(chat); err != nil {
-		am.logger.Printf("Failed to save chat: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to add telegram chat",
-		})
+		am.log(c).Errorf("Failed to save chat: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to add telegram chat")
 	}
 	return c.JSON(http.StatusCreated, chat)
 }
 
+// chatFromRequest validates a request to register a chat and builds the chat in the
+// namespace the key may register it in
+func (am *AppManager) chatFromRequest(c echo.Context, req AddTelegramChatRequest) (*storage.Chat, error) {
+	if req.ChatID == 0 {
+		return nil, fmt.Errorf("Chat ID is required")
+	}
+
+	namespace, err := am.resolveNamespace(c, req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	// A namespaced key must not take over a chat registered elsewhere
+	if existing, err := am.storage.GetChat(req.ChatID); err == nil && !namespaceAllows(c, existing.Namespace) {
+		return nil, errChatRegistered
+	}
+
+	return &storage.Chat{
+		ChatID:    req.ChatID,
+		Name:      req.Name,
+		Namespace: namespace,
+	}, nil
+}
+
 // handleRemoveTelegramChat removes a telegram chat from the registry and all source associations
 func (am *AppManager) handleRemoveTelegramChat(c echo.Context) error {
 	chatIDStr := c.Param("chat_id")
 	chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid chat ID",
-		})
+		return errorJSON(c, http.StatusBadRequest, "Invalid chat ID")
 	}
 	if err := am.storage.DeleteChat(chatID); err != nil {
-		am.logger.Printf("Failed to delete chat: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to remove telegram chat",
-		})
+		am.log(c).Errorf("Failed to delete chat: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to remove telegram chat")
 	}
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "Chat removed",
@@ -83,18 +108,24 @@ func (am *AppManager) handleRemoveTelegramChat(c echo.Context) error {
 func (am *AppManager) handleGetSourceTelegramChats(c echo.Context) error {
 	sourceID := c.Param("source_id")
 	if _, err := am.storage.GetSource(sourceID); err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Source not found",
-		})
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+	chats, err := am.getSourceTelegramChats(sourceID)
+	if err != nil {
+		am.log(c).Errorf("Failed to get source chats: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get source telegram chats")
 	}
+	return c.JSON(http.StatusOK, chats)
+}
+
+// getSourceTelegramChats returns the chats attached to a source; chats unknown to the
+// chats bucket are returned with an empty name
+func (am *AppManager) getSourceTelegramChats(sourceID string) ([]*storage.Chat, error) {
 	chatIDs, err := am.storage.GetSourceChats(sourceID)
 	if err != nil {
-		am.logger.Printf("Failed to get source chats: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get source telegram chats",
-		})
+		return nil, err
 	}
-	var chats []*storage.Chat
+	chats := []*storage.Chat{}
 	for _, id := range chatIDs {
 		chat, err := am.storage.GetChat(id)
 		if err != nil {
@@ -103,10 +134,7 @@ func (am *AppManager) handleGetSourceTelegramChats(c echo.Context) error {
 		}
 		chats = append(chats, chat)
 	}
-	if chats == nil {
-		chats = []*storage.Chat{}
-	}
-	return c.JSON(http.StatusOK, chats)
+	return chats, nil
 }
 
 // handleAddSourceTelegramChat associates a telegram chat with a source
@@ -115,25 +143,17 @@ func (am *AppManager) handleAddSourceTelegramChat(c echo.Context) error {
 	chatIDStr := c.Param("chat_id")
 	chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid chat ID",
-		})
+		return errorJSON(c, http.StatusBadRequest, "Invalid chat ID")
 	}
 	if _, err := am.storage.GetSource(sourceID); err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Source not found",
-		})
+		return errorJSON(c, http.StatusNotFound, "Source not found")
 	}
 	if _, err := am.storage.GetChat(chatID); err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Telegram chat not found. Add the chat in Sinks first.",
-		})
+		return errorJSON(c, http.StatusNotFound, "Telegram chat not found. Add the chat in Sinks first.")
 	}
 	if err := am.storage.AddSourceChat(sourceID, chatID); err != nil {
-		am.logger.Printf("Failed to add source chat: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to add telegram chat to source",
-		})
+		am.log(c).Errorf("Failed to add source chat: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to add telegram chat to source")
 	}
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"message":   "Telegram chat added to source",
@@ -148,15 +168,11 @@ func (am *AppManager) handleRemoveSourceTelegramChat(c echo.Context) error {
 	chatIDStr := c.Param("chat_id")
 	chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid chat ID",
-		})
+		return errorJSON(c, http.StatusBadRequest, "Invalid chat ID")
 	}
 	if err := am.storage.RemoveSourceChat(sourceID, chatID); err != nil {
-		am.logger.Printf("Failed to remove source chat: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to remove telegram chat from source",
-		})
+		am.log(c).Errorf("Failed to remove source chat: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to remove telegram chat from source")
 	}
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message":   "Telegram chat removed from source",
@@ -170,17 +186,13 @@ func (am *AppManager) handleTestTelegramChat(c echo.Context) error {
 	chatIDStr := c.Param("chat_id")
 	chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid chat ID",
-		})
+		return errorJSON(c, http.StatusBadRequest, "Invalid chat ID")
 	}
 
 	// Get the bot instance
 	tgBot := am.botProcess.GetBot()
 	if tgBot == nil {
-		return c.JSON(http.StatusServiceUnavailable, map[string]string{
-			"error": "Telegram bot not available. Check if TELEGRAM_TOKEN is configured.",
-		})
+		return errorJSON(c, http.StatusServiceUnavailable, "Telegram bot not available. Check if TELEGRAM_TOKEN is configured.")
 	}
 
 	// Create a test message
@@ -199,13 +211,11 @@ func (am *AppManager) handleTestTelegramChat(c echo.Context) error {
 	err = tgBot.SendTestMessage(ctx, chatID, testMessage)
 
 	if err != nil {
-		am.logger.Printf("Failed to send test message to chat %d: %v", chatID, err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": fmt.Sprintf("Failed to send test message: %v", err),
-		})
+		am.log(c).Errorf("Failed to send test message to chat %d: %v", chatID, err)
+		return errorJSON(c, http.StatusInternalServerError, fmt.Sprintf("Failed to send test message: %v", err))
 	}
 
-	am.logger.Printf("Sent test notification to Telegram chat %d", chatID)
+	am.log(c).Printf("Sent test notification to Telegram chat %d", chatID)
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message": "Test notification sent successfully",
@@ -214,6 +224,16 @@ func (am *AppManager) handleTestTelegramChat(c echo.Context) error {
 	})
 }
 
+// WebhookTestResponse reports the outcome of a test delivery; failures are returned
+// with status 502 and the same body
+type WebhookTestResponse struct {
+	Message   string `json:"message,omitempty"`
+	WebhookID string `json:"webhook_id"`
+	URL       string `json:"url"`
+	*notifier.DeliveryResult
+	SentAt time.Time `json:"sent_at"`
+}
+
 // handleTestWebhook sends a test notification to a specific webhook
 func (am *AppManager) handleTestWebhook(c echo.Context) error {
 	webhookID := c.Param("webhook_id")
@@ -221,15 +241,11 @@ func (am *AppManager) handleTestWebhook(c echo.Context) error {
 	// Get the webhook from storage
 	webhook, err := am.storage.GetWebhook(webhookID)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Webhook not found",
-		})
+		return errorJSON(c, http.StatusNotFound, "Webhook not found")
 	}
 
 	if !webhook.Enabled {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Webhook is disabled",
-		})
+		return errorJSON(c, http.StatusBadRequest, "Webhook is disabled")
 	}
 
 	// Create test source and status change for payload
@@ -252,26 +268,27 @@ func (am *AppManager) handleTestWebhook(c echo.Context) error {
 		Timestamp:  time.Now(),
 	}
 
-	// Get webhook notifier to send test
+	// Fall back to a standalone notifier when the bot isn't running
 	webhookNotifier := am.botProcess.GetWebhookNotifier()
 	if webhookNotifier == nil {
-		return c.JSON(http.StatusServiceUnavailable, map[string]string{
-			"error": "Webhook notifier not available",
-		})
+		webhookNotifier = notifier.NewWebhookNotifier(am.storage)
 	}
 
-	// Send test webhook (synchronously for this test)
-	am.logger.Printf("Sending test webhook to %s", webhook.URL)
-	
-	// We'll use the OnStatusChange method but note this is a test
-	go webhookNotifier.OnStatusChange(testSource, testChange)
+	am.log(c).Printf("Sending test webhook to %s", webhook.URL)
+	result := webhookNotifier.SendTest(webhook, testSource, testChange)
 
-	am.logger.Printf("Sent test notification to webhook %s (%s)", webhook.URL, webhookID)
+	response := WebhookTestResponse{
+		WebhookID:      webhookID,
+		URL:            webhook.URL,
+		DeliveryResult: result,
+		SentAt:         time.Now(),
+	}
+	if !result.Success {
+		am.log(c).Warnf("Test notification to webhook %s (%s) failed: %s", webhook.URL, webhookID, result.Error)
+		return c.JSON(http.StatusBadGateway, response)
+	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message":    "Test notification sent successfully",
-		"webhook_id": webhookID,
-		"url":        webhook.URL,
-		"sent_at":    time.Now(),
-	})
+	am.log(c).Printf("Sent test notification to webhook %s (%s)", webhook.URL, webhookID)
+	response.Message = "Test notification sent successfully"
+	return c.JSON(http.StatusOK, response)
 }
@@ -0,0 +1,204 @@
+package appmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// SourceTemplateRequest is the request body for creating or replacing a source template
+type SourceTemplateRequest struct {
+	Name                  string                  `json:"name"`
+	Type                  string                  `json:"type"`                              // "ping", "http", or "webhook"
+	CheckInterval         string                  `json:"check_interval"`                    // e.g. "30s", "1m"
+	GracePeriodMultiplier *float64                `json:"grace_period_multiplier,omitempty"` // webhook: default 2.5
+	ExpectedHeaders       string                  `json:"expected_headers,omitempty"`
+	ExpectedContent       string                  `json:"expected_content,omitempty"`
+	Public                bool                    `json:"public"`
+	Tags                  []string                `json:"tags,omitempty"`
+	SLO                   *storage.SLO            `json:"slo,omitempty"`
+	ResponseLimits        *storage.ResponseLimits `json:"response_limits,omitempty"` // http only
+	ResponseSchema        json.RawMessage         `json:"response_schema,omitempty"` // http only
+	RedirectPolicy        *storage.RedirectPolicy `json:"redirect_policy,omitempty"` // http only
+	TLSPolicy             *storage.TLSPolicy      `json:"tls_policy,omitempty"`      // http only
+	Locations             []string                `json:"locations,omitempty"`
+	Resolver              string                  `json:"resolver,omitempty"`       // ping/http only
+	AddressFamily         string                  `json:"address_family,omitempty"` // ping/http only
+	Proxy                 string                  `json:"proxy,omitempty"`          // http only
+	Headers               map[string]string       `json:"headers,omitempty"`        // http only
+	HTTPVersion           string                  `json:"http_version,omitempty"`   // http only
+	OutageMessage         string                  `json:"outage_message,omitempty"`
+	RecoveryMessage       string                  `json:"recovery_message,omitempty"`
+	TelegramChatIDs       []int64                 `json:"telegram_chat_ids,omitempty"` // Registered chats to notify
+	WebhookIDs            []string                `json:"webhook_ids,omitempty"`
+}
+
+// handleGetSourceTemplates lists source templates by name
+func (am *AppManager) handleGetSourceTemplates(c echo.Context) error {
+	templates, err := am.storage.GetSourceTemplates()
+	if err != nil {
+		am.log(c).Errorf("Failed to list source templates: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to list source templates")
+	}
+	if templates == nil {
+		templates = []*storage.SourceTemplate{}
+	}
+	return c.JSON(http.StatusOK, templates)
+}
+
+// handleGetSourceTemplate returns a source template by ID or name
+func (am *AppManager) handleGetSourceTemplate(c echo.Context) error {
+	template, err := am.storage.FindSourceTemplate(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source template not found")
+	}
+	return c.JSON(http.StatusOK, template)
+}
+
+// handleCreateSourceTemplate creates a source template
+func (am *AppManager) handleCreateSourceTemplate(c echo.Context) error {
+	template := &storage.SourceTemplate{}
+	if err := am.bindSourceTemplate(c, template); err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	if am.templateNameTaken(template) {
+		return errorJSON(c, http.StatusConflict, "A source template with this name already exists")
+	}
+
+	if err := am.storage.SaveSourceTemplate(template); err != nil {
+		am.log(c).Errorf("Failed to create source template: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to create source template")
+	}
+
+	return c.JSON(http.StatusCreated, template)
+}
+
+// handleUpdateSourceTemplate replaces a source template. Sources created from it are unchanged.
+func (am *AppManager) handleUpdateSourceTemplate(c echo.Context) error {
+	template, err := am.storage.FindSourceTemplate(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source template not found")
+	}
+	if err := am.bindSourceTemplate(c, template); err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	if am.templateNameTaken(template) {
+		return errorJSON(c, http.StatusConflict, "A source template with this name already exists")
+	}
+
+	if err := am.storage.SaveSourceTemplate(template); err != nil {
+		am.log(c).Errorf("Failed to update source template: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to update source template")
+	}
+
+	return c.JSON(http.StatusOK, template)
+}
+
+// handleDeleteSourceTemplate deletes a source template. Sources created from it are kept.
+func (am *AppManager) handleDeleteSourceTemplate(c echo.Context) error {
+	template, err := am.storage.FindSourceTemplate(c.Param("id"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, "Source template not found")
+	}
+
+	if err := am.storage.DeleteSourceTemplate(template.ID); err != nil {
+		am.log(c).Errorf("Failed to delete source template: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to delete source template")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Source template deleted successfully",
+		"id":      template.ID,
+	})
+}
+
+// bindSourceTemplate reads a SourceTemplateRequest into template and validates it with the
+// same rules as a created source
+func (am *AppManager) bindSourceTemplate(c echo.Context, template *storage.SourceTemplate) error {
+	var req SourceTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return errors.New("Invalid request body")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errors.New("Name is required")
+	}
+	// Validate the settings as a source with a placeholder target
+	source, err := sourceFromCreateRequest(CreateSourceRequest{
+		Name: name, Type: req.Type, Target: "template", CheckInterval: req.CheckInterval,
+		GracePeriodMultiplier: req.GracePeriodMultiplier, ExpectedHeaders: req.ExpectedHeaders,
+		ExpectedContent: req.ExpectedContent, Public: req.Public, Tags: req.Tags, SLO: req.SLO,
+		ResponseLimits: req.ResponseLimits, ResponseSchema: req.ResponseSchema,
+		RedirectPolicy: req.RedirectPolicy, TLSPolicy: req.TLSPolicy, Locations: req.Locations, OutageMessage: req.OutageMessage,
+		RecoveryMessage: req.RecoveryMessage, Resolver: req.Resolver, AddressFamily: req.AddressFamily,
+		Proxy: req.Proxy, Headers: req.Headers, HTTPVersion: req.HTTPVersion,
+	})
+	if err != nil {
+		return err
+	}
+	if source.CheckInterval <= 0 {
+		return errors.New("check_interval must be positive")
+	}
+
+	for _, chatID := range req.TelegramChatIDs {
+		if _, err := am.storage.GetChat(chatID); err != nil {
+			return fmt.Errorf("Unknown telegram chat: %d", chatID)
+		}
+	}
+	for _, webhookID := range req.WebhookIDs {
+		if _, err := am.storage.GetWebhook(webhookID); err != nil {
+			return fmt.Errorf("Unknown webhook: %s", webhookID)
+		}
+	}
+
+	template.Name = name
+	template.Type = source.Type
+	template.CheckInterval = source.CheckInterval
+	template.GracePeriodMultiplier = source.GracePeriodMultiplier
+	template.ExpectedHeaders = source.ExpectedHeaders
+	template.ExpectedContent = source.ExpectedContent
+	template.Public = source.Public
+	template.Tags = source.Tags
+	template.SLO = source.SLO
+	template.ResponseLimits = source.ResponseLimits
+	template.ResponseSchema = source.ResponseSchema
+	template.RedirectPolicy = source.RedirectPolicy
+	template.TLSPolicy = source.TLSPolicy
+	template.Locations = source.Locations
+	template.Resolver = source.Resolver
+	template.AddressFamily = source.AddressFamily
+	template.Proxy = source.Proxy
+	template.Headers = source.Headers
+	template.HTTPVersion = source.HTTPVersion
+	template.OutageMessage = source.OutageMessage
+	template.RecoveryMessage = source.RecoveryMessage
+	template.ChatIDs = req.TelegramChatIDs
+	template.WebhookIDs = req.WebhookIDs
+	return nil
+}
+
+// templateNameTaken reports whether another template has the same name
+func (am *AppManager) templateNameTaken(template *storage.SourceTemplate) bool {
+	existing, err := am.storage.FindSourceTemplate(template.Name)
+	return err == nil && existing.ID != template.ID
+}
+
+// sourceFromTemplate builds a source from the template named by req.Template, taking only
+// name and target from req. The caller attaches the template's sinks once the source is saved.
+func (am *AppManager) sourceFromTemplate(req CreateSourceRequest) (*storage.Source, *storage.SourceTemplate, error) {
+	template, err := am.storage.FindSourceTemplate(req.Template)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unknown template: %s", req.Template)
+	}
+	if err := validateSourceFields(req.Name, template.Type, req.Target); err != nil {
+		return nil, nil, err
+	}
+	return template.NewSource(req.Name, req.Target), template, nil
+}
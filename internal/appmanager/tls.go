@@ -0,0 +1,65 @@
+package appmanager
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"tg-monitor-bot/internal/config"
+)
+
+// tlsSettings selects how the API server listens: plain HTTP, a static certificate or ACME
+type tlsSettings struct {
+	certFile      string
+	keyFile       string
+	autocertHost  string
+	autocertCache string
+}
+
+// newTLSSettings validates the TLS config; the autocert cache defaults to a directory next to the DB
+func newTLSSettings(cfg *config.Config) (tlsSettings, error) {
+	settings := tlsSettings{
+		certFile:      cfg.TLSCertFile,
+		keyFile:       cfg.TLSKeyFile,
+		autocertHost:  cfg.TLSAutocertDomain,
+		autocertCache: cfg.TLSAutocertCacheDir,
+	}
+
+	if (settings.certFile == "") != (settings.keyFile == "") {
+		return settings, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if settings.certFile != "" && settings.autocertHost != "" {
+		return settings, fmt.Errorf("set either TLS_CERT_FILE/TLS_KEY_FILE or TLS_AUTOCERT_DOMAIN, not both")
+	}
+	if settings.autocertHost != "" && settings.autocertCache == "" {
+		settings.autocertCache = filepath.Join(filepath.Dir(cfg.DBPath), "autocert")
+	}
+
+	return settings, nil
+}
+
+// scheme returns the URL scheme the API server is reachable with
+func (t tlsSettings) scheme() string {
+	if t.certFile != "" || t.autocertHost != "" {
+		return "https"
+	}
+	return "http"
+}
+
+// startServer runs the Echo server on addr until it is shut down
+func (am *AppManager) startServer(addr string) error {
+	switch {
+	case am.tls.certFile != "":
+		return am.echoServer.StartTLS(addr, am.tls.certFile, am.tls.keyFile)
+	case am.tls.autocertHost != "":
+		// Certificates are obtained via the TLS-ALPN-01 challenge, so the server must be
+		// reachable on port 443 for the configured host
+		am.echoServer.AutoTLSManager.Prompt = autocert.AcceptTOS
+		am.echoServer.AutoTLSManager.HostPolicy = autocert.HostWhitelist(am.tls.autocertHost)
+		am.echoServer.AutoTLSManager.Cache = autocert.DirCache(am.tls.autocertCache)
+		return am.echoServer.StartAutoTLS(addr)
+	default:
+		return am.echoServer.Start(addr)
+	}
+}
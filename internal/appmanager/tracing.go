@@ -0,0 +1,24 @@
+package appmanager
+
+import (
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/tracing"
+)
+
+// applyTracing starts, reconfigures or stops span export for OTLP_ENDPOINT. Like the log
+// file it runs at start and on every config change; unchanged settings keep the exporter.
+func (am *AppManager) applyTracing(cfg *config.Config) {
+	headers, err := config.ParseHeaderList(cfg.OTLPHeaders)
+	if err != nil {
+		am.logger.Errorf("❌ Tracing disabled: OTLP_HEADERS: %v", err)
+		tracing.Shutdown()
+		return
+	}
+	tracing.Setup(tracing.Options{
+		Endpoint:    cfg.OTLPEndpoint,
+		Headers:     headers,
+		ServiceName: "outage-monitor-bot",
+		Version:     am.version,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+}
@@ -0,0 +1,30 @@
+package appmanager
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// uiFiles holds the embedded admin dashboard served under /ui
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// uiHandler serves the embedded dashboard; the SPA calls the REST API with the user's API key
+func uiHandler() echo.HandlerFunc {
+	sub, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err) // The embed path is fixed at compile time
+	}
+	fileServer := http.StripPrefix("/ui/", http.FileServer(http.FS(sub)))
+	return echo.WrapHandler(fileServer)
+}
+
+// handleUIRedirect sends /ui to /ui/ so relative asset and API paths resolve.
+// The target is relative so the redirect also works behind the /api/ reverse proxy.
+func (am *AppManager) handleUIRedirect(c echo.Context) error {
+	return c.Redirect(http.StatusMovedPermanently, "ui/")
+}
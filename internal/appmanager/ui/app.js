@@ -0,0 +1,398 @@
+// Embedded admin dashboard. Talks to the REST API relative to /ui/, so it also works behind the /api/ proxy.
+(function () {
+  'use strict';
+
+  const API = '../';
+  const KEY_STORAGE = 'outage-monitor-api-key';
+
+  let apiKey = localStorage.getItem(KEY_STORAGE) || '';
+  let sources = [];
+  let uptime = {}; // source ID -> row of GET /uptime over the last 7 days
+  let health = {}; // source ID -> row of GET /health-scores over the last 24 hours
+  let stream = null;
+
+  const $ = (sel) => document.querySelector(sel);
+
+  // --- API -----------------------------------------------------------------
+
+  async function api(method, path, body) {
+    const res = await fetch(API + path, {
+      method,
+      headers: { 'X-API-Key': apiKey, 'Content-Type': 'application/json' },
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    if (res.status === 401) {
+      logout();
+      throw new Error('Invalid API key');
+    }
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      throw new Error(data.error || res.statusText);
+    }
+    return data;
+  }
+
+  function toast(message) {
+    const el = $('#toast');
+    el.textContent = message;
+    el.hidden = false;
+    clearTimeout(toast.timer);
+    toast.timer = setTimeout(() => { el.hidden = true; }, 4000);
+  }
+
+  function run(promise) {
+    return promise.catch((err) => toast(err.message));
+  }
+
+  // --- Formatting ----------------------------------------------------------
+
+  function statusOf(source) {
+    if (!source.enabled) return 'paused';
+    if (source.current_status === 1) return 'online';
+    if (source.current_status === 0) return 'offline';
+    return 'unknown';
+  }
+
+  function formatTime(value) {
+    if (!value || value.startsWith('0001-')) return '—';
+    return new Date(value).toLocaleString();
+  }
+
+  function formatDuration(ms) {
+    const s = Math.round(ms / 1000);
+    if (s < 60) return s + 's';
+    if (s < 3600) return Math.round(s / 60) + 'm';
+    if (s < 86400) return (s / 3600).toFixed(1) + 'h';
+    return (s / 86400).toFixed(1) + 'd';
+  }
+
+  function formatInterval(ns) {
+    return formatDuration(ns / 1e6);
+  }
+
+  function cell(text, className) {
+    const td = document.createElement('td');
+    td.textContent = text;
+    if (className) td.className = className;
+    return td;
+  }
+
+  function button(label, onClick) {
+    const b = document.createElement('button');
+    b.textContent = label;
+    b.addEventListener('click', onClick);
+    return b;
+  }
+
+  // --- Sources -------------------------------------------------------------
+
+  async function loadSources() {
+    const [list, summary, scores] = await Promise.all([
+      api('GET', 'sources'), api('GET', 'uptime?period=7d'), api('GET', 'health-scores'),
+    ]);
+    sources = list;
+    sources.sort((a, b) => a.name.localeCompare(b.name));
+    uptime = Object.fromEntries(summary.sources.map((row) => [row.source_id, row]));
+    health = Object.fromEntries(scores.map((row) => [row.source_id, row]));
+    renderTagFilter();
+    renderSources();
+    renderHistorySelect();
+  }
+
+  function renderTagFilter() {
+    const select = $('#tag-filter');
+    const current = select.value;
+    const tags = [...new Set(sources.flatMap((s) => s.tags || []))].sort();
+    select.replaceChildren(new Option('All tags', ''), ...tags.map((t) => new Option(t, t)));
+    if (tags.includes(current)) select.value = current;
+  }
+
+  function nameCell(source) {
+    const td = cell(source.name);
+    for (const tag of source.tags || []) {
+      const span = document.createElement('span');
+      span.className = 'tag';
+      span.textContent = tag;
+      td.appendChild(span);
+    }
+    return td;
+  }
+
+  function uptimeCell(source) {
+    const row = uptime[source.id];
+    if (!row || row.uptime_percent === null) return cell('—');
+    const td = cell(row.uptime_percent.toFixed(2) + '%');
+    if (row.outage_count > 0) {
+      td.title = row.outage_count + ' outage(s), ' + formatDuration(row.downtime_ms) + ' down';
+    }
+    return td;
+  }
+
+  function healthCell(source) {
+    const row = health[source.id];
+    if (!row || row.score === null) return cell('—');
+    const badge = document.createElement('span');
+    badge.className = 'badge ' + row.status;
+    badge.textContent = row.score;
+    const parts = ['uptime ' + row.uptime_score, 'flaps ' + row.flap_score + ' (' + row.flaps + ' outage(s))'];
+    if (row.latency_score !== null) parts.push('latency ' + row.latency_score + ' (x' + row.latency.ratio.toFixed(2) + ')');
+    badge.title = row.status + ': ' + parts.join(', ');
+    const td = document.createElement('td');
+    td.appendChild(badge);
+    return td;
+  }
+
+  function renderSources() {
+    const body = $('#sources-body');
+    body.replaceChildren();
+    const tag = $('#tag-filter').value;
+    for (const source of sources) {
+      if (tag && !(source.tags || []).includes(tag)) continue;
+      const tr = document.createElement('tr');
+      tr.id = 'source-' + source.id;
+
+      const status = statusOf(source);
+      const badge = document.createElement('span');
+      badge.className = 'badge ' + status;
+      badge.textContent = status;
+      const statusCell = document.createElement('td');
+      statusCell.appendChild(badge);
+
+      const actions = document.createElement('td');
+      actions.appendChild(button(source.enabled ? 'Pause' : 'Resume', () =>
+        run(api('POST', 'sources/' + source.id + (source.enabled ? '/pause' : '/resume')).then(loadSources))));
+      actions.appendChild(button('Edit', () => editSource(source)));
+      actions.appendChild(button('Delete', () => {
+        if (confirm('Move "' + source.name + '" to trash?')) {
+          run(api('DELETE', 'sources/' + source.id).then(loadSources));
+        }
+      }));
+
+      tr.append(
+        statusCell,
+        nameCell(source),
+        cell(source.type),
+        cell(source.type === 'webhook' ? 'token ' + (source.webhook_token || '') : source.target),
+        cell(formatInterval(source.check_interval)),
+        uptimeCell(source),
+        healthCell(source),
+        cell(formatTime(source.last_check_time)),
+        cell(source.last_error || '', 'error'),
+        actions,
+      );
+      body.appendChild(tr);
+    }
+  }
+
+  function editSource(source) {
+    const form = $('#source-form');
+    form.reset();
+    form.hidden = false;
+    $('#source-form-title').textContent = source ? 'Edit ' + source.name : 'New source';
+    form.elements.id.value = source ? source.id : '';
+    if (source) {
+      form.elements.name.value = source.name;
+      form.elements.type.value = source.type;
+      form.elements.target.value = source.target;
+      form.elements.check_interval.value = formatInterval(source.check_interval);
+      form.elements.enabled.checked = source.enabled;
+      form.elements.public.checked = !!source.public;
+      form.elements.tags.value = (source.tags || []).join(', ');
+      form.elements.outage_message.value = source.outage_message || '';
+      form.elements.recovery_message.value = source.recovery_message || '';
+    }
+    form.scrollIntoView({ behavior: 'smooth' });
+  }
+
+  async function saveSource(event) {
+    event.preventDefault();
+    const form = event.target;
+    const id = form.elements.id.value;
+    const body = {
+      name: form.elements.name.value,
+      type: form.elements.type.value,
+      target: form.elements.target.value,
+      check_interval: form.elements.check_interval.value,
+      enabled: form.elements.enabled.checked,
+      public: form.elements.public.checked,
+      tags: form.elements.tags.value.split(',').map((t) => t.trim()).filter(Boolean),
+      outage_message: form.elements.outage_message.value,
+      recovery_message: form.elements.recovery_message.value,
+    };
+    if (id) {
+      // Webhook validation settings aren't editable here; send them back unchanged
+      const existing = sources.find((s) => s.id === id) || {};
+      body.expected_headers = existing.expected_headers || '';
+      body.expected_content = existing.expected_content || '';
+      if (existing.grace_period_multiplier) body.grace_period_multiplier = existing.grace_period_multiplier;
+      await api('PUT', 'sources/' + id, body);
+    } else {
+      await api('POST', 'sources', body);
+    }
+    form.hidden = true;
+    toast('Source saved');
+    await loadSources();
+  }
+
+  // --- Live updates --------------------------------------------------------
+
+  function connectStream() {
+    if (stream) stream.close();
+    stream = new EventSource(API + 'events/stream?api_key=' + encodeURIComponent(apiKey));
+    stream.onopen = () => { $('#live').className = 'live on'; };
+    stream.onerror = () => { $('#live').className = 'live off'; };
+    stream.addEventListener('status_change', (e) => {
+      const change = JSON.parse(e.data);
+      const source = sources.find((s) => s.id === change.source_id);
+      if (source) {
+        source.current_status = change.new_status;
+        renderSources();
+        const row = document.getElementById('source-' + source.id);
+        if (row) row.classList.add('flash');
+      }
+      toast(change.source_name + ' is now ' + (change.new_status === 1 ? 'online' : 'offline'));
+      if ($('#history-source').value === change.source_id) loadHistory();
+    });
+  }
+
+  // --- History -------------------------------------------------------------
+
+  function renderHistorySelect() {
+    const select = $('#history-source');
+    const current = select.value;
+    select.replaceChildren();
+    for (const source of sources) {
+      const option = document.createElement('option');
+      option.value = source.id;
+      option.textContent = source.name;
+      select.appendChild(option);
+    }
+    if (current) select.value = current;
+  }
+
+  async function loadHistory() {
+    const sourceID = $('#history-source').value;
+    if (!sourceID) return;
+
+    const [rollups, events] = await Promise.all([
+      api('GET', 'sources/' + sourceID + '/rollups?days=90'),
+      api('GET', 'events?source_id=' + encodeURIComponent(sourceID) + '&limit=100'),
+    ]);
+
+    renderChart(rollups);
+
+    const body = $('#events-body');
+    body.replaceChildren();
+    for (const event of events) {
+      const tr = document.createElement('tr');
+      tr.append(
+        cell(formatTime(event.timestamp)),
+        cell(event.new_status === 1 ? '🟢 back online' : '🔴 went offline'),
+        cell(formatDuration(event.duration_ms)),
+      );
+      body.appendChild(tr);
+    }
+  }
+
+  function renderChart(rollups) {
+    const svg = $('#uptime-chart');
+    svg.replaceChildren();
+    const ns = 'http://www.w3.org/2000/svg';
+    const width = 900 / Math.max(rollups.length, 1);
+
+    let monitored = 0;
+    let downtime = 0;
+    rollups.forEach((r, i) => {
+      monitored += r.monitored_ms;
+      downtime += r.downtime_ms;
+      const height = Math.max(2, r.uptime_percent * 1.2);
+      const rect = document.createElementNS(ns, 'rect');
+      rect.setAttribute('x', i * width + 1);
+      rect.setAttribute('y', 120 - height);
+      rect.setAttribute('width', Math.max(width - 2, 1));
+      rect.setAttribute('height', height);
+      rect.setAttribute('fill', r.uptime_percent >= 99.9 ? '#2da44e' : r.uptime_percent >= 95 ? '#d4a72c' : '#cf222e');
+      const title = document.createElementNS(ns, 'title');
+      title.textContent = r.date + ': ' + r.uptime_percent.toFixed(2) + '% (' + r.outage_count + ' outages)';
+      rect.appendChild(title);
+      svg.appendChild(rect);
+    });
+
+    $('#uptime-summary').textContent = monitored > 0
+      ? ((monitored - downtime) / monitored * 100).toFixed(3) + '% uptime over ' + rollups.length + ' days'
+      : 'No completed days yet';
+  }
+
+  // --- Config --------------------------------------------------------------
+
+  async function loadConfig() {
+    const config = await api('GET', 'config');
+    const body = $('#config-body');
+    body.replaceChildren();
+    for (const key of Object.keys(config).sort()) {
+      const input = document.createElement('input');
+      input.value = config[key];
+      const valueCell = document.createElement('td');
+      valueCell.appendChild(input);
+
+      const actions = document.createElement('td');
+      actions.appendChild(button('Save', () => {
+        if (input.value === config[key]) return;
+        run(api('PUT', 'config/' + encodeURIComponent(key), { value: input.value }).then(() => {
+          config[key] = input.value;
+          toast(key + ' saved, bot restarting');
+        }));
+      }));
+
+      const tr = document.createElement('tr');
+      tr.append(cell(key), valueCell, actions);
+      body.appendChild(tr);
+    }
+  }
+
+  // --- Navigation & auth ---------------------------------------------------
+
+  function showTab(name) {
+    document.querySelectorAll('nav button').forEach((b) => b.classList.toggle('active', b.dataset.tab === name));
+    for (const tab of ['sources', 'history', 'config']) {
+      $('#tab-' + tab).hidden = tab !== name;
+    }
+    if (name === 'history') run(loadHistory());
+    if (name === 'config') run(loadConfig());
+  }
+
+  function logout() {
+    localStorage.removeItem(KEY_STORAGE);
+    apiKey = '';
+    if (stream) stream.close();
+    $('#app').hidden = true;
+    $('#login').hidden = false;
+  }
+
+  async function start() {
+    $('#login').hidden = true;
+    $('#app').hidden = false;
+    await loadSources();
+    connectStream();
+  }
+
+  $('#login-form').addEventListener('submit', (e) => {
+    e.preventDefault();
+    apiKey = $('#api-key').value.trim();
+    localStorage.setItem(KEY_STORAGE, apiKey);
+    run(start());
+  });
+  $('#logout').addEventListener('click', logout);
+  $('#new-source').addEventListener('click', () => editSource(null));
+  $('#tag-filter').addEventListener('change', renderSources);
+  $('#cancel-source').addEventListener('click', () => { $('#source-form').hidden = true; });
+  $('#source-form').addEventListener('submit', (e) => run(saveSource(e)));
+  $('#history-source').addEventListener('change', () => run(loadHistory()));
+  document.querySelectorAll('nav button').forEach((b) => b.addEventListener('click', () => showTab(b.dataset.tab)));
+
+  if (apiKey) {
+    run(start());
+  } else {
+    logout();
+  }
+})();
@@ -0,0 +1,157 @@
+package appmanager
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/storage"
+)
+
+var (
+	// errLastAllowedUser is returned when removing the only user left in ALLOWED_USERS
+	errLastAllowedUser = errors.New("can't remove the last allowed user: an empty ALLOWED_USERS lets everyone use the bot")
+	// errUserNotAllowed is returned when removing a user ALLOWED_USERS doesn't list
+	errUserNotAllowed = errors.New("user is not in ALLOWED_USERS")
+)
+
+// AllowedUserRequest is the body for POST /users
+type AllowedUserRequest struct {
+	User string `json:"user"` // Numeric Telegram user ID, or @username of a user who has messaged the bot
+}
+
+// AllowedUserResponse is an ALLOWED_USERS entry with what the bot last saw of the user
+type AllowedUserResponse struct {
+	ID         int64      `json:"id"`
+	Username   string     `json:"username,omitempty"`
+	FirstName  string     `json:"first_name,omitempty"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"` // Unset for users who never messaged the bot
+}
+
+// SeenUserResponse is a user who has messaged the bot
+type SeenUserResponse struct {
+	*storage.TelegramUser
+	Allowed bool `json:"allowed"`
+}
+
+// setUserAllowed adds a user to ALLOWED_USERS (allow) or removes them and returns the new
+// list. Saving the config applies the list to the running bot without recreating it.
+func (am *AppManager) setUserAllowed(userID int64, allow bool, updatedBy string) ([]int64, error) {
+	am.usersMu.Lock()
+	defer am.usersMu.Unlock()
+
+	users := config.ParseIDs(am.configManager.Get("ALLOWED_USERS"))
+	i := slices.Index(users, userID)
+	switch {
+	case allow && i >= 0:
+		return users, nil
+	case allow:
+		users = append(users, userID)
+	case i < 0:
+		return users, errUserNotAllowed
+	case len(users) == 1:
+		return users, errLastAllowedUser
+	default:
+		users = slices.Delete(users, i, i+1)
+	}
+
+	if err := am.configManager.SetMany(map[string]string{"ALLOWED_USERS": config.JoinIDs(users)}, updatedBy); err != nil {
+		return nil, err
+	}
+	am.recordSystemEvent(storage.SystemEventConfigChange, "Config updated: ALLOWED_USERS", map[string]string{
+		"key": "ALLOWED_USERS",
+		"by":  updatedBy,
+	})
+	return users, nil
+}
+
+// allowedUsersResponse describes users with what the bot last saw of them
+func (am *AppManager) allowedUsersResponse(users []int64) []AllowedUserResponse {
+	resp := make([]AllowedUserResponse, 0, len(users))
+	for _, id := range users {
+		entry := AllowedUserResponse{ID: id}
+		if user, err := am.storage.GetTelegramUser(id); err == nil {
+			entry.Username = user.Username
+			entry.FirstName = user.FirstName
+			entry.LastSeenAt = &user.LastSeenAt
+		}
+		resp = append(resp, entry)
+	}
+	return resp
+}
+
+// handleGetUsers lists ALLOWED_USERS; an empty list means everyone may use the bot
+func (am *AppManager) handleGetUsers(c echo.Context) error {
+	users := config.ParseIDs(am.configManager.Get("ALLOWED_USERS"))
+	return c.JSON(http.StatusOK, am.allowedUsersResponse(users))
+}
+
+// handleGetSeenUsers lists the users who have messaged the bot, most recent first, so that
+// users who were refused can be found and allowed
+func (am *AppManager) handleGetSeenUsers(c echo.Context) error {
+	seen, err := am.storage.ListTelegramUsers()
+	if err != nil {
+		am.log(c).Errorf("Failed to list Telegram users: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to list users")
+	}
+
+	users := config.ParseIDs(am.configManager.Get("ALLOWED_USERS"))
+	resp := make([]SeenUserResponse, 0, len(seen))
+	for _, user := range seen {
+		resp = append(resp, SeenUserResponse{
+			TelegramUser: user,
+			Allowed:      len(users) == 0 || slices.Contains(users, user.ID),
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// handleAddUser adds a user to ALLOWED_USERS
+func (am *AppManager) handleAddUser(c echo.Context) error {
+	var req AllowedUserRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+	userID, err := am.storage.ResolveTelegramUser(req.User)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	users, err := am.setUserAllowed(userID, true, "api")
+	if errors.Is(err, ErrFileBackedConfig) {
+		return errorJSON(c, http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		am.log(c).Errorf("Failed to add allowed user: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to update ALLOWED_USERS")
+	}
+
+	am.log(c).Printf("User %d allowed by %s", userID, authKeyName(c))
+	return c.JSON(http.StatusOK, am.allowedUsersResponse(users))
+}
+
+// handleRemoveUser removes a user, given by ID or @username, from ALLOWED_USERS
+func (am *AppManager) handleRemoveUser(c echo.Context) error {
+	userID, err := am.storage.ResolveTelegramUser(c.Param("user"))
+	if err != nil {
+		return errorJSON(c, http.StatusNotFound, err.Error())
+	}
+
+	users, err := am.setUserAllowed(userID, false, "api")
+	switch {
+	case errors.Is(err, errUserNotAllowed):
+		return errorJSON(c, http.StatusNotFound, "User is not allowed")
+	case errors.Is(err, errLastAllowedUser), errors.Is(err, ErrFileBackedConfig):
+		return errorJSON(c, http.StatusConflict, err.Error())
+	case err != nil:
+		am.log(c).Errorf("Failed to remove allowed user: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to update ALLOWED_USERS")
+	}
+
+	am.log(c).Printf("User %d removed from allowed users by %s", userID, authKeyName(c))
+	return c.JSON(http.StatusOK, am.allowedUsersResponse(users))
+}
@@ -0,0 +1,106 @@
+package appmanager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/storage"
+)
+
+// watchdogTick is how often the watchdog looks at the time of the last completed check
+const watchdogTick = 30 * time.Second
+
+// watchdogIntervals is how many check intervals of the most frequent source may pass
+// without a completed check before checks count as stalled, if longer than WATCHDOG_TIMEOUT
+const watchdogIntervals = 3
+
+// watchdogState remembers whether checks are stalled, so alerts are sent on transitions only
+type watchdogState struct {
+	mu        sync.Mutex
+	stalled   bool
+	lastCheck time.Time
+}
+
+// get returns whether checks are stalled and when the last one completed
+func (w *watchdogState) get() (bool, time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stalled, w.lastCheck
+}
+
+// stallThreshold returns how long the monitor may go without a completed check: the
+// longer of WATCHDOG_TIMEOUT and watchdogIntervals of the most frequent source
+func stallThreshold(cfg *config.Config, shortestInterval time.Duration) time.Duration {
+	threshold := cfg.WatchdogTimeout
+	if expected := watchdogIntervals * shortestInterval; expected > threshold {
+		threshold = expected
+	}
+	return threshold
+}
+
+// runWatchdog checks for stalled checks until ctx is cancelled
+func (am *AppManager) runWatchdog(ctx context.Context) {
+	ticker := time.NewTicker(watchdogTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if cfg, err := am.configManager.AsConfig(); err == nil {
+				am.checkWatchdog(ctx, cfg, time.Now())
+			}
+		}
+	}
+}
+
+// checkWatchdog detects checks that stopped completing while the monitor looks running
+// (stuck goroutines, a held database lock) and alerts admin chats when that starts and ends
+func (am *AppManager) checkWatchdog(ctx context.Context, cfg *config.Config, now time.Time) {
+	stalled := false
+	var lastCheck time.Time
+	var threshold time.Duration
+
+	mon := am.botProcess.GetMonitor()
+	if cfg.WatchdogTimeout > 0 && am.botProcess.IsRunning() && mon != nil && mon.ActiveMonitors() > 0 {
+		lastCheck = mon.LastCheck()
+		threshold = stallThreshold(cfg, mon.ShortestInterval())
+		stalled = now.Sub(lastCheck) > threshold
+	}
+
+	am.watchdog.mu.Lock()
+	wasStalled := am.watchdog.stalled
+	am.watchdog.stalled = stalled
+	am.watchdog.lastCheck = lastCheck
+	am.watchdog.mu.Unlock()
+
+	switch {
+	case stalled && !wasStalled:
+		since := now.Sub(lastCheck).Round(time.Second)
+		am.logger.Errorf("❌ Watchdog: no check has completed for %v (expected within %v)", since, threshold)
+		am.recordSystemEvent(storage.SystemEventWatchdog, "Checks stalled", map[string]string{
+			"last_check": lastCheck.Format(time.RFC3339),
+			"threshold":  threshold.String(),
+		})
+		am.alertAdmins(ctx, cfg, fmt.Sprintf("⚠️ Checks stalled: no check has completed for %v (expected within %v). Sources are not being monitored.", since, threshold))
+	case !stalled && wasStalled:
+		am.logger.Println("✅ Watchdog: checks resumed")
+		am.recordSystemEvent(storage.SystemEventWatchdog, "Checks resumed", nil)
+		am.alertAdmins(ctx, cfg, "✅ Checks resumed.")
+	}
+}
+
+// alertAdmins sends text to ADMIN_CHAT_IDS; without a Telegram bot it is only logged
+func (am *AppManager) alertAdmins(ctx context.Context, cfg *config.Config, text string) {
+	telegramBot := am.botProcess.GetBot()
+	if telegramBot == nil || len(cfg.AdminChatIDs) == 0 {
+		return
+	}
+	for _, chatID := range cfg.AdminChatIDs {
+		telegramBot.SendAlert(ctx, chatID, text)
+	}
+}
@@ -1,6 +1,8 @@
 package appmanager
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -8,18 +10,48 @@ import (
 	"tg-monitor-bot/internal/storage"
 )
 
-// handleGetWebhooks returns all webhooks
+// errInvalidWebhookMethod is returned for webhook methods other than GET, POST and PUT
+var errInvalidWebhookMethod = errors.New("Invalid HTTP method. Use GET, POST, or PUT")
+
+// errInvalidWebhookFormat is returned for unknown webhook payload formats
+var errInvalidWebhookFormat = errors.New("Invalid format. Leave it empty for the native payload or use alertmanager")
+
+// CreateWebhookRequest is the request body for creating a webhook
+type CreateWebhookRequest struct {
+	Name      string            `json:"name"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"` // GET, POST, or PUT (default POST)
+	Headers   map[string]string `json:"headers,omitempty"`
+	Enabled   bool              `json:"enabled"`
+	Namespace string            `json:"namespace,omitempty"` // Unscoped keys only; namespaced keys create in their own
+	Format    string            `json:"format,omitempty"`    // Empty for the native payload, or alertmanager
+}
+
+// UpdateWebhookRequest is the request body for updating a webhook; omitted fields are left unchanged
+type UpdateWebhookRequest struct {
+	Name      *string           `json:"name"`
+	URL       *string           `json:"url"`
+	Method    *string           `json:"method"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Enabled   *bool             `json:"enabled"`
+	Namespace *string           `json:"namespace,omitempty"` // "" = global
+	Format    *string           `json:"format,omitempty"`    // "" = native payload
+}
+
+// handleGetWebhooks returns all webhooks, or those of the key's namespace or ?namespace=
 func (am *AppManager) handleGetWebhooks(c echo.Context) error {
-	webhooks, err := am.storage.ListWebhooks()
+	all, err := am.storage.ListWebhooks()
 	if err != nil {
-		am.logger.Printf("Failed to list webhooks: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to list webhooks",
-		})
+		am.log(c).Errorf("Failed to list webhooks: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to list webhooks")
 	}
 
-	if webhooks == nil {
-		webhooks = []*storage.Webhook{}
+	namespace, filtered := namespaceFilter(c)
+	webhooks := []*storage.Webhook{}
+	for _, webhook := range all {
+		if !filtered || webhook.Namespace == namespace {
+			webhooks = append(webhooks, webhook)
+		}
 	}
 
 	return c.JSON(http.StatusOK, webhooks)
@@ -27,25 +59,31 @@ func (am *AppManager) handleGetWebhooks(c echo.Context) error {
 
 // handleCreateWebhook creates a new webhook
 func (am *AppManager) handleCreateWebhook(c echo.Context) error {
-	var req struct {
-		Name    string            `json:"name"`
-		URL     string            `json:"url"`
-		Method  string            `json:"method"`
-		Headers map[string]string `json:"headers,omitempty"`
-		Enabled bool              `json:"enabled"`
-	}
+	var req CreateWebhookRequest
 
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	webhook, err := am.webhookFromCreateRequest(c, req)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
 	}
 
+	if err := am.storage.SaveWebhook(webhook); err != nil {
+		am.log(c).Errorf("Failed to create webhook: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to create webhook")
+	}
+
+	return c.JSON(http.StatusCreated, webhook)
+}
+
+// webhookFromCreateRequest validates a create request and builds the webhook in the
+// namespace the key may create it in
+func (am *AppManager) webhookFromCreateRequest(c echo.Context, req CreateWebhookRequest) (*storage.Webhook, error) {
 	// Validation
 	if req.URL == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "URL is required",
-		})
+		return nil, fmt.Errorf("URL is required")
 	}
 
 	if req.Method == "" {
@@ -54,27 +92,26 @@ func (am *AppManager) handleCreateWebhook(c echo.Context) error {
 
 	// Validate HTTP method
 	if req.Method != "GET" && req.Method != "POST" && req.Method != "PUT" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid HTTP method. Use GET, POST, or PUT",
-		})
+		return nil, errInvalidWebhookMethod
 	}
-
-	webhook := &storage.Webhook{
-		Name:    req.Name,
-		URL:     req.URL,
-		Method:  req.Method,
-		Headers: req.Headers,
-		Enabled: req.Enabled,
+	if !storage.ValidWebhookFormat(req.Format) {
+		return nil, errInvalidWebhookFormat
 	}
 
-	if err := am.storage.SaveWebhook(webhook); err != nil {
-		am.logger.Printf("Failed to create webhook: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to create webhook",
-		})
+	namespace, err := am.resolveNamespace(c, req.Namespace)
+	if err != nil {
+		return nil, err
 	}
 
-	return c.JSON(http.StatusCreated, webhook)
+	return &storage.Webhook{
+		Name:      req.Name,
+		URL:       req.URL,
+		Method:    req.Method,
+		Headers:   req.Headers,
+		Enabled:   req.Enabled,
+		Namespace: namespace,
+		Format:    req.Format,
+	}, nil
 }
 
 // handleUpdateWebhook updates a webhook
@@ -83,23 +120,41 @@ func (am *AppManager) handleUpdateWebhook(c echo.Context) error {
 
 	webhook, err := am.storage.GetWebhook(webhookID)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Webhook not found",
-		})
+		return errorJSON(c, http.StatusNotFound, "Webhook not found")
 	}
 
-	var req struct {
-		Name    *string            `json:"name"`
-		URL     *string            `json:"url"`
-		Method  *string            `json:"method"`
-		Headers map[string]string  `json:"headers,omitempty"`
-		Enabled *bool              `json:"enabled"`
-	}
+	var req UpdateWebhookRequest
 
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := am.applyWebhookUpdate(c, webhook, req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := am.storage.SaveWebhook(webhook); err != nil {
+		am.log(c).Errorf("Failed to update webhook: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to update webhook")
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+// applyWebhookUpdate applies the fields set in an update request to webhook
+func (am *AppManager) applyWebhookUpdate(c echo.Context, webhook *storage.Webhook, req UpdateWebhookRequest) error {
+	if req.Method != nil && *req.Method != "GET" && *req.Method != "POST" && *req.Method != "PUT" {
+		return errInvalidWebhookMethod
+	}
+	if req.Format != nil && !storage.ValidWebhookFormat(*req.Format) {
+		return errInvalidWebhookFormat
+	}
+	if req.Namespace != nil {
+		namespace, err := am.resolveNamespace(c, *req.Namespace)
+		if err != nil {
+			return err
+		}
+		webhook.Namespace = namespace
 	}
 
 	if req.Name != nil {
@@ -111,14 +166,13 @@ func (am *AppManager) handleUpdateWebhook(c echo.Context) error {
 	}
 
 	if req.Method != nil {
-		if *req.Method != "GET" && *req.Method != "POST" && *req.Method != "PUT" {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid HTTP method. Use GET, POST, or PUT",
-			})
-		}
 		webhook.Method = *req.Method
 	}
 
+	if req.Format != nil {
+		webhook.Format = *req.Format
+	}
+
 	if len(req.Headers) > 0 {
 		webhook.Headers = req.Headers
 	}
@@ -126,15 +180,7 @@ func (am *AppManager) handleUpdateWebhook(c echo.Context) error {
 	if req.Enabled != nil {
 		webhook.Enabled = *req.Enabled
 	}
-
-	if err := am.storage.SaveWebhook(webhook); err != nil {
-		am.logger.Printf("Failed to update webhook: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to update webhook",
-		})
-	}
-
-	return c.JSON(http.StatusOK, webhook)
+	return nil
 }
 
 // handleDeleteWebhook deletes a webhook
@@ -143,24 +189,12 @@ func (am *AppManager) handleDeleteWebhook(c echo.Context) error {
 
 	// Verify webhook exists
 	if _, err := am.storage.GetWebhook(webhookID); err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Webhook not found",
-		})
-	}
-
-	// Remove associations
-	sources, err := am.storage.GetWebhookSources(webhookID)
-	if err == nil {
-		for _, sourceID := range sources {
-			am.storage.RemoveSourceWebhook(sourceID, webhookID)
-		}
+		return errorJSON(c, http.StatusNotFound, "Webhook not found")
 	}
 
 	if err := am.storage.DeleteWebhook(webhookID); err != nil {
-		am.logger.Printf("Failed to delete webhook: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to delete webhook",
-		})
+		am.log(c).Errorf("Failed to delete webhook: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to delete webhook")
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{
@@ -176,23 +210,17 @@ func (am *AppManager) handleAddSourceWebhook(c echo.Context) error {
 
 	// Verify source exists
 	if _, err := am.storage.GetSource(sourceID); err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Source not found",
-		})
+		return errorJSON(c, http.StatusNotFound, "Source not found")
 	}
 
 	// Verify webhook exists
 	if _, err := am.storage.GetWebhook(webhookID); err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Webhook not found",
-		})
+		return errorJSON(c, http.StatusNotFound, "Webhook not found")
 	}
 
 	if err := am.storage.AddSourceWebhook(sourceID, webhookID); err != nil {
-		am.logger.Printf("Failed to add source webhook: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to add webhook to source",
-		})
+		am.log(c).Errorf("Failed to add source webhook: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to add webhook to source")
 	}
 
 	return c.JSON(http.StatusCreated, map[string]string{
@@ -208,10 +236,8 @@ func (am *AppManager) handleRemoveSourceWebhook(c echo.Context) error {
 	webhookID := c.Param("webhook_id")
 
 	if err := am.storage.RemoveSourceWebhook(sourceID, webhookID); err != nil {
-		am.logger.Printf("Failed to remove source webhook: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to remove webhook from source",
-		})
+		am.log(c).Errorf("Failed to remove source webhook: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to remove webhook from source")
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{
@@ -227,17 +253,13 @@ func (am *AppManager) handleGetSourceWebhooks(c echo.Context) error {
 
 	// Verify source exists
 	if _, err := am.storage.GetSource(sourceID); err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Source not found",
-		})
+		return errorJSON(c, http.StatusNotFound, "Source not found")
 	}
 
 	webhooks, err := am.storage.GetSourceWebhooks(sourceID)
 	if err != nil {
-		am.logger.Printf("Failed to get source webhooks: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get source webhooks",
-		})
+		am.log(c).Errorf("Failed to get source webhooks: %v", err)
+		return errorJSON(c, http.StatusInternalServerError, "Failed to get source webhooks")
 	}
 
 	if webhooks == nil {
@@ -0,0 +1,113 @@
+package appmanager
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxRecordedWebhookRequests is how many incoming requests are kept per source
+const maxRecordedWebhookRequests = 50
+
+// Outcomes of an incoming webhook request
+const (
+	webhookOutcomeAccepted        = "accepted"
+	webhookOutcomePaused          = "paused"
+	webhookOutcomeHeaderMismatch  = "header_mismatch"
+	webhookOutcomeContentMismatch = "content_mismatch"
+	webhookOutcomeMissingContent  = "missing_content"
+	webhookOutcomeError           = "error"
+)
+
+// credentialHeaders are masked in recorded requests
+var credentialHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Api-Key":           true,
+}
+
+// IncomingWebhookRequest is a recorded request to /webhooks/incoming/:token
+type IncomingWebhookRequest struct {
+	ReceivedAt  time.Time         `json:"received_at"`
+	RemoteIP    string            `json:"remote_ip"`
+	Method      string            `json:"method"`
+	Headers     map[string]string `json:"headers"` // Credential headers are masked
+	BodySnippet string            `json:"body_snippet,omitempty"`
+	Outcome     string            `json:"outcome"` // accepted, paused, header_mismatch, content_mismatch, missing_content or error
+	Detail      string            `json:"detail,omitempty"`
+	StatusCode  int               `json:"status_code"`
+}
+
+// webhookRequestLog keeps the most recent incoming requests per source in memory.
+// The zero value is ready to use.
+type webhookRequestLog struct {
+	mu       sync.Mutex
+	bySource map[string][]*IncomingWebhookRequest
+}
+
+// add records a request, dropping the oldest beyond maxRecordedWebhookRequests
+func (l *webhookRequestLog) add(sourceID string, req *IncomingWebhookRequest) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.bySource == nil {
+		l.bySource = make(map[string][]*IncomingWebhookRequest)
+	}
+	requests := append(l.bySource[sourceID], req)
+	if len(requests) > maxRecordedWebhookRequests {
+		requests = requests[len(requests)-maxRecordedWebhookRequests:]
+	}
+	l.bySource[sourceID] = requests
+}
+
+// recent returns up to limit requests for a source, newest first
+func (l *webhookRequestLog) recent(sourceID string, limit int) []*IncomingWebhookRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	requests := l.bySource[sourceID]
+	result := make([]*IncomingWebhookRequest, 0, min(limit, len(requests)))
+	for i := len(requests) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, requests[i])
+	}
+	return result
+}
+
+// recordedHeaders flattens request headers, masking credentials
+func recordedHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if credentialHeaders[http.CanonicalHeaderKey(name)] {
+			value = maskString(value)
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+// handleGetWebhookRequests returns the most recent incoming requests for a webhook source
+func (am *AppManager) handleGetWebhookRequests(c echo.Context) error {
+	source, err := am.storage.GetSource(c.Param("id"))
+	if err != nil || source.IsDeleted() {
+		return errorJSON(c, http.StatusNotFound, "Source not found")
+	}
+	if source.Type != "webhook" {
+		return errorJSON(c, http.StatusBadRequest, "Only webhook sources receive incoming requests")
+	}
+
+	limit := 20
+	if value := c.QueryParam("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxRecordedWebhookRequests {
+			return errorJSON(c, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxRecordedWebhookRequests))
+		}
+	}
+
+	return c.JSON(http.StatusOK, am.webhookRequests.recent(source.ID, limit))
+}
@@ -3,6 +3,8 @@ package bot
 import (
 	"context"
 	"fmt"
+	"html"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -10,6 +12,8 @@ import (
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 
+	"tg-monitor-bot/internal/monitor"
+	"tg-monitor-bot/internal/notifier"
 	"tg-monitor-bot/internal/storage"
 )
 
@@ -25,17 +29,31 @@ I monitor your infrastructure and alert you when things go down!
 
 *Source Management:*
 /add\_source - Add a new monitoring source
+/add\_from\_template <template> <name> <target> - Add a source from a template
+/templates - List source templates
+/discover [cidr...] - Scan the network for hosts
+/discovered - List discovered hosts not monitored yet
+/promote <ip,...|all> [template] - Monitor discovered hosts
 /remove\_source <name> - Remove a source
-/list\_sources - List all sources
+/list\_sources [tag] - List all sources, or those with a tag
 
 *Status & History:*
 /status [name] - View current status
 /history <name> [limit] - View status change history
+/note <name> <text> - Add a note to the current or latest outage
+/uptime\_all [period] - Uptime of all sources (default 7d)
+/events [group id] - Latest status changes, or those of an alert group
 
 *Control:*
 /check <name> - Manual check now
 /pause <name> - Pause monitoring
 /resume <name> - Resume monitoring
+/test\_notifications <name> - Send a test alert to all sinks of a source
+
+*Users (admin chats):*
+/users - List allowed users
+/add\_user <@username|id> - Allow a user
+/remove\_user <@username|id> - Remove a user
 
 *Examples:*
 ` + "`/add_source Home_Power ping 192.168.1.1 10s 123456789`" + `
@@ -48,7 +66,7 @@ I monitor your infrastructure and alert you when things go down!
 		ParseMode: models.ParseModeMarkdown,
 	})
 	if err != nil {
-		b.logger.Printf("Failed to send start message: %v", err)
+		b.logger.Errorf("Failed to send start message: %v", err)
 	}
 }
 
@@ -88,17 +106,20 @@ func (b *Bot) handleAddSource(ctx context.Context, tgBot *bot.Bot, update *model
 		return
 	}
 
-	// Parse chat IDs (optional, defaults to current chat)
+	// Parse chat IDs (optional, defaults to current chat); a namespaced chat may only
+	// notify chats of its own namespace
+	namespace := b.chatNamespace(update.Message.Chat.ID)
 	var chatIDs []int64
 	if len(args) >= 6 {
 		chatIDsStr := strings.Split(args[5], ",")
 		for _, idStr := range chatIDsStr {
 			id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64)
-			if err == nil {
+			if err == nil && (namespace == "" || b.chatNamespace(id) == namespace) {
 				chatIDs = append(chatIDs, id)
 			}
 		}
-	} else {
+	}
+	if len(chatIDs) == 0 {
 		chatIDs = []int64{update.Message.Chat.ID}
 	}
 
@@ -110,6 +131,7 @@ func (b *Bot) handleAddSource(ctx context.Context, tgBot *bot.Bot, update *model
 		CheckInterval: interval,
 		Enabled:       true,
 		CreatedAt:     time.Now(),
+		Namespace:     namespace,
 	}
 
 	// Do initial check
@@ -128,7 +150,7 @@ func (b *Bot) handleAddSource(ctx context.Context, tgBot *bot.Bot, update *model
 	// Add chat associations
 	for _, chatID := range chatIDs {
 		if err := b.storage.AddSourceChat(source.ID, chatID); err != nil {
-			b.logger.Printf("Failed to add chat %d to source: %v", chatID, err)
+			b.logger.Errorf("Failed to add chat %d to source: %v", chatID, err)
 		}
 	}
 
@@ -158,6 +180,321 @@ func (b *Bot) handleAddSource(ctx context.Context, tgBot *bot.Bot, update *model
 			name, sourceType, target, interval, statusEmoji, statusText, len(chatIDs)))
 }
 
+// handleAddFromTemplate handles the /add_from_template command
+// Format: /add_from_template <template> <name> <target>
+// Example: /add_from_template lan_host Host_21 192.168.1.21
+func (b *Bot) handleAddFromTemplate(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	args := strings.Fields(update.Message.Text)
+	if len(args) < 4 {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			"❌ Usage: /add_from_template <template> <name> <target>\n"+
+				"Example: /add_from_template lan_host Host_21 192.168.1.21\n"+
+				"Use /templates to list templates")
+		return
+	}
+
+	template, err := b.storage.FindSourceTemplate(args[1])
+	if err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("❌ Template '%s' not found. Use /templates to list templates", args[1]))
+		return
+	}
+	if template.Type == "webhook" {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			"❌ Webhook sources need a token; create them from this template via the API")
+		return
+	}
+
+	source := template.NewSource(args[2], args[3])
+	source.Namespace = b.chatNamespace(update.Message.Chat.ID)
+
+	// Do initial check
+	initialStatus := b.monitor.CheckSource(source)
+	source.CurrentStatus = initialStatus
+	source.LastCheckTime = time.Now()
+	source.LastChangeTime = time.Now()
+
+	if err := b.storage.SaveSource(source); err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("❌ Failed to save source: %v", err))
+		return
+	}
+
+	// Notify the template's chats, or this chat when it has none. The template's sinks
+	// belong to the admin, so sources of a namespaced chat only notify the chat itself.
+	chats, webhooks := len(template.ChatIDs), len(template.WebhookIDs)
+	if source.Namespace != "" {
+		chats, webhooks = 0, 0
+	} else if err := b.storage.AttachTemplateSinks(template, source.ID); err != nil {
+		b.logger.Errorf("Failed to attach template sinks to source: %v", err)
+	}
+	if chats == 0 {
+		chats = 1
+		if err := b.storage.AddSourceChat(source.ID, update.Message.Chat.ID); err != nil {
+			b.logger.Errorf("Failed to add chat %d to source: %v", update.Message.Chat.ID, err)
+		}
+	}
+
+	// Start monitoring
+	monitorCtx := context.Background() // Use background context for long-running monitor
+	if err := b.monitor.AddSource(monitorCtx, source); err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("❌ Failed to start monitoring: %v", err))
+		return
+	}
+
+	statusEmoji := "🔴"
+	statusText := "OFFLINE"
+	if initialStatus == 1 {
+		statusEmoji = "🟢"
+		statusText = "ONLINE"
+	}
+
+	b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+		fmt.Sprintf("✅ Source added from template %s and monitoring started!\n\n"+
+			"Name: %s\n"+
+			"Type: %s\n"+
+			"Target: %s\n"+
+			"Interval: %v\n"+
+			"Initial status: %s %s\n"+
+			"Notifying %d chat(s) and %d webhook(s)",
+			template.Name, source.Name, source.Type, source.Target, source.CheckInterval,
+			statusEmoji, statusText, chats, webhooks))
+}
+
+// handleTemplates handles the /templates command
+func (b *Bot) handleTemplates(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	templates, err := b.storage.GetSourceTemplates()
+	if err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("❌ Failed to get templates: %v", err))
+		return
+	}
+	if len(templates) == 0 {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			"📋 No source templates. Create them with POST /api/v1/templates")
+		return
+	}
+
+	var message strings.Builder
+	message.WriteString("📋 *Source Templates*\n\n")
+	for _, template := range templates {
+		message.WriteString(fmt.Sprintf("`%s` - %s every %v\n", template.Name, template.Type, template.CheckInterval))
+		if len(template.Tags) > 0 {
+			message.WriteString(fmt.Sprintf("   Tags: %s\n", formatTags(template.Tags)))
+		}
+		message.WriteString(fmt.Sprintf("   Notifies %d chat(s) and %d webhook(s)\n", len(template.ChatIDs), len(template.WebhookIDs)))
+	}
+	message.WriteString("\nUse /add\\_from\\_template <template> <name> <target>")
+
+	b.sendMessage(ctx, tgBot, update.Message.Chat.ID, message.String())
+}
+
+// handleDiscover handles the /discover command
+// Format: /discover [cidr...]
+// Example: /discover 192.168.1.0/24
+func (b *Bot) handleDiscover(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	if b.denyInNamespace(ctx, tgBot, update.Message.Chat.ID) {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	ranges := strings.Fields(update.Message.Text)[1:]
+	if len(ranges) == 0 {
+		ranges = b.config.DiscoveryRanges
+	}
+
+	scan, err := b.monitor.StartDiscovery(ranges, b.config.DiscoveryPorts, func(scan *monitor.DiscoveryScan) {
+		if scan.Error != "" {
+			b.sendMessage(context.Background(), tgBot, chatID, fmt.Sprintf("❌ Discovery failed: %s", scan.Error))
+			return
+		}
+		b.sendMessage(context.Background(), tgBot, chatID,
+			fmt.Sprintf("🔎 Discovery finished: %d address(es) scanned, %d host(s) found, %d new\n\n"+
+				"Use /discovered to list hosts not monitored yet", scan.Scanned, scan.Found, scan.New))
+	})
+	if err != nil {
+		b.sendMessage(ctx, tgBot, chatID,
+			fmt.Sprintf("❌ %v\nUsage: /discover [cidr...]\nExample: /discover 192.168.1.0/24", err))
+		return
+	}
+
+	b.sendMessage(ctx, tgBot, chatID,
+		fmt.Sprintf("🔎 Scanning %s (ports %s)... I'll reply when it's done.",
+			strings.Join(scan.Ranges, ", "), formatPorts(scan.Ports)))
+}
+
+// handleDiscovered handles the /discovered command, listing hosts no source monitors yet
+func (b *Bot) handleDiscovered(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	if b.denyInNamespace(ctx, tgBot, update.Message.Chat.ID) {
+		return
+	}
+
+	hosts, err := b.storage.GetDiscoveredHosts()
+	if err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("❌ Failed to get discovered hosts: %v", err))
+		return
+	}
+
+	var message strings.Builder
+	count := 0
+	for _, host := range hosts {
+		if host.SourceID != "" {
+			continue
+		}
+		count++
+		message.WriteString(fmt.Sprintf("`%s`", host.IP))
+		if host.Hostname != "" {
+			message.WriteString(fmt.Sprintf(" `%s`", host.Hostname))
+		}
+		message.WriteString(fmt.Sprintf(" - %s", strings.Join(host.Methods, ", ")))
+		if len(host.OpenPorts) > 0 {
+			message.WriteString(fmt.Sprintf(", ports %s", formatPorts(host.OpenPorts)))
+		}
+		message.WriteString("\n")
+	}
+	if count == 0 {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			"🔎 No new hosts. Use /discover [cidr...] to scan")
+		return
+	}
+
+	b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+		fmt.Sprintf("🔎 *New Hosts* (%d)\n\n%s\nUse /promote <ip,...|all> [template] to monitor them", count, message.String()))
+}
+
+// handlePromote handles the /promote command, creating a ping source (or one from the
+// template) for each discovered host
+// Format: /promote <ip[,ip...]|all> [template]
+// Example: /promote 192.168.1.21,192.168.1.22 lan_host
+func (b *Bot) handlePromote(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	if b.denyInNamespace(ctx, tgBot, update.Message.Chat.ID) {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	args := strings.Fields(update.Message.Text)
+	if len(args) < 2 {
+		b.sendMessage(ctx, tgBot, chatID,
+			"❌ Usage: /promote <ip[,ip...]|all> [template]\n"+
+				"Example: /promote 192.168.1.21,192.168.1.22 lan\\_host\n"+
+				"Use /discovered to list new hosts")
+		return
+	}
+
+	var template *storage.SourceTemplate
+	if len(args) > 2 {
+		var err error
+		if template, err = b.storage.FindSourceTemplate(args[2]); err != nil {
+			b.sendMessage(ctx, tgBot, chatID,
+				fmt.Sprintf("❌ Template '%s' not found. Use /templates to list templates", args[2]))
+			return
+		}
+		if template.Type == "webhook" {
+			b.sendMessage(ctx, tgBot, chatID, "❌ Webhook templates can't monitor a host")
+			return
+		}
+	}
+
+	var hosts []*storage.DiscoveredHost
+	if args[1] == "all" {
+		all, err := b.storage.GetDiscoveredHosts()
+		if err != nil {
+			b.sendMessage(ctx, tgBot, chatID, fmt.Sprintf("❌ Failed to get discovered hosts: %v", err))
+			return
+		}
+		for _, host := range all {
+			if host.SourceID == "" {
+				hosts = append(hosts, host)
+			}
+		}
+	} else {
+		for _, ip := range strings.Split(args[1], ",") {
+			host, err := b.storage.GetDiscoveredHost(ip)
+			if err != nil {
+				b.sendMessage(ctx, tgBot, chatID,
+					fmt.Sprintf("❌ Host %s was not discovered. Use /discovered to list new hosts", ip))
+				return
+			}
+			if host.SourceID != "" {
+				if source, err := b.storage.GetSource(host.SourceID); err == nil && !source.IsDeleted() {
+					b.sendMessage(ctx, tgBot, chatID,
+						fmt.Sprintf("❌ Host %s is already monitored by %s", ip, source.Name))
+					return
+				}
+			}
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		b.sendMessage(ctx, tgBot, chatID, "🔎 No new hosts to promote")
+		return
+	}
+
+	var promoted []string
+	for _, host := range hosts {
+		var source *storage.Source
+		if template != nil {
+			source = template.NewSource(host.SourceName(), host.SourceTarget(template.Type))
+		} else {
+			source = &storage.Source{
+				Name:          host.SourceName(),
+				Type:          "ping",
+				Target:        host.IP,
+				CheckInterval: b.config.DefaultCheckInterval,
+				CurrentStatus: -1,
+				Enabled:       true,
+				CreatedAt:     time.Now(),
+			}
+		}
+
+		if err := b.storage.SaveSource(source); err != nil {
+			b.sendMessage(ctx, tgBot, chatID, fmt.Sprintf("❌ Failed to save source for %s: %v", host.IP, err))
+			return
+		}
+		// Notify the template's chats, or this chat when it has none
+		if template != nil {
+			if err := b.storage.AttachTemplateSinks(template, source.ID); err != nil {
+				b.logger.Errorf("Failed to attach template sinks to source: %v", err)
+			}
+		}
+		if template == nil || len(template.ChatIDs) == 0 {
+			if err := b.storage.AddSourceChat(source.ID, chatID); err != nil {
+				b.logger.Errorf("Failed to add chat %d to source: %v", chatID, err)
+			}
+		}
+		if err := b.storage.SetDiscoveredHostSource(host.IP, source.ID); err != nil {
+			b.logger.Warnf("Failed to link discovered host %s to %s: %v", host.IP, source.ID, err)
+		}
+
+		if err := b.monitor.AddSource(context.Background(), source); err != nil {
+			b.logger.Warnf("Failed to start monitoring %s: %v", source.Name, err)
+		}
+		promoted = append(promoted, fmt.Sprintf("`%s` - %s %s", source.Name, source.Type, source.Target))
+	}
+
+	b.sendMessage(ctx, tgBot, chatID,
+		fmt.Sprintf("✅ Monitoring %d discovered host(s):\n\n%s", len(promoted), strings.Join(promoted, "\n")))
+}
+
 // handleRemoveSource handles the /remove_source command
 func (b *Bot) handleRemoveSource(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 	if update.Message == nil {
@@ -174,7 +511,7 @@ func (b *Bot) handleRemoveSource(ctx context.Context, tgBot *bot.Bot, update *mo
 	name := strings.Join(args[1:], " ")
 
 	// Find source by name
-	source, err := b.storage.GetSourceByName(name)
+	source, err := b.findSource(update.Message.Chat.ID, name)
 	if err != nil {
 		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
 			fmt.Sprintf("❌ Source not found: %s", name))
@@ -183,23 +520,19 @@ func (b *Bot) handleRemoveSource(ctx context.Context, tgBot *bot.Bot, update *mo
 
 	// Stop monitoring
 	if err := b.monitor.RemoveSource(source.ID); err != nil {
-		b.logger.Printf("Failed to stop monitoring: %v", err)
+		b.logger.Errorf("Failed to stop monitoring: %v", err)
 	}
 
-	// Remove chat associations
-	if err := b.storage.RemoveAllSourceChats(source.ID); err != nil {
-		b.logger.Printf("Failed to remove chat associations: %v", err)
-	}
-
-	// Delete source
-	if err := b.storage.DeleteSource(source.ID); err != nil {
+	// Move source to trash (history and chat associations are kept for restore)
+	if err := b.storage.SoftDeleteSource(source.ID); err != nil {
 		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
 			fmt.Sprintf("❌ Failed to delete source: %v", err))
 		return
 	}
 
 	b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
-		fmt.Sprintf("✅ Source '%s' removed and monitoring stopped", name))
+		fmt.Sprintf("✅ Source '%s' removed and monitoring stopped\n\n"+
+			"It can be restored via POST /sources/%s/restore until it is purged.", name, source.ID))
 }
 
 // handleListSources handles the /list_sources command
@@ -208,21 +541,44 @@ func (b *Bot) handleListSources(ctx context.Context, tgBot *bot.Bot, update *mod
 		return
 	}
 
-	sources, err := b.storage.GetAllSources()
+	sources, err := b.chatSources(update.Message.Chat.ID)
 	if err != nil {
 		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
 			fmt.Sprintf("❌ Failed to get sources: %v", err))
 		return
 	}
 
+	// Optional tag filter: /list_sources <tag>
+	args := strings.Fields(update.Message.Text)
+	tag := ""
+	if len(args) >= 2 {
+		tag = strings.ToLower(strings.TrimPrefix(args[1], "#"))
+		var tagged []*storage.Source
+		for _, source := range sources {
+			if source.HasTag(tag) {
+				tagged = append(tagged, source)
+			}
+		}
+		sources = tagged
+	}
+
 	if len(sources) == 0 {
+		if tag != "" {
+			b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+				fmt.Sprintf("📋 No sources with tag `%s`", tag))
+			return
+		}
 		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
 			"📋 No sources configured.\n\nUse /add_source to add one!")
 		return
 	}
 
 	var message strings.Builder
-	message.WriteString("📋 *Monitoring Sources*\n\n")
+	if tag != "" {
+		message.WriteString(fmt.Sprintf("📋 *Monitoring Sources* tagged `%s`\n\n", tag))
+	} else {
+		message.WriteString("📋 *Monitoring Sources*\n\n")
+	}
 
 	for i, source := range sources {
 		statusEmoji := "🔴"
@@ -243,6 +599,9 @@ func (b *Bot) handleListSources(ctx context.Context, tgBot *bot.Bot, update *mod
 		message.WriteString(fmt.Sprintf("%d. *%s* %s %s%s\n", i+1, source.Name, statusEmoji, statusText, enabledText))
 		message.WriteString(fmt.Sprintf("   Type: %s (%s)\n", source.Type, source.Target))
 		message.WriteString(fmt.Sprintf("   Check: every %v (last %v ago)\n", source.CheckInterval, formatDuration(timeSinceCheck)))
+		if len(source.Tags) > 0 {
+			message.WriteString(fmt.Sprintf("   Tags: %s\n", formatTags(source.Tags)))
+		}
 
 		if source.CurrentStatus == 1 {
 			message.WriteString(fmt.Sprintf("   Uptime: %v\n", formatDuration(timeSinceChange)))
@@ -259,7 +618,7 @@ func (b *Bot) handleListSources(ctx context.Context, tgBot *bot.Bot, update *mod
 		ParseMode: models.ParseModeMarkdown,
 	})
 	if err != nil {
-		b.logger.Printf("Failed to send list: %v", err)
+		b.logger.Errorf("Failed to send list: %v", err)
 	}
 }
 
@@ -274,7 +633,7 @@ func (b *Bot) handleStatus(ctx context.Context, tgBot *bot.Bot, update *models.U
 	// If specific source requested
 	if len(args) >= 2 {
 		name := strings.Join(args[1:], " ")
-		source, err := b.storage.GetSourceByName(name)
+		source, err := b.findSource(update.Message.Chat.ID, name)
 		if err != nil {
 			b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
 				fmt.Sprintf("❌ Source not found: %s", name))
@@ -286,7 +645,7 @@ func (b *Bot) handleStatus(ctx context.Context, tgBot *bot.Bot, update *models.U
 	}
 
 	// Show summary of all sources
-	sources, err := b.storage.GetAllSources()
+	sources, err := b.chatSources(update.Message.Chat.ID)
 	if err != nil {
 		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
 			fmt.Sprintf("❌ Failed to get sources: %v", err))
@@ -300,18 +659,31 @@ func (b *Bot) handleStatus(ctx context.Context, tgBot *bot.Bot, update *models.U
 	}
 
 	online := 0
+	var attention []string
+	now := time.Now()
 	for _, source := range sources {
 		if source.CurrentStatus == 1 {
 			online++
 		}
+		health, err := monitor.ComputeHealthScore(b.storage, b.monitor, source, monitor.DefaultHealthWindow, now)
+		if err != nil {
+			b.logger.Errorf("Failed to compute health score of %s: %v", source.Name, err)
+			continue
+		}
+		if health.Score != nil && health.Status != monitor.HealthHealthy {
+			attention = append(attention, fmt.Sprintf("%s %s: %d/100", healthEmoji(health.Status), escapeMarkdown(source.Name), *health.Score))
+		}
 	}
 
 	message := fmt.Sprintf("📊 *Overall Status*\n\n"+
 		"Total sources: %d\n"+
 		"🟢 Online: %d\n"+
-		"🔴 Offline: %d\n\n"+
-		"Use `/status <name>` for details",
+		"🔴 Offline: %d\n\n",
 		len(sources), online, len(sources)-online)
+	if len(attention) > 0 {
+		message += "*Health below 80 (24h):*\n" + strings.Join(attention, "\n") + "\n\n"
+	}
+	message += "Use `/status <name>` for details"
 
 	_, err = tgBot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    update.Message.Chat.ID,
@@ -319,8 +691,21 @@ func (b *Bot) handleStatus(ctx context.Context, tgBot *bot.Bot, update *models.U
 		ParseMode: models.ParseModeMarkdown,
 	})
 	if err != nil {
-		b.logger.Printf("Failed to send status: %v", err)
+		b.logger.Errorf("Failed to send status: %v", err)
+	}
+}
+
+// healthEmoji marks a health score status
+func healthEmoji(status string) string {
+	switch status {
+	case monitor.HealthHealthy:
+		return "💚"
+	case monitor.HealthDegraded:
+		return "💛"
+	case monitor.HealthUnhealthy:
+		return "💔"
 	}
+	return "⚪"
 }
 
 // showSourceStatus shows detailed status for a specific source
@@ -341,6 +726,17 @@ func (b *Bot) showSourceStatus(ctx context.Context, tgBot *bot.Bot, chatID int64
 	} else {
 		durationText = fmt.Sprintf("Downtime: %v", formatDuration(timeSinceChange))
 	}
+	if source.LastError != "" {
+		durationText += fmt.Sprintf("\nLast error: `%s` (%v ago)", source.LastError, formatDuration(time.Since(source.LastErrorTime)))
+	}
+	if len(source.Tags) > 0 {
+		durationText += "\nTags: " + formatTags(source.Tags)
+	}
+	if health, err := monitor.ComputeHealthScore(b.storage, b.monitor, source, monitor.DefaultHealthWindow, time.Now()); err != nil {
+		b.logger.Errorf("Failed to compute health score of %s: %v", source.Name, err)
+	} else if health.Score != nil {
+		durationText += fmt.Sprintf("\nHealth (24h): %s %d/100, %s", healthEmoji(health.Status), *health.Score, health.Status)
+	}
 
 	message := fmt.Sprintf("%s *%s*: %s\n\n"+
 		"Target: %s (%s)\n"+
@@ -366,7 +762,7 @@ func (b *Bot) showSourceStatus(ctx context.Context, tgBot *bot.Bot, chatID int64
 		ParseMode: models.ParseModeMarkdown,
 	})
 	if err != nil {
-		b.logger.Printf("Failed to send status: %v", err)
+		b.logger.Errorf("Failed to send status: %v", err)
 	}
 }
 
@@ -395,7 +791,7 @@ func (b *Bot) handleHistory(ctx context.Context, tgBot *bot.Bot, update *models.
 	}
 
 	// Find source
-	source, err := b.storage.GetSourceByName(name)
+	source, err := b.findSource(update.Message.Chat.ID, name)
 	if err != nil {
 		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
 			fmt.Sprintf("❌ Source not found: %s", name))
@@ -403,7 +799,7 @@ func (b *Bot) handleHistory(ctx context.Context, tgBot *bot.Bot, update *models.
 	}
 
 	// Get status changes
-	changes, err := b.storage.GetStatusChanges(source.ID, limit)
+	changes, err := b.storage.GetStatusChanges(source.ID, time.Time{}, time.Time{}, limit)
 	if err != nil {
 		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
 			fmt.Sprintf("❌ Failed to get history: %v", err))
@@ -416,6 +812,16 @@ func (b *Bot) handleHistory(ctx context.Context, tgBot *bot.Bot, update *models.
 		return
 	}
 
+	// Notes are attached to the change that started an outage
+	notesByChange := make(map[string][]*storage.IncidentNote)
+	notes, err := b.storage.GetIncidentNotes(source.ID, changes[len(changes)-1].Timestamp, time.Time{})
+	if err != nil {
+		b.logger.Errorf("Failed to get incident notes of %s: %v", source.ID, err)
+	}
+	for _, note := range notes {
+		notesByChange[note.ChangeID] = append(notesByChange[note.ChangeID], note)
+	}
+
 	var message strings.Builder
 	message.WriteString(fmt.Sprintf("📜 *Status History: %s*\n\n", name))
 
@@ -440,6 +846,9 @@ func (b *Bot) handleHistory(ctx context.Context, tgBot *bot.Bot, update *models.
 		} else {
 			message.WriteString(fmt.Sprintf("   Downtime was: %v\n", formatDuration(duration)))
 		}
+		for _, note := range notesByChange[change.ID] {
+			message.WriteString(fmt.Sprintf("   📝 %s (%s)\n", escapeMarkdown(note.Text), escapeMarkdown(note.Author)))
+		}
 
 		message.WriteString("\n")
 	}
@@ -450,8 +859,255 @@ func (b *Bot) handleHistory(ctx context.Context, tgBot *bot.Bot, update *models.
 		ParseMode: models.ParseModeMarkdown,
 	})
 	if err != nil {
-		b.logger.Printf("Failed to send history: %v", err)
+		b.logger.Errorf("Failed to send history: %v", err)
+	}
+}
+
+// maxEventLines caps the status changes listed by /events
+const maxEventLines = 50
+
+// handleEvents handles the /events command: the latest status changes of the chat's sources,
+// or with an alert group ID, e.g. /events 5f0c..., every change notified in that group
+func (b *Bot) handleEvents(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	sources, err := b.chatSources(chatID)
+	if err != nil {
+		b.sendMessage(ctx, tgBot, chatID, fmt.Sprintf("❌ Failed to get sources: %v", err))
+		return
+	}
+	names := make(map[string]string, len(sources))
+	for _, source := range sources {
+		names[source.ID] = source.Name
+	}
+
+	title := "📜 *Latest status changes*"
+	var changes []*storage.StatusChange
+	if args := strings.Fields(update.Message.Text); len(args) >= 2 {
+		group, err := b.storage.GetAlertGroup(args[1])
+		if err != nil {
+			b.sendMessage(ctx, tgBot, chatID, fmt.Sprintf("❌ Alert group not found: %s", escapeMarkdown(args[1])))
+			return
+		}
+		if changes, err = b.storage.GetAlertGroupChanges(group); err != nil {
+			b.sendMessage(ctx, tgBot, chatID, fmt.Sprintf("❌ Failed to get events: %v", err))
+			return
+		}
+		title = fmt.Sprintf("📜 *Alert group* (%s - %s)",
+			group.Start.Format("2006-01-02 15:04:05"), group.End.Format("15:04:05"))
+	} else if changes, err = b.storage.GetRecentChanges(time.Time{}, time.Time{}, 200); err != nil {
+		b.sendMessage(ctx, tgBot, chatID, fmt.Sprintf("❌ Failed to get events: %v", err))
+		return
+	}
+
+	// Only the chat's sources; other namespaces' changes are left out
+	changes = slices.DeleteFunc(changes, func(change *storage.StatusChange) bool {
+		_, ok := names[change.SourceID]
+		return !ok
+	})
+	if len(changes) == 0 {
+		b.sendMessage(ctx, tgBot, chatID, "📜 No status changes recorded")
+		return
+	}
+
+	var message strings.Builder
+	message.WriteString(title + "\n\n")
+	for i, change := range changes {
+		if i == maxEventLines {
+			message.WriteString(fmt.Sprintf("…and %d more\n", len(changes)-i))
+			break
+		}
+		emoji := "🔴"
+		if change.NewStatus == 1 {
+			emoji = "🟢"
+		}
+		message.WriteString(fmt.Sprintf("%s %s, %s\n", emoji, escapeMarkdown(names[change.SourceID]), change.Timestamp.Format("2006-01-02 15:04:05")))
+	}
+	b.sendMessage(ctx, tgBot, chatID, message.String())
+}
+
+// handleNote handles the /note command: it attaches a note to the source's current or
+// latest outage, e.g. /note Home_Power ISP confirmed fiber cut, ETA 2h
+func (b *Bot) handleNote(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	args := strings.Fields(update.Message.Text)
+	if len(args) < 3 {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			"❌ Usage: /note <name> <text>\n"+
+				"Example: /note Home\\_Power ISP confirmed fiber cut, ETA 2h")
+		return
+	}
+	name := args[1]
+
+	source, err := b.findSource(update.Message.Chat.ID, name)
+	if err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("❌ Source not found: %s", name))
+		return
+	}
+
+	outage, err := b.storage.FindOutage(source.ID, time.Now())
+	if err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("❌ No outage of %s to add a note to", name))
+		return
+	}
+
+	note := &storage.IncidentNote{
+		Text:   strings.Join(args[2:], " "),
+		Author: telegramAuthor(update.Message.From),
+	}
+	if err := b.storage.AddIncidentNote(outage, note); err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID, fmt.Sprintf("❌ %s", escapeMarkdown(err.Error())))
+		return
+	}
+
+	when := "ongoing"
+	if source.CurrentStatus == 1 || outage.Timestamp.Before(source.LastChangeTime) {
+		when = "ended"
 	}
+	b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+		fmt.Sprintf("📝 Note added to the outage of %s since %s (%s)",
+			name, outage.Timestamp.Format("2006-01-02 15:04"), when))
+}
+
+// telegramAuthor names the sender of a message for notes: @username, else first name or ID
+func telegramAuthor(from *models.User) string {
+	switch {
+	case from == nil:
+		return "telegram"
+	case from.Username != "":
+		return "@" + from.Username
+	case from.FirstName != "":
+		return from.FirstName
+	}
+	return strconv.FormatInt(from.ID, 10)
+}
+
+// escapeMarkdown escapes user text for messages sent with the Markdown parse mode
+func escapeMarkdown(text string) string {
+	return strings.NewReplacer("_", "\\_", "*", "\\*", "`", "\\`", "[", "\\[").Replace(text)
+}
+
+// handleUptimeAll handles the /uptime_all command: a table of every source's uptime,
+// outage count and downtime over a period (default 7d)
+func (b *Bot) handleUptimeAll(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	period := 7 * 24 * time.Hour
+	label := "7d"
+	if args := strings.Fields(update.Message.Text); len(args) >= 2 {
+		var err error
+		if period, err = parseUptimePeriod(args[1]); err != nil {
+			b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+				"❌ Usage: /uptime_all [period]\n"+
+					"Period is e.g. 24h, 7d or 30d (max 366d)")
+			return
+		}
+		label = args[1]
+	}
+
+	sources, err := b.chatSources(update.Message.Chat.ID)
+	if err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("❌ Failed to get sources: %v", err))
+		return
+	}
+	if len(sources) == 0 {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			"📊 No sources to monitor")
+		return
+	}
+
+	to := time.Now()
+	summary, err := b.storage.ComputeUptimeSummary(sources, to.Add(-period), to)
+	if err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("❌ Failed to compute uptime: %v", err))
+		return
+	}
+
+	header := fmt.Sprintf("📈 *Uptime over %s*\n%s overall, %d outage(s), %s down\n\n",
+		label, formatUptime(summary.UptimePercent), summary.OutageCount,
+		formatDuration(time.Duration(summary.DowntimeMs)*time.Millisecond))
+	if summary.PlannedDowntimeMs > 0 {
+		header = strings.TrimSuffix(header, "\n\n") + fmt.Sprintf("\n%s SLA-adjusted, %s of it planned\n\n",
+			formatUptime(summary.SLAUptimePercent), formatDuration(time.Duration(summary.PlannedDowntimeMs)*time.Millisecond))
+	}
+
+	// The table goes in code blocks so it lines up; long tables are split over several messages
+	var rows []string
+	for _, row := range summary.Sources {
+		emoji := "🟢"
+		if !row.Enabled {
+			emoji = "⏸"
+		} else if row.CurrentStatus == 0 {
+			emoji = "🔴"
+		}
+		rows = append(rows, fmt.Sprintf("%s %-20s %8s %3d %s", emoji, truncateName(row.SourceName, 20),
+			formatUptime(row.UptimePercent), row.OutageCount, formatDuration(time.Duration(row.DowntimeMs)*time.Millisecond)))
+	}
+	for len(rows) > 0 {
+		var message strings.Builder
+		message.WriteString(header)
+		message.WriteString("```\n")
+		for len(rows) > 0 && message.Len()+len(rows[0]) < uptimeMessageLimit {
+			message.WriteString(strings.ReplaceAll(rows[0], "`", "'") + "\n")
+			rows = rows[1:]
+		}
+		message.WriteString("```")
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID, message.String())
+		header = ""
+	}
+}
+
+// uptimeMessageLimit keeps each /uptime_all message under Telegram's 4096 character limit
+const uptimeMessageLimit = 3900
+
+// parseUptimePeriod parses a period like 24h, 7d or 30d, up to a year
+func parseUptimePeriod(value string) (time.Duration, error) {
+	var period time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		period = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if period, err = time.ParseDuration(value); err != nil {
+			return 0, err
+		}
+	}
+	if period <= 0 || period > 366*24*time.Hour {
+		return 0, fmt.Errorf("period out of range: %s", value)
+	}
+	return period, nil
+}
+
+// formatUptime formats an uptime percentage, or a dash when nothing was monitored
+func formatUptime(uptime *float64) string {
+	if uptime == nil {
+		return "—"
+	}
+	return fmt.Sprintf("%.2f%%", *uptime)
+}
+
+// truncateName shortens a name to at most n characters for table columns
+func truncateName(name string, n int) string {
+	runes := []rune(name)
+	if len(runes) <= n {
+		return name
+	}
+	return string(runes[:n-1]) + "…"
 }
 
 // handleCheck handles the /check command (manual check)
@@ -469,7 +1125,7 @@ func (b *Bot) handleCheck(ctx context.Context, tgBot *bot.Bot, update *models.Up
 
 	name := strings.Join(args[1:], " ")
 
-	source, err := b.storage.GetSourceByName(name)
+	source, err := b.findSource(update.Message.Chat.ID, name)
 	if err != nil {
 		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
 			fmt.Sprintf("❌ Source not found: %s", name))
@@ -478,18 +1134,92 @@ func (b *Bot) handleCheck(ctx context.Context, tgBot *bot.Bot, update *models.Up
 
 	b.sendMessage(ctx, tgBot, update.Message.Chat.ID, "🔍 Checking...")
 
-	status := b.monitor.CheckSource(source)
+	outcome, err := b.monitor.CheckNow(source.ID)
+	if err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("❌ Check failed: %v", err))
+		return
+	}
 
 	statusEmoji := "🔴"
 	statusText := "OFFLINE"
-	if status == 1 {
+	if outcome.Status == 1 {
 		statusEmoji = "🟢"
 		statusText = "ONLINE"
 	}
 
-	b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
-		fmt.Sprintf("%s *%s* is %s\n\nType: %s\nTarget: %s",
-			statusEmoji, name, statusText, source.Type, source.Target))
+	message := fmt.Sprintf("%s *%s* is %s\n\nType: %s\nTarget: %s\nLatency: %v",
+		statusEmoji, name, statusText, source.Type, source.Target, outcome.Latency.Round(time.Millisecond))
+	if outcome.Error != "" {
+		message += fmt.Sprintf("\nError: `%s`", outcome.Error)
+	}
+	b.sendMessage(ctx, tgBot, update.Message.Chat.ID, message)
+}
+
+// handleTestNotifications handles the /test_notifications command: it sends a test outage
+// notification through every sink of a source and reports the result per sink
+func (b *Bot) handleTestNotifications(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	args := strings.Fields(update.Message.Text)
+	if len(args) < 2 {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			"❌ Usage: /test\\_notifications <name>")
+		return
+	}
+	if b.dispatcher == nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID, "❌ Notifications are not available")
+		return
+	}
+
+	name := strings.Join(args[1:], " ")
+
+	source, err := b.findSource(update.Message.Chat.ID, name)
+	if err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("❌ Source not found: %s", name))
+		return
+	}
+
+	results, err := b.dispatcher.Test(ctx, source)
+	if err != nil {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("❌ Test failed: %v", err))
+		return
+	}
+	if len(results) == 0 {
+		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
+			fmt.Sprintf("⚠️ %s has no sinks, so nobody would be notified", name))
+		return
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "🧪 Test notifications for %s:\n", name)
+	for _, result := range results {
+		sinkName := result.SinkName
+		if sinkName == "" {
+			sinkName = result.SinkID
+		}
+		switch {
+		case result.Skipped != "":
+			fmt.Fprintf(&message, "\n⚪ %s: skipped, %s", sinkName, result.Skipped)
+		case result.Success:
+			fmt.Fprintf(&message, "\n🟢 %s: sent in %dms", sinkName, result.LatencyMs)
+		default:
+			fmt.Fprintf(&message, "\n🔴 %s: %s", sinkName, result.Error)
+		}
+	}
+
+	// Plain text: sink names and errors may contain Markdown characters
+	_, err = tgBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   message.String(),
+	})
+	if err != nil {
+		b.logger.Errorf("Failed to send test results: %v", err)
+	}
 }
 
 // handlePause handles the /pause command
@@ -507,7 +1237,7 @@ func (b *Bot) handlePause(ctx context.Context, tgBot *bot.Bot, update *models.Up
 
 	name := strings.Join(args[1:], " ")
 
-	source, err := b.storage.GetSourceByName(name)
+	source, err := b.findSource(update.Message.Chat.ID, name)
 	if err != nil {
 		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
 			fmt.Sprintf("❌ Source not found: %s", name))
@@ -539,7 +1269,7 @@ func (b *Bot) handleResume(ctx context.Context, tgBot *bot.Bot, update *models.U
 
 	name := strings.Join(args[1:], " ")
 
-	source, err := b.storage.GetSourceByName(name)
+	source, err := b.findSource(update.Message.Chat.ID, name)
 	if err != nil {
 		b.sendMessage(ctx, tgBot, update.Message.Chat.ID,
 			fmt.Sprintf("❌ Source not found: %s", name))
@@ -556,6 +1286,106 @@ func (b *Bot) handleResume(ctx context.Context, tgBot *bot.Bot, update *models.U
 		fmt.Sprintf("▶️ Monitoring resumed for: *%s*", name))
 }
 
+// handleUsers handles the /users command, listing ALLOWED_USERS
+func (b *Bot) handleUsers(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+	if b.denyUnlessAdminChat(ctx, tgBot, chatID) {
+		return
+	}
+
+	b.accessMu.RLock()
+	users := slices.Clone(b.allowedUsers)
+	b.accessMu.RUnlock()
+	b.sendMessage(ctx, tgBot, chatID, b.formatAllowedUsers(users))
+}
+
+// handleAddUser handles the /add_user command
+// Format: /add_user <@username|user_id>
+func (b *Bot) handleAddUser(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.changeAllowedUser(ctx, tgBot, update, true)
+}
+
+// handleRemoveUser handles the /remove_user command
+// Format: /remove_user <@username|user_id>
+func (b *Bot) handleRemoveUser(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
+	b.changeAllowedUser(ctx, tgBot, update, false)
+}
+
+// changeAllowedUser adds the user named in an /add_user or /remove_user command to
+// ALLOWED_USERS or removes them
+func (b *Bot) changeAllowedUser(ctx context.Context, tgBot *bot.Bot, update *models.Update, allow bool) {
+	if update.Message == nil {
+		return
+	}
+	chatID := update.Message.Chat.ID
+	if b.denyUnlessAdminChat(ctx, tgBot, chatID) {
+		return
+	}
+
+	args := strings.Fields(update.Message.Text)
+	if len(args) != 2 {
+		b.sendMessage(ctx, tgBot, chatID,
+			fmt.Sprintf("❌ Usage: %s <@username|user\\_id>", strings.ReplaceAll(args[0], "_", "\\_")))
+		return
+	}
+	userID, err := b.storage.ResolveTelegramUser(args[1])
+	if err != nil {
+		b.sendMessage(ctx, tgBot, chatID, fmt.Sprintf("❌ %v", err))
+		return
+	}
+
+	b.accessMu.RLock()
+	fn := b.allowedUsersFunc
+	b.accessMu.RUnlock()
+	if fn == nil {
+		b.sendMessage(ctx, tgBot, chatID, "❌ User management is not available")
+		return
+	}
+	users, err := fn(userID, allow, "telegram")
+	if err != nil {
+		b.sendMessage(ctx, tgBot, chatID, fmt.Sprintf("❌ %v", err))
+		return
+	}
+
+	verb := "removed from"
+	if allow {
+		verb = "added to"
+	}
+	b.logger.Printf("User %d %s the allowed users by user %d", userID, verb, update.Message.From.ID)
+	b.sendMessage(ctx, tgBot, chatID,
+		fmt.Sprintf("✅ %s %s the allowed users\n\n%s", b.formatTelegramUser(userID), verb, b.formatAllowedUsers(users)))
+}
+
+// formatAllowedUsers lists the allowed users with the names the bot knows them by
+func (b *Bot) formatAllowedUsers(users []int64) string {
+	if len(users) == 0 {
+		return "👥 Everyone may use the bot (ALLOWED\\_USERS is empty)"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("👥 *Allowed users (%d):*\n", len(users)))
+	for _, id := range users {
+		sb.WriteString("• " + b.formatTelegramUser(id) + "\n")
+	}
+	return sb.String()
+}
+
+// formatTelegramUser formats a user ID with the @username or name last seen for it
+func (b *Bot) formatTelegramUser(id int64) string {
+	label := strconv.FormatInt(id, 10)
+	if user, err := b.storage.GetTelegramUser(id); err == nil {
+		if user.Username != "" {
+			label += " @" + user.Username
+		} else if user.FirstName != "" {
+			label += " " + user.FirstName
+		}
+	}
+	// Code spans keep underscores in usernames from breaking Markdown
+	return "`" + strings.ReplaceAll(label, "`", "'") + "`"
+}
+
 // formatStatusChangeMessage formats a notification message for a status change
 func (b *Bot) formatStatusChangeMessage(source *storage.Source, change *storage.StatusChange) string {
 	duration := time.Duration(change.DurationMs) * time.Millisecond
@@ -565,29 +1395,158 @@ func (b *Bot) formatStatusChangeMessage(source *storage.Source, change *storage.
 		checkType = fmt.Sprintf("%s (%s)", source.Type, source.Target)
 	}
 
+	// The source's own text, e.g. a runbook link, goes below the standard message
+	note := ""
+	if message := source.NotificationMessage(change.NewStatus); message != "" {
+		note = "\n\n" + html.EscapeString(message)
+	}
+	// The likely cause of an outage goes above the source's own text
+	if change.Hint != "" {
+		note = "\n\n🔎 " + html.EscapeString(change.Hint) + note
+	}
+	// Tests say so up front, so nobody acts on them
+	test := ""
+	if change.Test {
+		test = "🧪 <b>TEST NOTIFICATION</b>, nothing changed\n\n"
+	}
+
 	if change.NewStatus == 1 {
 		// Restored (OFFLINE → ONLINE)
-		return fmt.Sprintf("🟢 <b>RESTORED</b>\n"+
+		return fmt.Sprintf("%s🟢 <b>RESTORED</b>\n"+
 			"%s is now <b>ONLINE</b>\n\n"+
 			"Downtime: %v\n"+
 			"Check type: %s\n"+
-			"Time: %s",
+			"Time: %s%s",
+			test,
 			source.Name,
 			formatDuration(duration),
 			checkType,
-			change.Timestamp.Format("2006-01-02 15:04:05"))
+			change.Timestamp.Format("2006-01-02 15:04:05"),
+			note)
 	}
 
 	// Outage (ONLINE → OFFLINE)
-	return fmt.Sprintf("🔴 <b>OUTAGE DETECTED</b>\n"+
+	return fmt.Sprintf("%s🔴 <b>OUTAGE DETECTED</b>\n"+
 		"%s is now <b>OFFLINE</b>\n\n"+
 		"Was online for: %v\n"+
 		"Check type: %s\n"+
-		"Time: %s",
+		"Time: %s%s",
+		test,
 		source.Name,
 		formatDuration(duration),
 		checkType,
-		change.Timestamp.Format("2006-01-02 15:04:05"))
+		change.Timestamp.Format("2006-01-02 15:04:05"),
+		note)
+}
+
+// maxAlertGroupLines caps the sources listed in an alert group message; /events has them all
+const maxAlertGroupLines = 20
+
+// formatAlertGroupMessage formats the combined notification of an alert group
+func formatAlertGroupMessage(group *storage.AlertGroup, changes []notifier.GroupedChange, window time.Duration) string {
+	emoji := "🔴"
+	if group.NewStatus == 1 {
+		emoji = "🟢"
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("%s <b>%s</b>\n\n", emoji, notifier.GroupSummary(len(changes), group.NewStatus, window)))
+	for i, grouped := range changes {
+		if i == maxAlertGroupLines {
+			message.WriteString(fmt.Sprintf("…and %d more\n", len(changes)-i))
+			break
+		}
+		line := html.EscapeString(grouped.Source.Name)
+		if grouped.Source.Target != "" {
+			line += " (" + html.EscapeString(grouped.Source.Target) + ")"
+		}
+		message.WriteString("• " + line + "\n")
+	}
+	message.WriteString(fmt.Sprintf("\nTime: %s - %s\nFull list: <code>/events %s</code>",
+		group.Start.Format("2006-01-02 15:04:05"), group.End.Format("15:04:05"), group.ID))
+	return message.String()
+}
+
+// formatTags renders tags as Markdown code spans so characters like "_" survive
+func formatTags(tags []string) string {
+	formatted := make([]string, len(tags))
+	for i, tag := range tags {
+		formatted[i] = "`" + tag + "`"
+	}
+	return strings.Join(formatted, " ")
+}
+
+// formatPorts joins port numbers with commas
+func formatPorts(ports []int) string {
+	formatted := make([]string, len(ports))
+	for i, port := range ports {
+		formatted[i] = strconv.Itoa(port)
+	}
+	return strings.Join(formatted, ",")
+}
+
+// chatNamespace returns the namespace a chat is registered in; empty for global chats,
+// which see every source
+func (b *Bot) chatNamespace(chatID int64) string {
+	if chat, err := b.storage.GetChat(chatID); err == nil {
+		return chat.Namespace
+	}
+	return ""
+}
+
+// chatSources returns the sources a chat may see: all of them, or those of its namespace
+func (b *Bot) chatSources(chatID int64) ([]*storage.Source, error) {
+	sources, err := b.storage.GetAllSources()
+	namespace := b.chatNamespace(chatID)
+	if err != nil || namespace == "" {
+		return sources, err
+	}
+
+	var visible []*storage.Source
+	for _, source := range sources {
+		if source.Namespace == namespace {
+			visible = append(visible, source)
+		}
+	}
+	return visible, nil
+}
+
+// findSource looks up a source by name among those the chat may see
+func (b *Bot) findSource(chatID int64, name string) (*storage.Source, error) {
+	if b.chatNamespace(chatID) == "" {
+		return b.storage.GetSourceByName(name)
+	}
+
+	sources, err := b.chatSources(chatID)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		if source.Name == name {
+			return source, nil
+		}
+	}
+	return nil, fmt.Errorf("source not found")
+}
+
+// denyInNamespace replies that a command spanning namespaces isn't available in a chat
+// mapped to one, and reports whether it did
+func (b *Bot) denyInNamespace(ctx context.Context, tgBot *bot.Bot, chatID int64) bool {
+	if b.chatNamespace(chatID) == "" {
+		return false
+	}
+	b.sendMessage(ctx, tgBot, chatID, "❌ This command is not available in this chat")
+	return true
+}
+
+// denyUnlessAdminChat replies that user management is only available in ADMIN_CHAT_IDS chats
+// that aren't mapped to a namespace, and reports whether it did
+func (b *Bot) denyUnlessAdminChat(ctx context.Context, tgBot *bot.Bot, chatID int64) bool {
+	if slices.Contains(b.config.AdminChatIDs, chatID) && b.chatNamespace(chatID) == "" {
+		return false
+	}
+	b.sendMessage(ctx, tgBot, chatID, "❌ User management is only available in ADMIN\\_CHAT\\_IDS chats")
+	return true
 }
 
 // Helper function to send a message
@@ -598,7 +1557,7 @@ func (b *Bot) sendMessage(ctx context.Context, tgBot *bot.Bot, chatID int64, tex
 		ParseMode: models.ParseModeMarkdown,
 	})
 	if err != nil {
-		b.logger.Printf("Failed to send message: %v", err)
+		b.logger.Errorf("Failed to send message: %v", err)
 	}
 }
 
@@ -2,36 +2,53 @@ package bot
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 
 	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/logging"
 	"tg-monitor-bot/internal/monitor"
+	"tg-monitor-bot/internal/notifier"
 	"tg-monitor-bot/internal/storage"
 )
 
+// AllowedUsersFunc adds a user to ALLOWED_USERS (allow) or removes them, recording updatedBy
+// as the origin of the change, and returns the new list
+type AllowedUsersFunc func(userID int64, allow bool, updatedBy string) ([]int64, error)
+
 type Bot struct {
-	bot     *bot.Bot
-	config  *config.Config
-	storage *storage.BoltDB
-	monitor *monitor.Monitor
-	logger  *log.Logger
+	bot        *bot.Bot
+	config     *config.Config
+	storage    *storage.BoltDB
+	monitor    *monitor.Monitor
+	dispatcher *notifier.Dispatcher // Backs /test_notifications; nil disables it
+	logger     *logging.Logger
+
+	accessMu         sync.RWMutex
+	allowedUsers     []int64          // ALLOWED_USERS; replaced in place by SetAllowedUsers
+	allowedUsersFunc AllowedUsersFunc // Backs /add_user and /remove_user; nil disables them
 }
 
 // New creates a new Bot instance
 func New(cfg *config.Config, db *storage.BoltDB, mon *monitor.Monitor) (*Bot, error) {
 	b := &Bot{
-		config:  cfg,
-		storage: db,
-		monitor: mon,
-		logger:  log.New(log.Writer(), "[BOT] ", log.LstdFlags),
+		config:       cfg,
+		storage:      db,
+		monitor:      mon,
+		logger:       logging.New("bot"),
+		allowedUsers: cfg.AllowedUsers,
 	}
 
+	// Middlewares wrap command handlers as well as the default handler
 	opts := []bot.Option{
-		bot.WithDefaultHandler(b.loggingMiddleware(b.authMiddleware(b.defaultHandler))),
+		bot.WithMiddlewares(b.loggingMiddleware, b.authMiddleware),
+		bot.WithDefaultHandler(b.defaultHandler),
 	}
 
 	tgBot, err := bot.New(cfg.TelegramToken, opts...)
@@ -57,6 +74,32 @@ func (b *Bot) SetMonitor(mon *monitor.Monitor) {
 	b.monitor = mon
 }
 
+// SetDispatcher sets the notification pipeline /test_notifications sends through
+func (b *Bot) SetDispatcher(dispatcher *notifier.Dispatcher) {
+	b.dispatcher = dispatcher
+}
+
+// SetAllowedUsers replaces the allow-list of the running bot; empty allows everyone
+func (b *Bot) SetAllowedUsers(ids []int64) {
+	b.accessMu.Lock()
+	defer b.accessMu.Unlock()
+	b.allowedUsers = ids
+}
+
+// SetAllowedUsersFunc sets the callback /add_user and /remove_user change ALLOWED_USERS with
+func (b *Bot) SetAllowedUsersFunc(fn AllowedUsersFunc) {
+	b.accessMu.Lock()
+	defer b.accessMu.Unlock()
+	b.allowedUsersFunc = fn
+}
+
+// userAllowed reports whether ALLOWED_USERS lets a user use the bot
+func (b *Bot) userAllowed(userID int64) bool {
+	b.accessMu.RLock()
+	defer b.accessMu.RUnlock()
+	return len(b.allowedUsers) == 0 || slices.Contains(b.allowedUsers, userID)
+}
+
 // registerHandlers registers all command handlers
 func (b *Bot) registerHandlers() {
 	// Basic commands
@@ -65,16 +108,32 @@ func (b *Bot) registerHandlers() {
 	// Source management
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/add_source", bot.MatchTypePrefix, b.handleAddSource)
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/remove_source", bot.MatchTypePrefix, b.handleRemoveSource)
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/list_sources", bot.MatchTypeExact, b.handleListSources)
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/list_sources", bot.MatchTypePrefix, b.handleListSources)
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/add_from_template", bot.MatchTypePrefix, b.handleAddFromTemplate)
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/templates", bot.MatchTypePrefix, b.handleTemplates)
+
+	// Network discovery; /discovered before /discover, which prefixes it
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/discovered", bot.MatchTypePrefix, b.handleDiscovered)
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/discover", bot.MatchTypePrefix, b.handleDiscover)
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/promote", bot.MatchTypePrefix, b.handlePromote)
 
 	// Status and history
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/status", bot.MatchTypePrefix, b.handleStatus)
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/history", bot.MatchTypePrefix, b.handleHistory)
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/note", bot.MatchTypePrefix, b.handleNote)
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/uptime_all", bot.MatchTypePrefix, b.handleUptimeAll)
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/events", bot.MatchTypePrefix, b.handleEvents)
 
 	// Control
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/check", bot.MatchTypePrefix, b.handleCheck)
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/pause", bot.MatchTypePrefix, b.handlePause)
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/resume", bot.MatchTypePrefix, b.handleResume)
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/test_notifications", bot.MatchTypePrefix, b.handleTestNotifications)
+
+	// User management, in ADMIN_CHAT_IDS chats only
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/users", bot.MatchTypePrefix, b.handleUsers)
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/add_user", bot.MatchTypePrefix, b.handleAddUser)
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/remove_user", bot.MatchTypePrefix, b.handleRemoveUser)
 }
 
 // loggingMiddleware logs all incoming updates
@@ -90,41 +149,41 @@ func (b *Bot) loggingMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 			}
 		}
 
-		b.logger.Printf("Received update from user: %s", userInfo)
+		b.logger.Debugf("Received update from user: %s", userInfo)
 
 		next(ctx, tgBot, update)
 
-		b.logger.Printf("Processed update in %v", time.Since(start))
+		b.logger.Debugf("Processed update in %v", time.Since(start))
 	}
 }
 
-// authMiddleware checks if user is authorized
+// authMiddleware checks if user is authorized. Members of a chat mapped to a namespace
+// are, within that chat, whether or not ALLOWED_USERS lists them.
 func (b *Bot) authMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 	return func(ctx context.Context, tgBot *bot.Bot, update *models.Update) {
 		if update.Message == nil || update.Message.From == nil {
 			return
 		}
 
-		userID := update.Message.From.ID
+		from := update.Message.From
+		userID := from.ID
 
-		// Check if user is in allowed list (if configured)
-		if len(b.config.AllowedUsers) > 0 {
-			allowed := false
-			for _, allowedID := range b.config.AllowedUsers {
-				if userID == allowedID {
-					allowed = true
-					break
-				}
-			}
+		// Recorded before the check, so that admins can add users by @username once they
+		// have tried the bot
+		if err := b.storage.RecordTelegramUser(&storage.TelegramUser{
+			ID: userID, Username: from.Username, FirstName: from.FirstName,
+		}); err != nil {
+			b.logger.Warnf("Failed to record user %d: %v", userID, err)
+		}
 
-			if !allowed {
-				b.logger.Printf("Unauthorized access attempt from user ID: %d", userID)
-				_, _ = tgBot.SendMessage(ctx, &bot.SendMessageParams{
-					ChatID: update.Message.Chat.ID,
-					Text:   "❌ Unauthorized. You are not allowed to use this bot.",
-				})
-				return
-			}
+		// Check if user is in allowed list (if configured)
+		if !b.userAllowed(userID) && b.chatNamespace(update.Message.Chat.ID) == "" {
+			b.logger.Warnf("Unauthorized access attempt from user ID: %d", userID)
+			_, _ = tgBot.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "❌ Unauthorized. You are not allowed to use this bot.",
+			})
+			return
 		}
 
 		next(ctx, tgBot, update)
@@ -143,33 +202,36 @@ func (b *Bot) defaultHandler(ctx context.Context, tgBot *bot.Bot, update *models
 	})
 }
 
-// OnStatusChange is called by the Monitor when a source's status changes
-func (b *Bot) OnStatusChange(source *storage.Source, change *storage.StatusChange) {
-	ctx := context.Background()
-
-	// Get all chats for this source
-	chatIDs, err := b.storage.GetSourceChats(source.ID)
+// Send implements notifier.Sender: it sends a status change notification to the chat of a
+// Telegram sink
+func (b *Bot) Send(ctx context.Context, sink *storage.Sink, source *storage.Source, change *storage.StatusChange) (int, error) {
+	chatID, err := strconv.ParseInt(sink.Key, 10, 64)
 	if err != nil {
-		b.logger.Printf("Failed to get chats for source %s: %v", source.Name, err)
-		return
+		return 0, fmt.Errorf("invalid chat ID %q", sink.Key)
 	}
 
-	// Format notification message
-	message := b.formatStatusChangeMessage(source, change)
-
-	// Send to all configured chats
-	for _, chatID := range chatIDs {
-		_, err := b.bot.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:    chatID,
-			Text:      message,
-			ParseMode: models.ParseModeHTML,
-		})
-		if err != nil {
-			b.logger.Printf("Failed to send notification to chat %d: %v", chatID, err)
-		} else {
-			b.logger.Printf("Sent status change notification for %s to chat %d", source.Name, chatID)
-		}
+	_, err = b.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      b.formatStatusChangeMessage(source, change),
+		ParseMode: models.ParseModeHTML,
+	})
+	return 0, err
+}
+
+// SendGroup implements notifier.GroupSender: it sends one message for the changes of an alert
+// group to the chat of a Telegram sink
+func (b *Bot) SendGroup(ctx context.Context, sink *storage.Sink, group *storage.AlertGroup, changes []notifier.GroupedChange, window time.Duration) (int, error) {
+	chatID, err := strconv.ParseInt(sink.Key, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid chat ID %q", sink.Key)
 	}
+
+	_, err = b.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      formatAlertGroupMessage(group, changes, window),
+		ParseMode: models.ParseModeHTML,
+	})
+	return 0, err
 }
 
 // SendTestMessage sends a test message to a specific chat (for testing notifications)
@@ -180,3 +242,15 @@ func (b *Bot) SendTestMessage(ctx context.Context, chatID int64, text string) er
 	})
 	return err
 }
+
+// SendAlert sends an alert about the bot itself (not a source) to a chat
+func (b *Bot) SendAlert(ctx context.Context, chatID int64, text string) error {
+	_, err := b.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+	if err != nil {
+		b.logger.Errorf("Failed to send alert to chat %d: %v", chatID, err)
+	}
+	return err
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"strconv"
@@ -8,11 +10,18 @@ import (
 	"time"
 )
 
+// DefaultCORSAllowedHeaders are the request headers browsers may send cross-origin
+const DefaultCORSAllowedHeaders = "Content-Type,X-API-Key,Authorization"
+
+// DefaultOIDCRolesClaim is the JWT claim holding the caller's roles
+const DefaultOIDCRolesClaim = "roles"
+
 // Config holds all application configuration
 type Config struct {
 	// Telegram
 	TelegramToken string
 	AllowedUsers  []int64
+	AdminChatIDs  []int64 // Chats that receive alerts about the bot itself
 
 	// Database
 	DBPath string
@@ -23,12 +32,98 @@ type Config struct {
 	HTTPTimeout          time.Duration
 	DefaultCheckInterval time.Duration
 	MetricsRetention     time.Duration
+	CheckFlushInterval   time.Duration // How often unchanged check results are flushed to the DB (0 = write every check)
+	DNSResolver          string        // Where checks resolve host names, see ParseResolver; empty = system
+	HTTPUserAgent        string        // User-Agent of http checks; empty = Go's default
+	HTTPHeaders          string        // name=value pairs sent with every http check
+	// Transport of http checks; 0 = unlimited
+	HTTPMaxIdleConns        int           // Idle connections kept across all hosts
+	HTTPMaxConnsPerHost     int           // Connections per host, including active ones
+	HTTPDisableKeepAlives   bool          // A new connection for every check
+	HTTPTLSHandshakeTimeout time.Duration // Bound on the TLS handshake of a connection
+
+	// Soft delete
+	DeletedSourceRetention time.Duration // How long deleted sources stay in trash before purge
+
+	// Database maintenance
+	CompactionInterval time.Duration // 0 = scheduled compaction disabled
+
+	// Log file: empty path logs to stderr only
+	LogFile           string
+	LogFileMaxSize    int           // MB; 0 = no size limit
+	LogFileMaxAge     time.Duration // 0 = no age limit
+	LogFileMaxBackups int           // 0 = keep all rotated files
+
+	// Tracing: spans are exported over OTLP/HTTP; empty endpoint disables tracing
+	OTLPEndpoint       string
+	OTLPHeaders        string  // name=value pairs, e.g. "x-honeycomb-team=abc"
+	TracingSampleRatio float64 // Fraction of checks traced, 0 to 1
+
+	// Dead man's switch: heartbeats to an external service while the monitor runs
+	HeartbeatURL      string // Empty disables it
+	HeartbeatInterval time.Duration
+
+	// Error reporting to Sentry or a compatible service
+	SentryDSN         string // Empty disables it
+	SentryEnvironment string
+
+	// SLO burn rate alerts: multiples of the rate that spends exactly the whole error budget
+	SLOFastBurnRate float64 // Over the last hour; 0 disables
+	SLOSlowBurnRate float64 // Over the last 6 hours; 0 disables
+
+	// Watchdog: alert when no check has completed for too long
+	WatchdogTimeout time.Duration // 0 disables it
+
+	// Alert grouping: sources changing to the same status within the window are notified as one alert
+	AlertGroupWindow     time.Duration // 0 disables grouping
+	AlertGroupMinSources int           // Fewer changes in a window are notified one by one
+
+	// Scheduled reports, sent to the admin chats and by email
+	ReportPeriods []string // "week" and/or "month"; empty disables scheduled reports
+	ReportEmailTo []string // Empty sends reports to Telegram only
+
+	// SMTP server for email reports
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string // Empty sends without authentication
+	SMTPPassword string
+	SMTPFrom     string
+
+	// Network discovery of monitoring targets
+	DiscoveryRanges   []string      // IPv4 CIDR ranges scanned by default, e.g. "192.168.1.0/24"
+	DiscoveryPorts    []int         // TCP ports probed on every address
+	DiscoveryInterval time.Duration // Scheduled scans of DiscoveryRanges; 0 = on demand only
 
 	// API
 	APIEnabled bool
 	APIPort    int
+	APIBind    string // host:port or unix:///path/to.sock; empty listens on all interfaces at APIPort
 	APIKey     string
 
+	// API TLS: static certificate, or ACME (Let's Encrypt) for a hostname
+	TLSCertFile         string
+	TLSKeyFile          string
+	TLSAutocertDomain   string
+	TLSAutocertCacheDir string // Defaults to "autocert" next to the database
+
+	// API CORS: empty origins disables CORS
+	CORSAllowedOrigins []string
+	CORSAllowedHeaders []string
+
+	// API JWT auth: bearer tokens from an OIDC issuer; empty issuer disables it
+	OIDCIssuerURL   string
+	OIDCAudience    string
+	OIDCRolesClaim  string // Dot path into the claims, e.g. "realm_access.roles"
+	OIDCRoleMapping string // role=scope pairs, e.g. "monitor-admin=admin,monitor-viewer=read"
+	// Read-only GraphQL endpoint at /graphql
+	GraphQLEnabled bool
+	// net/http/pprof under /debug/pprof/ (admin scope)
+	PprofEnabled bool
+
+	// Public status page
+	StatusPageEnabled bool
+	StatusPageTitle   string
+
 	// Auto-restart
 	AutoRestartEnabled         bool
 	AutoRestartDelay           time.Duration
@@ -45,11 +140,62 @@ func Load() (*Config, error) {
 		PingCount:            getEnvInt("PING_COUNT", 3),
 		PingTimeout:          getEnvDuration("PING_TIMEOUT", 5*time.Second),
 		HTTPTimeout:          getEnvDuration("HTTP_TIMEOUT", 10*time.Second),
+		DNSResolver:          getEnv("DNS_RESOLVER", ""),
+		HTTPUserAgent:        getEnv("HTTP_USER_AGENT", ""),
+		HTTPHeaders:          getEnv("HTTP_HEADERS", ""),
+		HTTPMaxIdleConns:        getEnvInt("HTTP_MAX_IDLE_CONNS", 100),
+		HTTPMaxConnsPerHost:     getEnvInt("HTTP_MAX_CONNS_PER_HOST", 0),
+		HTTPDisableKeepAlives:   getEnvBool("HTTP_DISABLE_KEEP_ALIVES", false),
+		HTTPTLSHandshakeTimeout: getEnvDuration("HTTP_TLS_HANDSHAKE_TIMEOUT", 10*time.Second),
 		DefaultCheckInterval: getEnvDuration("DEFAULT_CHECK_INTERVAL", 30*time.Second),
 		MetricsRetention:     getEnvDuration("METRICS_RETENTION", 30*24*time.Hour), // 30 days
+		CheckFlushInterval:   getEnvDuration("CHECK_FLUSH_INTERVAL", 30*time.Second),
+		DeletedSourceRetention: getEnvDuration("DELETED_SOURCE_RETENTION", 30*24*time.Hour),
+		CompactionInterval:     getEnvDuration("COMPACTION_INTERVAL", 0),
+		LogFile:                getEnv("LOG_FILE", ""),
+		LogFileMaxSize:         getEnvInt("LOG_FILE_MAX_SIZE", 100),
+		LogFileMaxAge:          getEnvDuration("LOG_FILE_MAX_AGE", 0),
+		LogFileMaxBackups:      getEnvInt("LOG_FILE_MAX_BACKUPS", 5),
+		OTLPEndpoint:           getEnv("OTLP_ENDPOINT", ""),
+		OTLPHeaders:            getEnv("OTLP_HEADERS", ""),
+		TracingSampleRatio:     getEnvFloat("TRACING_SAMPLE_RATIO", 1.0),
+		HeartbeatURL:           getEnv("HEARTBEAT_URL", ""),
+		HeartbeatInterval:      getEnvDuration("HEARTBEAT_INTERVAL", time.Minute),
+		WatchdogTimeout:        getEnvDuration("WATCHDOG_TIMEOUT", 5*time.Minute),
+		AlertGroupWindow:       getEnvDuration("ALERT_GROUP_WINDOW", 0),
+		AlertGroupMinSources:   getEnvInt("ALERT_GROUP_MIN_SOURCES", 3),
+		SentryDSN:              getEnv("SENTRY_DSN", ""),
+		SLOFastBurnRate:        getEnvFloat("SLO_FAST_BURN_RATE", 14.4),
+		SLOSlowBurnRate:        getEnvFloat("SLO_SLOW_BURN_RATE", 6),
+		SentryEnvironment:      getEnv("SENTRY_ENVIRONMENT", ""),
+		ReportPeriods:          splitList(getEnv("REPORT_PERIODS", "")),
+		ReportEmailTo:          splitList(getEnv("REPORT_EMAIL_TO", "")),
+		SMTPHost:               getEnv("SMTP_HOST", ""),
+		SMTPPort:               getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:           getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:           getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:               getEnv("SMTP_FROM", ""),
+		DiscoveryRanges:        splitList(getEnv("DISCOVERY_RANGES", "")),
+		DiscoveryPorts:         parsePortsOrDefault(getEnv("DISCOVERY_PORTS", DefaultDiscoveryPorts)),
+		DiscoveryInterval:      getEnvDuration("DISCOVERY_INTERVAL", 0),
 		APIEnabled:           getEnvBool("API_ENABLED", true),
 		APIPort:              getEnvInt("API_PORT", 8080),
+		APIBind:              getEnv("API_BIND", ""),
 		APIKey:               getEnv("API_KEY", ""),
+		TLSCertFile:          getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:           getEnv("TLS_KEY_FILE", ""),
+		TLSAutocertDomain:    getEnv("TLS_AUTOCERT_DOMAIN", ""),
+		TLSAutocertCacheDir:  getEnv("TLS_AUTOCERT_CACHE_DIR", ""),
+		CORSAllowedOrigins:   splitList(getEnv("CORS_ALLOWED_ORIGINS", "")),
+		CORSAllowedHeaders:   splitList(getEnv("CORS_ALLOWED_HEADERS", DefaultCORSAllowedHeaders)),
+		OIDCIssuerURL:        getEnv("OIDC_ISSUER_URL", ""),
+		OIDCAudience:         getEnv("OIDC_AUDIENCE", ""),
+		OIDCRolesClaim:       getEnv("OIDC_ROLES_CLAIM", DefaultOIDCRolesClaim),
+		OIDCRoleMapping:      getEnv("OIDC_ROLE_MAPPING", ""),
+		GraphQLEnabled:       getEnvBool("GRAPHQL_ENABLED", false),
+		PprofEnabled:         getEnvBool("PPROF_ENABLED", false),
+		StatusPageEnabled:    getEnvBool("STATUS_PAGE_ENABLED", false),
+		StatusPageTitle:      getEnv("STATUS_PAGE_TITLE", "Service Status"),
 		// Auto-restart defaults
 		AutoRestartEnabled:         getEnvBool("AUTO_RESTART_ENABLED", true),
 		AutoRestartDelay:           getEnvDuration("AUTO_RESTART_DELAY", 30*time.Second),
@@ -62,15 +208,10 @@ func Load() (*Config, error) {
 	cfg.TelegramToken = os.Getenv("TELEGRAM_TOKEN")
 
 	// Optional: Allowed users (comma-separated list of user IDs)
-	if allowedUsersStr := os.Getenv("ALLOWED_USERS"); allowedUsersStr != "" {
-		userIDs := strings.Split(allowedUsersStr, ",")
-		for _, idStr := range userIDs {
-			id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64)
-			if err == nil {
-				cfg.AllowedUsers = append(cfg.AllowedUsers, id)
-			}
-		}
-	}
+	cfg.AllowedUsers = ParseIDs(os.Getenv("ALLOWED_USERS"))
+
+	// Optional: Admin chats (comma-separated list of chat IDs)
+	cfg.AdminChatIDs = ParseIDs(os.Getenv("ADMIN_CHAT_IDS"))
 
 	// Generate random API key if not provided
 	if cfg.APIEnabled && cfg.APIKey == "" {
@@ -88,10 +229,27 @@ func LoadFromMap(configMap map[string]string) (*Config, error) {
 		PingCount:            3,
 		PingTimeout:          5 * time.Second,
 		HTTPTimeout:          10 * time.Second,
+		HTTPMaxIdleConns:        100,
+		HTTPTLSHandshakeTimeout: 10 * time.Second,
 		DefaultCheckInterval: 30 * time.Second,
 		MetricsRetention:     30 * 24 * time.Hour,
+		CheckFlushInterval:   30 * time.Second,
+		DeletedSourceRetention: 30 * 24 * time.Hour,
+		LogFileMaxSize:         100,
+		LogFileMaxBackups:      5,
+		TracingSampleRatio:     1.0,
+		HeartbeatInterval:      time.Minute,
+		WatchdogTimeout:        5 * time.Minute,
+		AlertGroupMinSources:   3,
+		SLOFastBurnRate:        14.4,
+		SLOSlowBurnRate:        6,
+		SMTPPort:               587,
+		DiscoveryPorts:         parsePortsOrDefault(DefaultDiscoveryPorts),
 		APIEnabled:           true,
 		APIPort:              8080,
+		StatusPageTitle:      "Service Status",
+		CORSAllowedHeaders:   splitList(DefaultCORSAllowedHeaders),
+		OIDCRolesClaim:       DefaultOIDCRolesClaim,
 		// Auto-restart defaults
 		AutoRestartEnabled:         true,
 		AutoRestartDelay:           30 * time.Second,
@@ -106,13 +264,11 @@ func LoadFromMap(configMap map[string]string) (*Config, error) {
 	}
 
 	if val, ok := configMap["ALLOWED_USERS"]; ok && val != "" {
-		userIDs := strings.Split(val, ",")
-		for _, idStr := range userIDs {
-			id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64)
-			if err == nil {
-				cfg.AllowedUsers = append(cfg.AllowedUsers, id)
-			}
-		}
+		cfg.AllowedUsers = ParseIDs(val)
+	}
+
+	if val, ok := configMap["ADMIN_CHAT_IDS"]; ok && val != "" {
+		cfg.AdminChatIDs = ParseIDs(val)
 	}
 
 	if val, ok := configMap["DB_PATH"]; ok {
@@ -137,6 +293,40 @@ func LoadFromMap(configMap map[string]string) (*Config, error) {
 		}
 	}
 
+	if val, ok := configMap["DNS_RESOLVER"]; ok {
+		cfg.DNSResolver = val
+	}
+
+	if val, ok := configMap["HTTP_USER_AGENT"]; ok {
+		cfg.HTTPUserAgent = val
+	}
+
+	if val, ok := configMap["HTTP_HEADERS"]; ok {
+		cfg.HTTPHeaders = val
+	}
+
+	if val, ok := configMap["HTTP_MAX_IDLE_CONNS"]; ok {
+		if intVal, err := strconv.Atoi(val); err == nil {
+			cfg.HTTPMaxIdleConns = intVal
+		}
+	}
+
+	if val, ok := configMap["HTTP_MAX_CONNS_PER_HOST"]; ok {
+		if intVal, err := strconv.Atoi(val); err == nil {
+			cfg.HTTPMaxConnsPerHost = intVal
+		}
+	}
+
+	if val, ok := configMap["HTTP_DISABLE_KEEP_ALIVES"]; ok {
+		cfg.HTTPDisableKeepAlives = val == "true" || val == "1"
+	}
+
+	if val, ok := configMap["HTTP_TLS_HANDSHAKE_TIMEOUT"]; ok {
+		if duration, err := time.ParseDuration(val); err == nil {
+			cfg.HTTPTLSHandshakeTimeout = duration
+		}
+	}
+
 	if val, ok := configMap["DEFAULT_CHECK_INTERVAL"]; ok {
 		if duration, err := time.ParseDuration(val); err == nil {
 			cfg.DefaultCheckInterval = duration
@@ -149,6 +339,152 @@ func LoadFromMap(configMap map[string]string) (*Config, error) {
 		}
 	}
 
+	if val, ok := configMap["CHECK_FLUSH_INTERVAL"]; ok {
+		if duration, err := time.ParseDuration(val); err == nil {
+			cfg.CheckFlushInterval = duration
+		}
+	}
+
+	if val, ok := configMap["DELETED_SOURCE_RETENTION"]; ok {
+		if duration, err := time.ParseDuration(val); err == nil {
+			cfg.DeletedSourceRetention = duration
+		}
+	}
+
+	if val, ok := configMap["COMPACTION_INTERVAL"]; ok {
+		if duration, err := time.ParseDuration(val); err == nil {
+			cfg.CompactionInterval = duration
+		}
+	}
+
+	if val, ok := configMap["LOG_FILE"]; ok {
+		cfg.LogFile = val
+	}
+
+	if val, ok := configMap["LOG_FILE_MAX_SIZE"]; ok {
+		if intVal, err := strconv.Atoi(val); err == nil {
+			cfg.LogFileMaxSize = intVal
+		}
+	}
+
+	if val, ok := configMap["LOG_FILE_MAX_AGE"]; ok {
+		if duration, err := time.ParseDuration(val); err == nil {
+			cfg.LogFileMaxAge = duration
+		}
+	}
+
+	if val, ok := configMap["LOG_FILE_MAX_BACKUPS"]; ok {
+		if intVal, err := strconv.Atoi(val); err == nil {
+			cfg.LogFileMaxBackups = intVal
+		}
+	}
+
+	if val, ok := configMap["OTLP_ENDPOINT"]; ok {
+		cfg.OTLPEndpoint = val
+	}
+
+	if val, ok := configMap["OTLP_HEADERS"]; ok {
+		cfg.OTLPHeaders = val
+	}
+
+	if val, ok := configMap["TRACING_SAMPLE_RATIO"]; ok {
+		if floatVal, err := strconv.ParseFloat(val, 64); err == nil {
+			cfg.TracingSampleRatio = floatVal
+		}
+	}
+
+	if val, ok := configMap["HEARTBEAT_URL"]; ok {
+		cfg.HeartbeatURL = val
+	}
+
+	if val, ok := configMap["HEARTBEAT_INTERVAL"]; ok {
+		if duration, err := time.ParseDuration(val); err == nil {
+			cfg.HeartbeatInterval = duration
+		}
+	}
+
+	if val, ok := configMap["SENTRY_DSN"]; ok {
+		cfg.SentryDSN = val
+	}
+
+	if val, ok := configMap["SENTRY_ENVIRONMENT"]; ok {
+		cfg.SentryEnvironment = val
+	}
+
+	if val, ok := configMap["SLO_FAST_BURN_RATE"]; ok {
+		if floatVal, err := strconv.ParseFloat(val, 64); err == nil {
+			cfg.SLOFastBurnRate = floatVal
+		}
+	}
+
+	if val, ok := configMap["SLO_SLOW_BURN_RATE"]; ok {
+		if floatVal, err := strconv.ParseFloat(val, 64); err == nil {
+			cfg.SLOSlowBurnRate = floatVal
+		}
+	}
+
+	if val, ok := configMap["WATCHDOG_TIMEOUT"]; ok {
+		if duration, err := time.ParseDuration(val); err == nil {
+			cfg.WatchdogTimeout = duration
+		}
+	}
+
+	if val, ok := configMap["ALERT_GROUP_WINDOW"]; ok {
+		if duration, err := time.ParseDuration(val); err == nil {
+			cfg.AlertGroupWindow = duration
+		}
+	}
+
+	if val, ok := configMap["ALERT_GROUP_MIN_SOURCES"]; ok {
+		if intVal, err := strconv.Atoi(val); err == nil {
+			cfg.AlertGroupMinSources = intVal
+		}
+	}
+
+	if val, ok := configMap["REPORT_PERIODS"]; ok {
+		cfg.ReportPeriods = splitList(val)
+	}
+
+	if val, ok := configMap["REPORT_EMAIL_TO"]; ok {
+		cfg.ReportEmailTo = splitList(val)
+	}
+
+	if val, ok := configMap["SMTP_HOST"]; ok {
+		cfg.SMTPHost = val
+	}
+
+	if val, ok := configMap["SMTP_PORT"]; ok {
+		if intVal, err := strconv.Atoi(val); err == nil {
+			cfg.SMTPPort = intVal
+		}
+	}
+
+	if val, ok := configMap["SMTP_USERNAME"]; ok {
+		cfg.SMTPUsername = val
+	}
+
+	if val, ok := configMap["SMTP_PASSWORD"]; ok {
+		cfg.SMTPPassword = val
+	}
+
+	if val, ok := configMap["SMTP_FROM"]; ok {
+		cfg.SMTPFrom = val
+	}
+
+	if val, ok := configMap["DISCOVERY_RANGES"]; ok {
+		cfg.DiscoveryRanges = splitList(val)
+	}
+
+	if val, ok := configMap["DISCOVERY_PORTS"]; ok {
+		cfg.DiscoveryPorts = parsePortsOrDefault(val)
+	}
+
+	if val, ok := configMap["DISCOVERY_INTERVAL"]; ok {
+		if duration, err := time.ParseDuration(val); err == nil {
+			cfg.DiscoveryInterval = duration
+		}
+	}
+
 	if val, ok := configMap["API_ENABLED"]; ok {
 		cfg.APIEnabled = val == "true" || val == "1"
 	}
@@ -159,10 +495,70 @@ func LoadFromMap(configMap map[string]string) (*Config, error) {
 		}
 	}
 
+	if val, ok := configMap["API_BIND"]; ok {
+		cfg.APIBind = val
+	}
+
 	if val, ok := configMap["API_KEY"]; ok {
 		cfg.APIKey = val
 	}
 
+	if val, ok := configMap["TLS_CERT_FILE"]; ok {
+		cfg.TLSCertFile = val
+	}
+
+	if val, ok := configMap["TLS_KEY_FILE"]; ok {
+		cfg.TLSKeyFile = val
+	}
+
+	if val, ok := configMap["TLS_AUTOCERT_DOMAIN"]; ok {
+		cfg.TLSAutocertDomain = val
+	}
+
+	if val, ok := configMap["TLS_AUTOCERT_CACHE_DIR"]; ok {
+		cfg.TLSAutocertCacheDir = val
+	}
+
+	if val, ok := configMap["CORS_ALLOWED_ORIGINS"]; ok {
+		cfg.CORSAllowedOrigins = splitList(val)
+	}
+
+	if val, ok := configMap["CORS_ALLOWED_HEADERS"]; ok && val != "" {
+		cfg.CORSAllowedHeaders = splitList(val)
+	}
+
+	if val, ok := configMap["OIDC_ISSUER_URL"]; ok {
+		cfg.OIDCIssuerURL = val
+	}
+
+	if val, ok := configMap["OIDC_AUDIENCE"]; ok {
+		cfg.OIDCAudience = val
+	}
+
+	if val, ok := configMap["OIDC_ROLES_CLAIM"]; ok && val != "" {
+		cfg.OIDCRolesClaim = val
+	}
+
+	if val, ok := configMap["OIDC_ROLE_MAPPING"]; ok {
+		cfg.OIDCRoleMapping = val
+	}
+
+	if val, ok := configMap["GRAPHQL_ENABLED"]; ok {
+		cfg.GraphQLEnabled = val == "true" || val == "1"
+	}
+
+	if val, ok := configMap["PPROF_ENABLED"]; ok {
+		cfg.PprofEnabled = val == "true" || val == "1"
+	}
+
+	if val, ok := configMap["STATUS_PAGE_ENABLED"]; ok {
+		cfg.StatusPageEnabled = val == "true" || val == "1"
+	}
+
+	if val, ok := configMap["STATUS_PAGE_TITLE"]; ok && val != "" {
+		cfg.StatusPageTitle = val
+	}
+
 	if val, ok := configMap["AUTO_RESTART_ENABLED"]; ok {
 		cfg.AutoRestartEnabled = val == "true" || val == "1"
 	}
@@ -199,8 +595,53 @@ func LoadFromMap(configMap map[string]string) (*Config, error) {
 	return cfg, nil
 }
 
-// getEnv returns environment variable or default value
+// EncryptionKey returns the master key for encrypting secrets at rest.
+// It is read from ENCRYPTION_KEY or from the file named by ENCRYPTION_KEY_FILE.
+// A base64-encoded 32-byte value is used directly; any other value is treated as a
+// passphrase and hashed with SHA-256. Returns nil when no key is configured.
+func EncryptionKey() ([]byte, error) {
+	raw := os.Getenv("ENCRYPTION_KEY")
+	if path := os.Getenv("ENCRYPTION_KEY_FILE"); raw == "" && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ENCRYPTION_KEY_FILE: %w", err)
+		}
+		raw = string(data)
+	}
+
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	if key, err := base64.StdEncoding.DecodeString(raw); err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	sum := sha256.Sum256([]byte(raw))
+	return sum[:], nil
+}
+
+// FileValue reads the value of key from the file named by key+"_FILE", the convention for
+// Docker and Kubernetes secrets (e.g. TELEGRAM_TOKEN_FILE=/run/secrets/telegram_token).
+// Surrounding whitespace is trimmed. ok is false when key+"_FILE" is not set.
+func FileValue(key string) (value string, ok bool, err error) {
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return "", false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", true, fmt.Errorf("failed to read %s_FILE: %w", key, err)
+	}
+	return strings.TrimSpace(string(data)), true, nil
+}
+
+// getEnv returns environment variable or default value. A value in key+"_FILE" wins.
 func getEnv(key, defaultValue string) string {
+	if value, ok, err := FileValue(key); ok && err == nil {
+		return value
+	}
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
@@ -244,3 +685,49 @@ func getEnvFloat(key string, defaultValue float64) float64 {
 	}
 	return defaultValue
 }
+
+// ParseHeaderList parses comma-separated name=value pairs such as OTLP_HEADERS
+func ParseHeaderList(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range splitList(value) {
+		name, val, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q (use name=value)", pair)
+		}
+		headers[name] = strings.TrimSpace(val)
+	}
+	return headers, nil
+}
+
+// splitList splits a comma-separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ParseIDs parses a comma-separated list of Telegram user or chat IDs such as ALLOWED_USERS,
+// skipping entries that aren't numbers (Validate reports those)
+func ParseIDs(value string) []int64 {
+	var ids []int64
+	for _, item := range splitList(value) {
+		if id, err := strconv.ParseInt(item, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// JoinIDs formats IDs the way ALLOWED_USERS and ADMIN_CHAT_IDS expect them
+func JoinIDs(ids []int64) string {
+	fields := make([]string, len(ids))
+	for i, id := range ids {
+		fields[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(fields, ",")
+}
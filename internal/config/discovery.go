@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// DefaultDiscoveryPorts are the TCP ports a discovery scan probes when DISCOVERY_PORTS is unset
+const DefaultDiscoveryPorts = "22,80,443"
+
+// MaxDiscoveryAddresses caps the addresses of one discovery scan, across all its ranges
+const MaxDiscoveryAddresses = 4096
+
+// ParseDiscoveryRanges parses IPv4 CIDR ranges (a bare address is a /32) and checks that
+// together they hold at most MaxDiscoveryAddresses addresses
+func ParseDiscoveryRanges(ranges []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(ranges))
+	total := 0
+	for _, r := range ranges {
+		r = strings.TrimSpace(r)
+		if !strings.Contains(r, "/") {
+			r += "/32"
+		}
+		prefix, err := netip.ParsePrefix(r)
+		if err != nil || !prefix.Addr().Is4() {
+			return nil, fmt.Errorf("%q is not an IPv4 CIDR range (use e.g. 192.168.1.0/24)", r)
+		}
+		total += 1 << (32 - prefix.Bits())
+		if total > MaxDiscoveryAddresses {
+			return nil, fmt.Errorf("ranges hold more than %d addresses (a /20)", MaxDiscoveryAddresses)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// ParsePorts parses a comma-separated list of TCP ports
+func ParsePorts(value string) ([]int, error) {
+	var ports []int
+	for _, item := range splitList(value) {
+		port, err := strconv.Atoi(item)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("%q is not a TCP port", item)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// parsePortsOrDefault parses ports, falling back to DefaultDiscoveryPorts when they are invalid
+func parsePortsOrDefault(value string) []int {
+	ports, err := ParsePorts(value)
+	if err != nil {
+		ports, _ = ParsePorts(DefaultDiscoveryPorts)
+	}
+	return ports
+}
@@ -0,0 +1,51 @@
+package config
+
+import "strings"
+
+// FeatureKeyPrefix prefixes the config keys holding feature flags, e.g. FEATURE_EVENT_STREAM
+const FeatureKeyPrefix = "FEATURE_"
+
+// FeatureFlag gates an experimental subsystem so it can be toggled at runtime per deployment
+type FeatureFlag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+// FeatureFlags lists every known feature flag
+var FeatureFlags = []FeatureFlag{
+	{Name: "event_stream", Description: "Live status changes as Server-Sent Events at /events/stream", Default: true},
+	{Name: "selective_reload", Description: "Reload only the affected components on config change instead of restarting the bot", Default: true},
+}
+
+// LookupFeatureFlag returns the flag with the given name
+func LookupFeatureFlag(name string) (FeatureFlag, bool) {
+	for _, flag := range FeatureFlags {
+		if flag.Name == name {
+			return flag, true
+		}
+	}
+	return FeatureFlag{}, false
+}
+
+// FeatureKey returns the config key of a feature flag: event_stream -> FEATURE_EVENT_STREAM
+func FeatureKey(name string) string {
+	return FeatureKeyPrefix + strings.ToUpper(name)
+}
+
+// FeatureEnabled reports whether a flag is on in configMap, falling back to its default
+// when unset or unparseable. Unknown flags are off.
+func FeatureEnabled(configMap map[string]string, name string) bool {
+	flag, ok := LookupFeatureFlag(name)
+	if !ok {
+		return false
+	}
+	switch configMap[FeatureKey(name)] {
+	case "true", "1":
+		return true
+	case "false", "0":
+		return false
+	default:
+		return flag.Default
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// Resolver is where checks resolve host names: the system resolver when empty, otherwise
+// the given DNS servers or a DNS-over-HTTPS endpoint
+type Resolver struct {
+	Servers []string // host:port of plain DNS servers, tried in turn
+	DoHURL  string   // RFC 8484 endpoint, e.g. https://1.1.1.1/dns-query
+}
+
+// IsSystem reports whether the resolver is the system's
+func (r Resolver) IsSystem() bool {
+	return len(r.Servers) == 0 && r.DoHURL == ""
+}
+
+// ParseResolver parses a DNS_RESOLVER value: "" or "system", a comma-separated list of
+// DNS server IPs with an optional port (default 53), or an https:// DoH URL
+func ParseResolver(spec string) (Resolver, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || strings.EqualFold(spec, "system") {
+		return Resolver{}, nil
+	}
+
+	if strings.HasPrefix(spec, "https://") {
+		u, err := url.Parse(spec)
+		if err != nil || u.Host == "" {
+			return Resolver{}, fmt.Errorf("%q is not a DNS-over-HTTPS URL", spec)
+		}
+		return Resolver{DoHURL: spec}, nil
+	}
+
+	var resolver Resolver
+	for _, item := range splitList(spec) {
+		if addr, err := netip.ParseAddr(item); err == nil {
+			resolver.Servers = append(resolver.Servers, netip.AddrPortFrom(addr, 53).String())
+			continue
+		}
+		addrPort, err := netip.ParseAddrPort(item)
+		if err != nil || addrPort.Port() == 0 {
+			return Resolver{}, fmt.Errorf("%q is not a DNS server IP (use e.g. 1.1.1.1, 10.0.0.2:5353 or [2606:4700::1111]:53), \"system\" or an https:// DoH URL", item)
+		}
+		resolver.Servers = append(resolver.Servers, addrPort.String())
+	}
+	return resolver, nil
+}
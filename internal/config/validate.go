@@ -0,0 +1,294 @@
+package config
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Problem severities
+const (
+	SeverityError   = "error"   // The value is rejected or silently replaced by its default
+	SeverityWarning = "warning" // The value is accepted but probably not what was meant
+)
+
+// Problem is an issue found while validating a config map
+type Problem struct {
+	Key      string `json:"key,omitempty"` // Empty for problems spanning several keys
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Value types of known config keys
+var (
+	intKeys = []string{"PING_COUNT", "API_PORT", "AUTO_RESTART_MAX_ATTEMPTS", "LOG_FILE_MAX_SIZE", "LOG_FILE_MAX_BACKUPS", "SMTP_PORT", "ALERT_GROUP_MIN_SOURCES",
+		"HTTP_MAX_IDLE_CONNS", "HTTP_MAX_CONNS_PER_HOST"}
+
+	durationKeys = []string{
+		"PING_TIMEOUT", "HTTP_TIMEOUT", "DEFAULT_CHECK_INTERVAL", "METRICS_RETENTION",
+		"CHECK_FLUSH_INTERVAL", "DELETED_SOURCE_RETENTION", "COMPACTION_INTERVAL",
+		"LOG_FILE_MAX_AGE", "HEARTBEAT_INTERVAL", "WATCHDOG_TIMEOUT", "AUTO_RESTART_DELAY", "AUTO_RESTART_MAX_DELAY",
+		"DISCOVERY_INTERVAL", "ALERT_GROUP_WINDOW", "HTTP_TLS_HANDSHAKE_TIMEOUT",
+	}
+
+	boolKeys = []string{"API_ENABLED", "GRAPHQL_ENABLED", "PPROF_ENABLED", "STATUS_PAGE_ENABLED", "AUTO_RESTART_ENABLED", "HTTP_DISABLE_KEEP_ALIVES"}
+
+	floatKeys = []string{"AUTO_RESTART_BACKOFF_MULTIPLIER", "TRACING_SAMPLE_RATIO", "SLO_FAST_BURN_RATE", "SLO_SLOW_BURN_RATE"}
+
+	stringKeys = []string{
+		"TELEGRAM_TOKEN", "ALLOWED_USERS", "ADMIN_CHAT_IDS", "DB_PATH", "LOG_FILE", "OTLP_ENDPOINT", "OTLP_HEADERS", "HEARTBEAT_URL", "SENTRY_DSN", "SENTRY_ENVIRONMENT", "API_BIND", "API_KEY",
+		"TLS_CERT_FILE", "TLS_KEY_FILE", "TLS_AUTOCERT_DOMAIN", "TLS_AUTOCERT_CACHE_DIR",
+		"CORS_ALLOWED_ORIGINS", "CORS_ALLOWED_HEADERS",
+		"OIDC_ISSUER_URL", "OIDC_AUDIENCE", "OIDC_ROLES_CLAIM", "OIDC_ROLE_MAPPING",
+		"STATUS_PAGE_TITLE",
+		"REPORT_PERIODS", "REPORT_EMAIL_TO", "SMTP_HOST", "SMTP_USERNAME", "SMTP_PASSWORD", "SMTP_FROM",
+		"DISCOVERY_RANGES", "DISCOVERY_PORTS", "DNS_RESOLVER", "HTTP_USER_AGENT", "HTTP_HEADERS",
+		"WEBHOOK_BASE_URL", "PUBLIC_URL", // Read by the dashboard only
+	}
+)
+
+// ReportPeriods are the periods reports can cover
+var ReportPeriods = []string{"week", "month"}
+
+// deadMansSwitchMinInterval is the shortest HEARTBEAT_INTERVAL; the sender checks every 10s
+const deadMansSwitchMinInterval = 10 * time.Second
+
+// telegramTokenPattern matches BotFather tokens: <bot id>:<35 character secret>
+var telegramTokenPattern = regexp.MustCompile(`^\d+:[A-Za-z0-9_-]{30,}$`)
+
+// Validate checks a config map without applying it: values that don't parse (and would
+// silently fall back to defaults in LoadFromMap), out-of-range settings, unknown keys and
+// LoadFromMap's own errors. Problems are sorted by key.
+func Validate(configMap map[string]string) []Problem {
+	var problems []Problem
+	report := func(key, severity, format string, args ...any) {
+		problems = append(problems, Problem{Key: key, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	known := make(map[string]bool)
+	for _, keys := range [][]string{intKeys, durationKeys, boolKeys, floatKeys, stringKeys} {
+		for _, key := range keys {
+			known[key] = true
+		}
+	}
+	featureKeys := make([]string, 0, len(FeatureFlags))
+	for _, flag := range FeatureFlags {
+		featureKeys = append(featureKeys, FeatureKey(flag.Name))
+		known[FeatureKey(flag.Name)] = true
+	}
+	for key := range configMap {
+		if !known[key] {
+			if strings.HasPrefix(key, FeatureKeyPrefix) {
+				report(key, SeverityWarning, "unknown feature flag")
+			} else {
+				report(key, SeverityWarning, "unknown config key")
+			}
+		}
+	}
+
+	// Syntax: LoadFromMap ignores values it can't parse
+	for _, key := range intKeys {
+		if val, ok := configMap[key]; ok {
+			if _, err := strconv.Atoi(val); err != nil {
+				report(key, SeverityError, "%q is not an integer", val)
+			}
+		}
+	}
+	for _, key := range durationKeys {
+		if val, ok := configMap[key]; ok {
+			if d, err := time.ParseDuration(val); err != nil {
+				report(key, SeverityError, "%q is not a duration (use e.g. 30s, 5m, 24h)", val)
+			} else if d < 0 {
+				report(key, SeverityError, "must not be negative")
+			}
+		}
+	}
+	for _, key := range slices.Concat(boolKeys, featureKeys) {
+		if val, ok := configMap[key]; ok {
+			if val != "true" && val != "false" && val != "1" && val != "0" {
+				report(key, SeverityError, "%q is not a boolean (use true or false)", val)
+			}
+		}
+	}
+	for _, key := range floatKeys {
+		if val, ok := configMap[key]; ok {
+			if _, err := strconv.ParseFloat(val, 64); err != nil {
+				report(key, SeverityError, "%q is not a number", val)
+			}
+		}
+	}
+	if val := configMap["ALLOWED_USERS"]; val != "" {
+		for _, id := range strings.Split(val, ",") {
+			if _, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64); err != nil {
+				report("ALLOWED_USERS", SeverityError, "%q is not a Telegram user ID", strings.TrimSpace(id))
+			}
+		}
+	}
+	if val := configMap["ADMIN_CHAT_IDS"]; val != "" {
+		for _, id := range strings.Split(val, ",") {
+			if _, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64); err != nil {
+				report("ADMIN_CHAT_IDS", SeverityError, "%q is not a Telegram chat ID", strings.TrimSpace(id))
+			}
+		}
+	}
+
+	cfg, err := LoadFromMap(configMap)
+	if err != nil {
+		report("", SeverityError, "%v", err)
+		sortProblems(problems)
+		return problems
+	}
+
+	// Semantics, on the parsed values (defaults included)
+	if _, ok := configMap["API_PORT"]; ok && (cfg.APIPort < 1 || cfg.APIPort > 65535) {
+		report("API_PORT", SeverityError, "must be between 1 and 65535")
+	}
+	if cfg.PingCount < 1 {
+		report("PING_COUNT", SeverityError, "must be at least 1")
+	}
+	if cfg.PingTimeout <= 0 {
+		report("PING_TIMEOUT", SeverityError, "must be positive")
+	}
+	if cfg.HTTPTimeout <= 0 {
+		report("HTTP_TIMEOUT", SeverityError, "must be positive")
+	}
+	if cfg.HTTPMaxIdleConns < 0 {
+		report("HTTP_MAX_IDLE_CONNS", SeverityError, "must not be negative")
+	}
+	if cfg.HTTPMaxConnsPerHost < 0 {
+		report("HTTP_MAX_CONNS_PER_HOST", SeverityError, "must not be negative")
+	}
+	if cfg.HTTPTLSHandshakeTimeout < 0 {
+		report("HTTP_TLS_HANDSHAKE_TIMEOUT", SeverityError, "must not be negative")
+	}
+	if cfg.DefaultCheckInterval < time.Second {
+		report("DEFAULT_CHECK_INTERVAL", SeverityError, "must be at least 1s")
+	} else if cfg.HTTPTimeout > cfg.DefaultCheckInterval {
+		report("HTTP_TIMEOUT", SeverityWarning, "is longer than DEFAULT_CHECK_INTERVAL (%v), checks may overlap", cfg.DefaultCheckInterval)
+	}
+	if cfg.MetricsRetention > 0 && cfg.MetricsRetention < time.Hour {
+		report("METRICS_RETENTION", SeverityWarning, "history older than %v will be deleted", cfg.MetricsRetention)
+	}
+	if cfg.LogFileMaxSize < 0 {
+		report("LOG_FILE_MAX_SIZE", SeverityError, "must not be negative")
+	}
+	if cfg.LogFileMaxBackups < 0 {
+		report("LOG_FILE_MAX_BACKUPS", SeverityError, "must not be negative")
+	}
+	if cfg.TracingSampleRatio < 0 || cfg.TracingSampleRatio > 1 {
+		report("TRACING_SAMPLE_RATIO", SeverityError, "must be between 0 and 1")
+	}
+	if cfg.OTLPEndpoint != "" && !strings.HasPrefix(cfg.OTLPEndpoint, "http://") && !strings.HasPrefix(cfg.OTLPEndpoint, "https://") {
+		report("OTLP_ENDPOINT", SeverityError, "must be an http:// or https:// URL, e.g. http://localhost:4318")
+	}
+	if _, err := ParseHeaderList(cfg.OTLPHeaders); err != nil {
+		report("OTLP_HEADERS", SeverityError, "%v", err)
+	}
+	if cfg.HeartbeatURL != "" && !strings.HasPrefix(cfg.HeartbeatURL, "http://") && !strings.HasPrefix(cfg.HeartbeatURL, "https://") {
+		report("HEARTBEAT_URL", SeverityError, "must be an http:// or https:// URL")
+	}
+	if cfg.HeartbeatInterval < deadMansSwitchMinInterval {
+		report("HEARTBEAT_INTERVAL", SeverityError, "must be at least %v", deadMansSwitchMinInterval)
+	}
+	if cfg.SentryDSN != "" {
+		if u, err := url.Parse(cfg.SentryDSN); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User == nil || strings.Trim(u.Path, "/") == "" {
+			report("SENTRY_DSN", SeverityError, "must be a DSN like https://<key>@<host>/<project id>")
+		}
+	}
+	if cfg.SLOFastBurnRate < 0 {
+		report("SLO_FAST_BURN_RATE", SeverityError, "must not be negative")
+	}
+	if cfg.SLOSlowBurnRate < 0 {
+		report("SLO_SLOW_BURN_RATE", SeverityError, "must not be negative")
+	}
+	for _, period := range cfg.ReportPeriods {
+		if !slices.Contains(ReportPeriods, period) {
+			report("REPORT_PERIODS", SeverityError, "%q is not a report period (use %s)", period, strings.Join(ReportPeriods, " or "))
+		}
+	}
+	for _, address := range cfg.ReportEmailTo {
+		if _, err := mail.ParseAddress(address); err != nil {
+			report("REPORT_EMAIL_TO", SeverityError, "%q is not an email address", address)
+		}
+	}
+	if len(cfg.ReportEmailTo) > 0 && (cfg.SMTPHost == "" || cfg.SMTPFrom == "") {
+		report("REPORT_EMAIL_TO", SeverityError, "needs SMTP_HOST and SMTP_FROM to send email")
+	}
+	if len(cfg.ReportEmailTo) > 0 && len(cfg.ReportPeriods) == 0 {
+		report("REPORT_EMAIL_TO", SeverityWarning, "has no effect without REPORT_PERIODS")
+	}
+	if cfg.SMTPFrom != "" {
+		if _, err := mail.ParseAddress(cfg.SMTPFrom); err != nil {
+			report("SMTP_FROM", SeverityError, "%q is not an email address", cfg.SMTPFrom)
+		}
+	}
+	if cfg.SMTPPort < 1 || cfg.SMTPPort > 65535 {
+		report("SMTP_PORT", SeverityError, "must be between 1 and 65535")
+	}
+	if _, err := ParseResolver(cfg.DNSResolver); err != nil {
+		report("DNS_RESOLVER", SeverityError, "%v", err)
+	}
+	if strings.ContainsAny(cfg.HTTPUserAgent, "\r\n") {
+		report("HTTP_USER_AGENT", SeverityError, "must be a single line")
+	}
+	if _, err := ParseHeaderList(cfg.HTTPHeaders); err != nil {
+		report("HTTP_HEADERS", SeverityError, "%v", err)
+	}
+	if _, err := ParseDiscoveryRanges(cfg.DiscoveryRanges); err != nil {
+		report("DISCOVERY_RANGES", SeverityError, "%v", err)
+	}
+	if val, ok := configMap["DISCOVERY_PORTS"]; ok {
+		if _, err := ParsePorts(val); err != nil {
+			report("DISCOVERY_PORTS", SeverityError, "%v", err)
+		}
+	}
+	if cfg.DiscoveryInterval > 0 && len(cfg.DiscoveryRanges) == 0 {
+		report("DISCOVERY_INTERVAL", SeverityWarning, "has no effect without DISCOVERY_RANGES")
+	}
+	if cfg.WatchdogTimeout < 0 {
+		report("WATCHDOG_TIMEOUT", SeverityError, "must not be negative")
+	}
+	if cfg.AlertGroupWindow < 0 {
+		report("ALERT_GROUP_WINDOW", SeverityError, "must not be negative")
+	} else if cfg.AlertGroupWindow > 10*time.Minute {
+		report("ALERT_GROUP_WINDOW", SeverityWarning, "holds every notification back for %v", cfg.AlertGroupWindow)
+	}
+	if cfg.AlertGroupMinSources < 2 {
+		report("ALERT_GROUP_MIN_SOURCES", SeverityError, "must be at least 2")
+	}
+	if cfg.AutoRestartBackoffMultiplier < 1 {
+		report("AUTO_RESTART_BACKOFF_MULTIPLIER", SeverityError, "must be at least 1")
+	}
+	if cfg.AutoRestartMaxDelay < cfg.AutoRestartDelay {
+		report("AUTO_RESTART_MAX_DELAY", SeverityError, "must not be shorter than AUTO_RESTART_DELAY (%v)", cfg.AutoRestartDelay)
+	}
+	if cfg.TelegramToken != "" && cfg.TelegramToken != "your_bot_token_here" && !telegramTokenPattern.MatchString(cfg.TelegramToken) {
+		report("TELEGRAM_TOKEN", SeverityError, "does not look like a bot token (expected <bot id>:<secret> from @BotFather)")
+	}
+
+	sortProblems(problems)
+	return problems
+}
+
+// HasErrors reports whether any problem is an error rather than a warning
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// sortProblems orders problems by key, keeping the check order within a key
+func sortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Key < problems[j].Key
+	})
+}
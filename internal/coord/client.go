@@ -0,0 +1,235 @@
+// Package coord coordinates instances through Redis: a lease so only one instance checks
+// sources and sends notifications, and a channel that fans status changes out to read-only
+// replicas. It speaks the small subset of the Redis protocol it needs, so any server
+// compatible with Redis 2.6+ (Valkey, KeyDB, managed Redis) works.
+package coord
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dialTimeout bounds connecting to Redis and each command round trip
+const dialTimeout = 5 * time.Second
+
+// DefaultPrefix namespaces keys and channels, so deployments can share a Redis server
+const DefaultPrefix = "outage-monitor:"
+
+// Client runs commands over one connection, reconnecting after errors
+type Client struct {
+	addr     string
+	username string
+	password string
+	db       int
+	tls      bool
+	prefix   string
+
+	mu   sync.Mutex
+	conn *conn
+}
+
+// conn is a connection with its reader
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// Open parses a redis:// or rediss:// (TLS) URL such as redis://:password@host:6379/0 and
+// checks the server answers. Keys and channels are prefixed with prefix.
+func Open(rawURL, prefix string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid Redis URL: scheme must be redis or rediss")
+	}
+	c := &Client{addr: u.Host, tls: u.Scheme == "rediss", prefix: prefix}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis URL: database must be a number")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	if _, err := c.Do(ctx, "PING"); err != nil {
+		return nil, fmt.Errorf("failed to reach Redis at %s: %w", c.addr, err)
+	}
+	return c, nil
+}
+
+// Key returns name with the client's prefix
+func (c *Client) Key(name string) string {
+	return c.prefix + name
+}
+
+// Addr returns the server address
+func (c *Client) Addr() string {
+	return c.addr
+}
+
+// Do runs a command and returns its reply: a string, int64, []byte (nil for a nil bulk
+// string) or []any. Error replies are returned as errors.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		cn, err := c.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = cn
+	}
+	reply, err := c.conn.roundTrip(ctx, args)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		c.conn.Close() // The stream may be out of sync; reconnect next time
+		c.conn = nil
+	}
+	return reply, err
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// dial connects, authenticates and selects the database
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var nc net.Conn
+	var err error
+	if c.tls {
+		host, _, _ := net.SplitHostPort(c.addr)
+		nc, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", c.addr)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", c.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc)}
+
+	if c.password != "" {
+		args := []string{"AUTH", c.password}
+		if c.username != "" {
+			args = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := cn.roundTrip(ctx, args); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := cn.roundTrip(ctx, []string{"SELECT", strconv.Itoa(c.db)}); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// roundTrip writes a command and reads its reply
+func (cn *conn) roundTrip(ctx context.Context, args []string) (any, error) {
+	deadline := time.Now().Add(dialTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	cn.SetDeadline(deadline)
+	if err := cn.write(args); err != nil {
+		return nil, err
+	}
+	return cn.read()
+}
+
+// write sends a command as an array of bulk strings
+func (cn *conn) write(args []string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := cn.Write([]byte(b.String()))
+	return err
+}
+
+// read parses one reply
+func (cn *conn) read() (any, error) {
+	line, err := cn.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return []byte(nil), err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return []any(nil), err
+		}
+		items := make([]any, n)
+		for i := range items {
+			// Error items (e.g. in EXEC replies) are kept as values
+			if items[i], err = cn.read(); err != nil {
+				var replyErr redisError
+				if !errors.As(err, &replyErr) {
+					return nil, err
+				}
+				items[i] = err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
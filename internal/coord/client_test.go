@@ -0,0 +1,286 @@
+package coord
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeReply is a canned raw RESP reply; hangup closes the connection after writing it
+type fakeReply struct {
+	raw    string
+	hangup bool
+}
+
+// fakeRedis is an in-process RESP server that answers each command by its name
+type fakeRedis struct {
+	ln      net.Listener
+	replies map[string]fakeReply
+
+	mu       sync.Mutex
+	commands [][]string
+	conns    int
+}
+
+func newFakeRedis(t *testing.T, replies map[string]fakeReply) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	f := &fakeRedis{ln: ln, replies: replies}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns++
+			f.mu.Unlock()
+			go f.serve(nc)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) url() string {
+	return "redis://" + f.ln.Addr().String()
+}
+
+// serve reads commands as arrays of bulk strings and writes the canned replies
+func (f *fakeRedis) serve(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, args)
+		f.mu.Unlock()
+
+		reply, ok := f.replies[strings.ToUpper(args[0])]
+		if !ok {
+			switch strings.ToUpper(args[0]) {
+			case "PING":
+				reply = fakeReply{raw: "+PONG\r\n"}
+			case "AUTH", "SELECT":
+				reply = fakeReply{raw: "+OK\r\n"}
+			default:
+				reply = fakeReply{raw: "-ERR unknown command '" + args[0] + "'\r\n"}
+			}
+		}
+		if _, err := io.WriteString(nc, reply.raw); err != nil || reply.hangup {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) received() ([][]string, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.commands...), f.conns
+}
+
+// readCommand parses one client command
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+	if line[0] != '*' || err != nil || n < 1 {
+		return nil, errors.New("malformed command")
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+		if line[0] != '$' || err != nil {
+			return nil, errors.New("malformed argument")
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestClientReplyTypes(t *testing.T) {
+	server := newFakeRedis(t, map[string]fakeReply{
+		"STATUS":   {raw: "+OK\r\n"},
+		"INT":      {raw: ":-42\r\n"},
+		"BULK":     {raw: "$12\r\nhello\r\nworld\r\n"},
+		"NIL":      {raw: "$-1\r\n"},
+		"ARRAY":    {raw: "*4\r\n$1\r\na\r\n:2\r\n*1\r\n+nested\r\n-ERR inner\r\n"},
+		"NILARRAY": {raw: "*-1\r\n"},
+		"EMPTY":    {raw: "*0\r\n"},
+	})
+	client, err := Open(server.url(), DefaultPrefix)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		command string
+		want    any
+	}{
+		{"STATUS", "OK"},
+		{"INT", int64(-42)},
+		{"BULK", []byte("hello\r\nworld")},
+		{"NIL", []byte(nil)},
+		{"ARRAY", []any{[]byte("a"), int64(2), []any{"nested"}, redisError("ERR inner")}},
+		{"NILARRAY", []any(nil)},
+		{"EMPTY", []any{}},
+	}
+	for _, tt := range tests {
+		reply, err := client.Do(context.Background(), tt.command)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(reply, tt.want) {
+			t.Errorf("%s: expected %#v, got %#v", tt.command, tt.want, reply)
+		}
+	}
+
+	// Arguments are sent as length-prefixed bulk strings, so CRLF and spaces survive
+	if _, err := client.Do(context.Background(), "STATUS", client.Key("k"), "a b\r\nc", ""); err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	commands, conns := server.received()
+	if last := commands[len(commands)-1]; !reflect.DeepEqual(last, []string{"STATUS", "outage-monitor:k", "a b\r\nc", ""}) {
+		t.Errorf("Expected the arguments to arrive intact, got %q", last)
+	}
+	if conns != 1 {
+		t.Errorf("Expected all commands over one connection, got %d", conns)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	server := newFakeRedis(t, map[string]fakeReply{
+		"FAIL":    {raw: "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		"WEIRD":   {raw: "?what\r\n"},
+		"BADINT":  {raw: ":many\r\n"},
+		"PARTIAL": {raw: "$10\r\nabc", hangup: true},
+		"HALFARR": {raw: "*2\r\n+one\r\n", hangup: true},
+		"HANGUP":  {hangup: true},
+	})
+	client, err := Open(server.url(), DefaultPrefix)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	// An error reply is returned as an error, but the connection stays in sync and is reused
+	_, err = client.Do(ctx, "FAIL")
+	var replyErr redisError
+	if !errors.As(err, &replyErr) || err.Error() != "redis: WRONGTYPE Operation against a key holding the wrong kind of value" {
+		t.Errorf("Expected the error reply, got %v", err)
+	}
+	if reply, err := client.Do(ctx, "PING"); err != nil || reply != "PONG" {
+		t.Errorf("Expected PONG after an error reply, got %v, %v", reply, err)
+	}
+	if _, conns := server.received(); conns != 1 {
+		t.Errorf("Expected the connection to be kept after an error reply, got %d connections", conns)
+	}
+
+	// Protocol errors and broken connections drop the connection; the next command redials
+	for i, command := range []string{"WEIRD", "BADINT", "PARTIAL", "HALFARR", "HANGUP"} {
+		if _, err := client.Do(ctx, command); err == nil || errors.As(err, &replyErr) {
+			t.Errorf("%s: expected a connection error, got %v", command, err)
+		}
+		if reply, err := client.Do(ctx, "PING"); err != nil || reply != "PONG" {
+			t.Errorf("%s: expected PONG after reconnecting, got %v, %v", command, reply, err)
+		}
+		if _, conns := server.received(); conns != i+2 {
+			t.Errorf("%s: expected %d connections, got %d", command, i+2, conns)
+		}
+	}
+	if _, err := client.Do(ctx, "PARTIAL"); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF for a truncated bulk string, got %v", err)
+	}
+	if _, err := client.Do(ctx, "HANGUP"); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF when the server closes without replying, got %v", err)
+	}
+}
+
+func TestClientDeadline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		// Accept and never answer
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer nc.Close()
+		}
+	}()
+
+	client := &Client{addr: ln.Addr().String()}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var netErr net.Error
+	if _, err := client.Do(ctx, "PING"); !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Expected a timeout from the context deadline, got %v", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	server := newFakeRedis(t, nil)
+	client, err := Open("redis://monitor:s3cret@"+server.ln.Addr().String()+"/3", "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer client.Close()
+
+	commands, _ := server.received()
+	want := [][]string{{"AUTH", "monitor", "s3cret"}, {"SELECT", "3"}, {"PING"}}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("Expected %q, got %q", want, commands)
+	}
+
+	rejecting := newFakeRedis(t, map[string]fakeReply{"AUTH": {raw: "-WRONGPASS invalid username-password pair\r\n"}})
+	if _, err := Open("redis://:wrong@"+rejecting.ln.Addr().String(), ""); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Expected the AUTH error, got %v", err)
+	}
+
+	for _, rawURL := range []string{"http://localhost:6379", "redis://localhost:6379/zero", "redis://%zz"} {
+		if _, err := Open(rawURL, ""); err == nil || !strings.HasPrefix(err.Error(), "invalid Redis URL") {
+			t.Errorf("%s: expected an invalid URL error, got %v", rawURL, err)
+		}
+	}
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := closed.Addr().String()
+	closed.Close()
+	if _, err := Open("redis://"+addr, ""); err == nil || !strings.Contains(err.Error(), "failed to reach Redis") {
+		t.Errorf("Expected an unreachable server error, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package coord
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Renewal and release only touch the key while it still holds this owner's value, so an
+// instance that lost its lease can never extend or delete the new holder's
+const (
+	renewScript   = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`
+	releaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+)
+
+// Lease is a key held by one owner at a time. It expires unless renewed, so a crashed
+// holder is replaced after the TTL.
+type Lease struct {
+	client *Client
+	key    string
+	owner  string
+	ttl    time.Duration
+
+	lost     chan struct{}
+	lostOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// AcquireLease waits until the lease name is free and takes it for owner, retrying every
+// retry. waiting is called with the current holder whenever an attempt fails. It returns
+// ctx's error when ctx ends first. The lease is renewed in the background until Release.
+func (c *Client) AcquireLease(ctx context.Context, name, owner string, ttl, retry time.Duration, waiting func(holder string)) (*Lease, error) {
+	key := c.Key(name)
+	for {
+		reply, err := c.Do(ctx, "SET", key, owner, "NX", "PX", msString(ttl))
+		if err == nil && reply != nil {
+			if _, ok := reply.(string); ok {
+				break
+			}
+		}
+		if waiting != nil {
+			holder := "unknown"
+			if err != nil {
+				holder = "unknown (" + err.Error() + ")"
+			} else if value, _ := c.Do(ctx, "GET", key); value != nil {
+				if b, ok := value.([]byte); ok && b != nil {
+					holder = string(b)
+				}
+			}
+			waiting(holder)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retry):
+		}
+	}
+
+	renewCtx, cancel := context.WithCancel(context.Background())
+	l := &Lease{
+		client: c,
+		key:    key,
+		owner:  owner,
+		ttl:    ttl,
+		lost:   make(chan struct{}),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go l.renew(renewCtx)
+	return l, nil
+}
+
+// Lost is closed when the lease could not be renewed before it expired, or another owner
+// took it. The holder must stop acting as leader.
+func (l *Lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release stops renewing and frees the lease for the next owner
+func (l *Lease) Release() error {
+	l.cancel()
+	<-l.done
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	_, err := l.client.Do(ctx, "EVAL", releaseScript, "1", l.key, l.owner)
+	return err
+}
+
+// renew extends the lease every third of its TTL. Errors are retried until the TTL has
+// passed since the last successful renewal, as the key may have expired by then.
+func (l *Lease) renew(ctx context.Context) {
+	defer close(l.done)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reply, err := l.client.Do(ctx, "EVAL", renewScript, "1", l.key, l.owner, msString(l.ttl))
+		switch {
+		case err == nil && reply == int64(1):
+			renewed = time.Now()
+		case err == nil:
+			l.markLost() // Someone else holds the key
+			return
+		case errors.Is(err, context.Canceled):
+			return
+		case time.Since(renewed) >= l.ttl:
+			l.markLost()
+			return
+		}
+	}
+}
+
+func (l *Lease) markLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
+
+// msString formats d in milliseconds, as PX and PEXPIRE take them
+func msString(d time.Duration) string {
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
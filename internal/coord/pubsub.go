@@ -0,0 +1,71 @@
+package coord
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// subscribeRetry is how long Subscribe waits before reconnecting after an error
+const subscribeRetry = 5 * time.Second
+
+// Publish sends payload to the subscribers of channel
+func (c *Client) Publish(ctx context.Context, channel string, payload []byte) error {
+	_, err := c.Do(ctx, "PUBLISH", c.Key(channel), string(payload))
+	return err
+}
+
+// Subscribe calls handle with every message on channel until ctx ends. It reconnects
+// after errors; messages published while disconnected are missed. onError, if set, is
+// called with each connection error.
+func (c *Client) Subscribe(ctx context.Context, channel string, handle func([]byte), onError func(error)) {
+	for {
+		err := c.subscribeOnce(ctx, c.Key(channel), handle)
+		if ctx.Err() != nil {
+			return
+		}
+		if onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(subscribeRetry):
+		}
+	}
+}
+
+// subscribeOnce reads messages on a dedicated connection until it fails or ctx ends
+func (c *Client) subscribeOnce(ctx context.Context, key string, handle func([]byte)) error {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cn.Close()
+
+	// A subscribed connection only receives, so closing it is how reads are interrupted
+	stop := context.AfterFunc(ctx, func() { cn.Close() })
+	defer stop()
+
+	if _, err := cn.roundTrip(ctx, []string{"SUBSCRIBE", key}); err != nil {
+		return err
+	}
+	cn.SetDeadline(time.Time{})
+
+	for {
+		reply, err := cn.read()
+		if err != nil {
+			return err
+		}
+		items, ok := reply.([]any)
+		if !ok || len(items) != 3 {
+			return fmt.Errorf("redis: unexpected pub/sub reply %v", reply)
+		}
+		if kind, _ := items[0].([]byte); string(kind) != "message" {
+			continue
+		}
+		if payload, ok := items[2].([]byte); ok {
+			handle(payload)
+		}
+	}
+}
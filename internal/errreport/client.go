@@ -0,0 +1,181 @@
+package errreport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"tg-monitor-bot/internal/logging"
+)
+
+const (
+	// queueSize bounds the events waiting to be sent; more are dropped
+	queueSize = 100
+	// repeatWindow is how long an event with the same fingerprint is suppressed, so a
+	// failing database or an unreachable webhook doesn't use up the project's quota
+	repeatWindow = time.Minute
+)
+
+// client sends events to the envelope endpoint of a Sentry project
+type client struct {
+	opts     Options
+	endpoint string
+	auth     string
+	http     *http.Client
+	logger   *logging.Logger
+	queue    chan *event
+	stop     chan struct{}
+	done     sync.WaitGroup
+
+	mu       sync.Mutex
+	lastSeen map[string]time.Time // fingerprint -> last time an event was queued
+}
+
+// ParseDSN returns the envelope endpoint and public key of a Sentry DSN
+func ParseDSN(dsn string) (endpoint, publicKey string, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid DSN: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", "", fmt.Errorf("invalid DSN: scheme must be http or https")
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return "", "", fmt.Errorf("invalid DSN: missing public key")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	projectID := path[slash+1:]
+	if u.Host == "" || projectID == "" {
+		return "", "", fmt.Errorf("invalid DSN: expected https://<key>@<host>/<project id>")
+	}
+	endpoint = fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:slash], projectID)
+	return endpoint, u.User.Username(), nil
+}
+
+// newClient starts a client goroutine for opts
+func newClient(opts Options) (*client, error) {
+	endpoint, publicKey, err := ParseDSN(opts.DSN)
+	if err != nil {
+		return nil, err
+	}
+	c := &client{
+		opts:     opts,
+		endpoint: endpoint,
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_key=%s, sentry_client=outage-monitor-bot/%s", publicKey, opts.Release),
+		http:     &http.Client{Timeout: 10 * time.Second},
+		logger:   logging.New("errreport"),
+		queue:    make(chan *event, queueSize),
+		stop:     make(chan struct{}),
+		lastSeen: make(map[string]time.Time),
+	}
+	c.done.Add(1)
+	go c.run()
+	c.logger.Printf("Reporting errors to %s", endpoint)
+	return c, nil
+}
+
+// capture queues an event unless the same one was queued within repeatWindow
+func (c *client) capture(ev *event) {
+	ev.ServerName = c.opts.ServerName
+	ev.Release = c.opts.Release
+	ev.Environment = c.opts.Environment
+
+	exc := ev.Exception.Values[0]
+	fingerprint := ev.Logger + "|" + exc.Type + "|" + exc.Value
+	now := time.Now()
+	c.mu.Lock()
+	if last, ok := c.lastSeen[fingerprint]; ok && now.Sub(last) < repeatWindow {
+		c.mu.Unlock()
+		return
+	}
+	if len(c.lastSeen) >= 1000 {
+		for key, last := range c.lastSeen {
+			if now.Sub(last) >= repeatWindow {
+				delete(c.lastSeen, key)
+			}
+		}
+	}
+	c.lastSeen[fingerprint] = now
+	c.mu.Unlock()
+
+	select {
+	case c.queue <- ev:
+	default:
+		c.logger.Warnf("Dropped error report: queue full")
+	}
+}
+
+// run sends queued events until shutdown, then the ones still queued
+func (c *client) run() {
+	defer c.done.Done()
+	for {
+		select {
+		case ev := <-c.queue:
+			c.send(ev)
+		case <-c.stop:
+			for {
+				select {
+				case ev := <-c.queue:
+					c.send(ev)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// shutdown sends the queued events and stops the client
+func (c *client) shutdown() {
+	close(c.stop)
+	c.done.Wait()
+}
+
+// send posts an event as a Sentry envelope. Failures are logged and the event is dropped.
+func (c *client) send(ev *event) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		c.logger.Errorf("Failed to encode error report: %v", err)
+		return
+	}
+	header, _ := json.Marshal(map[string]string{
+		"event_id": ev.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+		"dsn":      c.opts.DSN,
+	})
+	itemHeader, _ := json.Marshal(map[string]any{"type": "event", "length": len(payload)})
+
+	var body bytes.Buffer
+	body.Write(header)
+	body.WriteByte('\n')
+	body.Write(itemHeader)
+	body.WriteByte('\n')
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, &body)
+	if err != nil {
+		c.logger.Errorf("Failed to create error report request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", c.auth)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.logger.Warnf("Failed to send error report: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		c.logger.Warnf("Failed to send error report: server returned %d: %s", resp.StatusCode, excerpt)
+	}
+}
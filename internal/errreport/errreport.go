@@ -0,0 +1,203 @@
+// Package errreport sends panics and unexpected errors to Sentry (or any service accepting
+// Sentry envelopes, such as GlitchTip) with their stack and context. Without a DSN every
+// call is a cheap no-op.
+package errreport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tg-monitor-bot/internal/tracing"
+)
+
+// Tags describe where an error happened, e.g. component, source.id or webhook.id
+type Tags map[string]string
+
+// Options configure error reporting
+type Options struct {
+	DSN         string // https://<public key>@<host>/<project id>; empty disables reporting
+	Environment string // e.g. production or staging
+	Release     string
+	ServerName  string
+}
+
+var (
+	current atomic.Pointer[client]
+	setupMu sync.Mutex
+)
+
+// Setup starts reporting to opts.DSN, replacing and flushing the previous client. Calling
+// it again with the same options keeps the running client. An invalid DSN disables
+// reporting and is returned.
+func Setup(opts Options) error {
+	setupMu.Lock()
+	defer setupMu.Unlock()
+
+	old := current.Load()
+	if old != nil && old.opts == opts {
+		return nil
+	}
+	var next *client
+	var err error
+	if opts.DSN != "" {
+		next, err = newClient(opts)
+	}
+	current.Store(next)
+	if old != nil {
+		old.shutdown()
+	}
+	return err
+}
+
+// Shutdown sends queued events and disables reporting
+func Shutdown() {
+	setupMu.Lock()
+	defer setupMu.Unlock()
+
+	if old := current.Swap(nil); old != nil {
+		old.shutdown()
+	}
+}
+
+// Enabled reports whether a DSN is configured
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+// CaptureError reports err with tags and the caller's stack. The trace of the span in ctx,
+// if any, is attached so the event links to it.
+func CaptureError(ctx context.Context, err error, tags Tags) {
+	c := current.Load()
+	if c == nil || err == nil {
+		return
+	}
+	c.capture(newEvent(ctx, "error", fmt.Sprintf("%T", err), err.Error(), tags))
+}
+
+// CapturePanic reports a recovered panic value with tags. It must be called from the
+// deferred function that recovered, so the stack still contains the panicking frames.
+func CapturePanic(ctx context.Context, recovered any, tags Tags) {
+	c := current.Load()
+	if c == nil || recovered == nil {
+		return
+	}
+	c.capture(newEvent(ctx, "fatal", "panic", fmt.Sprint(recovered), tags))
+}
+
+// event is a Sentry event
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Exception   exceptionList     `json:"exception"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Contexts    map[string]any    `json:"contexts,omitempty"`
+}
+
+type (
+	exceptionList struct {
+		Values []exception `json:"values"`
+	}
+	exception struct {
+		Type       string     `json:"type"`
+		Value      string     `json:"value"`
+		Stacktrace stacktrace `json:"stacktrace"`
+	}
+	stacktrace struct {
+		Frames []frame `json:"frames"`
+	}
+	frame struct {
+		Function string `json:"function"`
+		Module   string `json:"module,omitempty"`
+		Filename string `json:"filename"`
+		AbsPath  string `json:"abs_path"`
+		Lineno   int    `json:"lineno"`
+		InApp    bool   `json:"in_app"`
+	}
+)
+
+// modulePrefix marks this module's frames as in-app
+const modulePrefix = "tg-monitor-bot/"
+
+// newEvent builds an event with the stack of its caller's caller
+func newEvent(ctx context.Context, level, errType, message string, tags Tags) *event {
+	var id [16]byte
+	rand.Read(id[:])
+	ev := &event{
+		EventID:   hex.EncodeToString(id[:]),
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Platform:  "go",
+		Level:     level,
+		Logger:    tags["component"],
+		Exception: exceptionList{Values: []exception{{
+			Type:       errType,
+			Value:      message,
+			Stacktrace: stacktrace{Frames: callerFrames(3)},
+		}}},
+		Tags: tags,
+	}
+	if traceID, spanID := tracing.FromContext(ctx).IDs(); traceID != "" {
+		ev.Contexts = map[string]any{"trace": map[string]string{"trace_id": traceID, "span_id": spanID}}
+	}
+	return ev
+}
+
+// callerFrames returns the stack above skip frames, outermost first as Sentry expects
+func callerFrames(skip int) []frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var out []frame
+	for {
+		f, more := frames.Next()
+		module, function := splitFunction(f.Function)
+		out = append(out, frame{
+			Function: function,
+			Module:   module,
+			Filename: shortPath(f.File),
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    strings.HasPrefix(f.Function, modulePrefix),
+		})
+		if !more {
+			break
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// splitFunction splits tg-monitor-bot/internal/monitor.(*Monitor).CheckOnce into the
+// package path and (*Monitor).CheckOnce
+func splitFunction(name string) (module, function string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+1+dot+1:]
+}
+
+// shortPath keeps the last two elements of a file path, e.g. monitor/checker.go
+func shortPath(path string) string {
+	parts := strings.Split(path, "/")
+	if len(parts) > 2 {
+		parts = parts[len(parts)-2:]
+	}
+	return strings.Join(parts, "/")
+}
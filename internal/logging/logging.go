@@ -0,0 +1,164 @@
+// Package logging configures log/slog for the whole process and provides per-component loggers
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Output formats accepted by Setup
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// output is where the default handler writes: the writer given to Setup, plus the log file
+// set by SetFile. It lives outside the handler so loggers created before SetFile pick up the file.
+var output = &teeWriter{primary: os.Stderr}
+
+// teeWriter writes to primary and, if set, to a log file
+type teeWriter struct {
+	mu      sync.RWMutex
+	primary io.Writer
+	file    *RotatingFile
+}
+
+// Write writes p to both outputs. Log file errors are dropped: there is nowhere to report them.
+func (t *teeWriter) Write(p []byte) (int, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.file != nil {
+		t.file.Write(p)
+	}
+	return t.primary.Write(p)
+}
+
+// ParseLevel maps LOG_LEVEL values (debug, info, warn, error) to a slog level; empty is info
+func ParseLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q (use debug, info, warn or error)", value)
+	}
+}
+
+// Setup installs the default slog logger writing to w in format ("text" or "json") at level.
+// The standard log package is routed through it too, at info level.
+// Loggers created by New before Setup keep the previous handler.
+// A log file set by SetFile gets the same records.
+func Setup(w io.Writer, level, format string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(output, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(output, opts)
+	default:
+		return fmt.Errorf("unknown log format %q (use text or json)", format)
+	}
+
+	output.mu.Lock()
+	output.primary = w
+	output.mu.Unlock()
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// SetFile copies all log output to path, rotated according to opts. An empty path closes
+// the current log file; setting the same path again only updates opts.
+func SetFile(path string, opts RotateOptions) error {
+	output.mu.Lock()
+	defer output.mu.Unlock()
+
+	if output.file != nil && output.file.Path() == path {
+		output.file.SetOptions(opts)
+		return nil
+	}
+
+	var file *RotatingFile
+	if path != "" {
+		var err error
+		if file, err = OpenRotatingFile(path, opts); err != nil {
+			return err
+		}
+	}
+	if output.file != nil {
+		output.file.Close()
+	}
+	output.file = file
+	return nil
+}
+
+// Logger is a component logger. Printf and Println log at info level, so call sites written
+// for the standard log package keep working; Debugf, Warnf and Errorf pick another level.
+type Logger struct {
+	*slog.Logger
+}
+
+// New returns a logger whose records carry a component field, e.g. component=storage
+func New(component string) *Logger {
+	return &Logger{slog.Default().With("component", component)}
+}
+
+// NewWithHandler returns a component logger writing to handler instead of the default
+func NewWithHandler(handler slog.Handler, component string) *Logger {
+	return &Logger{slog.New(handler).With("component", component)}
+}
+
+// With returns a logger that adds attrs to every record, e.g. With("request_id", id)
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{l.Logger.With(args...)}
+}
+
+// Printf logs at info level
+func (l *Logger) Printf(format string, args ...any) {
+	l.logf(slog.LevelInfo, format, args...)
+}
+
+// Println logs its operands, separated by spaces, at info level
+func (l *Logger) Println(args ...any) {
+	if l.Enabled(context.Background(), slog.LevelInfo) {
+		l.Log(context.Background(), slog.LevelInfo, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+	}
+}
+
+// Debugf logs at debug level; the message is only formatted when debug is enabled
+func (l *Logger) Debugf(format string, args ...any) {
+	l.logf(slog.LevelDebug, format, args...)
+}
+
+// Warnf logs at warn level
+func (l *Logger) Warnf(format string, args ...any) {
+	l.logf(slog.LevelWarn, format, args...)
+}
+
+// Errorf logs at error level
+func (l *Logger) Errorf(format string, args ...any) {
+	l.logf(slog.LevelError, format, args...)
+}
+
+// logf formats and logs the message if level is enabled
+func (l *Logger) logf(level slog.Level, format string, args ...any) {
+	if l.Enabled(context.Background(), level) {
+		l.Log(context.Background(), level, fmt.Sprintf(format, args...))
+	}
+}
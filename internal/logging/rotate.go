@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RotateOptions controls when a log file is rotated and how many rotated files are kept
+type RotateOptions struct {
+	MaxSize    int64         // Rotate before the file grows past this many bytes (0 = no size limit)
+	MaxAge     time.Duration // Rotate once the file has been written to for this long, across restarts (0 = no age limit)
+	MaxBackups int           // Rotated files to keep as <path>.1 (newest) to <path>.N (0 = keep all)
+}
+
+// RotatingFile is an append-only log file that renames itself to <path>.1 when it gets too
+// big or too old, shifting older backups up and deleting those past MaxBackups
+type RotatingFile struct {
+	path string
+	opts RotateOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// OpenRotatingFile opens or creates path for appending, creating its directory if needed
+func OpenRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	r := &RotatingFile{path: path, opts: opts}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Path returns the file's path
+func (r *RotatingFile) Path() string {
+	return r.path
+}
+
+// SetOptions changes the rotation limits; they apply from the next write
+func (r *RotatingFile) SetOptions(opts RotateOptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.opts = opts
+}
+
+// Write appends p, rotating first if p would push the file past a limit. A single
+// record larger than MaxSize is still written whole.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	tooBig := r.opts.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.opts.MaxSize
+	tooOld := r.opts.MaxAge > 0 && time.Since(r.openedAt) >= r.opts.MaxAge
+	if tooBig || tooOld {
+		// A failed rename keeps appending to the current file rather than losing records
+		if err := r.rotate(); err != nil && r.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the file; later writes fail
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// open opens the current file for appending
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	r.openedAt = time.Now()
+	if r.size > 0 {
+		// A file kept from an earlier run goes on aging from its last write, so frequent
+		// restarts don't postpone rotation by MaxAge
+		r.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// rotate closes the file, moves it to <path>.1 and opens a new one. If the move fails the
+// old file is reopened.
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	r.file = nil
+
+	err := r.shiftBackups()
+	if openErr := r.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+// shiftBackups renames <path>.i to <path>.i+1, drops backups past MaxBackups and renames
+// the current file to <path>.1
+func (r *RotatingFile) shiftBackups() error {
+	// Find the highest existing backup so shifting never overwrites one
+	last := 0
+	for {
+		if _, err := os.Stat(r.backupPath(last + 1)); err != nil {
+			break
+		}
+		last++
+	}
+	for i := last; i >= 1; i-- {
+		if r.opts.MaxBackups > 0 && i >= r.opts.MaxBackups {
+			os.Remove(r.backupPath(i))
+			continue
+		}
+		if err := os.Rename(r.backupPath(i), r.backupPath(i+1)); err != nil {
+			return fmt.Errorf("failed to shift log backup: %w", err)
+		}
+	}
+	if err := os.Rename(r.path, r.backupPath(1)); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return nil
+}
+
+// backupPath returns the name of the i-th newest rotated file
+func (r *RotatingFile) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
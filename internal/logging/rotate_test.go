@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       RotateOptions
+		existing   string        // Content of the file before it is opened; empty = none
+		existingAt time.Duration // How long ago the existing file was last written
+		writes     []string
+		want       map[string]string // File suffix ("" for the current file) → content; others must not exist
+	}{
+		{
+			name:   "no limits",
+			writes: []string{"a\n", "b\n", "c\n"},
+			want:   map[string]string{"": "a\nb\nc\n"},
+		},
+		{
+			name:   "size",
+			opts:   RotateOptions{MaxSize: 5},
+			writes: []string{"aaa\n", "bbb\n", "ccc\n"},
+			want:   map[string]string{"": "ccc\n", ".1": "bbb\n", ".2": "aaa\n"},
+		},
+		{
+			name:   "record larger than the limit",
+			opts:   RotateOptions{MaxSize: 2},
+			writes: []string{"aaaa\n", "bbbb\n"},
+			want:   map[string]string{"": "bbbb\n", ".1": "aaaa\n"},
+		},
+		{
+			name:   "backups past the limit dropped",
+			opts:   RotateOptions{MaxSize: 5, MaxBackups: 2},
+			writes: []string{"aaa\n", "bbb\n", "ccc\n", "ddd\n"},
+			want:   map[string]string{"": "ddd\n", ".1": "ccc\n", ".2": "bbb\n"},
+		},
+		{
+			name:       "size counts the existing file",
+			opts:       RotateOptions{MaxSize: 5},
+			existing:   "old\n",
+			existingAt: time.Minute,
+			writes:     []string{"new\n"},
+			want:       map[string]string{"": "new\n", ".1": "old\n"},
+		},
+		{
+			name:       "age counted from the existing file's last write",
+			opts:       RotateOptions{MaxAge: time.Hour},
+			existing:   "old\n",
+			existingAt: 2 * time.Hour,
+			writes:     []string{"a\n", "b\n"},
+			want:       map[string]string{"": "a\nb\n", ".1": "old\n"},
+		},
+		{
+			name:       "existing file younger than the age limit",
+			opts:       RotateOptions{MaxAge: time.Hour},
+			existing:   "old\n",
+			existingAt: time.Minute,
+			writes:     []string{"a\n"},
+			want:       map[string]string{"": "old\na\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "logs", "bot.log")
+			if tt.existing != "" {
+				os.MkdirAll(filepath.Dir(path), 0755)
+				if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+					t.Fatalf("Failed to write the existing file: %v", err)
+				}
+				written := time.Now().Add(-tt.existingAt)
+				if err := os.Chtimes(path, written, written); err != nil {
+					t.Fatalf("Failed to set the modification time: %v", err)
+				}
+			}
+
+			r, err := OpenRotatingFile(path, tt.opts)
+			if err != nil {
+				t.Fatalf("OpenRotatingFile failed: %v", err)
+			}
+			for _, p := range tt.writes {
+				if n, err := r.Write([]byte(p)); err != nil || n != len(p) {
+					t.Fatalf("Write failed: %d, %v", n, err)
+				}
+			}
+			r.Close()
+
+			files, _ := filepath.Glob(path + "*")
+			if len(files) != len(tt.want) {
+				t.Errorf("Expected %d files, got %v", len(tt.want), files)
+			}
+			for suffix, want := range tt.want {
+				if got, err := os.ReadFile(path + suffix); err != nil || string(got) != want {
+					t.Errorf("Expected bot.log%s to contain %q, got %q (%v)", suffix, want, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRotatingFileAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.log")
+	r, err := OpenRotatingFile(path, RotateOptions{MaxAge: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile failed: %v", err)
+	}
+	defer r.Close()
+
+	r.Write([]byte("a\n"))
+	r.Write([]byte("b\n"))
+	time.Sleep(60 * time.Millisecond)
+	r.Write([]byte("c\n"))
+	if got, _ := os.ReadFile(path + ".1"); string(got) != "a\nb\n" {
+		t.Errorf("Expected the file rotated once it got too old, got backup %q", got)
+	}
+
+	// Closed files refuse writes
+	r.Close()
+	if _, err := r.Write([]byte("d\n")); err != os.ErrClosed {
+		t.Errorf("Expected os.ErrClosed, got %v", err)
+	}
+}
@@ -2,46 +2,88 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/errreport"
+	"tg-monitor-bot/internal/logging"
 	"tg-monitor-bot/internal/storage"
+	"tg-monitor-bot/internal/tracing"
 )
 
-// StatusChangeCallback is called when a source's status changes
-type StatusChangeCallback func(*storage.Source, *storage.StatusChange)
+// StatusChangeCallback is called when a source's status changes. ctx carries the trace span
+// of the check that detected the change; it is never cancelled.
+type StatusChangeCallback func(context.Context, *storage.Source, *storage.StatusChange)
 
 // Monitor handles all monitoring operations
 type Monitor struct {
 	storage         *storage.BoltDB
 	config          *config.Config
 	client          *http.Client
-	logger          *log.Logger
+	networks        networks     // resolvers and transports of checks, by resolver spec
+	logger          *logging.Logger
 	onStatusChange  StatusChangeCallback
 	activeMonitors  map[string]context.CancelFunc // sourceID -> cancel function
 	monitorsMu      sync.RWMutex
 	sources         map[string]*storage.Source // sourceID -> source (in-memory cache)
 	sourcesMu       sync.RWMutex
+	pendingChecks   map[string]storage.CheckResult // sourceID -> latest unflushed check result
+	pendingMu       sync.Mutex
+	events          *EventBus // optional; receives status changes for live streaming
+	configMu        sync.RWMutex // guards config and client, which UpdateConfig replaces
+	stats           checkStats   // check rates and durations for Stats
+	durations       durationLog  // recent durations per source for CheckDurations
+	schemas         schemaCache  // compiled response schemas per source
+	discovery       discoveryState // network discovery scan, see StartDiscovery
+	createdAt       time.Time
 }
 
 // New creates a new Monitor instance
 func New(db *storage.BoltDB, cfg *config.Config, callback StatusChangeCallback) *Monitor {
-	return &Monitor{
+	m := &Monitor{
 		storage:        db,
 		config:         cfg,
 		client: &http.Client{
 			Timeout: cfg.HTTPTimeout,
 		},
-		logger:         log.New(log.Writer(), "[MONITOR] ", log.LstdFlags),
+		logger:         logging.New("monitor"),
 		onStatusChange: callback,
 		activeMonitors: make(map[string]context.CancelFunc),
 		sources:        make(map[string]*storage.Source),
+		pendingChecks:  make(map[string]storage.CheckResult),
+		createdAt:      time.Now(),
 	}
+	m.networks.configure(transportSettingsOf(cfg))
+	return m
+}
+
+// SetEventBus sets the bus that status changes are published to
+func (m *Monitor) SetEventBus(bus *EventBus) {
+	m.events = bus
+}
+
+// UpdateConfig applies new check settings (timeouts, ping count, transport settings) to the
+// running monitor. They take effect from the next check; the flush interval is fixed for the
+// monitor's lifetime.
+func (m *Monitor) UpdateConfig(cfg *config.Config) {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+	m.config = cfg
+	m.client = &http.Client{Timeout: cfg.HTTPTimeout}
+	m.networks.configure(transportSettingsOf(cfg))
+}
+
+// settings returns the current config and HTTP client
+func (m *Monitor) settings() (*config.Config, *http.Client) {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	return m.config, m.client
 }
 
 // Start begins monitoring all enabled sources from the database
@@ -58,16 +100,16 @@ func (m *Monitor) Start(ctx context.Context) error {
 
 	// Debug: log all source IDs to detect duplicates
 	if len(sources) > 0 {
-		m.logger.Println("Source IDs from database:")
+		m.logger.Debugf("Source IDs from database:")
 		sourceIDCount := make(map[string]int)
 		for _, source := range sources {
 			sourceIDCount[source.ID]++
-			m.logger.Printf("  - %s (ID: %s)", source.Name, source.ID)
+			m.logger.Debugf("  - %s (ID: %s)", source.Name, source.ID)
 		}
 		// Check for duplicates
 		for id, count := range sourceIDCount {
 			if count > 1 {
-				m.logger.Printf("⚠️  WARNING: Source ID %s appears %d times in database query result!", id, count)
+				m.logger.Warnf("⚠️  WARNING: Source ID %s appears %d times in database query result!", id, count)
 			}
 		}
 	} else {
@@ -77,18 +119,60 @@ func (m *Monitor) Start(ctx context.Context) error {
 	// Start monitoring each source
 	successCount := 0
 	for _, source := range sources {
-		m.logger.Printf("Adding source to monitor: %s (ID: %s)", source.Name, source.ID)
+		m.logger.Debugf("Adding source to monitor: %s (ID: %s)", source.Name, source.ID)
 		if err := m.AddSource(ctx, source); err != nil {
-			m.logger.Printf("❌ Failed to start monitoring source %s: %v", source.Name, err)
+			m.logger.Errorf("❌ Failed to start monitoring source %s: %v", source.Name, err)
 		} else {
 			successCount++
 		}
 	}
 
+	if cfg, _ := m.settings(); cfg.CheckFlushInterval > 0 {
+		go m.flushLoop(ctx)
+	}
+
 	m.logger.Printf("✅ Monitor started successfully with %d/%d sources active", successCount, len(sources))
 	return nil
 }
 
+// flushLoop periodically persists buffered check results and flushes once more on shutdown
+func (m *Monitor) flushLoop(ctx context.Context) {
+	cfg, _ := m.settings()
+	ticker := time.NewTicker(cfg.CheckFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.flushCheckResults()
+			return
+		case <-ticker.C:
+			m.flushCheckResults()
+		}
+	}
+}
+
+// flushCheckResults writes all buffered check results in one transaction
+func (m *Monitor) flushCheckResults() {
+	m.pendingMu.Lock()
+	if len(m.pendingChecks) == 0 {
+		m.pendingMu.Unlock()
+		return
+	}
+	results := make([]storage.CheckResult, 0, len(m.pendingChecks))
+	for _, result := range m.pendingChecks {
+		results = append(results, result)
+	}
+	m.pendingChecks = make(map[string]storage.CheckResult)
+	m.pendingMu.Unlock()
+
+	ctx, span := tracing.Start(context.Background(), "flush_check_results", tracing.Int("check_results", len(results)))
+	defer span.End()
+	if err := m.storageWrite(ctx, "save_check_results", func() error { return m.storage.SaveCheckResults(results) }); err != nil {
+		m.logger.Errorf("Failed to flush %d check results: %v", len(results), err)
+	}
+}
+
 // AddSource starts monitoring a new source
 func (m *Monitor) AddSource(ctx context.Context, source *storage.Source) error {
 	m.monitorsMu.Lock()
@@ -96,7 +180,7 @@ func (m *Monitor) AddSource(ctx context.Context, source *storage.Source) error {
 
 	// Check if already monitoring
 	if _, exists := m.activeMonitors[source.ID]; exists {
-		m.logger.Printf("⚠️  Source %s (ID: %s) already being monitored - skipping", source.Name, source.ID)
+		m.logger.Warnf("⚠️  Source %s (ID: %s) already being monitored - skipping", source.Name, source.ID)
 		return fmt.Errorf("source already being monitored")
 	}
 
@@ -109,7 +193,7 @@ func (m *Monitor) AddSource(ctx context.Context, source *storage.Source) error {
 	sourceCtx, cancel := context.WithCancel(ctx)
 	m.activeMonitors[source.ID] = cancel
 
-	m.logger.Printf("Starting goroutine for: %s (ID: %s, type: %s, target: %s, interval: %v)",
+	m.logger.Debugf("Starting goroutine for: %s (ID: %s, type: %s, target: %s, interval: %v)",
 		source.Name, source.ID, source.Type, source.Target, source.CheckInterval)
 
 	// Start monitoring goroutine
@@ -127,7 +211,7 @@ func (m *Monitor) RemoveSource(sourceID string) error {
 
 	cancel, exists := m.activeMonitors[sourceID]
 	if !exists {
-		m.logger.Printf("⚠️  Cannot remove source %s - not being monitored", sourceID)
+		m.logger.Warnf("⚠️  Cannot remove source %s - not being monitored", sourceID)
 		return fmt.Errorf("source not being monitored")
 	}
 
@@ -149,11 +233,23 @@ func (m *Monitor) RemoveSource(sourceID string) error {
 	m.sourcesMu.Lock()
 	delete(m.sources, sourceID)
 	m.sourcesMu.Unlock()
+	m.durations.forget(sourceID)
+	m.schemas.forget(sourceID)
 
 	m.logger.Printf("✅ Stopped monitoring: %s (total active: %d)", sourceName, len(m.activeMonitors))
 	return nil
 }
 
+// ActiveMonitors returns the number of per-source check goroutines
+func (m *Monitor) ActiveMonitors() int {
+	m.monitorsMu.RLock()
+	defer m.monitorsMu.RUnlock()
+	return len(m.activeMonitors)
+}
+
+// ErrSourceNotFound is returned when a source does not exist or has been soft-deleted
+var ErrSourceNotFound = errors.New("source not found")
+
 // PauseSource temporarily disables monitoring for a source
 func (m *Monitor) PauseSource(sourceID string) error {
 	m.sourcesMu.Lock()
@@ -164,10 +260,13 @@ func (m *Monitor) PauseSource(sourceID string) error {
 		// Source not in cache, try loading from database
 		dbSource, err := m.storage.GetSource(sourceID)
 		if err != nil {
-			return fmt.Errorf("source not found")
+			return ErrSourceNotFound
 		}
 		source = dbSource
 	}
+	if source.IsDeleted() {
+		return ErrSourceNotFound
+	}
 
 	source.Enabled = false
 	if err := m.storage.UpdateSource(source); err != nil {
@@ -188,10 +287,13 @@ func (m *Monitor) ResumeSource(sourceID string) error {
 		// Source not in cache, try loading from database
 		dbSource, err := m.storage.GetSource(sourceID)
 		if err != nil {
-			return fmt.Errorf("source not found")
+			return ErrSourceNotFound
 		}
 		source = dbSource
 	}
+	if source.IsDeleted() {
+		return ErrSourceNotFound
+	}
 
 	source.Enabled = true
 	if err := m.storage.UpdateSource(source); err != nil {
@@ -204,24 +306,39 @@ func (m *Monitor) ResumeSource(sourceID string) error {
 
 // CheckSource performs a single check of a source and returns the status
 func (m *Monitor) CheckSource(source *storage.Source) int {
+	status, _ := m.runCheck(source)
+	return status
+}
+
+// Probe checks a source once without recording the result or notifying anyone. It needs
+// no database, so it also serves one-off checks from the command line.
+func (m *Monitor) Probe(source *storage.Source) (int, string) {
+	return m.runCheck(source)
+}
+
+// runCheck performs a single check and returns the status and, when offline, the reason
+func (m *Monitor) runCheck(source *storage.Source) (int, string) {
 	switch source.Type {
 	case "ping":
-		return m.PingTarget(source.Target)
+		cfg, _ := m.settings()
+		return m.ping(source.Target, resolverSpec(cfg, source), source.AddressFamily)
 	case "http":
-		return m.CheckHTTP(source.Target)
+		return m.checkHTTP(source)
 	case "webhook":
 		return m.checkWebhookSource(source)
 	default:
-		m.logger.Printf("Unknown source type: %s", source.Type)
-		return 0
+		m.logger.Warnf("Unknown source type: %s", source.Type)
+		errreport.CaptureError(context.Background(), fmt.Errorf("unknown source type: %s", source.Type),
+			errreport.Tags{"component": "monitor", "source.id": source.ID, "source.type": source.Type})
+		return 0, fmt.Sprintf("unknown source type: %s", source.Type)
 	}
 }
 
 // checkWebhookSource returns 1 if last heartbeat was within grace period, 0 otherwise
-func (m *Monitor) checkWebhookSource(source *storage.Source) int {
+func (m *Monitor) checkWebhookSource(source *storage.Source) (int, string) {
 	if source.LastCheckTime.IsZero() {
-		m.logger.Printf("Webhook check %s: OFFLINE (no heartbeat yet)", source.Name)
-		return 0
+		m.logger.Debugf("Webhook check %s: OFFLINE (no heartbeat yet)", source.Name)
+		return 0, "no heartbeat received yet"
 	}
 	mult := source.GracePeriodMultiplier
 	if mult <= 0 {
@@ -230,46 +347,57 @@ func (m *Monitor) checkWebhookSource(source *storage.Source) int {
 	graceDuration := time.Duration(float64(source.CheckInterval) * mult)
 	deadline := source.LastCheckTime.Add(graceDuration)
 	if time.Now().After(deadline) {
-		m.logger.Printf("Webhook check %s: OFFLINE (last heartbeat %v ago, grace %v)", source.Name, time.Since(source.LastCheckTime).Round(time.Second), graceDuration.Round(time.Second))
-		return 0
+		m.logger.Debugf("Webhook check %s: OFFLINE (last heartbeat %v ago, grace %v)", source.Name, time.Since(source.LastCheckTime).Round(time.Second), graceDuration.Round(time.Second))
+		return 0, fmt.Sprintf("no heartbeat for %v (grace period %v)", time.Since(source.LastCheckTime).Round(time.Second), graceDuration.Round(time.Second))
 	}
-	m.logger.Printf("Webhook check %s: ONLINE (heartbeat within grace period)", source.Name)
-	return 1
+	m.logger.Debugf("Webhook check %s: ONLINE (heartbeat within grace period)", source.Name)
+	return 1, ""
 }
 
-// RecordWebhookReceived updates the in-memory LastCheckTime after an incoming webhook heartbeat.
-// Call this after persisting via storage.UpdateSourceStatus so the next tick uses the new LastCheckTime.
+// RecordWebhookReceived updates the in-memory LastCheckTime and heartbeat metadata after an incoming
+// webhook heartbeat. Call this after persisting via storage.RecordHeartbeat so the next tick uses the new LastCheckTime.
 // NOTE: CurrentStatus is intentionally NOT updated here; the next monitorSource tick will detect
 // the 0→1 transition and fire the "back online" notification through the normal status-change path.
-func (m *Monitor) RecordWebhookReceived(sourceID string, receivedAt time.Time) {
+func (m *Monitor) RecordWebhookReceived(sourceID string, heartbeat *storage.Heartbeat) {
 	m.sourcesMu.Lock()
 	defer m.sourcesMu.Unlock()
 	source, exists := m.sources[sourceID]
 	if !exists {
 		return
 	}
-	source.LastCheckTime = receivedAt
+	source.LastCheckTime = heartbeat.ReceivedAt
+	source.LastHeartbeat = heartbeat
 	m.sources[sourceID] = source
 }
 
+// UpdateWebhookToken updates the cached token of a webhook source after rotation,
+// so later writes of the cached source don't bring the old token back
+func (m *Monitor) UpdateWebhookToken(sourceID, token string) {
+	m.sourcesMu.Lock()
+	defer m.sourcesMu.Unlock()
+	if source, exists := m.sources[sourceID]; exists {
+		source.WebhookToken = token
+	}
+}
+
 // monitorSource continuously monitors a single source
 func (m *Monitor) monitorSource(ctx context.Context, source *storage.Source) {
-	m.logger.Printf("🔵 Goroutine started for: %s (ID: %s)", source.Name, source.ID)
+	m.logger.Debugf("🔵 Goroutine started for: %s (ID: %s)", source.Name, source.ID)
 
 	ticker := time.NewTicker(source.CheckInterval)
 	defer ticker.Stop()
 
 	// Perform initial check immediately
-	m.logger.Printf("⏱️  Initial check for: %s", source.Name)
+	m.logger.Debugf("⏱️  Initial check for: %s", source.Name)
 	m.performCheck(source)
 
 	for {
 		select {
 		case <-ctx.Done():
-			m.logger.Printf("🔴 Goroutine stopping for: %s (ID: %s)", source.Name, source.ID)
+			m.logger.Debugf("🔴 Goroutine stopping for: %s (ID: %s)", source.Name, source.ID)
 			return
 		case <-ticker.C:
-			m.logger.Printf("⏱️  Scheduled check for: %s", source.Name)
+			m.logger.Debugf("⏱️  Scheduled check for: %s", source.Name)
 			m.performCheck(source)
 		}
 	}
@@ -282,8 +410,29 @@ func (m *Monitor) performCheck(source *storage.Source) {
 		return
 	}
 
-	checkTime := time.Now()
-	newStatus := m.CheckSource(source)
+	m.CheckOnce(source)
+}
+
+// storageWrite runs a storage write in a child span of ctx named storage.<op> and records
+// its latency for Stats. Failures are sent to error reporting.
+func (m *Monitor) storageWrite(ctx context.Context, op string, write func() error) error {
+	_, span := tracing.Start(ctx, "storage."+op)
+	defer span.End()
+	start := time.Now()
+	err := write()
+	m.stats.recordWrite(op, time.Since(start), time.Now())
+	span.RecordError(err)
+	if err != nil {
+		errreport.CaptureError(ctx, err, errreport.Tags{"component": "monitor", "operation": op})
+	}
+	return err
+}
+
+// applyCheckResult records a check outcome on the source, persisting it and, when the status
+// changed, saving the change and notifying. Returns the status change, or nil.
+func (m *Monitor) applyCheckResult(ctx context.Context, source *storage.Source, checkTime time.Time, newStatus int, lastError string) *storage.StatusChange {
+	// Record why the check failed (cleared on success)
+	source.SetLastError(lastError, checkTime)
 
 	// Update last check time (for ping/http; webhook uses LastCheckTime as last heartbeat received)
 	if source.Type != "webhook" {
@@ -307,75 +456,310 @@ func (m *Monitor) performCheck(source *storage.Source) {
 		}
 
 		// Save status change to database immediately
-		if err := m.storage.SaveStatusChange(change); err != nil {
-			m.logger.Printf("Failed to save status change: %v", err)
+		if err := m.storageWrite(ctx, "save_status_change", func() error { return m.storage.SaveStatusChange(change) }); err != nil {
+			m.logger.Errorf("Failed to save status change: %v", err)
 		}
 
 		// Update source status in database.
 		// For webhook sources, use UpdateSourceCurrentStatus to preserve LastCheckTime
 		// (which tracks the last heartbeat received, not the monitor tick time).
 		if source.Type == "webhook" {
-			if err := m.storage.UpdateSourceCurrentStatus(source.ID, newStatus, checkTime); err != nil {
-				m.logger.Printf("Failed to update source status: %v", err)
+			if err := m.storageWrite(ctx, "update_source_status", func() error {
+				return m.storage.UpdateSourceCurrentStatus(source.ID, newStatus, checkTime, lastError)
+			}); err != nil {
+				m.logger.Errorf("Failed to update source status: %v", err)
 			}
 		} else {
-			if err := m.storage.UpdateSourceStatus(source.ID, newStatus, checkTime); err != nil {
-				m.logger.Printf("Failed to update source status: %v", err)
+			if err := m.storageWrite(ctx, "update_source_status", func() error {
+				return m.storage.UpdateSourceStatus(source.ID, newStatus, checkTime, lastError)
+			}); err != nil {
+				m.logger.Errorf("Failed to update source status: %v", err)
 			}
 		}
 
+		// The status write above supersedes any buffered check result
+		m.pendingMu.Lock()
+		delete(m.pendingChecks, source.ID)
+		m.pendingMu.Unlock()
+
 		// Update in-memory source
 		source.CurrentStatus = newStatus
 		source.LastChangeTime = checkTime
 
-		// Update cache
+		// Update cache (sources checked via CheckNow may not be monitored)
 		m.sourcesMu.Lock()
-		m.sources[source.ID] = source
+		if _, cached := m.sources[source.ID]; cached {
+			m.sources[source.ID] = source
+		}
 		m.sourcesMu.Unlock()
 
-		// Trigger notification callback
-		if m.onStatusChange != nil {
-			go m.onStatusChange(source, change)
+		// Publish to live subscribers
+		if m.events != nil {
+			m.events.Publish(Event{SourceID: source.ID, SourceName: source.Name, Change: change})
+		}
+
+		// Trigger notification callback (paused sources can only get here via CheckNow)
+		if m.onStatusChange != nil && source.Enabled {
+			go m.onStatusChange(ctx, source, change)
 		}
+		return change
 	} else if source.Type != "webhook" {
 		// No status change: update check time in database for ping/http sources.
 		// For webhook sources, LastCheckTime is managed exclusively by the heartbeat handler
-		// (handleIncomingWebhook → UpdateSourceStatus), so we must not overwrite it here.
-		if err := m.storage.UpdateSourceStatus(source.ID, source.CurrentStatus, checkTime); err != nil {
-			m.logger.Printf("Failed to update check time: %v", err)
+		// (handleIncomingWebhook → RecordHeartbeat), so we must not overwrite it here.
+		if cfg, _ := m.settings(); cfg.CheckFlushInterval > 0 {
+			// Buffer the result; flushLoop coalesces writes for all sources into one transaction
+			m.pendingMu.Lock()
+			m.pendingChecks[source.ID] = storage.CheckResult{SourceID: source.ID, CheckTime: checkTime, LastError: lastError}
+			m.pendingMu.Unlock()
+		} else if err := m.storageWrite(ctx, "update_source_status", func() error {
+			return m.storage.UpdateSourceStatus(source.ID, source.CurrentStatus, checkTime, lastError)
+		}); err != nil {
+			m.logger.Errorf("Failed to update check time: %v", err)
 		}
 	}
+	return nil
+}
+
+// CheckOutcome is the result of an on-demand check
+type CheckOutcome struct {
+	Source         *storage.Source
+	Status         int
+	PreviousStatus int
+	Change         *storage.StatusChange // nil when the status did not change
+	Latency        time.Duration
+	Error          string
+	CheckedAt      time.Time
+}
+
+// CheckNow checks a source immediately and records the result like a scheduled check.
+// Paused sources are checked and persisted too, but status changes don't trigger notifications.
+func (m *Monitor) CheckNow(sourceID string) (*CheckOutcome, error) {
+	// Use the cached source so the monitoring goroutine sees the new status
+	m.sourcesMu.RLock()
+	source, exists := m.sources[sourceID]
+	m.sourcesMu.RUnlock()
+	if !exists {
+		dbSource, err := m.storage.GetSource(sourceID)
+		if err != nil || dbSource.IsDeleted() {
+			return nil, fmt.Errorf("source not found")
+		}
+		source = dbSource
+	}
+
+	outcome := m.CheckOnce(source)
+	m.logger.Printf("Manual check of %s: status %d in %v", source.Name, outcome.Status, outcome.Latency.Round(time.Millisecond))
+	return outcome, nil
+}
+
+// CheckOnce checks a source and records the result like a scheduled check. It works without
+// Start, so one-shot runs can persist statuses and pick up status changes from Change.
+// Each call is traced as a check span; the probe, storage writes and notifications are children.
+func (m *Monitor) CheckOnce(source *storage.Source) *CheckOutcome {
+	ctx, span := tracing.Start(context.Background(), "check",
+		tracing.String("source.id", source.ID),
+		tracing.String("source.name", source.Name),
+		tracing.String("source.type", source.Type))
+	defer span.End()
+
+	outcome := &CheckOutcome{Source: source, PreviousStatus: source.CurrentStatus, CheckedAt: time.Now()}
+	_, probe := tracing.Start(ctx, "probe."+source.Type, tracing.String("target", source.Target))
+	outcome.Status, outcome.Error = m.runCheck(source)
+	probe.SetError(outcome.Error)
+	probe.End()
+	outcome.Latency = time.Since(outcome.CheckedAt)
+	m.stats.recordCheck(source.Type, outcome.Latency, time.Now())
+	if m.durations.record(source.ID, outcome.Latency, source.CheckInterval, time.Now()) {
+		m.logger.Warnf("⚠️  Check of %s took %v, %.0f%% of its %v interval; checks will drift. Lower the timeout or raise the interval.",
+			source.Name, outcome.Latency.Round(time.Millisecond), float64(outcome.Latency)/float64(source.CheckInterval)*100, source.CheckInterval)
+	}
+
+	outcome.Change = m.applyCheckResult(ctx, source, outcome.CheckedAt, outcome.Status, outcome.Error)
+	span.SetAttributes(tracing.Int("check.status", outcome.Status), tracing.Bool("check.status_changed", outcome.Change != nil))
+	span.SetError(outcome.Error)
+	return outcome
 }
 
 // CheckHTTP performs an HTTP request and returns binary status
 func (m *Monitor) CheckHTTP(url string) int {
-	ctx, cancel := context.WithTimeout(context.Background(), m.config.HTTPTimeout)
+	status, _ := m.checkHTTP(&storage.Source{Type: "http", Target: url})
+	return status
+}
+
+// checkHTTP performs an HTTP request and returns the status and, when offline, the reason.
+// A 2xx/3xx response that breaches the source's response limits or schema is offline too.
+func (m *Monitor) checkHTTP(source *storage.Source) (int, string) {
+	url, limits := source.Target, source.ResponseLimits
+	cfg, client := m.settings()
+	client = m.networks.httpClient(client, resolverSpec(cfg, source), sourceTransport(source))
+	client = redirectClient(client, source.RedirectPolicy)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPTimeout)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		m.logger.Printf("HTTP check failed for %s: %v", url, err)
-		return 0
+		m.logger.Debugf("HTTP check failed for %s: %v", url, err)
+		return 0, fmt.Sprintf("invalid request: %v", err)
 	}
+	setCheckHeaders(req, cfg, source)
 
-	resp, err := m.client.Do(req)
+	start := time.Now()
+	resp, err := client.Do(req)
 	if err != nil {
-		m.logger.Printf("HTTP check failed for %s: %v", url, err)
-		return 0
+		m.logger.Debugf("HTTP check failed for %s: %v", url, err)
+		if ctx.Err() == context.DeadlineExceeded {
+			return 0, fmt.Sprintf("timeout after %v", cfg.HTTPTimeout)
+		}
+		return 0, fmt.Sprintf("request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
-	// Drain and close body
-	io.Copy(io.Discard, resp.Body)
+	// Drain and close body; past a max body size, one more byte is enough to tell. Only a
+	// body to validate against a schema is kept.
+	var body io.Reader = resp.Body
+	if limits != nil && limits.MaxBodyBytes > 0 {
+		body = io.LimitReader(body, limits.MaxBodyBytes+1)
+	}
+	var bodyBytes int64
+	var content []byte
+	var readErr error
+	if len(source.ResponseSchema) > 0 {
+		content, readErr = io.ReadAll(io.LimitReader(body, maxSchemaBodyBytes+1))
+		bodyBytes = int64(len(content))
+	} else {
+		bodyBytes, readErr = io.Copy(io.Discard, body)
+	}
+	elapsed := time.Since(start)
 
 	// Online if status code is 2xx or 3xx
-	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
-		m.logger.Printf("HTTP check %s: ONLINE (status %d)", url, resp.StatusCode)
-		return 1
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		m.logger.Debugf("HTTP check %s: OFFLINE (status %d)", url, resp.StatusCode)
+		return 0, fmt.Sprintf("HTTP %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	if violation := source.RedirectPolicy.Violation(resp.Request.URL.String(), resp.StatusCode, resp.Header.Get("Location")); violation != "" {
+		m.logger.Debugf("HTTP check %s: OFFLINE (%s)", url, violation)
+		return 0, violation
+	}
+
+	if violation := source.TLSPolicy.Violation(resp.TLS); violation != "" {
+		m.logger.Debugf("HTTP check %s: OFFLINE (%s)", url, violation)
+		return 0, violation
+	}
+
+	if violation := protocolViolation(source.HTTPVersion, resp); violation != "" {
+		m.logger.Debugf("HTTP check %s: OFFLINE (%s)", url, violation)
+		return 0, violation
+	}
+
+	if !limits.IsZero() || len(source.ResponseSchema) > 0 {
+		if readErr != nil {
+			m.logger.Debugf("HTTP check %s: OFFLINE (reading body: %v)", url, readErr)
+			return 0, fmt.Sprintf("reading body failed: %v", readErr)
+		}
+		if violation := limits.Violation(elapsed, bodyBytes); violation != "" {
+			m.logger.Debugf("HTTP check %s: OFFLINE (status %d, %s)", url, resp.StatusCode, violation)
+			return 0, fmt.Sprintf("HTTP %d but %s", resp.StatusCode, violation)
+		}
+	}
+
+	if len(source.ResponseSchema) > 0 {
+		if violation := m.validateResponse(source, content); violation != "" {
+			m.logger.Debugf("HTTP check %s: OFFLINE (status %d, %s)", url, resp.StatusCode, violation)
+			return 0, fmt.Sprintf("HTTP %d but %s", resp.StatusCode, violation)
+		}
+	}
+
+	m.logger.Debugf("HTTP check %s: ONLINE (status %d)", url, resp.StatusCode)
+	return 1, ""
+}
+
+// protocolViolation returns why a response breaches the HTTP version a source requires, or
+// "" when it doesn't. HTTP/3 isn't spoken over QUIC; h3-advertised only checks Alt-Svc.
+func protocolViolation(version string, resp *http.Response) string {
+	switch version {
+	case storage.HTTPVersion2:
+		if resp.ProtoMajor != 2 {
+			return fmt.Sprintf("%s negotiated, expected HTTP/2", resp.Proto)
+		}
+	case storage.HTTPVersion3Advertised:
+		altSvc := resp.Header.Values("Alt-Svc")
+		if len(altSvc) == 0 {
+			return "HTTP/3 not advertised: no Alt-Svc header"
+		}
+		for _, value := range altSvc {
+			for _, service := range strings.Split(value, ",") {
+				protocol, _, _ := strings.Cut(strings.TrimSpace(service), "=")
+				if protocol == "h3" || strings.HasPrefix(protocol, "h3-") {
+					return ""
+				}
+			}
+		}
+		return fmt.Sprintf("HTTP/3 not advertised: Alt-Svc is %q", strings.Join(altSvc, ", "))
+	}
+	return ""
+}
+
+// setCheckHeaders sets the headers of an http check: HTTP_HEADERS, then HTTP_USER_AGENT, then
+// the source's own. An empty source header removes the header, and Host sets the request's
+// host name, e.g. to check a virtual host by IP.
+func setCheckHeaders(req *http.Request, cfg *config.Config, source *storage.Source) {
+	headers, _ := config.ParseHeaderList(cfg.HTTPHeaders) // Validated when saved
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if cfg.HTTPUserAgent != "" {
+		req.Header.Set("User-Agent", cfg.HTTPUserAgent)
 	}
+	for name, value := range source.Headers {
+		switch {
+		case name == "Host" && value != "":
+			req.Host = value
+		case value == "":
+			req.Header.Del(name)
+		default:
+			req.Header.Set(name, value)
+		}
+	}
+}
+
+// redirectClient returns client following redirects as policy says
+func redirectClient(client *http.Client, policy *storage.RedirectPolicy) *http.Client {
+	if policy.IsZero() {
+		return client
+	}
+	limit := policy.Limit()
+	withPolicy := *client
+	withPolicy.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if limit == 0 {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > limit {
+			return fmt.Errorf("stopped after %d redirects", limit)
+		}
+		return nil
+	}
+	return &withPolicy
+}
 
-	m.logger.Printf("HTTP check %s: OFFLINE (status %d)", url, resp.StatusCode)
-	return 0
+// maxSchemaBodyBytes caps the response bodies validated against a schema, which are read
+// into memory
+const maxSchemaBodyBytes = 1 << 20
+
+// validateResponse checks a response body against a source's JSON Schema and returns the
+// violation, or "" when the body satisfies it
+func (m *Monitor) validateResponse(source *storage.Source, body []byte) string {
+	if len(body) > maxSchemaBodyBytes {
+		return fmt.Sprintf("body is over %d bytes, too large to validate against the schema", maxSchemaBodyBytes)
+	}
+	compiled, err := m.schemas.compile(source.ID, source.ResponseSchema)
+	if err != nil {
+		return fmt.Sprintf("invalid response schema: %v", err)
+	}
+	if err := compiled.ValidateJSON(body); err != nil {
+		return "response violates the schema: " + err.Error()
+	}
+	return ""
 }
 
 // GetSource retrieves a source from the cache or database
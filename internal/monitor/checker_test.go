@@ -0,0 +1,178 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/storage"
+)
+
+// lastWriteOp returns the operation of the monitor's most recent storage write, or ""
+func lastWriteOp(m *Monitor) string {
+	m.stats.mu.Lock()
+	defer m.stats.mu.Unlock()
+	if m.stats.lastWrite == nil {
+		return ""
+	}
+	return m.stats.lastWrite.Operation
+}
+
+func TestCheckResultCoalescing(t *testing.T) {
+	db, err := storage.NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	api := &storage.Source{ID: "api", Name: "API", Type: "http", Target: "https://api.example.com", CurrentStatus: 1, Enabled: true, LastChangeTime: start}
+	web := &storage.Source{ID: "web", Name: "Web", Type: "http", Target: "https://example.com", CurrentStatus: 1, Enabled: true, LastChangeTime: start}
+	for _, source := range []*storage.Source{api, web} {
+		if err := db.SaveSource(source); err != nil {
+			t.Fatalf("SaveSource failed: %v", err)
+		}
+	}
+
+	// The ticker never fires during the test; flushes come from shutdown
+	m := New(db, &config.Config{HTTPTimeout: time.Second, CheckFlushInterval: time.Hour}, nil)
+	ctx := context.Background()
+
+	// Unchanged results are buffered: only the latest per source is kept and nothing is written
+	for i := 1; i <= 3; i++ {
+		m.applyCheckResult(ctx, api, start.Add(time.Duration(i)*time.Minute), 1, "")
+	}
+	m.applyCheckResult(ctx, web, start.Add(2*time.Minute), 1, "")
+	if op := lastWriteOp(m); op != "" {
+		t.Fatalf("Expected no storage write for unchanged results, got %s", op)
+	}
+	m.pendingMu.Lock()
+	pending, pendingAPI := len(m.pendingChecks), m.pendingChecks["api"]
+	m.pendingMu.Unlock()
+	if pending != 2 || !pendingAPI.CheckTime.Equal(start.Add(3*time.Minute)) {
+		t.Errorf("Expected one pending result per source, the latest for api, got %d and %+v", pending, pendingAPI)
+	}
+	if stored, _ := db.GetSource("api"); !stored.LastCheckTime.IsZero() {
+		t.Errorf("Expected the database untouched before a flush, got last check %v", stored.LastCheckTime)
+	}
+
+	// A status change is written at once and supersedes the source's buffered result
+	change := m.applyCheckResult(ctx, api, start.Add(4*time.Minute), 0, "connection refused")
+	if change == nil || change.OldStatus != 1 || change.NewStatus != 0 {
+		t.Fatalf("Expected a 1 → 0 status change, got %+v", change)
+	}
+	if op := lastWriteOp(m); op != "update_source_status" {
+		t.Errorf("Expected the status change to be written immediately, last write was %q", op)
+	}
+	stored, _ := db.GetSource("api")
+	if stored.CurrentStatus != 0 || !stored.LastCheckTime.Equal(start.Add(4*time.Minute)) || stored.LastError != "connection refused" {
+		t.Errorf("Expected api stored offline at the change, got %+v", stored)
+	}
+	if saved, err := db.GetLastStatusChange("api"); err != nil || saved.NewStatus != 0 || !saved.Timestamp.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("Expected the status change to be saved, got %+v, %v", saved, err)
+	}
+	m.pendingMu.Lock()
+	_, apiPending := m.pendingChecks["api"]
+	_, webPending := m.pendingChecks["web"]
+	m.pendingMu.Unlock()
+	if apiPending || !webPending {
+		t.Errorf("Expected only api's buffered result dropped, api pending %v, web pending %v", apiPending, webPending)
+	}
+
+	// Shutdown flushes what is still buffered in one transaction
+	m.applyCheckResult(ctx, api, start.Add(5*time.Minute), 0, "connection refused")
+	m.applyCheckResult(ctx, web, start.Add(5*time.Minute), 1, "")
+	loopCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.flushLoop(loopCtx)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("flushLoop did not return after shutdown")
+	}
+
+	if op := lastWriteOp(m); op != "save_check_results" {
+		t.Errorf("Expected the buffered results flushed on shutdown, last write was %q", op)
+	}
+	for _, id := range []string{"api", "web"} {
+		if stored, _ := db.GetSource(id); !stored.LastCheckTime.Equal(start.Add(5 * time.Minute)) {
+			t.Errorf("Expected %s flushed with the latest check time, got %v", id, stored.LastCheckTime)
+		}
+	}
+	if stored, _ := db.GetSource("api"); stored.CurrentStatus != 0 || stored.LastError != "connection refused" {
+		t.Errorf("Expected the flush to keep api offline with its error, got %+v", stored)
+	}
+	m.pendingMu.Lock()
+	pending = len(m.pendingChecks)
+	m.pendingMu.Unlock()
+	if pending != 0 {
+		t.Errorf("Expected nothing pending after the flush, got %d", pending)
+	}
+}
+
+func TestCheckResultWithoutFlushInterval(t *testing.T) {
+	db, err := storage.NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	source := &storage.Source{ID: "api", Name: "API", Type: "http", CurrentStatus: 1, Enabled: true, LastChangeTime: start}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+
+	// CHECK_FLUSH_INTERVAL=0 writes every result
+	m := New(db, &config.Config{HTTPTimeout: time.Second}, nil)
+	m.applyCheckResult(context.Background(), source, start.Add(time.Minute), 1, "")
+	if stored, _ := db.GetSource("api"); !stored.LastCheckTime.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected the result written immediately, got last check %v", stored.LastCheckTime)
+	}
+	if len(m.pendingChecks) != 0 {
+		t.Errorf("Expected nothing buffered, got %d", len(m.pendingChecks))
+	}
+}
+
+func TestResponseSchemaCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	m := New(nil, &config.Config{HTTPTimeout: time.Second}, nil)
+	source := &storage.Source{ID: "api", Type: "http", Target: server.URL, ResponseSchema: []byte(`{"required":["status"]}`)}
+	if status, reason := m.checkHTTP(source); status != 1 {
+		t.Fatalf("Expected the body to satisfy the schema, got %q", reason)
+	}
+
+	// Later checks reuse the compiled schema
+	first, _ := m.schemas.compile("api", source.ResponseSchema)
+	if status, _ := m.checkHTTP(source); status != 1 {
+		t.Fatal("Expected the second check online")
+	}
+	if again, _ := m.schemas.compile("api", source.ResponseSchema); again != first {
+		t.Error("Expected the cached schema to be reused")
+	}
+
+	// An edited schema is compiled again rather than served stale
+	source = &storage.Source{ID: "api", Type: "http", Target: server.URL, ResponseSchema: []byte(`{"required":["version"]}`)}
+	if status, reason := m.checkHTTP(source); status != 0 || !strings.Contains(reason, "violates the schema") {
+		t.Errorf("Expected the edited schema to be applied, got %d %q", status, reason)
+	}
+
+	// A removed source's schema is dropped
+	m.schemas.forget("api")
+	if _, cached := m.schemas.bySource["api"]; cached {
+		t.Error("Expected the schema to be forgotten")
+	}
+}
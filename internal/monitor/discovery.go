@@ -0,0 +1,278 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	probing "github.com/prometheus-community/pro-bing"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/storage"
+)
+
+const (
+	// discoveryWorkers is how many addresses are probed at once
+	discoveryWorkers = 64
+	// discoveryPingTimeout bounds the single echo request sent to each address
+	discoveryPingTimeout = time.Second
+	// discoveryDialTimeout bounds each TCP connection attempt
+	discoveryDialTimeout = 500 * time.Millisecond
+	// discoveryLookupTimeout bounds the reverse DNS lookup of each host found
+	discoveryLookupTimeout = 2 * time.Second
+)
+
+// arpTablePath lists the kernel's neighbour cache on Linux; elsewhere ARP isn't used
+const arpTablePath = "/proc/net/arp"
+
+// ErrDiscoveryRunning is returned when a discovery scan is started while one is running
+var ErrDiscoveryRunning = errors.New("a discovery scan is already running")
+
+// DiscoveryScan describes the last (or running) network discovery scan
+type DiscoveryScan struct {
+	Running    bool       `json:"running"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Ranges     []string   `json:"ranges"`
+	Ports      []int      `json:"ports"`
+	Scanned    int        `json:"scanned"` // Addresses probed
+	Found      int        `json:"found"`   // Hosts that answered
+	New        int        `json:"new"`     // Hosts not found by an earlier scan
+	Error      string     `json:"error,omitempty"`
+}
+
+// discoveryState guards the single discovery scan a monitor runs at a time
+type discoveryState struct {
+	mu   sync.Mutex
+	scan *DiscoveryScan
+}
+
+// LastDiscovery returns a copy of the last or running discovery scan, or nil
+func (m *Monitor) LastDiscovery() *DiscoveryScan {
+	m.discovery.mu.Lock()
+	defer m.discovery.mu.Unlock()
+	if m.discovery.scan == nil {
+		return nil
+	}
+	scan := *m.discovery.scan
+	return &scan
+}
+
+// StartDiscovery validates the ranges, then scans them for hosts in the background and
+// stores the hosts found. done, if set, is called with the finished scan.
+func (m *Monitor) StartDiscovery(ranges []string, ports []int, done func(*DiscoveryScan)) (*DiscoveryScan, error) {
+	prefixes, err := config.ParseDiscoveryRanges(ranges)
+	if err != nil {
+		return nil, err
+	}
+	if len(prefixes) == 0 {
+		return nil, errors.New("no ranges to scan (set DISCOVERY_RANGES or pass ranges)")
+	}
+
+	m.discovery.mu.Lock()
+	if m.discovery.scan != nil && m.discovery.scan.Running {
+		m.discovery.mu.Unlock()
+		return nil, ErrDiscoveryRunning
+	}
+	ports = sortedPorts(ports)
+	scan := &DiscoveryScan{Running: true, StartedAt: time.Now(), Ports: ports}
+	for _, prefix := range prefixes {
+		scan.Ranges = append(scan.Ranges, prefix.String())
+	}
+	m.discovery.scan = scan
+	started := *scan
+	m.discovery.mu.Unlock()
+
+	go func() {
+		hosts, scanned := m.discover(context.Background(), prefixes, ports)
+		added, err := m.storage.SaveDiscoveredHosts(hosts)
+
+		m.discovery.mu.Lock()
+		finished := time.Now()
+		scan.Running = false
+		scan.FinishedAt = &finished
+		scan.Scanned, scan.Found, scan.New = scanned, len(hosts), added
+		if err != nil {
+			scan.Error = fmt.Sprintf("failed to save hosts: %v", err)
+		}
+		result := *scan
+		m.discovery.mu.Unlock()
+
+		if err != nil {
+			m.logger.Errorf("Discovery scan of %s: %s", strings.Join(result.Ranges, ", "), result.Error)
+		} else {
+			m.logger.Printf("Discovery scan of %s: %d address(es), %d host(s) found, %d new in %v",
+				strings.Join(result.Ranges, ", "), scanned, len(hosts), added, finished.Sub(result.StartedAt).Round(time.Second))
+		}
+		if done != nil {
+			done(&result)
+		}
+	}()
+
+	return &started, nil
+}
+
+// discover probes every address of prefixes with a ping and TCP connections to ports, then
+// adds addresses the ARP table resolved meanwhile. It returns the hosts found and the number
+// of addresses probed.
+func (m *Monitor) discover(ctx context.Context, prefixes []netip.Prefix, ports []int) ([]*storage.DiscoveredHost, int) {
+	var addrs []netip.Addr
+	for _, prefix := range prefixes {
+		addrs = append(addrs, hostAddrs(prefix)...)
+	}
+
+	results := make([]*storage.DiscoveredHost, len(addrs))
+	forEachParallel(len(addrs), func(i int) {
+		results[i] = m.probeHost(ctx, addrs[i], ports)
+	})
+
+	// The probes made the kernel resolve every address on the local network, so hosts that
+	// drop pings and have no open port still show up in the ARP table
+	arp := readARPTable()
+	now := time.Now()
+	var hosts []*storage.DiscoveredHost
+	for i, addr := range addrs {
+		host := results[i]
+		mac, resolved := arp[addr]
+		if host == nil && !resolved {
+			continue
+		}
+		if host == nil {
+			host = &storage.DiscoveredHost{IP: addr.String()}
+		}
+		if resolved {
+			host.MAC = mac
+			host.Methods = append(host.Methods, storage.DiscoveredByARP)
+		}
+		host.LastSeen = now
+		hosts = append(hosts, host)
+	}
+
+	forEachParallel(len(hosts), func(i int) {
+		hosts[i].Hostname = lookupHostname(ctx, hosts[i].IP)
+	})
+	return hosts, len(addrs)
+}
+
+// forEachParallel calls fn for 0..n-1 on up to discoveryWorkers goroutines and waits for all
+func forEachParallel(n int, fn func(int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(discoveryWorkers, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// probeHost pings addr and connects to each port, returning nil when nothing answered
+func (m *Monitor) probeHost(ctx context.Context, addr netip.Addr, ports []int) *storage.DiscoveredHost {
+	host := &storage.DiscoveredHost{IP: addr.String()}
+
+	pinger, err := probing.NewPinger(addr.String())
+	if err == nil {
+		pinger.Count = 1
+		pinger.Timeout = discoveryPingTimeout
+		pinger.SetPrivileged(runtime.GOOS != "darwin")
+		if pinger.RunWithContext(ctx) == nil && pinger.Statistics().PacketsRecv > 0 {
+			host.Methods = append(host.Methods, storage.DiscoveredByPing)
+		}
+	}
+
+	dialer := net.Dialer{Timeout: discoveryDialTimeout}
+	for _, port := range ports {
+		conn, err := dialer.DialContext(ctx, "tcp", netip.AddrPortFrom(addr, uint16(port)).String())
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		host.OpenPorts = append(host.OpenPorts, port)
+	}
+	if len(host.OpenPorts) > 0 {
+		host.Methods = append(host.Methods, storage.DiscoveredByTCP)
+	}
+
+	if len(host.Methods) == 0 {
+		return nil
+	}
+	return host
+}
+
+// hostAddrs lists the addresses of prefix, without the network and broadcast addresses of
+// ranges larger than a /31
+func hostAddrs(prefix netip.Prefix) []netip.Addr {
+	var addrs []netip.Addr
+	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+		addrs = append(addrs, addr)
+	}
+	if prefix.Bits() < 31 && len(addrs) > 2 {
+		addrs = addrs[1 : len(addrs)-1]
+	}
+	return addrs
+}
+
+// readARPTable returns the MAC address of every resolved neighbour, or nothing when the
+// table can't be read (not Linux, or no /proc)
+func readARPTable() map[netip.Addr]string {
+	table := make(map[netip.Addr]string)
+	file, err := os.Open(arpTablePath)
+	if err != nil {
+		return table
+	}
+	defer file.Close()
+
+	// IP address  HW type  Flags  HW address  Mask  Device
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimPrefix(fields[2], "0x"), 16, 8)
+		if err != nil || flags&0x2 == 0 { // ATF_COM: resolved
+			continue
+		}
+		if addr, err := netip.ParseAddr(fields[0]); err == nil {
+			table[addr] = fields[3]
+		}
+	}
+	return table
+}
+
+// lookupHostname returns the first reverse DNS name of ip without the trailing dot, or ""
+func lookupHostname(ctx context.Context, ip string) string {
+	ctx, cancel := context.WithTimeout(ctx, discoveryLookupTimeout)
+	defer cancel()
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
+
+// sortedPorts returns ports sorted and without duplicates
+func sortedPorts(ports []int) []int {
+	sorted := slices.Clone(ports)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+const (
+	// durationSamples is how many recent check durations are kept per source
+	durationSamples = 100
+	// slowCheckRatio is the share of the check interval from which a check counts as slow:
+	// the ticker drops ticks while a check runs, so such checks skew the schedule
+	slowCheckRatio = 0.8
+	// slowCheckWarnEvery limits slow check warnings to one per source in this period
+	slowCheckWarnEvery = 10 * time.Minute
+)
+
+// CheckDurations summarizes the recent check durations of a source
+type CheckDurations struct {
+	SourceID   string  `json:"source_id"`
+	Samples    int     `json:"samples"` // Up to the last 100 checks since the monitor started
+	P50Ms      float64 `json:"p50_ms"`
+	P95Ms      float64 `json:"p95_ms"`
+	MaxMs      float64 `json:"max_ms"`
+	IntervalMs int64   `json:"interval_ms"`
+	SlowChecks int     `json:"slow_checks"` // Samples that took at least 80% of the interval
+}
+
+// durationLog keeps a ring of recent check durations per source
+type durationLog struct {
+	mu       sync.Mutex
+	bySource map[string]*durationRing
+}
+
+// durationRing holds one source's recent durations
+type durationRing struct {
+	samples    [durationSamples]time.Duration
+	next       int
+	count      int
+	lastWarned time.Time
+}
+
+// record stores a check duration and reports whether it is slow for interval and a
+// warning is due
+func (l *durationLog) record(sourceID string, duration, interval time.Duration, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.bySource == nil {
+		l.bySource = make(map[string]*durationRing)
+	}
+	ring, ok := l.bySource[sourceID]
+	if !ok {
+		ring = &durationRing{}
+		l.bySource[sourceID] = ring
+	}
+	ring.samples[ring.next] = duration
+	ring.next = (ring.next + 1) % durationSamples
+	if ring.count < durationSamples {
+		ring.count++
+	}
+
+	if interval <= 0 || float64(duration) < slowCheckRatio*float64(interval) || now.Sub(ring.lastWarned) < slowCheckWarnEvery {
+		return false
+	}
+	ring.lastWarned = now
+	return true
+}
+
+// forget drops a source's durations
+func (l *durationLog) forget(sourceID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.bySource, sourceID)
+}
+
+// summary computes percentiles over a source's recorded durations
+func (l *durationLog) summary(sourceID string, interval time.Duration) *CheckDurations {
+	result := &CheckDurations{SourceID: sourceID, IntervalMs: interval.Milliseconds()}
+
+	l.mu.Lock()
+	ring, ok := l.bySource[sourceID]
+	var samples []time.Duration
+	if ok {
+		samples = append(samples, ring.samples[:ring.count]...)
+	}
+	l.mu.Unlock()
+	if len(samples) == 0 {
+		return result
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	result.Samples = len(samples)
+	result.P50Ms = ms(percentile(samples, 50))
+	result.P95Ms = ms(percentile(samples, 95))
+	result.MaxMs = ms(samples[len(samples)-1])
+	for _, d := range samples {
+		if interval > 0 && float64(d) >= slowCheckRatio*float64(interval) {
+			result.SlowChecks++
+		}
+	}
+	return result
+}
+
+// percentile returns the nearest-rank percentile p of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 × n)
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// CheckDurations returns the recent check durations of a source with p50 and p95
+func (m *Monitor) CheckDurations(source *storage.Source) *CheckDurations {
+	return m.durations.summary(source.ID, source.CheckInterval)
+}
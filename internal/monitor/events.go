@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"sync"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// subscriberBuffer is how many events a slow subscriber may lag behind before events are dropped
+const subscriberBuffer = 64
+
+// Event is a status change published by the monitor
+type Event struct {
+	SourceID   string
+	SourceName string
+	Change     *storage.StatusChange
+}
+
+// EventBus fans out monitor events to live subscribers (e.g. SSE clients).
+// It outlives individual Monitor instances so subscribers survive bot restarts.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+}
+
+// NewEventBus creates an empty EventBus
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber and returns its channel and an unsubscribe function
+func (eb *EventBus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	eb.mu.Lock()
+	eb.subscribers[ch] = struct{}{}
+	eb.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			eb.mu.Lock()
+			delete(eb.subscribers, ch)
+			eb.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish delivers an event to all subscribers without blocking; full subscribers miss the event
+func (eb *EventBus) Publish(event Event) {
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+
+	for ch := range eb.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// SubscriberCount returns the number of active subscribers
+func (eb *EventBus) SubscriberCount() int {
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+	return len(eb.subscribers)
+}
@@ -0,0 +1,163 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// DefaultHealthWindow is the period health scores are computed over unless one is given
+const DefaultHealthWindow = 24 * time.Hour
+
+// Health score weights; components without data are left out and the rest scaled up
+const (
+	healthUptimeWeight  = 60
+	healthLatencyWeight = 20
+	healthFlapWeight    = 20
+
+	// healthUptimeFloor is the uptime at which the uptime component reaches 0
+	healthUptimeFloor = 90.0
+	// healthFlapLimit is the number of outages in the window at which the flap component reaches 0
+	healthFlapLimit = 10
+	// Latency trend: the recent median check duration over the earlier one. Up to
+	// healthLatencyOK scores 100, healthLatencyBad and above 0.
+	healthLatencyOK  = 1.2
+	healthLatencyBad = 3.0
+	// healthLatencyMinSamples is how many recent check durations the trend needs
+	healthLatencyMinSamples = 20
+)
+
+// Health score statuses
+const (
+	HealthHealthy   = "healthy"   // 80 and above
+	HealthDegraded  = "degraded"  // 50 to 79
+	HealthUnhealthy = "unhealthy" // Below 50
+	HealthUnknown   = "unknown"   // Status never known in the window
+)
+
+// LatencyTrend compares a source's recent check durations with the ones before them
+type LatencyTrend struct {
+	Samples    int     `json:"samples"`
+	EarlierP50 float64 `json:"earlier_p50_ms"` // Median of the older half of the samples
+	RecentP50  float64 `json:"recent_p50_ms"`  // Median of the newer half
+	Ratio      float64 `json:"ratio"`          // RecentP50 / EarlierP50; above 1 means checks got slower
+}
+
+// HealthScore is a 0-100 indicator of a source's recent health, blending uptime, whether
+// checks are getting slower and how often it went down
+type HealthScore struct {
+	SourceID      string        `json:"source_id"`
+	SourceName    string        `json:"source_name"`
+	Score         *int          `json:"score"` // nil when the status was never known in the window
+	Status        string        `json:"status"`
+	From          time.Time     `json:"from"`
+	To            time.Time     `json:"to"`
+	UptimePercent *float64      `json:"uptime_percent"`
+	UptimeScore   *int          `json:"uptime_score"`
+	Latency       *LatencyTrend `json:"latency,omitempty"` // nil while the monitor has too few check durations
+	LatencyScore  *int          `json:"latency_score"`
+	Flaps         int           `json:"flaps"` // Outages that started in the window
+	FlapScore     int           `json:"flap_score"`
+}
+
+// ComputeHealthScore computes the health score of a source over [to-window, to). The latency
+// component needs the recent check durations kept by a running monitor; m may be nil.
+func ComputeHealthScore(db *storage.BoltDB, m *Monitor, source *storage.Source, window time.Duration, to time.Time) (*HealthScore, error) {
+	from := to.Add(-window)
+	stats, err := db.ComputeUptimeStats(source, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute uptime: %w", err)
+	}
+
+	health := &HealthScore{
+		SourceID:      source.ID,
+		SourceName:    source.Name,
+		Status:        HealthUnknown,
+		From:          from,
+		To:            to,
+		UptimePercent: stats.UptimePercent,
+		Flaps:         stats.OutageCount,
+		FlapScore:     clampScore(100 * (1 - float64(stats.OutageCount)/healthFlapLimit)),
+	}
+	if m != nil {
+		health.Latency = m.durations.trend(source.ID)
+	}
+	if stats.UptimePercent == nil {
+		return health, nil
+	}
+
+	uptimeScore := clampScore((*stats.UptimePercent - healthUptimeFloor) / (100 - healthUptimeFloor) * 100)
+	health.UptimeScore = &uptimeScore
+	total := uptimeScore*healthUptimeWeight + health.FlapScore*healthFlapWeight
+	weights := healthUptimeWeight + healthFlapWeight
+	if health.Latency != nil {
+		latencyScore := clampScore((healthLatencyBad - health.Latency.Ratio) / (healthLatencyBad - healthLatencyOK) * 100)
+		health.LatencyScore = &latencyScore
+		total += latencyScore * healthLatencyWeight
+		weights += healthLatencyWeight
+	}
+
+	score := (total + weights/2) / weights
+	health.Score = &score
+	switch {
+	case score >= 80:
+		health.Status = HealthHealthy
+	case score >= 50:
+		health.Status = HealthDegraded
+	default:
+		health.Status = HealthUnhealthy
+	}
+	return health, nil
+}
+
+// clampScore rounds a score to an integer between 0 and 100
+func clampScore(score float64) int {
+	switch {
+	case score <= 0:
+		return 0
+	case score >= 100:
+		return 100
+	}
+	return int(score + 0.5)
+}
+
+// trend compares the median of a source's older recorded durations with the newer ones;
+// nil with fewer than healthLatencyMinSamples samples
+func (l *durationLog) trend(sourceID string) *LatencyTrend {
+	l.mu.Lock()
+	ring, ok := l.bySource[sourceID]
+	var samples []time.Duration
+	if ok {
+		// Oldest first: a full ring starts at the next slot to overwrite
+		start := 0
+		if ring.count == durationSamples {
+			start = ring.next
+		}
+		for i := 0; i < ring.count; i++ {
+			samples = append(samples, ring.samples[(start+i)%durationSamples])
+		}
+	}
+	l.mu.Unlock()
+	if len(samples) < healthLatencyMinSamples {
+		return nil
+	}
+
+	median := func(durations []time.Duration) float64 {
+		sorted := append([]time.Duration(nil), durations...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		return float64(percentile(sorted, 50).Microseconds()) / 1000
+	}
+	half := len(samples) / 2
+	trend := &LatencyTrend{
+		Samples:    len(samples),
+		EarlierP50: median(samples[:half]),
+		RecentP50:  median(samples[half:]),
+		Ratio:      1,
+	}
+	if trend.EarlierP50 > 0 {
+		trend.Ratio = trend.RecentP50 / trend.EarlierP50
+	}
+	return trend
+}
@@ -0,0 +1,480 @@
+package monitor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// JSONSchema is a compiled JSON Schema for http response bodies. It supports the keywords
+// health endpoints need: type, enum, const, properties, required, additionalProperties,
+// items, minItems, maxItems, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// minLength, maxLength, pattern (RE2 syntax), allOf, anyOf, oneOf and not. Other keywords
+// such as $schema, title or description are ignored; $ref is rejected.
+type JSONSchema struct {
+	always *bool // Boolean schema: true accepts and false rejects everything
+
+	types    []string
+	enum     []interface{}
+	hasConst bool
+	constant interface{}
+
+	properties           map[string]*JSONSchema
+	required             []string
+	additionalProperties *JSONSchema
+
+	items    *JSONSchema
+	minItems *int
+	maxItems *int
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	allOf []*JSONSchema
+	anyOf []*JSONSchema
+	oneOf []*JSONSchema
+	not   *JSONSchema
+}
+
+// SchemaViolation is where and how a document fails a schema
+type SchemaViolation struct {
+	Path    string // JSON pointer, "" for the document itself
+	Message string
+}
+
+func (v *SchemaViolation) Error() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+var schemaTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+// CompileJSONSchema parses a JSON Schema document
+func CompileJSONSchema(data []byte) (*JSONSchema, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("schema is not valid JSON: %w", err)
+	}
+	return compileSchema(doc, "")
+}
+
+// schemaCache keeps the compiled response schema of each source, so checks don't parse it
+// again. An edited schema has a different hash and is compiled once more.
+type schemaCache struct {
+	mu       sync.Mutex
+	bySource map[string]*cachedSchema
+}
+
+// cachedSchema is a source's compiled schema, or why it failed to compile
+type cachedSchema struct {
+	hash   [sha256.Size]byte
+	schema *JSONSchema
+	err    error
+}
+
+// compile returns the compiled schema of a source, compiling data unless it is cached
+func (c *schemaCache) compile(sourceID string, data []byte) (*JSONSchema, error) {
+	hash := sha256.Sum256(data)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.bySource[sourceID]; ok && cached.hash == hash {
+		return cached.schema, cached.err
+	}
+	if c.bySource == nil {
+		c.bySource = make(map[string]*cachedSchema)
+	}
+	schema, err := CompileJSONSchema(data)
+	c.bySource[sourceID] = &cachedSchema{hash: hash, schema: schema, err: err}
+	return schema, err
+}
+
+// forget drops a removed source's schema
+func (c *schemaCache) forget(sourceID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.bySource, sourceID)
+}
+
+// compileSchema compiles the schema at path of the schema document
+func compileSchema(doc interface{}, path string) (*JSONSchema, error) {
+	if b, ok := doc.(bool); ok {
+		return &JSONSchema{always: &b}, nil
+	}
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, schemaError(path, "a schema must be an object or a boolean")
+	}
+	if _, ok := obj["$ref"]; ok {
+		return nil, schemaError(path, "$ref is not supported")
+	}
+
+	s := &JSONSchema{}
+	var err error
+	if v, ok := obj["type"]; ok {
+		switch t := v.(type) {
+		case string:
+			s.types = []string{t}
+		case []interface{}:
+			for _, item := range t {
+				name, ok := item.(string)
+				if !ok {
+					return nil, schemaError(path+"/type", "must be a string or an array of strings")
+				}
+				s.types = append(s.types, name)
+			}
+		default:
+			return nil, schemaError(path+"/type", "must be a string or an array of strings")
+		}
+		for _, t := range s.types {
+			if !schemaTypes[t] {
+				return nil, schemaError(path+"/type", fmt.Sprintf("unknown type %q", t))
+			}
+		}
+	}
+	if v, ok := obj["enum"]; ok {
+		if s.enum, ok = v.([]interface{}); !ok {
+			return nil, schemaError(path+"/enum", "must be an array")
+		}
+	}
+	if v, ok := obj["const"]; ok {
+		s.hasConst, s.constant = true, v
+	}
+
+	if v, ok := obj["properties"]; ok {
+		props, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, schemaError(path+"/properties", "must be an object")
+		}
+		s.properties = make(map[string]*JSONSchema, len(props))
+		for name, prop := range props {
+			if s.properties[name], err = compileSchema(prop, path+"/properties/"+escapePointer(name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if v, ok := obj["required"]; ok {
+		names, ok := v.([]interface{})
+		if !ok {
+			return nil, schemaError(path+"/required", "must be an array of strings")
+		}
+		for _, item := range names {
+			name, ok := item.(string)
+			if !ok {
+				return nil, schemaError(path+"/required", "must be an array of strings")
+			}
+			s.required = append(s.required, name)
+		}
+	}
+	if s.additionalProperties, err = compileSubschema(obj, "additionalProperties", path); err != nil {
+		return nil, err
+	}
+
+	if s.items, err = compileSubschema(obj, "items", path); err != nil {
+		return nil, err
+	}
+	if s.minItems, err = schemaCount(obj, "minItems", path); err != nil {
+		return nil, err
+	}
+	if s.maxItems, err = schemaCount(obj, "maxItems", path); err != nil {
+		return nil, err
+	}
+
+	for keyword, target := range map[string]**float64{
+		"minimum": &s.minimum, "maximum": &s.maximum,
+		"exclusiveMinimum": &s.exclusiveMinimum, "exclusiveMaximum": &s.exclusiveMaximum,
+	} {
+		if v, ok := obj[keyword]; ok {
+			n, ok := v.(float64)
+			if !ok {
+				return nil, schemaError(path+"/"+keyword, "must be a number")
+			}
+			*target = &n
+		}
+	}
+
+	if s.minLength, err = schemaCount(obj, "minLength", path); err != nil {
+		return nil, err
+	}
+	if s.maxLength, err = schemaCount(obj, "maxLength", path); err != nil {
+		return nil, err
+	}
+	if v, ok := obj["pattern"]; ok {
+		expr, ok := v.(string)
+		if !ok {
+			return nil, schemaError(path+"/pattern", "must be a string")
+		}
+		if s.pattern, err = regexp.Compile(expr); err != nil {
+			return nil, schemaError(path+"/pattern", err.Error())
+		}
+	}
+
+	for keyword, target := range map[string]*[]*JSONSchema{"allOf": &s.allOf, "anyOf": &s.anyOf, "oneOf": &s.oneOf} {
+		v, ok := obj[keyword]
+		if !ok {
+			continue
+		}
+		list, ok := v.([]interface{})
+		if !ok || len(list) == 0 {
+			return nil, schemaError(path+"/"+keyword, "must be a non-empty array of schemas")
+		}
+		for i, item := range list {
+			sub, err := compileSchema(item, fmt.Sprintf("%s/%s/%d", path, keyword, i))
+			if err != nil {
+				return nil, err
+			}
+			*target = append(*target, sub)
+		}
+	}
+	if s.not, err = compileSubschema(obj, "not", path); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// compileSubschema compiles the schema under keyword, nil when it's absent
+func compileSubschema(obj map[string]interface{}, keyword, path string) (*JSONSchema, error) {
+	v, ok := obj[keyword]
+	if !ok {
+		return nil, nil
+	}
+	return compileSchema(v, path+"/"+keyword)
+}
+
+// schemaCount reads a non-negative integer keyword, nil when it's absent
+func schemaCount(obj map[string]interface{}, keyword, path string) (*int, error) {
+	v, ok := obj[keyword]
+	if !ok {
+		return nil, nil
+	}
+	n, ok := v.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, schemaError(path+"/"+keyword, "must be a non-negative integer")
+	}
+	count := int(n)
+	return &count, nil
+}
+
+func schemaError(path, message string) error {
+	if path == "" {
+		return errors.New("schema: " + message)
+	}
+	return fmt.Errorf("schema %s: %s", path, message)
+}
+
+// ValidateJSON checks that data is a JSON document satisfying the schema. The error is a
+// *SchemaViolation for the first violation found.
+func (s *JSONSchema) ValidateJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return &SchemaViolation{Message: fmt.Sprintf("response is not valid JSON: %v", err)}
+	}
+	if decoder.More() {
+		return &SchemaViolation{Message: "response is not valid JSON: data after the top-level value"}
+	}
+	if v := s.validate(doc, ""); v != nil {
+		return v
+	}
+	return nil
+}
+
+// validate returns the first violation of value at path, nil when it satisfies the schema
+func (s *JSONSchema) validate(value interface{}, path string) *SchemaViolation {
+	if s.always != nil {
+		if *s.always {
+			return nil
+		}
+		return &SchemaViolation{Path: path, Message: "no value is allowed here"}
+	}
+
+	if len(s.types) > 0 && !hasType(s.types, value) {
+		return &SchemaViolation{Path: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(s.types, " or "), jsonType(value))}
+	}
+	if s.enum != nil {
+		found := false
+		for _, allowed := range s.enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &SchemaViolation{Path: path, Message: fmt.Sprintf("%s is not one of %s", compactJSON(value), compactJSON(s.enum))}
+		}
+	}
+	if s.hasConst && !reflect.DeepEqual(s.constant, value) {
+		return &SchemaViolation{Path: path, Message: fmt.Sprintf("expected %s, got %s", compactJSON(s.constant), compactJSON(value))}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				return &SchemaViolation{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names) // Report the same violation on every check
+		for _, name := range names {
+			propPath := path + "/" + escapePointer(name)
+			if prop, ok := s.properties[name]; ok {
+				if violation := prop.validate(v[name], propPath); violation != nil {
+					return violation
+				}
+			} else if s.additionalProperties != nil {
+				if violation := s.additionalProperties.validate(v[name], propPath); violation != nil {
+					if s.additionalProperties.always != nil {
+						violation.Message = "unexpected property"
+					}
+					return violation
+				}
+			}
+		}
+	case []interface{}:
+		if s.minItems != nil && len(v) < *s.minItems {
+			return &SchemaViolation{Path: path, Message: fmt.Sprintf("expected at least %d items, got %d", *s.minItems, len(v))}
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			return &SchemaViolation{Path: path, Message: fmt.Sprintf("expected at most %d items, got %d", *s.maxItems, len(v))}
+		}
+		if s.items != nil {
+			for i, item := range v {
+				if violation := s.items.validate(item, path+"/"+strconv.Itoa(i)); violation != nil {
+					return violation
+				}
+			}
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			return &SchemaViolation{Path: path, Message: fmt.Sprintf("%v is less than the minimum %v", v, *s.minimum)}
+		}
+		if s.maximum != nil && v > *s.maximum {
+			return &SchemaViolation{Path: path, Message: fmt.Sprintf("%v is greater than the maximum %v", v, *s.maximum)}
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			return &SchemaViolation{Path: path, Message: fmt.Sprintf("%v must be greater than %v", v, *s.exclusiveMinimum)}
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			return &SchemaViolation{Path: path, Message: fmt.Sprintf("%v must be less than %v", v, *s.exclusiveMaximum)}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength != nil && length < *s.minLength {
+			return &SchemaViolation{Path: path, Message: fmt.Sprintf("expected at least %d characters, got %d", *s.minLength, length)}
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			return &SchemaViolation{Path: path, Message: fmt.Sprintf("expected at most %d characters, got %d", *s.maxLength, length)}
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return &SchemaViolation{Path: path, Message: fmt.Sprintf("%s does not match pattern %q", compactJSON(v), s.pattern.String())}
+		}
+	}
+
+	for _, sub := range s.allOf {
+		if violation := sub.validate(value, path); violation != nil {
+			return violation
+		}
+	}
+	if len(s.anyOf) > 0 {
+		matched := false
+		for _, sub := range s.anyOf {
+			if sub.validate(value, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return &SchemaViolation{Path: path, Message: "does not match any schema of anyOf"}
+		}
+	}
+	if len(s.oneOf) > 0 {
+		matches := 0
+		for _, sub := range s.oneOf {
+			if sub.validate(value, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return &SchemaViolation{Path: path, Message: fmt.Sprintf("matches %d schemas of oneOf, expected exactly one", matches)}
+		}
+	}
+	if s.not != nil && s.not.validate(value, path) == nil {
+		return &SchemaViolation{Path: path, Message: "matches the schema of not"}
+	}
+	return nil
+}
+
+// hasType reports whether value has one of types; integers are numbers too
+func hasType(types []string, value interface{}) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type of a decoded value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// compactJSON renders a decoded value for a violation message, cut to a readable length
+func compactJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	if runes := []rune(string(data)); len(runes) > 80 {
+		return string(runes[:77]) + "..."
+	}
+	return string(data)
+}
+
+// escapePointer escapes a property name for a JSON pointer
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -1,6 +1,9 @@
 package monitor
 
 import (
+	"context"
+	"fmt"
+	"net"
 	"runtime"
 
 	probing "github.com/prometheus-community/pro-bing"
@@ -8,36 +11,58 @@ import (
 
 // PingTarget performs an ICMP ping and returns binary status (1=online, 0=offline)
 func (m *Monitor) PingTarget(target string) int {
-	pinger, err := probing.NewPinger(target)
-	if err != nil {
-		m.logger.Printf("Failed to create pinger for %s: %v", target, err)
-		return 0
+	cfg, _ := m.settings()
+	status, _ := m.ping(target, cfg.DNSResolver, "")
+	return status
+}
+
+// ping performs an ICMP ping and returns the status and, when offline, the reason. Host
+// names are resolved with the resolver of resolverSpec to an address of the family.
+func (m *Monitor) ping(target, resolverSpec, family string) (int, string) {
+	cfg, _ := m.settings()
+	var pinger *probing.Pinger
+	if family == "" && (resolverSpec == "" || net.ParseIP(target) != nil) {
+		var err error
+		if pinger, err = probing.NewPinger(target); err != nil {
+			m.logger.Errorf("Failed to create pinger for %s: %v", target, err)
+			return 0, fmt.Sprintf("ping setup failed: %v", err)
+		}
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.PingTimeout)
+		addr, err := lookupIP(ctx, m.networks.network(resolverSpec).resolver, target, family)
+		cancel()
+		if err != nil {
+			m.logger.Debugf("DNS lookup failed for %s: %v", target, err)
+			return 0, fmt.Sprintf("dns lookup failed: %v", err)
+		}
+		pinger = probing.New(target)
+		pinger.SetIPAddr(addr)
 	}
 
 	// Configure pinger
-	pinger.Count = m.config.PingCount
-	pinger.Timeout = m.config.PingTimeout
+	pinger.Count = cfg.PingCount
+	pinger.Timeout = cfg.PingTimeout
 
 	// Use unprivileged mode on macOS (no sudo required)
 	// Privileged mode on Linux (requires setcap)
 	pinger.SetPrivileged(runtime.GOOS != "darwin")
 
 	// Run ping
-	err = pinger.Run()
+	err := pinger.Run()
 	if err != nil {
-		m.logger.Printf("Ping failed for %s: %v", target, err)
-		return 0
+		m.logger.Debugf("Ping failed for %s: %v", target, err)
+		return 0, fmt.Sprintf("ping failed: %v", err)
 	}
 
 	stats := pinger.Statistics()
 
 	// Online if we received at least one packet
 	if stats.PacketsRecv > 0 {
-		m.logger.Printf("Ping %s: ONLINE (RTT: %v, loss: %.2f%%)",
+		m.logger.Debugf("Ping %s: ONLINE (RTT: %v, loss: %.2f%%)",
 			target, stats.AvgRtt, stats.PacketLoss)
-		return 1
+		return 1, ""
 	}
 
-	m.logger.Printf("Ping %s: OFFLINE (100%% packet loss)", target)
-	return 0
+	m.logger.Debugf("Ping %s: OFFLINE (100%% packet loss)", target)
+	return 0, fmt.Sprintf("no reply: 100%% packet loss (%d packets, timeout %v)", stats.PacketsSent, cfg.PingTimeout)
 }
@@ -0,0 +1,387 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tg-monitor-bot/internal/config"
+	"tg-monitor-bot/internal/storage"
+)
+
+// dohTimeout bounds a DNS-over-HTTPS exchange
+const dohTimeout = 5 * time.Second
+
+// ipv6Head is how long a prefer-ipv6 dial tries IPv6 alone before racing IPv4, the same
+// as Go's Happy Eyeballs fallback delay
+const ipv6Head = 300 * time.Millisecond
+
+// checkNetwork is how checks with one resolver reach their targets. Each resolver has its
+// own transports, so a pooled connection is never reused for a source that would resolve
+// the host differently, e.g. with split-horizon DNS.
+type checkNetwork struct {
+	resolver   *net.Resolver
+	transports map[transportKey]*http.Transport // Built lazily
+}
+
+// transportKey tells the transports of a checkNetwork apart
+type transportKey struct {
+	family string // storage.AddressFamily*, or empty
+	proxy  string // SOCKS5 proxy URL, or empty to connect directly
+	http2  bool   // Speak HTTP/2 only, see newCheckTransport
+	audit  bool   // For sources with a TLS policy, see newCheckTransport
+}
+
+// sourceTransport returns the key of the transport of an http source
+func sourceTransport(source *storage.Source) transportKey {
+	return transportKey{family: source.AddressFamily, proxy: source.Proxy, http2: source.HTTPVersion == storage.HTTPVersion2,
+		audit: !source.TLSPolicy.IsZero()}
+}
+
+// transportSettings are the HTTP_* transport knobs every check transport is built with
+type transportSettings struct {
+	maxIdleConns        int
+	maxConnsPerHost     int
+	disableKeepAlives   bool
+	tlsHandshakeTimeout time.Duration
+}
+
+// transportSettingsOf returns the transport settings of a config
+func transportSettingsOf(cfg *config.Config) transportSettings {
+	return transportSettings{
+		maxIdleConns:        cfg.HTTPMaxIdleConns,
+		maxConnsPerHost:     cfg.HTTPMaxConnsPerHost,
+		disableKeepAlives:   cfg.HTTPDisableKeepAlives,
+		tlsHandshakeTimeout: cfg.HTTPTLSHandshakeTimeout,
+	}
+}
+
+// networks caches a checkNetwork per resolver spec
+type networks struct {
+	mu       sync.Mutex
+	bySpec   map[string]*checkNetwork
+	settings transportSettings
+}
+
+// configure sets the settings of the transports. When they change, the cached networks are
+// dropped, closing their idle connections, and rebuilt on demand.
+func (n *networks) configure(settings transportSettings) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if settings == n.settings {
+		return
+	}
+	for _, network := range n.bySpec {
+		for _, transport := range network.transports {
+			transport.CloseIdleConnections()
+		}
+	}
+	n.bySpec = nil
+	n.settings = settings
+}
+
+// resolverSpec returns the resolver a source's checks use: its own, or DNS_RESOLVER
+func resolverSpec(cfg *config.Config, source *storage.Source) string {
+	if source.Resolver != "" {
+		return source.Resolver
+	}
+	return cfg.DNSResolver
+}
+
+// network returns the check network of a resolver spec. Specs are validated when saved, so
+// an invalid one falls back to the system resolver.
+func (n *networks) network(spec string) *checkNetwork {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if network, ok := n.bySpec[spec]; ok {
+		return network
+	}
+
+	resolver, err := config.ParseResolver(spec)
+	if err != nil {
+		resolver = config.Resolver{}
+	}
+	network := &checkNetwork{resolver: newResolver(resolver)}
+	if n.bySpec == nil {
+		n.bySpec = make(map[string]*checkNetwork)
+	}
+	n.bySpec[spec] = network
+	return network
+}
+
+// httpClient returns client with the transport of the network for key
+func (n *networks) httpClient(client *http.Client, spec string, key transportKey) *http.Client {
+	network := n.network(spec)
+
+	n.mu.Lock()
+	transport, ok := network.transports[key]
+	if !ok {
+		transport = newCheckTransport(network.resolver, key, n.settings)
+		if network.transports == nil {
+			network.transports = make(map[transportKey]*http.Transport)
+		}
+		network.transports[key] = transport
+	}
+	n.mu.Unlock()
+
+	withTransport := *client
+	withTransport.Transport = transport
+	return &withTransport
+}
+
+// newCheckTransport returns a transport with settings, resolving names with resolver and
+// dialing the address family (see dialFamily) of key. With a proxy, it connects through the SOCKS5
+// proxy, which resolves the target's name itself, so onion services work over Tor; the
+// resolver and family then only apply to reaching the proxy. An HTTP/2 transport offers only
+// h2 over TLS and uses prior knowledge (h2c) for http:// URLs, so a server without HTTP/2
+// fails the connection. An audit transport,
+// used for sources with a TLS policy, still verifies certificates but, unlike Go's defaults,
+// accepts TLS 1.0 and every cipher suite, so the policy can report what a weak server
+// negotiates instead of a bare handshake failure. It doesn't reuse connections, so every
+// check sees a fresh handshake.
+func newCheckTransport(resolver *net.Resolver, key transportKey, settings transportSettings) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Resolver: resolver}
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialFamily(ctx, dialer, key.family, network, address)
+	}
+	transport.MaxIdleConns = settings.maxIdleConns
+	transport.MaxConnsPerHost = settings.maxConnsPerHost
+	transport.DisableKeepAlives = settings.disableKeepAlives
+	transport.TLSHandshakeTimeout = settings.tlsHandshakeTimeout
+	if key.proxy != "" {
+		if proxy, err := url.Parse(key.proxy); err == nil {
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+	}
+	if key.http2 {
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP2(true)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+	}
+	if key.audit {
+		var suites []uint16
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			suites = append(suites, suite.ID)
+		}
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS10, CipherSuites: suites}
+		transport.DisableKeepAlives = true
+	}
+	return transport
+}
+
+// newResolver builds the net.Resolver of a parsed resolver spec
+func newResolver(spec config.Resolver) *net.Resolver {
+	switch {
+	case spec.DoHURL != "":
+		client := &http.Client{Timeout: dohTimeout}
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return &dohConn{ctx: ctx, client: client, url: spec.DoHURL}, nil
+			},
+		}
+	case len(spec.Servers) > 0:
+		var next atomic.Uint32
+		return &net.Resolver{
+			PreferGo: true,
+			// Go's resolver retries through the servers of resolv.conf; each attempt goes
+			// to the next configured server instead
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				server := spec.Servers[int(next.Add(1)-1)%len(spec.Servers)]
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return net.DefaultResolver
+}
+
+// dialFamily dials a TCP address over an address family. IPv4 and IPv6 restrict the dial to
+// that family, so a broken AAAA or A record of the host is never tried. Prefer-ipv6 dials
+// IPv6 and races IPv4 when that hasn't connected within ipv6Head or has failed, taking the
+// first connection.
+func dialFamily(ctx context.Context, dialer *net.Dialer, family, network, address string) (net.Conn, error) {
+	switch family {
+	case storage.AddressFamilyIPv4:
+		return dialer.DialContext(ctx, "tcp4", address)
+	case storage.AddressFamilyIPv6:
+		return dialer.DialContext(ctx, "tcp6", address)
+	case storage.AddressFamilyPreferIPv6:
+	default:
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type dialed struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan dialed, 2)
+	dial := func(network string) {
+		conn, err := dialer.DialContext(ctx, network, address)
+		results <- dialed{conn, err}
+	}
+
+	go dial("tcp6")
+	pending, fellBack := 1, false
+	fallback := time.NewTimer(ipv6Head)
+	defer fallback.Stop()
+	var firstErr error
+	for {
+		select {
+		case <-fallback.C:
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				// Close the connection of a slower attempt, if it still connects
+				go func(pending int) {
+					for ; pending > 0; pending-- {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}
+				}(pending)
+				return result.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = result.err // IPv6's, which says more when the host has no A record
+			}
+			if fellBack {
+				if pending == 0 {
+					return nil, firstErr
+				}
+				continue
+			}
+		}
+
+		// IPv6 is slow or failed: race IPv4
+		fallback.Stop()
+		fellBack = true
+		pending++
+		go dial("tcp4")
+	}
+}
+
+// lookupIP resolves host with resolver to an address of the family. Without a family it
+// prefers IPv4 like the system ping does.
+func lookupIP(ctx context.Context, resolver *net.Resolver, host, family string) (*net.IPAddr, error) {
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var v4, v6 *net.IPAddr
+	for i := range addrs {
+		if addrs[i].IP.To4() != nil && v4 == nil {
+			v4 = &addrs[i]
+		} else if addrs[i].IP.To4() == nil && v6 == nil {
+			v6 = &addrs[i]
+		}
+	}
+
+	var addr *net.IPAddr
+	switch family {
+	case storage.AddressFamilyIPv4:
+		addr = v4
+	case storage.AddressFamilyIPv6:
+		addr = v6
+	case storage.AddressFamilyPreferIPv6:
+		if addr = v6; addr == nil {
+			addr = v4
+		}
+	default:
+		if addr = v4; addr == nil {
+			addr = v6
+		}
+	}
+	if addr == nil {
+		if family == storage.AddressFamilyIPv4 || family == storage.AddressFamilyIPv6 {
+			return nil, fmt.Errorf("no %s address for %s", family, host)
+		}
+		return nil, fmt.Errorf("no addresses for %s", host)
+	}
+	return addr, nil
+}
+
+// dohConn carries the DNS messages of Go's resolver over HTTPS (RFC 8484). It isn't a
+// PacketConn, so the resolver talks to it like to a TCP server: a query prefixed with its
+// length is written, then the length-prefixed answer is read back.
+type dohConn struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+	query  bytes.Buffer
+	answer *bytes.Reader
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	return c.query.Write(b)
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.answer == nil {
+		if err := c.exchange(); err != nil {
+			return 0, err
+		}
+	}
+	return c.answer.Read(b)
+}
+
+// exchange posts the buffered query and buffers the answer
+func (c *dohConn) exchange() error {
+	query := c.query.Bytes()
+	if len(query) < 2 || int(binary.BigEndian.Uint16(query)) != len(query)-2 {
+		return errors.New("dns over https: incomplete query")
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.url, bytes.NewReader(query[2:]))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("dns over https: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("dns over https: %s returned HTTP %d", c.url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 0xffff+1))
+	if err != nil {
+		return fmt.Errorf("dns over https: %w", err)
+	}
+	if len(body) > 0xffff {
+		return errors.New("dns over https: answer too large")
+	}
+
+	answer := make([]byte, 2, 2+len(body))
+	binary.BigEndian.PutUint16(answer, uint16(len(body)))
+	c.answer = bytes.NewReader(append(answer, body...))
+	return nil
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr(c.url) }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr(c.url) }
+func (c *dohConn) SetDeadline(t time.Time) error      { return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// dohAddr is the address of a DoH endpoint
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return string(a) }
@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// statsWindow is how far back check rates and average durations look
+const statsWindow = time.Minute
+
+// PerformanceStats describe the health of the check loop
+type PerformanceStats struct {
+	ChecksPerMinute    int                `json:"checks_per_minute"`
+	AvgCheckDurationMs map[string]float64 `json:"avg_check_duration_ms"` // By source type, over the last minute
+	ActiveMonitors     int                `json:"active_monitors"`       // Per-source check goroutines
+	LastStorageWrite   *StorageWriteStat  `json:"last_storage_write,omitempty"`
+}
+
+// StorageWriteStat is the latency of the monitor's most recent database write
+type StorageWriteStat struct {
+	Operation string    `json:"operation"`
+	LatencyMs float64   `json:"latency_ms"`
+	At        time.Time `json:"at"`
+}
+
+// checkStats counts checks in one-second buckets covering statsWindow, so memory stays
+// constant however many sources are monitored
+type checkStats struct {
+	mu        sync.Mutex
+	buckets   [int(statsWindow / time.Second)]checkBucket
+	lastWrite *StorageWriteStat
+	lastCheck time.Time // When the most recent check finished
+}
+
+// checkBucket holds the checks finished within one second
+type checkBucket struct {
+	second int64
+	byType map[string]checkTotals
+}
+
+// checkTotals sums the checks of one source type
+type checkTotals struct {
+	count    int
+	duration time.Duration
+}
+
+// recordCheck counts a check of sourceType that took duration
+func (s *checkStats) recordCheck(sourceType string, duration time.Duration, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	second := now.Unix()
+	bucket := &s.buckets[second%int64(len(s.buckets))]
+	if bucket.second != second || bucket.byType == nil {
+		bucket.second = second
+		bucket.byType = make(map[string]checkTotals)
+	}
+	totals := bucket.byType[sourceType]
+	totals.count++
+	totals.duration += duration
+	bucket.byType[sourceType] = totals
+	s.lastCheck = now
+}
+
+// recordWrite remembers the latency of a storage write
+func (s *checkStats) recordWrite(operation string, latency time.Duration, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastWrite = &StorageWriteStat{
+		Operation: operation,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+		At:        now,
+	}
+}
+
+// snapshot sums the buckets of the last statsWindow
+func (s *checkStats) snapshot(now time.Time) PerformanceStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byType := make(map[string]checkTotals)
+	for _, bucket := range s.buckets {
+		if now.Unix()-bucket.second >= int64(len(s.buckets)) {
+			continue
+		}
+		for sourceType, totals := range bucket.byType {
+			sum := byType[sourceType]
+			sum.count += totals.count
+			sum.duration += totals.duration
+			byType[sourceType] = sum
+		}
+	}
+
+	stats := PerformanceStats{AvgCheckDurationMs: make(map[string]float64)}
+	for sourceType, totals := range byType {
+		stats.ChecksPerMinute += totals.count
+		stats.AvgCheckDurationMs[sourceType] = float64(totals.duration.Microseconds()) / 1000 / float64(totals.count)
+	}
+	if s.lastWrite != nil {
+		lastWrite := *s.lastWrite
+		stats.LastStorageWrite = &lastWrite
+	}
+	return stats
+}
+
+// LastCheck returns when the most recent check finished, or when the monitor was created if
+// none has yet
+func (m *Monitor) LastCheck() time.Time {
+	m.stats.mu.Lock()
+	defer m.stats.mu.Unlock()
+	if m.stats.lastCheck.IsZero() {
+		return m.createdAt
+	}
+	return m.stats.lastCheck
+}
+
+// ShortestInterval returns the smallest check interval of the actively monitored sources,
+// 0 when none are monitored
+func (m *Monitor) ShortestInterval() time.Duration {
+	m.monitorsMu.RLock()
+	defer m.monitorsMu.RUnlock()
+	m.sourcesMu.RLock()
+	defer m.sourcesMu.RUnlock()
+
+	var shortest time.Duration
+	for sourceID := range m.activeMonitors {
+		source, ok := m.sources[sourceID]
+		if !ok || source.CheckInterval <= 0 {
+			continue
+		}
+		if shortest == 0 || source.CheckInterval < shortest {
+			shortest = source.CheckInterval
+		}
+	}
+	return shortest
+}
+
+// Stats reports check throughput and durations over the last minute, the number of check
+// goroutines and the latency of the last storage write
+func (m *Monitor) Stats() PerformanceStats {
+	stats := m.stats.snapshot(time.Now())
+	stats.ActiveMonitors = m.ActiveMonitors()
+	return stats
+}
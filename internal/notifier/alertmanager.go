@@ -0,0 +1,149 @@
+package notifier
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// alertmanagerAlertName is the alertname label of every alert the bot sends
+const alertmanagerAlertName = "SourceDown"
+
+// AlertmanagerPayload is the body Prometheus Alertmanager posts to webhook receivers
+// (version 4), so receivers written for Alertmanager accept the bot's notifications as-is.
+// Each status change is a group with a single alert.
+type AlertmanagerPayload struct {
+	Version           string              `json:"version"`
+	GroupKey          string              `json:"groupKey"`
+	TruncatedAlerts   int                 `json:"truncatedAlerts"`
+	Status            string              `json:"status"` // firing or resolved
+	Receiver          string              `json:"receiver"`
+	GroupLabels       map[string]string   `json:"groupLabels"`
+	CommonLabels      map[string]string   `json:"commonLabels"`
+	CommonAnnotations map[string]string   `json:"commonAnnotations"`
+	ExternalURL       string              `json:"externalURL"`
+	Alerts            []AlertmanagerAlert `json:"alerts"`
+}
+
+// AlertmanagerAlert is one alert in an AlertmanagerPayload
+type AlertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"` // Zero while firing
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// buildAlertmanagerPayload describes a status change as an Alertmanager alert: firing when the
+// source went offline, resolved when it recovered. A recovery carries the outage's start, so
+// receivers can match it to the firing alert by fingerprint or by its labels.
+func buildAlertmanagerPayload(webhook *storage.Webhook, source *storage.Source, change *storage.StatusChange) AlertmanagerPayload {
+	labels := map[string]string{
+		"alertname":   alertmanagerAlertName,
+		"source":      source.Name,
+		"source_id":   source.ID,
+		"source_type": source.Type,
+		"target":      source.Target,
+		"severity":    "critical",
+	}
+	if source.Namespace != "" {
+		labels["namespace"] = source.Namespace
+	}
+	if len(source.Tags) > 0 {
+		labels["tags"] = strings.Join(source.Tags, ",")
+	}
+	if change.Test {
+		labels["test"] = "true"
+	}
+
+	alert := AlertmanagerAlert{
+		Status:      "firing",
+		Labels:      labels,
+		Annotations: map[string]string{"summary": fmt.Sprintf("%s is down", source.Name)},
+		StartsAt:    change.Timestamp.UTC(),
+		Fingerprint: alertmanagerFingerprint(labels),
+	}
+	if change.NewStatus != 0 {
+		alert.Status = "resolved"
+		alert.Annotations["summary"] = fmt.Sprintf("%s is back up", source.Name)
+		alert.StartsAt = change.Timestamp.Add(-time.Duration(change.DurationMs) * time.Millisecond).UTC()
+		alert.EndsAt = change.Timestamp.UTC()
+	} else if source.LastError != "" {
+		alert.Annotations["error"] = source.LastError
+	}
+	if message := source.NotificationMessage(change.NewStatus); message != "" {
+		alert.Annotations["description"] = message
+	}
+	if change.Hint != "" {
+		alert.Annotations["hint"] = change.Hint
+	}
+
+	receiver := webhook.Name
+	if receiver == "" {
+		receiver = webhook.ID
+	}
+	groupLabels := map[string]string{"alertname": alertmanagerAlertName, "source_id": source.ID}
+	return AlertmanagerPayload{
+		Version:           "4",
+		GroupKey:          fmt.Sprintf(`{}:{alertname=%q, source_id=%q}`, alertmanagerAlertName, source.ID),
+		Status:            alert.Status,
+		Receiver:          receiver,
+		GroupLabels:       groupLabels,
+		CommonLabels:      labels,
+		CommonAnnotations: alert.Annotations,
+		Alerts:            []AlertmanagerAlert{alert},
+	}
+}
+
+// alertmanagerFingerprint identifies an alert by its identifying labels, so the firing and
+// resolved notifications of an outage share a fingerprint
+func alertmanagerFingerprint(labels map[string]string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(labels["alertname"] + "\xff" + labels["source_id"]))
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+// buildAlertmanagerGroupPayload describes the changes of an alert group as one Alertmanager
+// group with an alert per change. Each alert is the one buildAlertmanagerPayload would send, so
+// fingerprints match the alerts of the same sources sent outside groups.
+func buildAlertmanagerGroupPayload(webhook *storage.Webhook, group *storage.AlertGroup, changes []GroupedChange, window time.Duration) AlertmanagerPayload {
+	payload := AlertmanagerPayload{
+		Version:           "4",
+		GroupKey:          fmt.Sprintf(`{}:{alertname=%q}`, alertmanagerAlertName),
+		Status:            "resolved",
+		GroupLabels:       map[string]string{"alertname": alertmanagerAlertName},
+		CommonAnnotations: map[string]string{},
+		Alerts:            make([]AlertmanagerAlert, 0, len(changes)),
+	}
+	for i, grouped := range changes {
+		single := buildAlertmanagerPayload(webhook, grouped.Source, grouped.Change)
+		payload.Receiver = single.Receiver
+		alert := single.Alerts[0]
+		if alert.Status == "firing" {
+			payload.Status = "firing"
+		}
+		payload.Alerts = append(payload.Alerts, alert)
+
+		// Common labels are the ones every alert has with the same value
+		if i == 0 {
+			payload.CommonLabels = make(map[string]string, len(alert.Labels))
+			for name, value := range alert.Labels {
+				payload.CommonLabels[name] = value
+			}
+			continue
+		}
+		for name, value := range payload.CommonLabels {
+			if alert.Labels[name] != value {
+				delete(payload.CommonLabels, name)
+			}
+		}
+	}
+	payload.CommonAnnotations["summary"] = GroupSummary(len(changes), group.NewStatus, window)
+	payload.CommonAnnotations["alert_group_id"] = group.ID
+	return payload
+}
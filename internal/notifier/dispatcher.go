@@ -0,0 +1,212 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tg-monitor-bot/internal/errreport"
+	"tg-monitor-bot/internal/logging"
+	"tg-monitor-bot/internal/storage"
+	"tg-monitor-bot/internal/tracing"
+)
+
+// Sender delivers status change notifications to the sinks of one type
+type Sender interface {
+	// Send notifies a single sink. The status code, such as the HTTP status of a webhook
+	// response, is recorded in the delivery log when not zero.
+	Send(ctx context.Context, sink *storage.Sink, source *storage.Source, change *storage.StatusChange) (int, error)
+}
+
+// Dispatcher is the delivery pipeline for status change notifications: it looks up the sinks
+// linked to a source, hands each one to the Sender registered for its type and records the
+// attempt in the delivery log
+type Dispatcher struct {
+	storage  *storage.BoltDB
+	logger   *logging.Logger
+	mu       sync.RWMutex
+	senders  map[string]Sender
+	pending  sync.WaitGroup // Deliveries in flight, see Wait
+	inFlight atomic.Int64   // Same count, readable for InFlight
+}
+
+// NewDispatcher creates a dispatcher without senders
+func NewDispatcher(db *storage.BoltDB) *Dispatcher {
+	return &Dispatcher{
+		storage: db,
+		logger:  logging.New("notifier"),
+		senders: make(map[string]Sender),
+	}
+}
+
+// SetSender registers the sender for a sink type. A nil sender removes it; sinks of a type
+// without a sender are skipped, as Telegram chats are while no bot is running.
+func (d *Dispatcher) SetSender(sinkType string, sender Sender) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if sender == nil {
+		delete(d.senders, sinkType)
+		return
+	}
+	d.senders[sinkType] = sender
+}
+
+// sender returns the sender registered for a sink type, or nil
+func (d *Dispatcher) sender(sinkType string) Sender {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.senders[sinkType]
+}
+
+// OnStatusChange implements the StatusChangeCallback interface. It notifies every enabled sink
+// of the source concurrently, each traced as a child of the span in ctx.
+func (d *Dispatcher) OnStatusChange(ctx context.Context, source *storage.Source, change *storage.StatusChange) {
+	sinks, err := d.storage.GetSourceSinks(source.ID)
+	if err != nil {
+		d.logger.Errorf("Failed to get sinks for source %s: %v", source.ID, err)
+		return
+	}
+	if change.NewStatus == 0 && !change.Test && change.Hint == "" {
+		change.Hint = d.rootCauseHint(source, change)
+	}
+
+	for _, sink := range sinks {
+		if !sink.Enabled {
+			continue
+		}
+		sender := d.sender(sink.Type)
+		if sender == nil {
+			d.logger.Debugf("No sender for %s, skipping sink %s", sink.Type, sink.ID)
+			continue
+		}
+
+		d.pending.Add(1)
+		d.inFlight.Add(1)
+		go func(sink *storage.Sink) {
+			defer d.pending.Done()
+			defer d.inFlight.Add(-1)
+			d.deliver(ctx, sender, sink, source, change)
+		}(sink)
+	}
+}
+
+// InFlight returns the number of deliveries started but not yet finished
+func (d *Dispatcher) InFlight() int64 {
+	return d.inFlight.Load()
+}
+
+// Wait blocks until all deliveries started by OnStatusChange have finished
+func (d *Dispatcher) Wait() {
+	d.pending.Wait()
+}
+
+// deliver notifies one sink and records the attempt in the delivery log
+func (d *Dispatcher) deliver(ctx context.Context, sender Sender, sink *storage.Sink, source *storage.Source, change *storage.StatusChange) {
+	ctx, span := tracing.StartKind(ctx, "notify."+sink.Type, tracing.KindClient,
+		tracing.String("source.id", source.ID), tracing.String("sink.id", sink.ID), tracing.String("sink.name", sink.Name))
+	defer span.End()
+
+	start := time.Now()
+	statusCode, err := sender.Send(ctx, sink, source, change)
+	if statusCode != 0 {
+		span.SetAttributes(tracing.Int("http.status_code", statusCode))
+	}
+	span.RecordError(err)
+
+	delivery := &storage.Delivery{
+		SinkType:       sink.Type,
+		SinkID:         sink.Key,
+		SinkName:       sink.Name,
+		SourceID:       source.ID,
+		SourceName:     source.Name,
+		StatusChangeID: change.ID,
+		OldStatus:      change.OldStatus,
+		NewStatus:      change.NewStatus,
+		Success:        err == nil,
+		LatencyMs:      time.Since(start).Milliseconds(),
+		StatusCode:     statusCode,
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+		d.logger.Errorf("Failed to notify %s about %s: %v", sink.ID, source.Name, err)
+		errreport.CaptureError(ctx, err, errreport.Tags{
+			"component": "notifier", "sink": sink.Type, "sink.id": sink.ID, "source.id": source.ID,
+		})
+	} else {
+		d.logger.Printf("Sent status change notification for %s to %s", source.Name, sink.ID)
+	}
+
+	if err := d.storage.SaveDelivery(delivery); err != nil {
+		d.logger.Errorf("Failed to record delivery to %s: %v", sink.ID, err)
+	}
+}
+
+// TestResult is the outcome of a test notification to one sink
+type TestResult struct {
+	SinkID     string `json:"sink_id"`
+	SinkType   string `json:"sink_type"`
+	SinkName   string `json:"sink_name"`
+	Success    bool   `json:"success"`
+	Skipped    string `json:"skipped,omitempty"` // Why nothing was sent, e.g. the sink is disabled
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Test sends a test outage notification for source to each of its sinks concurrently and
+// waits for the results, in the order of the sinks. Disabled sinks and types without a sender
+// are reported as skipped. Test deliveries are not recorded in the delivery log.
+func (d *Dispatcher) Test(ctx context.Context, source *storage.Source) ([]TestResult, error) {
+	sinks, err := d.storage.GetSourceSinks(source.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	change := &storage.StatusChange{
+		ID:         "test",
+		SourceID:   source.ID,
+		OldStatus:  1,
+		NewStatus:  0,
+		Timestamp:  now,
+		DurationMs: now.Sub(source.LastChangeTime).Milliseconds(),
+		Test:       true,
+	}
+	if source.LastChangeTime.IsZero() {
+		change.DurationMs = 0
+	}
+
+	results := make([]TestResult, len(sinks))
+	var wg sync.WaitGroup
+	for i, sink := range sinks {
+		results[i] = TestResult{SinkID: sink.ID, SinkType: sink.Type, SinkName: sink.Name}
+		if !sink.Enabled {
+			results[i].Skipped = "sink is disabled"
+			continue
+		}
+		sender := d.sender(sink.Type)
+		if sender == nil {
+			results[i].Skipped = fmt.Sprintf("no %s sender is running", sink.Type)
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *TestResult, sink *storage.Sink) {
+			defer wg.Done()
+			start := time.Now()
+			statusCode, err := sender.Send(ctx, sink, source, change)
+			result.Success = err == nil
+			result.StatusCode = statusCode
+			result.LatencyMs = time.Since(start).Milliseconds()
+			if err != nil {
+				result.Error = err.Error()
+			}
+		}(&results[i], sink)
+	}
+	wg.Wait()
+
+	d.logger.Printf("Sent test notifications for %s to %d sinks", source.Name, len(sinks))
+	return results, nil
+}
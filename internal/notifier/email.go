@@ -0,0 +1,112 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPSettings is the mail server email is sent through
+type SMTPSettings struct {
+	Host     string
+	Port     int
+	Username string // Empty sends without authentication
+	Password string
+	From     string
+}
+
+// Email is a message with plain text and HTML alternatives
+type Email struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string // Optional
+}
+
+// SendEmail delivers an email. The connection is upgraded with STARTTLS when the server
+// offers it; authentication is only attempted over TLS (or to localhost), as net/smtp requires.
+func SendEmail(settings SMTPSettings, email Email) error {
+	if settings.Host == "" || settings.From == "" {
+		return fmt.Errorf("SMTP_HOST and SMTP_FROM are required to send email")
+	}
+	from, err := mail.ParseAddress(settings.From)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_FROM: %w", err)
+	}
+
+	var auth smtp.Auth
+	if settings.Username != "" {
+		auth = smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)
+	}
+	addr := net.JoinHostPort(settings.Host, strconv.Itoa(settings.Port))
+	message, err := buildMessage(settings.From, email)
+	if err != nil {
+		return err
+	}
+	if err := smtp.SendMail(addr, auth, from.Address, email.To, message); err != nil {
+		return fmt.Errorf("failed to send email via %s: %w", addr, err)
+	}
+	return nil
+}
+
+// buildMessage renders email as a MIME message, multipart/alternative when it has HTML
+func buildMessage(from string, email Email) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", from)
+	for _, to := range email.To {
+		header("To", to)
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if email.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, email.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	boundary := "alt-" + hex.EncodeToString(random)
+	header("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	} {
+		fmt.Fprintf(&buf, "--%s\r\nContent-Type: %s\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n", boundary, part.contentType)
+		if err := writeQuotedPrintable(&buf, part.body); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable appends body encoded as quoted-printable
+func writeQuotedPrintable(buf *bytes.Buffer, body string) error {
+	w := quotedprintable.NewWriter(buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,256 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"tg-monitor-bot/internal/errreport"
+	"tg-monitor-bot/internal/logging"
+	"tg-monitor-bot/internal/storage"
+	"tg-monitor-bot/internal/tracing"
+)
+
+// GroupedChange is a status change in an alert group, with the source that changed
+type GroupedChange struct {
+	Source *storage.Source
+	Change *storage.StatusChange
+}
+
+// GroupSender is implemented by senders that can combine the changes of an alert group into
+// a single message. Sinks of other senders get one notification per change.
+type GroupSender interface {
+	// SendGroup notifies a single sink of the changes of group that concern it, which took
+	// place within window
+	SendGroup(ctx context.Context, sink *storage.Sink, group *storage.AlertGroup, changes []GroupedChange, window time.Duration) (int, error)
+}
+
+// GroupSummary describes an alert group in one line, e.g. "12 sources went OFFLINE in the last 60s"
+func GroupSummary(count, newStatus int, window time.Duration) string {
+	status := "OFFLINE"
+	if newStatus == 1 {
+		status = "back ONLINE"
+	}
+	last := fmt.Sprintf("%ds", int(window.Seconds()))
+	if window >= 2*time.Minute {
+		last = fmt.Sprintf("%dm", int(window.Minutes()))
+	}
+	return fmt.Sprintf("%d sources went %s in the last %s", count, status, last)
+}
+
+// pendingGroup collects the changes to one status while a grouping window is open
+type pendingGroup struct {
+	ctx     context.Context // Of the first change, without its cancellation
+	changes []GroupedChange
+	timer   *time.Timer
+}
+
+// Grouper holds status changes back for a window and notifies them through the dispatcher as
+// one alert group when at least minSources changed to the same status within it, e.g. a whole
+// subnet going down, instead of flooding the sinks with a message per source. Fewer changes
+// are notified one by one as usual when the window closes.
+type Grouper struct {
+	dispatcher *Dispatcher
+	storage    *storage.BoltDB
+	logger     *logging.Logger
+
+	mu         sync.Mutex
+	window     time.Duration // 0 disables grouping
+	minSources int
+	pending    map[int]*pendingGroup // By new status
+}
+
+// NewGrouper creates a grouper in front of dispatcher; grouping is off until Configure
+func NewGrouper(dispatcher *Dispatcher, db *storage.BoltDB) *Grouper {
+	return &Grouper{
+		dispatcher: dispatcher,
+		storage:    db,
+		logger:     logging.New("notifier"),
+		pending:    make(map[int]*pendingGroup),
+	}
+}
+
+// Configure sets the grouping window and how many changes it takes to group them. A window of
+// 0 disables grouping; changes already held back are still notified when their window closes.
+func (g *Grouper) Configure(window time.Duration, minSources int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.window = window
+	g.minSources = max(minSources, 2)
+}
+
+// OnStatusChange implements the StatusChangeCallback interface. Test changes and all changes
+// while grouping is disabled go to the dispatcher right away.
+func (g *Grouper) OnStatusChange(ctx context.Context, source *storage.Source, change *storage.StatusChange) {
+	g.mu.Lock()
+	if g.window <= 0 || change.Test {
+		g.mu.Unlock()
+		g.dispatcher.OnStatusChange(ctx, source, change)
+		return
+	}
+
+	p := g.pending[change.NewStatus]
+	if p == nil {
+		status := change.NewStatus
+		p = &pendingGroup{ctx: context.WithoutCancel(ctx)}
+		p.timer = time.AfterFunc(g.window, func() { g.flush(status) })
+		g.pending[status] = p
+	}
+	p.changes = append(p.changes, GroupedChange{Source: source, Change: change})
+	g.mu.Unlock()
+}
+
+// Flush notifies the changes held back right away, e.g. before the bot process stops
+func (g *Grouper) Flush() {
+	g.mu.Lock()
+	statuses := make([]int, 0, len(g.pending))
+	for status, p := range g.pending {
+		p.timer.Stop()
+		statuses = append(statuses, status)
+	}
+	g.mu.Unlock()
+
+	for _, status := range statuses {
+		g.flush(status)
+	}
+}
+
+// flush closes the window of changes to status and notifies them, grouped or one by one
+func (g *Grouper) flush(status int) {
+	g.mu.Lock()
+	p := g.pending[status]
+	delete(g.pending, status)
+	window, minSources := g.window, g.minSources
+	g.mu.Unlock()
+	if p == nil {
+		return
+	}
+
+	if len(p.changes) < minSources || window <= 0 {
+		g.notifyEach(p)
+		return
+	}
+
+	changes := make([]*storage.StatusChange, len(p.changes))
+	for i, grouped := range p.changes {
+		changes[i] = grouped.Change
+	}
+	group := &storage.AlertGroup{}
+	if err := g.storage.SaveAlertGroup(group, changes); err != nil {
+		g.logger.Errorf("Failed to save alert group, notifying %d changes one by one: %v", len(changes), err)
+		g.notifyEach(p)
+		return
+	}
+
+	g.logger.Printf("Grouped %d status changes into alert group %s", len(changes), group.ID)
+	g.dispatcher.OnGroup(p.ctx, group, p.changes, window)
+}
+
+// notifyEach notifies the changes of a window that didn't make a group
+func (g *Grouper) notifyEach(p *pendingGroup) {
+	for _, grouped := range p.changes {
+		g.dispatcher.OnStatusChange(p.ctx, grouped.Source, grouped.Change)
+	}
+}
+
+// OnGroup notifies every enabled sink linked to a source of the group once, about the changes
+// of its sources. Sinks concerned by a single change, or whose sender can't combine changes,
+// are notified of each change as by OnStatusChange. Every change is recorded in the delivery log.
+func (d *Dispatcher) OnGroup(ctx context.Context, group *storage.AlertGroup, changes []GroupedChange, window time.Duration) {
+	type sinkChanges struct {
+		sink    *storage.Sink
+		changes []GroupedChange
+	}
+	var order []string
+	bySink := make(map[string]*sinkChanges)
+	for _, grouped := range changes {
+		sinks, err := d.storage.GetSourceSinks(grouped.Source.ID)
+		if err != nil {
+			d.logger.Errorf("Failed to get sinks for source %s: %v", grouped.Source.ID, err)
+			continue
+		}
+		for _, sink := range sinks {
+			if !sink.Enabled {
+				continue
+			}
+			entry := bySink[sink.ID]
+			if entry == nil {
+				entry = &sinkChanges{sink: sink}
+				bySink[sink.ID] = entry
+				order = append(order, sink.ID)
+			}
+			entry.changes = append(entry.changes, grouped)
+		}
+	}
+
+	for _, id := range order {
+		entry := bySink[id]
+		sender := d.sender(entry.sink.Type)
+		if sender == nil {
+			d.logger.Debugf("No sender for %s, skipping sink %s", entry.sink.Type, entry.sink.ID)
+			continue
+		}
+		groupSender, ok := sender.(GroupSender)
+
+		d.pending.Add(1)
+		d.inFlight.Add(1)
+		go func(sink *storage.Sink, changes []GroupedChange) {
+			defer d.pending.Done()
+			defer d.inFlight.Add(-1)
+			if !ok || len(changes) == 1 {
+				for _, grouped := range changes {
+					d.deliver(ctx, sender, sink, grouped.Source, grouped.Change)
+				}
+				return
+			}
+			d.deliverGroup(ctx, groupSender, sink, group, changes, window)
+		}(entry.sink, entry.changes)
+	}
+}
+
+// deliverGroup sends the combined notification of an alert group to one sink and records the
+// attempt in the delivery log once per change
+func (d *Dispatcher) deliverGroup(ctx context.Context, sender GroupSender, sink *storage.Sink, group *storage.AlertGroup, changes []GroupedChange, window time.Duration) {
+	ctx, span := tracing.StartKind(ctx, "notify."+sink.Type, tracing.KindClient,
+		tracing.String("alert_group.id", group.ID), tracing.String("sink.id", sink.ID), tracing.String("sink.name", sink.Name))
+	defer span.End()
+
+	start := time.Now()
+	statusCode, err := sender.SendGroup(ctx, sink, group, changes, window)
+	latency := time.Since(start).Milliseconds()
+	if statusCode != 0 {
+		span.SetAttributes(tracing.Int("http.status_code", statusCode))
+	}
+	span.RecordError(err)
+	if err != nil {
+		d.logger.Errorf("Failed to notify %s about alert group %s: %v", sink.ID, group.ID, err)
+		errreport.CaptureError(ctx, err, errreport.Tags{
+			"component": "notifier", "sink": sink.Type, "sink.id": sink.ID, "alert_group.id": group.ID,
+		})
+	} else {
+		d.logger.Printf("Sent alert group notification for %d sources to %s", len(changes), sink.ID)
+	}
+
+	for _, grouped := range changes {
+		delivery := &storage.Delivery{
+			SinkType:       sink.Type,
+			SinkID:         sink.Key,
+			SinkName:       sink.Name,
+			SourceID:       grouped.Source.ID,
+			SourceName:     grouped.Source.Name,
+			StatusChangeID: grouped.Change.ID,
+			OldStatus:      grouped.Change.OldStatus,
+			NewStatus:      grouped.Change.NewStatus,
+			Success:        err == nil,
+			LatencyMs:      latency,
+			StatusCode:     statusCode,
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if err := d.storage.SaveDelivery(delivery); err != nil {
+			d.logger.Errorf("Failed to record delivery to %s: %v", sink.ID, err)
+		}
+	}
+}
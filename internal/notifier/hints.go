@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+// hintWindow is how long before an outage other failures count as related to it
+const hintWindow = 15 * time.Minute
+
+// maxHintSources caps the sources named in a hint
+const maxHintSources = 3
+
+// rootCauseHint points at the likely cause of an outage: the source's declared dependencies
+// that are down, e.g. "upstream 'Core Router' is also down, likely cause", or failing that
+// other sources on the same host or with a tag in common that went down shortly before and
+// are still down. Empty when there are none.
+func (d *Dispatcher) rootCauseHint(source *storage.Source, change *storage.StatusChange) string {
+	related, err := d.storage.FindRelatedOutages(source, change.Timestamp, hintWindow)
+	if err != nil {
+		d.logger.Warnf("Failed to find outages related to %s: %v", source.Name, err)
+		return ""
+	}
+	return formatRootCauseHint(related)
+}
+
+// formatRootCauseHint describes related outages in one sentence. Upstream sources, which come
+// first, are named alone when there are any.
+func formatRootCauseHint(related []storage.RelatedOutage) string {
+	if len(related) == 0 {
+		return ""
+	}
+	upstream := 0
+	for upstream < len(related) && related[upstream].Reason == "upstream" {
+		upstream++
+	}
+	if upstream > 0 {
+		related = related[:upstream]
+	}
+
+	names := make([]string, 0, maxHintSources+1)
+	for i, outage := range related {
+		if i == maxHintSources {
+			names = append(names, fmt.Sprintf("%d more", len(related)-i))
+			break
+		}
+		if upstream > 0 {
+			names = append(names, fmt.Sprintf("'%s'", outage.Source.Name))
+		} else {
+			names = append(names, fmt.Sprintf("'%s' (%s)", outage.Source.Name, outage.Reason))
+		}
+	}
+
+	verb := "is"
+	if len(related) > 1 {
+		verb = "are"
+	}
+	list := names[0]
+	if len(names) > 1 {
+		list = strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+	}
+	if upstream > 0 {
+		return fmt.Sprintf("upstream %s %s also down, likely cause", list, verb)
+	}
+	return fmt.Sprintf("%s %s also down, likely a shared cause", list, verb)
+}
@@ -0,0 +1,104 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"tg-monitor-bot/internal/storage"
+)
+
+func TestFormatRootCauseHint(t *testing.T) {
+	outage := func(name, reason string) storage.RelatedOutage {
+		return storage.RelatedOutage{Source: &storage.Source{Name: name}, Reason: reason}
+	}
+	tests := []struct {
+		related []storage.RelatedOutage
+		want    string
+	}{
+		{related: nil, want: ""},
+		{
+			related: []storage.RelatedOutage{outage("Core Router", "upstream")},
+			want:    "upstream 'Core Router' is also down, likely cause",
+		},
+		{
+			// Siblings aren't named next to a down dependency
+			related: []storage.RelatedOutage{outage("Core Router", "upstream"), outage("Switch", "upstream"), outage("SSH", "same host")},
+			want:    "upstream 'Core Router' and 'Switch' are also down, likely cause",
+		},
+		{
+			related: []storage.RelatedOutage{outage("SSH", "same host"), outage("Printer", "tag office")},
+			want:    "'SSH' (same host) and 'Printer' (tag office) are also down, likely a shared cause",
+		},
+		{
+			related: []storage.RelatedOutage{outage("A", "tag x"), outage("B", "tag x"), outage("C", "tag x"), outage("D", "tag x"), outage("E", "tag x")},
+			want:    "'A' (tag x), 'B' (tag x), 'C' (tag x) and 2 more are also down, likely a shared cause",
+		},
+	}
+	for _, tt := range tests {
+		if got := formatRootCauseHint(tt.related); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestDispatcherRootCauseHint(t *testing.T) {
+	db, err := storage.NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	var mu sync.Mutex
+	var payloads []WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		payloads = append(payloads, payload)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	router := &storage.Source{ID: "router", Name: "Core Router", Type: "ping", Target: "10.0.0.1", Enabled: true, CurrentStatus: 0, LastChangeTime: now.Add(-time.Hour)}
+	app := &storage.Source{ID: "app", Name: "App", Type: "http", Target: "https://app.example.com", DependsOn: []string{"router"}, Enabled: true, CurrentStatus: 0, LastChangeTime: now}
+	webhook := &storage.Webhook{Name: "Ops", URL: server.URL, Method: http.MethodPost, Enabled: true}
+	for _, source := range []*storage.Source{router, app} {
+		if err := db.SaveSource(source); err != nil {
+			t.Fatalf("SaveSource failed: %v", err)
+		}
+	}
+	if err := db.SaveWebhook(webhook); err != nil {
+		t.Fatalf("SaveWebhook failed: %v", err)
+	}
+	if err := db.AddSourceSink(app.ID, storage.WebhookSinkID(webhook.ID)); err != nil {
+		t.Fatalf("AddSourceSink failed: %v", err)
+	}
+
+	d := NewDispatcher(db)
+	d.SetSender(storage.SinkTypeWebhook, NewWebhookNotifier(db))
+	d.OnStatusChange(context.Background(), app, &storage.StatusChange{SourceID: app.ID, OldStatus: 1, NewStatus: 0, Timestamp: now})
+	d.OnStatusChange(context.Background(), app, &storage.StatusChange{SourceID: app.ID, OldStatus: 0, NewStatus: 1, Timestamp: now.Add(time.Minute)})
+	d.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(payloads) != 2 {
+		t.Fatalf("Expected two notifications, got %d", len(payloads))
+	}
+	for _, payload := range payloads {
+		want := ""
+		if payload.StatusChange.NewStatus == 0 {
+			want = "upstream 'Core Router' is also down, likely cause"
+		}
+		if payload.StatusChange.Hint != want {
+			t.Errorf("Expected hint %q for new status %d, got %q", want, payload.StatusChange.NewStatus, payload.StatusChange.Hint)
+		}
+	}
+}
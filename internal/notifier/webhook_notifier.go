@@ -2,13 +2,16 @@ package notifier
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"time"
 
+	"tg-monitor-bot/internal/logging"
 	"tg-monitor-bot/internal/storage"
+	"tg-monitor-bot/internal/tracing"
 )
 
 // WebhookPayload represents the payload sent to webhooks
@@ -16,6 +19,7 @@ type WebhookPayload struct {
 	Source     *SourceData     `json:"source"`
 	StatusChange *StatusChangeData `json:"status_change"`
 	Timestamp  string          `json:"timestamp"`
+	Test       bool            `json:"test,omitempty"` // Sent by a notification test, not a real status change
 }
 
 // SourceData represents source information in webhook payload
@@ -36,12 +40,45 @@ type StatusChangeData struct {
 	NewStatus  int    `json:"new_status"`
 	DurationMs int64  `json:"duration_ms"`
 	Timestamp  string `json:"timestamp"`
+	Message    string `json:"message,omitempty"` // The source's outage or recovery message
+	Hint       string `json:"hint,omitempty"`    // Likely cause of an outage, e.g. related sources also down
 }
 
-// WebhookNotifier sends webhooks on status changes
+// WebhookGroupPayload is sent instead of a WebhookPayload per source when correlated status
+// changes were grouped into one alert
+type WebhookGroupPayload struct {
+	AlertGroup *AlertGroupData  `json:"alert_group"`
+	Changes    []WebhookPayload `json:"changes"` // One per source of the group the webhook is linked to
+	Timestamp  string           `json:"timestamp"`
+}
+
+// AlertGroupData represents an alert group in webhook payloads
+type AlertGroupData struct {
+	ID            string `json:"id"`
+	NewStatus     int    `json:"new_status"`
+	Count         int    `json:"count"` // Changes in this payload
+	WindowSeconds int    `json:"window_seconds"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	Summary       string `json:"summary"` // e.g. "12 sources went OFFLINE in the last 60s"
+}
+
+// maxResponseExcerpt caps how much of a webhook response body is kept for diagnostics
+const maxResponseExcerpt = 1024
+
+// DeliveryResult is the outcome of a single webhook request
+type DeliveryResult struct {
+	Success      bool   `json:"success"`
+	StatusCode   int    `json:"status_code,omitempty"`
+	LatencyMs    int64  `json:"latency_ms"`
+	ResponseBody string `json:"response_body,omitempty"` // First 1 KiB of the response
+	Error        string `json:"error,omitempty"`
+}
+
+// WebhookNotifier sends status changes to webhook sinks
 type WebhookNotifier struct {
 	storage *storage.BoltDB
-	logger  *log.Logger
+	logger  *logging.Logger
 	client  *http.Client
 }
 
@@ -49,56 +86,86 @@ type WebhookNotifier struct {
 func NewWebhookNotifier(db *storage.BoltDB) *WebhookNotifier {
 	return &WebhookNotifier{
 		storage: db,
-		logger:  log.New(log.Writer(), "[WEBHOOK_NOTIFIER] ", log.LstdFlags),
+		logger:  logging.New("webhook_notifier"),
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
 	}
 }
 
-// OnStatusChange implements the StatusChangeCallback interface
-// It sends webhooks to all configured webhooks for the source
-func (wn *WebhookNotifier) OnStatusChange(source *storage.Source, change *storage.StatusChange) {
-	// Get webhooks for this source
-	webhooks, err := wn.storage.GetSourceWebhooks(source.ID)
+// Send implements Sender: it posts the payload for a status change to the webhook of a sink
+// and returns the HTTP status of the response. The request carries a traceparent header for
+// the span in ctx.
+func (wn *WebhookNotifier) Send(ctx context.Context, sink *storage.Sink, source *storage.Source, change *storage.StatusChange) (int, error) {
+	webhook, err := wn.storage.GetWebhook(sink.Key)
 	if err != nil {
-		wn.logger.Printf("Failed to get webhooks for source %s: %v", source.ID, err)
-		return
+		return 0, err
 	}
 
-	if len(webhooks) == 0 {
-		return // No webhooks configured for this source
+	wn.logger.Debugf("Sending webhook to %s for source %s (status: %d→%d)",
+		webhook.URL, source.Name, change.OldStatus, change.NewStatus)
+	statusCode, _, err := wn.sendWebhook(ctx, webhook, wn.payloadFor(webhook, source, change))
+	if err == nil {
+		wn.storage.UpdateWebhookLastTriggered(webhook.ID)
 	}
+	return statusCode, err
+}
 
-	// Build payload
-	payload := wn.buildPayload(source, change)
+// SendGroup implements GroupSender: it posts one payload for the changes of an alert group to
+// the webhook of a sink, in the webhook's format
+func (wn *WebhookNotifier) SendGroup(ctx context.Context, sink *storage.Sink, group *storage.AlertGroup, changes []GroupedChange, window time.Duration) (int, error) {
+	webhook, err := wn.storage.GetWebhook(sink.Key)
+	if err != nil {
+		return 0, err
+	}
 
-	// Send to each webhook
-	for _, webhook := range webhooks {
-		if !webhook.Enabled {
-			continue // Skip disabled webhooks
-		}
+	var payload interface{}
+	if webhook.Format == storage.WebhookFormatAlertmanager {
+		payload = buildAlertmanagerGroupPayload(webhook, group, changes, window)
+	} else {
+		payload = wn.buildGroupPayload(group, changes, window)
+	}
 
-		wn.logger.Printf("Sending webhook to %s for source %s (status: %d→%d)",
-			webhook.URL, source.Name, change.OldStatus, change.NewStatus)
+	wn.logger.Debugf("Sending alert group %s with %d changes to %s", group.ID, len(changes), webhook.URL)
+	statusCode, _, err := wn.sendWebhook(ctx, webhook, payload)
+	if err == nil {
+		wn.storage.UpdateWebhookLastTriggered(webhook.ID)
+	}
+	return statusCode, err
+}
 
-		go wn.sendWebhook(webhook, payload)
+// SendTest delivers a payload for source and change to a single webhook synchronously.
+// Test deliveries are not recorded in the delivery log.
+func (wn *WebhookNotifier) SendTest(webhook *storage.Webhook, source *storage.Source, change *storage.StatusChange) *DeliveryResult {
+	start := time.Now()
+	statusCode, body, err := wn.sendWebhook(context.Background(), webhook, wn.payloadFor(webhook, source, change))
+
+	result := &DeliveryResult{
+		Success:      err == nil,
+		StatusCode:   statusCode,
+		LatencyMs:    time.Since(start).Milliseconds(),
+		ResponseBody: body,
+	}
+	if err != nil {
+		result.Error = err.Error()
 	}
+	return result
 }
 
-// sendWebhook sends a single webhook request
-func (wn *WebhookNotifier) sendWebhook(webhook *storage.Webhook, payload WebhookPayload) {
+// sendWebhook sends a single webhook request and returns the HTTP status code and
+// the start of the response body. The request carries a traceparent header for the span in ctx.
+func (wn *WebhookNotifier) sendWebhook(ctx context.Context, webhook *storage.Webhook, payload interface{}) (int, string, error) {
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		wn.logger.Printf("Failed to marshal webhook payload: %v", err)
-		return
+		wn.logger.Errorf("Failed to marshal webhook payload: %v", err)
+		return 0, "", fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
 	// Create request
-	req, err := http.NewRequest(webhook.Method, webhook.URL, bytes.NewReader(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, webhook.Method, webhook.URL, bytes.NewReader(payloadBytes))
 	if err != nil {
-		wn.logger.Printf("Failed to create webhook request: %v", err)
-		return
+		wn.logger.Errorf("Failed to create webhook request: %v", err)
+		return 0, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set default content type
@@ -108,26 +175,35 @@ func (wn *WebhookNotifier) sendWebhook(webhook *storage.Webhook, payload Webhook
 	for key, value := range webhook.Headers {
 		req.Header.Set(key, value)
 	}
+	tracing.Inject(ctx, req.Header)
 
 	// Send request
 	resp, err := wn.client.Do(req)
 	if err != nil {
-		wn.logger.Printf("Failed to send webhook to %s: %v", webhook.URL, err)
-		return
+		wn.logger.Errorf("Failed to send webhook to %s: %v", webhook.URL, err)
+		return 0, "", err
 	}
 	defer resp.Body.Close()
 
-	// Read response body (for debugging/logging)
-	body, _ := io.ReadAll(resp.Body)
+	// Keep the start of the response body for debugging/logging
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseExcerpt))
 
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		wn.logger.Printf("Webhook sent successfully to %s (status: %d)", webhook.URL, resp.StatusCode)
-		// Update last triggered timestamp
-		wn.storage.UpdateWebhookLastTriggered(webhook.ID)
-	} else {
-		wn.logger.Printf("Webhook request failed for %s (status: %d, body: %s)",
-			webhook.URL, resp.StatusCode, string(body))
+		wn.logger.Debugf("Webhook sent successfully to %s (status: %d)", webhook.URL, resp.StatusCode)
+		return resp.StatusCode, string(body), nil
 	}
+
+	wn.logger.Warnf("Webhook request failed for %s (status: %d, body: %s)",
+		webhook.URL, resp.StatusCode, string(body))
+	return resp.StatusCode, string(body), fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// payloadFor builds the payload in the webhook's format
+func (wn *WebhookNotifier) payloadFor(webhook *storage.Webhook, source *storage.Source, change *storage.StatusChange) interface{} {
+	if webhook.Format == storage.WebhookFormatAlertmanager {
+		return buildAlertmanagerPayload(webhook, source, change)
+	}
+	return wn.buildPayload(source, change)
 }
 
 // buildPayload creates a webhook payload from source and status change
@@ -148,7 +224,31 @@ func (wn *WebhookNotifier) buildPayload(source *storage.Source, change *storage.
 			NewStatus:  change.NewStatus,
 			DurationMs: change.DurationMs,
 			Timestamp:  change.Timestamp.Format(time.RFC3339),
+			Message:    source.NotificationMessage(change.NewStatus),
+			Hint:       change.Hint,
 		},
 		Timestamp: time.Now().Format(time.RFC3339),
+		Test:      change.Test,
+	}
+}
+
+// buildGroupPayload creates a webhook payload for the changes of an alert group
+func (wn *WebhookNotifier) buildGroupPayload(group *storage.AlertGroup, changes []GroupedChange, window time.Duration) WebhookGroupPayload {
+	payload := WebhookGroupPayload{
+		AlertGroup: &AlertGroupData{
+			ID:            group.ID,
+			NewStatus:     group.NewStatus,
+			Count:         len(changes),
+			WindowSeconds: int(window.Seconds()),
+			Start:         group.Start.Format(time.RFC3339),
+			End:           group.End.Format(time.RFC3339),
+			Summary:       GroupSummary(len(changes), group.NewStatus, window),
+		},
+		Changes:   make([]WebhookPayload, 0, len(changes)),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	for _, grouped := range changes {
+		payload.Changes = append(payload.Changes, wn.buildPayload(grouped.Source, grouped.Change))
 	}
+	return payload
 }
@@ -0,0 +1,21 @@
+package storage
+
+import "fmt"
+
+// Address families a ping or http source can be restricted to. Empty means the default:
+// pings prefer IPv4, http dials whatever the resolver returns first, falling back to the
+// other family.
+const (
+	AddressFamilyIPv4       = "ipv4"        // IPv4 only, ignoring AAAA records
+	AddressFamilyIPv6       = "ipv6"        // IPv6 only, ignoring A records
+	AddressFamilyPreferIPv6 = "prefer-ipv6" // IPv6 when the host has an AAAA record, else IPv4
+)
+
+// ValidateAddressFamily checks an address family, allowing empty for the default
+func ValidateAddressFamily(family string) error {
+	switch family {
+	case "", AddressFamilyIPv4, AddressFamilyIPv6, AddressFamilyPreferIPv6:
+		return nil
+	}
+	return fmt.Errorf("address_family must be %s, %s or %s", AddressFamilyIPv4, AddressFamilyIPv6, AddressFamilyPreferIPv6)
+}
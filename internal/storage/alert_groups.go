@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// AlertGroup is a set of correlated status changes, e.g. a whole subnet going down, that were
+// notified as one combined alert instead of one message per source
+type AlertGroup struct {
+	ID        string    `msgpack:"id" json:"id"`
+	NewStatus int       `msgpack:"new_status" json:"new_status"` // Status every source changed to
+	Start     time.Time `msgpack:"start" json:"start"`           // First change of the group
+	End       time.Time `msgpack:"end" json:"end"`               // Last change of the group
+	SourceIDs []string  `msgpack:"source_ids" json:"source_ids"` // In the order of their changes
+	CreatedAt time.Time `msgpack:"created_at" json:"created_at"`
+}
+
+// SaveAlertGroup records changes as one alert group, setting the ID, range and sources of the
+// group and the GroupID of every change
+func (b *BoltDB) SaveAlertGroup(group *AlertGroup, changes []*StatusChange) error {
+	if len(changes) == 0 {
+		return fmt.Errorf("alert group has no status changes")
+	}
+
+	group.ID = uuid.New().String()
+	group.NewStatus = changes[0].NewStatus
+	group.Start = changes[0].Timestamp
+	group.End = changes[0].Timestamp
+	group.SourceIDs = make([]string, 0, len(changes))
+	for _, change := range changes {
+		if change.Timestamp.Before(group.Start) {
+			group.Start = change.Timestamp
+		}
+		if change.Timestamp.After(group.End) {
+			group.End = change.Timestamp
+		}
+		group.SourceIDs = append(group.SourceIDs, change.SourceID)
+	}
+	if group.CreatedAt.IsZero() {
+		group.CreatedAt = time.Now()
+	}
+
+	data, err := msgpack.Marshal(group)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert group: %w", err)
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		groups := tx.Bucket([]byte(alertGroupsBucket))
+		statusChanges := tx.Bucket([]byte(statusChangesBucket))
+		if groups == nil || statusChanges == nil {
+			return fmt.Errorf("alert groups bucket not found")
+		}
+
+		for _, change := range changes {
+			change.GroupID = group.ID
+			data, err := msgpack.Marshal(change)
+			if err != nil {
+				return fmt.Errorf("failed to marshal status change: %w", err)
+			}
+			if err := statusChanges.Put(makeStatusChangeKey(change.SourceID, change.Timestamp), data); err != nil {
+				return fmt.Errorf("failed to save status change: %w", err)
+			}
+		}
+		if err := groups.Put([]byte(group.ID), data); err != nil {
+			return fmt.Errorf("failed to save alert group: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetAlertGroup returns an alert group by ID
+func (b *BoltDB) GetAlertGroup(id string) (*AlertGroup, error) {
+	var group AlertGroup
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(alertGroupsBucket))
+		if bucket == nil {
+			return fmt.Errorf("alert groups bucket not found")
+		}
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("alert group not found: %s", id)
+		}
+		return msgpack.Unmarshal(data, &group)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// GetAlertGroupChanges returns the status changes of an alert group, newest first
+func (b *BoltDB) GetAlertGroupChanges(group *AlertGroup) ([]*StatusChange, error) {
+	changes := []*StatusChange{}
+	seen := make(map[string]bool)
+	for _, sourceID := range group.SourceIDs {
+		if seen[sourceID] {
+			continue // A source that flapped within the window is listed once per change
+		}
+		seen[sourceID] = true
+
+		sourceChanges, err := b.GetStatusChanges(sourceID, group.Start, group.End.Add(time.Nanosecond), len(group.SourceIDs)*2)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range sourceChanges {
+			if change.GroupID == group.ID {
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Timestamp.After(changes[j].Timestamp)
+	})
+	return changes, nil
+}
+
+// deleteOldAlertGroups removes alert groups whose last change is before cutoff
+func deleteOldAlertGroups(tx *bolt.Tx, cutoff time.Time) error {
+	bucket := tx.Bucket([]byte(alertGroupsBucket))
+	if bucket == nil {
+		return nil
+	}
+
+	var stale [][]byte
+	err := bucket.ForEach(func(k, v []byte) error {
+		var group AlertGroup
+		if err := msgpack.Unmarshal(v, &group); err == nil && group.End.Before(cutoff) {
+			stale = append(stale, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range stale {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAlertGroups(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var changes []*StatusChange
+	for i, id := range []string{"a", "b", "c"} {
+		change := &StatusChange{SourceID: id, OldStatus: 1, NewStatus: 0, Timestamp: start.Add(time.Duration(i) * 10 * time.Second)}
+		if err := db.SaveStatusChange(change); err != nil {
+			t.Fatalf("SaveStatusChange failed: %v", err)
+		}
+		changes = append(changes, change)
+	}
+	// Not part of the group
+	db.SaveStatusChange(&StatusChange{SourceID: "a", OldStatus: 0, NewStatus: 1, Timestamp: start.Add(5 * time.Second)})
+
+	group := &AlertGroup{}
+	if err := db.SaveAlertGroup(group, changes); err != nil {
+		t.Fatalf("SaveAlertGroup failed: %v", err)
+	}
+	if group.ID == "" || !group.Start.Equal(start) || !group.End.Equal(start.Add(20*time.Second)) || len(group.SourceIDs) != 3 {
+		t.Errorf("Expected the group's range and sources set, got %+v", group)
+	}
+
+	stored, err := db.GetAlertGroup(group.ID)
+	if err != nil || stored.NewStatus != 0 {
+		t.Fatalf("Expected the stored group, got %+v (%v)", stored, err)
+	}
+	grouped, err := db.GetAlertGroupChanges(stored)
+	if err != nil || len(grouped) != 3 {
+		t.Fatalf("Expected the 3 grouped changes, got %d (%v)", len(grouped), err)
+	}
+	if grouped[0].SourceID != "c" || grouped[0].GroupID != group.ID {
+		t.Errorf("Expected the newest change first with its group ID, got %+v", grouped[0])
+	}
+
+	if _, err := db.DeleteOldStatusChanges(time.Minute); err != nil {
+		t.Fatalf("DeleteOldStatusChanges failed: %v", err)
+	}
+	if _, err := db.GetAlertGroup(group.ID); err == nil {
+		t.Error("Expected the group removed with its changes")
+	}
+}
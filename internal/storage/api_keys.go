@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// API key scopes, or roles; each scope includes the ones before it
+const (
+	ScopeRead     = "read"     // Read-only: GET requests, except config and admin endpoints
+	ScopeOperator = "operator" // Also checks, pauses and resumes sources, maintenance windows and test notifications
+	ScopeWrite    = "write"    // Everything except config, key management and admin endpoints
+	ScopeAdmin    = "admin"    // Full access, including config and /keys
+)
+
+// apiKeySecretPrefix makes generated keys recognizable in configs and leak scanners
+const apiKeySecretPrefix = "omk_"
+
+// hashedConfigKeyPrefix marks an API_KEY config value stored as a SHA-256 hash
+const hashedConfigKeyPrefix = "sha256:"
+
+var scopeRanks = map[string]int{
+	ScopeRead:     1,
+	ScopeOperator: 2,
+	ScopeWrite:    3,
+	ScopeAdmin:    4,
+}
+
+// APIKey is a named API credential. Only a SHA-256 hash of the secret is stored;
+// the secret itself is returned once, when the key is created.
+type APIKey struct {
+	ID         string     `msgpack:"id" json:"id"`
+	Name       string     `msgpack:"name" json:"name"`
+	Scope      string     `msgpack:"scope" json:"scope"`
+	Hash       string     `msgpack:"hash" json:"-"`
+	Prefix     string     `msgpack:"prefix" json:"prefix"` // First characters of the secret, to tell keys apart
+	CreatedAt  time.Time  `msgpack:"created_at" json:"created_at"`
+	ExpiresAt  *time.Time `msgpack:"expires_at" json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `msgpack:"revoked_at" json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `msgpack:"last_used_at" json:"last_used_at,omitempty"`
+	Namespace  string     `msgpack:"namespace" json:"namespace,omitempty"` // Limits the key to one namespace; empty = all
+}
+
+// ValidScope reports whether scope is one of read, operator, write or admin
+func ValidScope(scope string) bool {
+	_, ok := scopeRanks[scope]
+	return ok
+}
+
+// ScopeAllows reports whether a credential with scope may perform an action requiring required
+func ScopeAllows(scope, required string) bool {
+	return scopeRanks[scope] >= scopeRanks[required] && scopeRanks[scope] > 0
+}
+
+// Active reports whether the key is neither revoked nor expired at now
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// HashAPIKeySecret returns the hex SHA-256 of a key secret. Secrets are random, so an
+// unsalted fast hash is sufficient.
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashConfigAPIKey returns the at-rest form of an API_KEY config value ("sha256:<hex>").
+// Values that are already hashed are returned unchanged.
+func HashConfigAPIKey(value string) string {
+	if value == "" || strings.HasPrefix(value, hashedConfigKeyPrefix) {
+		return value
+	}
+	return hashedConfigKeyPrefix + HashAPIKeySecret(value)
+}
+
+// ConfigAPIKeyHash returns the hex hash that presented keys are compared against,
+// for an API_KEY value in either plain or hashed form
+func ConfigAPIKeyHash(value string) string {
+	if hash, ok := strings.CutPrefix(value, hashedConfigKeyPrefix); ok {
+		return strings.ToLower(hash)
+	}
+	return HashAPIKeySecret(value)
+}
+
+// GenerateAPIKeySecret returns a new random key secret with 192 bits of entropy,
+// used for named keys and by `genkey` for API_KEY
+func GenerateAPIKeySecret() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return apiKeySecretPrefix + hex.EncodeToString(raw), nil
+}
+
+// CreateAPIKey generates a new key and returns it together with its secret. A non-empty
+// namespace limits the key to that namespace, which admin keys can't be.
+func (b *BoltDB) CreateAPIKey(name, scope, namespace string, expiresAt *time.Time) (*APIKey, string, error) {
+	if !ValidScope(scope) {
+		return nil, "", fmt.Errorf("invalid scope %q", scope)
+	}
+	if namespace != "" && scope == ScopeAdmin {
+		return nil, "", fmt.Errorf("namespaced keys can't have the admin scope")
+	}
+
+	secret, err := GenerateAPIKeySecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &APIKey{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Scope:     scope,
+		Hash:      HashAPIKeySecret(secret),
+		Prefix:    secret[:len(apiKeySecretPrefix)+6],
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+		Namespace: namespace,
+	}
+	if err := b.putAPIKey(key); err != nil {
+		return nil, "", err
+	}
+
+	b.logger.Printf("Created API key: %s (%s)", key.Name, key.Scope)
+	return key, secret, nil
+}
+
+// putAPIKey writes a key as-is
+func (b *BoltDB) putAPIKey(key *APIKey) error {
+	data, err := msgpack.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal API key: %w", err)
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiKeysBucket))
+		if bucket == nil {
+			return fmt.Errorf("api_keys bucket not found")
+		}
+
+		if err := bucket.Put([]byte(key.ID), data); err != nil {
+			return fmt.Errorf("failed to save API key: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetAPIKey retrieves a key by ID
+func (b *BoltDB) GetAPIKey(id string) (*APIKey, error) {
+	var key APIKey
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiKeysBucket))
+		if bucket == nil {
+			return fmt.Errorf("api_keys bucket not found")
+		}
+
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("API key not found")
+		}
+		return msgpack.Unmarshal(data, &key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys returns all keys, including revoked and expired ones, oldest first
+func (b *BoltDB) ListAPIKeys() ([]*APIKey, error) {
+	keys := []*APIKey{}
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiKeysBucket))
+		if bucket == nil {
+			return fmt.Errorf("api_keys bucket not found")
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			var key APIKey
+			if err := msgpack.Unmarshal(v, &key); err != nil {
+				b.logger.Errorf("Failed to unmarshal API key: %v", err)
+				return nil // Skip malformed keys
+			}
+			keys = append(keys, &key)
+			return nil
+		})
+	})
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, err
+}
+
+// FindAPIKey returns the key whose secret matches, or nil. Hashes are compared in
+// constant time; revoked and expired keys are returned too so callers can say why.
+func (b *BoltDB) FindAPIKey(secret string) (*APIKey, error) {
+	hash := []byte(HashAPIKeySecret(secret))
+
+	keys, err := b.ListAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *APIKey
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare(hash, []byte(key.Hash)) == 1 {
+			found = key
+		}
+	}
+	return found, nil
+}
+
+// RevokeAPIKey marks a key as revoked; it is kept for auditing until deleted
+func (b *BoltDB) RevokeAPIKey(id string) (*APIKey, error) {
+	key, err := b.GetAPIKey(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.RevokedAt == nil {
+		now := time.Now()
+		key.RevokedAt = &now
+		if err := b.putAPIKey(key); err != nil {
+			return nil, err
+		}
+		b.logger.Printf("Revoked API key: %s", key.Name)
+	}
+	return key, nil
+}
+
+// TouchAPIKey records when a key was last used
+func (b *BoltDB) TouchAPIKey(id string, usedAt time.Time) error {
+	key, err := b.GetAPIKey(id)
+	if err != nil {
+		return err
+	}
+
+	key.LastUsedAt = &usedAt
+	return b.putAPIKey(key)
+}
+
+// DeleteAPIKey removes a key permanently
+func (b *BoltDB) DeleteAPIKey(id string) error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiKeysBucket))
+		if bucket == nil {
+			return fmt.Errorf("api_keys bucket not found")
+		}
+
+		if bucket.Get([]byte(id)) == nil {
+			return fmt.Errorf("API key not found")
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
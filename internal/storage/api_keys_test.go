@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	if _, _, err := db.CreateAPIKey("bad", "superuser", "", nil); err == nil {
+		t.Error("Expected error for unknown scope")
+	}
+
+	key, secret, err := db.CreateAPIKey("ci", ScopeWrite, "", nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if !strings.HasPrefix(secret, key.Prefix) || key.Hash == secret || key.Hash != HashAPIKeySecret(secret) {
+		t.Errorf("Expected hashed secret with matching prefix, got %+v", key)
+	}
+
+	found, err := db.FindAPIKey(secret)
+	if err != nil || found == nil || found.ID != key.ID {
+		t.Fatalf("Expected FindAPIKey to return the key, got %v, %v", found, err)
+	}
+	if found, _ := db.FindAPIKey(secret + "x"); found != nil {
+		t.Error("Expected no key for a wrong secret")
+	}
+
+	now := time.Now()
+	if !found.Active(now) {
+		t.Error("Expected new key to be active")
+	}
+	expired := now.Add(-time.Minute)
+	expiredKey, _, err := db.CreateAPIKey("old", ScopeRead, "", &expired)
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if expiredKey.Active(now) {
+		t.Error("Expected expired key to be inactive")
+	}
+
+	revoked, err := db.RevokeAPIKey(key.ID)
+	if err != nil {
+		t.Fatalf("RevokeAPIKey failed: %v", err)
+	}
+	if revoked.Active(now) {
+		t.Error("Expected revoked key to be inactive")
+	}
+
+	keys, err := db.ListAPIKeys()
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d (%v)", len(keys), err)
+	}
+	if err := db.DeleteAPIKey(key.ID); err != nil {
+		t.Fatalf("DeleteAPIKey failed: %v", err)
+	}
+	if err := db.DeleteAPIKey(key.ID); err == nil {
+		t.Error("Expected error deleting a missing key")
+	}
+
+	scopes := []struct {
+		scope, required string
+		allowed         bool
+	}{
+		{ScopeRead, ScopeRead, true},
+		{ScopeRead, ScopeWrite, false},
+		{ScopeRead, ScopeOperator, false},
+		{ScopeOperator, ScopeOperator, true},
+		{ScopeOperator, ScopeWrite, false},
+		{ScopeWrite, ScopeRead, true},
+		{ScopeWrite, ScopeAdmin, false},
+		{ScopeAdmin, ScopeWrite, true},
+		{"", ScopeRead, false},
+	}
+	for _, tt := range scopes {
+		if got := ScopeAllows(tt.scope, tt.required); got != tt.allowed {
+			t.Errorf("ScopeAllows(%q, %q) = %v, want %v", tt.scope, tt.required, got, tt.allowed)
+		}
+	}
+}
+
+func TestConfigAPIKeyHash(t *testing.T) {
+	hashed := HashConfigAPIKey("s3cret")
+	if !strings.HasPrefix(hashed, "sha256:") || strings.Contains(hashed, "s3cret") {
+		t.Fatalf("Expected sha256-prefixed hash, got %q", hashed)
+	}
+	if HashConfigAPIKey(hashed) != hashed {
+		t.Error("Expected hashing an already hashed value to be a no-op")
+	}
+	if HashConfigAPIKey("") != "" {
+		t.Error("Expected empty value to stay empty")
+	}
+	if ConfigAPIKeyHash(hashed) != HashAPIKeySecret("s3cret") || ConfigAPIKeyHash("s3cret") != HashAPIKeySecret("s3cret") {
+		t.Error("Expected plain and hashed API_KEY values to authenticate the same secret")
+	}
+}
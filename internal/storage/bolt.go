@@ -1,42 +1,63 @@
 package storage
 
 import (
+	"crypto/cipher"
 	"fmt"
-	"log"
+	"os"
+	"sync"
 	"time"
 
 	bolt "go.etcd.io/bbolt"
+
+	"tg-monitor-bot/internal/logging"
 )
 
 const (
 	// Bucket names
 	sourcesBucket        = "sources"
-	sourceChatsBucket    = "source_chats"
-	chatsBucket          = "chats" // registry of telegram chats (chat_id -> name, etc.)
+	sourceSinksBucket    = "source_sinks" // links between sources and notification sinks
+	sinkSourcesBucket    = "sink_sources" // reverse index of source_sinks keyed by sink ID
+	chatsBucket          = "chats"        // registry of telegram chats (chat_id -> name, etc.)
 	statusChangesBucket  = "status_changes"
 	configBucket         = "config"
 	webhooksBucket       = "webhooks"
-	sourceWebhooksBucket = "source_webhooks"
+	metaBucket           = "meta" // internal metadata (wrapped data key, etc.)
+	rollupsBucket        = "daily_rollups"
+	deliveriesBucket     = "deliveries"    // notification delivery log
+	apiKeysBucket        = "api_keys"      // named API keys (secrets stored hashed)
+	systemEventsBucket   = "system_events" // app lifecycle history (startups, restarts, config changes)
+	maintenanceBucket    = "maintenance"   // maintenance windows that suppress notifications
+	templatesBucket      = "source_templates"
+	discoveryBucket      = "discovered_hosts" // hosts found by network discovery scans, keyed by IP
+	namespacesBucket     = "namespaces"       // tenants that own sources, sinks, chats and API keys
+	telegramUsersBucket  = "telegram_users"   // users who have messaged the bot, to resolve @usernames
+	incidentNotesBucket  = "incident_notes"   // operators' notes on outages, keyed by source and outage start
+	plannedOutagesBucket = "planned_outages"  // outages marked as planned, keyed by source and outage start
+	alertGroupsBucket    = "alert_groups"     // correlated status changes notified as one alert, keyed by ID
 )
 
 // BoltDB wraps the bbolt database
 type BoltDB struct {
 	db     *bolt.DB
-	logger *log.Logger
+	mu     sync.RWMutex // guards db; held exclusively while the file is swapped by Compact
+	path   string
+	logger *logging.Logger
+	cipher cipher.AEAD // data key cipher for secret values; nil when encryption is disabled
+	// readOnly is set for a copy opened with OpenReadOnly; writes fail
+	readOnly bool
 }
 
 // NewBoltDB creates a new BoltDB instance
 func NewBoltDB(path string) (*BoltDB, error) {
-	db, err := bolt.Open(path, 0600, &bolt.Options{
-		Timeout: 1 * time.Second,
-	})
+	db, err := openBolt(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	bdb := &BoltDB{
 		db:     db,
-		logger: log.New(log.Writer(), "[STORAGE] ", log.LstdFlags),
+		path:   path,
+		logger: logging.New("storage"),
 	}
 
 	// Initialize buckets
@@ -45,22 +66,143 @@ func NewBoltDB(path string) (*BoltDB, error) {
 		return nil, err
 	}
 
+	// Bring existing data up to the current schema
+	if err := bdb.runMigrations(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	bdb.logger.Printf("Database initialized at %s", path)
 
 	return bdb, nil
 }
 
+// OpenReadOnly opens a copy of the database, such as a replicated backup, without
+// modifying it: buckets are not created and migrations are not run, so the copy must
+// come from the same version. Other processes may read the file at the same time.
+func OpenReadOnly(path string) (*BoltDB, error) {
+	db, err := openBoltReadOnly(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	bdb := &BoltDB{
+		db:       db,
+		path:     path,
+		logger:   logging.New("storage"),
+		readOnly: true,
+	}
+	if err := bdb.checkReadOnlySchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	bdb.logger.Printf("Database opened read-only at %s", path)
+	return bdb, nil
+}
+
+// openBolt opens the bbolt file with the standard options
+func openBolt(path string) (*bolt.DB, error) {
+	return bolt.Open(path, 0600, &bolt.Options{
+		Timeout: 1 * time.Second,
+	})
+}
+
+// openBoltReadOnly opens the bbolt file with a shared lock for reading
+func openBoltReadOnly(path string) (*bolt.DB, error) {
+	return bolt.Open(path, 0600, &bolt.Options{
+		Timeout:  1 * time.Second,
+		ReadOnly: true,
+	})
+}
+
+// checkReadOnlySchema fails unless a read-only copy is at the schema version this build
+// migrates to, since it can't be migrated
+func (b *BoltDB) checkReadOnlySchema() error {
+	version, err := b.SchemaVersion()
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version != latestSchemaVersion() {
+		return fmt.Errorf("database schema version %d doesn't match version %d of this build; copy it from an instance running the same version", version, latestSchemaVersion())
+	}
+	return nil
+}
+
+// Path returns the database file path
+func (b *BoltDB) Path() string {
+	return b.path
+}
+
+// ReadOnly reports whether the database was opened with OpenReadOnly
+func (b *BoltDB) ReadOnly() bool {
+	return b.readOnly
+}
+
+// Reopen reopens a read-only database, picking up a newer copy that replaced the file.
+// The current file stays open if the new one can't be used.
+func (b *BoltDB) Reopen() error {
+	if !b.readOnly {
+		return fmt.Errorf("only read-only databases can be reopened")
+	}
+	db, err := openBoltReadOnly(b.path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	next := &BoltDB{db: db, readOnly: true}
+	if err := next.checkReadOnlySchema(); err != nil {
+		db.Close()
+		return err
+	}
+
+	b.mu.Lock()
+	old := b.db
+	b.db = db
+	b.mu.Unlock()
+	return old.Close()
+}
+
+// update runs a read-write transaction
+func (b *BoltDB) update(fn func(tx *bolt.Tx) error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.db.Update(fn)
+}
+
+// view runs a read-only transaction
+func (b *BoltDB) view(fn func(tx *bolt.Tx) error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.db.View(fn)
+}
+
 // initBuckets creates required buckets if they don't exist
 func (b *BoltDB) initBuckets() error {
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		buckets := []string{
 			sourcesBucket,
-			sourceChatsBucket,
+			sourceSinksBucket,
+			sinkSourcesBucket,
 			chatsBucket,
 			statusChangesBucket,
 			configBucket,
 			webhooksBucket,
-			sourceWebhooksBucket,
+			metaBucket,
+			rollupsBucket,
+			deliveriesBucket,
+			apiKeysBucket,
+			systemEventsBucket,
+			maintenanceBucket,
+			templatesBucket,
+			discoveryBucket,
+			namespacesBucket,
+			telegramUsersBucket,
+			incidentNotesBucket,
+			plannedOutagesBucket,
+			alertGroupsBucket,
+		}
+		if readSchemaVersion(tx) < sinksSchemaVersion {
+			buckets = append(buckets, sourceChatsBucket, chatSourcesBucket, sourceWebhooksBucket)
 		}
 
 		for _, bucket := range buckets {
@@ -76,11 +218,202 @@ func (b *BoltDB) initBuckets() error {
 
 // Close closes the database connection
 func (b *BoltDB) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	b.logger.Println("Closing database")
 	return b.db.Close()
 }
 
-// DB returns the underlying bbolt database
+// DB returns the underlying bbolt database.
+// The returned handle becomes invalid after Compact swaps the file.
 func (b *BoltDB) DB() *bolt.DB {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 	return b.db
 }
+
+// CompactResult describes the outcome of a database compaction
+type CompactResult struct {
+	SizeBefore int64 `json:"size_before"`
+	SizeAfter  int64 `json:"size_after"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// Compact rewrites the database into a new file without free pages and atomically
+// swaps it in place of the current one. All reads and writes block while it runs. If the
+// compacted file can't be swapped in or opened, the original is restored and reopened.
+func (b *BoltDB) Compact() (*CompactResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	start := time.Now()
+	tmpPath := b.path + ".compact"
+	_ = os.Remove(tmpPath)
+
+	before, err := os.Stat(b.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat database: %w", err)
+	}
+
+	dst, err := openBolt(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compacted database: %w", err)
+	}
+
+	if err := bolt.Compact(dst, b.db, 64*1024); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to compact database: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to close compacted database: %w", err)
+	}
+
+	if err := b.db.Close(); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to close database: %w", err)
+	}
+
+	// Keep a hard link to the original file until the compacted one has been opened, so a
+	// failed reopen can put it back. The path itself is only ever replaced atomically.
+	origPath := b.path + ".precompact"
+	_ = os.Remove(origPath)
+	if err := os.Link(b.path, origPath); err != nil {
+		os.Remove(tmpPath)
+		return nil, b.reopenAfterCompact(fmt.Errorf("failed to keep the original database: %w", err))
+	}
+	if err := os.Rename(tmpPath, b.path); err != nil {
+		os.Remove(tmpPath)
+		os.Remove(origPath)
+		return nil, b.reopenAfterCompact(fmt.Errorf("failed to swap compacted database: %w", err))
+	}
+	db, err := reopenBolt(b.path)
+	if err != nil {
+		return nil, b.restoreAfterCompact(origPath, fmt.Errorf("failed to reopen database after compaction: %w", err))
+	}
+	b.db = db
+	os.Remove(origPath)
+
+	after, err := os.Stat(b.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat compacted database: %w", err)
+	}
+
+	result := &CompactResult{
+		SizeBefore: before.Size(),
+		SizeAfter:  after.Size(),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	b.logger.Printf("Compacted database: %d → %d bytes in %dms", result.SizeBefore, result.SizeAfter, result.DurationMs)
+	return result, nil
+}
+
+// reopenBolt opens the database file after compaction; tests replace it to simulate failures
+var reopenBolt = openBolt
+
+// restoreAfterCompact moves the original file back in place of the compacted one and
+// reopens it. Called with b.mu held.
+func (b *BoltDB) restoreAfterCompact(origPath string, cause error) error {
+	if err := os.Rename(origPath, b.path); err != nil {
+		b.logger.Errorf("Failed to restore %s after a failed compaction: %v", origPath, err)
+		return fmt.Errorf("%w; the original database is kept at %s and storage is unusable until restart", cause, origPath)
+	}
+	return b.reopenAfterCompact(cause)
+}
+
+// reopenAfterCompact reopens the original file after a failed compaction. If even that
+// fails, b.db stays closed and every operation returns bolt.ErrDatabaseNotOpen.
+// Called with b.mu held.
+func (b *BoltDB) reopenAfterCompact(cause error) error {
+	db, err := reopenBolt(b.path)
+	if err != nil {
+		b.logger.Errorf("Failed to reopen database after a failed compaction: %v", err)
+		return fmt.Errorf("%w; reopening the original database failed too, storage is unusable until restart: %v", cause, err)
+	}
+	b.db = db
+	return cause
+}
+
+// Backup writes a consistent snapshot of the database to path while it stays in use.
+// The copy is written to a temporary file first, so path never holds a partial backup.
+func (b *BoltDB) Backup(path string) error {
+	tmpPath := path + ".tmp"
+	err := b.view(func(tx *bolt.Tx) error {
+		return tx.CopyFile(tmpPath, 0600)
+	})
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	b.logger.Printf("Database backed up to %s", path)
+	return nil
+}
+
+// DBStats are bbolt runtime counters and the database file size
+type DBStats struct {
+	FileSize      int64 `json:"file_size"`
+	FreePages     int   `json:"free_pages"`
+	PendingPages  int   `json:"pending_pages"`
+	FreeAlloc     int   `json:"free_alloc"`     // Bytes allocated in free pages
+	FreelistInuse int   `json:"freelist_inuse"` // Bytes used by the freelist
+	OpenReadTx    int   `json:"open_read_tx"`
+	ReadTx        int   `json:"read_tx"`     // Read transactions started since open
+	PageWrites    int64 `json:"page_writes"` // Page writes to disk since open
+	PageAllocs    int64 `json:"page_allocs"`
+	WriteTimeMs   int64 `json:"write_time_ms"` // Total time spent writing pages to disk
+}
+
+// Stats returns bbolt counters for the open database; they restart after Compact reopens it
+func (b *BoltDB) Stats() (*DBStats, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat database: %w", err)
+	}
+	stats := b.db.Stats()
+	return &DBStats{
+		FileSize:      info.Size(),
+		FreePages:     stats.FreePageN,
+		PendingPages:  stats.PendingPageN,
+		FreeAlloc:     stats.FreeAlloc,
+		FreelistInuse: stats.FreelistInuse,
+		OpenReadTx:    stats.OpenTxN,
+		ReadTx:        stats.TxN,
+		PageWrites:    stats.TxStats.GetWrite(),
+		PageAllocs:    stats.TxStats.GetPageAlloc(),
+		WriteTimeMs:   stats.TxStats.GetWriteTime().Milliseconds(),
+	}, nil
+}
+
+// Ping verifies the database is open and readable
+func (b *BoltDB) Ping() error {
+	return b.view(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(metaBucket)) == nil {
+			return fmt.Errorf("meta bucket not found")
+		}
+		return nil
+	})
+}
+
+// PingWrite verifies the database accepts writes: a marker key is written to the meta bucket
+// and removed again in one committed (and synced) transaction
+func (b *BoltDB) PingWrite() error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metaBucket))
+		if bucket == nil {
+			return fmt.Errorf("meta bucket not found")
+		}
+		key := []byte("selftest")
+		if err := bucket.Put(key, []byte(time.Now().UTC().Format(time.RFC3339Nano))); err != nil {
+			return err
+		}
+		return bucket.Delete(key)
+	})
+}
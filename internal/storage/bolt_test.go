@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := NewBoltDB(path)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	source := &Source{Name: "API", Type: "http", Target: "https://example.com"}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+
+	if _, err := db.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if got, err := db.GetSource(source.ID); err != nil || got.Name != "API" {
+		t.Errorf("Expected the source after compaction, got %+v (%v)", got, err)
+	}
+	for _, leftover := range []string{path + ".compact", path + ".precompact"} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, got %v", filepath.Base(leftover), err)
+		}
+	}
+}
+
+func TestCompactReopenFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := NewBoltDB(path)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	source := &Source{Name: "API", Type: "http", Target: "https://example.com"}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+
+	// The compacted file fails to open; the original is restored and reopened
+	defer func() { reopenBolt = openBolt }()
+	calls := 0
+	reopenBolt = func(path string) (*bolt.DB, error) {
+		if calls++; calls == 1 {
+			return nil, errors.New("simulated open failure")
+		}
+		return openBolt(path)
+	}
+	if _, err := db.Compact(); err == nil || !strings.Contains(err.Error(), "simulated open failure") {
+		t.Fatalf("Expected the reopen error, got %v", err)
+	}
+	if got, err := db.GetSource(source.ID); err != nil || got.Name != "API" {
+		t.Errorf("Expected the original database back in use, got %+v (%v)", got, err)
+	}
+	if err := db.SaveSource(&Source{Name: "Web", Type: "http", Target: "https://example.org"}); err != nil {
+		t.Errorf("Expected the restored database to be writable, got %v", err)
+	}
+	if _, err := os.Stat(path + ".precompact"); !os.IsNotExist(err) {
+		t.Errorf("Expected the kept original to be moved back, got %v", err)
+	}
+
+	// If the original can't be reopened either, the error says so and operations fail cleanly
+	reopenBolt = func(string) (*bolt.DB, error) { return nil, errors.New("simulated open failure") }
+	if _, err := db.Compact(); err == nil || !strings.Contains(err.Error(), "unusable until restart") {
+		t.Fatalf("Expected the store to be reported unusable, got %v", err)
+	}
+	if _, err := db.GetSource(source.ID); !errors.Is(err, bolt.ErrDatabaseNotOpen) {
+		t.Errorf("Expected reads to fail with ErrDatabaseNotOpen, got %v", err)
+	}
+
+	// The data is intact on disk for the next start
+	reopenBolt = openBolt
+	db.Close()
+	if db, err = NewBoltDB(path); err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	if sources, err := db.GetAllSources(); err != nil || len(sources) != 2 {
+		t.Errorf("Expected both sources on disk, got %d (%v)", len(sources), err)
+	}
+}
@@ -14,6 +14,8 @@ type Chat struct {
 	ChatID    int64     `msgpack:"chat_id" json:"chat_id"`
 	Name      string    `msgpack:"name" json:"name"`
 	CreatedAt time.Time `msgpack:"created_at" json:"created_at"`
+	// Namespace the chat belongs to; the bot limits it to that namespace's sources
+	Namespace string `msgpack:"namespace" json:"namespace,omitempty"`
 }
 
 func chatKey(chatID int64) []byte {
@@ -31,7 +33,7 @@ func (b *BoltDB) SaveChat(chat *Chat) error {
 		return fmt.Errorf("failed to marshal chat: %w", err)
 	}
 
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(chatsBucket))
 		if bucket == nil {
 			return fmt.Errorf("chats bucket not found")
@@ -39,7 +41,7 @@ func (b *BoltDB) SaveChat(chat *Chat) error {
 		if err := bucket.Put(chatKey(chat.ChatID), data); err != nil {
 			return fmt.Errorf("failed to save chat: %w", err)
 		}
-		b.logger.Printf("Saved chat %d (%s)", chat.ChatID, chat.Name)
+		b.logger.Debugf("Saved chat %d (%s)", chat.ChatID, chat.Name)
 		return nil
 	})
 }
@@ -47,7 +49,7 @@ func (b *BoltDB) SaveChat(chat *Chat) error {
 // GetChat retrieves a chat from the registry by ID
 func (b *BoltDB) GetChat(chatID int64) (*Chat, error) {
 	var chat *Chat
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(chatsBucket))
 		if bucket == nil {
 			return fmt.Errorf("chats bucket not found")
@@ -65,7 +67,7 @@ func (b *BoltDB) GetChat(chatID int64) (*Chat, error) {
 // ListChats returns all chats in the registry
 func (b *BoltDB) ListChats() ([]*Chat, error) {
 	var chats []*Chat
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(chatsBucket))
 		if bucket == nil {
 			return fmt.Errorf("chats bucket not found")
@@ -73,7 +75,7 @@ func (b *BoltDB) ListChats() ([]*Chat, error) {
 		return bucket.ForEach(func(k, v []byte) error {
 			chat := &Chat{}
 			if err := msgpack.Unmarshal(v, chat); err != nil {
-				b.logger.Printf("Failed to unmarshal chat: %v", err)
+				b.logger.Errorf("Failed to unmarshal chat: %v", err)
 				return nil
 			}
 			chats = append(chats, chat)
@@ -85,7 +87,7 @@ func (b *BoltDB) ListChats() ([]*Chat, error) {
 
 // DeleteChat removes a chat from the registry and from all source associations
 func (b *BoltDB) DeleteChat(chatID int64) error {
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		chatsB := tx.Bucket([]byte(chatsBucket))
 		if chatsB == nil {
 			return fmt.Errorf("chats bucket not found")
@@ -94,26 +96,11 @@ func (b *BoltDB) DeleteChat(chatID int64) error {
 			return fmt.Errorf("failed to delete chat: %w", err)
 		}
 
-		// Remove from all source_chats
-		scB := tx.Bucket([]byte(sourceChatsBucket))
-		if scB != nil {
-			c := scB.Cursor()
-			var keysToDelete [][]byte
-			for k, v := c.First(); k != nil; k, v = c.Next() {
-				var sc SourceChat
-				if err := msgpack.Unmarshal(v, &sc); err != nil {
-					continue
-				}
-				if sc.ChatID == chatID {
-					keysToDelete = append(keysToDelete, append([]byte(nil), k...))
-				}
-			}
-			for _, key := range keysToDelete {
-				_ = scB.Delete(key)
-			}
+		if err := deleteSinkLinks(tx, TelegramSinkID(chatID)); err != nil {
+			return err
 		}
 
-		b.logger.Printf("Deleted chat %d", chatID)
+		b.logger.Debugf("Deleted chat %d", chatID)
 		return nil
 	})
 }
@@ -14,13 +14,25 @@ type ConfigEntry struct {
 	Value     string    `msgpack:"value"`
 	UpdatedAt time.Time `msgpack:"updated_at"`
 	UpdatedBy string    `msgpack:"updated_by"` // "env", "api", "initial"
+
+	encrypted bool // Value was stored encrypted
 }
 
-// SaveConfig stores a config entry in the database
+// SaveConfig stores a config entry in the database.
+// Values of sensitive keys are encrypted when encryption at rest is enabled.
 func (b *BoltDB) SaveConfig(key, value, updatedBy string) error {
+	stored := value
+	if IsSensitiveConfigKey(key) {
+		enc, err := b.encryptValue(value)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt config %s: %w", key, err)
+		}
+		stored = enc
+	}
+
 	entry := &ConfigEntry{
 		Key:       key,
-		Value:     value,
+		Value:     stored,
 		UpdatedAt: time.Now(),
 		UpdatedBy: updatedBy,
 	}
@@ -30,7 +42,7 @@ func (b *BoltDB) SaveConfig(key, value, updatedBy string) error {
 		return fmt.Errorf("failed to marshal config entry: %w", err)
 	}
 
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(configBucket))
 		if bucket == nil {
 			return fmt.Errorf("config bucket not found")
@@ -40,7 +52,7 @@ func (b *BoltDB) SaveConfig(key, value, updatedBy string) error {
 			return fmt.Errorf("failed to save config: %w", err)
 		}
 
-		b.logger.Printf("Saved config: %s (by %s)", key, updatedBy)
+		b.logger.Debugf("Saved config: %s (by %s)", key, updatedBy)
 		return nil
 	})
 }
@@ -49,7 +61,7 @@ func (b *BoltDB) SaveConfig(key, value, updatedBy string) error {
 func (b *BoltDB) GetConfig(key string) (*ConfigEntry, error) {
 	var entry *ConfigEntry
 
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(configBucket))
 		if bucket == nil {
 			return fmt.Errorf("config bucket not found")
@@ -65,7 +77,7 @@ func (b *BoltDB) GetConfig(key string) (*ConfigEntry, error) {
 			return fmt.Errorf("failed to unmarshal config: %w", err)
 		}
 
-		return nil
+		return b.decryptConfigEntry(entry)
 	})
 
 	return entry, err
@@ -75,7 +87,7 @@ func (b *BoltDB) GetConfig(key string) (*ConfigEntry, error) {
 func (b *BoltDB) GetAllConfig() (map[string]*ConfigEntry, error) {
 	configs := make(map[string]*ConfigEntry)
 
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(configBucket))
 		if bucket == nil {
 			return fmt.Errorf("config bucket not found")
@@ -86,7 +98,11 @@ func (b *BoltDB) GetAllConfig() (map[string]*ConfigEntry, error) {
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			var entry ConfigEntry
 			if err := msgpack.Unmarshal(v, &entry); err != nil {
-				b.logger.Printf("Failed to unmarshal config %s: %v", string(k), err)
+				b.logger.Errorf("Failed to unmarshal config %s: %v", string(k), err)
+				continue
+			}
+			if err := b.decryptConfigEntry(&entry); err != nil {
+				b.logger.Errorf("Failed to decrypt config %s: %v", string(k), err)
 				continue
 			}
 
@@ -99,9 +115,20 @@ func (b *BoltDB) GetAllConfig() (map[string]*ConfigEntry, error) {
 	return configs, err
 }
 
+// decryptConfigEntry replaces an encrypted entry value with its plaintext
+func (b *BoltDB) decryptConfigEntry(entry *ConfigEntry) error {
+	plain, encrypted, err := b.decryptValue(entry.Value)
+	if err != nil {
+		return err
+	}
+	entry.Value = plain
+	entry.encrypted = encrypted
+	return nil
+}
+
 // DeleteConfig removes a config entry
 func (b *BoltDB) DeleteConfig(key string) error {
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(configBucket))
 		if bucket == nil {
 			return fmt.Errorf("config bucket not found")
@@ -111,7 +138,7 @@ func (b *BoltDB) DeleteConfig(key string) error {
 			return fmt.Errorf("failed to delete config: %w", err)
 		}
 
-		b.logger.Printf("Deleted config: %s", key)
+		b.logger.Debugf("Deleted config: %s", key)
 		return nil
 	})
 }
@@ -120,7 +147,7 @@ func (b *BoltDB) DeleteConfig(key string) error {
 func (b *BoltDB) ConfigExists(key string) bool {
 	exists := false
 
-	b.db.View(func(tx *bolt.Tx) error {
+	b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(configBucket))
 		if bucket == nil {
 			return nil
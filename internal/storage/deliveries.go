@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Delivery records a single notification attempt to a sink
+type Delivery struct {
+	ID             string    `msgpack:"id" json:"id"`
+	Timestamp      time.Time `msgpack:"timestamp" json:"timestamp"`
+	SinkType       string    `msgpack:"sink_type" json:"sink_type"` // "telegram" or "webhook"
+	SinkID         string    `msgpack:"sink_id" json:"sink_id"`     // chat ID or webhook ID
+	SinkName       string    `msgpack:"sink_name" json:"sink_name,omitempty"`
+	SourceID       string    `msgpack:"source_id" json:"source_id"`
+	SourceName     string    `msgpack:"source_name" json:"source_name"`
+	StatusChangeID string    `msgpack:"status_change_id" json:"status_change_id"`
+	OldStatus      int       `msgpack:"old_status" json:"old_status"`
+	NewStatus      int       `msgpack:"new_status" json:"new_status"`
+	Success        bool      `msgpack:"success" json:"success"`
+	LatencyMs      int64     `msgpack:"latency_ms" json:"latency_ms"`
+	StatusCode     int       `msgpack:"status_code" json:"status_code,omitempty"` // HTTP status for webhook sinks
+	Error          string    `msgpack:"error" json:"error,omitempty"`
+}
+
+// DeliveryFilter narrows down delivery log queries; zero values match everything
+type DeliveryFilter struct {
+	SourceID       string
+	SinkType       string
+	StatusChangeID string
+	Success        *bool
+	Limit          int
+}
+
+// makeDeliveryKey creates a time-ordered key (timestamp nanoseconds + ID)
+func makeDeliveryKey(timestamp time.Time, id string) []byte {
+	tsBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(tsBytes, uint64(timestamp.UnixNano()))
+	return append(tsBytes, []byte(id)...)
+}
+
+// SaveDelivery stores a notification attempt in the delivery log
+func (b *BoltDB) SaveDelivery(delivery *Delivery) error {
+	if delivery.ID == "" {
+		delivery.ID = uuid.New().String()
+	}
+
+	if delivery.Timestamp.IsZero() {
+		delivery.Timestamp = time.Now()
+	}
+
+	data, err := msgpack.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(deliveriesBucket))
+		if bucket == nil {
+			return fmt.Errorf("deliveries bucket not found")
+		}
+
+		if err := bucket.Put(makeDeliveryKey(delivery.Timestamp, delivery.ID), data); err != nil {
+			return fmt.Errorf("failed to save delivery: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetDeliveries retrieves delivery log entries matching the filter, newest first
+func (b *BoltDB) GetDeliveries(filter DeliveryFilter) ([]*Delivery, error) {
+	var deliveries []*Delivery
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(deliveriesBucket))
+		if bucket == nil {
+			return fmt.Errorf("deliveries bucket not found")
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if filter.Limit > 0 && len(deliveries) >= filter.Limit {
+				break
+			}
+
+			var delivery Delivery
+			if err := msgpack.Unmarshal(v, &delivery); err != nil {
+				b.logger.Errorf("Failed to unmarshal delivery: %v", err)
+				continue
+			}
+
+			if filter.SourceID != "" && delivery.SourceID != filter.SourceID {
+				continue
+			}
+			if filter.SinkType != "" && delivery.SinkType != filter.SinkType {
+				continue
+			}
+			if filter.StatusChangeID != "" && delivery.StatusChangeID != filter.StatusChangeID {
+				continue
+			}
+			if filter.Success != nil && delivery.Success != *filter.Success {
+				continue
+			}
+
+			deliveries = append(deliveries, &delivery)
+		}
+
+		return nil
+	})
+
+	return deliveries, err
+}
+
+// DeleteOldDeliveries removes delivery log entries older than the specified duration
+func (b *BoltDB) DeleteOldDeliveries(olderThan time.Duration) (int, error) {
+	cutoff := makeDeliveryKey(time.Now().Add(-olderThan), "")
+	deleted := 0
+
+	err := b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(deliveriesBucket))
+		if bucket == nil {
+			return fmt.Errorf("deliveries bucket not found")
+		}
+
+		// Keys are time-ordered, so old entries form a prefix of the bucket
+		c := bucket.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			deleted++
+		}
+
+		return nil
+	})
+
+	if err == nil && deleted > 0 {
+		b.logger.Printf("Deleted %d old deliveries", deleted)
+	}
+
+	return deleted, err
+}
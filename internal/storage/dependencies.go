@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDependencies bounds the upstream sources a single source declares
+const maxDependencies = 20
+
+// NormalizeDependencies trims and de-duplicates the upstream source IDs of a source, keeping
+// their order
+func NormalizeDependencies(ids []string) ([]string, error) {
+	normalized := make([]string, 0, len(ids))
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		normalized = append(normalized, id)
+	}
+	if len(normalized) > maxDependencies {
+		return nil, fmt.Errorf("at most %d dependencies per source", maxDependencies)
+	}
+	return normalized, nil
+}
+
+// ValidateDependencies checks the declared dependencies of source before it is saved: each
+// one must be another live source of the same namespace, and the dependency graph must stay
+// free of cycles
+func (b *BoltDB) ValidateDependencies(source *Source) error {
+	if len(source.DependsOn) == 0 {
+		return nil
+	}
+	sources, err := b.GetAllSources()
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]*Source, len(sources))
+	for _, s := range sources {
+		byID[s.ID] = s
+	}
+	byID[source.ID] = source // Its new dependencies, not the stored ones
+
+	for _, id := range source.DependsOn {
+		if id == source.ID {
+			return fmt.Errorf("source cannot depend on itself")
+		}
+		dependency := byID[id]
+		if dependency == nil || dependency.IsDeleted() || dependency.Namespace != source.Namespace {
+			return fmt.Errorf("dependency %q not found", id)
+		}
+	}
+
+	// A cycle through source means it is reachable from its own dependencies
+	visited := make(map[string]bool)
+	queue := append([]string(nil), source.DependsOn...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == source.ID {
+			return fmt.Errorf("dependencies would form a cycle through %q", source.Name)
+		}
+		if visited[id] || byID[id] == nil {
+			continue
+		}
+		visited[id] = true
+		queue = append(queue, byID[id].DependsOn...)
+	}
+	return nil
+}
+
+// upstreamSources returns the live sources source depends on, directly or through other
+// dependencies, nearest first
+func upstreamSources(source *Source, byID map[string]*Source) []*Source {
+	var upstream []*Source
+	visited := map[string]bool{source.ID: true}
+	queue := append([]string(nil), source.DependsOn...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		dependency := byID[id]
+		if visited[id] || dependency == nil || dependency.IsDeleted() {
+			continue
+		}
+		visited[id] = true
+		upstream = append(upstream, dependency)
+		queue = append(queue, dependency.DependsOn...)
+	}
+	return upstream
+}
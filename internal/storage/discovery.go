@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Ways a discovery scan can find a host
+const (
+	DiscoveredByPing = "ping"
+	DiscoveredByARP  = "arp"
+	DiscoveredByTCP  = "tcp"
+)
+
+// DiscoveredHost is a host found by a network discovery scan, keyed by IP
+type DiscoveredHost struct {
+	IP        string    `msgpack:"ip" json:"ip"`
+	Hostname  string    `msgpack:"hostname" json:"hostname,omitempty"` // Reverse DNS
+	MAC       string    `msgpack:"mac" json:"mac,omitempty"`           // From the ARP table, on the local network only
+	Methods   []string  `msgpack:"methods" json:"methods"`             // How the last scan that found it did: ping, arp, tcp
+	OpenPorts []int     `msgpack:"open_ports" json:"open_ports,omitempty"`
+	FirstSeen time.Time `msgpack:"first_seen" json:"first_seen"`
+	LastSeen  time.Time `msgpack:"last_seen" json:"last_seen"`
+	// Live source whose target is the host, e.g. after promoting it; empty for new hosts
+	SourceID string `msgpack:"source_id" json:"source_id,omitempty"`
+}
+
+// SourceName returns a name for a source monitoring the host: the first label of its
+// hostname, or its IP
+func (h *DiscoveredHost) SourceName() string {
+	if h.Hostname == "" {
+		return h.IP
+	}
+	name, _, _ := strings.Cut(strings.TrimSuffix(h.Hostname, "."), ".")
+	return name
+}
+
+// SourceTarget returns the target of a source of the given type monitoring the host: its IP
+// for ping, and for http an http URL, or https when 443 is its only web port open
+func (h *DiscoveredHost) SourceTarget(sourceType string) string {
+	if sourceType != "http" {
+		return h.IP
+	}
+	if slices.Contains(h.OpenPorts, 443) && !slices.Contains(h.OpenPorts, 80) {
+		return "https://" + h.IP
+	}
+	return "http://" + h.IP
+}
+
+// targetHost returns the host part of a ping target or http URL, lowercased
+func targetHost(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	return strings.ToLower(strings.TrimSuffix(target, "."))
+}
+
+// matchSource sets SourceID to the first source targeting the host's IP or hostname, or clears it
+func (h *DiscoveredHost) matchSource(sources []*Source) {
+	h.SourceID = ""
+	hostname := strings.ToLower(strings.TrimSuffix(h.Hostname, "."))
+	for _, source := range sources {
+		if host := targetHost(source.Target); host != "" && (host == h.IP || host == hostname) {
+			h.SourceID = source.ID
+			return
+		}
+	}
+}
+
+// SaveDiscoveredHosts records the hosts found by a scan and returns how many were not known
+// before. Known hosts keep their first_seen. Every stored host is then matched to the live
+// sources, so hosts monitored since are no longer new.
+func (b *BoltDB) SaveDiscoveredHosts(found []*DiscoveredHost) (int, error) {
+	sources, err := b.GetAllSources()
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	err = b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(discoveryBucket))
+		if bucket == nil {
+			return fmt.Errorf("discovered_hosts bucket not found")
+		}
+
+		for _, host := range found {
+			if data := bucket.Get([]byte(host.IP)); data != nil {
+				var known DiscoveredHost
+				if err := msgpack.Unmarshal(data, &known); err == nil {
+					host.FirstSeen = known.FirstSeen
+				}
+			} else {
+				added++
+			}
+			if host.FirstSeen.IsZero() {
+				host.FirstSeen = host.LastSeen
+			}
+			data, err := msgpack.Marshal(host)
+			if err != nil {
+				return fmt.Errorf("failed to marshal discovered host: %w", err)
+			}
+			if err := bucket.Put([]byte(host.IP), data); err != nil {
+				return fmt.Errorf("failed to save discovered host: %w", err)
+			}
+		}
+
+		// Rematch every host, since sources change between scans
+		rematched := make(map[string][]byte)
+		err := bucket.ForEach(func(k, v []byte) error {
+			var host DiscoveredHost
+			if err := msgpack.Unmarshal(v, &host); err != nil {
+				return nil // Skip malformed hosts
+			}
+			previous := host.SourceID
+			if host.matchSource(sources); host.SourceID == previous {
+				return nil
+			}
+			data, err := msgpack.Marshal(&host)
+			if err != nil {
+				return fmt.Errorf("failed to marshal discovered host: %w", err)
+			}
+			rematched[string(k)] = data
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for ip, data := range rematched {
+			if err := bucket.Put([]byte(ip), data); err != nil {
+				return fmt.Errorf("failed to save discovered host: %w", err)
+			}
+		}
+		return nil
+	})
+	return added, err
+}
+
+// GetDiscoveredHost retrieves a discovered host by IP
+func (b *BoltDB) GetDiscoveredHost(ip string) (*DiscoveredHost, error) {
+	var host DiscoveredHost
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(discoveryBucket))
+		if bucket == nil {
+			return fmt.Errorf("discovered_hosts bucket not found")
+		}
+		data := bucket.Get([]byte(ip))
+		if data == nil {
+			return fmt.Errorf("discovered host not found")
+		}
+		return msgpack.Unmarshal(data, &host)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &host, nil
+}
+
+// GetDiscoveredHosts retrieves all discovered hosts, by IP
+func (b *BoltDB) GetDiscoveredHosts() ([]*DiscoveredHost, error) {
+	var hosts []*DiscoveredHost
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(discoveryBucket))
+		if bucket == nil {
+			return fmt.Errorf("discovered_hosts bucket not found")
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var host DiscoveredHost
+			if err := msgpack.Unmarshal(v, &host); err != nil {
+				b.logger.Errorf("Failed to unmarshal discovered host: %v", err)
+				return nil // Skip malformed hosts
+			}
+			hosts = append(hosts, &host)
+			return nil
+		})
+	})
+
+	// Numeric order: 10.0.0.2 before 10.0.0.10
+	sort.Slice(hosts, func(i, j int) bool {
+		first, errFirst := netip.ParseAddr(hosts[i].IP)
+		second, errSecond := netip.ParseAddr(hosts[j].IP)
+		if errFirst != nil || errSecond != nil {
+			return hosts[i].IP < hosts[j].IP
+		}
+		return first.Less(second)
+	})
+	return hosts, err
+}
+
+// SetDiscoveredHostSource links a discovered host to the source created for it
+func (b *BoltDB) SetDiscoveredHostSource(ip, sourceID string) error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(discoveryBucket))
+		if bucket == nil {
+			return fmt.Errorf("discovered_hosts bucket not found")
+		}
+		data := bucket.Get([]byte(ip))
+		if data == nil {
+			return fmt.Errorf("discovered host not found")
+		}
+		var host DiscoveredHost
+		if err := msgpack.Unmarshal(data, &host); err != nil {
+			return err
+		}
+		host.SourceID = sourceID
+		data, err := msgpack.Marshal(&host)
+		if err != nil {
+			return fmt.Errorf("failed to marshal discovered host: %w", err)
+		}
+		return bucket.Put([]byte(ip), data)
+	})
+}
+
+// DeleteDiscoveredHost forgets a discovered host; the next scan that finds it adds it again
+func (b *BoltDB) DeleteDiscoveredHost(ip string) error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(discoveryBucket))
+		if bucket == nil {
+			return fmt.Errorf("discovered_hosts bucket not found")
+		}
+		if bucket.Get([]byte(ip)) == nil {
+			return fmt.Errorf("discovered host not found")
+		}
+		return bucket.Delete([]byte(ip))
+	})
+}
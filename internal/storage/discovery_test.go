@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiscoveredHosts(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	monitored := &Source{Name: "Router", Type: "http", Target: "https://router.lan/status", CheckInterval: time.Minute, Enabled: true}
+	if err := db.SaveSource(monitored); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+
+	firstScan := time.Now().Add(-time.Hour)
+	added, err := db.SaveDiscoveredHosts([]*DiscoveredHost{
+		{IP: "10.0.0.10", Methods: []string{DiscoveredByPing}, LastSeen: firstScan},
+		{IP: "10.0.0.2", Hostname: "router.lan", Methods: []string{DiscoveredByTCP}, OpenPorts: []int{443}, LastSeen: firstScan},
+	})
+	if err != nil || added != 2 {
+		t.Fatalf("Expected 2 new hosts, got %d (%v)", added, err)
+	}
+
+	hosts, err := db.GetDiscoveredHosts()
+	if err != nil || len(hosts) != 2 {
+		t.Fatalf("Expected 2 hosts, got %d (%v)", len(hosts), err)
+	}
+	if hosts[0].IP != "10.0.0.2" || hosts[1].IP != "10.0.0.10" {
+		t.Errorf("Expected hosts in numeric order, got %s, %s", hosts[0].IP, hosts[1].IP)
+	}
+	if hosts[0].SourceID != monitored.ID {
+		t.Errorf("Expected the router to match its source by hostname, got %q", hosts[0].SourceID)
+	}
+	if hosts[1].SourceID != "" || !hosts[1].FirstSeen.Equal(firstScan) {
+		t.Errorf("Expected an unmonitored host first seen by the scan, got %+v", hosts[1])
+	}
+
+	if name := hosts[0].SourceName(); name != "router" {
+		t.Errorf("Expected the first hostname label as source name, got %q", name)
+	}
+	if name := hosts[1].SourceName(); name != "10.0.0.10" {
+		t.Errorf("Expected the IP as source name without a hostname, got %q", name)
+	}
+	if target := hosts[0].SourceTarget("http"); target != "https://10.0.0.2" {
+		t.Errorf("Expected https for a host with only 443 open, got %q", target)
+	}
+	if target := hosts[1].SourceTarget("ping"); target != "10.0.0.10" {
+		t.Errorf("Expected the IP as ping target, got %q", target)
+	}
+
+	// A later scan keeps first_seen, and matches sources created since
+	pinged := &Source{Name: "NAS", Type: "ping", Target: "10.0.0.10", CheckInterval: time.Minute, Enabled: true}
+	if err := db.SaveSource(pinged); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+	added, err = db.SaveDiscoveredHosts([]*DiscoveredHost{
+		{IP: "10.0.0.10", Methods: []string{DiscoveredByARP}, LastSeen: time.Now()},
+	})
+	if err != nil || added != 0 {
+		t.Fatalf("Expected no new hosts, got %d (%v)", added, err)
+	}
+	host, err := db.GetDiscoveredHost("10.0.0.10")
+	if err != nil {
+		t.Fatalf("GetDiscoveredHost failed: %v", err)
+	}
+	if !host.FirstSeen.Equal(firstScan) || host.LastSeen.Equal(firstScan) {
+		t.Errorf("Expected first_seen kept and last_seen updated, got %v / %v", host.FirstSeen, host.LastSeen)
+	}
+	if host.SourceID != pinged.ID {
+		t.Errorf("Expected the host to match the new source, got %q", host.SourceID)
+	}
+
+	if err := db.SetDiscoveredHostSource("10.0.0.10", ""); err != nil {
+		t.Fatalf("SetDiscoveredHostSource failed: %v", err)
+	}
+	if err := db.DeleteDiscoveredHost("10.0.0.10"); err != nil {
+		t.Fatalf("DeleteDiscoveredHost failed: %v", err)
+	}
+	if _, err := db.GetDiscoveredHost("10.0.0.10"); err == nil {
+		t.Error("Expected the host to be deleted")
+	}
+	if err := db.DeleteDiscoveredHost("10.0.0.10"); err == nil {
+		t.Error("Expected an error deleting a missing host")
+	}
+}
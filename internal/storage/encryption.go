@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// encryptedPrefix marks a value encrypted with the data key
+	encryptedPrefix = "enc:v1:"
+	// dataKeyName is the meta bucket key holding the wrapped data key
+	dataKeyName = "data_key"
+)
+
+// sensitiveConfigKeys lists config keys whose values are encrypted at rest
+var sensitiveConfigKeys = map[string]bool{
+	"TELEGRAM_TOKEN": true,
+	"API_KEY":        true,
+	"OTLP_HEADERS":   true, // Usually carries a collector API key
+	"HEARTBEAT_URL":  true, // Ping URLs embed the check's secret ID
+	"SENTRY_DSN":     true, // Carries the project key
+	"SMTP_PASSWORD":  true,
+	"HTTP_HEADERS":   true, // May carry an Authorization header for checks
+}
+
+// IsSensitiveConfigKey reports whether a config key holds a secret value
+func IsSensitiveConfigKey(key string) bool {
+	return sensitiveConfigKeys[key]
+}
+
+// EnableEncryption turns on envelope encryption for secret fields.
+// The master key wraps a random data key stored in the meta bucket; the data key
+// encrypts the values themselves. Existing plaintext secrets are re-encrypted in place.
+func (b *BoltDB) EnableEncryption(masterKey []byte) error {
+	kek, err := newGCM(masterKey)
+	if err != nil {
+		return fmt.Errorf("invalid master key: %w", err)
+	}
+
+	// A read-only copy can only use the data key it already has
+	run := b.update
+	if b.readOnly {
+		run = b.view
+	}
+
+	var dataKey []byte
+	err = run(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metaBucket))
+		if bucket == nil {
+			return fmt.Errorf("meta bucket not found")
+		}
+
+		if wrapped := bucket.Get([]byte(dataKeyName)); wrapped != nil {
+			dataKey, err = openBytes(kek, wrapped)
+			if err != nil {
+				return fmt.Errorf("failed to unwrap data key (wrong master key?): %w", err)
+			}
+			return nil
+		}
+		if b.readOnly {
+			return fmt.Errorf("read-only database has no data key")
+		}
+
+		dataKey = make([]byte, 32)
+		if _, err := rand.Read(dataKey); err != nil {
+			return fmt.Errorf("failed to generate data key: %w", err)
+		}
+		wrapped, err := sealBytes(kek, dataKey)
+		if err != nil {
+			return fmt.Errorf("failed to wrap data key: %w", err)
+		}
+		if err := bucket.Put([]byte(dataKeyName), wrapped); err != nil {
+			return fmt.Errorf("failed to save data key: %w", err)
+		}
+		b.logger.Println("Generated new data encryption key")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	b.cipher, err = newGCM(dataKey)
+	if err != nil {
+		return fmt.Errorf("invalid data key: %w", err)
+	}
+
+	if b.readOnly {
+		b.logger.Println("Encryption at rest enabled for secret values (read-only)")
+		return nil
+	}
+	if err := b.encryptExistingSecrets(); err != nil {
+		return fmt.Errorf("failed to encrypt existing secrets: %w", err)
+	}
+
+	b.logger.Println("Encryption at rest enabled for secret values")
+	return nil
+}
+
+// EncryptionEnabled reports whether secret values are encrypted at rest
+func (b *BoltDB) EncryptionEnabled() bool {
+	return b.cipher != nil
+}
+
+// encryptExistingSecrets re-saves plaintext secrets written before encryption was enabled
+func (b *BoltDB) encryptExistingSecrets() error {
+	configs, err := b.GetAllConfig()
+	if err != nil {
+		return err
+	}
+	for key, entry := range configs {
+		if !IsSensitiveConfigKey(key) || entry.encrypted {
+			continue
+		}
+		if err := b.SaveConfig(key, entry.Value, entry.UpdatedBy); err != nil {
+			return err
+		}
+	}
+
+	webhooks, err := b.ListWebhooks()
+	if err != nil {
+		return err
+	}
+	for _, webhook := range webhooks {
+		if len(webhook.Headers) == 0 || webhook.headersEncrypted {
+			continue
+		}
+		if err := b.putWebhook(webhook); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encryptValue encrypts a secret value; it is a no-op when encryption is disabled
+func (b *BoltDB) encryptValue(plain string) (string, error) {
+	if b.cipher == nil || plain == "" {
+		return plain, nil
+	}
+	sealed, err := sealBytes(b.cipher, []byte(plain))
+	if err != nil {
+		return "", err
+	}
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptValue decrypts a value written by encryptValue. Plaintext values
+// (written before encryption was enabled) are returned unchanged.
+func (b *BoltDB) decryptValue(value string) (string, bool, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, false, nil
+	}
+	if b.cipher == nil {
+		return "", true, fmt.Errorf("value is encrypted but no encryption key is configured")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", true, fmt.Errorf("failed to decode encrypted value: %w", err)
+	}
+	plain, err := openBytes(b.cipher, sealed)
+	if err != nil {
+		return "", true, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plain), true, nil
+}
+
+// newGCM builds an AES-GCM cipher from a 16, 24 or 32 byte key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealBytes encrypts data and prepends the random nonce
+func sealBytes(aead cipher.AEAD, data []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+// openBytes decrypts data produced by sealBytes
+func openBytes(aead cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// encryptHeaders returns a copy of headers with every value encrypted
+func (b *BoltDB) encryptHeaders(headers map[string]string) (map[string]string, error) {
+	if b.cipher == nil || len(headers) == 0 {
+		return headers, nil
+	}
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		enc, err := b.encryptValue(v)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = enc
+	}
+	return out, nil
+}
+
+// decryptHeaders decrypts header values in place and reports whether any were encrypted
+func (b *BoltDB) decryptHeaders(headers map[string]string) (bool, error) {
+	encrypted := false
+	for k, v := range headers {
+		plain, wasEncrypted, err := b.decryptValue(v)
+		if err != nil {
+			return false, fmt.Errorf("header %s: %w", k, err)
+		}
+		encrypted = encrypted || wasEncrypted
+		headers[k] = plain
+	}
+	return encrypted, nil
+}
+
+// unmarshalWebhook decodes a stored webhook and decrypts its secret fields
+func (b *BoltDB) unmarshalWebhook(data []byte) (*Webhook, error) {
+	webhook := &Webhook{}
+	if err := msgpack.Unmarshal(data, webhook); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal webhook: %w", err)
+	}
+	encrypted, err := b.decryptHeaders(webhook.Headers)
+	if err != nil {
+		return nil, err
+	}
+	webhook.headersEncrypted = encrypted
+	return webhook, nil
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestEncryptionAtRest(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := NewBoltDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	// Plaintext written before encryption is enabled must be migrated
+	if err := db.SaveConfig("API_KEY", "secret-key", "env"); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	key := []byte(strings.Repeat("k", 32))
+	if err := db.EnableEncryption(key); err != nil {
+		t.Fatalf("EnableEncryption failed: %v", err)
+	}
+
+	webhook := &Webhook{URL: "https://example.com", Method: "POST", Headers: map[string]string{"Authorization": "Bearer abc"}}
+	if err := db.SaveWebhook(webhook); err != nil {
+		t.Fatalf("SaveWebhook failed: %v", err)
+	}
+
+	// Raw bucket contents must not contain plaintext secrets
+	db.DB().View(func(tx *bolt.Tx) error {
+		if raw := tx.Bucket([]byte(configBucket)).Get([]byte("API_KEY")); strings.Contains(string(raw), "secret-key") {
+			t.Error("API_KEY stored in plaintext")
+		}
+		if raw := tx.Bucket([]byte(webhooksBucket)).Get([]byte(webhook.ID)); strings.Contains(string(raw), "Bearer abc") {
+			t.Error("Webhook header stored in plaintext")
+		}
+		return nil
+	})
+
+	if webhook.Headers["Authorization"] != "Bearer abc" {
+		t.Error("SaveWebhook must not modify the in-memory headers")
+	}
+
+	db.Close()
+
+	// Reopen with the same key and read back plaintext
+	db, err = NewBoltDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.EnableEncryption(key); err != nil {
+		t.Fatalf("EnableEncryption on reopen failed: %v", err)
+	}
+
+	entry, err := db.GetConfig("API_KEY")
+	if err != nil || entry.Value != "secret-key" {
+		t.Errorf("Expected decrypted API_KEY, got %v (err %v)", entry, err)
+	}
+
+	got, err := db.GetWebhook(webhook.ID)
+	if err != nil || got.Headers["Authorization"] != "Bearer abc" {
+		t.Errorf("Expected decrypted webhook header, got %v (err %v)", got, err)
+	}
+
+	if err := db.EnableEncryption([]byte(strings.Repeat("x", 32))); err == nil {
+		t.Error("Expected error when enabling encryption with a different master key")
+	}
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// ExportFormatVersion is bumped when the Export layout changes incompatibly. Version 1 kept
+// chat and webhook links apart; Import still reads it.
+const ExportFormatVersion = 2
+
+// Export is a portable copy of the monitoring setup: sources (trashed ones included),
+// notification sinks, their links to sources, namespaces and the non-secret config. History,
+// API keys and secrets (TELEGRAM_TOKEN, API_KEY) are not included; webhook headers are, in
+// plain text.
+type Export struct {
+	FormatVersion  int               `json:"format_version"`
+	ExportedAt     time.Time         `json:"exported_at"`
+	Sources        []*Source         `json:"sources"`
+	Webhooks       []*Webhook        `json:"webhooks"`
+	Chats          []*Chat           `json:"chats"`
+	Namespaces     []*Namespace      `json:"namespaces,omitempty"`
+	SourceSinks    []SourceSink      `json:"source_sinks"`
+	SourceWebhooks []SourceWebhook   `json:"source_webhooks,omitempty"` // Version 1 only
+	SourceChats    []SourceChat      `json:"source_chats,omitempty"`    // Version 1 only
+	Config         map[string]string `json:"config"`
+}
+
+// ImportResult counts what Import wrote
+type ImportResult struct {
+	Sources    int `json:"sources"`
+	Webhooks   int `json:"webhooks"`
+	Chats      int `json:"chats"`
+	Namespaces int `json:"namespaces,omitempty"`
+	Links      int `json:"links"`
+	Config     int `json:"config"`
+}
+
+// Export reads the monitoring setup for backup or migration to another instance
+func (b *BoltDB) Export() (*Export, error) {
+	export := &Export{
+		FormatVersion: ExportFormatVersion,
+		ExportedAt:    time.Now().UTC(),
+		Config:        make(map[string]string),
+	}
+
+	active, err := b.GetAllSources()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sources: %w", err)
+	}
+	deleted, err := b.GetDeletedSources()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deleted sources: %w", err)
+	}
+	export.Sources = append(active, deleted...)
+
+	if export.Webhooks, err = b.ListWebhooks(); err != nil {
+		return nil, fmt.Errorf("failed to read webhooks: %w", err)
+	}
+	if export.Chats, err = b.ListChats(); err != nil {
+		return nil, fmt.Errorf("failed to read chats: %w", err)
+	}
+	if export.Namespaces, err = b.ListNamespaces(); err != nil {
+		return nil, fmt.Errorf("failed to read namespaces: %w", err)
+	}
+
+	for _, source := range export.Sources {
+		sinkIDs, err := b.GetSourceSinkIDs(source.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sinks of source %s: %w", source.ID, err)
+		}
+		for _, sinkID := range sinkIDs {
+			export.SourceSinks = append(export.SourceSinks, SourceSink{SourceID: source.ID, SinkID: sinkID})
+		}
+	}
+
+	entries, err := b.GetAllConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	for key, entry := range entries {
+		if !IsSensitiveConfigKey(key) {
+			export.Config[key] = entry.Value
+		}
+	}
+
+	return export, nil
+}
+
+// Import writes an export into the database. Records are matched by ID and overwritten;
+// records missing from the export are kept, so importing is safe to repeat.
+func (b *BoltDB) Import(export *Export) (*ImportResult, error) {
+	if export.FormatVersion != 1 && export.FormatVersion != ExportFormatVersion {
+		return nil, fmt.Errorf("unsupported export format version %d (expected %d)", export.FormatVersion, ExportFormatVersion)
+	}
+
+	result := &ImportResult{}
+	for _, ns := range export.Namespaces {
+		if err := b.SaveNamespace(ns); err != nil {
+			return result, err
+		}
+		result.Namespaces++
+	}
+	if len(export.Sources) > 0 {
+		if err := b.SaveSources(export.Sources); err != nil {
+			return result, err
+		}
+		result.Sources = len(export.Sources)
+	}
+	for _, webhook := range export.Webhooks {
+		if err := b.SaveWebhook(webhook); err != nil {
+			return result, err
+		}
+		result.Webhooks++
+	}
+	for _, chat := range export.Chats {
+		if err := b.SaveChat(chat); err != nil {
+			return result, err
+		}
+		result.Chats++
+	}
+	for _, link := range export.SourceSinks {
+		if err := b.AddSourceSink(link.SourceID, link.SinkID); err != nil {
+			return result, err
+		}
+		result.Links++
+	}
+	for _, link := range export.SourceWebhooks {
+		if err := b.AddSourceWebhook(link.SourceID, link.WebhookID); err != nil {
+			return result, err
+		}
+		result.Links++
+	}
+	for _, link := range export.SourceChats {
+		if err := b.AddSourceChat(link.SourceID, link.ChatID); err != nil {
+			return result, err
+		}
+		result.Links++
+	}
+	for key, value := range export.Config {
+		if IsSensitiveConfigKey(key) {
+			continue
+		}
+		if err := b.SaveConfig(key, value, "import"); err != nil {
+			return result, err
+		}
+		result.Config++
+	}
+
+	return result, nil
+}
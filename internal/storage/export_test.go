@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExportImport(t *testing.T) {
+	dir := t.TempDir()
+	src, err := NewBoltDB(filepath.Join(dir, "src.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer src.Close()
+
+	source := &Source{Name: "api", Type: "http", Target: "https://example.com", CheckInterval: time.Minute, Enabled: true}
+	if err := src.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+	webhook := &Webhook{Name: "ops", URL: "https://hooks.example.com", Method: "POST", Headers: map[string]string{"Authorization": "Bearer x"}, Enabled: true}
+	if err := src.SaveWebhook(webhook); err != nil {
+		t.Fatalf("SaveWebhook failed: %v", err)
+	}
+	if err := src.SaveChat(&Chat{ChatID: 42, Name: "ops chat"}); err != nil {
+		t.Fatalf("SaveChat failed: %v", err)
+	}
+	if err := src.AddSourceWebhook(source.ID, webhook.ID); err != nil {
+		t.Fatalf("AddSourceWebhook failed: %v", err)
+	}
+	if err := src.AddSourceChat(source.ID, 42); err != nil {
+		t.Fatalf("AddSourceChat failed: %v", err)
+	}
+	if err := src.SaveConfig("PING_COUNT", "5", "test"); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	if err := src.SaveConfig("TELEGRAM_TOKEN", "secret", "test"); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	export, err := src.Export()
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if _, ok := export.Config["TELEGRAM_TOKEN"]; ok {
+		t.Error("Expected secrets to be left out of the export")
+	}
+	if len(export.Sources) != 1 || len(export.SourceSinks) != 2 {
+		t.Fatalf("Expected one source with one webhook and one chat link, got %+v", export)
+	}
+
+	dst, err := NewBoltDB(filepath.Join(dir, "dst.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer dst.Close()
+
+	// Importing twice must not duplicate anything
+	for i := 0; i < 2; i++ {
+		result, err := dst.Import(export)
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if result.Sources != 1 || result.Webhooks != 1 || result.Chats != 1 || result.Links != 2 || result.Config != 1 {
+			t.Errorf("Unexpected import result: %+v", result)
+		}
+	}
+
+	imported, err := dst.GetSource(source.ID)
+	if err != nil || imported.Target != source.Target {
+		t.Errorf("Expected source to be imported, got %+v, %v", imported, err)
+	}
+	if webhooks, _ := dst.GetSourceWebhooks(source.ID); len(webhooks) != 1 || webhooks[0].Headers["Authorization"] != "Bearer x" {
+		t.Errorf("Expected webhook link with headers, got %+v", webhooks)
+	}
+	if chats, _ := dst.GetSourceChats(source.ID); len(chats) != 1 || chats[0] != 42 {
+		t.Errorf("Expected chat link, got %v", chats)
+	}
+	if entry, _ := dst.GetConfig("PING_COUNT"); entry == nil || entry.Value != "5" || entry.UpdatedBy != "import" {
+		t.Errorf("Expected imported config, got %+v", entry)
+	}
+	if entry, _ := dst.GetConfig("TELEGRAM_TOKEN"); entry != nil {
+		t.Errorf("Expected no secret to be imported, got %+v", entry)
+	}
+
+	// Version 1 exports kept chat and webhook links apart
+	legacy := &Export{FormatVersion: 1, SourceChats: []SourceChat{{SourceID: source.ID, ChatID: 43}}}
+	if result, err := dst.Import(legacy); err != nil || result.Links != 1 {
+		t.Errorf("Expected a version 1 export to import, got %+v, %v", result, err)
+	}
+	if chats, _ := dst.GetSourceChats(source.ID); len(chats) != 2 {
+		t.Errorf("Expected the version 1 chat link to be added, got %v", chats)
+	}
+
+	export.FormatVersion = ExportFormatVersion + 1
+	if _, err := dst.Import(export); err == nil {
+		t.Error("Expected error for an unknown format version")
+	}
+}
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewBoltDB(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	source := &Source{Name: "router", Type: "ping", Target: "192.168.1.1", Enabled: true}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+
+	backupPath := filepath.Join(dir, "backup.db")
+	if err := db.Backup(backupPath); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	restored, err := NewBoltDB(backupPath)
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	defer restored.Close()
+	if got, err := restored.GetSource(source.ID); err != nil || got.Name != "router" {
+		t.Errorf("Expected source in backup, got %+v, %v", got, err)
+	}
+}
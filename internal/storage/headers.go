@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"fmt"
+	"net/textproto"
+	"strings"
+)
+
+// maxHeaders bounds the request headers of a single http source
+const maxHeaders = 20
+
+// maxHeaderValueLength bounds a single header value
+const maxHeaderValueLength = 1000
+
+// NormalizeHeaders validates the request headers of an http source and canonicalizes their
+// names, so "user-agent" and "User-Agent" are the same header. Values are trimmed; an empty
+// value removes a header HTTP_HEADERS or HTTP_USER_AGENT would set.
+func NormalizeHeaders(headers map[string]string) (map[string]string, error) {
+	if len(headers) > maxHeaders {
+		return nil, fmt.Errorf("at most %d headers are allowed", maxHeaders)
+	}
+	normalized := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if !isHeaderName(name) {
+			return nil, fmt.Errorf("invalid header name %q", name)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) > maxHeaderValueLength || strings.ContainsAny(value, "\r\n\x00") {
+			return nil, fmt.Errorf("header %s: value must be a single line of at most %d characters", name, maxHeaderValueLength)
+		}
+		canonical := textproto.CanonicalMIMEHeaderKey(name)
+		if _, ok := normalized[canonical]; ok {
+			return nil, fmt.Errorf("header %s is set twice", canonical)
+		}
+		normalized[canonical] = value
+	}
+	return normalized, nil
+}
+
+// isHeaderName reports whether name is an HTTP token (RFC 9110)
+func isHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c > '~' || !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", c)) {
+			return false
+		}
+	}
+	return true
+}
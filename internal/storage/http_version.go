@@ -0,0 +1,18 @@
+package storage
+
+import "errors"
+
+// HTTP versions an http source can require. Empty means whatever the server negotiates.
+const (
+	HTTPVersion2           = "2"             // Only HTTP/2: ALPN h2 over TLS, prior knowledge (h2c) over plain HTTP
+	HTTPVersion3Advertised = "h3-advertised" // HTTP/3 advertised in Alt-Svc; the check itself doesn't use QUIC
+)
+
+// ValidateHTTPVersion checks a required HTTP version, allowing empty for none
+func ValidateHTTPVersion(version string) error {
+	switch version {
+	case "", HTTPVersion2, HTTPVersion3Advertised:
+		return nil
+	}
+	return errors.New(`http_version must be "2" or "h3-advertised"`)
+}
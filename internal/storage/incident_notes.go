@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// MaxIncidentNoteLength caps the text of an incident note, in characters
+const MaxIncidentNoteLength = 1000
+
+// ErrNoOutage is returned when a note can't be attached because the source has no outage
+// recorded at or before the requested time
+var ErrNoOutage = errors.New("no outage recorded for the source")
+
+// IncidentNote is an operator's comment on an outage, e.g. "ISP confirmed fiber cut, ETA 2h".
+// An outage is identified by the status change that started it.
+type IncidentNote struct {
+	ID          string    `msgpack:"id" json:"id"`
+	SourceID    string    `msgpack:"source_id" json:"source_id"`
+	ChangeID    string    `msgpack:"change_id" json:"change_id"`       // Status change that started the outage
+	OutageStart time.Time `msgpack:"outage_start" json:"outage_start"` // Timestamp of that change
+	Text        string    `msgpack:"text" json:"text"`
+	Author      string    `msgpack:"author" json:"author"` // API key name or Telegram user
+	CreatedAt   time.Time `msgpack:"created_at" json:"created_at"`
+}
+
+// makeIncidentNoteKey orders a source's notes by outage, then by creation:
+// sourceID + ":" + outage start (ns) + created at (ns)
+func makeIncidentNoteKey(note *IncidentNote) []byte {
+	key := make([]byte, 0, len(note.SourceID)+17)
+	key = append(key, note.SourceID+":"...)
+	key = binary.BigEndian.AppendUint64(key, uint64(note.OutageStart.UnixNano()))
+	return binary.BigEndian.AppendUint64(key, uint64(note.CreatedAt.UnixNano()))
+}
+
+// FindOutage returns the status change that started the latest outage of a source at or
+// before at: the outage in progress then, or the last one that ended before it
+func (b *BoltDB) FindOutage(sourceID string, at time.Time) (*StatusChange, error) {
+	const page = 100
+	to := at.Add(time.Nanosecond)
+	for {
+		changes, err := b.GetStatusChanges(sourceID, time.Time{}, to, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range changes {
+			if change.NewStatus == 0 {
+				return change, nil
+			}
+		}
+		if len(changes) < page {
+			return nil, ErrNoOutage
+		}
+		to = changes[len(changes)-1].Timestamp
+	}
+}
+
+// AddIncidentNote stores a note on the outage started by change, setting its ID and CreatedAt
+func (b *BoltDB) AddIncidentNote(change *StatusChange, note *IncidentNote) error {
+	note.Text = strings.TrimSpace(note.Text)
+	if note.Text == "" {
+		return fmt.Errorf("note text is required")
+	}
+	if len([]rune(note.Text)) > MaxIncidentNoteLength {
+		return fmt.Errorf("note text is longer than %d characters", MaxIncidentNoteLength)
+	}
+
+	note.ID = uuid.New().String()
+	note.SourceID = change.SourceID
+	note.ChangeID = change.ID
+	note.OutageStart = change.Timestamp
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = time.Now()
+	}
+
+	data, err := msgpack.Marshal(note)
+	if err != nil {
+		return fmt.Errorf("failed to marshal incident note: %w", err)
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(incidentNotesBucket))
+		if bucket == nil {
+			return fmt.Errorf("incident notes bucket not found")
+		}
+		if err := bucket.Put(makeIncidentNoteKey(note), data); err != nil {
+			return fmt.Errorf("failed to save incident note: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetIncidentNotes returns the notes on outages of a source that started in [from, to),
+// oldest outage first and in the order they were written. Zero bounds are open.
+func (b *BoltDB) GetIncidentNotes(sourceID string, from, to time.Time) ([]*IncidentNote, error) {
+	notes := []*IncidentNote{}
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(incidentNotesBucket))
+		if bucket == nil {
+			return fmt.Errorf("incident notes bucket not found")
+		}
+
+		prefix := []byte(sourceID + ":")
+		start := prefix
+		if !from.IsZero() {
+			start = binary.BigEndian.AppendUint64(append([]byte(nil), prefix...), uint64(from.UnixNano()))
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(start); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var note IncidentNote
+			if err := msgpack.Unmarshal(v, &note); err != nil {
+				b.logger.Errorf("Failed to unmarshal incident note: %v", err)
+				continue
+			}
+			if !to.IsZero() && !note.OutageStart.Before(to) {
+				break
+			}
+			notes = append(notes, &note)
+		}
+		return nil
+	})
+
+	return notes, err
+}
+
+// DeleteIncidentNote removes a note of a source
+func (b *BoltDB) DeleteIncidentNote(sourceID, id string) error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(incidentNotesBucket))
+		if bucket == nil {
+			return fmt.Errorf("incident notes bucket not found")
+		}
+
+		prefix := []byte(sourceID + ":")
+		c := bucket.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var note IncidentNote
+			if err := msgpack.Unmarshal(v, &note); err == nil && note.ID == id {
+				return bucket.Delete(k)
+			}
+		}
+		return fmt.Errorf("incident note not found: %s", id)
+	})
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIncidentNotes(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	source := &Source{Name: "router", Type: "ping", Target: "192.168.1.1", Enabled: true}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+
+	if _, err := db.FindOutage(source.ID, time.Now()); !errors.Is(err, ErrNoOutage) {
+		t.Errorf("Expected ErrNoOutage without history, got %v", err)
+	}
+
+	base := time.Now().Add(-10 * time.Hour).Truncate(time.Second)
+	for i, status := range []int{0, 1, 0, 1} {
+		change := &StatusChange{ID: string(rune('a' + i)), SourceID: source.ID, OldStatus: 1 - status, NewStatus: status, Timestamp: base.Add(time.Duration(i) * time.Hour)}
+		if err := db.SaveStatusChange(change); err != nil {
+			t.Fatalf("SaveStatusChange failed: %v", err)
+		}
+	}
+
+	// After the second outage ended, the latest one is still found; during the first, that one
+	latest, err := db.FindOutage(source.ID, time.Now())
+	if err != nil || latest.ID != "c" {
+		t.Fatalf("Expected the second outage, got %+v (%v)", latest, err)
+	}
+	first, err := db.FindOutage(source.ID, base.Add(30*time.Minute))
+	if err != nil || first.ID != "a" {
+		t.Fatalf("Expected the first outage, got %+v (%v)", first, err)
+	}
+
+	if err := db.AddIncidentNote(latest, &IncidentNote{Text: "  ISP confirmed fiber cut, ETA 2h ", Author: "ops"}); err != nil {
+		t.Fatalf("AddIncidentNote failed: %v", err)
+	}
+	if err := db.AddIncidentNote(first, &IncidentNote{Text: "power blip", Author: "ops"}); err != nil {
+		t.Fatalf("AddIncidentNote failed: %v", err)
+	}
+	if err := db.AddIncidentNote(first, &IncidentNote{Text: " "}); err == nil {
+		t.Error("Expected an empty note to be rejected")
+	}
+	if err := db.AddIncidentNote(first, &IncidentNote{Text: strings.Repeat("x", MaxIncidentNoteLength+1)}); err == nil {
+		t.Error("Expected an overlong note to be rejected")
+	}
+
+	notes, err := db.GetIncidentNotes(source.ID, time.Time{}, time.Time{})
+	if err != nil || len(notes) != 2 || notes[0].ChangeID != "a" || notes[1].Text != "ISP confirmed fiber cut, ETA 2h" {
+		t.Fatalf("Expected both notes, oldest outage first, got %+v (%v)", notes, err)
+	}
+	if !notes[1].OutageStart.Equal(latest.Timestamp) {
+		t.Errorf("Expected the note to record the outage start, got %v", notes[1].OutageStart)
+	}
+	if ranged, _ := db.GetIncidentNotes(source.ID, base.Add(time.Hour), time.Time{}); len(ranged) != 1 || ranged[0].ChangeID != "c" {
+		t.Errorf("Expected only the second outage's note from its range, got %+v", ranged)
+	}
+
+	if err := db.DeleteIncidentNote(source.ID, notes[0].ID); err != nil {
+		t.Fatalf("DeleteIncidentNote failed: %v", err)
+	}
+	if err := db.DeleteIncidentNote(source.ID, notes[0].ID); err == nil {
+		t.Error("Expected deleting a missing note to fail")
+	}
+
+	if err := db.PurgeSource(source.ID); err != nil {
+		t.Fatalf("PurgeSource failed: %v", err)
+	}
+	if remaining, _ := db.GetIncidentNotes(source.ID, time.Time{}, time.Time{}); len(remaining) != 0 {
+		t.Errorf("Expected purge to remove the notes, got %+v", remaining)
+	}
+}
@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Maintenance window recurrences
+const (
+	RecurNone    = ""
+	RecurDaily   = "daily"
+	RecurWeekly  = "weekly"
+	RecurMonthly = "monthly"
+)
+
+// MaintenanceWindow is a planned period during which status change notifications of its
+// sources are suppressed. A window without sources or tags covers every source.
+type MaintenanceWindow struct {
+	ID        string    `msgpack:"id" json:"id"`
+	Name      string    `msgpack:"name" json:"name"`
+	SourceIDs []string  `msgpack:"source_ids" json:"source_ids,omitempty"`
+	Tags      []string  `msgpack:"tags" json:"tags,omitempty"` // Normalized with NormalizeTags
+	Start     time.Time `msgpack:"start" json:"start"`
+	// End of the (first) occurrence; nil keeps a one-off window open until it is closed
+	End *time.Time `msgpack:"end" json:"end,omitempty"`
+	// Recurrence repeats the first occurrence daily, weekly or monthly (on the same day, or the
+	// last day of shorter months) until RecurUntil, if set
+	Recurrence string     `msgpack:"recurrence" json:"recurrence,omitempty"`
+	RecurUntil *time.Time `msgpack:"recur_until" json:"recur_until,omitempty"`
+	CreatedAt  time.Time  `msgpack:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `msgpack:"updated_at" json:"updated_at"`
+}
+
+// MaintenanceSpan is one occurrence of a maintenance window
+type MaintenanceSpan struct {
+	Start time.Time
+	End   time.Time // Zero for an open-ended window
+}
+
+// ValidRecurrence reports whether recurrence is empty, daily, weekly or monthly
+func ValidRecurrence(recurrence string) bool {
+	return slices.Contains([]string{RecurNone, RecurDaily, RecurWeekly, RecurMonthly}, recurrence)
+}
+
+// Validate checks the window's schedule
+func (w *MaintenanceWindow) Validate() error {
+	if w.Start.IsZero() {
+		return fmt.Errorf("start is required")
+	}
+	if w.End != nil && !w.End.After(w.Start) {
+		return fmt.Errorf("end must be after start")
+	}
+	if !ValidRecurrence(w.Recurrence) {
+		return fmt.Errorf("invalid recurrence %q (use daily, weekly or monthly)", w.Recurrence)
+	}
+	if w.Recurrence != RecurNone {
+		if w.End == nil {
+			return fmt.Errorf("recurring windows need an end")
+		}
+		if w.End.Sub(w.Start) > w.minPeriod() {
+			return fmt.Errorf("occurrences of a %s window can't overlap", w.Recurrence)
+		}
+	} else if w.RecurUntil != nil {
+		return fmt.Errorf("recur_until needs a recurrence")
+	}
+	return nil
+}
+
+// minPeriod is the shortest time between two occurrences
+func (w *MaintenanceWindow) minPeriod() time.Duration {
+	switch w.Recurrence {
+	case RecurDaily:
+		return 24 * time.Hour
+	case RecurWeekly:
+		return 7 * 24 * time.Hour
+	case RecurMonthly:
+		return 28 * 24 * time.Hour
+	}
+	return 0
+}
+
+// occurrenceStart returns the start of the nth occurrence (0 is the first)
+func (w *MaintenanceWindow) occurrenceStart(n int) time.Time {
+	switch w.Recurrence {
+	case RecurDaily:
+		return w.Start.AddDate(0, 0, n)
+	case RecurWeekly:
+		return w.Start.AddDate(0, 0, 7*n)
+	case RecurMonthly:
+		// Same day of the month, or the last day of shorter months
+		first := time.Date(w.Start.Year(), w.Start.Month()+time.Month(n), 1, 0, 0, 0, 0, w.Start.Location())
+		day := min(w.Start.Day(), first.AddDate(0, 1, -1).Day())
+		return time.Date(first.Year(), first.Month(), day, w.Start.Hour(), w.Start.Minute(), w.Start.Second(), w.Start.Nanosecond(), w.Start.Location())
+	}
+	return w.Start
+}
+
+// Occurrences returns the occurrences that overlap [from, to), oldest first
+func (w *MaintenanceWindow) Occurrences(from, to time.Time) []MaintenanceSpan {
+	if w.Recurrence == RecurNone {
+		if !w.Start.Before(to) || (w.End != nil && !w.End.After(from)) {
+			return nil
+		}
+		span := MaintenanceSpan{Start: w.Start}
+		if w.End != nil {
+			span.End = *w.End
+		}
+		return []MaintenanceSpan{span}
+	}
+
+	length := w.End.Sub(w.Start)
+	// Skip ahead to just before from; months vary in length, so this is an estimate
+	n := 0
+	if gap := from.Sub(w.Start) - length; gap > 0 {
+		n = int(gap/w.minPeriod()) - 1
+		if w.Recurrence == RecurMonthly {
+			n = int(gap/(31*24*time.Hour)) - 1
+		}
+		n = max(n, 0)
+	}
+
+	var spans []MaintenanceSpan
+	for ; ; n++ {
+		start := w.occurrenceStart(n)
+		if !start.Before(to) || (w.RecurUntil != nil && !start.Before(*w.RecurUntil)) {
+			break
+		}
+		if end := start.Add(length); end.After(from) {
+			spans = append(spans, MaintenanceSpan{Start: start, End: end})
+		}
+	}
+	return spans
+}
+
+// ActiveAt reports whether an occurrence of the window covers at
+func (w *MaintenanceWindow) ActiveAt(at time.Time) bool {
+	return len(w.Occurrences(at, at.Add(time.Nanosecond))) > 0
+}
+
+// NextStart returns the start of the first occurrence after at, or nil when there is none
+func (w *MaintenanceWindow) NextStart(at time.Time) *time.Time {
+	if w.Recurrence == RecurNone {
+		if w.Start.After(at) {
+			start := w.Start
+			return &start
+		}
+		return nil
+	}
+	for _, span := range w.Occurrences(at, at.Add(w.minPeriod()+31*24*time.Hour)) {
+		if span.Start.After(at) {
+			return &span.Start
+		}
+	}
+	return nil
+}
+
+// Covers reports whether the window applies to source
+func (w *MaintenanceWindow) Covers(source *Source) bool {
+	if len(w.SourceIDs) == 0 && len(w.Tags) == 0 {
+		return true
+	}
+	if slices.Contains(w.SourceIDs, source.ID) {
+		return true
+	}
+	for _, tag := range w.Tags {
+		if source.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveMaintenanceWindow creates or replaces a maintenance window
+func (b *BoltDB) SaveMaintenanceWindow(window *MaintenanceWindow) error {
+	if window.ID == "" {
+		window.ID = uuid.New().String()
+	}
+	if window.CreatedAt.IsZero() {
+		window.CreatedAt = time.Now()
+	}
+	window.UpdatedAt = time.Now()
+
+	data, err := msgpack.Marshal(window)
+	if err != nil {
+		return fmt.Errorf("failed to marshal maintenance window: %w", err)
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(maintenanceBucket))
+		if bucket == nil {
+			return fmt.Errorf("maintenance bucket not found")
+		}
+		if err := bucket.Put([]byte(window.ID), data); err != nil {
+			return fmt.Errorf("failed to save maintenance window: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetMaintenanceWindow retrieves a maintenance window by ID
+func (b *BoltDB) GetMaintenanceWindow(id string) (*MaintenanceWindow, error) {
+	var window MaintenanceWindow
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(maintenanceBucket))
+		if bucket == nil {
+			return fmt.Errorf("maintenance bucket not found")
+		}
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("maintenance window not found")
+		}
+		return msgpack.Unmarshal(data, &window)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// GetMaintenanceWindows retrieves all maintenance windows, by start
+func (b *BoltDB) GetMaintenanceWindows() ([]*MaintenanceWindow, error) {
+	var windows []*MaintenanceWindow
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(maintenanceBucket))
+		if bucket == nil {
+			return fmt.Errorf("maintenance bucket not found")
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var window MaintenanceWindow
+			if err := msgpack.Unmarshal(v, &window); err != nil {
+				b.logger.Errorf("Failed to unmarshal maintenance window: %v", err)
+				return nil // Skip malformed windows
+			}
+			windows = append(windows, &window)
+			return nil
+		})
+	})
+
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+	return windows, err
+}
+
+// DeleteMaintenanceWindow removes a maintenance window
+func (b *BoltDB) DeleteMaintenanceWindow(id string) error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(maintenanceBucket))
+		if bucket == nil {
+			return fmt.Errorf("maintenance bucket not found")
+		}
+		if bucket.Get([]byte(id)) == nil {
+			return fmt.Errorf("maintenance window not found")
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// InMaintenance returns the first maintenance window covering source at the given time, or nil
+func (b *BoltDB) InMaintenance(source *Source, at time.Time) (*MaintenanceWindow, error) {
+	windows, err := b.GetMaintenanceWindows()
+	if err != nil {
+		return nil, err
+	}
+	for _, window := range windows {
+		if window.Covers(source) && window.ActiveAt(at) {
+			return window, nil
+		}
+	}
+	return nil, nil
+}
+
+// maintenanceSpans returns the merged maintenance occurrences covering source within
+// [from, to), clipped to it, oldest first
+func (b *BoltDB) maintenanceSpans(source *Source, from, to time.Time) ([]MaintenanceSpan, error) {
+	windows, err := b.GetMaintenanceWindows()
+	if err != nil {
+		return nil, err
+	}
+
+	var spans []MaintenanceSpan
+	for _, window := range windows {
+		if !window.Covers(source) {
+			continue
+		}
+		for _, span := range window.Occurrences(from, to) {
+			if span.Start.Before(from) {
+				span.Start = from
+			}
+			if span.End.IsZero() || span.End.After(to) {
+				span.End = to
+			}
+			spans = append(spans, span)
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start.Before(spans[j].Start) })
+
+	merged := spans[:0]
+	for _, span := range spans {
+		if n := len(merged); n > 0 && !span.Start.After(merged[n-1].End) {
+			if span.End.After(merged[n-1].End) {
+				merged[n-1].End = span.End
+			}
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged, nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowOccurrences(t *testing.T) {
+	start := time.Date(2026, 1, 31, 22, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	until := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	monthly := &MaintenanceWindow{Start: start, End: &end, Recurrence: RecurMonthly, RecurUntil: &until}
+	if err := monthly.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	spans := monthly.Occurrences(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 monthly occurrences before recur_until, got %+v", spans)
+	}
+	// The 31st falls on the last day of February
+	if !spans[1].Start.Equal(time.Date(2026, 2, 28, 22, 0, 0, 0, time.UTC)) || !spans[2].Start.Equal(time.Date(2026, 3, 31, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected occurrences on February 28 and March 31, got %+v", spans)
+	}
+
+	weekly := &MaintenanceWindow{Start: start, End: &end, Recurrence: RecurWeekly}
+	if !weekly.ActiveAt(start.AddDate(0, 0, 70).Add(time.Hour)) {
+		t.Error("Expected the weekly window to be active 10 weeks later")
+	}
+	if weekly.ActiveAt(start.AddDate(0, 0, 70).Add(3 * time.Hour)) {
+		t.Error("Expected the weekly window to be inactive after the occurrence ends")
+	}
+	if next := weekly.NextStart(start.Add(time.Hour)); next == nil || !next.Equal(start.AddDate(0, 0, 7)) {
+		t.Errorf("Expected the next start a week later, got %v", next)
+	}
+
+	open := &MaintenanceWindow{Start: start}
+	if !open.ActiveAt(start.AddDate(1, 0, 0)) || open.ActiveAt(start.Add(-time.Second)) {
+		t.Error("Expected an open-ended window to be active from its start on")
+	}
+
+	for _, invalid := range []*MaintenanceWindow{
+		{},
+		{Start: start, End: &start},
+		{Start: start, Recurrence: RecurDaily},
+		{Start: start, End: &end, Recurrence: "hourly"},
+		{Start: start, End: &until, Recurrence: RecurDaily},
+		{Start: start, End: &end, RecurUntil: &until},
+	} {
+		if invalid.Validate() == nil {
+			t.Errorf("Expected %+v to be invalid", invalid)
+		}
+	}
+}
+
+func TestMaintenanceWindows(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	source := &Source{ID: "api", Name: "API", Tags: []string{"prod"}, CurrentStatus: 1, CreatedAt: from.Add(-time.Hour)}
+	other := &Source{ID: "web", Name: "Web", CurrentStatus: 1, CreatedAt: from.Add(-time.Hour)}
+	db.SaveSource(source)
+	db.SaveSource(other)
+	db.SaveStatusChange(&StatusChange{SourceID: "api", OldStatus: -1, NewStatus: 1, Timestamp: from.Add(-time.Hour)})
+	db.SaveStatusChange(&StatusChange{SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(3 * time.Hour)})
+	db.SaveStatusChange(&StatusChange{SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(5 * time.Hour)})
+
+	// Deploy of prod sources from 2h to 4h
+	end := from.Add(4 * time.Hour)
+	window := &MaintenanceWindow{Name: "Deploy", Tags: []string{"prod"}, Start: from.Add(2 * time.Hour), End: &end}
+	if err := db.SaveMaintenanceWindow(window); err != nil {
+		t.Fatalf("SaveMaintenanceWindow failed: %v", err)
+	}
+	if window.ID == "" {
+		t.Fatal("Expected an ID to be assigned")
+	}
+
+	if active, err := db.InMaintenance(source, from.Add(3*time.Hour)); err != nil || active == nil || active.ID != window.ID {
+		t.Errorf("Expected the prod source to be in maintenance at 3h, got %v (%v)", active, err)
+	}
+	if active, _ := db.InMaintenance(other, from.Add(3*time.Hour)); active != nil {
+		t.Error("Expected the untagged source not to be in maintenance")
+	}
+
+	segments, err := db.GetTimeline(source, from, from.Add(6*time.Hour))
+	if err != nil {
+		t.Fatalf("GetTimeline failed: %v", err)
+	}
+	expected := []string{SegmentUp, SegmentMaintenance, SegmentDown, SegmentUp}
+	if len(segments) != len(expected) {
+		t.Fatalf("Expected %v, got %+v", expected, segments)
+	}
+	for i, state := range expected {
+		if segments[i].State != state {
+			t.Errorf("Segment %d: expected %s, got %+v", i, state, segments[i])
+		}
+	}
+	if !segments[1].Start.Equal(from.Add(2*time.Hour)) || !segments[2].Start.Equal(end) {
+		t.Errorf("Expected maintenance from 2h to 4h, got %+v", segments[1])
+	}
+
+	if err := db.DeleteMaintenanceWindow(window.ID); err != nil {
+		t.Fatalf("DeleteMaintenanceWindow failed: %v", err)
+	}
+	if windows, _ := db.GetMaintenanceWindows(); len(windows) != 0 {
+		t.Errorf("Expected no windows after delete, got %d", len(windows))
+	}
+}
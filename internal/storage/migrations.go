@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// schemaVersionName is the meta bucket key holding the current schema version
+const schemaVersionName = "schema_version"
+
+// Buckets retired by migration 3, which moved their links to source_sinks. initBuckets
+// still creates them for databases below version 3, which migrations 2 and 3 read.
+const (
+	sourceChatsBucket    = "source_chats"
+	chatSourcesBucket    = "chat_sources" // reverse index of source_chats keyed by chat ID
+	sourceWebhooksBucket = "source_webhooks"
+)
+
+// sinksSchemaVersion is the version from which links are stored in source_sinks
+const sinksSchemaVersion = 3
+
+// migration transforms existing data from version-1 to version.
+// Each migration runs in its own transaction together with the version bump,
+// so a failed migration leaves the database at the previous version.
+type migration struct {
+	version     int
+	description string
+	apply       func(tx *bolt.Tx) error
+}
+
+// migrations lists all schema migrations in ascending version order.
+// Append new entries here when changing Source/StatusChange encodings or key formats;
+// never edit or reorder migrations that have already been released.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "baseline schema (sources, status changes, config, chats, webhooks)",
+		apply:       func(tx *bolt.Tx) error { return nil },
+	},
+	{
+		version:     2,
+		description: "build chat_sources reverse index from source_chats",
+		apply:       buildChatSourcesIndex,
+	},
+	{
+		version:     sinksSchemaVersion,
+		description: "move source_chats and source_webhooks links to source_sinks",
+		apply:       moveLinksToSinks,
+	},
+}
+
+// latestSchemaVersion returns the version the database is migrated to on startup
+func latestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].version
+}
+
+// SchemaVersion returns the schema version currently stored in the database
+func (b *BoltDB) SchemaVersion() (int, error) {
+	version := 0
+	err := b.view(func(tx *bolt.Tx) error {
+		version = readSchemaVersion(tx)
+		return nil
+	})
+	return version, err
+}
+
+// runMigrations applies all pending migrations in order
+func (b *BoltDB) runMigrations() error {
+	current, err := b.SchemaVersion()
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	if current > latestSchemaVersion() {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", current, latestSchemaVersion())
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		b.logger.Printf("Applying migration %d: %s", m.version, m.description)
+		err := b.update(func(tx *bolt.Tx) error {
+			if err := m.apply(tx); err != nil {
+				return err
+			}
+			return writeSchemaVersion(tx, m.version)
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d failed: %w", m.version, err)
+		}
+		current = m.version
+	}
+
+	return nil
+}
+
+// readSchemaVersion reads the schema version inside a transaction (0 if unset)
+func readSchemaVersion(tx *bolt.Tx) int {
+	bucket := tx.Bucket([]byte(metaBucket))
+	if bucket == nil {
+		return 0
+	}
+	data := bucket.Get([]byte(schemaVersionName))
+	if len(data) != 8 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(data))
+}
+
+// writeSchemaVersion stores the schema version inside a transaction
+func writeSchemaVersion(tx *bolt.Tx, version int) error {
+	bucket := tx.Bucket([]byte(metaBucket))
+	if bucket == nil {
+		return fmt.Errorf("meta bucket not found")
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(version))
+	return bucket.Put([]byte(schemaVersionName), data)
+}
+
+// buildChatSourcesIndex populates the chat_sources reverse index from existing source_chats entries
+func buildChatSourcesIndex(tx *bolt.Tx) error {
+	scB := tx.Bucket([]byte(sourceChatsBucket))
+	csB := tx.Bucket([]byte(chatSourcesBucket))
+	if scB == nil || csB == nil {
+		return fmt.Errorf("source_chats or chat_sources bucket not found")
+	}
+
+	return scB.ForEach(func(k, v []byte) error {
+		var sc SourceChat
+		if err := msgpack.Unmarshal(v, &sc); err != nil {
+			return nil // Skip malformed entries
+		}
+		return csB.Put(makeChatSourceKey(sc.ChatID, sc.SourceID), []byte(sc.SourceID))
+	})
+}
+
+// makeChatSourceKey creates the reverse-index key (chat ID first) for a source-chat relationship
+func makeChatSourceKey(chatID int64, sourceID string) []byte {
+	return append(chatKeyPrefix(chatID), []byte(sourceID)...)
+}
+
+// chatKeyPrefix returns the reverse-index prefix covering all sources of a chat
+func chatKeyPrefix(chatID int64) []byte {
+	return append(chatIDBytes(chatID), ':')
+}
+
+// chatIDBytes encodes a chat ID the way the legacy source_chats keys did
+func chatIDBytes(chatID int64) []byte {
+	data := make([]byte, 8, 9)
+	binary.BigEndian.PutUint64(data, uint64(chatID))
+	return data
+}
+
+// moveLinksToSinks copies the source_chats and source_webhooks links into source_sinks and
+// sink_sources and drops the old buckets along with the chat_sources index
+func moveLinksToSinks(tx *bolt.Tx) error {
+	if scB := tx.Bucket([]byte(sourceChatsBucket)); scB != nil {
+		err := scB.ForEach(func(k, v []byte) error {
+			var sc SourceChat
+			if err := msgpack.Unmarshal(v, &sc); err != nil {
+				return nil // Skip malformed entries
+			}
+			return putSourceSink(tx, sc.SourceID, TelegramSinkID(sc.ChatID))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// source_webhooks keys are "<source ID>:<webhook ID>"
+	if swB := tx.Bucket([]byte(sourceWebhooksBucket)); swB != nil {
+		err := swB.ForEach(func(k, v []byte) error {
+			sourceID, webhookID, found := strings.Cut(string(k), ":")
+			if !found || webhookID == "" {
+				return nil
+			}
+			return putSourceSink(tx, sourceID, WebhookSinkID(webhookID))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range []string{sourceChatsBucket, chatSourcesBucket, sourceWebhooksBucket} {
+		if tx.Bucket([]byte(name)) == nil {
+			continue
+		}
+		if err := tx.DeleteBucket([]byte(name)); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestRunMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := NewBoltDB(path)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	if version, err := db.SchemaVersion(); err != nil || version != latestSchemaVersion() {
+		t.Fatalf("Expected a new database at version %d, got %d (%v)", latestSchemaVersion(), version, err)
+	}
+	db.view(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(sourceChatsBucket)) != nil || tx.Bucket([]byte(chatSourcesBucket)) != nil {
+			t.Error("Expected a new database to end up without the retired link buckets")
+		}
+		return nil
+	})
+
+	// Roll back to the baseline: a source with a chat link in the version 1 layout
+	err = db.update(func(tx *bolt.Tx) error {
+		chats, err := tx.CreateBucket([]byte(sourceChatsBucket))
+		if err != nil {
+			return err
+		}
+		data, _ := msgpack.Marshal(&SourceChat{SourceID: "s1", ChatID: -100})
+		if err := chats.Put(append([]byte("s1:"), chatIDBytes(-100)...), data); err != nil {
+			return err
+		}
+		return writeSchemaVersion(tx, 1)
+	})
+	if err != nil {
+		t.Fatalf("Failed to write version 1 data: %v", err)
+	}
+	if err := db.SaveSource(&Source{ID: "s1", Name: "API", Type: "http", Target: "https://example.com"}); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+	db.Close()
+
+	if db, err = NewBoltDB(path); err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	if version, _ := db.SchemaVersion(); version != latestSchemaVersion() {
+		t.Errorf("Expected the version to be bumped to %d, got %d", latestSchemaVersion(), version)
+	}
+	if source, err := db.GetSource("s1"); err != nil || source.Name != "API" {
+		t.Errorf("Expected the source to survive the migrations, got %+v (%v)", source, err)
+	}
+	if chats, _ := db.GetSourceChats("s1"); len(chats) != 1 || chats[0] != -100 {
+		t.Errorf("Expected the chat link to be migrated, got %v", chats)
+	}
+
+	// Migrations already applied don't run again: a legacy bucket created now stays untouched
+	err = db.update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte(sourceWebhooksBucket))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to create bucket: %v", err)
+	}
+	if err := db.runMigrations(); err != nil {
+		t.Fatalf("Second runMigrations failed: %v", err)
+	}
+	db.view(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(sourceWebhooksBucket)) == nil {
+			t.Error("Expected a second run to apply no migrations")
+		}
+		return nil
+	})
+	if version, _ := db.SchemaVersion(); version != latestSchemaVersion() {
+		t.Errorf("Expected the version to stay at %d, got %d", latestSchemaVersion(), version)
+	}
+}
+
+func TestRunMigrationsFailure(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	latest := latestSchemaVersion()
+	defer func(saved []migration) { migrations = saved }(migrations)
+	migrations = append(migrations[:len(migrations):len(migrations)], migration{
+		version:     latest + 1,
+		description: "fails halfway",
+		apply: func(tx *bolt.Tx) error {
+			if _, err := tx.CreateBucket([]byte("half_done")); err != nil {
+				return err
+			}
+			return errors.New("disk on fire")
+		},
+	})
+
+	// A failed migration is rolled back with its version bump
+	if err := db.runMigrations(); err == nil || !strings.Contains(err.Error(), "migration") || !strings.Contains(err.Error(), "disk on fire") {
+		t.Errorf("Expected the migration error, got %v", err)
+	}
+	if version, _ := db.SchemaVersion(); version != latest {
+		t.Errorf("Expected the version to stay at %d, got %d", latest, version)
+	}
+	db.view(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte("half_done")) != nil {
+			t.Error("Expected the failed migration's writes to be rolled back")
+		}
+		return nil
+	})
+
+	// A database from a newer build is refused rather than downgraded
+	migrations = migrations[:len(migrations)-1]
+	if err := db.update(func(tx *bolt.Tx) error { return writeSchemaVersion(tx, latest+1) }); err != nil {
+		t.Fatalf("Failed to write schema version: %v", err)
+	}
+	if err := db.runMigrations(); err == nil || !strings.Contains(err.Error(), "newer than supported") {
+		t.Errorf("Expected a newer schema to be refused, got %v", err)
+	}
+}
+
+func TestChatSourcesIndexBackfill(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	// Links written before the reverse index existed, in the buckets of a version 1 database
+	links := []SourceChat{{SourceID: "s1", ChatID: -100}, {SourceID: "s2", ChatID: -100}, {SourceID: "s2", ChatID: 42}}
+	err = db.update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucket([]byte(chatSourcesBucket)); err != nil {
+			return err
+		}
+		chats, err := tx.CreateBucket([]byte(sourceChatsBucket))
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			data, _ := msgpack.Marshal(&link)
+			if err := chats.Put(append([]byte(link.SourceID+":"), chatIDBytes(link.ChatID)...), data); err != nil {
+				return err
+			}
+		}
+		return chats.Put([]byte("s3:broken"), []byte("not msgpack"))
+	})
+	if err != nil {
+		t.Fatalf("Failed to write legacy links: %v", err)
+	}
+
+	if err := db.update(buildChatSourcesIndex); err != nil {
+		t.Fatalf("buildChatSourcesIndex failed: %v", err)
+	}
+	indexed := make(map[string]string)
+	db.view(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(chatSourcesBucket)).ForEach(func(k, v []byte) error {
+			indexed[string(k)] = string(v)
+			return nil
+		})
+	})
+	if len(indexed) != len(links) {
+		t.Errorf("Expected one index entry per link, malformed ones skipped, got %d", len(indexed))
+	}
+	for _, link := range links {
+		key := string(append(append(chatIDBytes(link.ChatID), ':'), link.SourceID...))
+		if indexed[key] != link.SourceID {
+			t.Errorf("Expected the index to map chat %d to %s", link.ChatID, link.SourceID)
+		}
+	}
+
+	// The links then move to source_sinks, whose sink_sources index follows every change
+	if err := db.update(moveLinksToSinks); err != nil {
+		t.Fatalf("moveLinksToSinks failed: %v", err)
+	}
+	if sources, _ := db.GetChatSources(-100); len(sources) != 2 || sources[0] != "s1" || sources[1] != "s2" {
+		t.Errorf("Expected chat -100 to list s1 and s2, got %v", sources)
+	}
+	if err := db.RemoveSourceChat("s1", -100); err != nil {
+		t.Fatalf("RemoveSourceChat failed: %v", err)
+	}
+	if sources, _ := db.GetChatSources(-100); len(sources) != 1 || sources[0] != "s2" {
+		t.Errorf("Expected unlinking to drop s1 from the index, got %v", sources)
+	}
+	if err := db.RemoveSourceSink("s2", TelegramSinkID(42)); err != nil {
+		t.Fatalf("RemoveSourceSink failed: %v", err)
+	}
+	if sources, _ := db.GetChatSources(42); len(sources) != 0 {
+		t.Errorf("Expected chat 42 to have no sources left, got %v", sources)
+	}
+	if err := db.AddSourceChat("s3", 42); err != nil {
+		t.Fatalf("AddSourceChat failed: %v", err)
+	}
+	if sources, _ := db.GetChatSources(42); len(sources) != 1 || sources[0] != "s3" {
+		t.Errorf("Expected linking to add s3 to the index, got %v", sources)
+	}
+}
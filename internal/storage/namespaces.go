@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNamespaceInUse is returned when deleting a namespace that still owns resources
+var ErrNamespaceInUse = errors.New("namespace still has sources, webhooks, chats or API keys")
+
+// Namespace separates the sources, sinks, chats and API keys of one tenant from the rest.
+// Resources with an empty namespace are global: only unscoped keys and chats see them.
+type Namespace struct {
+	Name        string    `msgpack:"name" json:"name"` // Normalized with NormalizeNamespace
+	Description string    `msgpack:"description" json:"description,omitempty"`
+	CreatedAt   time.Time `msgpack:"created_at" json:"created_at"`
+}
+
+// NamespaceUsage counts the resources that belong to a namespace
+type NamespaceUsage struct {
+	Sources  int `json:"sources"` // Including sources in trash
+	Webhooks int `json:"webhooks"`
+	Chats    int `json:"chats"`
+	APIKeys  int `json:"api_keys"` // Active keys only
+}
+
+// InUse reports whether anything still belongs to the namespace
+func (u NamespaceUsage) InUse() bool {
+	return u.Sources+u.Webhooks+u.Chats+u.APIKeys > 0
+}
+
+// NormalizeNamespace lowercases and trims a namespace name, which follows the tag rules
+func NormalizeNamespace(name string) (string, error) {
+	normalized, err := normalizeNames("namespace", []string{name}, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(normalized) == 0 {
+		return "", fmt.Errorf("namespace name is required")
+	}
+	return normalized[0], nil
+}
+
+// SaveNamespace creates or updates a namespace
+func (b *BoltDB) SaveNamespace(ns *Namespace) error {
+	if ns.CreatedAt.IsZero() {
+		ns.CreatedAt = time.Now()
+	}
+
+	data, err := msgpack.Marshal(ns)
+	if err != nil {
+		return fmt.Errorf("failed to marshal namespace: %w", err)
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(namespacesBucket))
+		if bucket == nil {
+			return fmt.Errorf("namespaces bucket not found")
+		}
+		if err := bucket.Put([]byte(ns.Name), data); err != nil {
+			return fmt.Errorf("failed to save namespace: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetNamespace retrieves a namespace by name
+func (b *BoltDB) GetNamespace(name string) (*Namespace, error) {
+	var ns Namespace
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(namespacesBucket))
+		if bucket == nil {
+			return fmt.Errorf("namespaces bucket not found")
+		}
+		data := bucket.Get([]byte(name))
+		if data == nil {
+			return fmt.Errorf("namespace not found")
+		}
+		return msgpack.Unmarshal(data, &ns)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ns, nil
+}
+
+// ListNamespaces returns all namespaces ordered by name
+func (b *BoltDB) ListNamespaces() ([]*Namespace, error) {
+	namespaces := []*Namespace{}
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(namespacesBucket))
+		if bucket == nil {
+			return fmt.Errorf("namespaces bucket not found")
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var ns Namespace
+			if err := msgpack.Unmarshal(v, &ns); err != nil {
+				b.logger.Errorf("Failed to unmarshal namespace: %v", err)
+				return nil // Skip malformed namespaces
+			}
+			namespaces = append(namespaces, &ns)
+			return nil
+		})
+	})
+
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Name < namespaces[j].Name })
+	return namespaces, err
+}
+
+// GetNamespaceUsage counts the sources, webhooks, chats and active API keys in a namespace
+func (b *BoltDB) GetNamespaceUsage(name string) (NamespaceUsage, error) {
+	var usage NamespaceUsage
+
+	sources, err := b.listSources(func(s *Source) bool { return s.Namespace == name })
+	if err != nil {
+		return usage, err
+	}
+	usage.Sources = len(sources)
+
+	webhooks, err := b.ListWebhooks()
+	if err != nil {
+		return usage, err
+	}
+	for _, webhook := range webhooks {
+		if webhook.Namespace == name {
+			usage.Webhooks++
+		}
+	}
+
+	chats, err := b.ListChats()
+	if err != nil {
+		return usage, err
+	}
+	for _, chat := range chats {
+		if chat.Namespace == name {
+			usage.Chats++
+		}
+	}
+
+	keys, err := b.ListAPIKeys()
+	if err != nil {
+		return usage, err
+	}
+	now := time.Now()
+	for _, key := range keys {
+		if key.Namespace == name && key.Active(now) {
+			usage.APIKeys++
+		}
+	}
+	return usage, nil
+}
+
+// DeleteNamespace removes an empty namespace; it fails with ErrNamespaceInUse otherwise
+func (b *BoltDB) DeleteNamespace(name string) error {
+	if _, err := b.GetNamespace(name); err != nil {
+		return err
+	}
+	usage, err := b.GetNamespaceUsage(name)
+	if err != nil {
+		return err
+	}
+	if usage.InUse() {
+		return ErrNamespaceInUse
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(namespacesBucket))
+		if bucket == nil {
+			return fmt.Errorf("namespaces bucket not found")
+		}
+		return bucket.Delete([]byte(name))
+	})
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNamespaces(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	if name, err := NormalizeNamespace(" Home-Lab "); err != nil || name != "home-lab" {
+		t.Errorf("Expected home-lab, got %q (%v)", name, err)
+	}
+	if _, err := NormalizeNamespace("a b"); err == nil {
+		t.Error("Expected error for a name with a space")
+	}
+	if _, err := NormalizeNamespace(""); err == nil {
+		t.Error("Expected error for an empty name")
+	}
+
+	if err := db.SaveNamespace(&Namespace{Name: "bob"}); err != nil {
+		t.Fatalf("SaveNamespace failed: %v", err)
+	}
+	if err := db.SaveNamespace(&Namespace{Name: "alice", Description: "Alice's homelab"}); err != nil {
+		t.Fatalf("SaveNamespace failed: %v", err)
+	}
+	namespaces, err := db.ListNamespaces()
+	if err != nil || len(namespaces) != 2 || namespaces[0].Name != "alice" {
+		t.Fatalf("Expected alice and bob by name, got %v (%v)", namespaces, err)
+	}
+
+	source := &Source{Name: "nas", Type: "ping", Target: "192.168.1.5", CheckInterval: time.Minute, Namespace: "alice"}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+	if err := db.SaveChat(&Chat{ChatID: 42, Namespace: "alice"}); err != nil {
+		t.Fatalf("SaveChat failed: %v", err)
+	}
+	revoked, _, err := db.CreateAPIKey("old", ScopeWrite, "bob", nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if _, _, err := db.CreateAPIKey("root", ScopeAdmin, "bob", nil); err == nil {
+		t.Error("Expected error for a namespaced admin key")
+	}
+
+	usage, err := db.GetNamespaceUsage("alice")
+	if err != nil || usage.Sources != 1 || usage.Chats != 1 || usage.APIKeys != 0 {
+		t.Errorf("Expected 1 source and 1 chat, got %+v (%v)", usage, err)
+	}
+	if err := db.DeleteNamespace("alice"); !errors.Is(err, ErrNamespaceInUse) {
+		t.Errorf("Expected ErrNamespaceInUse, got %v", err)
+	}
+
+	// Revoked keys don't keep a namespace alive
+	if err := db.DeleteNamespace("bob"); !errors.Is(err, ErrNamespaceInUse) {
+		t.Errorf("Expected ErrNamespaceInUse while the key is active, got %v", err)
+	}
+	if _, err := db.RevokeAPIKey(revoked.ID); err != nil {
+		t.Fatalf("RevokeAPIKey failed: %v", err)
+	}
+	if err := db.DeleteNamespace("bob"); err != nil {
+		t.Errorf("DeleteNamespace failed: %v", err)
+	}
+	if err := db.DeleteNamespace("bob"); err == nil {
+		t.Error("Expected error deleting a missing namespace")
+	}
+
+	export, err := db.Export()
+	if err != nil || len(export.Namespaces) != 1 || export.Namespaces[0].Name != "alice" {
+		t.Errorf("Expected the export to include alice, got %v (%v)", export.Namespaces, err)
+	}
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// PlannedOutage marks an outage of a source as planned, so SLA-adjusted availability leaves
+// it out. An outage is identified by the status change that started it, as with notes.
+type PlannedOutage struct {
+	SourceID    string    `msgpack:"source_id" json:"source_id"`
+	ChangeID    string    `msgpack:"change_id" json:"change_id"`       // Status change that started the outage
+	OutageStart time.Time `msgpack:"outage_start" json:"outage_start"` // Timestamp of that change
+	Reason      string    `msgpack:"reason" json:"reason,omitempty"`
+	MarkedBy    string    `msgpack:"marked_by" json:"marked_by"` // API key name
+	CreatedAt   time.Time `msgpack:"created_at" json:"created_at"`
+}
+
+// makePlannedOutageKey orders a source's planned outages by start: sourceID + ":" + start (ns)
+func makePlannedOutageKey(sourceID string, start time.Time) []byte {
+	key := make([]byte, 0, len(sourceID)+9)
+	key = append(key, sourceID+":"...)
+	return binary.BigEndian.AppendUint64(key, uint64(start.UnixNano()))
+}
+
+// MarkOutagePlanned marks the outage started by change as planned, replacing an earlier mark
+func (b *BoltDB) MarkOutagePlanned(change *StatusChange, mark *PlannedOutage) error {
+	mark.Reason = strings.TrimSpace(mark.Reason)
+	if len([]rune(mark.Reason)) > MaxIncidentNoteLength {
+		return fmt.Errorf("reason is longer than %d characters", MaxIncidentNoteLength)
+	}
+	mark.SourceID = change.SourceID
+	mark.ChangeID = change.ID
+	mark.OutageStart = change.Timestamp
+	if mark.CreatedAt.IsZero() {
+		mark.CreatedAt = time.Now()
+	}
+
+	data, err := msgpack.Marshal(mark)
+	if err != nil {
+		return fmt.Errorf("failed to marshal planned outage: %w", err)
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(plannedOutagesBucket))
+		if bucket == nil {
+			return fmt.Errorf("planned outages bucket not found")
+		}
+		if err := bucket.Put(makePlannedOutageKey(mark.SourceID, mark.OutageStart), data); err != nil {
+			return fmt.Errorf("failed to save planned outage: %w", err)
+		}
+		return nil
+	})
+}
+
+// UnmarkOutagePlanned removes the planned mark from the outage of a source started by the
+// status change changeID
+func (b *BoltDB) UnmarkOutagePlanned(sourceID, changeID string) error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(plannedOutagesBucket))
+		if bucket == nil {
+			return fmt.Errorf("planned outages bucket not found")
+		}
+
+		prefix := []byte(sourceID + ":")
+		c := bucket.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var mark PlannedOutage
+			if err := msgpack.Unmarshal(v, &mark); err == nil && mark.ChangeID == changeID {
+				return bucket.Delete(k)
+			}
+		}
+		return fmt.Errorf("outage is not marked as planned: %s", changeID)
+	})
+}
+
+// GetPlannedOutages returns the planned marks of a source's outages that started in
+// [from, to), oldest first. Zero bounds are open.
+func (b *BoltDB) GetPlannedOutages(sourceID string, from, to time.Time) ([]*PlannedOutage, error) {
+	marks := []*PlannedOutage{}
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(plannedOutagesBucket))
+		if bucket == nil {
+			return fmt.Errorf("planned outages bucket not found")
+		}
+
+		prefix := []byte(sourceID + ":")
+		start := prefix
+		if !from.IsZero() {
+			start = makePlannedOutageKey(sourceID, from)
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(start); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var mark PlannedOutage
+			if err := msgpack.Unmarshal(v, &mark); err != nil {
+				b.logger.Errorf("Failed to unmarshal planned outage: %v", err)
+				continue
+			}
+			if !to.IsZero() && !mark.OutageStart.Before(to) {
+				break
+			}
+			marks = append(marks, &mark)
+		}
+		return nil
+	})
+
+	return marks, err
+}
+
+// classifyPlanned works out how much of each replayed outage was planned: all of it when
+// the outage is marked as planned, otherwise the part that a maintenance window covered
+func (b *BoltDB) classifyPlanned(source *Source, replay *statusReplay, from, to time.Time) error {
+	if len(replay.outages) == 0 {
+		return nil
+	}
+
+	marks, err := b.GetPlannedOutages(source.ID, time.Time{}, to)
+	if err != nil {
+		return err
+	}
+	marked := make(map[int64]bool, len(marks))
+	for _, mark := range marks {
+		marked[mark.OutageStart.UnixNano()] = true
+	}
+
+	spans, err := b.maintenanceSpans(source, from, to)
+	if err != nil {
+		return err
+	}
+
+	for i := range replay.outages {
+		o := &replay.outages[i]
+		start := o.start
+		if o.startedBefore && len(marks) > 0 {
+			// The replay clipped the start; the mark is keyed by the change that began it
+			change, err := b.FindOutage(source.ID, o.start)
+			if err != nil && !errors.Is(err, ErrNoOutage) {
+				return err
+			}
+			if change != nil {
+				start = change.Timestamp
+			}
+		}
+		if marked[start.UnixNano()] {
+			o.marked = true
+			o.planned = o.end.Sub(o.start)
+			continue
+		}
+		for _, span := range spans {
+			overlapStart, overlapEnd := span.Start, span.End
+			if o.start.After(overlapStart) {
+				overlapStart = o.start
+			}
+			if o.end.Before(overlapEnd) {
+				overlapEnd = o.end
+			}
+			if overlapEnd.After(overlapStart) {
+				o.planned += overlapEnd.Sub(overlapStart)
+			}
+		}
+	}
+	return nil
+}
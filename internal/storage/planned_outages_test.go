@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPlannedOutages(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Hour)
+	source := &Source{ID: "src", Name: "API", Type: "http", CreatedAt: from.Add(-time.Hour)}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+
+	// A 1h outage that is marked planned, and a 1h outage half covered by maintenance
+	changes := []*StatusChange{
+		{ID: "up", SourceID: "src", OldStatus: -1, NewStatus: 1, Timestamp: from.Add(-time.Hour)},
+		{ID: "deploy", SourceID: "src", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(1 * time.Hour)},
+		{ID: "deployed", SourceID: "src", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(2 * time.Hour)},
+		{ID: "crash", SourceID: "src", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(5 * time.Hour)},
+		{ID: "recovered", SourceID: "src", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(6 * time.Hour)},
+	}
+	for _, change := range changes {
+		if err := db.SaveStatusChange(change); err != nil {
+			t.Fatalf("SaveStatusChange failed: %v", err)
+		}
+	}
+	end := from.Add(7 * time.Hour)
+	if err := db.SaveMaintenanceWindow(&MaintenanceWindow{Name: "db upgrade", Start: from.Add(5*time.Hour + 30*time.Minute), End: &end}); err != nil {
+		t.Fatalf("SaveMaintenanceWindow failed: %v", err)
+	}
+
+	outage, err := db.FindOutage("src", from.Add(90*time.Minute))
+	if err != nil || outage.ID != "deploy" {
+		t.Fatalf("Expected the deploy outage, got %+v (%v)", outage, err)
+	}
+	if err := db.MarkOutagePlanned(outage, &PlannedOutage{Reason: " release 1.4 ", MarkedBy: "ops"}); err != nil {
+		t.Fatalf("MarkOutagePlanned failed: %v", err)
+	}
+	marks, err := db.GetPlannedOutages("src", time.Time{}, time.Time{})
+	if err != nil || len(marks) != 1 || marks[0].ChangeID != "deploy" || marks[0].Reason != "release 1.4" {
+		t.Fatalf("Expected the mark, got %+v (%v)", marks, err)
+	}
+
+	stats, err := db.ComputeUptimeStats(source, from, to)
+	if err != nil {
+		t.Fatalf("ComputeUptimeStats failed: %v", err)
+	}
+	if stats.DowntimeMs != (2*time.Hour).Milliseconds() || stats.PlannedDowntimeMs != (90*time.Minute).Milliseconds() {
+		t.Errorf("Expected 2h down of which 1h30m planned, got %d and %d", stats.DowntimeMs, stats.PlannedDowntimeMs)
+	}
+	// Raw: 8h of 10h up. SLA: 30m unplanned of 8h30m
+	if stats.UptimePercent == nil || *stats.UptimePercent != 80 {
+		t.Errorf("Expected 80%% raw uptime, got %v", stats.UptimePercent)
+	}
+	if want := 8.0 / 8.5 * 100; stats.SLAUptimePercent == nil || *stats.SLAUptimePercent < want-1e-9 || *stats.SLAUptimePercent > want+1e-9 {
+		t.Errorf("Expected %.4f%% SLA uptime, got %v", want, stats.SLAUptimePercent)
+	}
+
+	// A range starting mid-outage still finds the mark
+	windows, err := db.GetOutageWindows(source, from.Add(90*time.Minute), to)
+	if err != nil || len(windows) != 2 || !windows[0].StartedBefore || !windows[0].Planned || windows[1].Planned {
+		t.Fatalf("Expected the clipped planned outage and an unplanned one, got %+v (%v)", windows, err)
+	}
+	if windows[1].PlannedMs != (30 * time.Minute).Milliseconds() {
+		t.Errorf("Expected 30m of the second outage in maintenance, got %dms", windows[1].PlannedMs)
+	}
+
+	if err := db.UnmarkOutagePlanned("src", "deploy"); err != nil {
+		t.Fatalf("UnmarkOutagePlanned failed: %v", err)
+	}
+	if err := db.UnmarkOutagePlanned("src", "deploy"); err == nil {
+		t.Error("Expected unmarking an unmarked outage to fail")
+	}
+	if stats, _ := db.ComputeUptimeStats(source, from, to); stats.PlannedDowntimeMs != (30 * time.Minute).Milliseconds() {
+		t.Errorf("Expected only the maintenance part planned after unmarking, got %dms", stats.PlannedDowntimeMs)
+	}
+}
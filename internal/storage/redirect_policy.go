@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Redirect modes of an http source
+const (
+	RedirectFollow  = "follow"  // Follow redirects, up to MaxRedirects
+	RedirectNone    = "none"    // Don't follow; a redirect response fails the check
+	RedirectRequire = "require" // Follow, and the final URL must start with Target
+)
+
+// DefaultMaxRedirects is how many redirects are followed when MaxRedirects isn't set, the
+// same as Go's default client
+const DefaultMaxRedirects = 10
+
+// MaxRedirectsLimit caps max_redirects
+const MaxRedirectsLimit = 30
+
+// RedirectPolicy controls how an http source's check treats redirects, e.g. so a site that
+// starts redirecting to a parking page is reported OFFLINE. Nil means follow up to 10.
+type RedirectPolicy struct {
+	Mode         string `msgpack:"mode" json:"mode" yaml:"mode"`                                      // follow, none or require
+	MaxRedirects int    `msgpack:"max_redirects" json:"max_redirects,omitempty" yaml:"max_redirects"` // follow/require; 0 = 10
+	Target       string `msgpack:"target" json:"target,omitempty" yaml:"target"`                      // require: URL prefix of the final URL
+}
+
+// IsZero reports whether the policy is unset
+func (p *RedirectPolicy) IsZero() bool {
+	return p == nil || *p == RedirectPolicy{}
+}
+
+// Validate checks the mode and its settings
+func (p *RedirectPolicy) Validate() error {
+	switch p.Mode {
+	case RedirectFollow, RedirectNone, RedirectRequire:
+	default:
+		return errors.New("redirect_policy.mode must be follow, none or require")
+	}
+	if p.MaxRedirects < 0 || p.MaxRedirects > MaxRedirectsLimit {
+		return fmt.Errorf("redirect_policy.max_redirects must be between 0 and %d", MaxRedirectsLimit)
+	}
+	if p.Mode == RedirectNone && p.MaxRedirects != 0 {
+		return errors.New("redirect_policy.max_redirects doesn't apply to mode none")
+	}
+	if p.Mode != RedirectRequire {
+		if p.Target != "" {
+			return errors.New("redirect_policy.target only applies to mode require")
+		}
+		return nil
+	}
+	target, err := url.Parse(p.Target)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return errors.New("redirect_policy.target must be an http(s) URL")
+	}
+	return nil
+}
+
+// Limit returns how many redirects a check follows
+func (p *RedirectPolicy) Limit() int {
+	switch {
+	case p.IsZero():
+		return DefaultMaxRedirects
+	case p.Mode == RedirectNone:
+		return 0
+	case p.MaxRedirects == 0:
+		return DefaultMaxRedirects
+	}
+	return p.MaxRedirects
+}
+
+// Violation returns why a check whose last request went to finalURL and got statusCode, with
+// location as the redirect target, breaches the policy, or "" when it doesn't
+func (p *RedirectPolicy) Violation(finalURL string, statusCode int, location string) string {
+	if p.IsZero() {
+		return ""
+	}
+	switch p.Mode {
+	case RedirectNone:
+		if statusCode >= 300 && statusCode < 400 {
+			return fmt.Sprintf("HTTP %d redirect to %s, but redirects are not followed", statusCode, location)
+		}
+	case RedirectRequire:
+		if !strings.HasPrefix(finalURL, p.Target) {
+			return fmt.Sprintf("HTTP %d from %s, expected a redirect to %s", statusCode, finalURL, p.Target)
+		}
+	}
+	return ""
+}
+
+// Copy returns a copy of the policy, nil when it's unset
+func (p *RedirectPolicy) Copy() *RedirectPolicy {
+	if p.IsZero() {
+		return nil
+	}
+	copied := *p
+	return &copied
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// RelatedOutage is another source that is down too, with how it relates to the source
+type RelatedOutage struct {
+	Source *Source
+	Reason string    // "upstream", "same host" or "tag <name>"
+	Since  time.Time // When it went down
+}
+
+// relatedOutageRank orders the reasons: declared dependencies, then the same host, then tags
+func relatedOutageRank(reason string) int {
+	switch reason {
+	case "upstream":
+		return 0
+	case "same host":
+		return 1
+	}
+	return 2
+}
+
+// FindRelatedOutages returns the enabled sources that are down along with source. Its
+// declared dependencies count whenever they went down, directly or through other
+// dependencies. Other sources of the same namespace count if they went down at most window
+// before at and share the host of their target or a tag with source. Upstream sources come
+// first, then the ones on the same host, each ordered by when they went down.
+func (b *BoltDB) FindRelatedOutages(source *Source, at time.Time, window time.Duration) ([]RelatedOutage, error) {
+	sources, err := b.GetAllSources()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*Source, len(sources))
+	for _, other := range sources {
+		byID[other.ID] = other
+	}
+
+	var related []RelatedOutage
+	upstream := make(map[string]bool)
+	for _, dependency := range upstreamSources(source, byID) {
+		upstream[dependency.ID] = true
+		if dependency.Enabled && dependency.CurrentStatus == 0 && !dependency.LastChangeTime.After(at) {
+			related = append(related, RelatedOutage{Source: dependency, Reason: "upstream", Since: dependency.LastChangeTime})
+		}
+	}
+
+	host := targetHost(source.Target)
+	for _, other := range sources {
+		if other.ID == source.ID || upstream[other.ID] || !other.Enabled || other.IsDeleted() ||
+			other.Namespace != source.Namespace || other.CurrentStatus != 0 {
+			continue
+		}
+		if other.LastChangeTime.After(at) || other.LastChangeTime.Before(at.Add(-window)) {
+			continue
+		}
+
+		reason := ""
+		if host != "" && targetHost(other.Target) == host {
+			reason = "same host"
+		} else {
+			for _, tag := range source.Tags {
+				if other.HasTag(tag) {
+					reason = "tag " + tag
+					break
+				}
+			}
+		}
+		if reason != "" {
+			related = append(related, RelatedOutage{Source: other, Reason: reason, Since: other.LastChangeTime})
+		}
+	}
+
+	sort.SliceStable(related, func(i, j int) bool {
+		if ri, rj := relatedOutageRank(related[i].Reason), relatedOutageRank(related[j].Reason); ri != rj {
+			return ri < rj
+		}
+		return related[i].Since.Before(related[j].Since)
+	})
+	return related, nil
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindRelatedOutages(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	sources := []*Source{
+		{ID: "web", Name: "Web", Type: "http", Target: "https://10.0.0.5/health", Tags: []string{"office"}, Enabled: true, CurrentStatus: 0, LastChangeTime: now},
+		{ID: "router", Name: "Core Router", Type: "ping", Target: "10.0.0.1", Tags: []string{"office"}, Enabled: true, CurrentStatus: 0, LastChangeTime: now.Add(-2 * time.Minute)},
+		{ID: "ssh", Name: "SSH", Type: "ping", Target: "10.0.0.5", Enabled: true, CurrentStatus: 0, LastChangeTime: now.Add(-time.Minute)},
+		{ID: "old", Name: "Printer", Type: "ping", Target: "10.0.0.9", Tags: []string{"office"}, Enabled: true, CurrentStatus: 0, LastChangeTime: now.Add(-time.Hour)},
+		{ID: "up", Name: "NAS", Type: "ping", Target: "10.0.0.7", Tags: []string{"office"}, Enabled: true, CurrentStatus: 1, LastChangeTime: now.Add(-time.Minute)},
+		{ID: "other", Name: "Tenant", Type: "ping", Target: "10.0.0.5", Namespace: "acme", Enabled: true, CurrentStatus: 0, LastChangeTime: now},
+	}
+	for _, source := range sources {
+		if err := db.SaveSource(source); err != nil {
+			t.Fatalf("SaveSource failed: %v", err)
+		}
+	}
+
+	related, err := db.FindRelatedOutages(sources[0], now, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("FindRelatedOutages failed: %v", err)
+	}
+	// The same host first; long-standing, recovered and other namespaces' outages are unrelated
+	if len(related) != 2 || related[0].Source.ID != "ssh" || related[0].Reason != "same host" ||
+		related[1].Source.ID != "router" || related[1].Reason != "tag office" {
+		t.Fatalf("Expected SSH on the same host and the router by tag, got %+v", related)
+	}
+}
+
+func TestFindRelatedOutagesUpstream(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	sources := []*Source{
+		{ID: "app", Name: "App", Type: "http", Target: "https://app.example.com", DependsOn: []string{"db", "lb"}, Enabled: true, CurrentStatus: 0, LastChangeTime: now},
+		{ID: "db", Name: "Database", Type: "ping", Target: "10.0.1.2", DependsOn: []string{"router"}, Enabled: true, CurrentStatus: 1, LastChangeTime: now.Add(-time.Hour)},
+		{ID: "lb", Name: "Load Balancer", Type: "ping", Target: "10.0.1.3", Enabled: true, CurrentStatus: 0, LastChangeTime: now.Add(-time.Minute)},
+		{ID: "router", Name: "Core Router", Type: "ping", Target: "10.0.0.1", Enabled: true, CurrentStatus: 0, LastChangeTime: now.Add(-3 * time.Hour)},
+		{ID: "api", Name: "API", Type: "http", Target: "https://app.example.com/api", Enabled: true, CurrentStatus: 0, LastChangeTime: now.Add(-2 * time.Minute)},
+	}
+	for _, source := range sources {
+		if err := db.SaveSource(source); err != nil {
+			t.Fatalf("SaveSource failed: %v", err)
+		}
+	}
+
+	// Dependencies count through ones that are up and however long ago they went down
+	related, err := db.FindRelatedOutages(sources[0], now, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("FindRelatedOutages failed: %v", err)
+	}
+	if len(related) != 3 || related[0].Source.ID != "router" || related[0].Reason != "upstream" ||
+		related[1].Source.ID != "lb" || related[1].Reason != "upstream" || related[2].Source.ID != "api" || related[2].Reason != "same host" {
+		t.Fatalf("Expected the router and load balancer upstream, then the API on the same host, got %+v", related)
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	deletedAt := time.Now()
+	for _, source := range []*Source{
+		{ID: "app", Name: "App", DependsOn: []string{"db"}},
+		{ID: "db", Name: "Database", DependsOn: []string{"router"}},
+		{ID: "router", Name: "Core Router"},
+		{ID: "old", Name: "Old", DeletedAt: &deletedAt},
+		{ID: "tenant", Name: "Tenant", Namespace: "acme"},
+	} {
+		if err := db.SaveSource(source); err != nil {
+			t.Fatalf("SaveSource failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		source  *Source
+		wantErr string
+	}{
+		{source: &Source{ID: "web", Name: "Web", DependsOn: []string{"app", "router"}}},
+		{source: &Source{ID: "web", Name: "Web", DependsOn: []string{"web"}}, wantErr: "itself"},
+		{source: &Source{ID: "web", Name: "Web", DependsOn: []string{"missing"}}, wantErr: "not found"},
+		{source: &Source{ID: "web", Name: "Web", DependsOn: []string{"old"}}, wantErr: "not found"},
+		{source: &Source{ID: "web", Name: "Web", DependsOn: []string{"tenant"}}, wantErr: "not found"},
+		{source: &Source{ID: "router", Name: "Core Router", DependsOn: []string{"app"}}, wantErr: "cycle"},
+	}
+	for _, tt := range tests {
+		err := db.ValidateDependencies(tt.source)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%v: unexpected error %v", tt.source.DependsOn, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.source.DependsOn, tt.wantErr, err)
+		}
+	}
+
+	if ids, err := NormalizeDependencies([]string{" db ", "", "db", "router"}); err != nil || len(ids) != 2 || ids[0] != "db" || ids[1] != "router" {
+		t.Errorf("Expected [db router], got %v (%v)", ids, err)
+	}
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"time"
+)
+
+// Reliability totals the outages and recoveries of one or more sources over a period, from
+// which mean time to recovery and mean time between failures are derived. Totals of several
+// sources are combined with Add, so group means are weighted by outage rather than by source.
+type Reliability struct {
+	Sources           int      `json:"sources"`
+	MonitoredMs       int64    `json:"monitored_ms"`
+	DowntimeMs        int64    `json:"downtime_ms"`
+	OutageCount       int      `json:"outage_count"`        // Outages that started in the period
+	RecoveredCount    int      `json:"recovered_count"`     // Outages that started and ended in the period
+	RecoveryMs        int64    `json:"recovery_ms"`         // Total length of the recovered outages
+	UptimePercent     *float64 `json:"uptime_percent"`      // nil when nothing was monitored
+	MTTRMs            *int64   `json:"mttr_ms"`             // RecoveryMs / RecoveredCount; nil when nothing recovered
+	MTBFMs            *int64   `json:"mtbf_ms"`             // Uptime / OutageCount; nil without outages
+	PlannedDowntimeMs int64    `json:"planned_downtime_ms"` // Part of DowntimeMs in outages marked as planned or in maintenance windows
+	SLAUptimePercent  *float64 `json:"sla_uptime_percent"`  // Uptime with planned downtime left out; nil when nothing unplanned was monitored
+}
+
+// ComputeReliability computes the reliability totals of a source over [from, to)
+func (b *BoltDB) ComputeReliability(source *Source, from, to time.Time) (*Reliability, error) {
+	replay, err := b.replayStatus(source, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.classifyPlanned(source, replay, from, to); err != nil {
+		return nil, err
+	}
+
+	r := &Reliability{
+		Sources:           1,
+		MonitoredMs:       replay.monitored.Milliseconds(),
+		DowntimeMs:        replay.downtime().Milliseconds(),
+		PlannedDowntimeMs: replay.plannedDowntime().Milliseconds(),
+		OutageCount:       replay.outagesStarted(),
+	}
+	for _, o := range replay.outages {
+		if !o.startedBefore && !o.ongoing {
+			r.RecoveredCount++
+			r.RecoveryMs += o.end.Sub(o.start).Milliseconds()
+		}
+	}
+	r.derive()
+	return r, nil
+}
+
+// Add adds the totals of other, e.g. another source of the same tag, and updates the means
+func (r *Reliability) Add(other *Reliability) {
+	r.Sources += other.Sources
+	r.MonitoredMs += other.MonitoredMs
+	r.DowntimeMs += other.DowntimeMs
+	r.PlannedDowntimeMs += other.PlannedDowntimeMs
+	r.OutageCount += other.OutageCount
+	r.RecoveredCount += other.RecoveredCount
+	r.RecoveryMs += other.RecoveryMs
+	r.derive()
+}
+
+// derive computes uptime, MTTR and MTBF from the totals
+func (r *Reliability) derive() {
+	r.UptimePercent, r.MTTRMs, r.MTBFMs = nil, nil, nil
+	if r.MonitoredMs > 0 {
+		uptime := float64(r.MonitoredMs-r.DowntimeMs) / float64(r.MonitoredMs) * 100
+		r.UptimePercent = &uptime
+	}
+	r.SLAUptimePercent = slaUptimePercent(r.MonitoredMs, r.DowntimeMs, r.PlannedDowntimeMs)
+	if r.RecoveredCount > 0 {
+		mttr := r.RecoveryMs / int64(r.RecoveredCount)
+		r.MTTRMs = &mttr
+	}
+	if r.OutageCount > 0 {
+		mtbf := (r.MonitoredMs - r.DowntimeMs) / int64(r.OutageCount)
+		r.MTBFMs = &mtbf
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestComputeReliability(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	web := &Source{ID: "web", Name: "Web", CurrentStatus: 1, CreatedAt: from.Add(-time.Hour)}
+	api := &Source{ID: "api", Name: "API", CurrentStatus: 0, CreatedAt: from.Add(-time.Hour)}
+	db.SaveSource(web)
+	db.SaveSource(api)
+
+	// Web: one 2h outage. API: a 1h outage, then one from 20h still ongoing at the end.
+	for _, change := range []*StatusChange{
+		{SourceID: "web", OldStatus: -1, NewStatus: 1, Timestamp: from.Add(-time.Hour)},
+		{SourceID: "web", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(4 * time.Hour)},
+		{SourceID: "web", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(6 * time.Hour)},
+		{SourceID: "api", OldStatus: -1, NewStatus: 1, Timestamp: from.Add(-time.Hour)},
+		{SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(2 * time.Hour)},
+		{SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(3 * time.Hour)},
+		{SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(20 * time.Hour)},
+	} {
+		if err := db.SaveStatusChange(change); err != nil {
+			t.Fatalf("SaveStatusChange failed: %v", err)
+		}
+	}
+
+	webStats, err := db.ComputeReliability(web, from, to)
+	if err != nil {
+		t.Fatalf("ComputeReliability failed: %v", err)
+	}
+	apiStats, err := db.ComputeReliability(api, from, to)
+	if err != nil {
+		t.Fatalf("ComputeReliability failed: %v", err)
+	}
+
+	if apiStats.OutageCount != 2 || apiStats.RecoveredCount != 1 {
+		t.Errorf("Expected 2 outages with 1 recovered for API, got %d and %d", apiStats.OutageCount, apiStats.RecoveredCount)
+	}
+	if apiStats.MTTRMs == nil || *apiStats.MTTRMs != time.Hour.Milliseconds() {
+		t.Errorf("Expected a 1h MTTR for API, ignoring the ongoing outage, got %v", apiStats.MTTRMs)
+	}
+	if apiStats.MTBFMs == nil || *apiStats.MTBFMs != (19*time.Hour/2).Milliseconds() {
+		t.Errorf("Expected a 9.5h MTBF for API, got %v", apiStats.MTBFMs)
+	}
+
+	total := &Reliability{}
+	total.Add(webStats)
+	total.Add(apiStats)
+	if total.Sources != 2 || total.OutageCount != 3 || total.RecoveredCount != 2 {
+		t.Errorf("Expected 3 outages with 2 recovered across 2 sources, got %+v", total)
+	}
+	if total.MTTRMs == nil || *total.MTTRMs != (90*time.Minute).Milliseconds() {
+		t.Errorf("Expected a combined MTTR of 1.5h, got %v", total.MTTRMs)
+	}
+	if total.MTBFMs == nil || *total.MTBFMs != (41*time.Hour/3).Milliseconds() {
+		t.Errorf("Expected a combined MTBF of 41h/3, got %v", total.MTBFMs)
+	}
+}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BucketStat describes one top-level bucket
+type BucketStat struct {
+	Name string `json:"name"`
+	Keys int    `json:"keys"`
+}
+
+// BucketStats lists all buckets with their key counts, for inspecting the file offline
+func (b *BoltDB) BucketStats() ([]BucketStat, error) {
+	var stats []BucketStat
+	err := b.view(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			stats = append(stats, BucketStat{Name: string(name), Keys: bucket.Stats().KeyN})
+			return nil
+		})
+	})
+	return stats, err
+}
+
+// OrphanReport counts records that point at a source or sink that no longer exists.
+// Soft-deleted sources still exist, so their links and history are not orphans.
+type OrphanReport struct {
+	SourceSinks    int `json:"source_sinks"`    // Links to a missing source or sink
+	SinkSources    int `json:"sink_sources"`    // Reverse-index entries without a matching link
+	StatusChanges  int `json:"status_changes"`  // History of a missing source
+	Rollups        int `json:"rollups"`         // Daily rollups of a missing source
+	IncidentNotes  int `json:"incident_notes"`  // Outage notes of a missing source
+	PlannedOutages int `json:"planned_outages"` // Planned marks on outages of a missing source
+}
+
+// Total is the number of orphaned records found
+func (r *OrphanReport) Total() int {
+	return r.SourceSinks + r.SinkSources + r.StatusChanges + r.Rollups + r.IncidentNotes + r.PlannedOutages
+}
+
+// FixOrphans finds orphaned links and history and, unless dryRun is set, deletes them in a
+// single transaction. They are left behind by hard deletes that predate PurgeSource.
+func (b *BoltDB) FixOrphans(dryRun bool) (*OrphanReport, error) {
+	report := &OrphanReport{}
+	fix := func(tx *bolt.Tx) error {
+		sourcesB := tx.Bucket([]byte(sourcesBucket))
+		if sourcesB == nil {
+			return fmt.Errorf("sources bucket not found")
+		}
+		sourceExists := func(id []byte) bool { return sourcesB.Get(id) != nil }
+
+		// Links to sinks of a type with a registry need the sink to be registered
+		sinkExists := func(sinkID []byte) bool {
+			sinkType, key, err := ParseSinkID(string(sinkID))
+			if err != nil {
+				return false
+			}
+			registry := sinkKinds[sinkType].registry
+			if registry == "" {
+				return true
+			}
+			bucket := tx.Bucket([]byte(registry))
+			return bucket != nil && bucket.Get([]byte(key)) != nil
+		}
+
+		// Keys of these buckets start with "<source ID>:"; source IDs never contain ':'
+		prefixed := []struct {
+			name  string
+			count *int
+			check func(k, v []byte) bool
+		}{
+			{sourceSinksBucket, &report.SourceSinks, func(_, v []byte) bool { return sinkExists(v) }},
+			{statusChangesBucket, &report.StatusChanges, nil},
+			{rollupsBucket, &report.Rollups, nil},
+			{incidentNotesBucket, &report.IncidentNotes, nil},
+			{plannedOutagesBucket, &report.PlannedOutages, nil},
+		}
+		for _, p := range prefixed {
+			bucket := tx.Bucket([]byte(p.name))
+			if bucket == nil {
+				continue
+			}
+			var orphans [][]byte
+			bucket.ForEach(func(k, v []byte) error {
+				sourceID, _, _ := bytes.Cut(k, []byte(":"))
+				if !sourceExists(sourceID) || p.check != nil && !p.check(k, v) {
+					orphans = append(orphans, append([]byte(nil), k...))
+				}
+				return nil
+			})
+			*p.count = len(orphans)
+			if err := deleteKeys(bucket, orphans, dryRun); err != nil {
+				return fmt.Errorf("failed to clean %s: %w", p.name, err)
+			}
+		}
+
+		// Reverse-index entries ("<sink ID>\x00<source ID>") must match a surviving link
+		forward, reverse := tx.Bucket([]byte(sourceSinksBucket)), tx.Bucket([]byte(sinkSourcesBucket))
+		if forward != nil && reverse != nil {
+			var orphans [][]byte
+			reverse.ForEach(func(k, v []byte) error {
+				sinkID, sourceID, found := bytes.Cut(k, []byte{0})
+				if !found || !sourceExists(sourceID) || !sinkExists(sinkID) ||
+					forward.Get(makeSourceSinkKey(string(sourceID), string(sinkID))) == nil {
+					orphans = append(orphans, append([]byte(nil), k...))
+				}
+				return nil
+			})
+			report.SinkSources = len(orphans)
+			if err := deleteKeys(reverse, orphans, dryRun); err != nil {
+				return fmt.Errorf("failed to clean %s: %w", sinkSourcesBucket, err)
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if dryRun {
+		err = b.view(fix)
+	} else {
+		err = b.update(fix)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun && report.Total() > 0 {
+		b.logger.Printf("Removed %d orphaned records: %+v", report.Total(), *report)
+	}
+	return report, nil
+}
+
+// deleteKeys removes keys from a bucket unless dryRun is set
+func deleteKeys(bucket *bolt.Bucket, keys [][]byte, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+	for _, key := range keys {
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DuplicateSources is a set of active sources with the same type and target
+type DuplicateSources struct {
+	Keep       *Source   `json:"keep"` // Oldest source, which the others are merged into
+	Duplicates []*Source `json:"duplicates"`
+}
+
+// FindDuplicateSources groups active sources that check the same type and target
+func (b *BoltDB) FindDuplicateSources() ([]DuplicateSources, error) {
+	sources, err := b.GetAllSources()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]*Source)
+	var order []string
+	for _, source := range sources {
+		key := source.Type + " " + source.Target
+		if _, seen := groups[key]; !seen {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], source)
+	}
+
+	var duplicates []DuplicateSources
+	for _, key := range order {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool { return group[i].CreatedAt.Before(group[j].CreatedAt) })
+		duplicates = append(duplicates, DuplicateSources{Keep: group[0], Duplicates: group[1:]})
+	}
+	return duplicates, nil
+}
+
+// MergeDuplicateSources moves the sink links of the duplicates to the kept source
+// and moves the duplicates to trash, from where they can still be restored
+func (b *BoltDB) MergeDuplicateSources(group DuplicateSources) error {
+	for _, duplicate := range group.Duplicates {
+		sinkIDs, err := b.GetSourceSinkIDs(duplicate.ID)
+		if err != nil {
+			return err
+		}
+		for _, sinkID := range sinkIDs {
+			if err := b.AddSourceSink(group.Keep.ID, sinkID); err != nil {
+				return err
+			}
+		}
+
+		if err := b.SoftDeleteSource(duplicate.ID); err != nil {
+			return err
+		}
+		b.logger.Printf("Merged duplicate source %s (%s) into %s", duplicate.Name, duplicate.ID, group.Keep.ID)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFixOrphans(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	kept := &Source{Name: "kept", Type: "ping", Target: "10.0.0.1", Enabled: true}
+	gone := &Source{Name: "gone", Type: "ping", Target: "10.0.0.2", Enabled: true}
+	for _, source := range []*Source{kept, gone} {
+		if err := db.SaveSource(source); err != nil {
+			t.Fatalf("SaveSource failed: %v", err)
+		}
+	}
+	webhook := &Webhook{Name: "ops", URL: "https://hooks.example.com", Method: "POST", Enabled: true}
+	if err := db.SaveWebhook(webhook); err != nil {
+		t.Fatalf("SaveWebhook failed: %v", err)
+	}
+	for _, source := range []*Source{kept, gone} {
+		if err := db.AddSourceChat(source.ID, 7); err != nil {
+			t.Fatalf("AddSourceChat failed: %v", err)
+		}
+		if err := db.AddSourceWebhook(source.ID, webhook.ID); err != nil {
+			t.Fatalf("AddSourceWebhook failed: %v", err)
+		}
+		if err := db.SaveStatusChange(&StatusChange{SourceID: source.ID, OldStatus: 1, NewStatus: 0}); err != nil {
+			t.Fatalf("SaveStatusChange failed: %v", err)
+		}
+	}
+
+	// A hard delete leaves the source's links and history behind
+	if err := db.DeleteSource(gone.ID); err != nil {
+		t.Fatalf("DeleteSource failed: %v", err)
+	}
+
+	report, err := db.FixOrphans(true)
+	if err != nil {
+		t.Fatalf("FixOrphans dry run failed: %v", err)
+	}
+	want := OrphanReport{SourceSinks: 2, SinkSources: 2, StatusChanges: 1}
+	if *report != want {
+		t.Errorf("Expected %+v, got %+v", want, *report)
+	}
+	if chats, _ := db.GetSourceChats(gone.ID); len(chats) != 1 {
+		t.Error("Expected dry run to keep the orphans")
+	}
+
+	if report, err = db.FixOrphans(false); err != nil || *report != want {
+		t.Fatalf("Expected %+v, got %+v, %v", want, report, err)
+	}
+	if chats, _ := db.GetSourceChats(gone.ID); len(chats) != 0 {
+		t.Errorf("Expected orphaned chat links to be removed, got %v", chats)
+	}
+	if sources, _ := db.GetChatSources(7); len(sources) != 1 || sources[0] != kept.ID {
+		t.Errorf("Expected chat index to list only the kept source, got %v", sources)
+	}
+	if chats, _ := db.GetSourceChats(kept.ID); len(chats) != 1 {
+		t.Error("Expected links of the kept source to stay")
+	}
+
+	// Deleting a webhook takes its source links with it
+	if err := db.DeleteWebhook(webhook.ID); err != nil {
+		t.Fatalf("DeleteWebhook failed: %v", err)
+	}
+	if webhooks, _ := db.GetSourceWebhooks(kept.ID); len(webhooks) != 0 {
+		t.Errorf("Expected webhook links to be removed, got %v", webhooks)
+	}
+	if report, err = db.FixOrphans(false); err != nil || report.Total() != 0 {
+		t.Errorf("Expected no orphans, got %+v, %v", report, err)
+	}
+}
+
+func TestDedupeSources(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	first := &Source{Name: "router", Type: "ping", Target: "192.168.1.1", Enabled: true, CreatedAt: now.Add(-time.Hour)}
+	second := &Source{Name: "router copy", Type: "ping", Target: "192.168.1.1", Enabled: true, CreatedAt: now}
+	other := &Source{Name: "site", Type: "http", Target: "https://192.168.1.1", Enabled: true, CreatedAt: now}
+	for _, source := range []*Source{second, first, other} {
+		if err := db.SaveSource(source); err != nil {
+			t.Fatalf("SaveSource failed: %v", err)
+		}
+	}
+	if err := db.AddSourceChat(second.ID, 9); err != nil {
+		t.Fatalf("AddSourceChat failed: %v", err)
+	}
+
+	groups, err := db.FindDuplicateSources()
+	if err != nil {
+		t.Fatalf("FindDuplicateSources failed: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Keep.ID != first.ID || len(groups[0].Duplicates) != 1 || groups[0].Duplicates[0].ID != second.ID {
+		t.Fatalf("Expected the newer copy to be a duplicate of the oldest source, got %+v", groups)
+	}
+
+	if err := db.MergeDuplicateSources(groups[0]); err != nil {
+		t.Fatalf("MergeDuplicateSources failed: %v", err)
+	}
+	if chats, _ := db.GetSourceChats(first.ID); len(chats) != 1 || chats[0] != 9 {
+		t.Errorf("Expected chat link to move to the kept source, got %v", chats)
+	}
+	if merged, err := db.GetSource(second.ID); err != nil || !merged.IsDeleted() {
+		t.Errorf("Expected duplicate to be in trash, got %+v, %v", merged, err)
+	}
+	if groups, _ := db.FindDuplicateSources(); len(groups) != 0 {
+		t.Errorf("Expected no duplicates left, got %+v", groups)
+	}
+}
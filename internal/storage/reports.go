@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// reportSentPrefix prefixes the meta keys recording the last period each scheduled report
+// was sent for, so restarts don't send it again
+const reportSentPrefix = "report_sent:"
+
+// GetReportSent returns the start of the last period the scheduled report of the given kind
+// (week or month) was sent for, or the zero time if it never was
+func (b *BoltDB) GetReportSent(period string) (time.Time, error) {
+	var sent time.Time
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metaBucket))
+		if bucket == nil {
+			return fmt.Errorf("meta bucket not found")
+		}
+		data := bucket.Get([]byte(reportSentPrefix + period))
+		if data == nil {
+			return nil
+		}
+		var err error
+		sent, err = time.Parse(time.RFC3339, string(data))
+		return err
+	})
+	return sent, err
+}
+
+// SetReportSent records that the scheduled report for the period starting at start was sent
+func (b *BoltDB) SetReportSent(period string, start time.Time) error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metaBucket))
+		if bucket == nil {
+			return fmt.Errorf("meta bucket not found")
+		}
+		return bucket.Put([]byte(reportSentPrefix+period), []byte(start.UTC().Format(time.RFC3339)))
+	})
+}
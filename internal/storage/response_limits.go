@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxResponseLimitMs caps max_response_ms; checks time out long before
+const MaxResponseLimitMs = 10 * 60 * 1000
+
+// ResponseLimits are extra failure criteria for http sources: a response that is too slow,
+// or whose body is too small or too large, fails the check even with a 2xx/3xx status.
+// A zero field means no limit.
+type ResponseLimits struct {
+	MaxResponseMs int64 `msgpack:"max_response_ms" json:"max_response_ms,omitempty" yaml:"max_response_ms"` // Including the body
+	MinBodyBytes  int64 `msgpack:"min_body_bytes" json:"min_body_bytes,omitempty" yaml:"min_body_bytes"`
+	MaxBodyBytes  int64 `msgpack:"max_body_bytes" json:"max_body_bytes,omitempty" yaml:"max_body_bytes"`
+}
+
+// IsZero reports whether no limit is set
+func (l *ResponseLimits) IsZero() bool {
+	return l == nil || *l == ResponseLimits{}
+}
+
+// Validate checks that the limits are non-negative and consistent
+func (l *ResponseLimits) Validate() error {
+	if l.MaxResponseMs < 0 || l.MaxResponseMs > MaxResponseLimitMs {
+		return fmt.Errorf("response_limits.max_response_ms must be between 0 and %d", MaxResponseLimitMs)
+	}
+	if l.MinBodyBytes < 0 || l.MaxBodyBytes < 0 {
+		return errors.New("response_limits body sizes must not be negative")
+	}
+	if l.MaxBodyBytes > 0 && l.MinBodyBytes > l.MaxBodyBytes {
+		return errors.New("response_limits.min_body_bytes must not exceed max_body_bytes")
+	}
+	return nil
+}
+
+// Violation returns why a response that took elapsed and had a body of bodyBytes breaches
+// the limits, or "" when it doesn't
+func (l *ResponseLimits) Violation(elapsed time.Duration, bodyBytes int64) string {
+	if l.IsZero() {
+		return ""
+	}
+	if limit := time.Duration(l.MaxResponseMs) * time.Millisecond; limit > 0 && elapsed > limit {
+		return fmt.Sprintf("slow response: %v exceeds the %v limit", elapsed.Round(time.Millisecond), limit)
+	}
+	if l.MinBodyBytes > 0 && bodyBytes < l.MinBodyBytes {
+		return fmt.Sprintf("body too small: %d bytes, expected at least %d", bodyBytes, l.MinBodyBytes)
+	}
+	if l.MaxBodyBytes > 0 && bodyBytes > l.MaxBodyBytes {
+		return fmt.Sprintf("body too large: more than %d bytes", l.MaxBodyBytes)
+	}
+	return ""
+}
+
+// Copy returns a copy of the limits, nil when none are set
+func (l *ResponseLimits) Copy() *ResponseLimits {
+	if l.IsZero() {
+		return nil
+	}
+	copied := *l
+	return &copied
+}
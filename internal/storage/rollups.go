@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// rollupDateFormat is the day key format used in the rollups bucket (UTC)
+const rollupDateFormat = "2006-01-02"
+
+// DailyRollup holds pre-computed availability aggregates for one source and one UTC day
+type DailyRollup struct {
+	SourceID      string    `msgpack:"source_id" json:"source_id"`
+	Date          string    `msgpack:"date" json:"date"` // YYYY-MM-DD (UTC)
+	UptimePercent float64   `msgpack:"uptime_percent" json:"uptime_percent"`
+	OutageCount   int       `msgpack:"outage_count" json:"outage_count"`
+	DowntimeMs    int64     `msgpack:"downtime_ms" json:"downtime_ms"`
+	MonitoredMs   int64     `msgpack:"monitored_ms" json:"monitored_ms"` // Time with a known status
+	ComputedAt    time.Time `msgpack:"computed_at" json:"computed_at"`
+}
+
+// makeRollupKey creates a sortable key from source ID and day
+func makeRollupKey(sourceID, date string) []byte {
+	return []byte(sourceID + ":" + date)
+}
+
+// SaveDailyRollup stores (or replaces) a daily rollup
+func (b *BoltDB) SaveDailyRollup(rollup *DailyRollup) error {
+	data, err := msgpack.Marshal(rollup)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rollup: %w", err)
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(rollupsBucket))
+		if bucket == nil {
+			return fmt.Errorf("rollups bucket not found")
+		}
+
+		return bucket.Put(makeRollupKey(rollup.SourceID, rollup.Date), data)
+	})
+}
+
+// GetDailyRollups retrieves rollups for a source between two days (inclusive), oldest first
+func (b *BoltDB) GetDailyRollups(sourceID string, from, to time.Time) ([]*DailyRollup, error) {
+	var rollups []*DailyRollup
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(rollupsBucket))
+		if bucket == nil {
+			return fmt.Errorf("rollups bucket not found")
+		}
+
+		c := bucket.Cursor()
+		start := makeRollupKey(sourceID, from.UTC().Format(rollupDateFormat))
+		end := makeRollupKey(sourceID, to.UTC().Format(rollupDateFormat))
+
+		for k, v := c.Seek(start); k != nil && bytes.Compare(k, end) <= 0; k, v = c.Next() {
+			var rollup DailyRollup
+			if err := msgpack.Unmarshal(v, &rollup); err != nil {
+				b.logger.Errorf("Failed to unmarshal rollup: %v", err)
+				continue
+			}
+			rollups = append(rollups, &rollup)
+		}
+
+		return nil
+	})
+
+	return rollups, err
+}
+
+// GetLatestRollupDate returns the most recent day rolled up for a source (zero time if none)
+func (b *BoltDB) GetLatestRollupDate(sourceID string) (time.Time, error) {
+	var latest time.Time
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(rollupsBucket))
+		if bucket == nil {
+			return fmt.Errorf("rollups bucket not found")
+		}
+
+		prefix := []byte(sourceID + ":")
+		c := bucket.Cursor()
+		var lastKey []byte
+		for k, _ := c.Seek(prefix); k != nil && startsWithPrefix(k, prefix); k, _ = c.Next() {
+			lastKey = k
+		}
+		if lastKey == nil {
+			return nil
+		}
+
+		day, err := time.Parse(rollupDateFormat, string(lastKey[len(prefix):]))
+		if err != nil {
+			return fmt.Errorf("invalid rollup key %q: %w", lastKey, err)
+		}
+		latest = day
+		return nil
+	})
+
+	return latest, err
+}
+
+// ComputeDailyRollup replays a source's status changes for one UTC day.
+// Time before the source was created or while its status was unknown is not counted.
+func (b *BoltDB) ComputeDailyRollup(source *Source, day time.Time) (*DailyRollup, error) {
+	dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	dayEnd := dayStart.Add(24 * time.Hour)
+	if now := time.Now(); dayEnd.After(now) {
+		dayEnd = now
+	}
+
+	replay, err := b.replayStatus(source, dayStart, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	rollup := &DailyRollup{
+		SourceID:    source.ID,
+		Date:        dayStart.Format(rollupDateFormat),
+		OutageCount: replay.outagesStarted(),
+		DowntimeMs:  replay.downtime().Milliseconds(),
+		MonitoredMs: replay.monitored.Milliseconds(),
+		ComputedAt:  time.Now(),
+	}
+	if replay.monitored > 0 {
+		rollup.UptimePercent = float64(replay.monitored-replay.downtime()) / float64(replay.monitored) * 100
+	}
+
+	return rollup, nil
+}
+
+// UptimeFromRollups returns the uptime percentage across rollups, weighted by monitored time.
+// ok is false when the rollups contain no monitored time.
+func UptimeFromRollups(rollups []*DailyRollup) (uptime float64, ok bool) {
+	var monitored, downtime int64
+	for _, rollup := range rollups {
+		monitored += rollup.MonitoredMs
+		downtime += rollup.DowntimeMs
+	}
+	if monitored == 0 {
+		return 0, false
+	}
+	return float64(monitored-downtime) / float64(monitored) * 100, true
+}
+
+// GetStatusChangesInRange returns a source's status changes with from <= timestamp < to, oldest first.
+// A zero from means "since the beginning".
+func (b *BoltDB) GetStatusChangesInRange(sourceID string, from, to time.Time) ([]*StatusChange, error) {
+	var changes []*StatusChange
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(statusChangesBucket))
+		if bucket == nil {
+			return fmt.Errorf("status_changes bucket not found")
+		}
+
+		prefix := []byte(sourceID + ":")
+		start := prefix
+		if !from.IsZero() {
+			start = makeStatusChangeKey(sourceID, from)
+		}
+		end := makeStatusChangeKey(sourceID, to)
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(start); k != nil && startsWithPrefix(k, prefix) && bytes.Compare(k, end) < 0; k, v = c.Next() {
+			var change StatusChange
+			if err := msgpack.Unmarshal(v, &change); err != nil {
+				b.logger.Errorf("Failed to unmarshal status change: %v", err)
+				continue
+			}
+			changes = append(changes, &change)
+		}
+
+		return nil
+	})
+
+	return changes, err
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestComputeDailyRollup(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -2)
+	source := &Source{ID: "src", Name: "Router", Type: "ping", CurrentStatus: 1, CreatedAt: day.AddDate(0, 0, -1)}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+
+	// Online before the day, offline 06:00-12:00, online again after
+	changes := []*StatusChange{
+		{SourceID: "src", OldStatus: -1, NewStatus: 1, Timestamp: day.Add(-time.Hour)},
+		{SourceID: "src", OldStatus: 1, NewStatus: 0, Timestamp: day.Add(6 * time.Hour)},
+		{SourceID: "src", OldStatus: 0, NewStatus: 1, Timestamp: day.Add(12 * time.Hour)},
+	}
+	for _, change := range changes {
+		if err := db.SaveStatusChange(change); err != nil {
+			t.Fatalf("SaveStatusChange failed: %v", err)
+		}
+	}
+
+	rollup, err := db.ComputeDailyRollup(source, day)
+	if err != nil {
+		t.Fatalf("ComputeDailyRollup failed: %v", err)
+	}
+
+	if rollup.OutageCount != 1 {
+		t.Errorf("Expected 1 outage, got %d", rollup.OutageCount)
+	}
+	if rollup.DowntimeMs != (6 * time.Hour).Milliseconds() {
+		t.Errorf("Expected 6h downtime, got %dms", rollup.DowntimeMs)
+	}
+	if math.Abs(rollup.UptimePercent-75) > 0.001 {
+		t.Errorf("Expected 75%% uptime, got %f", rollup.UptimePercent)
+	}
+
+	if err := db.SaveDailyRollup(rollup); err != nil {
+		t.Fatalf("SaveDailyRollup failed: %v", err)
+	}
+	latest, err := db.GetLatestRollupDate("src")
+	if err != nil || !latest.Equal(day) {
+		t.Errorf("Expected latest rollup %v, got %v (err %v)", day, latest, err)
+	}
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sink types. Each type keeps its settings in its own registry (chats, webhooks); the links
+// to sources and the delivery pipeline are shared.
+const (
+	SinkTypeTelegram = "telegram"
+	SinkTypeWebhook  = "webhook"
+)
+
+// Sink is a notification channel that sources can be linked to
+type Sink struct {
+	ID        string    `json:"id"` // "<type>:<key>", e.g. "telegram:-100123" or "webhook:<uuid>"
+	Type      string    `json:"type"`
+	Key       string    `json:"key"` // Chat ID or webhook ID, unique within the type
+	Name      string    `json:"name"`
+	Target    string    `json:"target"` // Where notifications go: the chat ID or the webhook URL
+	Enabled   bool      `json:"enabled"`
+	Namespace string    `json:"namespace,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// sinkKind reads and deletes the sinks of one type in that type's registry
+type sinkKind struct {
+	// registry is the bucket a link's key must exist in; empty when links needn't be
+	// registered, as the bot can notify any chat it is in
+	registry string
+	list     func(b *BoltDB) ([]*Sink, error)
+	get      func(b *BoltDB, key string) (*Sink, error)
+	delete   func(b *BoltDB, key string) error
+}
+
+// sinkKinds lists the supported sink types; add an entry here for a new channel type
+var sinkKinds = map[string]sinkKind{
+	SinkTypeTelegram: {
+		list: func(b *BoltDB) ([]*Sink, error) {
+			chats, err := b.ListChats()
+			sinks := make([]*Sink, 0, len(chats))
+			for _, chat := range chats {
+				sinks = append(sinks, chat.Sink())
+			}
+			return sinks, err
+		},
+		get: func(b *BoltDB, key string) (*Sink, error) {
+			chatID, err := strconv.ParseInt(key, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid chat ID %q", key)
+			}
+			chat, err := b.GetChat(chatID)
+			if err != nil {
+				return nil, err
+			}
+			return chat.Sink(), nil
+		},
+		delete: func(b *BoltDB, key string) error {
+			chatID, err := strconv.ParseInt(key, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid chat ID %q", key)
+			}
+			return b.DeleteChat(chatID)
+		},
+	},
+	SinkTypeWebhook: {
+		registry: webhooksBucket,
+		list: func(b *BoltDB) ([]*Sink, error) {
+			webhooks, err := b.ListWebhooks()
+			sinks := make([]*Sink, 0, len(webhooks))
+			for _, webhook := range webhooks {
+				sinks = append(sinks, webhook.Sink())
+			}
+			return sinks, err
+		},
+		get: func(b *BoltDB, key string) (*Sink, error) {
+			webhook, err := b.GetWebhook(key)
+			if err != nil {
+				return nil, err
+			}
+			return webhook.Sink(), nil
+		},
+		delete: func(b *BoltDB, key string) error { return b.DeleteWebhook(key) },
+	},
+}
+
+// SinkTypes returns the supported sink types in alphabetical order
+func SinkTypes() []string {
+	types := make([]string, 0, len(sinkKinds))
+	for sinkType := range sinkKinds {
+		types = append(types, sinkType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// SinkID returns the ID of the sink of sinkType with the type-specific key
+func SinkID(sinkType, key string) string {
+	return sinkType + ":" + key
+}
+
+// TelegramSinkID returns the sink ID of a Telegram chat
+func TelegramSinkID(chatID int64) string {
+	return SinkID(SinkTypeTelegram, strconv.FormatInt(chatID, 10))
+}
+
+// WebhookSinkID returns the sink ID of a webhook
+func WebhookSinkID(webhookID string) string {
+	return SinkID(SinkTypeWebhook, webhookID)
+}
+
+// ParseSinkID splits a sink ID into its type and key
+func ParseSinkID(id string) (sinkType, key string, err error) {
+	sinkType, key, ok := strings.Cut(id, ":")
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("invalid sink ID %q: expected <type>:<key>", id)
+	}
+	if _, known := sinkKinds[sinkType]; !known {
+		return "", "", fmt.Errorf("unknown sink type %q (expected one of %s)", sinkType, strings.Join(SinkTypes(), ", "))
+	}
+	if sinkType == SinkTypeTelegram {
+		if _, err := strconv.ParseInt(key, 10, 64); err != nil {
+			return "", "", fmt.Errorf("invalid chat ID %q", key)
+		}
+	}
+	return sinkType, key, nil
+}
+
+// Sink describes the chat as a notification sink
+func (c *Chat) Sink() *Sink {
+	key := strconv.FormatInt(c.ChatID, 10)
+	return &Sink{
+		ID:        SinkID(SinkTypeTelegram, key),
+		Type:      SinkTypeTelegram,
+		Key:       key,
+		Name:      c.Name,
+		Target:    key,
+		Enabled:   true,
+		Namespace: c.Namespace,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+// Sink describes the webhook as a notification sink
+func (w *Webhook) Sink() *Sink {
+	return &Sink{
+		ID:        WebhookSinkID(w.ID),
+		Type:      SinkTypeWebhook,
+		Key:       w.ID,
+		Name:      w.Name,
+		Target:    w.URL,
+		Enabled:   w.Enabled,
+		Namespace: w.Namespace,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+// ListSinks returns the sinks of all types, or of sinkType when it is set, ordered by ID
+func (b *BoltDB) ListSinks(sinkType string) ([]*Sink, error) {
+	types := SinkTypes()
+	if sinkType != "" {
+		if _, known := sinkKinds[sinkType]; !known {
+			return nil, fmt.Errorf("unknown sink type %q", sinkType)
+		}
+		types = []string{sinkType}
+	}
+
+	sinks := []*Sink{}
+	for _, t := range types {
+		found, err := sinkKinds[t].list(b)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, found...)
+	}
+	sort.Slice(sinks, func(i, j int) bool { return sinks[i].ID < sinks[j].ID })
+	return sinks, nil
+}
+
+// GetSink retrieves a sink by ID
+func (b *BoltDB) GetSink(id string) (*Sink, error) {
+	sinkType, key, err := ParseSinkID(id)
+	if err != nil {
+		return nil, err
+	}
+	return sinkKinds[sinkType].get(b, key)
+}
+
+// DeleteSink removes a sink from its registry together with its links to sources
+func (b *BoltDB) DeleteSink(id string) error {
+	sinkType, key, err := ParseSinkID(id)
+	if err != nil {
+		return err
+	}
+	return sinkKinds[sinkType].delete(b, key)
+}
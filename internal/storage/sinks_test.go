@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestSinks(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	source := &Source{Name: "api", Type: "http", Target: "https://example.com", Enabled: true}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+	if err := db.SaveChat(&Chat{ChatID: -100, Name: "ops"}); err != nil {
+		t.Fatalf("SaveChat failed: %v", err)
+	}
+	webhook := &Webhook{Name: "hook", URL: "https://hooks.example.com", Method: "POST", Enabled: true}
+	if err := db.SaveWebhook(webhook); err != nil {
+		t.Fatalf("SaveWebhook failed: %v", err)
+	}
+
+	if _, _, err := ParseSinkID("pager:1"); err == nil {
+		t.Error("Expected error for an unknown sink type")
+	}
+	if _, _, err := ParseSinkID("telegram:ops"); err == nil {
+		t.Error("Expected error for a non-numeric chat ID")
+	}
+
+	sinks, err := db.ListSinks("")
+	if err != nil || len(sinks) != 2 || sinks[0].ID != "telegram:-100" || sinks[1].Target != webhook.URL {
+		t.Fatalf("Expected the chat and the webhook, got %+v (%v)", sinks, err)
+	}
+
+	// Chats the bot was added to without registering them still get notifications
+	for _, sinkID := range []string{TelegramSinkID(-100), TelegramSinkID(42), WebhookSinkID(webhook.ID)} {
+		if err := db.AddSourceSink(source.ID, sinkID); err != nil {
+			t.Fatalf("AddSourceSink failed: %v", err)
+		}
+	}
+	linked, err := db.GetSourceSinks(source.ID)
+	if err != nil || len(linked) != 3 || linked[1].Key != "42" || !linked[1].Enabled {
+		t.Errorf("Expected three sinks including an unnamed chat, got %+v (%v)", linked, err)
+	}
+	if chats, _ := db.GetSourceChats(source.ID); len(chats) != 2 {
+		t.Errorf("Expected two chat links, got %v", chats)
+	}
+
+	if err := db.DeleteSink(WebhookSinkID(webhook.ID)); err != nil {
+		t.Fatalf("DeleteSink failed: %v", err)
+	}
+	if _, err := db.GetWebhook(webhook.ID); err == nil {
+		t.Error("Expected the webhook to be deleted")
+	}
+	if sources, _ := db.GetSinkSources(WebhookSinkID(webhook.ID)); len(sources) != 0 {
+		t.Errorf("Expected the webhook's links to be deleted, got %v", sources)
+	}
+
+	if err := db.PurgeSource(source.ID); err != nil {
+		t.Fatalf("PurgeSource failed: %v", err)
+	}
+	if sources, _ := db.GetChatSources(-100); len(sources) != 0 {
+		t.Errorf("Expected purge to remove the source's links, got %v", sources)
+	}
+}
+
+func TestMigrateLinksToSinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := NewBoltDB(path)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	// Recreate the version 2 layout: separate chat and webhook link buckets
+	err = db.update(func(tx *bolt.Tx) error {
+		chats, err := tx.CreateBucket([]byte(sourceChatsBucket))
+		if err != nil {
+			return err
+		}
+		data, _ := msgpack.Marshal(&SourceChat{SourceID: "s1", ChatID: -100})
+		if err := chats.Put(append([]byte("s1:"), chatIDBytes(-100)...), data); err != nil {
+			return err
+		}
+		webhooks, err := tx.CreateBucket([]byte(sourceWebhooksBucket))
+		if err != nil {
+			return err
+		}
+		if err := webhooks.Put([]byte("s1:wh1"), []byte("1")); err != nil {
+			return err
+		}
+		return writeSchemaVersion(tx, 2)
+	})
+	if err != nil {
+		t.Fatalf("Failed to write version 2 data: %v", err)
+	}
+	db.Close()
+
+	if db, err = NewBoltDB(path); err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	if ids, _ := db.GetSourceSinkIDs("s1"); len(ids) != 2 || ids[0] != "telegram:-100" || ids[1] != "webhook:wh1" {
+		t.Errorf("Expected both links to be migrated, got %v", ids)
+	}
+	if sources, _ := db.GetSinkSources("webhook:wh1"); len(sources) != 1 || sources[0] != "s1" {
+		t.Errorf("Expected the reverse index to be built, got %v", sources)
+	}
+	db.view(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(sourceChatsBucket)) != nil || tx.Bucket([]byte(sourceWebhooksBucket)) != nil {
+			t.Error("Expected the old link buckets to be dropped")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultSLOWindowDays is the SLO window when a source doesn't set one
+const DefaultSLOWindowDays = 30
+
+// SLO is a source's availability objective, e.g. 99.9% over 30 days
+type SLO struct {
+	Target     float64 `msgpack:"target" json:"target" yaml:"target"`                // Uptime percent, e.g. 99.9
+	WindowDays int     `msgpack:"window_days" json:"window_days" yaml:"window_days"` // Rolling window
+}
+
+// Validate checks the target and fills in the default window
+func (s *SLO) Validate() error {
+	if s.Target <= 0 || s.Target >= 100 {
+		return errors.New("slo.target must be a percentage between 0 and 100, e.g. 99.9")
+	}
+	if s.WindowDays == 0 {
+		s.WindowDays = DefaultSLOWindowDays
+	}
+	if s.WindowDays < 1 || s.WindowDays > 366 {
+		return errors.New("slo.window_days must be between 1 and 366")
+	}
+	return nil
+}
+
+// Burn rate windows: how fast the budget is being spent recently, as a multiple of the rate
+// that would use up exactly the whole budget over the SLO window
+const (
+	BurnRateShortWindow = time.Hour
+	BurnRateLongWindow  = 6 * time.Hour
+)
+
+// ErrorBudget is the state of a source's SLO over its rolling window
+type ErrorBudget struct {
+	SourceID          string    `json:"source_id"`
+	SourceName        string    `json:"source_name"`
+	Target            float64   `json:"target"`
+	WindowDays        int       `json:"window_days"`
+	From              time.Time `json:"from"`
+	To                time.Time `json:"to"`
+	UptimePercent     *float64  `json:"uptime_percent"` // nil when the status was never known in the window
+	MonitoredMs       int64     `json:"monitored_ms"`
+	DowntimeMs        int64     `json:"downtime_ms"`
+	AllowedDowntimeMs int64     `json:"allowed_downtime_ms"` // Budget: monitored time × (100 − target)%
+	RemainingMs       int64     `json:"remaining_ms"`        // Negative once the budget is exceeded
+	RemainingPercent  *float64  `json:"remaining_percent"`   // Of the budget; nil without monitored time
+	BurnRate1h        float64   `json:"burn_rate_1h"`
+	BurnRate6h        float64   `json:"burn_rate_6h"`
+	Exhausted         bool      `json:"exhausted"`
+}
+
+// ComputeErrorBudget computes a source's error budget over its SLO window ending at now.
+// Whole days come from the daily rollups; the partial first day and today, which have no
+// rollup yet, are replayed from status changes. Returns nil when the source has no SLO.
+func (b *BoltDB) ComputeErrorBudget(source *Source, now time.Time) (*ErrorBudget, error) {
+	if source.SLO == nil {
+		return nil, nil
+	}
+	now = now.UTC()
+	from := now.AddDate(0, 0, -source.SLO.WindowDays)
+	firstFullDay := from.Truncate(24 * time.Hour).Add(24 * time.Hour)
+	today := now.Truncate(24 * time.Hour)
+
+	var monitored, downtime time.Duration
+	replay := func(start, end time.Time) error {
+		if !end.After(start) {
+			return nil
+		}
+		r, err := b.replayStatus(source, start, end)
+		if err != nil {
+			return err
+		}
+		monitored += r.monitored
+		downtime += r.downtime()
+		return nil
+	}
+
+	if err := replay(from, firstFullDay); err != nil {
+		return nil, err
+	}
+	if firstFullDay.Before(today) {
+		rollups, err := b.GetDailyRollups(source.ID, firstFullDay, today.Add(-24*time.Hour))
+		if err != nil {
+			return nil, err
+		}
+		// Days without a rollup (the maintenance job hasn't run yet) are replayed
+		rolledUp := make(map[string]bool, len(rollups))
+		for _, rollup := range rollups {
+			monitored += time.Duration(rollup.MonitoredMs) * time.Millisecond
+			downtime += time.Duration(rollup.DowntimeMs) * time.Millisecond
+			rolledUp[rollup.Date] = true
+		}
+		for day := firstFullDay; day.Before(today); day = day.Add(24 * time.Hour) {
+			if !rolledUp[day.Format(rollupDateFormat)] {
+				if err := replay(day, day.Add(24*time.Hour)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if err := replay(today, now); err != nil {
+		return nil, err
+	}
+
+	budget := &ErrorBudget{
+		SourceID:    source.ID,
+		SourceName:  source.Name,
+		Target:      source.SLO.Target,
+		WindowDays:  source.SLO.WindowDays,
+		From:        from,
+		To:          now,
+		MonitoredMs: monitored.Milliseconds(),
+		DowntimeMs:  downtime.Milliseconds(),
+	}
+	allowedFraction := (100 - source.SLO.Target) / 100
+	if monitored > 0 {
+		uptime := float64(monitored-downtime) / float64(monitored) * 100
+		budget.UptimePercent = &uptime
+		allowed := time.Duration(float64(monitored) * allowedFraction)
+		budget.AllowedDowntimeMs = allowed.Milliseconds()
+		budget.RemainingMs = (allowed - downtime).Milliseconds()
+		remaining := float64(allowed-downtime) / float64(allowed) * 100
+		budget.RemainingPercent = &remaining
+		budget.Exhausted = downtime >= allowed
+	}
+
+	var err error
+	if budget.BurnRate1h, err = b.burnRate(source, now, BurnRateShortWindow, allowedFraction); err != nil {
+		return nil, err
+	}
+	if budget.BurnRate6h, err = b.burnRate(source, now, BurnRateLongWindow, allowedFraction); err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+// burnRate returns the share of the window spent offline divided by the allowed share
+func (b *BoltDB) burnRate(source *Source, now time.Time, window time.Duration, allowedFraction float64) (float64, error) {
+	r, err := b.replayStatus(source, now.Add(-window), now)
+	if err != nil || r.monitored == 0 {
+		return 0, err
+	}
+	return float64(r.downtime()) / float64(r.monitored) / allowedFraction, nil
+}
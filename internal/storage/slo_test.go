@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestComputeErrorBudget(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC)
+	source := &Source{ID: "src", Name: "API", Type: "http", CurrentStatus: 0, CreatedAt: now.AddDate(0, 0, -20),
+		SLO: &SLO{Target: 99, WindowDays: 10}}
+	db.SaveSource(source)
+
+	// Online throughout the window except a 3h outage on day 3 and one ongoing for 30 minutes
+	for _, change := range []*StatusChange{
+		{SourceID: "src", OldStatus: -1, NewStatus: 1, Timestamp: now.AddDate(0, 0, -20)},
+		{SourceID: "src", OldStatus: 1, NewStatus: 0, Timestamp: time.Date(2026, 3, 4, 8, 0, 0, 0, time.UTC)},
+		{SourceID: "src", OldStatus: 0, NewStatus: 1, Timestamp: time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC)},
+		{SourceID: "src", OldStatus: 1, NewStatus: 0, Timestamp: now.Add(-30 * time.Minute)},
+	} {
+		if err := db.SaveStatusChange(change); err != nil {
+			t.Fatalf("SaveStatusChange failed: %v", err)
+		}
+	}
+
+	// Only some days are rolled up; the rest are replayed
+	for _, day := range []time.Time{time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)} {
+		rollup, err := db.ComputeDailyRollup(source, day)
+		if err != nil {
+			t.Fatalf("ComputeDailyRollup failed: %v", err)
+		}
+		db.SaveDailyRollup(rollup)
+	}
+
+	budget, err := db.ComputeErrorBudget(source, now)
+	if err != nil {
+		t.Fatalf("ComputeErrorBudget failed: %v", err)
+	}
+
+	if budget.MonitoredMs != (240 * time.Hour).Milliseconds() {
+		t.Errorf("Expected 240h monitored, got %v", time.Duration(budget.MonitoredMs)*time.Millisecond)
+	}
+	if budget.DowntimeMs != (210 * time.Minute).Milliseconds() {
+		t.Errorf("Expected 3h30m downtime, got %v", time.Duration(budget.DowntimeMs)*time.Millisecond)
+	}
+	if budget.AllowedDowntimeMs != (144 * time.Minute).Milliseconds() {
+		t.Errorf("Expected 2h24m allowed downtime (1%% of 240h), got %v", time.Duration(budget.AllowedDowntimeMs)*time.Millisecond)
+	}
+	if budget.RemainingMs != -(66*time.Minute).Milliseconds() || !budget.Exhausted {
+		t.Errorf("Expected budget exceeded by 1h06m, got remaining %v, exhausted %v", time.Duration(budget.RemainingMs)*time.Millisecond, budget.Exhausted)
+	}
+	if budget.BurnRate1h < 49.9 || budget.BurnRate1h > 50.1 {
+		t.Errorf("Expected 1h burn rate 50 (50%% down vs 1%% allowed), got %v", budget.BurnRate1h)
+	}
+	if budget.BurnRate6h < 8.3 || budget.BurnRate6h > 8.4 {
+		t.Errorf("Expected 6h burn rate 8.33, got %v", budget.BurnRate6h)
+	}
+
+	source.SLO = nil
+	if budget, err := db.ComputeErrorBudget(source, now); err != nil || budget != nil {
+		t.Errorf("Expected no budget without an SLO, got %v, %v", budget, err)
+	}
+}
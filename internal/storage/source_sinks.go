@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// SourceSink links a source to a sink that gets its notifications
+type SourceSink struct {
+	SourceID string `json:"source_id"`
+	SinkID   string `json:"sink_id"`
+}
+
+// makeSourceSinkKey creates the source_sinks key of a link: "<source ID>:<sink ID>"
+func makeSourceSinkKey(sourceID, sinkID string) []byte {
+	return []byte(sourceID + ":" + sinkID)
+}
+
+// makeSinkSourceKey creates the sink_sources reverse-index key of a link. Sink IDs contain
+// ':', so the parts are separated by a NUL byte.
+func makeSinkSourceKey(sinkID, sourceID string) []byte {
+	return append(sinkKeyPrefix(sinkID), sourceID...)
+}
+
+// sinkKeyPrefix returns the reverse-index prefix covering all sources of a sink
+func sinkKeyPrefix(sinkID string) []byte {
+	return append([]byte(sinkID), 0)
+}
+
+// startsWithPrefix checks if a byte slice starts with a prefix
+func startsWithPrefix(data, prefix []byte) bool {
+	return bytes.HasPrefix(data, prefix)
+}
+
+// AddSourceSink links a sink to a source. Adding an existing link is a no-op.
+func (b *BoltDB) AddSourceSink(sourceID, sinkID string) error {
+	if _, _, err := ParseSinkID(sinkID); err != nil {
+		return err
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		if err := putSourceSink(tx, sourceID, sinkID); err != nil {
+			return err
+		}
+		b.logger.Debugf("Linked sink %s to source %s", sinkID, sourceID)
+		return nil
+	})
+}
+
+// putSourceSink writes a link and its reverse-index entry inside a transaction
+func putSourceSink(tx *bolt.Tx, sourceID, sinkID string) error {
+	forward, reverse := tx.Bucket([]byte(sourceSinksBucket)), tx.Bucket([]byte(sinkSourcesBucket))
+	if forward == nil || reverse == nil {
+		return fmt.Errorf("source_sinks or sink_sources bucket not found")
+	}
+	if err := forward.Put(makeSourceSinkKey(sourceID, sinkID), []byte(sinkID)); err != nil {
+		return fmt.Errorf("failed to add source-sink: %w", err)
+	}
+	if err := reverse.Put(makeSinkSourceKey(sinkID, sourceID), []byte(sourceID)); err != nil {
+		return fmt.Errorf("failed to add sink-source index: %w", err)
+	}
+	return nil
+}
+
+// RemoveSourceSink unlinks a sink from a source
+func (b *BoltDB) RemoveSourceSink(sourceID, sinkID string) error {
+	return b.update(func(tx *bolt.Tx) error {
+		forward, reverse := tx.Bucket([]byte(sourceSinksBucket)), tx.Bucket([]byte(sinkSourcesBucket))
+		if forward == nil || reverse == nil {
+			return fmt.Errorf("source_sinks or sink_sources bucket not found")
+		}
+		if err := forward.Delete(makeSourceSinkKey(sourceID, sinkID)); err != nil {
+			return fmt.Errorf("failed to remove source-sink: %w", err)
+		}
+		if err := reverse.Delete(makeSinkSourceKey(sinkID, sourceID)); err != nil {
+			return fmt.Errorf("failed to remove sink-source index: %w", err)
+		}
+
+		b.logger.Debugf("Unlinked sink %s from source %s", sinkID, sourceID)
+		return nil
+	})
+}
+
+// GetSourceSinkIDs retrieves the IDs of the sinks linked to a source, ordered by ID
+func (b *BoltDB) GetSourceSinkIDs(sourceID string) ([]string, error) {
+	var sinkIDs []string
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(sourceSinksBucket))
+		if bucket == nil {
+			return fmt.Errorf("source_sinks bucket not found")
+		}
+
+		prefix := []byte(sourceID + ":")
+		c := bucket.Cursor()
+		for k, v := c.Seek(prefix); k != nil && startsWithPrefix(k, prefix); k, v = c.Next() {
+			sinkIDs = append(sinkIDs, string(v))
+		}
+		return nil
+	})
+
+	return sinkIDs, err
+}
+
+// GetSourceSinks retrieves the sinks linked to a source. Links to Telegram chats missing from
+// the registry resolve to an unnamed sink; links to other missing sinks are skipped.
+func (b *BoltDB) GetSourceSinks(sourceID string) ([]*Sink, error) {
+	sinkIDs, err := b.GetSourceSinkIDs(sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve sinks outside the transaction to avoid nested read locks
+	sinks := make([]*Sink, 0, len(sinkIDs))
+	for _, sinkID := range sinkIDs {
+		sink, err := b.GetSink(sinkID)
+		if err != nil {
+			sinkType, key, _ := ParseSinkID(sinkID)
+			if sinkType != SinkTypeTelegram {
+				b.logger.Errorf("Failed to get sink %s: %v", sinkID, err)
+				continue
+			}
+			sink = &Sink{ID: sinkID, Type: sinkType, Key: key, Target: key, Enabled: true}
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return sinks, nil
+}
+
+// GetSinkSources retrieves the IDs of the sources linked to a sink using the sink_sources
+// reverse index
+func (b *BoltDB) GetSinkSources(sinkID string) ([]string, error) {
+	var sourceIDs []string
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(sinkSourcesBucket))
+		if bucket == nil {
+			return fmt.Errorf("sink_sources bucket not found")
+		}
+
+		prefix := sinkKeyPrefix(sinkID)
+		c := bucket.Cursor()
+		for k, v := c.Seek(prefix); k != nil && startsWithPrefix(k, prefix); k, v = c.Next() {
+			sourceIDs = append(sourceIDs, string(v))
+		}
+		return nil
+	})
+
+	return sourceIDs, err
+}
+
+// deleteSinkLinks removes all links of a sink inside a transaction, using the reverse index
+// to find its sources
+func deleteSinkLinks(tx *bolt.Tx, sinkID string) error {
+	forward, reverse := tx.Bucket([]byte(sourceSinksBucket)), tx.Bucket([]byte(sinkSourcesBucket))
+	if forward == nil || reverse == nil {
+		return fmt.Errorf("source_sinks or sink_sources bucket not found")
+	}
+
+	prefix := sinkKeyPrefix(sinkID)
+	var sourceIDs []string
+	c := reverse.Cursor()
+	for k, v := c.Seek(prefix); k != nil && startsWithPrefix(k, prefix); k, v = c.Next() {
+		sourceIDs = append(sourceIDs, string(v))
+	}
+	for _, sourceID := range sourceIDs {
+		if err := forward.Delete(makeSourceSinkKey(sourceID, sinkID)); err != nil {
+			return fmt.Errorf("failed to delete source-sink: %w", err)
+		}
+	}
+	if err := deletePrefix(reverse, prefix); err != nil {
+		return fmt.Errorf("failed to delete sink-source index: %w", err)
+	}
+	return nil
+}
+
+// deleteSourceLinks removes all links of a source inside a transaction, reverse-index
+// entries included
+func deleteSourceLinks(tx *bolt.Tx, sourceID string) error {
+	forward, reverse := tx.Bucket([]byte(sourceSinksBucket)), tx.Bucket([]byte(sinkSourcesBucket))
+	if forward == nil || reverse == nil {
+		return fmt.Errorf("source_sinks or sink_sources bucket not found")
+	}
+
+	prefix := []byte(sourceID + ":")
+	c := forward.Cursor()
+	for k, v := c.Seek(prefix); k != nil && startsWithPrefix(k, prefix); k, v = c.Next() {
+		if err := reverse.Delete(makeSinkSourceKey(string(v), sourceID)); err != nil {
+			return fmt.Errorf("failed to delete sink-source index: %w", err)
+		}
+	}
+	if err := deletePrefix(forward, prefix); err != nil {
+		return fmt.Errorf("failed to delete source-sinks: %w", err)
+	}
+	return nil
+}
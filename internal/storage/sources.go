@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/vmihailenco/msgpack/v5"
@@ -20,12 +23,146 @@ type Source struct {
 	LastCheckTime         time.Time     `msgpack:"last_check_time" json:"last_check_time"`
 	LastChangeTime        time.Time     `msgpack:"last_change_time" json:"last_change_time"` // When status last changed
 	Enabled               bool          `msgpack:"enabled" json:"enabled"`
+	Public                bool          `msgpack:"public" json:"public"` // Shown on the public status page
+	Tags                  []string      `msgpack:"tags" json:"tags,omitempty"` // Normalized with NormalizeTags
+	SLO                   *SLO          `msgpack:"slo" json:"slo,omitempty"`  // Availability objective; nil = none
+	ResponseLimits        *ResponseLimits `msgpack:"response_limits" json:"response_limits,omitempty"` // http only; nil = status code alone decides
+	ResponseSchema        json.RawMessage `msgpack:"response_schema" json:"response_schema,omitempty"` // http only: JSON Schema the body must satisfy
+	RedirectPolicy        *RedirectPolicy `msgpack:"redirect_policy" json:"redirect_policy,omitempty"` // http only; nil = follow up to 10
+	TLSPolicy             *TLSPolicy      `msgpack:"tls_policy" json:"tls_policy,omitempty"`             // http only; nil = Go's defaults
+	Resolver              string          `msgpack:"resolver" json:"resolver,omitempty"`                 // ping/http: DNS_RESOLVER syntax; empty = DNS_RESOLVER
+	AddressFamily         string          `msgpack:"address_family" json:"address_family,omitempty"`     // ping/http: ipv4, ipv6 or prefer-ipv6; empty = either
+	Proxy                 string          `msgpack:"proxy" json:"proxy,omitempty"`                       // http only: socks5:// URL checks go through; empty = direct
+	Headers               map[string]string `msgpack:"headers" json:"headers,omitempty"`                 // http only: request headers over HTTP_HEADERS; normalized with NormalizeHeaders
+	HTTPVersion           string          `msgpack:"http_version" json:"http_version,omitempty"`         // http only: "2" or "h3-advertised"; empty = any
+	Locations             []string      `msgpack:"locations" json:"locations,omitempty"` // Remote probe agents that also check it; normalized with NormalizeLocations
+	DependsOn             []string      `msgpack:"depends_on" json:"depends_on,omitempty"` // IDs of upstream sources it relies on, e.g. the router in front of it; see ValidateDependencies
 	CreatedAt             time.Time     `msgpack:"created_at" json:"created_at"`
+	// Details of the current failure; cleared when a check succeeds
+	LastError     string    `msgpack:"last_error" json:"last_error,omitempty"`
+	LastErrorTime time.Time `msgpack:"last_error_time" json:"last_error_time"`
 	// Webhook (incoming) source only
 	WebhookToken          string  `msgpack:"webhook_token" json:"webhook_token,omitempty"`
 	GracePeriodMultiplier float64 `msgpack:"grace_period_multiplier" json:"grace_period_multiplier,omitempty"`
 	ExpectedHeaders       string  `msgpack:"expected_headers" json:"expected_headers,omitempty"` // JSON object: {"Header-Name":"value"}
 	ExpectedContent       string  `msgpack:"expected_content" json:"expected_content,omitempty"`
+	LastHeartbeat         *Heartbeat `msgpack:"last_heartbeat" json:"last_heartbeat,omitempty"`
+	// Soft delete: set when the source is moved to trash, nil otherwise
+	DeletedAt *time.Time `msgpack:"deleted_at" json:"deleted_at,omitempty"`
+	// "file" for sources declared in CONFIG_FILE, which are reconciled with the file on every change
+	ManagedBy string `msgpack:"managed_by" json:"managed_by,omitempty"`
+	// Tenant owning the source; empty for global sources
+	Namespace string `msgpack:"namespace" json:"namespace,omitempty"`
+	// Text appended to outage and recovery notifications on every sink, e.g. a runbook link
+	OutageMessage   string `msgpack:"outage_message" json:"outage_message,omitempty"`
+	RecoveryMessage string `msgpack:"recovery_message" json:"recovery_message,omitempty"`
+}
+
+// MaxNotificationMessageLength caps a source's outage and recovery messages, which have to
+// fit into a Telegram message along with the standard text
+const MaxNotificationMessageLength = 1000
+
+// Heartbeat describes the last request received by a webhook source, for debugging which client pinged it
+type Heartbeat struct {
+	ReceivedAt     time.Time `msgpack:"received_at" json:"received_at"`
+	RemoteIP       string    `msgpack:"remote_ip" json:"remote_ip"`
+	UserAgent      string    `msgpack:"user_agent" json:"user_agent"`
+	Method         string    `msgpack:"method" json:"method"`
+	PayloadSnippet string    `msgpack:"payload_snippet" json:"payload_snippet,omitempty"` // First bytes of the request body
+}
+
+// NotificationMessage returns the custom text for a change to newStatus: the outage message
+// for offline, the recovery message for online
+func (s *Source) NotificationMessage(newStatus int) string {
+	if newStatus == 1 {
+		return s.RecoveryMessage
+	}
+	return s.OutageMessage
+}
+
+// IsDeleted reports whether the source has been soft-deleted
+func (s *Source) IsDeleted() bool {
+	return s.DeletedAt != nil
+}
+
+// HasTag reports whether the source carries the given (normalized) tag
+func (s *Source) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// HasLocation reports whether a probe agent at location checks the source
+func (s *Source) HasLocation(location string) bool {
+	for _, l := range s.Locations {
+		if l == location {
+			return true
+		}
+	}
+	return false
+}
+
+// maxTags and maxTagLength bound the tags on a single source
+const (
+	maxTags      = 20
+	maxTagLength = 32
+)
+
+// NormalizeTags lowercases, trims and de-duplicates tags, keeping their order.
+// Tags may contain letters, digits and "-", "_", ".", ":".
+func NormalizeTags(tags []string) ([]string, error) {
+	return normalizeNames("tag", tags, maxTags)
+}
+
+// LocalLocation names the central instance's own checks among probe locations
+const LocalLocation = "local"
+
+// maxLocations bounds the probe locations of a single source
+const maxLocations = 10
+
+// NormalizeLocations normalizes probe location names like tags. "local" is reserved for
+// the central instance.
+func NormalizeLocations(locations []string) ([]string, error) {
+	normalized, err := normalizeNames("location", locations, maxLocations)
+	if err != nil {
+		return nil, err
+	}
+	for _, location := range normalized {
+		if location == LocalLocation {
+			return nil, fmt.Errorf("location %q is reserved for the central instance", LocalLocation)
+		}
+	}
+	return normalized, nil
+}
+
+// normalizeNames lowercases, trims and de-duplicates names of the given kind, keeping
+// their order
+func normalizeNames(kind string, names []string, max int) ([]string, error) {
+	normalized := make([]string, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		if len(name) > maxTagLength {
+			return nil, fmt.Errorf("%s %q is longer than %d characters", kind, name, maxTagLength)
+		}
+		for _, r := range name {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_.:", r) {
+				return nil, fmt.Errorf("%s %q contains invalid character %q", kind, name, r)
+			}
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	if len(normalized) > max {
+		return nil, fmt.Errorf("at most %d %ss per source", max, kind)
+	}
+	return normalized, nil
 }
 
 // SaveSource stores a source in the database
@@ -47,7 +184,7 @@ func (b *BoltDB) SaveSource(source *Source) error {
 		return fmt.Errorf("failed to marshal source: %w", err)
 	}
 
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(sourcesBucket))
 		if bucket == nil {
 			return fmt.Errorf("sources bucket not found")
@@ -57,7 +194,46 @@ func (b *BoltDB) SaveSource(source *Source) error {
 			return fmt.Errorf("failed to save source: %w", err)
 		}
 
-		b.logger.Printf("Saved source: %s (%s %s)", source.Name, source.Type, source.Target)
+		b.logger.Debugf("Saved source: %s (%s %s)", source.Name, source.Type, source.Target)
+		return nil
+	})
+}
+
+// SaveSources stores several sources in a single transaction: either all are written or none
+func (b *BoltDB) SaveSources(sources []*Source) error {
+	now := time.Now()
+	encoded := make([][]byte, len(sources))
+	for i, source := range sources {
+		if source.ID == "" {
+			source.ID = uuid.New().String()
+		}
+		if source.CreatedAt.IsZero() {
+			source.CreatedAt = now
+		}
+		if source.LastChangeTime.IsZero() {
+			source.LastChangeTime = now
+		}
+
+		data, err := msgpack.Marshal(source)
+		if err != nil {
+			return fmt.Errorf("failed to marshal source %s: %w", source.ID, err)
+		}
+		encoded[i] = data
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(sourcesBucket))
+		if bucket == nil {
+			return fmt.Errorf("sources bucket not found")
+		}
+
+		for i, source := range sources {
+			if err := bucket.Put([]byte(source.ID), encoded[i]); err != nil {
+				return fmt.Errorf("failed to save source %s: %w", source.ID, err)
+			}
+		}
+
+		b.logger.Debugf("Saved %d sources", len(sources))
 		return nil
 	})
 }
@@ -66,7 +242,7 @@ func (b *BoltDB) SaveSource(source *Source) error {
 func (b *BoltDB) GetSource(id string) (*Source, error) {
 	var source *Source
 
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(sourcesBucket))
 		if bucket == nil {
 			return fmt.Errorf("sources bucket not found")
@@ -92,7 +268,7 @@ func (b *BoltDB) GetSource(id string) (*Source, error) {
 func (b *BoltDB) GetSourceByName(name string) (*Source, error) {
 	var source *Source
 
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(sourcesBucket))
 		if bucket == nil {
 			return fmt.Errorf("sources bucket not found")
@@ -106,7 +282,7 @@ func (b *BoltDB) GetSourceByName(name string) (*Source, error) {
 				continue
 			}
 
-			if s.Name == name {
+			if s.Name == name && !s.IsDeleted() {
 				source = &s
 				return nil
 			}
@@ -125,7 +301,7 @@ func (b *BoltDB) GetSourceByWebhookToken(token string) (*Source, error) {
 	}
 
 	var source *Source
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(sourcesBucket))
 		if bucket == nil {
 			return fmt.Errorf("sources bucket not found")
@@ -137,7 +313,7 @@ func (b *BoltDB) GetSourceByWebhookToken(token string) (*Source, error) {
 			if err := msgpack.Unmarshal(v, &s); err != nil {
 				continue
 			}
-			if s.Type == "webhook" && s.WebhookToken == token {
+			if s.Type == "webhook" && s.WebhookToken == token && !s.IsDeleted() {
 				source = &s
 				return nil
 			}
@@ -149,11 +325,21 @@ func (b *BoltDB) GetSourceByWebhookToken(token string) (*Source, error) {
 	return source, err
 }
 
-// GetAllSources retrieves all sources
+// GetAllSources retrieves all sources that are not soft-deleted
 func (b *BoltDB) GetAllSources() ([]*Source, error) {
+	return b.listSources(func(s *Source) bool { return !s.IsDeleted() })
+}
+
+// GetDeletedSources retrieves all soft-deleted sources
+func (b *BoltDB) GetDeletedSources() ([]*Source, error) {
+	return b.listSources(func(s *Source) bool { return s.IsDeleted() })
+}
+
+// listSources retrieves all sources matching the filter
+func (b *BoltDB) listSources(filter func(*Source) bool) ([]*Source, error) {
 	var sources []*Source
 
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(sourcesBucket))
 		if bucket == nil {
 			return fmt.Errorf("sources bucket not found")
@@ -164,11 +350,13 @@ func (b *BoltDB) GetAllSources() ([]*Source, error) {
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			var source Source
 			if err := msgpack.Unmarshal(v, &source); err != nil {
-				b.logger.Printf("Failed to unmarshal source: %v", err)
+				b.logger.Errorf("Failed to unmarshal source: %v", err)
 				continue
 			}
 
-			sources = append(sources, &source)
+			if filter(&source) {
+				sources = append(sources, &source)
+			}
 		}
 
 		return nil
@@ -194,9 +382,125 @@ func (b *BoltDB) GetEnabledSources() ([]*Source, error) {
 	return enabled, nil
 }
 
+// SoftDeleteSource moves a source to trash, keeping its history and associations
+func (b *BoltDB) SoftDeleteSource(id string) error {
+	now := time.Now()
+	return b.setSourceDeletedAt(id, &now)
+}
+
+// RestoreSource brings a soft-deleted source back from trash
+func (b *BoltDB) RestoreSource(id string) error {
+	return b.setSourceDeletedAt(id, nil)
+}
+
+// setSourceDeletedAt sets or clears the soft delete marker of a source
+func (b *BoltDB) setSourceDeletedAt(id string, deletedAt *time.Time) error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(sourcesBucket))
+		if bucket == nil {
+			return fmt.Errorf("sources bucket not found")
+		}
+
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("source not found")
+		}
+
+		var source Source
+		if err := msgpack.Unmarshal(data, &source); err != nil {
+			return fmt.Errorf("failed to unmarshal source: %w", err)
+		}
+
+		source.DeletedAt = deletedAt
+
+		newData, err := msgpack.Marshal(&source)
+		if err != nil {
+			return fmt.Errorf("failed to marshal source: %w", err)
+		}
+
+		if deletedAt != nil {
+			b.logger.Printf("Moved source to trash: %s (%s)", source.Name, id)
+		} else {
+			b.logger.Printf("Restored source from trash: %s (%s)", source.Name, id)
+		}
+		return bucket.Put([]byte(id), newData)
+	})
+}
+
+// PurgeSource permanently removes a source together with its status history
+// and sink links
+func (b *BoltDB) PurgeSource(id string) error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(sourcesBucket))
+		if bucket == nil {
+			return fmt.Errorf("sources bucket not found")
+		}
+
+		if err := bucket.Delete([]byte(id)); err != nil {
+			return fmt.Errorf("failed to delete source: %w", err)
+		}
+
+		if err := deleteSourceLinks(tx, id); err != nil {
+			return err
+		}
+
+		prefix := []byte(id + ":")
+		for _, name := range []string{statusChangesBucket, rollupsBucket, incidentNotesBucket, plannedOutagesBucket} {
+			if err := deletePrefix(tx.Bucket([]byte(name)), prefix); err != nil {
+				return fmt.Errorf("failed to purge %s: %w", name, err)
+			}
+		}
+
+		b.logger.Printf("Purged source: %s", id)
+		return nil
+	})
+}
+
+// PurgeDeletedSources permanently removes sources that have been in trash longer than olderThan
+func (b *BoltDB) PurgeDeletedSources(olderThan time.Duration) (int, error) {
+	deleted, err := b.GetDeletedSources()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	purged := 0
+	for _, source := range deleted {
+		if source.DeletedAt.After(cutoff) {
+			continue
+		}
+		if err := b.PurgeSource(source.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// deletePrefix removes all keys starting with prefix from a bucket
+func deletePrefix(bucket *bolt.Bucket, prefix []byte) error {
+	if bucket == nil {
+		return nil
+	}
+
+	c := bucket.Cursor()
+	var keysToDelete [][]byte
+	for k, _ := c.Seek(prefix); k != nil && startsWithPrefix(k, prefix); k, _ = c.Next() {
+		keysToDelete = append(keysToDelete, append([]byte(nil), k...))
+	}
+
+	for _, key := range keysToDelete {
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // DeleteSource removes a source from the database
 func (b *BoltDB) DeleteSource(id string) error {
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(sourcesBucket))
 		if bucket == nil {
 			return fmt.Errorf("sources bucket not found")
@@ -206,14 +510,24 @@ func (b *BoltDB) DeleteSource(id string) error {
 			return fmt.Errorf("failed to delete source: %w", err)
 		}
 
-		b.logger.Printf("Deleted source: %s", id)
+		b.logger.Debugf("Deleted source: %s", id)
 		return nil
 	})
 }
 
-// UpdateSourceStatus updates the status of a source
-func (b *BoltDB) UpdateSourceStatus(id string, status int, checkTime time.Time) error {
-	return b.db.Update(func(tx *bolt.Tx) error {
+// SetLastError records the failure reason of the latest check (empty clears it)
+func (s *Source) SetLastError(lastError string, checkTime time.Time) {
+	s.LastError = lastError
+	if lastError != "" {
+		s.LastErrorTime = checkTime
+	} else {
+		s.LastErrorTime = time.Time{}
+	}
+}
+
+// UpdateSourceStatus updates the status of a source along with the failure reason of the check
+func (b *BoltDB) UpdateSourceStatus(id string, status int, checkTime time.Time, lastError string) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(sourcesBucket))
 		if bucket == nil {
 			return fmt.Errorf("sources bucket not found")
@@ -232,6 +546,7 @@ func (b *BoltDB) UpdateSourceStatus(id string, status int, checkTime time.Time)
 		oldStatus := source.CurrentStatus
 		source.CurrentStatus = status
 		source.LastCheckTime = checkTime
+		source.SetLastError(lastError, checkTime)
 
 		if status != oldStatus {
 			source.LastChangeTime = checkTime
@@ -246,10 +561,97 @@ func (b *BoltDB) UpdateSourceStatus(id string, status int, checkTime time.Time)
 	})
 }
 
+// RecordHeartbeat marks a webhook source online at heartbeat.ReceivedAt and stores the request metadata
+func (b *BoltDB) RecordHeartbeat(id string, heartbeat *Heartbeat) error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(sourcesBucket))
+		if bucket == nil {
+			return fmt.Errorf("sources bucket not found")
+		}
+
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("source not found")
+		}
+
+		var source Source
+		if err := msgpack.Unmarshal(data, &source); err != nil {
+			return fmt.Errorf("failed to unmarshal source: %w", err)
+		}
+
+		if source.CurrentStatus != 1 {
+			source.LastChangeTime = heartbeat.ReceivedAt
+		}
+		source.CurrentStatus = 1
+		source.LastCheckTime = heartbeat.ReceivedAt
+		source.SetLastError("", heartbeat.ReceivedAt)
+		source.LastHeartbeat = heartbeat
+
+		newData, err := msgpack.Marshal(&source)
+		if err != nil {
+			return fmt.Errorf("failed to marshal source: %w", err)
+		}
+
+		return bucket.Put([]byte(id), newData)
+	})
+}
+
+// CheckResult is a buffered outcome of a check that did not change the source's status
+type CheckResult struct {
+	SourceID  string
+	CheckTime time.Time
+	LastError string
+}
+
+// SaveCheckResults persists buffered check results in a single transaction.
+// Only LastCheckTime and the last error are written; results older than the stored
+// LastCheckTime (e.g. superseded by a status change) and unknown sources are skipped.
+func (b *BoltDB) SaveCheckResults(results []CheckResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(sourcesBucket))
+		if bucket == nil {
+			return fmt.Errorf("sources bucket not found")
+		}
+
+		for _, result := range results {
+			data := bucket.Get([]byte(result.SourceID))
+			if data == nil {
+				continue // Source was purged since the check ran
+			}
+
+			var source Source
+			if err := msgpack.Unmarshal(data, &source); err != nil {
+				return fmt.Errorf("failed to unmarshal source: %w", err)
+			}
+
+			if !result.CheckTime.After(source.LastCheckTime) {
+				continue
+			}
+
+			source.LastCheckTime = result.CheckTime
+			source.SetLastError(result.LastError, result.CheckTime)
+
+			newData, err := msgpack.Marshal(&source)
+			if err != nil {
+				return fmt.Errorf("failed to marshal source: %w", err)
+			}
+			if err := bucket.Put([]byte(result.SourceID), newData); err != nil {
+				return fmt.Errorf("failed to save check result: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
 // UpdateSourceCurrentStatus updates only CurrentStatus and LastChangeTime without touching LastCheckTime.
 // Use for webhook sources where LastCheckTime tracks the last heartbeat received, not the last monitor tick.
-func (b *BoltDB) UpdateSourceCurrentStatus(id string, status int, changeTime time.Time) error {
-	return b.db.Update(func(tx *bolt.Tx) error {
+func (b *BoltDB) UpdateSourceCurrentStatus(id string, status int, changeTime time.Time, lastError string) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(sourcesBucket))
 		if bucket == nil {
 			return fmt.Errorf("sources bucket not found")
@@ -270,6 +672,7 @@ func (b *BoltDB) UpdateSourceCurrentStatus(id string, status int, changeTime tim
 		if status != oldStatus {
 			source.LastChangeTime = changeTime
 		}
+		source.SetLastError(lastError, changeTime)
 		// LastCheckTime is intentionally not updated here
 
 		newData, err := msgpack.Marshal(&source)
@@ -288,7 +691,7 @@ func (b *BoltDB) UpdateSource(source *Source) error {
 		return fmt.Errorf("failed to marshal source: %w", err)
 	}
 
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(sourcesBucket))
 		if bucket == nil {
 			return fmt.Errorf("sources bucket not found")
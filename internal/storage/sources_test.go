@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSourceLastError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := NewBoltDB(path)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	checkTime := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	source := &Source{ID: "api", Name: "API", Type: "http", Target: "https://example.com", CurrentStatus: 1, Enabled: true}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+
+	// A failed check stores the reason with its time, and it survives reopening the database
+	if err := db.UpdateSourceStatus("api", 0, checkTime, "HTTP 503"); err != nil {
+		t.Fatalf("UpdateSourceStatus failed: %v", err)
+	}
+	db.Close()
+	if db, err = NewBoltDB(path); err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	stored, err := db.GetSource("api")
+	if err != nil || stored.LastError != "HTTP 503" || !stored.LastErrorTime.Equal(checkTime) {
+		t.Fatalf("Expected the error and its time stored, got %q at %v (%v)", stored.LastError, stored.LastErrorTime, err)
+	}
+
+	// Buffered results update it too
+	later := checkTime.Add(time.Minute)
+	if err := db.SaveCheckResults([]CheckResult{{SourceID: "api", CheckTime: later, LastError: "connection refused"}}); err != nil {
+		t.Fatalf("SaveCheckResults failed: %v", err)
+	}
+	if stored, _ := db.GetSource("api"); stored.LastError != "connection refused" || !stored.LastErrorTime.Equal(later) {
+		t.Errorf("Expected the buffered error stored, got %q at %v", stored.LastError, stored.LastErrorTime)
+	}
+
+	// A successful check clears it
+	if err := db.UpdateSourceStatus("api", 1, later.Add(time.Minute), ""); err != nil {
+		t.Fatalf("UpdateSourceStatus failed: %v", err)
+	}
+	if stored, _ := db.GetSource("api"); stored.LastError != "" || !stored.LastErrorTime.IsZero() {
+		t.Errorf("Expected the error cleared, got %q at %v", stored.LastError, stored.LastErrorTime)
+	}
+
+	// So does a heartbeat of a webhook source
+	webhook := &Source{ID: "cron", Name: "Cron", Type: "webhook", CurrentStatus: 0, LastError: "no heartbeat", LastErrorTime: checkTime}
+	if err := db.SaveSource(webhook); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+	if err := db.RecordHeartbeat("cron", &Heartbeat{ReceivedAt: later}); err != nil {
+		t.Fatalf("RecordHeartbeat failed: %v", err)
+	}
+	if stored, _ := db.GetSource("cron"); stored.LastError != "" || !stored.LastErrorTime.IsZero() {
+		t.Errorf("Expected the heartbeat to clear the error, got %q at %v", stored.LastError, stored.LastErrorTime)
+	}
+}
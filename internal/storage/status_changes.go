@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,7 +19,10 @@ type StatusChange struct {
 	OldStatus  int       `msgpack:"old_status"`
 	NewStatus  int       `msgpack:"new_status"`
 	Timestamp  time.Time `msgpack:"timestamp"`
-	DurationMs int64     `msgpack:"duration_ms"` // Duration since last change in milliseconds
+	DurationMs int64     `msgpack:"duration_ms"`        // Duration since last change in milliseconds
+	Test       bool      `msgpack:"-"`                  // Synthetic change sent by a notification test, never stored
+	GroupID    string    `msgpack:"group_id,omitempty"` // Alert group it was notified in, if any
+	Hint       string    `msgpack:"-"`                  // Likely cause of an outage, set for notifications only
 }
 
 // makeStatusChangeKey creates a sortable key from source ID and timestamp
@@ -43,7 +48,7 @@ func (b *BoltDB) SaveStatusChange(change *StatusChange) error {
 		return fmt.Errorf("failed to marshal status change: %w", err)
 	}
 
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(statusChangesBucket))
 		if bucket == nil {
 			return fmt.Errorf("status_changes bucket not found")
@@ -55,100 +60,115 @@ func (b *BoltDB) SaveStatusChange(change *StatusChange) error {
 			return fmt.Errorf("failed to save status change: %w", err)
 		}
 
-		b.logger.Printf("Saved status change: source=%s, %d→%d, duration=%dms",
+		b.logger.Debugf("Saved status change: source=%s, %d→%d, duration=%dms",
 			change.SourceID, change.OldStatus, change.NewStatus, change.DurationMs)
 		return nil
 	})
 }
 
-// GetStatusChanges retrieves the latest N status changes for a specific source
-func (b *BoltDB) GetStatusChanges(sourceID string, limit int) ([]*StatusChange, error) {
+// GetStatusChanges retrieves the latest N status changes for a specific source, newest first.
+// A non-zero from/to restricts results to from <= timestamp < to; the range is resolved by
+// seeking the timestamp-ordered keys, so older ranges don't require scanning newer history.
+func (b *BoltDB) GetStatusChanges(sourceID string, from, to time.Time, limit int) ([]*StatusChange, error) {
 	var changes []*StatusChange
 
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(statusChangesBucket))
 		if bucket == nil {
 			return fmt.Errorf("status_changes bucket not found")
 		}
 
-		c := bucket.Cursor()
-		prefix := []byte(sourceID + ":")
-
-		// Collect all keys for this source
-		var keys [][]byte
-		for k, _ := c.Seek(prefix); k != nil && len(k) >= len(prefix) && string(k[:len(prefix)]) == string(prefix); k, _ = c.Next() {
-			keys = append(keys, append([]byte(nil), k...))
-		}
-
-		// Iterate in reverse to get newest first
-		count := 0
-		for i := len(keys) - 1; i >= 0 && count < limit; i-- {
-			v := bucket.Get(keys[i])
-			if v == nil {
-				continue
-			}
-
-			var change StatusChange
-			if err := msgpack.Unmarshal(v, &change); err != nil {
-				b.logger.Printf("Failed to unmarshal status change: %v", err)
-				continue
-			}
-
-			changes = append(changes, &change)
-			count++
-		}
-
+		changes = b.scanStatusChanges(bucket.Cursor(), sourceID, from, to, limit)
 		return nil
 	})
 
 	return changes, err
 }
 
-// GetRecentChanges retrieves the latest N status changes across all sources
-func (b *BoltDB) GetRecentChanges(limit int) ([]*StatusChange, error) {
+// GetRecentChanges retrieves the latest N status changes across all sources, newest first.
+// A non-zero from/to restricts results to from <= timestamp < to.
+func (b *BoltDB) GetRecentChanges(from, to time.Time, limit int) ([]*StatusChange, error) {
 	var changes []*StatusChange
 
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(statusChangesBucket))
 		if bucket == nil {
 			return fmt.Errorf("status_changes bucket not found")
 		}
 
+		// Keys are grouped by source, so visit each source's key range in turn
 		c := bucket.Cursor()
-
-		// Collect all keys
-		var keys [][]byte
-		for k, _ := c.First(); k != nil; k, _ = c.Next() {
-			keys = append(keys, append([]byte(nil), k...))
-		}
-
-		// Iterate in reverse to get newest first
-		count := 0
-		for i := len(keys) - 1; i >= 0 && count < limit; i-- {
-			v := bucket.Get(keys[i])
-			if v == nil {
+		for k, _ := c.First(); k != nil; {
+			if len(k) < 9 {
+				k, _ = c.Next()
 				continue
 			}
+			sourceID := string(k[:len(k)-9])
+			changes = append(changes, b.scanStatusChanges(c, sourceID, from, to, limit)...)
 
-			var change StatusChange
-			if err := msgpack.Unmarshal(v, &change); err != nil {
-				b.logger.Printf("Failed to unmarshal status change: %v", err)
-				continue
-			}
-
-			changes = append(changes, &change)
-			count++
+			// ';' sorts right after ':', so this skips past every key of the source
+			k, _ = c.Seek([]byte(sourceID + ";"))
 		}
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return changes, err
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Timestamp.After(changes[j].Timestamp)
+	})
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+
+	return changes, nil
+}
+
+// scanStatusChanges walks a source's keys backwards from to (exclusive) down to from (inclusive)
+func (b *BoltDB) scanStatusChanges(c *bolt.Cursor, sourceID string, from, to time.Time, limit int) []*StatusChange {
+	var changes []*StatusChange
+
+	prefix := []byte(sourceID + ":")
+	var startKey []byte
+	if !from.IsZero() {
+		startKey = makeStatusChangeKey(sourceID, from)
+	}
+
+	// Position the cursor on the newest key below the upper bound
+	var k, v []byte
+	if to.IsZero() {
+		k, v = c.Seek([]byte(sourceID + ";"))
+	} else {
+		k, v = c.Seek(makeStatusChangeKey(sourceID, to))
+	}
+	if k == nil {
+		k, v = c.Last()
+	} else {
+		k, v = c.Prev()
+	}
+
+	for ; k != nil && startsWithPrefix(k, prefix) && len(changes) < limit; k, v = c.Prev() {
+		if startKey != nil && bytes.Compare(k, startKey) < 0 {
+			break
+		}
+
+		var change StatusChange
+		if err := msgpack.Unmarshal(v, &change); err != nil {
+			b.logger.Errorf("Failed to unmarshal status change: %v", err)
+			continue
+		}
+
+		changes = append(changes, &change)
+	}
+
+	return changes
 }
 
 // GetLastStatusChange retrieves the most recent status change for a source
 func (b *BoltDB) GetLastStatusChange(sourceID string) (*StatusChange, error) {
-	changes, err := b.GetStatusChanges(sourceID, 1)
+	changes, err := b.GetStatusChanges(sourceID, time.Time{}, time.Time{}, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +185,7 @@ func (b *BoltDB) DeleteOldStatusChanges(olderThan time.Duration) (int, error) {
 	cutoff := time.Now().Add(-olderThan)
 	deleted := 0
 
-	err := b.db.Update(func(tx *bolt.Tx) error {
+	err := b.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(statusChangesBucket))
 		if bucket == nil {
 			return fmt.Errorf("status_changes bucket not found")
@@ -187,7 +207,8 @@ func (b *BoltDB) DeleteOldStatusChanges(olderThan time.Duration) (int, error) {
 			}
 		}
 
-		return nil
+		// Alert groups go with their last change
+		return deleteOldAlertGroups(tx, cutoff)
 	})
 
 	if err == nil && deleted > 0 {
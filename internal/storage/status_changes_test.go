@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatusChangesTimeRange(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	// One change per day in February, March and April for two sources
+	start := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+	for _, sourceID := range []string{"a", "b"} {
+		for day := start; day.Before(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)); day = day.AddDate(0, 0, 1) {
+			change := &StatusChange{SourceID: sourceID, OldStatus: 1, NewStatus: 0, Timestamp: day}
+			if err := db.SaveStatusChange(change); err != nil {
+				t.Fatalf("SaveStatusChange failed: %v", err)
+			}
+		}
+	}
+
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	changes, err := db.GetStatusChanges("a", march, april, 1000)
+	if err != nil {
+		t.Fatalf("GetStatusChanges failed: %v", err)
+	}
+	if len(changes) != 31 {
+		t.Fatalf("Expected 31 changes in March, got %d", len(changes))
+	}
+	if !changes[0].Timestamp.Equal(time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected newest March change first, got %v", changes[0].Timestamp)
+	}
+	if !changes[30].Timestamp.Equal(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected oldest March change last, got %v", changes[30].Timestamp)
+	}
+
+	// Open-ended range with a limit returns the newest changes
+	changes, err = db.GetStatusChanges("b", time.Time{}, time.Time{}, 5)
+	if err != nil {
+		t.Fatalf("GetStatusChanges failed: %v", err)
+	}
+	if len(changes) != 5 || !changes[0].Timestamp.Equal(time.Date(2026, 4, 30, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 5 newest changes ending 2026-04-30, got %d starting %v", len(changes), changes[0].Timestamp)
+	}
+
+	recent, err := db.GetRecentChanges(march, april, 1000)
+	if err != nil {
+		t.Fatalf("GetRecentChanges failed: %v", err)
+	}
+	if len(recent) != 62 {
+		t.Fatalf("Expected 62 changes in March across sources, got %d", len(recent))
+	}
+	for i := 1; i < len(recent); i++ {
+		if recent[i].Timestamp.After(recent[i-1].Timestamp) {
+			t.Fatalf("Expected newest first, got %v after %v", recent[i].Timestamp, recent[i-1].Timestamp)
+		}
+	}
+}
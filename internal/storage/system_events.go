@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// System event types
+const (
+	SystemEventStartup      = "startup"
+	SystemEventShutdown     = "shutdown"
+	SystemEventBotStart     = "bot_start"
+	SystemEventBotStop      = "bot_stop"
+	SystemEventBotRestart   = "bot_restart"
+	SystemEventConfigChange = "config_change"
+	SystemEventPanic        = "panic"
+	SystemEventWatchdog     = "watchdog"
+)
+
+// SystemEvent records something that happened to the application itself, as opposed to
+// the monitored sources: startups, shutdowns, bot restarts, config changes and panics
+type SystemEvent struct {
+	ID        string            `msgpack:"id" json:"id"`
+	Timestamp time.Time         `msgpack:"timestamp" json:"timestamp"`
+	Type      string            `msgpack:"type" json:"type"`
+	Message   string            `msgpack:"message" json:"message"`
+	Details   map[string]string `msgpack:"details" json:"details,omitempty"`
+}
+
+// SystemEventFilter narrows down system event queries; zero values match everything
+type SystemEventFilter struct {
+	Type  string
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+// RecordSystemEvent stores an application lifecycle event
+func (b *BoltDB) RecordSystemEvent(event *SystemEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	data, err := msgpack.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal system event: %w", err)
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(systemEventsBucket))
+		if bucket == nil {
+			return fmt.Errorf("system events bucket not found")
+		}
+
+		// Same time-ordered key layout as the delivery log
+		if err := bucket.Put(makeDeliveryKey(event.Timestamp, event.ID), data); err != nil {
+			return fmt.Errorf("failed to save system event: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetSystemEvents retrieves system events matching the filter, newest first
+func (b *BoltDB) GetSystemEvents(filter SystemEventFilter) ([]*SystemEvent, error) {
+	var events []*SystemEvent
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(systemEventsBucket))
+		if bucket == nil {
+			return fmt.Errorf("system events bucket not found")
+		}
+
+		c := bucket.Cursor()
+		k, v := c.Last()
+		if !filter.To.IsZero() {
+			// Position on the last entry before To
+			k, v = c.Seek(makeDeliveryKey(filter.To, ""))
+			if k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		}
+
+		var from []byte
+		if !filter.From.IsZero() {
+			from = makeDeliveryKey(filter.From, "")
+		}
+
+		for ; k != nil; k, v = c.Prev() {
+			if from != nil && bytes.Compare(k, from) < 0 {
+				break
+			}
+			if filter.Limit > 0 && len(events) >= filter.Limit {
+				break
+			}
+
+			var event SystemEvent
+			if err := msgpack.Unmarshal(v, &event); err != nil {
+				b.logger.Errorf("Failed to unmarshal system event: %v", err)
+				continue
+			}
+
+			if filter.Type != "" && event.Type != filter.Type {
+				continue
+			}
+
+			events = append(events, &event)
+		}
+
+		return nil
+	})
+
+	return events, err
+}
+
+// GetLastSystemEvent returns the most recent system event, or nil if none was recorded
+func (b *BoltDB) GetLastSystemEvent() (*SystemEvent, error) {
+	events, err := b.GetSystemEvents(SystemEventFilter{Limit: 1})
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	return events[0], nil
+}
+
+// DeleteOldSystemEvents removes system events older than the specified duration
+func (b *BoltDB) DeleteOldSystemEvents(olderThan time.Duration) (int, error) {
+	cutoff := makeDeliveryKey(time.Now().Add(-olderThan), "")
+	deleted := 0
+
+	err := b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(systemEventsBucket))
+		if bucket == nil {
+			return fmt.Errorf("system events bucket not found")
+		}
+
+		// Keys are time-ordered, so old entries form a prefix of the bucket
+		c := bucket.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			deleted++
+		}
+
+		return nil
+	})
+
+	if err == nil && deleted > 0 {
+		b.logger.Printf("Deleted %d old system events", deleted)
+	}
+
+	return deleted, err
+}
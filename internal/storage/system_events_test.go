@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSystemEvents(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	if last, err := db.GetLastSystemEvent(); err != nil || last != nil {
+		t.Fatalf("Expected no last event in empty log, got %v, %v", last, err)
+	}
+
+	start := time.Now().Add(-10 * time.Hour)
+	types := []string{SystemEventStartup, SystemEventBotRestart, SystemEventConfigChange, SystemEventShutdown, SystemEventStartup}
+	for i, eventType := range types {
+		event := &SystemEvent{Type: eventType, Message: eventType, Timestamp: start.Add(time.Duration(i) * time.Hour)}
+		if err := db.RecordSystemEvent(event); err != nil {
+			t.Fatalf("RecordSystemEvent failed: %v", err)
+		}
+		if event.ID == "" {
+			t.Fatal("Expected RecordSystemEvent to assign an ID")
+		}
+	}
+
+	events, err := db.GetSystemEvents(SystemEventFilter{})
+	if err != nil {
+		t.Fatalf("GetSystemEvents failed: %v", err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("Expected 5 events, got %d", len(events))
+	}
+	if !events[0].Timestamp.Equal(start.Add(4 * time.Hour)) {
+		t.Errorf("Expected newest event first, got %v", events[0].Timestamp)
+	}
+
+	startups, _ := db.GetSystemEvents(SystemEventFilter{Type: SystemEventStartup})
+	if len(startups) != 2 {
+		t.Errorf("Expected 2 startups, got %d", len(startups))
+	}
+
+	// From is inclusive, To is exclusive
+	window, _ := db.GetSystemEvents(SystemEventFilter{From: start.Add(time.Hour), To: start.Add(3 * time.Hour)})
+	if len(window) != 2 || window[0].Type != SystemEventConfigChange || window[1].Type != SystemEventBotRestart {
+		t.Errorf("Expected config change and bot restart in window, got %+v", window)
+	}
+
+	limited, _ := db.GetSystemEvents(SystemEventFilter{Limit: 3})
+	if len(limited) != 3 {
+		t.Errorf("Expected 3 events with limit, got %d", len(limited))
+	}
+
+	last, err := db.GetLastSystemEvent()
+	if err != nil || last == nil || last.Type != SystemEventStartup {
+		t.Errorf("Expected last event to be a startup, got %v, %v", last, err)
+	}
+
+	deleted, err := db.DeleteOldSystemEvents(8 * time.Hour)
+	if err != nil {
+		t.Fatalf("DeleteOldSystemEvents failed: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("Expected 3 old events deleted, got %d", deleted)
+	}
+}
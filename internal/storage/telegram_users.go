@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// telegramUserTouchInterval limits how often an unchanged user's LastSeenAt is rewritten
+const telegramUserTouchInterval = time.Hour
+
+// TelegramUser is a Telegram user who has messaged the bot, recorded so that the allow-list
+// can be managed by @username: the Bot API can't look users up by name
+type TelegramUser struct {
+	ID         int64     `msgpack:"id" json:"id"`
+	Username   string    `msgpack:"username" json:"username,omitempty"` // Without the @
+	FirstName  string    `msgpack:"first_name" json:"first_name,omitempty"`
+	LastSeenAt time.Time `msgpack:"last_seen_at" json:"last_seen_at"`
+}
+
+// RecordTelegramUser stores a user the bot has heard from. Unchanged users are only rewritten
+// once per telegramUserTouchInterval, as this runs for every message.
+func (b *BoltDB) RecordTelegramUser(user *TelegramUser) error {
+	if user.LastSeenAt.IsZero() {
+		user.LastSeenAt = time.Now()
+	}
+	if existing, err := b.GetTelegramUser(user.ID); err == nil &&
+		existing.Username == user.Username && existing.FirstName == user.FirstName &&
+		user.LastSeenAt.Sub(existing.LastSeenAt) < telegramUserTouchInterval {
+		return nil
+	}
+
+	data, err := msgpack.Marshal(user)
+	if err != nil {
+		return fmt.Errorf("failed to marshal telegram user: %w", err)
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(telegramUsersBucket))
+		if bucket == nil {
+			return fmt.Errorf("telegram users bucket not found")
+		}
+		if err := bucket.Put(chatKey(user.ID), data); err != nil {
+			return fmt.Errorf("failed to save telegram user: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetTelegramUser retrieves a recorded user by ID
+func (b *BoltDB) GetTelegramUser(id int64) (*TelegramUser, error) {
+	var user *TelegramUser
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(telegramUsersBucket))
+		if bucket == nil {
+			return fmt.Errorf("telegram users bucket not found")
+		}
+		data := bucket.Get(chatKey(id))
+		if data == nil {
+			return fmt.Errorf("telegram user not found")
+		}
+		user = &TelegramUser{}
+		return msgpack.Unmarshal(data, user)
+	})
+	return user, err
+}
+
+// ListTelegramUsers returns recorded users, most recently seen first
+func (b *BoltDB) ListTelegramUsers() ([]*TelegramUser, error) {
+	users := []*TelegramUser{}
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(telegramUsersBucket))
+		if bucket == nil {
+			return fmt.Errorf("telegram users bucket not found")
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			user := &TelegramUser{}
+			if err := msgpack.Unmarshal(v, user); err != nil {
+				b.logger.Errorf("Failed to unmarshal telegram user: %v", err)
+				return nil
+			}
+			users = append(users, user)
+			return nil
+		})
+	})
+
+	sort.Slice(users, func(i, j int) bool { return users[i].LastSeenAt.After(users[j].LastSeenAt) })
+	return users, err
+}
+
+// ResolveTelegramUser returns the ID of a user given as a numeric ID or as the @username of a
+// user who has messaged the bot. Usernames can move between accounts, so the most recently
+// seen holder wins.
+func (b *BoltDB) ResolveTelegramUser(ref string) (int64, error) {
+	ref = strings.TrimSpace(ref)
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return id, nil
+	}
+
+	username := strings.TrimPrefix(ref, "@")
+	if username == "" {
+		return 0, fmt.Errorf("user is required")
+	}
+	users, err := b.ListTelegramUsers()
+	if err != nil {
+		return 0, err
+	}
+	for _, user := range users {
+		if strings.EqualFold(user.Username, username) {
+			return user.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown user @%s: they have to message the bot once first, or use their numeric ID", username)
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTelegramUsers(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	seen := time.Now().Add(-2 * time.Hour)
+	if err := db.RecordTelegramUser(&TelegramUser{ID: 111, Username: "alice", LastSeenAt: seen}); err != nil {
+		t.Fatalf("RecordTelegramUser failed: %v", err)
+	}
+	// An unchanged user seen again within the interval isn't rewritten
+	if err := db.RecordTelegramUser(&TelegramUser{ID: 111, Username: "alice", LastSeenAt: seen.Add(time.Minute)}); err != nil {
+		t.Fatalf("RecordTelegramUser failed: %v", err)
+	}
+	if user, err := db.GetTelegramUser(111); err != nil || !user.LastSeenAt.Equal(seen) {
+		t.Errorf("Expected last seen to stay at %v, got %+v (%v)", seen, user, err)
+	}
+
+	// The username moved to another account, which was seen more recently
+	if err := db.RecordTelegramUser(&TelegramUser{ID: 222, Username: "Alice"}); err != nil {
+		t.Fatalf("RecordTelegramUser failed: %v", err)
+	}
+	if id, err := db.ResolveTelegramUser("@alice"); err != nil || id != 222 {
+		t.Errorf("Expected @alice to resolve to 222, got %d (%v)", id, err)
+	}
+	if id, err := db.ResolveTelegramUser(" 333 "); err != nil || id != 333 {
+		t.Errorf("Expected a numeric ID to resolve as is, got %d (%v)", id, err)
+	}
+	if _, err := db.ResolveTelegramUser("@bob"); err == nil {
+		t.Error("Expected error for a user who never messaged the bot")
+	}
+
+	users, err := db.ListTelegramUsers()
+	if err != nil || len(users) != 2 || users[0].ID != 222 {
+		t.Errorf("Expected 2 users, most recent first, got %v (%v)", users, err)
+	}
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+// SourceTemplate holds the defaults of near-identical sources, so a new one only needs a
+// name and target. Sources are copies: editing or deleting a template doesn't change them.
+type SourceTemplate struct {
+	ID                    string            `msgpack:"id" json:"id"`
+	Name                  string            `msgpack:"name" json:"name"` // Unique, case-insensitive
+	Type                  string            `msgpack:"type" json:"type"`
+	CheckInterval         time.Duration     `msgpack:"check_interval" json:"check_interval"`
+	GracePeriodMultiplier float64           `msgpack:"grace_period_multiplier" json:"grace_period_multiplier,omitempty"`
+	ExpectedHeaders       string            `msgpack:"expected_headers" json:"expected_headers,omitempty"`
+	ExpectedContent       string            `msgpack:"expected_content" json:"expected_content,omitempty"`
+	Public                bool              `msgpack:"public" json:"public"`
+	Tags                  []string          `msgpack:"tags" json:"tags,omitempty"`
+	SLO                   *SLO              `msgpack:"slo" json:"slo,omitempty"`
+	ResponseLimits        *ResponseLimits   `msgpack:"response_limits" json:"response_limits,omitempty"`
+	ResponseSchema        json.RawMessage   `msgpack:"response_schema" json:"response_schema,omitempty"`
+	RedirectPolicy        *RedirectPolicy   `msgpack:"redirect_policy" json:"redirect_policy,omitempty"`
+	TLSPolicy             *TLSPolicy        `msgpack:"tls_policy" json:"tls_policy,omitempty"`
+	Locations             []string          `msgpack:"locations" json:"locations,omitempty"`
+	Resolver              string            `msgpack:"resolver" json:"resolver,omitempty"`
+	AddressFamily         string            `msgpack:"address_family" json:"address_family,omitempty"`
+	Proxy                 string            `msgpack:"proxy" json:"proxy,omitempty"`
+	Headers               map[string]string `msgpack:"headers" json:"headers,omitempty"`
+	HTTPVersion           string            `msgpack:"http_version" json:"http_version,omitempty"`
+	OutageMessage         string            `msgpack:"outage_message" json:"outage_message,omitempty"`
+	RecoveryMessage       string            `msgpack:"recovery_message" json:"recovery_message,omitempty"`
+	// Notification sinks attached to every source created from the template
+	ChatIDs    []int64   `msgpack:"chat_ids" json:"telegram_chat_ids,omitempty"`
+	WebhookIDs []string  `msgpack:"webhook_ids" json:"webhook_ids,omitempty"`
+	CreatedAt  time.Time `msgpack:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `msgpack:"updated_at" json:"updated_at"`
+}
+
+// NewSource builds an enabled source with the template's settings. The caller validates
+// name and target, and assigns a webhook token for webhook templates.
+func (t *SourceTemplate) NewSource(name, target string) *Source {
+	var slo *SLO
+	if t.SLO != nil {
+		copied := *t.SLO
+		slo = &copied
+	}
+	if t.Type == "webhook" {
+		target = ""
+	}
+
+	return &Source{
+		ID:                    uuid.New().String(),
+		Name:                  name,
+		Type:                  t.Type,
+		Target:                target,
+		CheckInterval:         t.CheckInterval,
+		CurrentStatus:         -1,
+		Enabled:               true,
+		Public:                t.Public,
+		Tags:                  append([]string(nil), t.Tags...),
+		SLO:                   slo,
+		ResponseLimits:        t.ResponseLimits.Copy(),
+		ResponseSchema:        slices.Clone(t.ResponseSchema),
+		RedirectPolicy:        t.RedirectPolicy.Copy(),
+		TLSPolicy:             t.TLSPolicy.Copy(),
+		Locations:             append([]string(nil), t.Locations...),
+		Resolver:              t.Resolver,
+		AddressFamily:         t.AddressFamily,
+		Proxy:                 t.Proxy,
+		Headers:               maps.Clone(t.Headers),
+		HTTPVersion:           t.HTTPVersion,
+		CreatedAt:             time.Now(),
+		GracePeriodMultiplier: t.GracePeriodMultiplier,
+		ExpectedHeaders:       t.ExpectedHeaders,
+		ExpectedContent:       t.ExpectedContent,
+		OutageMessage:         t.OutageMessage,
+		RecoveryMessage:       t.RecoveryMessage,
+	}
+}
+
+// SaveSourceTemplate creates or replaces a source template
+func (b *BoltDB) SaveSourceTemplate(template *SourceTemplate) error {
+	if template.ID == "" {
+		template.ID = uuid.New().String()
+	}
+	if template.CreatedAt.IsZero() {
+		template.CreatedAt = time.Now()
+	}
+	template.UpdatedAt = time.Now()
+
+	data, err := msgpack.Marshal(template)
+	if err != nil {
+		return fmt.Errorf("failed to marshal source template: %w", err)
+	}
+
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(templatesBucket))
+		if bucket == nil {
+			return fmt.Errorf("source_templates bucket not found")
+		}
+		if err := bucket.Put([]byte(template.ID), data); err != nil {
+			return fmt.Errorf("failed to save source template: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetSourceTemplate retrieves a source template by ID
+func (b *BoltDB) GetSourceTemplate(id string) (*SourceTemplate, error) {
+	var template SourceTemplate
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(templatesBucket))
+		if bucket == nil {
+			return fmt.Errorf("source_templates bucket not found")
+		}
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("source template not found")
+		}
+		return msgpack.Unmarshal(data, &template)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// FindSourceTemplate retrieves a source template by ID or case-insensitive name
+func (b *BoltDB) FindSourceTemplate(ref string) (*SourceTemplate, error) {
+	if template, err := b.GetSourceTemplate(ref); err == nil {
+		return template, nil
+	}
+
+	templates, err := b.GetSourceTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, template := range templates {
+		if strings.EqualFold(template.Name, ref) {
+			return template, nil
+		}
+	}
+	return nil, fmt.Errorf("source template not found")
+}
+
+// GetSourceTemplates retrieves all source templates, by name
+func (b *BoltDB) GetSourceTemplates() ([]*SourceTemplate, error) {
+	var templates []*SourceTemplate
+
+	err := b.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(templatesBucket))
+		if bucket == nil {
+			return fmt.Errorf("source_templates bucket not found")
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var template SourceTemplate
+			if err := msgpack.Unmarshal(v, &template); err != nil {
+				b.logger.Errorf("Failed to unmarshal source template: %v", err)
+				return nil // Skip malformed templates
+			}
+			templates = append(templates, &template)
+			return nil
+		})
+	})
+
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, err
+}
+
+// DeleteSourceTemplate removes a source template; sources created from it are kept
+func (b *BoltDB) DeleteSourceTemplate(id string) error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(templatesBucket))
+		if bucket == nil {
+			return fmt.Errorf("source_templates bucket not found")
+		}
+		if bucket.Get([]byte(id)) == nil {
+			return fmt.Errorf("source template not found")
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// AttachTemplateSinks links the template's Telegram chats and webhooks to a source created
+// from it. Webhooks deleted since the template was saved are skipped.
+func (b *BoltDB) AttachTemplateSinks(template *SourceTemplate, sourceID string) error {
+	for _, chatID := range template.ChatIDs {
+		if err := b.AddSourceChat(sourceID, chatID); err != nil {
+			return err
+		}
+	}
+	for _, webhookID := range template.WebhookIDs {
+		if _, err := b.GetWebhook(webhookID); err != nil {
+			b.logger.Warnf("Template %s references missing webhook %s", template.Name, webhookID)
+			continue
+		}
+		if err := b.AddSourceWebhook(sourceID, webhookID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
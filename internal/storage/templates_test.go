@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSourceTemplates(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	db.SaveWebhook(&Webhook{ID: "wh1", Name: "Sink", URL: "https://example.com/hook", Enabled: true})
+	template := &SourceTemplate{Name: "LAN host", Type: "ping", CheckInterval: 30 * time.Second, Tags: []string{"lan"}, SLO: &SLO{Target: 99.5}, ChatIDs: []int64{42}, WebhookIDs: []string{"wh1", "gone"}}
+	if err := db.SaveSourceTemplate(template); err != nil {
+		t.Fatalf("SaveSourceTemplate failed: %v", err)
+	}
+	if template.ID == "" {
+		t.Fatal("Expected an ID to be assigned")
+	}
+
+	found, err := db.FindSourceTemplate("lan HOST")
+	if err != nil || found.ID != template.ID {
+		t.Fatalf("Expected to find the template by name, got %v (%v)", found, err)
+	}
+	if found, err = db.FindSourceTemplate(template.ID); err != nil || found.Name != "LAN host" {
+		t.Fatalf("Expected to find the template by ID, got %v (%v)", found, err)
+	}
+
+	source := found.NewSource("Host 21", "192.168.1.21")
+	if source.ID == "" || source.Target != "192.168.1.21" || source.CheckInterval != 30*time.Second || !source.HasTag("lan") || !source.Enabled || source.CurrentStatus != -1 {
+		t.Errorf("Expected a source with the template's settings, got %+v", source)
+	}
+	// Sources are copies of the template
+	source.SLO.Target = 90
+	source.Tags[0] = "changed"
+	if found.SLO.Target != 99.5 || found.Tags[0] != "lan" {
+		t.Error("Expected the template to be unaffected by changes to the source")
+	}
+
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+	// The missing webhook is skipped
+	if err := db.AttachTemplateSinks(found, source.ID); err != nil {
+		t.Fatalf("AttachTemplateSinks failed: %v", err)
+	}
+	if chats, _ := db.GetSourceChats(source.ID); len(chats) != 1 || chats[0] != 42 {
+		t.Errorf("Expected chat 42 to be attached, got %v", chats)
+	}
+	if webhooks, _ := db.GetSourceWebhooks(source.ID); len(webhooks) != 1 || webhooks[0].ID != "wh1" {
+		t.Errorf("Expected webhook wh1 to be attached, got %v", webhooks)
+	}
+
+	if err := db.DeleteSourceTemplate(template.ID); err != nil {
+		t.Fatalf("DeleteSourceTemplate failed: %v", err)
+	}
+	if _, err := db.FindSourceTemplate("LAN host"); err == nil {
+		t.Error("Expected the template to be deleted")
+	}
+	if _, err := db.GetSource(source.ID); err != nil {
+		t.Errorf("Expected the source to be kept, got %v", err)
+	}
+}
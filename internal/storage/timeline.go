@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"time"
+)
+
+// Timeline segment states
+const (
+	SegmentUp          = "up"
+	SegmentDown        = "down"
+	SegmentUnknown     = "unknown"     // Before the source existed, or no status known yet
+	SegmentMaintenance = "maintenance" // During a maintenance window covering the source
+)
+
+// TimelineSegment is a contiguous period during which a source stayed in one state
+type TimelineSegment struct {
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	State      string    `json:"state"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// GetTimeline returns contiguous segments covering [from, to), oldest first, with adjacent
+// segments of the same state merged. Maintenance windows override the status they cover.
+func (b *BoltDB) GetTimeline(source *Source, from, to time.Time) ([]TimelineSegment, error) {
+	status, changes, err := b.statusChangesFrom(source, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := []TimelineSegment{}
+	cursor := from
+	add := func(until time.Time, state string) {
+		if !until.After(cursor) {
+			return
+		}
+		if n := len(segments); n > 0 && segments[n-1].State == state {
+			segments[n-1].End = until
+			segments[n-1].DurationMs = until.Sub(segments[n-1].Start).Milliseconds()
+		} else {
+			segments = append(segments, TimelineSegment{Start: cursor, End: until, State: state, DurationMs: until.Sub(cursor).Milliseconds()})
+		}
+		cursor = until
+	}
+
+	if source.CreatedAt.After(from) {
+		add(minTime(source.CreatedAt, to), SegmentUnknown)
+	}
+	for _, change := range changes {
+		add(change.Timestamp, segmentState(status))
+		status = change.NewStatus
+	}
+	add(to, segmentState(status))
+
+	spans, err := b.maintenanceSpans(source, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return overlayMaintenance(segments, spans), nil
+}
+
+// overlayMaintenance replaces the parts of segments covered by spans (merged, clipped to the
+// segments' range, oldest first) with maintenance segments
+func overlayMaintenance(segments []TimelineSegment, spans []MaintenanceSpan) []TimelineSegment {
+	if len(spans) == 0 {
+		return segments
+	}
+
+	result := []TimelineSegment{}
+	add := func(start, end time.Time, state string) {
+		if !end.After(start) {
+			return
+		}
+		if n := len(result); n > 0 && result[n-1].State == state {
+			result[n-1].End = end
+			result[n-1].DurationMs = end.Sub(result[n-1].Start).Milliseconds()
+			return
+		}
+		result = append(result, TimelineSegment{Start: start, End: end, State: state, DurationMs: end.Sub(start).Milliseconds()})
+	}
+
+	i := 0
+	for _, segment := range segments {
+		cursor := segment.Start
+		for cursor.Before(segment.End) {
+			for i < len(spans) && !spans[i].End.After(cursor) {
+				i++
+			}
+			if i == len(spans) || !spans[i].Start.Before(segment.End) {
+				add(cursor, segment.End, segment.State)
+				break
+			}
+			if spans[i].Start.After(cursor) {
+				add(cursor, spans[i].Start, segment.State)
+				cursor = spans[i].Start
+			}
+			end := minTime(spans[i].End, segment.End)
+			add(cursor, end, SegmentMaintenance)
+			cursor = end
+		}
+	}
+	return result
+}
+
+// segmentState names the timeline state of a status
+func segmentState(status int) string {
+	switch status {
+	case 1:
+		return SegmentUp
+	case 0:
+		return SegmentDown
+	}
+	return SegmentUnknown
+}
+
+// minTime returns the earlier of two times
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
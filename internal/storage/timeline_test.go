@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetTimeline(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	// Created 2h into the range, online, down from 5h to 6h, then up until the end
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Hour)
+	source := &Source{ID: "src", Name: "API", CurrentStatus: 1, CreatedAt: from.Add(2 * time.Hour)}
+	db.SaveSource(source)
+	for _, change := range []*StatusChange{
+		{SourceID: "src", OldStatus: -1, NewStatus: 1, Timestamp: from.Add(2 * time.Hour)},
+		{SourceID: "src", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(5 * time.Hour)},
+		{SourceID: "src", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(6 * time.Hour)},
+	} {
+		if err := db.SaveStatusChange(change); err != nil {
+			t.Fatalf("SaveStatusChange failed: %v", err)
+		}
+	}
+
+	segments, err := db.GetTimeline(source, from, to)
+	if err != nil {
+		t.Fatalf("GetTimeline failed: %v", err)
+	}
+
+	expected := []struct {
+		start, end time.Duration
+		state      string
+	}{
+		{0, 2 * time.Hour, SegmentUnknown},
+		{2 * time.Hour, 5 * time.Hour, SegmentUp},
+		{5 * time.Hour, 6 * time.Hour, SegmentDown},
+		{6 * time.Hour, 10 * time.Hour, SegmentUp},
+	}
+	if len(segments) != len(expected) {
+		t.Fatalf("Expected %d segments, got %+v", len(expected), segments)
+	}
+	for i, want := range expected {
+		got := segments[i]
+		if !got.Start.Equal(from.Add(want.start)) || !got.End.Equal(from.Add(want.end)) || got.State != want.state {
+			t.Errorf("Segment %d: expected %s from %v to %v, got %+v", i, want.state, want.start, want.end, got)
+		}
+		if got.DurationMs != (want.end - want.start).Milliseconds() {
+			t.Errorf("Segment %d: expected duration %v, got %dms", i, want.end-want.start, got.DurationMs)
+		}
+	}
+
+	// A range inside the outage is a single down segment
+	segments, _ = db.GetTimeline(source, from.Add(5*time.Hour+10*time.Minute), from.Add(5*time.Hour+20*time.Minute))
+	if len(segments) != 1 || segments[0].State != SegmentDown {
+		t.Errorf("Expected a single down segment, got %+v", segments)
+	}
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// InsecureCiphers in BlockedCiphers stands for every cipher suite Go considers insecure,
+// such as RC4 and 3DES suites and CBC suites with SHA-256
+const InsecureCiphers = "insecure"
+
+// tlsVersions maps the versions a policy can require to their crypto/tls values
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSPolicy is a compliance assertion on the TLS connection of an http source: a check
+// fails when the server negotiates a version below MinVersion or a blocked cipher suite,
+// or doesn't serve the final response over TLS at all
+type TLSPolicy struct {
+	MinVersion     string   `msgpack:"min_version" json:"min_version,omitempty" yaml:"min_version"`             // "1.0" to "1.3"
+	BlockedCiphers []string `msgpack:"blocked_ciphers" json:"blocked_ciphers,omitempty" yaml:"blocked_ciphers"` // IANA names, or "insecure"
+}
+
+// IsZero reports whether the policy asserts nothing
+func (p *TLSPolicy) IsZero() bool {
+	return p == nil || (p.MinVersion == "" && len(p.BlockedCiphers) == 0)
+}
+
+// Validate checks the version and cipher suite names, normalizing the names to upper case
+func (p *TLSPolicy) Validate() error {
+	if _, ok := tlsVersions[p.MinVersion]; p.MinVersion != "" && !ok {
+		return errors.New("tls_policy.min_version must be 1.0, 1.1, 1.2 or 1.3")
+	}
+	for i, name := range p.BlockedCiphers {
+		if strings.EqualFold(name, InsecureCiphers) {
+			p.BlockedCiphers[i] = InsecureCiphers
+			continue
+		}
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if cipherSuiteID(name) == 0 {
+			return fmt.Errorf("tls_policy.blocked_ciphers: unknown cipher suite %q (use IANA names like TLS_RSA_WITH_3DES_EDE_CBC_SHA)", p.BlockedCiphers[i])
+		}
+		p.BlockedCiphers[i] = name
+	}
+	return nil
+}
+
+// Violation returns why a connection in state breaches the policy, or "" when it doesn't.
+// A nil state means the response wasn't served over TLS.
+func (p *TLSPolicy) Violation(state *tls.ConnectionState) string {
+	if p.IsZero() {
+		return ""
+	}
+	if state == nil {
+		return "not served over TLS, which the TLS policy requires"
+	}
+	if minVersion, ok := tlsVersions[p.MinVersion]; ok && state.Version < minVersion {
+		return fmt.Sprintf("%s negotiated, the TLS policy requires at least TLS %s", tls.VersionName(state.Version), p.MinVersion)
+	}
+	cipher := tls.CipherSuiteName(state.CipherSuite)
+	for _, blocked := range p.BlockedCiphers {
+		if blocked == cipher || (blocked == InsecureCiphers && isInsecureCipher(state.CipherSuite)) {
+			return fmt.Sprintf("cipher suite %s negotiated, which the TLS policy blocks", cipher)
+		}
+	}
+	return ""
+}
+
+// Equal reports whether two policies assert the same, treating nil as empty
+func (p *TLSPolicy) Equal(other *TLSPolicy) bool {
+	if p.IsZero() || other.IsZero() {
+		return p.IsZero() == other.IsZero()
+	}
+	return p.MinVersion == other.MinVersion && slices.Equal(p.BlockedCiphers, other.BlockedCiphers)
+}
+
+// Copy returns a copy of the policy, nil when it asserts nothing
+func (p *TLSPolicy) Copy() *TLSPolicy {
+	if p.IsZero() {
+		return nil
+	}
+	return &TLSPolicy{MinVersion: p.MinVersion, BlockedCiphers: slices.Clone(p.BlockedCiphers)}
+}
+
+// cipherSuiteID returns the ID of a cipher suite known to crypto/tls, 0 for unknown names
+func cipherSuiteID(name string) uint16 {
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if suite.Name == name {
+			return suite.ID
+		}
+	}
+	return 0
+}
+
+// isInsecureCipher reports whether crypto/tls lists the cipher suite as insecure
+func isInsecureCipher(id uint16) bool {
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"crypto/tls"
+	"strings"
+	"testing"
+)
+
+func TestTLSPolicy(t *testing.T) {
+	policy := &TLSPolicy{MinVersion: "1.2", BlockedCiphers: []string{"Insecure", " tls_ecdhe_rsa_with_aes_128_cbc_sha "}}
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if policy.BlockedCiphers[0] != InsecureCiphers || policy.BlockedCiphers[1] != "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA" {
+		t.Errorf("Expected normalized cipher names, got %v", policy.BlockedCiphers)
+	}
+
+	for _, invalid := range []*TLSPolicy{
+		{MinVersion: "1.4"},
+		{MinVersion: "TLS1.2"},
+		{BlockedCiphers: []string{"TLS_MADE_UP_SUITE"}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", invalid)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		state *tls.ConnectionState
+		want  string // Prefix; "" = no violation
+	}{
+		{"plain http", nil, "not served over TLS"},
+		{"TLS 1.3", &tls.ConnectionState{Version: tls.VersionTLS13, CipherSuite: tls.TLS_AES_128_GCM_SHA256}, ""},
+		{"TLS 1.1", &tls.ConnectionState{Version: tls.VersionTLS11, CipherSuite: tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA},
+			"TLS 1.1 negotiated, the TLS policy requires at least TLS 1.2"},
+		{"blocked by name", &tls.ConnectionState{Version: tls.VersionTLS12, CipherSuite: tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA},
+			"cipher suite TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA negotiated"},
+		{"insecure", &tls.ConnectionState{Version: tls.VersionTLS12, CipherSuite: tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA},
+			"cipher suite TLS_RSA_WITH_3DES_EDE_CBC_SHA negotiated"},
+		{"allowed CBC", &tls.ConnectionState{Version: tls.VersionTLS12, CipherSuite: tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA}, ""},
+	}
+	for _, tt := range tests {
+		got := policy.Violation(tt.state)
+		if (tt.want == "") != (got == "") || !strings.HasPrefix(got, tt.want) {
+			t.Errorf("%s: expected violation %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	var none *TLSPolicy
+	if got := none.Violation(nil); got != "" {
+		t.Errorf("Expected no violation without a policy, got %q", got)
+	}
+	if !none.Equal(&TLSPolicy{}) || policy.Equal(&TLSPolicy{MinVersion: "1.2"}) || !policy.Equal(policy.Copy()) {
+		t.Error("Expected policies to compare by their assertions")
+	}
+}
@@ -0,0 +1,323 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// UptimeStats summarizes a source's availability over a period, for SLA reporting
+type UptimeStats struct {
+	SourceID          string     `json:"source_id"`
+	From              time.Time  `json:"from"`
+	To                time.Time  `json:"to"`
+	UptimePercent     *float64   `json:"uptime_percent"` // nil when the status was never known in the period
+	MonitoredMs       int64      `json:"monitored_ms"`   // Time with a known status
+	DowntimeMs        int64      `json:"downtime_ms"`
+	OutageCount       int        `json:"outage_count"`                // Outages that started in the period
+	MTTRMs            *int64     `json:"mttr_ms"`                     // Mean time to recovery of outages that started and ended in the period
+	MTBFMs            *int64     `json:"mtbf_ms"`                     // Mean time between failures: uptime / outage count
+	LongestOutageMs   int64      `json:"longest_outage_ms"`           // Clipped to the period
+	LongestOutageAt   *time.Time `json:"longest_outage_at,omitempty"` // Start of the longest outage
+	Ongoing           bool       `json:"ongoing"`                     // Source is offline at the end of the period
+	PlannedDowntimeMs int64      `json:"planned_downtime_ms"`         // Part of DowntimeMs in outages marked as planned or in maintenance windows
+	SLAUptimePercent  *float64   `json:"sla_uptime_percent"`          // Uptime with planned downtime left out of downtime and monitored time
+}
+
+// OutageWindow is an offline period of a source, clipped to the requested range
+type OutageWindow struct {
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"` // End of the range when the outage is ongoing
+	DurationMs    int64     `json:"duration_ms"`
+	StartedBefore bool      `json:"started_before"` // Outage began before the range
+	Ongoing       bool      `json:"ongoing"`        // Outage had not ended by the end of the range
+	Planned       bool      `json:"planned"`        // Outage is marked as planned
+	PlannedMs     int64     `json:"planned_ms"`     // Planned part: all of a marked outage, or the part in maintenance
+}
+
+// outageSpan is a period during which a source was offline
+type outageSpan struct {
+	start, end time.Time
+	// Outage began before the replayed range (start is clipped to it)
+	startedBefore bool
+	// Outage still ongoing at the end of the replayed range (end is clipped to it)
+	ongoing bool
+	// Marked as planned, and how much of it counts as planned (set by classifyPlanned)
+	marked  bool
+	planned time.Duration
+}
+
+// statusReplay is the result of replaying status changes over a time range
+type statusReplay struct {
+	monitored time.Duration
+	outages   []outageSpan
+}
+
+// downtime returns the total offline time within the replayed range
+func (r *statusReplay) downtime() time.Duration {
+	var total time.Duration
+	for _, o := range r.outages {
+		total += o.end.Sub(o.start)
+	}
+	return total
+}
+
+// plannedDowntime returns the planned part of the offline time, once classifyPlanned ran
+func (r *statusReplay) plannedDowntime() time.Duration {
+	var total time.Duration
+	for _, o := range r.outages {
+		total += o.planned
+	}
+	return total
+}
+
+// slaUptimePercent is the uptime with planned downtime left out of both downtime and
+// monitored time; nil when nothing unplanned was monitored
+func slaUptimePercent(monitoredMs, downtimeMs, plannedMs int64) *float64 {
+	adjusted := monitoredMs - plannedMs
+	if adjusted <= 0 {
+		return nil
+	}
+	uptime := float64(adjusted-(downtimeMs-plannedMs)) / float64(adjusted) * 100
+	return &uptime
+}
+
+// outagesStarted counts outages that began within the replayed range
+func (r *statusReplay) outagesStarted() int {
+	count := 0
+	for _, o := range r.outages {
+		if !o.startedBefore {
+			count++
+		}
+	}
+	return count
+}
+
+// statusChangesFrom returns a source's status at from and its status changes within [from, to)
+func (b *BoltDB) statusChangesFrom(source *Source, from, to time.Time) (int, []*StatusChange, error) {
+	// Status at the start of the range comes from the last change before it
+	status := -1
+	previous, err := b.GetStatusChanges(source.ID, time.Time{}, from, 1)
+	if err != nil {
+		return status, nil, err
+	}
+	if len(previous) > 0 {
+		status = previous[0].NewStatus
+	}
+
+	changes, err := b.GetStatusChangesInRange(source.ID, from, to)
+	if err != nil {
+		return status, nil, err
+	}
+	if status == -1 && len(changes) > 0 {
+		status = changes[0].OldStatus
+	}
+	if status == -1 && len(changes) == 0 {
+		status = source.CurrentStatus
+	}
+	return status, changes, nil
+}
+
+// replayStatus walks a source's status changes over [from, to).
+// Time before the source was created or while its status was unknown is not counted.
+func (b *BoltDB) replayStatus(source *Source, from, to time.Time) (*statusReplay, error) {
+	status, changes, err := b.statusChangesFrom(source, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := from
+	if source.CreatedAt.After(cursor) {
+		cursor = source.CreatedAt
+	}
+
+	replay := &statusReplay{}
+	var outageStart time.Time
+	inOutage := false
+	if status == 0 {
+		outageStart, inOutage = cursor, true
+	}
+	startedBefore := inOutage
+
+	account := func(until time.Time) {
+		if !until.After(cursor) {
+			return
+		}
+		if status == 0 || status == 1 {
+			replay.monitored += until.Sub(cursor)
+		}
+		cursor = until
+	}
+
+	for _, change := range changes {
+		// Changes recorded before the source was created count from its creation
+		at := change.Timestamp
+		if at.Before(cursor) {
+			at = cursor
+		}
+		account(at)
+		if change.NewStatus == 0 && !inOutage {
+			outageStart, inOutage, startedBefore = at, true, false
+		} else if change.NewStatus != 0 && inOutage {
+			if at.After(outageStart) {
+				replay.outages = append(replay.outages, outageSpan{start: outageStart, end: at, startedBefore: startedBefore})
+			}
+			inOutage = false
+		}
+		status = change.NewStatus
+	}
+	account(to)
+
+	if inOutage && to.After(outageStart) {
+		replay.outages = append(replay.outages, outageSpan{start: outageStart, end: to, startedBefore: startedBefore, ongoing: true})
+	}
+
+	return replay, nil
+}
+
+// ComputeUptimeStats computes availability statistics for a source over [from, to)
+func (b *BoltDB) ComputeUptimeStats(source *Source, from, to time.Time) (*UptimeStats, error) {
+	replay, err := b.replayStatus(source, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.classifyPlanned(source, replay, from, to); err != nil {
+		return nil, err
+	}
+
+	downtime := replay.downtime()
+	stats := &UptimeStats{
+		SourceID:          source.ID,
+		From:              from,
+		To:                to,
+		MonitoredMs:       replay.monitored.Milliseconds(),
+		DowntimeMs:        downtime.Milliseconds(),
+		PlannedDowntimeMs: replay.plannedDowntime().Milliseconds(),
+		OutageCount:       replay.outagesStarted(),
+	}
+
+	if replay.monitored > 0 {
+		uptime := float64(replay.monitored-downtime) / float64(replay.monitored) * 100
+		stats.UptimePercent = &uptime
+	}
+	stats.SLAUptimePercent = slaUptimePercent(stats.MonitoredMs, stats.DowntimeMs, stats.PlannedDowntimeMs)
+
+	var recovered int
+	var recoveryTotal time.Duration
+	for _, o := range replay.outages {
+		length := o.end.Sub(o.start)
+		if length.Milliseconds() > stats.LongestOutageMs {
+			start := o.start
+			stats.LongestOutageMs = length.Milliseconds()
+			stats.LongestOutageAt = &start
+		}
+		if o.ongoing {
+			stats.Ongoing = true
+			continue
+		}
+		if !o.startedBefore {
+			recovered++
+			recoveryTotal += length
+		}
+	}
+
+	if recovered > 0 {
+		mttr := (recoveryTotal / time.Duration(recovered)).Milliseconds()
+		stats.MTTRMs = &mttr
+	}
+	if stats.OutageCount > 0 {
+		mtbf := ((replay.monitored - downtime) / time.Duration(stats.OutageCount)).Milliseconds()
+		stats.MTBFMs = &mtbf
+	}
+
+	return stats, nil
+}
+
+// GetOutageWindows returns the offline periods of a source within [from, to), oldest first
+func (b *BoltDB) GetOutageWindows(source *Source, from, to time.Time) ([]OutageWindow, error) {
+	replay, err := b.replayStatus(source, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.classifyPlanned(source, replay, from, to); err != nil {
+		return nil, err
+	}
+
+	windows := make([]OutageWindow, 0, len(replay.outages))
+	for _, o := range replay.outages {
+		windows = append(windows, OutageWindow{
+			Start:         o.start,
+			End:           o.end,
+			DurationMs:    o.end.Sub(o.start).Milliseconds(),
+			StartedBefore: o.startedBefore,
+			Ongoing:       o.ongoing,
+			Planned:       o.marked,
+			PlannedMs:     o.planned.Milliseconds(),
+		})
+	}
+	return windows, nil
+}
+
+// SourceUptime is one source's row in an UptimeSummary
+type SourceUptime struct {
+	SourceID          string   `json:"source_id"`
+	SourceName        string   `json:"source_name"`
+	CurrentStatus     int      `json:"current_status"`
+	Enabled           bool     `json:"enabled"`
+	UptimePercent     *float64 `json:"uptime_percent"` // nil when the status was never known in the period
+	OutageCount       int      `json:"outage_count"`
+	DowntimeMs        int64    `json:"downtime_ms"`
+	Ongoing           bool     `json:"ongoing"`
+	PlannedDowntimeMs int64    `json:"planned_downtime_ms"` // Part of DowntimeMs
+	SLAUptimePercent  *float64 `json:"sla_uptime_percent"`  // With planned downtime left out
+}
+
+// UptimeSummary is the availability of several sources over the same period
+type UptimeSummary struct {
+	From              time.Time      `json:"from"`
+	To                time.Time      `json:"to"`
+	UptimePercent     *float64       `json:"uptime_percent"` // Weighted by each source's monitored time
+	OutageCount       int            `json:"outage_count"`
+	DowntimeMs        int64          `json:"downtime_ms"`
+	Sources           []SourceUptime `json:"sources"`             // By name
+	PlannedDowntimeMs int64          `json:"planned_downtime_ms"` // Part of DowntimeMs
+	SLAUptimePercent  *float64       `json:"sla_uptime_percent"`  // With planned downtime left out
+}
+
+// ComputeUptimeSummary computes the uptime of each source over [from, to) and the overall totals
+func (b *BoltDB) ComputeUptimeSummary(sources []*Source, from, to time.Time) (*UptimeSummary, error) {
+	summary := &UptimeSummary{From: from, To: to, Sources: make([]SourceUptime, 0, len(sources))}
+	var monitoredMs int64
+
+	for _, source := range sources {
+		stats, err := b.ComputeUptimeStats(source, from, to)
+		if err != nil {
+			return nil, err
+		}
+		summary.Sources = append(summary.Sources, SourceUptime{
+			SourceID:          source.ID,
+			SourceName:        source.Name,
+			CurrentStatus:     source.CurrentStatus,
+			Enabled:           source.Enabled,
+			UptimePercent:     stats.UptimePercent,
+			OutageCount:       stats.OutageCount,
+			DowntimeMs:        stats.DowntimeMs,
+			Ongoing:           stats.Ongoing,
+			PlannedDowntimeMs: stats.PlannedDowntimeMs,
+			SLAUptimePercent:  stats.SLAUptimePercent,
+		})
+		monitoredMs += stats.MonitoredMs
+		summary.OutageCount += stats.OutageCount
+		summary.DowntimeMs += stats.DowntimeMs
+		summary.PlannedDowntimeMs += stats.PlannedDowntimeMs
+	}
+
+	if monitoredMs > 0 {
+		uptime := float64(monitoredMs-summary.DowntimeMs) / float64(monitoredMs) * 100
+		summary.UptimePercent = &uptime
+	}
+	summary.SLAUptimePercent = slaUptimePercent(monitoredMs, summary.DowntimeMs, summary.PlannedDowntimeMs)
+	sort.SliceStable(summary.Sources, func(i, j int) bool {
+		return summary.Sources[i].SourceName < summary.Sources[j].SourceName
+	})
+	return summary, nil
+}
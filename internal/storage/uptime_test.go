@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestComputeUptimeStats(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(100 * time.Hour)
+	source := &Source{ID: "src", Name: "API", Type: "http", CurrentStatus: 0, CreatedAt: from.Add(-time.Hour)}
+	if err := db.SaveSource(source); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+
+	// Online from before the period; outages of 2h and 6h, then a final outage still ongoing (4h)
+	changes := []*StatusChange{
+		{SourceID: "src", OldStatus: -1, NewStatus: 1, Timestamp: from.Add(-time.Hour)},
+		{SourceID: "src", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(10 * time.Hour)},
+		{SourceID: "src", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(12 * time.Hour)},
+		{SourceID: "src", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(50 * time.Hour)},
+		{SourceID: "src", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(56 * time.Hour)},
+		{SourceID: "src", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(96 * time.Hour)},
+	}
+	for _, change := range changes {
+		if err := db.SaveStatusChange(change); err != nil {
+			t.Fatalf("SaveStatusChange failed: %v", err)
+		}
+	}
+
+	stats, err := db.ComputeUptimeStats(source, from, to)
+	if err != nil {
+		t.Fatalf("ComputeUptimeStats failed: %v", err)
+	}
+
+	if stats.OutageCount != 3 {
+		t.Errorf("Expected 3 outages, got %d", stats.OutageCount)
+	}
+	if stats.DowntimeMs != (12 * time.Hour).Milliseconds() {
+		t.Errorf("Expected 12h downtime, got %v", time.Duration(stats.DowntimeMs)*time.Millisecond)
+	}
+	if stats.UptimePercent == nil || *stats.UptimePercent != 88 {
+		t.Errorf("Expected 88%% uptime, got %v", stats.UptimePercent)
+	}
+	if stats.MTTRMs == nil || *stats.MTTRMs != (4*time.Hour).Milliseconds() {
+		t.Errorf("Expected 4h MTTR from the two recovered outages, got %v", stats.MTTRMs)
+	}
+	if stats.MTBFMs == nil || *stats.MTBFMs != (88*time.Hour/3).Milliseconds() {
+		t.Errorf("Expected MTBF of 88h/3, got %v", stats.MTBFMs)
+	}
+	if stats.LongestOutageMs != (6 * time.Hour).Milliseconds() {
+		t.Errorf("Expected 6h longest outage, got %v", time.Duration(stats.LongestOutageMs)*time.Millisecond)
+	}
+	if !stats.Ongoing {
+		t.Error("Expected ongoing outage at end of period")
+	}
+
+	// Changes recorded before the source was created (e.g. imported history) count from its creation
+	late := &Source{ID: "late", Name: "Late", CurrentStatus: 1, CreatedAt: from.Add(5 * time.Hour)}
+	db.SaveSource(late)
+	db.SaveStatusChange(&StatusChange{SourceID: "late", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(time.Hour)})
+	db.SaveStatusChange(&StatusChange{SourceID: "late", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(2 * time.Hour)})
+	db.SaveStatusChange(&StatusChange{SourceID: "late", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(4 * time.Hour)})
+	db.SaveStatusChange(&StatusChange{SourceID: "late", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(7 * time.Hour)})
+	if stats, err = db.ComputeUptimeStats(late, from.Add(6*time.Hour), to); err != nil {
+		t.Fatalf("ComputeUptimeStats failed: %v", err)
+	}
+	if stats.DowntimeMs != time.Hour.Milliseconds() || stats.OutageCount != 0 {
+		t.Errorf("Expected 1h downtime of an outage that started before the period, got %+v", stats)
+	}
+	if stats, err = db.ComputeUptimeStats(late, from, to); err != nil {
+		t.Fatalf("ComputeUptimeStats failed: %v", err)
+	}
+	if stats.DowntimeMs != (2*time.Hour).Milliseconds() || stats.OutageCount != 1 {
+		t.Errorf("Expected one 2h outage from creation, got %+v", stats)
+	}
+}
+
+func TestGetOutageWindows(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	source := &Source{ID: "src", Name: "API", Type: "http", CurrentStatus: 0, CreatedAt: from.Add(-48 * time.Hour)}
+	db.SaveSource(source)
+
+	// Offline since before the range, recovers at 2h, down again from 20h until after the range
+	for _, change := range []*StatusChange{
+		{SourceID: "src", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(-time.Hour)},
+		{SourceID: "src", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(2 * time.Hour)},
+		{SourceID: "src", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(20 * time.Hour)},
+	} {
+		db.SaveStatusChange(change)
+	}
+
+	windows, err := db.GetOutageWindows(source, from, to)
+	if err != nil {
+		t.Fatalf("GetOutageWindows failed: %v", err)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("Expected 2 outage windows, got %+v", windows)
+	}
+	if !windows[0].Start.Equal(from) || !windows[0].StartedBefore || windows[0].DurationMs != (2*time.Hour).Milliseconds() {
+		t.Errorf("Expected first window clipped to range start, got %+v", windows[0])
+	}
+	if !windows[1].End.Equal(to) || !windows[1].Ongoing || windows[1].DurationMs != (4*time.Hour).Milliseconds() {
+		t.Errorf("Expected second window ongoing until range end, got %+v", windows[1])
+	}
+}
+
+func TestComputeUptimeSummary(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Hour)
+	web := &Source{ID: "web", Name: "Web", CurrentStatus: 1, Enabled: true, CreatedAt: from.Add(-time.Hour)}
+	api := &Source{ID: "api", Name: "API", CurrentStatus: 0, Enabled: true, CreatedAt: from.Add(-time.Hour)}
+	db.SaveSource(web)
+	db.SaveSource(api)
+
+	// Web is online throughout; API goes down for 1h, then again from 8h until the end
+	for _, change := range []*StatusChange{
+		{SourceID: "web", OldStatus: -1, NewStatus: 1, Timestamp: from.Add(-time.Hour)},
+		{SourceID: "api", OldStatus: -1, NewStatus: 1, Timestamp: from.Add(-time.Hour)},
+		{SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(2 * time.Hour)},
+		{SourceID: "api", OldStatus: 0, NewStatus: 1, Timestamp: from.Add(3 * time.Hour)},
+		{SourceID: "api", OldStatus: 1, NewStatus: 0, Timestamp: from.Add(8 * time.Hour)},
+	} {
+		if err := db.SaveStatusChange(change); err != nil {
+			t.Fatalf("SaveStatusChange failed: %v", err)
+		}
+	}
+
+	summary, err := db.ComputeUptimeSummary([]*Source{web, api}, from, to)
+	if err != nil {
+		t.Fatalf("ComputeUptimeSummary failed: %v", err)
+	}
+
+	if len(summary.Sources) != 2 || summary.Sources[0].SourceID != "api" || summary.Sources[1].SourceID != "web" {
+		t.Fatalf("Expected API then Web, got %+v", summary.Sources)
+	}
+	row := summary.Sources[0]
+	if row.OutageCount != 2 || row.DowntimeMs != (3*time.Hour).Milliseconds() || !row.Ongoing {
+		t.Errorf("Expected 2 outages, 3h down and ongoing for API, got %+v", row)
+	}
+	if row.UptimePercent == nil || *row.UptimePercent != 70 {
+		t.Errorf("Expected 70%% uptime for API, got %v", row.UptimePercent)
+	}
+	if summary.OutageCount != 2 || summary.DowntimeMs != (3*time.Hour).Milliseconds() {
+		t.Errorf("Expected totals of 2 outages and 3h down, got %d and %dms", summary.OutageCount, summary.DowntimeMs)
+	}
+	if summary.UptimePercent == nil || *summary.UptimePercent != 85 {
+		t.Errorf("Expected 85%% overall uptime, got %v", summary.UptimePercent)
+	}
+}
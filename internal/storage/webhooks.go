@@ -9,6 +9,17 @@ import (
 	bolt "go.etcd.io/bbolt"
 )
 
+// Webhook payload formats
+const (
+	WebhookFormatNative       = ""             // The bot's own JSON payload
+	WebhookFormatAlertmanager = "alertmanager" // Prometheus Alertmanager webhook receiver payload
+)
+
+// ValidWebhookFormat reports whether format is a supported webhook payload format
+func ValidWebhookFormat(format string) bool {
+	return format == WebhookFormatNative || format == WebhookFormatAlertmanager
+}
+
 // Webhook represents an HTTP webhook for notifications
 type Webhook struct {
 	ID            string            `msgpack:"id" json:"id"`
@@ -20,6 +31,10 @@ type Webhook struct {
 	CreatedAt     time.Time         `msgpack:"created_at" json:"created_at"`
 	UpdatedAt     time.Time         `msgpack:"updated_at" json:"updated_at"`
 	LastTriggered *time.Time        `msgpack:"last_triggered" json:"last_triggered,omitempty"`
+	Namespace     string            `msgpack:"namespace" json:"namespace,omitempty"` // Empty for global webhooks
+	Format        string            `msgpack:"format" json:"format,omitempty"`       // Payload format: empty for the native payload, or alertmanager
+
+	headersEncrypted bool // Headers were stored encrypted
 }
 
 // SaveWebhook stores a webhook in the database
@@ -34,12 +49,34 @@ func (b *BoltDB) SaveWebhook(webhook *Webhook) error {
 
 	webhook.UpdatedAt = time.Now()
 
-	data, err := msgpack.Marshal(webhook)
+	if err := b.putWebhook(webhook); err != nil {
+		return err
+	}
+
+	if webhook.Name != "" {
+		b.logger.Debugf("Saved webhook: %s (%s)", webhook.Name, webhook.Method)
+	} else {
+		b.logger.Debugf("Saved webhook: %s (%s)", webhook.URL, webhook.Method)
+	}
+	return nil
+}
+
+// putWebhook writes a webhook as-is, encrypting header values when enabled
+func (b *BoltDB) putWebhook(webhook *Webhook) error {
+	headers, err := b.encryptHeaders(webhook.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook headers: %w", err)
+	}
+
+	stored := *webhook
+	stored.Headers = headers
+
+	data, err := msgpack.Marshal(&stored)
 	if err != nil {
 		return fmt.Errorf("failed to marshal webhook: %w", err)
 	}
 
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(webhooksBucket))
 		if bucket == nil {
 			return fmt.Errorf("webhooks bucket not found")
@@ -48,12 +85,6 @@ func (b *BoltDB) SaveWebhook(webhook *Webhook) error {
 		if err := bucket.Put([]byte(webhook.ID), data); err != nil {
 			return fmt.Errorf("failed to save webhook: %w", err)
 		}
-
-		if webhook.Name != "" {
-			b.logger.Printf("Saved webhook: %s (%s)", webhook.Name, webhook.Method)
-		} else {
-			b.logger.Printf("Saved webhook: %s (%s)", webhook.URL, webhook.Method)
-		}
 		return nil
 	})
 }
@@ -62,7 +93,7 @@ func (b *BoltDB) SaveWebhook(webhook *Webhook) error {
 func (b *BoltDB) GetWebhook(id string) (*Webhook, error) {
 	var webhook *Webhook
 
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(webhooksBucket))
 		if bucket == nil {
 			return fmt.Errorf("webhooks bucket not found")
@@ -73,12 +104,9 @@ func (b *BoltDB) GetWebhook(id string) (*Webhook, error) {
 			return fmt.Errorf("webhook not found")
 		}
 
-		webhook = &Webhook{}
-		if err := msgpack.Unmarshal(data, webhook); err != nil {
-			return fmt.Errorf("failed to unmarshal webhook: %w", err)
-		}
-
-		return nil
+		var err error
+		webhook, err = b.unmarshalWebhook(data)
+		return err
 	})
 
 	return webhook, err
@@ -88,16 +116,16 @@ func (b *BoltDB) GetWebhook(id string) (*Webhook, error) {
 func (b *BoltDB) ListWebhooks() ([]*Webhook, error) {
 	var webhooks []*Webhook
 
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.view(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(webhooksBucket))
 		if bucket == nil {
 			return fmt.Errorf("webhooks bucket not found")
 		}
 
 		return bucket.ForEach(func(k, v []byte) error {
-			webhook := &Webhook{}
-			if err := msgpack.Unmarshal(v, webhook); err != nil {
-				b.logger.Printf("Failed to unmarshal webhook: %v", err)
+			webhook, err := b.unmarshalWebhook(v)
+			if err != nil {
+				b.logger.Errorf("Failed to unmarshal webhook: %v", err)
 				return nil // Skip malformed webhooks
 			}
 			webhooks = append(webhooks, webhook)
@@ -108,9 +136,9 @@ func (b *BoltDB) ListWebhooks() ([]*Webhook, error) {
 	return webhooks, err
 }
 
-// DeleteWebhook removes a webhook from the database
+// DeleteWebhook removes a webhook from the database and from all source associations
 func (b *BoltDB) DeleteWebhook(id string) error {
-	return b.db.Update(func(tx *bolt.Tx) error {
+	return b.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(webhooksBucket))
 		if bucket == nil {
 			return fmt.Errorf("webhooks bucket not found")
@@ -119,8 +147,11 @@ func (b *BoltDB) DeleteWebhook(id string) error {
 		if err := bucket.Delete([]byte(id)); err != nil {
 			return fmt.Errorf("failed to delete webhook: %w", err)
 		}
+		if err := deleteSinkLinks(tx, WebhookSinkID(id)); err != nil {
+			return err
+		}
 
-		b.logger.Printf("Deleted webhook: %s", id)
+		b.logger.Debugf("Deleted webhook: %s", id)
 		return nil
 	})
 }
@@ -0,0 +1,248 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tg-monitor-bot/internal/logging"
+)
+
+const (
+	// queueSize bounds the spans waiting for export; more are dropped
+	queueSize = 4096
+	// batchSize is the most spans sent in one request
+	batchSize = 512
+	// exportInterval is how often a partial batch is sent
+	exportInterval = 5 * time.Second
+)
+
+// exporter batches finished spans and posts them to the collector
+type exporter struct {
+	opts      Options
+	url       string
+	client    *http.Client
+	logger    *logging.Logger
+	queue     chan *Span
+	stop      chan struct{}
+	done      sync.WaitGroup
+	dropped   int // Spans dropped since the last export warning; guarded by droppedMu
+	droppedMu sync.Mutex
+}
+
+// newExporter starts an exporter goroutine for opts
+func newExporter(opts Options) *exporter {
+	url := strings.TrimSuffix(opts.Endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	e := &exporter{
+		opts:   opts,
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logging.New("tracing"),
+		queue:  make(chan *Span, queueSize),
+		stop:   make(chan struct{}),
+	}
+	e.done.Add(1)
+	go e.run()
+	e.logger.Printf("Exporting traces to %s (sample ratio %g)", url, opts.SampleRatio)
+	return e
+}
+
+// enqueue queues a finished span without blocking the caller
+func (e *exporter) enqueue(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+		e.droppedMu.Lock()
+		e.dropped++
+		e.droppedMu.Unlock()
+	}
+}
+
+// run sends full batches immediately and partial ones every exportInterval
+func (e *exporter) run() {
+	defer e.done.Done()
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			e.export(batch)
+			batch = batch[:0]
+		}
+	}
+	for {
+		select {
+		case span := <-e.queue:
+			if batch = append(batch, span); len(batch) == batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.stop:
+			for {
+				select {
+				case span := <-e.queue:
+					if batch = append(batch, span); len(batch) == batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// shutdown exports the queued spans and stops the exporter
+func (e *exporter) shutdown() {
+	close(e.stop)
+	e.done.Wait()
+}
+
+// export posts a batch to the collector. Failures are logged and the batch is dropped.
+func (e *exporter) export(batch []*Span) {
+	e.droppedMu.Lock()
+	dropped := e.dropped
+	e.dropped = 0
+	e.droppedMu.Unlock()
+	if dropped > 0 {
+		e.logger.Warnf("Dropped %d spans: export queue full", dropped)
+	}
+
+	body, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		e.logger.Errorf("Failed to encode spans: %v", err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		e.logger.Errorf("Failed to create trace export request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.opts.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		e.logger.Warnf("Failed to export %d spans: %v", len(batch), err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		e.logger.Warnf("Failed to export %d spans: collector returned %d: %s", len(batch), resp.StatusCode, excerpt)
+	}
+}
+
+// OTLP JSON encoding of an ExportTraceServiceRequest. IDs are hex and 64-bit integers
+// are strings, as the OTLP/HTTP JSON mapping requires.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttr `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+	otlpSpan struct {
+		TraceID           string     `json:"traceId"`
+		SpanID            string     `json:"spanId"`
+		ParentSpanID      string     `json:"parentSpanId,omitempty"`
+		Name              string     `json:"name"`
+		Kind              int        `json:"kind"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+		Attributes        []otlpAttr `json:"attributes,omitempty"`
+		Status            otlpStatus `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"` // 0 unset, 2 error
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttr struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+// encode converts spans to an OTLP request
+func (e *exporter) encode(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, span := range batch {
+		span.mu.Lock()
+		out := otlpSpan{
+			TraceID:           hex.EncodeToString(span.traceID[:]),
+			SpanID:            hex.EncodeToString(span.spanID[:]),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        encodeAttrs(span.attrs),
+		}
+		if span.parentID != [8]byte{} {
+			out.ParentSpanID = hex.EncodeToString(span.parentID[:])
+		}
+		if span.errorMsg != "" {
+			out.Status = otlpStatus{Code: 2, Message: span.errorMsg}
+		}
+		span.mu.Unlock()
+		spans = append(spans, out)
+	}
+
+	resource := []Attr{String("service.name", e.opts.ServiceName)}
+	if e.opts.Version != "" {
+		resource = append(resource, String("service.version", e.opts.Version))
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttrs(resource)},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "tg-monitor-bot", Version: e.opts.Version}, Spans: spans}},
+	}}}
+}
+
+// encodeAttrs converts attributes to OTLP AnyValues
+func encodeAttrs(attrs []Attr) []otlpAttr {
+	out := make([]otlpAttr, 0, len(attrs))
+	for _, attr := range attrs {
+		var value map[string]any
+		switch v := attr.Value.(type) {
+		case string:
+			value = map[string]any{"stringValue": v}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		out = append(out, otlpAttr{Key: attr.Key, Value: value})
+	}
+	return out
+}
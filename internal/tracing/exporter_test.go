@@ -0,0 +1,163 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tg-monitor-bot/internal/logging"
+)
+
+// collector is a stub OTLP/HTTP endpoint that records each export request
+type collector struct {
+	*httptest.Server
+	status int
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newCollector(t *testing.T, status int) *collector {
+	c := &collector{status: status}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		c.mu.Lock()
+		c.requests = append(c.requests, r)
+		c.bodies = append(c.bodies, body)
+		c.mu.Unlock()
+		w.WriteHeader(c.status)
+		io.WriteString(w, `{"error":"collector says no"}`)
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestExportSpans(t *testing.T) {
+	server := newCollector(t, http.StatusOK)
+	Setup(Options{
+		Endpoint:    server.URL + "/",
+		Headers:     map[string]string{"X-Api-Key": "secret"},
+		ServiceName: "outage-monitor",
+		Version:     "1.2.3",
+		SampleRatio: 1,
+	})
+	defer Shutdown()
+
+	ctx, parent := Start(context.Background(), "check", String("source.id", "src-1"))
+	_, child := StartKind(ctx, "http.request", KindClient, Int("http.status_code", 503), Bool("retry", false))
+	child.SetAttributes(Attr{"latency", 1.5})
+	child.RecordError(errors.New("service unavailable"))
+	child.End()
+	parent.End()
+	Shutdown() // Flushes the queue synchronously
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.requests) != 1 {
+		t.Fatalf("Expected one export request, got %d", len(server.requests))
+	}
+	req := server.requests[0]
+	if req.Method != http.MethodPost || req.URL.Path != "/v1/traces" {
+		t.Errorf("Expected POST /v1/traces, got %s %s", req.Method, req.URL.Path)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", got)
+	}
+	if got := req.Header.Get("X-Api-Key"); got != "secret" {
+		t.Errorf("Expected the configured header, got %q", got)
+	}
+
+	var payload otlpRequest
+	if err := json.Unmarshal(server.bodies[0], &payload); err != nil {
+		t.Fatalf("Failed to decode the payload: %v", err)
+	}
+	if len(payload.ResourceSpans) != 1 || len(payload.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Expected one resource and scope, got %s", server.bodies[0])
+	}
+	resource := payload.ResourceSpans[0].Resource.Attributes
+	if len(resource) != 2 || resource[0].Key != "service.name" || resource[0].Value["stringValue"] != "outage-monitor" ||
+		resource[1].Key != "service.version" || resource[1].Value["stringValue"] != "1.2.3" {
+		t.Errorf("Expected service name and version resource attributes, got %+v", resource)
+	}
+	scope := payload.ResourceSpans[0].ScopeSpans[0]
+	if scope.Scope.Name != "tg-monitor-bot" || len(scope.Spans) != 2 {
+		t.Fatalf("Expected two spans in the tg-monitor-bot scope, got %s", server.bodies[0])
+	}
+
+	// Spans are exported in the order they ended
+	got, gotParent := scope.Spans[0], scope.Spans[1]
+	traceID, spanID := child.IDs()
+	_, parentID := parent.IDs()
+	if got.TraceID != traceID || got.SpanID != spanID || got.ParentSpanID != parentID {
+		t.Errorf("Expected child IDs %s/%s with parent %s, got %+v", traceID, spanID, parentID, got)
+	}
+	if gotParent.TraceID != traceID || gotParent.ParentSpanID != "" || gotParent.Kind != KindInternal || gotParent.Status.Code != 0 {
+		t.Errorf("Expected an internal root span in the same trace, got %+v", gotParent)
+	}
+	if got.Name != "http.request" || got.Kind != KindClient || got.Status.Code != 2 || got.Status.Message != "service unavailable" {
+		t.Errorf("Expected a failed client span, got %+v", got)
+	}
+	start, errStart := strconv.ParseInt(got.StartTimeUnixNano, 10, 64)
+	end, errEnd := strconv.ParseInt(got.EndTimeUnixNano, 10, 64)
+	if errStart != nil || errEnd != nil || end < start {
+		t.Errorf("Expected nanosecond timestamps as strings, got %q to %q", got.StartTimeUnixNano, got.EndTimeUnixNano)
+	}
+	want := map[string]map[string]any{
+		"http.status_code": {"intValue": "503"}, // 64-bit integers are strings in OTLP JSON
+		"retry":            {"boolValue": false},
+		"latency":          {"doubleValue": 1.5},
+	}
+	for _, attr := range got.Attributes {
+		if expected, ok := want[attr.Key]; !ok || !maps.Equal(expected, attr.Value) {
+			t.Errorf("Unexpected attribute %s=%v", attr.Key, attr.Value)
+		}
+	}
+	if len(got.Attributes) != len(want) {
+		t.Errorf("Expected %d attributes, got %+v", len(want), got.Attributes)
+	}
+}
+
+func TestExportNon2xx(t *testing.T) {
+	server := newCollector(t, http.StatusBadRequest)
+	var logs bytes.Buffer
+	e := &exporter{
+		opts:   Options{Endpoint: server.URL + "/v1/traces", ServiceName: "outage-monitor"},
+		url:    server.URL + "/v1/traces",
+		client: server.Client(),
+		logger: logging.NewWithHandler(slog.NewTextHandler(&logs, nil), "tracing"),
+	}
+
+	now := time.Now()
+	batch := []*Span{{name: "check", kind: KindInternal, start: now, end: now, sampled: true}}
+	e.export(batch)
+
+	server.mu.Lock()
+	requests := len(server.requests)
+	server.mu.Unlock()
+	if requests != 1 {
+		t.Errorf("Expected the batch to be sent once and dropped, got %d requests", requests)
+	}
+	if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "collector returned 400") ||
+		!strings.Contains(logs.String(), "collector says no") {
+		t.Errorf("Expected a warning with the status and response excerpt, got %q", logs.String())
+	}
+
+	// The endpoint path isn't doubled when already given
+	exp := newExporter(Options{Endpoint: server.URL + "/v1/traces"})
+	defer exp.shutdown()
+	if exp.url != server.URL+"/v1/traces" {
+		t.Errorf("Expected %s/v1/traces, got %s", server.URL, exp.url)
+	}
+}
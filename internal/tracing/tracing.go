@@ -0,0 +1,241 @@
+// Package tracing records spans for checks, storage writes and notifications and exports
+// them to an OpenTelemetry collector over OTLP/HTTP with JSON encoding. Without an endpoint
+// every call is a cheap no-op.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Span kinds, as numbered by OTLP
+const (
+	KindInternal = 1
+	KindClient   = 3
+)
+
+// Attr is a span attribute
+type Attr struct {
+	Key   string
+	Value any // string, int, int64, float64 or bool
+}
+
+// String returns a string attribute
+func String(key, value string) Attr { return Attr{key, value} }
+
+// Int returns an integer attribute
+func Int(key string, value int) Attr { return Attr{key, int64(value)} }
+
+// Int64 returns an integer attribute
+func Int64(key string, value int64) Attr { return Attr{key, value} }
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attr { return Attr{key, value} }
+
+// Span is a timed operation. A nil *Span is valid and ignores all calls, so callers
+// never need to check whether tracing is enabled.
+type Span struct {
+	name     string
+	kind     int
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	sampled  bool
+	start    time.Time
+
+	mu       sync.Mutex
+	end      time.Time
+	attrs    []Attr
+	errorMsg string
+	ended    bool
+}
+
+type spanContextKey struct{}
+
+// FromContext returns the span stored in ctx, or nil
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// Start begins a span named name as a child of the span in ctx, or as the root of a new
+// trace. End must be called on the returned span.
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal, attrs...)
+}
+
+// StartKind is Start with a span kind, e.g. KindClient for outgoing requests
+func StartKind(ctx context.Context, name string, kind int, attrs ...Attr) (context.Context, *Span) {
+	exp := current.Load()
+	if exp == nil {
+		return ctx, nil
+	}
+
+	span := &Span{name: name, kind: kind, start: time.Now(), attrs: attrs}
+	rand.Read(span.spanID[:])
+	if parent := FromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+		span.sampled = parent.sampled
+	} else {
+		rand.Read(span.traceID[:])
+		span.sampled = exp.sample(span.traceID)
+	}
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil || !s.sampled {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed with message; an empty message is ignored
+func (s *Span) SetError(message string) {
+	if s == nil || message == "" {
+		return
+	}
+	s.mu.Lock()
+	s.errorMsg = message
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed with err; a nil error is ignored
+func (s *Span) RecordError(err error) {
+	if err != nil {
+		s.SetError(err.Error())
+	}
+}
+
+// End finishes the span and queues it for export. Later calls are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if exp := current.Load(); exp != nil && s.sampled {
+		exp.enqueue(s)
+	}
+}
+
+// TraceParent returns the W3C traceparent header value for the span
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]), flags)
+}
+
+// IDs returns the hex trace and span IDs, or empty strings for a nil span
+func (s *Span) IDs() (traceID, spanID string) {
+	if s == nil {
+		return "", ""
+	}
+	return hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:])
+}
+
+// Inject sets the traceparent header of an outgoing request to the span in ctx, so the
+// receiver can continue the trace
+func Inject(ctx context.Context, header http.Header) {
+	if span := FromContext(ctx); span != nil {
+		header.Set("traceparent", span.TraceParent())
+	}
+}
+
+// current is the active exporter; nil when tracing is disabled
+var current atomic.Pointer[exporter]
+
+// setupMu serializes Setup and Shutdown
+var setupMu sync.Mutex
+
+// Options configure the exporter
+type Options struct {
+	Endpoint    string            // OTLP/HTTP base URL, e.g. http://localhost:4318; empty disables tracing
+	Headers     map[string]string // Extra request headers, e.g. an API key for a hosted collector
+	ServiceName string
+	Version     string
+	SampleRatio float64 // Fraction of new traces recorded, 0 to 1
+}
+
+// Setup starts exporting spans with opts, replacing and flushing the previous exporter.
+// Calling it again with the same options keeps the running exporter.
+func Setup(opts Options) {
+	setupMu.Lock()
+	defer setupMu.Unlock()
+
+	old := current.Load()
+	if old != nil && old.sameOptions(opts) {
+		return
+	}
+	var next *exporter
+	if opts.Endpoint != "" {
+		next = newExporter(opts)
+	}
+	current.Store(next)
+	if old != nil {
+		old.shutdown()
+	}
+}
+
+// Shutdown exports queued spans and disables tracing
+func Shutdown() {
+	setupMu.Lock()
+	defer setupMu.Unlock()
+
+	if old := current.Swap(nil); old != nil {
+		old.shutdown()
+	}
+}
+
+// sample decides whether a new trace is recorded, consistently for a trace ID
+func (e *exporter) sample(traceID [16]byte) bool {
+	switch {
+	case e.opts.SampleRatio >= 1:
+		return true
+	case e.opts.SampleRatio <= 0:
+		return false
+	}
+	var n uint64
+	for _, b := range traceID[8:] {
+		n = n<<8 | uint64(b)
+	}
+	return float64(n>>11)/float64(1<<53) < e.opts.SampleRatio
+}
+
+// sameOptions reports whether the exporter was created with opts
+func (e *exporter) sameOptions(opts Options) bool {
+	if e.opts.Endpoint != opts.Endpoint || e.opts.ServiceName != opts.ServiceName ||
+		e.opts.Version != opts.Version || math.Abs(e.opts.SampleRatio-opts.SampleRatio) > 1e-9 ||
+		len(e.opts.Headers) != len(opts.Headers) {
+		return false
+	}
+	for key, value := range opts.Headers {
+		if e.opts.Headers[key] != value {
+			return false
+		}
+	}
+	return true
+}